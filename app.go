@@ -80,6 +80,10 @@ implement one or more of the following interfaces:
   - DispatcherModule: For modules that handle events
   - TemplateDataModule: For modules that provide template data
   - ConfigurableModule: For modules that require configuration
+  - BackupModule: For modules that own data to include in backups
+  - NamespacedModule: For modules that ship their own templates, static assets and routes
+  - TemplateModule: For modules that contribute template sources
+  - AssetModule: For modules that serve static files
 
 Creating a basic module:
 
@@ -160,22 +164,32 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/justinas/nosurf"
 
 	"github.com/patrickward/hop/conf"
+	"github.com/patrickward/hop/decode"
 	"github.com/patrickward/hop/dispatch"
 	"github.com/patrickward/hop/log"
+	"github.com/patrickward/hop/pulse"
 	"github.com/patrickward/hop/render"
 	"github.com/patrickward/hop/render/htmx"
+	"github.com/patrickward/hop/render/request"
 	"github.com/patrickward/hop/route"
+	"github.com/patrickward/hop/route/middleware"
 	"github.com/patrickward/hop/serve"
+	"github.com/patrickward/hop/templates"
 	"github.com/patrickward/hop/utils"
 )
 
@@ -189,7 +203,8 @@ type OnTemplateDataFunc func(r *http.Request, data *map[string]any)
 type AppConfig struct {
 	// Config holds the application's configuration settings
 	Config *conf.HopConfig
-	// Logger is the application's logging instance. If nil, a default logger will be created based on the configuration
+	// Logger is the application's logging instance. If nil, a default logger will be created based on the configuration,
+	// shipping logs to the syslog server and OpenTelemetry collector set in the log config
 	Logger *slog.Logger
 	// TemplateSources defines the sources for template files. Multiple sources can be provided with different prefixes
 	TemplateSources render.Sources
@@ -197,8 +212,17 @@ type AppConfig struct {
 	TemplateFuncs template.FuncMap
 	// TemplateExt defines the extension for template files (default: ".html")
 	TemplateExt string
+	// TemplateComponents holds components shared between pages and emails (optional)
+	TemplateComponents *templates.Components
+	// TemplateMetrics records the size and render duration of every rendered page, e.g. a
+	// pulse.StandardCollector (optional)
+	TemplateMetrics pulse.RenderRecorder
+	// TemplateSlowRenderThreshold logs a warning for every page that takes longer to render (optional)
+	TemplateSlowRenderThreshold time.Duration
 	// SessionStore provides the storage backend for sessions
 	SessionStore scs.Store
+	// EventStore persists dispatched events so they can be replayed after a restart (optional)
+	EventStore dispatch.EventStore
 	// Stdout writer for standard output (default: os.Stdout)
 	Stdout io.Writer
 	// Stderr writer for error output (default: os.Stderr)
@@ -210,40 +234,70 @@ type AppConfig struct {
 // of the application. App implements graceful shutdown and ensures modules are started
 // and stopped in the correct order.
 type App struct {
-	firstError     error                       // first error that occurred during initialization
-	logger         *slog.Logger                // logger instance
-	server         *serve.Server               // server instance
-	router         *route.Mux                  // router instance
-	tm             *render.TemplateManager     // template manager instance
-	config         *conf.HopConfig             // configuration
-	events         *dispatch.Dispatcher        // event bus instance
-	session        *scs.SessionManager         // session manager instance
-	modules        map[string]Module           // map of modules by ID
-	startOrder     []string                    // order in which modules should be started / stopped in reverse
-	dataModules    []TemplateDataModule        // modules that provide template data
-	mu             sync.RWMutex                // mutex for modules map
-	onTemplateData OnTemplateDataFunc          // callback function for populating template data
-	onShutdown     func(context.Context) error // callback function for shutting down the app. This is called when the server is shutting down.
+	firstError        error                       // first error that occurred during initialization
+	logger            *slog.Logger                // logger instance
+	server            *serve.Server               // server instance
+	router            *route.Mux                  // router instance
+	tm                *render.TemplateManager     // template manager instance
+	config            *conf.HopConfig             // configuration
+	events            *dispatch.Dispatcher        // event bus instance
+	session           *scs.SessionManager         // session manager instance
+	modules           map[string]Module           // map of modules by ID
+	startOrder        []string                    // order in which modules should be started / stopped in reverse
+	dataModules       []TemplateDataModule        // modules that provide template data
+	mu                sync.RWMutex                // mutex for modules map
+	modulesCtx        context.Context             // context the modules were started with, for restarted modules
+	services          map[reflect.Type]any        // typed services, see Register and Resolve
+	servicesMu        sync.RWMutex                // mutex for services map, separate so Init can resolve services
+	onTemplateData    OnTemplateDataFunc          // callback function for populating template data
+	onShutdown        func(context.Context) error // callback function for shutting down the app. This is called when the server is shutting down.
+	shutdownSteps     []ShutdownStep              // steps registered for the shutdown phases
+	onStart           []LifecycleHook             // hooks run once the modules have started
+	beforeServerStart []LifecycleHook             // hooks run right before the server starts
+	afterStop         []LifecycleHook             // hooks run once the app has stopped
+	maintenance       atomic.Bool                 // whether maintenance mode was switched on
 }
 
 // New creates a new application with core components
 func New(cfg AppConfig) (*App, error) {
 	// Create logger
-	logger := createLogger(&cfg)
+	logger, shippers, err := createLogger(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating logger: %w", err)
+	}
 
 	// Create events
-	eventBus := dispatch.NewDispatcher(logger)
+	var eventOpts []dispatch.Option
+	if cfg.EventStore != nil {
+		eventOpts = append(eventOpts, dispatch.WithEventStore(cfg.EventStore))
+	}
+	eventBus := dispatch.NewDispatcher(logger, eventOpts...)
+
+	// Create router
+	router := route.New()
+	router.SetEnvironment(cfg.Config.App.Environment)
+	router.SetFlags(func(flag string) bool {
+		return slices.Contains(cfg.Config.App.Features, flag)
+	})
+
+	// Create session manager
+	sm := createSessionStore(&cfg)
 
 	// Create template manager
 	var tm *render.TemplateManager
 	if len(cfg.TemplateSources) > 0 {
-		var err error
 		tm, err = render.NewTemplateManager(
 			cfg.TemplateSources,
 			render.TemplateManagerOptions{
-				Extension: cfg.TemplateExt,
-				Funcs:     cfg.TemplateFuncs,
-				Logger:    logger,
+				Extension:           cfg.TemplateExt,
+				Funcs:               cfg.TemplateFuncs,
+				Logger:              logger,
+				Components:          cfg.TemplateComponents,
+				URLFor:              router.URL,
+				A11yAudit:           cfg.Config.IsDevelopment(),
+				Metrics:             cfg.TemplateMetrics,
+				SlowRenderThreshold: cfg.TemplateSlowRenderThreshold,
+				Session:             sm,
 			})
 		if err != nil {
 			return nil, fmt.Errorf("error creating template manager: %w", err)
@@ -251,24 +305,26 @@ func New(cfg AppConfig) (*App, error) {
 
 	}
 
-	// Create session manager
-	sm := createSessionStore(&cfg)
-
-	// Create router
-	router := route.New()
-
 	// Create app
 	app := &App{
 		config:     cfg.Config,
 		logger:     logger,
 		events:     eventBus,
 		modules:    make(map[string]Module),
+		services:   make(map[reflect.Type]any),
 		router:     router,
 		session:    sm,
 		startOrder: make([]string, 0),
 		tm:         tm,
 	}
 
+	app.maintenance.Store(cfg.Config.Maintenance.Enabled)
+
+	// Send the buffered logs after everything that could log has stopped
+	for _, shipper := range shippers {
+		app.RegisterShutdownPhase(ShutdownFlush, "log-shipping", shipper.Close)
+	}
+
 	// Create server
 	app.server = serve.NewServer(cfg.Config, logger, router)
 	app.server.OnShutdown(func(ctx context.Context) error {
@@ -307,7 +363,7 @@ func (a *App) RegisterModule(m Module) *App {
 	}
 
 	if err := m.Init(); err != nil {
-		a.firstError = fmt.Errorf("failed to initialize module %s: %s", id, err)
+		a.firstError = fmt.Errorf("failed to initialize module %s: %w", id, err)
 		return a
 	}
 
@@ -322,6 +378,28 @@ func (a *App) RegisterModule(m Module) *App {
 		h.RegisterRoutes(a.router)
 	}
 
+	if nm, ok := m.(NamespacedModule); ok {
+		if err := a.mountNamespace(id, nm.Namespace()); err != nil {
+			a.firstError = fmt.Errorf("failed to mount module %s: %w", id, err)
+			return a
+		}
+	}
+
+	if tm, ok := m.(TemplateModule); ok {
+		if err := a.mountTemplates(id, tm.Templates()); err != nil {
+			a.firstError = fmt.Errorf("failed to mount templates of module %s: %w", id, err)
+			return a
+		}
+	}
+
+	if am, ok := m.(AssetModule); ok {
+		a.mountAssets(am.Assets())
+	}
+
+	if dm, ok := m.(DispatcherModule); ok {
+		dm.RegisterEvents(a.events)
+	}
+
 	return a
 }
 
@@ -338,8 +416,122 @@ func (a *App) GetModule(id string) (Module, error) {
 	return m, nil
 }
 
-// StartModules initializes and starts all modules without starting the server
+// RestartModule stops a running module, initializes it again and starts it, without restarting the server
+// or the other modules. It emits EventModuleStopped once the module is stopped and EventModuleStarted once
+// it is started again, so dependent modules can drop or rebuild what they hold from it.
+//
+// ctx bounds stopping the module, e.g. an admin request. The module is started again with the context the
+// app started its modules with, so it keeps running after the request and stops with the app. The module's
+// Stop must leave it able to Start again.
+func (a *App) RestartModule(ctx context.Context, id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	m, exists := a.modules[id]
+	if !exists {
+		return fmt.Errorf("module not found: %s", id)
+	}
+
+	return a.swapModule(ctx, m, nil)
+}
+
+// ReplaceModule stops the registered module with the ID of m and starts m in its place, keeping its
+// position in the start and stop order. Like RestartModule, it emits EventModuleStopped and
+// EventModuleStarted.
+//
+// The routes, event handlers, templates and assets registered by the original module stay in place, as
+// the router and dispatcher can't drop them; m's RegisterRoutes and RegisterEvents are not called. Handlers
+// that need the current module should look it up with GetModule rather than capture it.
+func (a *App) ReplaceModule(ctx context.Context, m Module) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	id := m.ID()
+	old, exists := a.modules[id]
+	if !exists {
+		return fmt.Errorf("module not found: %s", id)
+	}
+
+	return a.swapModule(ctx, old, m)
+}
+
+// swapModule stops old, then initializes and starts replacement in its place, or old again when
+// replacement is nil. The caller must hold the write lock.
+func (a *App) swapModule(ctx context.Context, old, replacement Module) error {
+	id := old.ID()
+	m := old
+	if replacement != nil {
+		m = replacement
+	}
+
+	if sm, ok := old.(ShutdownModule); ok {
+		a.logger.Info("stopping module", slog.String("module", id))
+		if err := sm.Stop(ctx); err != nil {
+			return fmt.Errorf("failed to stop module %s: %w", id, err)
+		}
+	}
+	a.emitModuleEvent(ctx, EventModuleStopped, id)
+
+	if err := m.Init(); err != nil {
+		return fmt.Errorf("failed to initialize module %s: %w", id, err)
+	}
+
+	if replacement != nil {
+		a.modules[id] = m
+		a.dataModules = slices.DeleteFunc(a.dataModules, func(tdm TemplateDataModule) bool {
+			return tdm.ID() == id
+		})
+		if tdm, ok := m.(TemplateDataModule); ok {
+			a.dataModules = append(a.dataModules, tdm)
+		}
+	}
+
+	if s, ok := m.(StartupModule); ok {
+		a.logger.Info("starting module", slog.String("module", id))
+		if err := s.Start(a.startContext(ctx)); err != nil {
+			return fmt.Errorf("failed to start module %s: %w", id, err)
+		}
+	}
+	a.emitModuleEvent(ctx, EventModuleStarted, id)
+
+	return nil
+}
+
+// startContext returns the context to start a restarted module with: the one the modules were started
+// with, or ctx without its cancellation when they haven't been. The caller must hold the lock.
+func (a *App) startContext(ctx context.Context) context.Context {
+	if a.modulesCtx != nil {
+		return a.modulesCtx
+	}
+	return context.WithoutCancel(ctx)
+}
+
+// emitModuleEvent emits a module lifecycle event, logging failures
+func (a *App) emitModuleEvent(ctx context.Context, signature, id string) {
+	if err := a.events.Emit(context.WithoutCancel(ctx), signature, ModuleEvent{ID: id}); err != nil {
+		a.logger.Error("failed to emit module event",
+			slog.String("event", signature),
+			slog.String("module", id),
+			slog.String("error", err.Error()))
+	}
+}
+
+// StartModules initializes and starts all modules without starting the server, then runs the OnStart
+// hooks
 func (a *App) StartModules(ctx context.Context) error {
+	a.mu.Lock()
+	a.modulesCtx = ctx
+	a.mu.Unlock()
+
+	if err := a.startModules(ctx); err != nil {
+		return err
+	}
+
+	return a.runHooks(ctx, "start", a.hooks(&a.onStart))
+}
+
+// startModules starts the modules that implement StartupModule, in registration order
+func (a *App) startModules(ctx context.Context) error {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
@@ -360,13 +552,25 @@ func (a *App) StartModules(ctx context.Context) error {
 	return errors.Join(errs...)
 }
 
-// Start initializes the app and starts all modules and the server
+// Start initializes the app and starts all modules and the server, running the OnStart hooks once the
+// modules have started and the BeforeServerStart hooks right before the server starts
 func (a *App) Start(ctx context.Context) error {
 	// First start all modules
 	if err := a.StartModules(ctx); err != nil {
 		return err
 	}
 
+	// Replay any persisted events that were not dispatched before the last shutdown
+	if n, err := a.events.Replay(ctx); err != nil {
+		a.logger.Error("failed to replay events", slog.String("error", err.Error()))
+	} else if n > 0 {
+		a.logger.Info("replayed events", slog.Int("count", n))
+	}
+
+	if err := a.runHooks(ctx, "before server start", a.hooks(&a.beforeServerStart)); err != nil {
+		return err
+	}
+
 	// Then start the server (this will block)
 	if err := a.server.Start(); err != nil {
 		a.logger.Error("failed to start server", slog.String("error", err.Error()))
@@ -381,15 +585,21 @@ func (a *App) ShutdownServer(ctx context.Context) error {
 	return a.server.Shutdown(ctx)
 }
 
-// Stop gracefully shuts down the app and all modules. This is only called when the server is shutting down.
+// Stop gracefully shuts down the app by running its shutdown phases in order (see ShutdownPhase), then
+// the AfterStop hooks. This is only called when the server is shutting down.
 func (a *App) Stop(ctx context.Context) error {
 	a.logger.Info("shutting down app")
 	a.mu.RLock()
-	defer a.mu.RUnlock()
+	err := a.runShutdown(ctx)
+	a.mu.RUnlock()
 
-	var errs []error
+	return errors.Join(err, a.runHooks(ctx, "after stop", a.hooks(&a.afterStop)))
+}
 
-	// Stop modules in reverse order that implement ShutdownModule
+// stopModules stops the modules that implement ShutdownModule, in reverse order. The caller must hold the
+// read lock.
+func (a *App) stopModules(ctx context.Context) error {
+	var errs []error
 	for i := len(a.startOrder) - 1; i >= 0; i-- {
 		id := a.startOrder[i]
 		m := a.modules[id]
@@ -397,18 +607,11 @@ func (a *App) Stop(ctx context.Context) error {
 			a.logger.Info("stopping module", "module", id)
 			if err := sm.Stop(ctx); err != nil {
 				errs = append(errs, err)
-				a.logger.Error("sailed to stop module", slog.String("module", id), slog.String("error", err.Error()))
+				a.logger.Error("failed to stop module", slog.String("module", id), slog.String("error", err.Error()))
 			}
 		}
 	}
 
-	// run the onShutdown callback if it's set
-	if a.onShutdown != nil {
-		if err := a.onShutdown(ctx); err != nil {
-			errs = append(errs, err)
-		}
-	}
-
 	return errors.Join(errs...)
 }
 
@@ -421,6 +624,9 @@ func (a *App) Dispatcher() *dispatch.Dispatcher { return a.events }
 // Router returns the router instance for the app
 func (a *App) Router() *route.Mux { return a.router }
 
+// Server returns the HTTP server of the app
+func (a *App) Server() *serve.Server { return a.server }
+
 // Session returns the session manager instance for the app
 func (a *App) Session() *scs.SessionManager { return a.session }
 
@@ -430,6 +636,69 @@ func (a *App) TM() *render.TemplateManager { return a.tm }
 // Config returns the configuration for the app
 func (a *App) Config() *conf.HopConfig { return a.config }
 
+// CSRF returns CSRF protection middleware configured from the csrf section of the configuration. Failed
+// checks render the 403 system error template when the app renders templates. optsFunc, which may be nil,
+// can adjust the options further, e.g. to add exemptions.
+//
+//	app.Router().Use(app.Session().LoadAndSave, app.CSRF(nil))
+func (a *App) CSRF(optsFunc func(opts *middleware.CSRFOptions)) route.Middleware {
+	cfg := a.config.Csrf
+	return middleware.CSRF(func(opts *middleware.CSRFOptions) {
+		opts.HTTPOnly = cfg.HTTPOnly
+		opts.Path = cfg.Path
+		opts.MaxAge = cfg.MaxAge
+		opts.SameSite = cfg.SameSite
+		opts.Secure = cfg.Secure
+		opts.ExemptGlobs = cfg.ExemptPaths
+		opts.Templates = a.tm
+		opts.Logger = a.logger
+		if optsFunc != nil {
+			optsFunc(opts)
+		}
+	})
+}
+
+// EnableMaintenance switches maintenance mode on. Requests passing through the Maintenance middleware
+// receive 503 Service Unavailable until DisableMaintenance is called.
+func (a *App) EnableMaintenance() {
+	a.maintenance.Store(true)
+	a.logger.Info("maintenance mode enabled")
+}
+
+// DisableMaintenance switches maintenance mode off
+func (a *App) DisableMaintenance() {
+	a.maintenance.Store(false)
+	a.logger.Info("maintenance mode disabled")
+}
+
+// InMaintenance reports whether the app is in maintenance mode, either because it was switched on in the
+// configuration or with EnableMaintenance, or because the server is shutting down
+func (a *App) InMaintenance() bool {
+	return a.maintenance.Load() || a.server.ShuttingDown()
+}
+
+// Maintenance returns middleware that answers requests with the 503 system error template and a
+// Retry-After header while the app is in maintenance mode. The message, retry delay and allowed paths
+// come from the maintenance section of the configuration. optsFunc, which may be nil, can adjust the
+// options further.
+//
+//	app.Router().Use(app.Maintenance(nil))
+func (a *App) Maintenance(optsFunc func(opts *middleware.MaintenanceOptions)) route.Middleware {
+	cfg := a.config.Maintenance
+	return middleware.Maintenance(func(opts *middleware.MaintenanceOptions) {
+		opts.Enabled = a.InMaintenance
+		opts.Message = cfg.Message
+		if cfg.RetryAfter.Duration > 0 {
+			opts.RetryAfter = cfg.RetryAfter.Duration
+		}
+		opts.AllowPaths = cfg.AllowPaths
+		opts.Templates = a.tm
+		if optsFunc != nil {
+			optsFunc(opts)
+		}
+	})
+}
+
 // RunInBackground runs a function in the background via the server
 func (a *App) RunInBackground(r *http.Request, fn func() error) {
 	a.server.BackgroundTask(r, fn)
@@ -440,7 +709,9 @@ func (a *App) OnTemplateData(fn OnTemplateDataFunc) {
 	a.onTemplateData = fn
 }
 
-// OnShutdown registers a function to be called when the app is shutting down
+// OnShutdown registers a function to be called when the app is shutting down. It runs in the
+// ShutdownFlush phase and replaces any function registered before; use RegisterShutdownPhase to add
+// steps to other phases.
 func (a *App) OnShutdown(fn func(context.Context) error) {
 	a.onShutdown = fn
 }
@@ -470,11 +741,15 @@ func (a *App) NewTemplateData(r *http.Request) map[string]any {
 		"BaseURL":            a.config.Server.BaseURL,
 		"CacheBuster":        cacheBuster,
 		"RequestPath":        r.URL.Path,
+		"RequestURL":         r.URL,
+		"Timezone":           request.Timezone(r),
+		"Locale":             decode.LocaleFromRequest(r).Tag,
 		"IsHome":             r.URL.Path == "/",
 		"IsHTMXRequest":      htmx.IsHtmxRequest(r),
 		"IsBoostedRequest":   htmx.IsBoostedRequest(r),
 		"IsAnyHtmxRequest":   htmx.IsAnyHtmxRequest(r),
-		"MaintenanceEnabled": a.config.Maintenance.Enabled,
+		"HTMX":               htmx.RequestDetails(r),
+		"MaintenanceEnabled": a.InMaintenance(),
 		"MaintenanceMessage": a.config.Maintenance.Message,
 	}
 
@@ -491,6 +766,9 @@ func (a *App) NewTemplateData(r *http.Request) map[string]any {
 	for _, tdm := range a.dataModules {
 		moduleData := make(map[string]any)
 		tdm.OnTemplateData(r, &moduleData)
+		if _, ok := tdm.(NamespacedModule); ok {
+			moduleData = map[string]any{"Modules": map[string]any{tdm.ID(): moduleData}}
+		}
 		utils.DeepMerge(&data, moduleData)
 	}
 
@@ -540,22 +818,140 @@ func (a *App) AddChainedRoutes(routes map[string]http.Handler, chain route.Chain
 // Private functions
 // -----------------------------------------------------------------------------
 
-func createLogger(cfg *AppConfig) *slog.Logger {
-	if cfg.Logger == nil {
-		if cfg.Stderr == nil {
-			cfg.Stderr = os.Stderr
+// mountNamespace mounts the templates, static assets and routes of a NamespacedModule under its ID
+func (a *App) mountNamespace(id string, ns ModuleNamespace) error {
+	prefix := ns.Prefix
+	if prefix == "" {
+		prefix = "/" + id
+	}
+	prefix = "/" + strings.Trim(prefix, "/")
+
+	if ns.Templates != nil {
+		if a.tm == nil {
+			return errors.New("module provides templates, but this app does not support rendering templates")
+		}
+		if err := a.tm.AddSource(id, ns.Templates); err != nil {
+			return err
+		}
+	}
+
+	if ns.Static != nil {
+		staticPrefix := strings.TrimSuffix(prefix, "/") + "/static/"
+		a.router.Handle("GET "+staticPrefix, http.StripPrefix(staticPrefix, http.FileServerFS(ns.Static)))
+	}
+
+	if ns.Routes != nil {
+		a.router.PrefixGroup(prefix, ns.Routes)
+	}
+
+	return nil
+}
+
+// mountTemplates adds the template sources of a TemplateModule, in namespace order
+func (a *App) mountTemplates(id string, sources render.Sources) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	if a.tm == nil {
+		return errors.New("module provides templates, but this app does not support rendering templates")
+	}
+
+	namespaces := make([]string, 0, len(sources))
+	for namespace := range sources {
+		namespaces = append(namespaces, namespace)
+	}
+	slices.Sort(namespaces)
+
+	for _, namespace := range namespaces {
+		fsID := namespace
+		if fsID == "" || fsID == "-" {
+			fsID = id
+		}
+		if err := a.tm.AddSource(fsID, sources[namespace]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountAssets serves the static file systems of an AssetModule under their URL prefixes
+func (a *App) mountAssets(assets map[string]fs.FS) {
+	for prefix, fsys := range assets {
+		prefix = "/" + strings.Trim(prefix, "/") + "/"
+		if prefix == "//" {
+			prefix = "/"
+		}
+		a.router.Handle("GET "+prefix, http.StripPrefix(prefix, http.FileServerFS(fsys)))
+	}
+}
+
+// createLogger creates the default logger when none is provided, along with the handlers shipping its records
+// to the configured syslog server and OpenTelemetry collector
+func createLogger(cfg *AppConfig) (*slog.Logger, []*log.ShippingHandler, error) {
+	if cfg.Logger != nil {
+		return cfg.Logger, nil, nil
+	}
+
+	if cfg.Stderr == nil {
+		cfg.Stderr = os.Stderr
+	}
+	logger := log.NewLogger(log.Options{
+		Format:      cfg.Config.Log.Format,
+		IncludeTime: cfg.Config.Log.IncludeTime,
+		Level:       cfg.Config.Log.Level,
+		Verbose:     cfg.Config.Log.Verbose,
+		Writer:      cfg.Stderr,
+	})
+
+	level := log.LevelFromString(cfg.Config.Log.Level)
+	var shippers []*log.ShippingHandler
+
+	if syslogCfg := cfg.Config.Log.Syslog; syslogCfg.Address != "" {
+		h, err := log.NewSyslogHandler(func(opts *log.SyslogOptions) {
+			opts.Level = level
+			opts.Network = syslogCfg.Network
+			opts.Address = syslogCfg.Address
+			opts.AppName = syslogCfg.AppName
+			opts.Facility = syslogCfg.Facility
+		})
+		if err != nil {
+			return nil, nil, err
 		}
-		logger := log.NewLogger(log.Options{
-			Format:      cfg.Config.Log.Format,
-			IncludeTime: cfg.Config.Log.IncludeTime,
-			Level:       cfg.Config.Log.Level,
-			Verbose:     cfg.Config.Log.Verbose,
-			Writer:      cfg.Stderr,
+		shippers = append(shippers, h)
+	}
+
+	if otlpCfg := cfg.Config.Log.OTLP; otlpCfg.Endpoint != "" {
+		headers := make(map[string]string, len(otlpCfg.Headers))
+		for _, header := range otlpCfg.Headers {
+			key, value, ok := strings.Cut(header, "=")
+			if !ok {
+				return nil, nil, fmt.Errorf("otlp: invalid header %q: must be key=value", header)
+			}
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+
+		h, err := log.NewOTLPHandler(func(opts *log.OTLPOptions) {
+			opts.Level = level
+			opts.Endpoint = otlpCfg.Endpoint
+			opts.ServiceName = otlpCfg.ServiceName
+			opts.Headers = headers
 		})
-		cfg.Logger = logger
+		if err != nil {
+			return nil, nil, err
+		}
+		shippers = append(shippers, h)
+	}
+
+	if len(shippers) > 0 {
+		handlers := []slog.Handler{logger.Handler()}
+		for _, h := range shippers {
+			handlers = append(handlers, h)
+		}
+		logger = slog.New(log.NewMultiHandler(handlers...))
 	}
 
-	return cfg.Logger
+	cfg.Logger = logger
+	return logger, shippers, nil
 }
 
 // createSessionStore creates a new session store based on the configuration
@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -14,6 +18,9 @@ import (
 
 	"github.com/patrickward/hop"
 	"github.com/patrickward/hop/conf"
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/render"
 	"github.com/patrickward/hop/route"
 )
 
@@ -295,6 +302,23 @@ func TestModuleStop(t *testing.T) {
 	}
 }
 
+func TestStopDrainsEventHandlers(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	var completed atomic.Bool
+	app.Dispatcher().On("test.event", func(ctx context.Context, event dispatch.Event) {
+		time.Sleep(20 * time.Millisecond)
+		completed.Store(true)
+	})
+
+	require.NoError(t, app.Dispatcher().Emit(context.Background(), "test.event", nil))
+	require.NoError(t, app.Stop(context.Background()))
+	assert.True(t, completed.Load(), "Stop should wait for in-flight handlers")
+
+	assert.ErrorIs(t, app.Dispatcher().Emit(context.Background(), "test.event", nil), dispatch.ErrClosed)
+}
+
 func TestModuleLifecycle(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -389,6 +413,139 @@ func TestModuleLifecycle(t *testing.T) {
 	}
 }
 
+// lifecycleModule counts its lifecycle calls
+type lifecycleModule struct {
+	id                   string
+	label                string
+	inits, starts, stops atomic.Int32
+	startErr             error
+	startCtx             context.Context
+}
+
+func (m *lifecycleModule) ID() string { return m.id }
+func (m *lifecycleModule) Init() error {
+	m.inits.Add(1)
+	return nil
+}
+func (m *lifecycleModule) Start(ctx context.Context) error {
+	m.starts.Add(1)
+	m.startCtx = ctx
+	return m.startErr
+}
+func (m *lifecycleModule) Stop(context.Context) error {
+	m.stops.Add(1)
+	return nil
+}
+func (m *lifecycleModule) OnTemplateData(_ *http.Request, data *map[string]any) {
+	(*data)["label"] = m.label
+}
+
+func TestRestartAndReplaceModule(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	events := make(chan string, 10)
+	app.Dispatcher().On("module.*", func(_ context.Context, event dispatch.Event) {
+		events <- event.Signature + " " + event.Payload.(hop.ModuleEvent).ID
+	})
+
+	original := &lifecycleModule{id: "cache", label: "original"}
+	app.RegisterModule(original)
+	require.NoError(t, app.Error())
+	require.NoError(t, app.StartModules(context.Background()))
+
+	receive := func() []string {
+		t.Helper()
+		var got []string
+		for range 2 {
+			select {
+			case e := <-events:
+				got = append(got, e)
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for module events")
+			}
+		}
+		return got
+	}
+
+	t.Run("restart", func(t *testing.T) {
+		require.NoError(t, app.RestartModule(context.Background(), "cache"))
+
+		assert.Equal(t, int32(2), original.inits.Load())
+		assert.Equal(t, int32(2), original.starts.Load())
+		assert.Equal(t, int32(1), original.stops.Load())
+		assert.ElementsMatch(t, []string{"module.stopped cache", "module.started cache"}, receive())
+	})
+
+	t.Run("restart outlives the request", func(t *testing.T) {
+		requestCtx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, app.RestartModule(requestCtx, "cache"))
+		cancel()
+		receive()
+
+		require.NoError(t, original.startCtx.Err(), "the module is started with the app's context")
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		replacement := &lifecycleModule{id: "cache", label: "replacement"}
+		require.NoError(t, app.ReplaceModule(context.Background(), replacement))
+
+		assert.Equal(t, int32(3), original.stops.Load())
+		assert.Equal(t, int32(1), replacement.inits.Load())
+		assert.Equal(t, int32(1), replacement.starts.Load())
+		assert.ElementsMatch(t, []string{"module.stopped cache", "module.started cache"}, receive())
+
+		m, err := app.GetModule("cache")
+		require.NoError(t, err)
+		assert.Same(t, replacement, m)
+
+		data := app.NewTemplateData(httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "replacement", data["label"])
+
+		require.NoError(t, app.Stop(context.Background()))
+		assert.Equal(t, int32(1), replacement.stops.Load())
+		assert.Equal(t, int32(3), original.stops.Load())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		assert.Error(t, app.RestartModule(context.Background(), "missing"))
+		assert.Error(t, app.ReplaceModule(context.Background(), &lifecycleModule{id: "missing"}))
+		assert.Error(t, app.ReplaceModule(context.Background(), &lifecycleModule{id: "cache", startErr: errors.New("boom")}))
+	})
+}
+
+// countingCollector counts the system metric collections of the pulse module
+type countingCollector struct {
+	pulse.Collector
+	collections atomic.Int32
+}
+
+func (c *countingCollector) RecordMemStats()       { c.collections.Add(1) }
+func (c *countingCollector) RecordGoroutineCount() {}
+func (c *countingCollector) Handler() http.Handler { return http.NotFoundHandler() }
+
+func TestRestartBuiltInModule(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	collector := &countingCollector{}
+	app.RegisterModule(pulse.NewModule(collector, &pulse.Config{CollectionInterval: 5 * time.Millisecond}))
+	require.NoError(t, app.Error())
+	require.NoError(t, app.StartModules(context.Background()))
+
+	for range 2 {
+		require.NoError(t, app.RestartModule(context.Background(), "hop.pulse"))
+	}
+
+	// The restarted module keeps collecting on its interval
+	collected := collector.collections.Load()
+	assert.Eventually(t, func() bool {
+		return collector.collections.Load() > collected+1
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, app.Stop(context.Background()))
+}
+
 func TestHTTPModuleRoutes(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -445,6 +602,135 @@ func TestHTTPModuleRoutes(t *testing.T) {
 	}
 }
 
+type mockNamespacedModule struct {
+	mockModule
+	ns hop.ModuleNamespace
+}
+
+func (m *mockNamespacedModule) Namespace() hop.ModuleNamespace { return m.ns }
+
+func (m *mockNamespacedModule) OnTemplateData(r *http.Request, data *map[string]any) {
+	(*data)["Greeting"] = "hello from " + m.id
+}
+
+func TestNamespacedModule(t *testing.T) {
+	app, err := hop.New(hop.AppConfig{
+		Config: &conf.HopConfig{App: conf.AppConfig{Environment: "test"}},
+		TemplateSources: render.Sources{"-": fstest.MapFS{
+			"layouts/base.html": {Data: []byte(`{{define "layout:base"}}<main>{{template "page:main" .}}</main>{{end}}`)},
+		}},
+	})
+	require.NoError(t, err)
+
+	module := &mockNamespacedModule{
+		mockModule: mockModule{id: "blog"},
+		ns: hop.ModuleNamespace{
+			Templates: fstest.MapFS{
+				"views/index.html": {Data: []byte(`{{define "page:main"}}{{.Modules.blog.Greeting}}{{end}}`)},
+			},
+			Static: fstest.MapFS{
+				"app.css": {Data: []byte("body{}")},
+			},
+		},
+	}
+	module.ns.Routes = func(g *route.Group) {
+		g.Get("/{$}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			app.NewResponse(r).Path("blog:index").StatusOK().Render(w, r)
+		}))
+	}
+
+	app.RegisterModule(module)
+	require.NoError(t, app.Error())
+
+	// Module data is scoped under .Modules.<id> instead of the top level
+	data := app.NewTemplateData(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NotContains(t, data, "Greeting")
+	assert.Equal(t, map[string]any{"blog": map[string]any{"Greeting": "hello from blog"}}, data["Modules"])
+
+	w := newTestResponseRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blog/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<main>hello from blog</main>", w.Body.String())
+
+	w = newTestResponseRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blog/static/app.css", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "body{}", w.Body.String())
+
+	// Modules that provide templates need an app that renders templates
+	plain, err := createTestApp(t)
+	require.NoError(t, err)
+	plain.RegisterModule(&mockNamespacedModule{
+		mockModule: mockModule{id: "blog"},
+		ns:         hop.ModuleNamespace{Templates: fstest.MapFS{}},
+	})
+	assert.ErrorContains(t, plain.Error(), "failed to mount module blog")
+}
+
+type mockAssetModule struct {
+	mockModule
+	templates render.Sources
+	assets    map[string]fs.FS
+}
+
+func (m *mockAssetModule) Templates() render.Sources { return m.templates }
+
+func (m *mockAssetModule) Assets() map[string]fs.FS { return m.assets }
+
+func TestTemplateAndAssetModules(t *testing.T) {
+	app, err := hop.New(hop.AppConfig{
+		Config: &conf.HopConfig{App: conf.AppConfig{Environment: "test"}},
+		TemplateSources: render.Sources{"-": fstest.MapFS{
+			"layouts/base.html": {Data: []byte(`{{define "layout:base"}}<main>{{template "page:main" .}}</main>{{end}}`)},
+		}},
+	})
+	require.NoError(t, err)
+
+	app.RegisterModule(&mockAssetModule{
+		mockModule: mockModule{id: "ui"},
+		templates: render.Sources{
+			"": fstest.MapFS{
+				"partials/button.html": {Data: []byte(`{{define "partial:button"}}<button>{{.}}</button>{{end}}`)},
+				"views/demo.html":      {Data: []byte(`{{define "page:main"}}{{template "partial:button" "ok"}}{{end}}`)},
+			},
+			"icons": fstest.MapFS{
+				"views/list.html": {Data: []byte(`{{define "page:main"}}icons{{end}}`)},
+			},
+		},
+		assets: map[string]fs.FS{
+			"/assets/ui": fstest.MapFS{"ui.css": {Data: []byte("button{}")}},
+		},
+	})
+	require.NoError(t, app.Error())
+
+	app.Router().Get("/demo", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.NewResponse(r).Path("ui:demo").StatusOK().Render(w, r)
+	}))
+	app.Router().Get("/icons", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.NewResponse(r).Path("icons:list").StatusOK().Render(w, r)
+	}))
+
+	w := newTestResponseRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/demo", nil))
+	assert.Equal(t, "<main><button>ok</button></main>", w.Body.String(), "the empty namespace stands for the module ID")
+
+	w = newTestResponseRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/icons", nil))
+	assert.Equal(t, "<main>icons</main>", w.Body.String())
+
+	w = newTestResponseRecorder()
+	app.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/ui/ui.css", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "button{}", w.Body.String())
+
+	// A namespace can only be mounted once
+	app.RegisterModule(&mockAssetModule{
+		mockModule: mockModule{id: "other"},
+		templates:  render.Sources{"icons": fstest.MapFS{}},
+	})
+	assert.ErrorContains(t, app.Error(), "failed to mount templates of module other")
+}
+
 // Helper to create a test app with minimal configuration
 func createTestApp(t *testing.T) (*hop.App, error) {
 	t.Helper()
@@ -675,3 +961,157 @@ func TestFullServerStart(t *testing.T) {
 	serverErr := <-errCh
 	assert.NoError(t, serverErr)
 }
+
+func TestLifecycleHooks(t *testing.T) {
+	t.Run("hooks run in order", func(t *testing.T) {
+		app, err := createTestApp(t)
+		require.NoError(t, err)
+
+		var calls []string
+		record := func(name string, err error) hop.LifecycleHook {
+			return func(context.Context) error {
+				calls = append(calls, name)
+				return err
+			}
+		}
+
+		app.RegisterModule(&mockModule{id: "module"})
+		app.OnStart(record("start 1", nil))
+		app.OnStart(record("start 2", nil))
+		app.OnStart(record("start 3", nil))
+		app.BeforeServerStart(record("before server 1", errors.New("not ready")))
+		app.BeforeServerStart(record("before server 2", nil))
+		app.AfterStop(record("after stop 1", errors.New("pid file missing")))
+		app.AfterStop(record("after stop 2", nil))
+		app.RegisterShutdownPhase(hop.ShutdownFlush, "flush", record("flush", nil))
+
+		// The failing BeforeServerStart hook keeps the server from starting
+		err = app.Start(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not ready")
+		assert.Equal(t, []string{"start 1", "start 2", "start 3", "before server 1", "before server 2"}, calls)
+
+		calls = nil
+		err = app.Stop(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "pid file missing")
+		assert.Equal(t, []string{"flush", "after stop 1", "after stop 2"}, calls)
+	})
+
+	t.Run("start errors are joined", func(t *testing.T) {
+		app, err := createTestApp(t)
+		require.NoError(t, err)
+
+		errA, errB := errors.New("a"), errors.New("b")
+		app.OnStart(func(context.Context) error { return errA })
+		app.OnStart(func(context.Context) error { return errB })
+
+		err = app.StartModules(context.Background())
+		assert.ErrorIs(t, err, errA)
+		assert.ErrorIs(t, err, errB)
+	})
+
+	t.Run("start hooks are skipped when a module fails", func(t *testing.T) {
+		app, err := createTestApp(t)
+		require.NoError(t, err)
+
+		app.RegisterModule(&mockModule{id: "broken", startErr: errors.New("start failed")})
+		var ran bool
+		app.OnStart(func(context.Context) error {
+			ran = true
+			return nil
+		})
+
+		assert.Error(t, app.StartModules(context.Background()))
+		assert.False(t, ran)
+	})
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	handler := app.Maintenance(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	assert.False(t, app.InMaintenance())
+	assert.Equal(t, http.StatusOK, serve())
+
+	app.EnableMaintenance()
+	assert.True(t, app.InMaintenance())
+	assert.Equal(t, true, app.NewTemplateData(httptest.NewRequest(http.MethodGet, "/", nil))["MaintenanceEnabled"])
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+
+	app.DisableMaintenance()
+	assert.Equal(t, http.StatusOK, serve())
+}
+
+type orderModule struct {
+	mockModule
+	record func(string)
+}
+
+func (m *orderModule) Stop(ctx context.Context) error {
+	m.record("module:" + m.id)
+	return nil
+}
+
+func TestShutdownPhases(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+	step := func(name string, err error) func(context.Context) error {
+		return func(ctx context.Context) error {
+			record(name)
+			return err
+		}
+	}
+
+	app.RegisterModule(&orderModule{mockModule: mockModule{id: "first"}, record: record})
+	app.RegisterModule(&orderModule{mockModule: mockModule{id: "second"}, record: record})
+	require.NoError(t, app.Error())
+
+	// Registered out of order on purpose; phases decide the order
+	app.RegisterShutdownPhase(hop.ShutdownFlush, "metrics", step("flush", nil))
+	app.RegisterShutdownPhase(hop.ShutdownStopJobs, "jobs", step("jobs", errors.New("queue stuck")))
+	app.RegisterShutdownPhase(hop.ShutdownDrainHTTP, "websockets", step("websockets", nil))
+	app.OnShutdown(step("on-shutdown", nil))
+
+	var names []string
+	for _, s := range app.ShutdownPlan() {
+		names = append(names, s.Phase.String()+"/"+s.Name)
+	}
+	assert.Equal(t, []string{
+		"drain-http/websockets",
+		"stop-jobs/events",
+		"stop-jobs/jobs",
+		"stop-modules/modules",
+		"flush/on-shutdown",
+		"flush/metrics",
+	}, names)
+
+	err = app.Stop(context.Background())
+	assert.ErrorContains(t, err, "jobs: queue stuck", "a failing step is reported without stopping the shutdown")
+
+	require.Len(t, order, 6)
+	assert.Equal(t, []string{"websockets", "jobs", "module:second", "module:first"}, order[:4])
+	// Steps within a phase run concurrently
+	assert.ElementsMatch(t, []string{"on-shutdown", "flush"}, order[4:])
+
+	assert.Panics(t, func() { app.RegisterShutdownPhase(hop.ShutdownPhase(99), "bad", step("bad", nil)) })
+}
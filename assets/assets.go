@@ -0,0 +1,217 @@
+// Package assets fingerprints static files, so they can be cached by browsers forever and still be
+// refreshed as soon as they change. A Manifest hashes every file of a file system at startup and maps
+// each name, e.g. "css/app.css", to a name carrying its content hash, e.g. "css/app.3f2a1b9c.css".
+//
+//	manifest, err := assets.New(static.Files, nil)
+//	if err != nil { ... }
+//	if err := manifest.Mount(app.Router()); err != nil { ... }
+//
+// Templates resolve URLs with the asset function from Manifest.FuncMap, passed to the template manager
+// with the other template functions:
+//
+//	<link rel="stylesheet" href="{{asset "css/app.css"}}"> <!-- /static/css/app.3f2a1b9c.css -->
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickward/hop/route"
+)
+
+// DefaultMaxAge is the default lifetime of fingerprinted files in browser caches
+const DefaultMaxAge = 365 * 24 * time.Hour
+
+// Options configures a Manifest
+type Options struct {
+	// Prefix is the URL path the files are served under. Default is "/static/".
+	Prefix string
+	// HashLength is the number of hex characters of the content hash added to file names. Default is 8.
+	HashLength int
+	// MaxAge is how long browsers may cache fingerprinted files. Default is DefaultMaxAge. Files requested
+	// by their plain name are always revalidated.
+	MaxAge time.Duration
+}
+
+// Manifest maps the names of static files to their fingerprinted names
+type Manifest struct {
+	fsys   fs.FS
+	prefix string
+	maxAge time.Duration
+	// names maps plain names to fingerprinted names, and sources fingerprinted names back to plain names
+	names   map[string]string
+	sources map[string]string
+}
+
+// New hashes every file of fsys and returns their Manifest
+func New(fsys fs.FS, optsFunc func(opts *Options)) (*Manifest, error) {
+	opts := Options{
+		Prefix:     "/static/",
+		HashLength: 8,
+		MaxAge:     DefaultMaxAge,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Prefix == "" {
+		opts.Prefix = "/static/"
+	}
+	if opts.HashLength <= 0 || opts.HashLength > sha256.Size*2 {
+		opts.HashLength = 8
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+
+	m := &Manifest{
+		fsys:    fsys,
+		prefix:  "/" + strings.Trim(opts.Prefix, "/") + "/",
+		maxAge:  opts.MaxAge,
+		names:   make(map[string]string),
+		sources: make(map[string]string),
+	}
+	if m.prefix == "//" {
+		m.prefix = "/"
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		hash, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		hashed := fingerprint(name, hash[:opts.HashLength])
+		m.names[name] = hashed
+		m.sources[hashed] = name
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("assets: hashing files: %w", err)
+	}
+
+	return m, nil
+}
+
+// Lookup returns the fingerprinted name of a file, e.g. "css/app.3f2a1b9c.css" for "css/app.css"
+func (m *Manifest) Lookup(name string) (string, bool) {
+	hashed, ok := m.names[strings.TrimPrefix(name, "/")]
+	return hashed, ok
+}
+
+// URL returns the URL of the fingerprinted file, or of the file itself when it isn't in the manifest
+func (m *Manifest) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if hashed, ok := m.names[name]; ok {
+		return m.prefix + hashed
+	}
+	return m.prefix + name
+}
+
+// Names returns a copy of the manifest, mapping plain names to fingerprinted names
+func (m *Manifest) Names() map[string]string {
+	names := make(map[string]string, len(m.names))
+	for name, hashed := range m.names {
+		names[name] = hashed
+	}
+	return names
+}
+
+// WriteJSON writes the manifest as a JSON object mapping plain names to fingerprinted names, the format
+// used by most asset bundlers, e.g. for a CDN upload script
+func (m *Manifest) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m.names)
+}
+
+// FuncMap returns the asset template function, which resolves a file name to its fingerprinted URL
+func (m *Manifest) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset": m.URL,
+	}
+}
+
+// FileSystem returns the files as an http.FileSystem that resolves request paths under the prefix, by
+// their fingerprinted or plain name. Directories are not listed.
+func (m *Manifest) FileSystem() http.FileSystem {
+	return fileSystem{m}
+}
+
+// Mount serves the files under the prefix with route.Mux.ServeDirectory. Fingerprinted files are cached
+// for MaxAge and marked immutable; files requested by their plain name are revalidated on every use.
+func (m *Manifest) Mount(mux *route.Mux) error {
+	return mux.ServeDirectory("GET "+m.prefix+"{file...}", m.FileSystem(), m.cacheControl)
+}
+
+// cacheControl sets the Cache-Control header of served files
+func (m *Manifest) cacheControl(next http.Handler) http.Handler {
+	immutable := "public, max-age=" + strconv.Itoa(int(m.maxAge.Seconds())) + ", immutable"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := m.sources[strings.TrimPrefix(r.URL.Path, m.prefix)]; ok {
+			w.Header().Set("Cache-Control", immutable)
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fileSystem serves the files of a Manifest
+type fileSystem struct {
+	m *Manifest
+}
+
+func (f fileSystem) Open(name string) (http.File, error) {
+	name, ok := strings.CutPrefix(name, f.m.prefix)
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	if source, ok := f.m.sources[name]; ok {
+		name = source
+	}
+	if _, ok := f.m.names[name]; !ok {
+		return nil, fs.ErrNotExist
+	}
+	return http.FS(f.m.fsys).Open("/" + name)
+}
+
+// hashFile returns the hex encoded SHA-256 hash of a file
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fingerprint inserts the hash before the extension of a file name
+func fingerprint(name, hash string) string {
+	dir, file := path.Split(name)
+	ext := path.Ext(file)
+	if ext == file {
+		// Dot files such as ".well-known" have no extension to keep
+		ext = ""
+	}
+	return dir + strings.TrimSuffix(file, ext) + "." + hash + ext
+}
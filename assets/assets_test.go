@@ -0,0 +1,98 @@
+package assets_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/assets"
+	"github.com/patrickward/hop/route"
+)
+
+var files = fstest.MapFS{
+	"css/app.css": {Data: []byte("body{}")},
+	"js/app.js":   {Data: []byte("console.log(1)")},
+	"favicon":     {Data: []byte("icon")},
+}
+
+func TestManifest(t *testing.T) {
+	manifest, err := assets.New(files, nil)
+	require.NoError(t, err)
+
+	hashed, ok := manifest.Lookup("css/app.css")
+	require.True(t, ok)
+	assert.Regexp(t, `^css/app\.[0-9a-f]{8}\.css$`, hashed)
+	assert.Equal(t, "/static/"+hashed, manifest.URL("/css/app.css"))
+	assert.Regexp(t, `^/static/favicon\.[0-9a-f]{8}$`, manifest.URL("favicon"))
+	assert.Equal(t, "/static/missing.css", manifest.URL("missing.css"))
+
+	t.Run("the hash changes with the content", func(t *testing.T) {
+		changed, err := assets.New(fstest.MapFS{"css/app.css": {Data: []byte("body{color:red}")}}, nil)
+		require.NoError(t, err)
+		changedName, _ := changed.Lookup("css/app.css")
+		assert.NotEqual(t, hashed, changedName)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		other, err := assets.New(files, func(opts *assets.Options) {
+			opts.Prefix = "assets"
+			opts.HashLength = 12
+		})
+		require.NoError(t, err)
+		assert.Regexp(t, `^/assets/css/app\.[0-9a-f]{12}\.css$`, other.URL("css/app.css"))
+	})
+
+	t.Run("writes the manifest as json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, manifest.WriteJSON(&buf))
+		var names map[string]string
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &names))
+		assert.Equal(t, manifest.Names(), names)
+		assert.Len(t, names, 3)
+	})
+
+	t.Run("asset template function", func(t *testing.T) {
+		tmpl := template.Must(template.New("page").Funcs(manifest.FuncMap()).Parse(`<script src="{{asset "js/app.js"}}"></script>`))
+		var buf strings.Builder
+		require.NoError(t, tmpl.Execute(&buf, nil))
+		assert.Equal(t, `<script src="`+manifest.URL("js/app.js")+`"></script>`, buf.String())
+	})
+}
+
+func TestManifest_Mount(t *testing.T) {
+	manifest, err := assets.New(files, func(opts *assets.Options) {
+		opts.MaxAge = time.Hour
+	})
+	require.NoError(t, err)
+
+	mux := route.New()
+	require.NoError(t, manifest.Mount(mux))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get(manifest.URL("css/app.css"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "body{}", w.Body.String())
+	assert.Equal(t, "public, max-age=3600, immutable", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/css")
+
+	w = get("/static/css/app.css")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"), "plain names are revalidated")
+
+	assert.Equal(t, http.StatusNotFound, get("/static/css/app.00000000.css").Code)
+	assert.Equal(t, http.StatusNotFound, get("/static/css/").Code, "directories are not listed")
+}
@@ -0,0 +1,315 @@
+// Package auth provides session based authentication on top of scs sessions: logging users in and out,
+// loading the current user for each request, requiring authentication for routes, and remember-me tokens
+// that keep users logged in after their session expires.
+//
+//	authn := auth.New(&auth.Config{
+//	    Session:  app.Session(),
+//	    Users:    users, // implements auth.UserStore
+//	    Remember: auth.NewMemoryRememberStore(),
+//	})
+//	app.RegisterModule(authn) // adds IsAuthenticated and CurrentUser to template data
+//
+//	router.Use(app.Session().LoadAndSave, authn.Middleware)
+//	router.PrefixGroup("/account", func(g *route.Group) {
+//	    g.Use(authn.RequireAuthenticated)
+//	    g.Get("/", accountHandler)
+//	})
+//
+// Administrators can impersonate users with StartImpersonating and StopImpersonating when
+// Config.CanImpersonate allows it. Routes wrapped with ForbidImpersonation are unavailable while
+// impersonating, and audit events are emitted when the module is registered with the app's dispatcher.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/render/htmx"
+)
+
+// ErrUserNotFound is returned by a UserStore when no user has the ID
+var ErrUserNotFound = errors.New("auth: user not found")
+
+// User is implemented by the application's user type
+type User interface {
+	// AuthID returns the unique, stable identifier stored in the session
+	AuthID() string
+}
+
+// UserStore loads users by ID
+type UserStore interface {
+	// FindUser returns the user with the ID, or an error wrapping ErrUserNotFound
+	FindUser(ctx context.Context, id string) (User, error)
+}
+
+// UserStoreFunc adapts a function to the UserStore interface
+type UserStoreFunc func(ctx context.Context, id string) (User, error)
+
+// FindUser calls f(ctx, id)
+func (f UserStoreFunc) FindUser(ctx context.Context, id string) (User, error) {
+	return f(ctx, id)
+}
+
+// Session is the subset of *scs.SessionManager used for authentication
+type Session interface {
+	GetString(ctx context.Context, key string) string
+	Put(ctx context.Context, key string, val interface{})
+	Remove(ctx context.Context, key string)
+	RenewToken(ctx context.Context) error
+	RememberMe(ctx context.Context, val bool)
+}
+
+// Config configures an Auth
+type Config struct {
+	// Session stores the ID of the logged-in user (required). Typically the application's *scs.SessionManager.
+	Session Session
+	// Users loads the logged-in user for each request (required)
+	Users UserStore
+	// Remember stores remember-me tokens. When nil, remember-me is disabled.
+	Remember RememberStore
+	// SessionKey is the session key holding the user ID. Defaults to "auth.user_id".
+	SessionKey string
+	// ImpersonatorKey is the session key holding the administrator's user ID during impersonation.
+	// Defaults to "auth.impersonator_id".
+	ImpersonatorKey string
+	// CanImpersonate reports whether admin may impersonate target. When nil, impersonation is disabled.
+	CanImpersonate func(ctx context.Context, admin, target User) bool
+	// LoginPath is where RequireAuthenticated redirects anonymous browser requests. Defaults to "/login".
+	LoginPath string
+	// RememberCookie is the name of the remember-me cookie. Defaults to "remember_token".
+	RememberCookie string
+	// RememberFor is how long remember-me tokens are valid. Defaults to 30 days.
+	RememberFor time.Duration
+	// CookieSecure sets the Secure attribute of the remember-me cookie. It should be true in production.
+	CookieSecure bool
+	// Logger is used to log failures. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Auth authenticates users with sessions. It implements hop.TemplateDataModule, so registering it as a
+// module adds IsAuthenticated and CurrentUser to the data of every template.
+type Auth struct {
+	config *Config
+	events *dispatch.Dispatcher
+}
+
+type userContextKey struct{}
+
+// New creates an Auth
+func New(config *Config) *Auth {
+	if config == nil {
+		config = &Config{}
+	}
+
+	if config.SessionKey == "" {
+		config.SessionKey = "auth.user_id"
+	}
+
+	if config.ImpersonatorKey == "" {
+		config.ImpersonatorKey = "auth.impersonator_id"
+	}
+
+	if config.LoginPath == "" {
+		config.LoginPath = "/login"
+	}
+
+	if config.RememberCookie == "" {
+		config.RememberCookie = "remember_token"
+	}
+
+	if config.RememberFor <= 0 {
+		config.RememberFor = 30 * 24 * time.Hour
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	return &Auth{config: config}
+}
+
+// ID returns the module ID
+func (a *Auth) ID() string {
+	return "hop.auth"
+}
+
+// Init validates the configuration
+func (a *Auth) Init() error {
+	if a.config.Session == nil {
+		return errors.New("auth: a session is required")
+	}
+	if a.config.Users == nil {
+		return errors.New("auth: a user store is required")
+	}
+	return nil
+}
+
+// OnTemplateData adds IsAuthenticated and CurrentUser to the template data, along with IsImpersonating
+// and Impersonator for showing an impersonation banner
+func (a *Auth) OnTemplateData(r *http.Request, data *map[string]any) {
+	user := CurrentUser(r)
+	if user == nil {
+		user, _ = a.sessionUser(r)
+	}
+	impersonator := Impersonator(r)
+	if impersonator == nil && user != nil && CurrentUser(r) == nil {
+		impersonator, _ = a.sessionImpersonator(r.Context())
+	}
+	(*data)["IsAuthenticated"] = user != nil
+	(*data)["CurrentUser"] = user
+	(*data)["IsImpersonating"] = impersonator != nil
+	(*data)["Impersonator"] = impersonator
+}
+
+// Login logs the user in. The session token is renewed to prevent session fixation. When remember is
+// true and a RememberStore is configured, a remember-me cookie is also set.
+func (a *Auth) Login(w http.ResponseWriter, r *http.Request, user User, remember bool) error {
+	ctx := r.Context()
+	if err := a.config.Session.RenewToken(ctx); err != nil {
+		return fmt.Errorf("auth: renewing session token: %w", err)
+	}
+	a.config.Session.Remove(ctx, a.config.ImpersonatorKey)
+	a.config.Session.Put(ctx, a.config.SessionKey, user.AuthID())
+
+	if remember && a.config.Remember != nil {
+		a.config.Session.RememberMe(ctx, true)
+		if err := a.issueRememberToken(w, r, user.AuthID()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Logout logs the current user out, renewing the session token and revoking the remember-me token of
+// this browser, if any
+func (a *Auth) Logout(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	a.config.Session.Remove(ctx, a.config.SessionKey)
+	a.config.Session.Remove(ctx, a.config.ImpersonatorKey)
+	if err := a.config.Session.RenewToken(ctx); err != nil {
+		return fmt.Errorf("auth: renewing session token: %w", err)
+	}
+
+	if a.config.Remember != nil {
+		if cookie, err := r.Cookie(a.config.RememberCookie); err == nil {
+			if selector, _, ok := splitRememberToken(cookie.Value); ok {
+				if err := a.config.Remember.Delete(ctx, selector); err != nil {
+					return fmt.Errorf("auth: deleting remember token: %w", err)
+				}
+			}
+		}
+		a.clearRememberCookie(w)
+	}
+
+	return nil
+}
+
+// LogoutEverywhere logs the current user out and revokes all of their remember-me tokens, so other
+// browsers are logged out once their sessions expire
+func (a *Auth) LogoutEverywhere(w http.ResponseWriter, r *http.Request) error {
+	if user := CurrentUser(r); user != nil && a.config.Remember != nil {
+		if err := a.config.Remember.DeleteForUser(r.Context(), user.AuthID()); err != nil {
+			return fmt.Errorf("auth: deleting remember tokens: %w", err)
+		}
+	}
+	return a.Logout(w, r)
+}
+
+// Middleware loads the current user from the session, or from a remember-me cookie when the session has
+// no user, and stores it in the request context for CurrentUser, along with the administrator for
+// Impersonator during impersonation. It must run after the session is loaded, e.g. after scs's LoadAndSave.
+func (a *Auth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := a.sessionUser(r)
+		if err != nil {
+			a.config.Logger.Error("loading session user", slog.String("error", err.Error()))
+		}
+
+		if user == nil && a.config.Remember != nil {
+			if user, err = a.rememberedUser(w, r); err != nil {
+				a.config.Logger.Error("loading remembered user", slog.String("error", err.Error()))
+			}
+		}
+
+		var impersonator User
+		if user != nil {
+			impersonator, err = a.sessionImpersonator(r.Context())
+			switch {
+			case errors.Is(err, errImpersonatorNotFound):
+				user = nil
+			case err != nil:
+				// Without the impersonator, the request could bypass ForbidImpersonation
+				a.config.Logger.Error("loading impersonator", slog.String("error", err.Error()))
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if user != nil {
+			ctx := context.WithValue(r.Context(), userContextKey{}, user)
+			if impersonator != nil {
+				ctx = context.WithValue(ctx, impersonatorContextKey{}, impersonator)
+			}
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAuthenticated rejects anonymous requests. Browser requests are redirected to LoginPath with
+// the original URL in the "next" query parameter; htmx requests receive an HX-Redirect header instead,
+// and other requests receive 401 Unauthorized. It must run after Middleware.
+func (a *Auth) RequireAuthenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsAuthenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		login := a.config.LoginPath + "?next=" + url.QueryEscape(r.URL.RequestURI())
+		switch {
+		case r.Header.Get(htmx.HXRequest) == "true":
+			w.Header().Set(htmx.HXRedirect, login)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			http.Redirect(w, r, login, http.StatusSeeOther)
+		default:
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+	})
+}
+
+// CurrentUser returns the user loaded by Middleware, or nil for anonymous requests
+func CurrentUser(r *http.Request) User {
+	user, _ := r.Context().Value(userContextKey{}).(User)
+	return user
+}
+
+// IsAuthenticated reports whether the request has a logged-in user
+func IsAuthenticated(r *http.Request) bool {
+	return CurrentUser(r) != nil
+}
+
+// sessionUser loads the user whose ID is stored in the session
+func (a *Auth) sessionUser(r *http.Request) (User, error) {
+	id := a.config.Session.GetString(r.Context(), a.config.SessionKey)
+	if id == "" {
+		return nil, nil
+	}
+
+	user, err := a.config.Users.FindUser(r.Context(), id)
+	if errors.Is(err, ErrUserNotFound) {
+		// The user was deleted, so the session no longer identifies anyone
+		a.config.Session.Remove(r.Context(), a.config.SessionKey)
+		a.config.Session.Remove(r.Context(), a.config.ImpersonatorKey)
+		return nil, nil
+	}
+	return user, err
+}
@@ -0,0 +1,267 @@
+package auth_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/auth"
+	"github.com/patrickward/hop/dispatch"
+)
+
+var _ auth.Session = (*scs.SessionManager)(nil)
+
+type testUser struct {
+	id   string
+	name string
+}
+
+func (u *testUser) AuthID() string { return u.id }
+
+// memorySession is a single browser's session
+type memorySession struct {
+	values   map[string]any
+	renewed  int
+	remember bool
+}
+
+func newMemorySession() *memorySession {
+	return &memorySession{values: map[string]any{}}
+}
+
+func (s *memorySession) GetString(_ context.Context, key string) string {
+	v, _ := s.values[key].(string)
+	return v
+}
+func (s *memorySession) Put(_ context.Context, key string, val interface{}) { s.values[key] = val }
+func (s *memorySession) Remove(_ context.Context, key string)               { delete(s.values, key) }
+func (s *memorySession) RenewToken(_ context.Context) error                 { s.renewed++; return nil }
+func (s *memorySession) RememberMe(_ context.Context, val bool)             { s.remember = val }
+
+func newAuth(session auth.Session, remember auth.RememberStore) *auth.Auth {
+	users := auth.UserStoreFunc(func(ctx context.Context, id string) (auth.User, error) {
+		if id == "42" {
+			return &testUser{id: "42", name: "Ada"}, nil
+		}
+		return nil, auth.ErrUserNotFound
+	})
+	return auth.New(&auth.Config{Session: session, Users: users, Remember: remember})
+}
+
+// currentUserHandler writes the name of the current user
+var currentUserHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if u, ok := auth.CurrentUser(r).(*testUser); ok {
+		_, _ = w.Write([]byte(u.name))
+	}
+})
+
+func TestAuth_LoginLogout(t *testing.T) {
+	session := newMemorySession()
+	a := newAuth(session, nil)
+	require.NoError(t, a.Init())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	require.NoError(t, a.Login(rec, req, &testUser{id: "42"}, false))
+	assert.Equal(t, 1, session.renewed)
+	assert.Empty(t, rec.Result().Cookies(), "remember-me is disabled without a store")
+
+	rec = httptest.NewRecorder()
+	a.Middleware(currentUserHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "Ada", rec.Body.String())
+
+	data := map[string]any{}
+	a.OnTemplateData(httptest.NewRequest(http.MethodGet, "/", nil), &data)
+	assert.Equal(t, true, data["IsAuthenticated"])
+	assert.Equal(t, "Ada", data["CurrentUser"].(*testUser).name)
+
+	require.NoError(t, a.Logout(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/logout", nil)))
+	assert.Equal(t, 2, session.renewed)
+
+	rec = httptest.NewRecorder()
+	a.Middleware(currentUserHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, rec.Body.String())
+
+	data = map[string]any{}
+	a.OnTemplateData(httptest.NewRequest(http.MethodGet, "/", nil), &data)
+	assert.Equal(t, false, data["IsAuthenticated"])
+}
+
+func TestAuth_RememberMe(t *testing.T) {
+	store := auth.NewMemoryRememberStore()
+
+	rec := httptest.NewRecorder()
+	first := newMemorySession()
+	require.NoError(t, newAuth(first, store).Login(rec, httptest.NewRequest(http.MethodPost, "/login", nil), &testUser{id: "42"}, true))
+	assert.True(t, first.remember)
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	original := cookies[0]
+	assert.Equal(t, "remember_token", original.Name)
+	assert.True(t, original.HttpOnly)
+
+	// A new browser session with the cookie is logged back in and receives a rotated token
+	second := newMemorySession()
+	a := newAuth(second, store)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(original)
+	rec = httptest.NewRecorder()
+	a.Middleware(currentUserHandler).ServeHTTP(rec, req)
+
+	assert.Equal(t, "Ada", rec.Body.String())
+	assert.Equal(t, "42", second.GetString(context.Background(), "auth.user_id"))
+	rotated := rec.Result().Cookies()
+	require.Len(t, rotated, 1)
+	assert.NotEqual(t, original.Value, rotated[0].Value)
+
+	// The original token was used, so it no longer works
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(original)
+	rec = httptest.NewRecorder()
+	newAuth(newMemorySession(), store).Middleware(currentUserHandler).ServeHTTP(rec, req)
+	assert.Empty(t, rec.Body.String())
+
+	// Logging out revokes the rotated token
+	req = httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(rotated[0])
+	require.NoError(t, a.Logout(httptest.NewRecorder(), req))
+
+	selector, _, _ := strings.Cut(rotated[0].Value, ":")
+	_, err := store.Find(context.Background(), selector)
+	assert.ErrorIs(t, err, auth.ErrTokenNotFound)
+}
+
+func TestAuth_RequireAuthenticated(t *testing.T) {
+	a := newAuth(newMemorySession(), nil)
+	handler := a.Middleware(a.RequireAuthenticated(currentUserHandler))
+
+	tests := []struct {
+		name           string
+		method         string
+		headers        map[string]string
+		expectStatus   int
+		expectLocation string
+		expectRedirect string
+	}{
+		{name: "browser", method: http.MethodGet, expectStatus: http.StatusSeeOther, expectLocation: "/login?next=%2Faccount%3Ftab%3D1"},
+		{name: "htmx", method: http.MethodGet, headers: map[string]string{"HX-Request": "true"}, expectStatus: http.StatusUnauthorized, expectRedirect: "/login?next=%2Faccount%3Ftab%3D1"},
+		{name: "post", method: http.MethodPost, expectStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/account?tab=1", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+			assert.Equal(t, tt.expectLocation, rec.Header().Get("Location"))
+			assert.Equal(t, tt.expectRedirect, rec.Header().Get("HX-Redirect"))
+		})
+	}
+
+	t.Run("authenticated", func(t *testing.T) {
+		session := newMemorySession()
+		session.Put(context.Background(), "auth.user_id", "42")
+		a := newAuth(session, nil)
+
+		rec := httptest.NewRecorder()
+		a.Middleware(a.RequireAuthenticated(currentUserHandler)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Ada", rec.Body.String())
+	})
+}
+
+func TestAuth_Impersonation(t *testing.T) {
+	users := map[string]*testUser{
+		"1":  {id: "1", name: "Admin"},
+		"42": {id: "42", name: "Ada"},
+	}
+	session := newMemorySession()
+	session.Put(context.Background(), "auth.user_id", "1")
+
+	a := auth.New(&auth.Config{
+		Session: session,
+		Users: auth.UserStoreFunc(func(ctx context.Context, id string) (auth.User, error) {
+			if u, ok := users[id]; ok {
+				return u, nil
+			}
+			return nil, auth.ErrUserNotFound
+		}),
+		CanImpersonate: func(ctx context.Context, admin, target auth.User) bool {
+			return admin.AuthID() == "1"
+		},
+	})
+
+	events := dispatch.NewDispatcher(slog.Default())
+	started := make(chan auth.ImpersonationEvent, 1)
+	events.On(auth.EventImpersonationStarted, dispatch.HandlePayload(func(ctx context.Context, e auth.ImpersonationEvent) {
+		started <- e
+	}))
+	a.RegisterEvents(events)
+
+	// serve runs handler behind Middleware and returns the response
+	serve := func(handler http.Handler) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		a.Middleware(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+		return rec
+	}
+
+	serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.ErrorIs(t, a.StopImpersonating(w, r), auth.ErrNotImpersonating)
+		assert.ErrorIs(t, a.StartImpersonating(w, r, users["1"]), auth.ErrImpersonationDenied)
+		require.NoError(t, a.StartImpersonating(w, r, users["42"]))
+	}))
+	assert.Equal(t, 1, session.renewed)
+
+	select {
+	case e := <-started:
+		assert.Equal(t, "1", e.ImpersonatorID)
+		assert.Equal(t, "42", e.UserID)
+	case <-time.After(time.Second):
+		t.Fatal("impersonation started event was not emitted")
+	}
+
+	// The impersonated user is current, the administrator is retained, and protected routes are blocked
+	serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Ada", auth.CurrentUser(r).(*testUser).name)
+		assert.Equal(t, "Admin", auth.Impersonator(r).(*testUser).name)
+
+		data := map[string]any{}
+		a.OnTemplateData(r, &data)
+		assert.Equal(t, true, data["IsImpersonating"])
+		assert.Equal(t, "Admin", data["Impersonator"].(*testUser).name)
+
+		assert.ErrorIs(t, a.StartImpersonating(w, r, users["1"]), auth.ErrAlreadyImpersonating)
+	}))
+	rec := serve(a.ForbidImpersonation(currentUserHandler))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Stopping restores the administrator
+	serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, a.StopImpersonating(w, r))
+	}))
+	rec = serve(a.ForbidImpersonation(currentUserHandler))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Admin", rec.Body.String())
+
+	// A deleted administrator ends the impersonated session instead of lifting its restrictions
+	serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, a.StartImpersonating(w, r, users["42"]))
+	}))
+	delete(users, "1")
+	rec = serve(currentUserHandler)
+	assert.Empty(t, rec.Body.String())
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/route/middleware"
+)
+
+// Signatures of the audit events emitted when impersonation starts and stops. The payload is an
+// ImpersonationEvent.
+const (
+	EventImpersonationStarted = "auth.impersonation_started"
+	EventImpersonationStopped = "auth.impersonation_stopped"
+)
+
+var (
+	// ErrNotAuthenticated is returned when an action requires a logged-in user
+	ErrNotAuthenticated = errors.New("auth: not authenticated")
+	// ErrImpersonationDenied is returned when the current user may not impersonate the target user
+	ErrImpersonationDenied = errors.New("auth: impersonation denied")
+	// ErrAlreadyImpersonating is returned when starting an impersonation while one is in progress
+	ErrAlreadyImpersonating = errors.New("auth: already impersonating")
+	// ErrNotImpersonating is returned when stopping an impersonation that was not started
+	ErrNotImpersonating = errors.New("auth: not impersonating")
+)
+
+// ImpersonationEvent is the payload of the impersonation audit events
+type ImpersonationEvent struct {
+	// ImpersonatorID is the ID of the administrator
+	ImpersonatorID string
+	// UserID is the ID of the impersonated user
+	UserID string
+	// ClientIP is the address of the administrator
+	ClientIP string
+	// At is when the impersonation started or stopped
+	At time.Time
+}
+
+type impersonatorContextKey struct{}
+
+// RegisterEvents captures the dispatcher that impersonation audit events are emitted on
+func (a *Auth) RegisterEvents(events *dispatch.Dispatcher) {
+	a.events = events
+}
+
+// StartImpersonating logs the current user in as target, keeping the current user's ID in the session so
+// that StopImpersonating can restore it. Config.CanImpersonate must allow it; impersonations cannot be
+// nested. The session token is renewed and EventImpersonationStarted is emitted.
+func (a *Auth) StartImpersonating(w http.ResponseWriter, r *http.Request, target User) error {
+	ctx := r.Context()
+	admin := CurrentUser(r)
+	if admin == nil {
+		return ErrNotAuthenticated
+	}
+	if IsImpersonating(r) || a.config.Session.GetString(ctx, a.config.ImpersonatorKey) != "" {
+		return ErrAlreadyImpersonating
+	}
+	if a.config.CanImpersonate == nil || admin.AuthID() == target.AuthID() || !a.config.CanImpersonate(ctx, admin, target) {
+		return ErrImpersonationDenied
+	}
+
+	if err := a.config.Session.RenewToken(ctx); err != nil {
+		return fmt.Errorf("auth: renewing session token: %w", err)
+	}
+	a.config.Session.Put(ctx, a.config.ImpersonatorKey, admin.AuthID())
+	a.config.Session.Put(ctx, a.config.SessionKey, target.AuthID())
+
+	a.emitImpersonation(r, EventImpersonationStarted, admin.AuthID(), target.AuthID())
+	return nil
+}
+
+// StopImpersonating ends the impersonation and logs the administrator back in. The session token is
+// renewed and EventImpersonationStopped is emitted.
+func (a *Auth) StopImpersonating(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	adminID := a.config.Session.GetString(ctx, a.config.ImpersonatorKey)
+	if adminID == "" {
+		return ErrNotImpersonating
+	}
+	userID := a.config.Session.GetString(ctx, a.config.SessionKey)
+
+	if err := a.config.Session.RenewToken(ctx); err != nil {
+		return fmt.Errorf("auth: renewing session token: %w", err)
+	}
+	a.config.Session.Remove(ctx, a.config.ImpersonatorKey)
+	a.config.Session.Put(ctx, a.config.SessionKey, adminID)
+
+	a.emitImpersonation(r, EventImpersonationStopped, adminID, userID)
+	return nil
+}
+
+// ForbidImpersonation rejects requests made while impersonating with 403 Forbidden. Use it on routes an
+// administrator must not reach as another user, such as changing passwords or email addresses, managing
+// API keys, or administration pages that would otherwise be authorized by the impersonated user's role.
+// It must run after Middleware.
+func (a *Auth) ForbidImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsImpersonating(r) {
+			a.config.Logger.WarnContext(r.Context(), "request blocked during impersonation",
+				slog.String("impersonator_id", Impersonator(r).AuthID()),
+				slog.String("user_id", CurrentUser(r).AuthID()),
+				slog.String("path", r.URL.Path))
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Impersonator returns the administrator impersonating the current user, or nil when the request is not
+// impersonated
+func Impersonator(r *http.Request) User {
+	user, _ := r.Context().Value(impersonatorContextKey{}).(User)
+	return user
+}
+
+// IsImpersonating reports whether the current user is being impersonated
+func IsImpersonating(r *http.Request) bool {
+	return Impersonator(r) != nil
+}
+
+// errImpersonatorNotFound is returned by sessionImpersonator when the administrator no longer exists
+var errImpersonatorNotFound = errors.New("auth: impersonator not found")
+
+// sessionImpersonator loads the administrator whose ID is stored in the session during impersonation.
+// When the administrator was deleted, the session is logged out entirely rather than leaving the
+// impersonated user logged in without the impersonation restrictions.
+func (a *Auth) sessionImpersonator(ctx context.Context) (User, error) {
+	id := a.config.Session.GetString(ctx, a.config.ImpersonatorKey)
+	if id == "" {
+		return nil, nil
+	}
+
+	user, err := a.config.Users.FindUser(ctx, id)
+	if errors.Is(err, ErrUserNotFound) {
+		a.config.Session.Remove(ctx, a.config.ImpersonatorKey)
+		a.config.Session.Remove(ctx, a.config.SessionKey)
+		return nil, errImpersonatorNotFound
+	}
+	return user, err
+}
+
+// emitImpersonation emits an impersonation audit event, if a dispatcher is registered
+func (a *Auth) emitImpersonation(r *http.Request, signature, adminID, userID string) {
+	a.config.Logger.InfoContext(r.Context(), signature,
+		slog.String("impersonator_id", adminID),
+		slog.String("user_id", userID))
+
+	if a.events == nil {
+		return
+	}
+
+	event := ImpersonationEvent{
+		ImpersonatorID: adminID,
+		UserID:         userID,
+		ClientIP:       middleware.ClientIP(r),
+		At:             time.Now(),
+	}
+	// Handlers run after the response is written, so they must not see the request's cancellation
+	if err := a.events.Emit(context.WithoutCancel(r.Context()), signature, event); err != nil {
+		a.config.Logger.Error("emitting impersonation event", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTokenNotFound is returned by a RememberStore when no token has the selector
+var ErrTokenNotFound = errors.New("auth: remember token not found")
+
+// RememberToken is a stored remember-me token. The cookie holds the selector and the validator; only a
+// hash of the validator is stored, so a leaked store cannot be used to log in.
+type RememberToken struct {
+	Selector      string
+	ValidatorHash []byte
+	UserID        string
+	ExpiresAt     time.Time
+}
+
+// RememberStore persists remember-me tokens
+type RememberStore interface {
+	// Create stores a new token
+	Create(ctx context.Context, token RememberToken) error
+	// Find returns the token with the selector, or an error wrapping ErrTokenNotFound
+	Find(ctx context.Context, selector string) (RememberToken, error)
+	// Delete removes the token with the selector, if it exists
+	Delete(ctx context.Context, selector string) error
+	// DeleteForUser removes every token of a user
+	DeleteForUser(ctx context.Context, userID string) error
+}
+
+// MemoryRememberStore keeps remember-me tokens in memory. It is intended for development and tests, as
+// tokens are lost when the process restarts.
+type MemoryRememberStore struct {
+	mu     sync.Mutex
+	tokens map[string]RememberToken
+}
+
+// NewMemoryRememberStore creates an empty in-memory store
+func NewMemoryRememberStore() *MemoryRememberStore {
+	return &MemoryRememberStore{tokens: make(map[string]RememberToken)}
+}
+
+// Create stores a new token
+func (s *MemoryRememberStore) Create(_ context.Context, token RememberToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token.Selector] = token
+	return nil
+}
+
+// Find returns the token with the selector
+func (s *MemoryRememberStore) Find(_ context.Context, selector string) (RememberToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[selector]
+	if !ok {
+		return RememberToken{}, ErrTokenNotFound
+	}
+	return token, nil
+}
+
+// Delete removes the token with the selector
+func (s *MemoryRememberStore) Delete(_ context.Context, selector string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, selector)
+	return nil
+}
+
+// DeleteForUser removes every token of a user
+func (s *MemoryRememberStore) DeleteForUser(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for selector, token := range s.tokens {
+		if token.UserID == userID {
+			delete(s.tokens, selector)
+		}
+	}
+	return nil
+}
+
+// issueRememberToken stores a new token for the user and sets the remember-me cookie
+func (a *Auth) issueRememberToken(w http.ResponseWriter, r *http.Request, userID string) error {
+	selector, err := randomToken(12)
+	if err != nil {
+		return err
+	}
+	validator, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256([]byte(validator))
+	expires := time.Now().Add(a.config.RememberFor)
+	err = a.config.Remember.Create(r.Context(), RememberToken{
+		Selector:      selector,
+		ValidatorHash: hash[:],
+		UserID:        userID,
+		ExpiresAt:     expires,
+	})
+	if err != nil {
+		return fmt.Errorf("auth: storing remember token: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.config.RememberCookie,
+		Value:    selector + ":" + validator,
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(a.config.RememberFor.Seconds()),
+		Secure:   a.config.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// rememberedUser logs in the user identified by a valid remember-me cookie. The token is single use: it
+// is replaced by a new one, so a stolen cookie stops working once the owner's browser uses it.
+func (a *Auth) rememberedUser(w http.ResponseWriter, r *http.Request) (User, error) {
+	cookie, err := r.Cookie(a.config.RememberCookie)
+	if err != nil {
+		return nil, nil
+	}
+
+	selector, validator, ok := splitRememberToken(cookie.Value)
+	if !ok {
+		a.clearRememberCookie(w)
+		return nil, nil
+	}
+
+	ctx := r.Context()
+	token, err := a.config.Remember.Find(ctx, selector)
+	if errors.Is(err, ErrTokenNotFound) {
+		a.clearRememberCookie(w)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256([]byte(validator))
+	if subtle.ConstantTimeCompare(hash[:], token.ValidatorHash) != 1 || time.Now().After(token.ExpiresAt) {
+		// A wrong validator for a known selector suggests the token was stolen and already used
+		if err := a.config.Remember.Delete(ctx, selector); err != nil {
+			return nil, err
+		}
+		a.clearRememberCookie(w)
+		return nil, nil
+	}
+
+	user, err := a.config.Users.FindUser(ctx, token.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		_ = a.config.Remember.DeleteForUser(ctx, token.UserID)
+		a.clearRememberCookie(w)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.config.Remember.Delete(ctx, selector); err != nil {
+		return nil, err
+	}
+	if err := a.Login(w, r, user, true); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// clearRememberCookie expires the remember-me cookie
+func (a *Auth) clearRememberCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.config.RememberCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   a.config.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// splitRememberToken splits a cookie value into its selector and validator
+func splitRememberToken(value string) (string, string, bool) {
+	selector, validator, ok := strings.Cut(value, ":")
+	return selector, validator, ok && selector != "" && validator != ""
+}
+
+// randomToken returns n random bytes, base64url encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("auth: generating token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
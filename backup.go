@@ -0,0 +1,316 @@
+package hop
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// backupManifestName is the name of the manifest entry in a backup archive
+	backupManifestName = "manifest.json"
+	// backupModulePrefix is the directory that holds module entries in a backup archive
+	backupModulePrefix = "modules/"
+)
+
+// BackupManifest describes the contents of a backup archive
+type BackupManifest struct {
+	// CreatedAt is when the backup was created
+	CreatedAt time.Time `json:"created_at"`
+	// Modules lists the IDs of the modules included in the backup
+	Modules []string `json:"modules"`
+}
+
+// Backup writes a gzipped tar archive containing the data of every registered BackupModule to w.
+// The archive holds a manifest.json entry followed by one modules/<id> entry per module.
+// Each module is written to a temporary file first, so large backups are not held in memory.
+func (a *App) Backup(ctx context.Context, w io.Writer) error {
+	modules := a.backupModules()
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := BackupManifest{CreatedAt: time.Now().UTC()}
+	for _, m := range modules {
+		manifest.Modules = append(manifest.Modules, m.ID())
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding backup manifest: %w", err)
+	}
+
+	if err := writeTarEntry(tw, backupManifestName, manifest.CreatedAt, int64(len(data)), strings.NewReader(string(data))); err != nil {
+		return err
+	}
+
+	for _, m := range modules {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		a.logger.Info("backing up module", slog.String("module", m.ID()))
+		if err := a.backupModule(ctx, tw, m, manifest.CreatedAt); err != nil {
+			return fmt.Errorf("backing up module %s: %w", m.ID(), err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("closing backup archive: %w", err)
+	}
+
+	return gz.Close()
+}
+
+// BackupToDir writes a timestamped backup archive to dir and returns its path. This is useful for
+// scheduled backups.
+func (a *App) BackupToDir(ctx context.Context, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("creating backup directory: %w", err)
+	}
+
+	path := filepath.Join(dir, backupFilename(time.Now()))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("creating backup file: %w", err)
+	}
+
+	if err := a.Backup(ctx, f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("closing backup file: %w", err)
+	}
+
+	return path, nil
+}
+
+// Restore reads a backup archive created by Backup and restores the data of each included module.
+// If ids are provided, only those modules are restored. The manifest, which Backup writes as the first
+// entry, is read and checked before any module is restored: an archive without one, or one listing modules
+// that are not registered or no longer implement BackupModule, is rejected without changing anything.
+func (a *App) Restore(ctx context.Context, r io.Reader, ids ...string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("reading backup archive: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	tr := tar.NewReader(gz)
+	manifest, err := readBackupManifest(tr)
+	if err != nil {
+		return err
+	}
+
+	modules := make(map[string]BackupModule)
+	for _, m := range a.backupModules() {
+		modules[m.ID()] = m
+	}
+
+	only := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		only[id] = true
+	}
+
+	listed := make(map[string]bool, len(manifest.Modules))
+	for _, id := range manifest.Modules {
+		listed[id] = true
+		if len(only) > 0 && !only[id] {
+			continue
+		}
+		if _, ok := modules[id]; !ok {
+			return fmt.Errorf("backup contains data for unknown module: %s", id)
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading backup archive: %w", err)
+		}
+
+		id, ok := strings.CutPrefix(hdr.Name, backupModulePrefix)
+		if !ok || id == "" {
+			continue
+		}
+
+		if !listed[id] {
+			return fmt.Errorf("backup contains data for module missing from its manifest: %s", id)
+		}
+
+		if len(only) > 0 && !only[id] {
+			continue
+		}
+
+		a.logger.Info("restoring module", slog.String("module", id))
+		if err := modules[id].Restore(ctx, tr); err != nil {
+			return fmt.Errorf("restoring module %s: %w", id, err)
+		}
+	}
+}
+
+// BackupJob returns a function that writes a backup archive to dir with BackupToDir, for running backups
+// on a schedule. Its signature matches scheduler.Job:
+//
+//	_ = s.AddCron("backup", "0 3 * * *", app.BackupJob("/var/backups/myapp"), scheduler.Timeout(time.Hour))
+func (a *App) BackupJob(dir string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		path, err := a.BackupToDir(ctx, dir)
+		if err != nil {
+			return err
+		}
+
+		a.logger.Info("backup written", slog.String("path", path))
+		return nil
+	}
+}
+
+// BackupHandler returns an http.Handler that streams a backup archive as a download.
+// It should be mounted behind authentication that restricts access to administrators.
+func (a *App) BackupHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", backupFilename(time.Now())))
+
+		if err := a.Backup(r.Context(), w); err != nil {
+			// Headers and part of the archive may already have been sent, so the error can only be logged
+			a.logger.Error("backup failed", slog.String("error", err.Error()))
+		}
+	})
+}
+
+// RestoreHandler returns an http.Handler that restores a backup archive posted in the request body,
+// either directly or as a multipart file field named "backup". It should be mounted behind
+// authentication that restricts access to administrators.
+func (a *App) RestoreHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body io.Reader = r.Body
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, _, err := r.FormFile("backup")
+			if err != nil {
+				http.Error(w, "missing backup file", http.StatusBadRequest)
+				return
+			}
+			defer func() { _ = file.Close() }()
+			body = file
+		}
+
+		if err := a.Restore(r.Context(), body); err != nil {
+			a.logger.Error("restore failed", slog.String("error", err.Error()))
+			http.Error(w, "restore failed", http.StatusUnprocessableEntity)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// backupModules returns the registered modules that implement BackupModule, in start order
+func (a *App) backupModules() []BackupModule {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var modules []BackupModule
+	for _, id := range a.startOrder {
+		if bm, ok := a.modules[id].(BackupModule); ok {
+			modules = append(modules, bm)
+		}
+	}
+	return modules
+}
+
+// backupModule spools a module backup to a temporary file and copies it into the archive
+func (a *App) backupModule(ctx context.Context, tw *tar.Writer, m BackupModule, modTime time.Time) error {
+	tmp, err := os.CreateTemp("", "hop-backup-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if err := m.Backup(ctx, tmp); err != nil {
+		return err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return writeTarEntry(tw, backupModulePrefix+m.ID(), modTime, size, tmp)
+}
+
+// readBackupManifest reads and decodes the manifest, which must be the first entry of the archive
+func readBackupManifest(tr *tar.Reader) (BackupManifest, error) {
+	var manifest BackupManifest
+
+	hdr, err := tr.Next()
+	if errors.Is(err, io.EOF) || err == nil && hdr.Name != backupManifestName {
+		return manifest, errors.New("backup archive is missing its manifest")
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("reading backup archive: %w", err)
+	}
+
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("decoding backup manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// writeTarEntry writes a single file entry to the archive
+func writeTarEntry(tw *tar.Writer, name string, modTime time.Time, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    size,
+		ModTime: modTime,
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing archive header for %s: %w", name, err)
+	}
+
+	if _, err := io.CopyN(tw, r, size); err != nil {
+		return fmt.Errorf("writing archive entry %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// backupFilename returns the file name for a backup created at t
+func backupFilename(t time.Time) string {
+	return "backup-" + t.UTC().Format("20060102-150405") + ".tar.gz"
+}
@@ -0,0 +1,163 @@
+package hop
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SQLiteBackup is a BackupModule holding the tables of a SQLite database, such as the database of the
+// kv, lease, jobs or session stores. Register one per database:
+//
+//	app.RegisterModule(hop.NewSQLiteBackup("app.db", db))
+//
+// The backup is a consistent snapshot made with VACUUM INTO, which needs SQLite 3.27 or later. Restore
+// replaces the rows of every table in the snapshot within one transaction; the schema is left as is, so
+// the database must have been migrated to the same version as the one that was backed up.
+type SQLiteBackup struct {
+	id string
+	db *sql.DB
+}
+
+var _ BackupModule = (*SQLiteBackup)(nil)
+
+// NewSQLiteBackup creates a SQLiteBackup for db. The id names the module and its entry in backup archives,
+// so it must be unique among the registered modules.
+func NewSQLiteBackup(id string, db *sql.DB) *SQLiteBackup {
+	return &SQLiteBackup{id: id, db: db}
+}
+
+// ID implements Module
+func (s *SQLiteBackup) ID() string {
+	return s.id
+}
+
+// Init implements Module
+func (s *SQLiteBackup) Init() error {
+	return nil
+}
+
+// Start implements Module
+func (s *SQLiteBackup) Start(context.Context) error {
+	return nil
+}
+
+// Stop implements Module
+func (s *SQLiteBackup) Stop(context.Context) error {
+	return nil
+}
+
+// Backup writes a snapshot of the database to w
+func (s *SQLiteBackup) Backup(ctx context.Context, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "hop-sqlite-backup-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "snapshot.db")
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Restore replaces the rows of the database's tables with those of the snapshot read from r
+func (s *SQLiteBackup) Restore(ctx context.Context, r io.Reader) error {
+	dir, err := os.MkdirTemp("", "hop-sqlite-restore-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "snapshot.db")
+	if err := writeFile(path, r); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	// ATTACH applies to a single connection, and can't run within a transaction
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS snapshot", path); err != nil {
+		return fmt.Errorf("attaching snapshot: %w", err)
+	}
+	defer func() { _, _ = conn.ExecContext(context.WithoutCancel(ctx), "DETACH DATABASE snapshot") }()
+
+	tables, err := snapshotTables(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, table := range tables {
+		name := quoteIdentifier(table)
+		if _, err := tx.ExecContext(ctx, "DELETE FROM main."+name); err != nil {
+			return fmt.Errorf("clearing table %s: %w", table, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO main."+name+" SELECT * FROM snapshot."+name); err != nil {
+			return fmt.Errorf("restoring table %s: %w", table, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// snapshotTables returns the names of the tables in the attached snapshot, leaving out SQLite's own
+func snapshotTables(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	rows, err := conn.QueryContext(ctx,
+		"SELECT name FROM snapshot.sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshot tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// quoteIdentifier quotes a SQLite identifier
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// writeFile writes the content of r to a new file at path
+func writeFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
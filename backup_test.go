@@ -0,0 +1,266 @@
+package hop_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3" // Import the SQLite driver
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop"
+)
+
+type mockBackupModule struct {
+	mockModule
+	data      string
+	restored  string
+	backupErr error
+}
+
+func (m *mockBackupModule) Backup(_ context.Context, w io.Writer) error {
+	if m.backupErr != nil {
+		return m.backupErr
+	}
+	_, err := io.WriteString(w, m.data)
+	return err
+}
+
+func (m *mockBackupModule) Restore(_ context.Context, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	m.restored = string(b)
+	return err
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	sessions := &mockBackupModule{mockModule: mockModule{id: "sessions"}, data: "session-data"}
+	uploads := &mockBackupModule{mockModule: mockModule{id: "uploads"}, data: "upload-data"}
+	app.RegisterModule(sessions).
+		RegisterModule(&mockModule{id: "plain"}).
+		RegisterModule(uploads)
+	require.NoError(t, app.Error())
+
+	var buf bytes.Buffer
+	require.NoError(t, app.Backup(context.Background(), &buf))
+
+	require.NoError(t, app.Restore(context.Background(), bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, "session-data", sessions.restored)
+	assert.Equal(t, "upload-data", uploads.restored)
+
+	// Restore only selected modules
+	sessions.restored, uploads.restored = "", ""
+	require.NoError(t, app.Restore(context.Background(), bytes.NewReader(buf.Bytes()), "uploads"))
+	assert.Empty(t, sessions.restored)
+	assert.Equal(t, "upload-data", uploads.restored)
+}
+
+func TestBackupModuleError(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	app.RegisterModule(&mockBackupModule{mockModule: mockModule{id: "broken"}, backupErr: errors.New("disk full")})
+	require.NoError(t, app.Error())
+
+	err = app.Backup(context.Background(), io.Discard)
+	assert.ErrorContains(t, err, "disk full")
+}
+
+func TestRestoreUnknownModule(t *testing.T) {
+	source, err := createTestApp(t)
+	require.NoError(t, err)
+	source.RegisterModule(&mockBackupModule{mockModule: mockModule{id: "sessions"}, data: "x"})
+
+	var buf bytes.Buffer
+	require.NoError(t, source.Backup(context.Background(), &buf))
+
+	target, err := createTestApp(t)
+	require.NoError(t, err)
+
+	err = target.Restore(context.Background(), &buf)
+	assert.ErrorContains(t, err, "unknown module: sessions")
+}
+
+func TestRestoreChecksManifestFirst(t *testing.T) {
+	archive := func(entries ...string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for i := 0; i < len(entries); i += 2 {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: entries[i], Mode: 0o600, Size: int64(len(entries[i+1]))}))
+			_, err := tw.Write([]byte(entries[i+1]))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		require.NoError(t, gz.Close())
+		return &buf
+	}
+
+	tests := []struct {
+		name    string
+		archive *bytes.Buffer
+		wantErr string
+	}{
+		{
+			name:    "missing manifest",
+			archive: archive("modules/sessions", "new-data"),
+			wantErr: "missing its manifest",
+		},
+		{
+			name:    "manifest after the modules",
+			archive: archive("modules/sessions", "new-data", "manifest.json", `{"modules":["sessions"]}`),
+			wantErr: "missing its manifest",
+		},
+		{
+			name:    "unknown module listed",
+			archive: archive("manifest.json", `{"modules":["sessions","uploads"]}`, "modules/sessions", "new-data"),
+			wantErr: "unknown module: uploads",
+		},
+		{
+			name:    "module missing from the manifest",
+			archive: archive("manifest.json", `{"modules":[]}`, "modules/sessions", "new-data"),
+			wantErr: "missing from its manifest: sessions",
+		},
+		{
+			name:    "malformed manifest",
+			archive: archive("manifest.json", `{"modules":`, "modules/sessions", "new-data"),
+			wantErr: "decoding backup manifest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, err := createTestApp(t)
+			require.NoError(t, err)
+
+			sessions := &mockBackupModule{mockModule: mockModule{id: "sessions"}}
+			app.RegisterModule(sessions)
+
+			err = app.Restore(context.Background(), tt.archive)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Empty(t, sessions.restored, "no module should be restored from a rejected archive")
+		})
+	}
+}
+
+func TestBackupJob(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	mod := &mockBackupModule{mockModule: mockModule{id: "sessions"}, data: "session-data"}
+	app.RegisterModule(mod)
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	job := app.BackupJob(dir)
+	require.NoError(t, job(context.Background()))
+
+	paths, err := filepath.Glob(filepath.Join(dir, "backup-*.tar.gz"))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+
+	f, err := os.Open(paths[0])
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, app.Restore(context.Background(), f))
+	assert.Equal(t, "session-data", mod.restored)
+}
+
+func TestBackupToDir(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	mod := &mockBackupModule{mockModule: mockModule{id: "sessions"}, data: "session-data"}
+	app.RegisterModule(mod)
+
+	path, err := app.BackupToDir(context.Background(), t.TempDir())
+	require.NoError(t, err)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, app.Restore(context.Background(), f))
+	assert.Equal(t, "session-data", mod.restored)
+}
+
+func TestBackupAndRestoreHandlers(t *testing.T) {
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+
+	mod := &mockBackupModule{mockModule: mockModule{id: "sessions"}, data: "session-data"}
+	app.RegisterModule(mod)
+
+	w := httptest.NewRecorder()
+	app.BackupHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/backup", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	w2 := httptest.NewRecorder()
+	app.RestoreHandler().ServeHTTP(w2, httptest.NewRequest(http.MethodPost, "/admin/restore", bytes.NewReader(w.Body.Bytes())))
+	assert.Equal(t, http.StatusNoContent, w2.Code)
+	assert.Equal(t, "session-data", mod.restored)
+
+	w3 := httptest.NewRecorder()
+	app.RestoreHandler().ServeHTTP(w3, httptest.NewRequest(http.MethodPost, "/admin/restore", bytes.NewReader([]byte("not a backup"))))
+	assert.Equal(t, http.StatusUnprocessableEntity, w3.Code)
+}
+
+var _ hop.BackupModule = (*mockBackupModule)(nil)
+
+func TestSQLiteBackup(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL);
+		CREATE TABLE "user settings" (name TEXT PRIMARY KEY, value TEXT);
+		INSERT INTO notes (body) VALUES ('first'), ('second');
+		INSERT INTO "user settings" VALUES ('theme', 'dark')`)
+	require.NoError(t, err)
+
+	app, err := createTestApp(t)
+	require.NoError(t, err)
+	app.RegisterModule(hop.NewSQLiteBackup("app.db", db))
+	require.NoError(t, app.Error())
+
+	var buf bytes.Buffer
+	require.NoError(t, app.Backup(ctx, &buf))
+
+	_, err = db.Exec(`DELETE FROM notes WHERE body = 'first';
+		INSERT INTO notes (body) VALUES ('third');
+		UPDATE "user settings" SET value = 'light'`)
+	require.NoError(t, err)
+
+	require.NoError(t, app.Restore(ctx, &buf))
+
+	var bodies []string
+	rows, err := db.Query("SELECT body FROM notes ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var body string
+		require.NoError(t, rows.Scan(&body))
+		bodies = append(bodies, body)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"first", "second"}, bodies)
+
+	var theme string
+	require.NoError(t, db.QueryRow(`SELECT value FROM "user settings" WHERE name = 'theme'`).Scan(&theme))
+	assert.Equal(t, "dark", theme)
+}
@@ -0,0 +1,201 @@
+package check
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Address is a postal address as typically collected from a form
+type Address struct {
+	Line1      string `json:"line1" form:"line1"`
+	Line2      string `json:"line2,omitempty" form:"line2"`
+	City       string `json:"city" form:"city"`
+	Region     string `json:"region,omitempty" form:"region"`
+	PostalCode string `json:"postal_code,omitempty" form:"postal_code"`
+	Country    string `json:"country" form:"country"`
+}
+
+// Address field names used for required fields and field errors
+const (
+	AddressLine1      = "line1"
+	AddressLine2      = "line2"
+	AddressCity       = "city"
+	AddressRegion     = "region"
+	AddressPostalCode = "postal_code"
+	AddressCountry    = "country"
+)
+
+// AddressFormat describes the country-specific rules for an address
+type AddressFormat struct {
+	// Required lists the address fields that must be present
+	Required []string
+	// PostalCode is the pattern postal codes must match. Empty means postal codes are not checked.
+	PostalCode string
+	// Lines is the display layout. Each entry is a line made of field names in braces, e.g. "{city} {region}".
+	Lines []string
+
+	postalRegex *regexp.Regexp
+}
+
+var (
+	addressFormatsMu sync.RWMutex
+
+	defaultAddressFormat = newAddressFormat(AddressFormat{
+		Required: []string{AddressLine1, AddressCity, AddressCountry},
+		Lines:    []string{"{line1}", "{line2}", "{postal_code} {city}", "{region}", "{country}"},
+	})
+
+	addressFormats = map[string]*AddressFormat{
+		"US": newAddressFormat(AddressFormat{
+			Required:   []string{AddressLine1, AddressCity, AddressRegion, AddressPostalCode},
+			PostalCode: `^\d{5}(-\d{4})?$`,
+			Lines:      []string{"{line1}", "{line2}", "{city}, {region} {postal_code}", "{country}"},
+		}),
+		"CA": newAddressFormat(AddressFormat{
+			Required:   []string{AddressLine1, AddressCity, AddressRegion, AddressPostalCode},
+			PostalCode: `^(?i)[A-Z]\d[A-Z] ?\d[A-Z]\d$`,
+			Lines:      []string{"{line1}", "{line2}", "{city} {region} {postal_code}", "{country}"},
+		}),
+		"GB": newAddressFormat(AddressFormat{
+			Required:   []string{AddressLine1, AddressCity, AddressPostalCode},
+			PostalCode: `^(?i)[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`,
+			Lines:      []string{"{line1}", "{line2}", "{city}", "{postal_code}", "{country}"},
+		}),
+		"DE": newAddressFormat(AddressFormat{
+			Required:   []string{AddressLine1, AddressCity, AddressPostalCode},
+			PostalCode: `^\d{5}$`,
+			Lines:      []string{"{line1}", "{line2}", "{postal_code} {city}", "{country}"},
+		}),
+		"FR": newAddressFormat(AddressFormat{
+			Required:   []string{AddressLine1, AddressCity, AddressPostalCode},
+			PostalCode: `^\d{5}$`,
+			Lines:      []string{"{line1}", "{line2}", "{postal_code} {city}", "{country}"},
+		}),
+		"AU": newAddressFormat(AddressFormat{
+			Required:   []string{AddressLine1, AddressCity, AddressRegion, AddressPostalCode},
+			PostalCode: `^\d{4}$`,
+			Lines:      []string{"{line1}", "{line2}", "{city} {region} {postal_code}", "{country}"},
+		}),
+		"IE": newAddressFormat(AddressFormat{
+			Required: []string{AddressLine1, AddressCity},
+			Lines:    []string{"{line1}", "{line2}", "{city}", "{region}", "{postal_code}", "{country}"},
+		}),
+	}
+)
+
+// newAddressFormat compiles the postal code pattern of f
+func newAddressFormat(f AddressFormat) *AddressFormat {
+	if f.PostalCode != "" {
+		f.postalRegex = regexp.MustCompile(f.PostalCode)
+	}
+	return &f
+}
+
+// RegisterAddressFormat adds or replaces the address format for an ISO 3166-1 alpha-2 country code.
+// It panics if the postal code pattern does not compile.
+func RegisterAddressFormat(country string, format AddressFormat) {
+	addressFormatsMu.Lock()
+	defer addressFormatsMu.Unlock()
+	addressFormats[strings.ToUpper(country)] = newAddressFormat(format)
+}
+
+// LookupAddressFormat returns the address format for a country, falling back to a generic format
+func LookupAddressFormat(country string) *AddressFormat {
+	addressFormatsMu.RLock()
+	defer addressFormatsMu.RUnlock()
+	if f, ok := addressFormats[strings.ToUpper(strings.TrimSpace(country))]; ok {
+		return f
+	}
+	return defaultAddressFormat
+}
+
+// Field returns the value of the named address field
+func (a Address) Field(name string) string {
+	switch name {
+	case AddressLine1:
+		return a.Line1
+	case AddressLine2:
+		return a.Line2
+	case AddressCity:
+		return a.City
+	case AddressRegion:
+		return a.Region
+	case AddressPostalCode:
+		return a.PostalCode
+	case AddressCountry:
+		return a.Country
+	default:
+		return ""
+	}
+}
+
+// CheckAddress validates an address against the rules for its country and adds a field error for each
+// problem. Field errors are keyed by prefix and the address field name, e.g. "shipping.postal_code".
+// An empty prefix uses the bare field names. It returns true if the address is valid.
+//
+// Example usage:
+// CheckAddress(v, "shipping", Address{Line1: "1 Main St", City: "Springfield", Country: "US"})
+// v.Field("shipping.region") // returns "region is required"
+func CheckAddress(v *Validator, prefix string, a Address) bool {
+	format := LookupAddressFormat(a.Country)
+
+	key := func(field string) string {
+		if prefix == "" {
+			return field
+		}
+		return prefix + "." + field
+	}
+
+	valid := true
+	for _, field := range format.Required {
+		if !Required(a.Field(field)) {
+			v.AddFieldError(key(field), strings.ReplaceAll(field, "_", " ")+" is required")
+			valid = false
+		}
+	}
+
+	if a.PostalCode != "" && !PostalCode(a.Country)(a.PostalCode) {
+		v.AddFieldError(key(AddressPostalCode), "postal code is invalid")
+		valid = false
+	}
+
+	return valid
+}
+
+// PostalCode returns a validation function that checks a postal code against the format for a country.
+// Countries without a known pattern accept any non-empty value.
+//
+// Example usage:
+// PostalCode("US")("12345-6789") // returns true
+// PostalCode("GB")("12345") // returns false
+func PostalCode(country string) ValidationFunc {
+	return func(value any) bool {
+		str, ok := value.(string)
+		if !ok || !Required(str) {
+			return false
+		}
+		format := LookupAddressFormat(country)
+		return format.postalRegex == nil || format.postalRegex.MatchString(strings.TrimSpace(str))
+	}
+}
+
+// FormatAddress returns the display lines for an address using its country's layout.
+// Empty lines are omitted.
+func FormatAddress(a Address) []string {
+	format := LookupAddressFormat(a.Country)
+
+	lines := make([]string, 0, len(format.Lines))
+	for _, layout := range format.Lines {
+		line := layout
+		for _, field := range []string{AddressLine1, AddressLine2, AddressCity, AddressRegion, AddressPostalCode, AddressCountry} {
+			line = strings.ReplaceAll(line, "{"+field+"}", strings.TrimSpace(a.Field(field)))
+		}
+		line = strings.Join(strings.Fields(line), " ")
+		line = strings.Trim(line, ", ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,112 @@
+package check_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/patrickward/hop/check"
+)
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		address    check.Address
+		wantFields []string
+	}{
+		{
+			name:    "valid us address",
+			address: check.Address{Line1: "1 Main St", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "US"},
+		},
+		{
+			name:       "us requires region and postal code",
+			address:    check.Address{Line1: "1 Main St", City: "Springfield", Country: "US"},
+			wantFields: []string{"shipping.region", "shipping.postal_code"},
+		},
+		{
+			name:       "invalid gb postal code",
+			address:    check.Address{Line1: "10 Downing St", City: "London", PostalCode: "12345", Country: "GB"},
+			wantFields: []string{"shipping.postal_code"},
+		},
+		{
+			name:    "gb does not require a region",
+			address: check.Address{Line1: "10 Downing St", City: "London", PostalCode: "SW1A 2AA", Country: "gb"},
+		},
+		{
+			name:       "unknown country uses generic rules",
+			address:    check.Address{City: "Somewhere", Country: "ZZ"},
+			wantFields: []string{"shipping.line1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := check.NewValidator()
+			valid := check.CheckAddress(v, "shipping", tt.address)
+
+			if valid != (len(tt.wantFields) == 0) {
+				t.Errorf("CheckAddress() = %v, errors: %v", valid, v.Fields())
+			}
+			if len(v.Fields()) != len(tt.wantFields) {
+				t.Errorf("got field errors %v, want %v", v.Fields(), tt.wantFields)
+			}
+			for _, field := range tt.wantFields {
+				if !v.HasField(field) {
+					t.Errorf("expected error for %s, got %v", field, v.Fields())
+				}
+			}
+		})
+	}
+}
+
+func TestPostalCode(t *testing.T) {
+	if !check.PostalCode("US")("12345-6789") {
+		t.Error("expected ZIP+4 to be valid")
+	}
+	if !check.PostalCode("CA")("k1a 0b1") {
+		t.Error("expected Canadian postal code to be valid")
+	}
+	if check.PostalCode("DE")("1234") {
+		t.Error("expected short German postal code to be invalid")
+	}
+	if !check.PostalCode("ZZ")("anything") {
+		t.Error("expected unknown country to accept any postal code")
+	}
+	if check.PostalCode("ZZ")("") {
+		t.Error("expected empty postal code to be invalid")
+	}
+}
+
+func TestFormatAddress(t *testing.T) {
+	tests := []struct {
+		address check.Address
+		want    []string
+	}{
+		{
+			address: check.Address{Line1: "1 Main St", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "US"},
+			want:    []string{"1 Main St", "Springfield, IL 62701", "US"},
+		},
+		{
+			address: check.Address{Line1: "Unter den Linden 1", Line2: "3. OG", City: "Berlin", PostalCode: "10117", Country: "DE"},
+			want:    []string{"Unter den Linden 1", "3. OG", "10117 Berlin", "DE"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := check.FormatAddress(tt.address); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FormatAddress() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestRegisterAddressFormat(t *testing.T) {
+	check.RegisterAddressFormat("XA", check.AddressFormat{
+		Required:   []string{check.AddressLine1, check.AddressPostalCode},
+		PostalCode: `^XA-\d{3}$`,
+		Lines:      []string{"{line1}", "{postal_code}"},
+	})
+
+	v := check.NewValidator()
+	if !check.CheckAddress(v, "", check.Address{Line1: "Somewhere", PostalCode: "XA-123", Country: "XA"}) {
+		t.Errorf("expected registered format to accept address, got %v", v.Fields())
+	}
+}
@@ -0,0 +1,518 @@
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONSchema is a compiled JSON Schema. It supports the commonly used subset of the specification:
+// type, enum, const, properties, required, additionalProperties, items, minItems, maxItems,
+// uniqueItems, minLength, maxLength, pattern, format (email, date, date-time, uri), minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, allOf, anyOf, oneOf, not, and local $ref
+// references to "#/definitions/..." or "#/$defs/...". Unsupported keywords are ignored.
+type JSONSchema struct {
+	root *schemaNode
+	raw  json.RawMessage
+}
+
+// SchemaError describes a single location where a value does not match a schema
+type SchemaError struct {
+	// Path is a JSON pointer to the invalid value, e.g. "/user/email". The root is "".
+	Path string `json:"path"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+// SchemaErrors is the list of problems found when validating a value against a schema
+type SchemaErrors []SchemaError
+
+// Error implements the error interface
+func (e SchemaErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		path := err.Path
+		if path == "" {
+			path = "/"
+		}
+		msgs[i] = path + ": " + err.Message
+	}
+	return "schema validation failed: " + strings.Join(msgs, "; ")
+}
+
+// schemaNode is a parsed schema or subschema
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 schemaTypes            `json:"type"`
+	Enum                 []any                  `json:"enum"`
+	Const                *any                   `json:"const"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *json.RawMessage       `json:"additionalProperties"`
+	Items                *schemaNode            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	UniqueItems          bool                   `json:"uniqueItems"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	Format               string                 `json:"format"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum"`
+	MultipleOf           *float64               `json:"multipleOf"`
+	AllOf                []*schemaNode          `json:"allOf"`
+	AnyOf                []*schemaNode          `json:"anyOf"`
+	OneOf                []*schemaNode          `json:"oneOf"`
+	Not                  *schemaNode            `json:"not"`
+	Definitions          map[string]*schemaNode `json:"definitions"`
+	Defs                 map[string]*schemaNode `json:"$defs"`
+
+	// allowAdditional and additional are derived from AdditionalProperties
+	allowAdditional bool
+	additional      *schemaNode
+	pattern         *regexp.Regexp
+}
+
+// schemaTypes holds the "type" keyword, which may be a string or an array of strings
+type schemaTypes []string
+
+// UnmarshalJSON accepts a single type name or a list of type names
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = list
+	return nil
+}
+
+// ParseJSONSchema parses and compiles a JSON Schema document
+//
+// Example usage:
+// schema, err := ParseJSONSchema([]byte(`{"type": "object", "required": ["email"]}`))
+// err = schema.ValidateJSON([]byte(`{"name": "Jo"}`)) // returns SchemaErrors
+func ParseJSONSchema(data []byte) (*JSONSchema, error) {
+	var root schemaNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing JSON schema: %w", err)
+	}
+
+	if err := root.compile(); err != nil {
+		return nil, err
+	}
+
+	return &JSONSchema{root: &root, raw: append(json.RawMessage(nil), data...)}, nil
+}
+
+// MustParseJSONSchema is like ParseJSONSchema but panics if the schema cannot be parsed
+func MustParseJSONSchema(data []byte) *JSONSchema {
+	s, err := ParseJSONSchema(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Raw returns the original schema document
+func (s *JSONSchema) Raw() json.RawMessage {
+	return s.raw
+}
+
+// MarshalJSON returns the original schema document
+func (s *JSONSchema) MarshalJSON() ([]byte, error) {
+	return s.raw, nil
+}
+
+// ValidateJSON validates a JSON document against the schema. It returns SchemaErrors when the
+// document does not match, or a decoding error if data is not valid JSON.
+func (s *JSONSchema) ValidateJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("decoding JSON: %w", err)
+	}
+
+	return s.validateDecoded(v)
+}
+
+// Validate validates a Go value against the schema by encoding it to JSON first
+func (s *JSONSchema) Validate(value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding value: %w", err)
+	}
+	return s.ValidateJSON(data)
+}
+
+// validateDecoded validates a decoded JSON value
+func (s *JSONSchema) validateDecoded(v any) error {
+	var errs SchemaErrors
+	s.root.validate(s.root, v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// compile prepares a node and its subschemas for validation
+func (n *schemaNode) compile() error {
+	if n == nil {
+		return nil
+	}
+
+	if n.Pattern != "" {
+		rx, err := regexp.Compile(n.Pattern)
+		if err != nil {
+			return fmt.Errorf("compiling schema pattern %q: %w", n.Pattern, err)
+		}
+		n.pattern = rx
+	}
+
+	n.allowAdditional = true
+	if n.AdditionalProperties != nil {
+		var allowed bool
+		if err := json.Unmarshal(*n.AdditionalProperties, &allowed); err == nil {
+			n.allowAdditional = allowed
+		} else {
+			var sub schemaNode
+			if err := json.Unmarshal(*n.AdditionalProperties, &sub); err != nil {
+				return fmt.Errorf("parsing additionalProperties: %w", err)
+			}
+			n.additional = &sub
+		}
+	}
+
+	children := []*schemaNode{n.Items, n.Not, n.additional}
+	children = append(children, n.AllOf...)
+	children = append(children, n.AnyOf...)
+	children = append(children, n.OneOf...)
+	for _, m := range []map[string]*schemaNode{n.Properties, n.Definitions, n.Defs} {
+		for _, child := range m {
+			children = append(children, child)
+		}
+	}
+
+	for _, child := range children {
+		if err := child.compile(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolve follows a local $ref from the root schema
+func (n *schemaNode) resolve(root *schemaNode) (*schemaNode, error) {
+	var defs map[string]*schemaNode
+	name, ok := strings.CutPrefix(n.Ref, "#/definitions/")
+	if ok {
+		defs = root.Definitions
+	} else if name, ok = strings.CutPrefix(n.Ref, "#/$defs/"); ok {
+		defs = root.Defs
+	} else if n.Ref == "#" {
+		return root, nil
+	}
+
+	if target, found := defs[name]; ok && found {
+		return target, nil
+	}
+	return nil, fmt.Errorf("unresolved reference %q", n.Ref)
+}
+
+// validate appends an error to errs for every problem found in v
+func (n *schemaNode) validate(root *schemaNode, v any, path string, errs *SchemaErrors) {
+	add := func(format string, args ...any) {
+		*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if n.Ref != "" {
+		target, err := n.resolve(root)
+		if err != nil {
+			add("%v", err)
+			return
+		}
+		target.validate(root, v, path, errs)
+		return
+	}
+
+	if len(n.Type) > 0 && !matchesAnyType(v, n.Type) {
+		add("expected %s, got %s", strings.Join(n.Type, " or "), jsonTypeOf(v))
+		return
+	}
+
+	if len(n.Enum) > 0 {
+		found := false
+		for _, allowed := range n.Enum {
+			if jsonEqual(v, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add("must be one of %s", formatEnum(n.Enum))
+		}
+	}
+
+	if n.Const != nil && !jsonEqual(v, *n.Const) {
+		add("must equal %v", *n.Const)
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		n.validateObject(root, val, path, errs)
+	case []any:
+		n.validateArray(root, val, path, errs)
+	case string:
+		n.validateString(val, add)
+	case json.Number:
+		f, err := val.Float64()
+		if err == nil {
+			n.validateNumber(f, add)
+		}
+	}
+
+	for _, sub := range n.AllOf {
+		sub.validate(root, v, path, errs)
+	}
+
+	if len(n.AnyOf) > 0 && countMatches(root, n.AnyOf, v, path) == 0 {
+		add("must match at least one schema in anyOf")
+	}
+
+	if len(n.OneOf) > 0 {
+		if matches := countMatches(root, n.OneOf, v, path); matches != 1 {
+			add("must match exactly one schema in oneOf, matched %d", matches)
+		}
+	}
+
+	if n.Not != nil && countMatches(root, []*schemaNode{n.Not}, v, path) == 1 {
+		add("must not match the schema in not")
+	}
+}
+
+// validateObject checks object keywords
+func (n *schemaNode) validateObject(root *schemaNode, obj map[string]any, path string, errs *SchemaErrors) {
+	for _, name := range n.Required {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, SchemaError{Path: path + "/" + escapePointer(name), Message: "is required"})
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "/" + escapePointer(key)
+		if prop, ok := n.Properties[key]; ok {
+			prop.validate(root, obj[key], childPath, errs)
+			continue
+		}
+		if n.additional != nil {
+			n.additional.validate(root, obj[key], childPath, errs)
+			continue
+		}
+		if !n.allowAdditional {
+			*errs = append(*errs, SchemaError{Path: childPath, Message: "is not allowed"})
+		}
+	}
+}
+
+// validateArray checks array keywords
+func (n *schemaNode) validateArray(root *schemaNode, arr []any, path string, errs *SchemaErrors) {
+	if n.MinItems != nil && len(arr) < *n.MinItems {
+		*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf("must have at least %d items", *n.MinItems)})
+	}
+	if n.MaxItems != nil && len(arr) > *n.MaxItems {
+		*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf("must have at most %d items", *n.MaxItems)})
+	}
+
+	if n.UniqueItems {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					*errs = append(*errs, SchemaError{Path: path, Message: "items must be unique"})
+					i = len(arr)
+					break
+				}
+			}
+		}
+	}
+
+	if n.Items != nil {
+		for i, item := range arr {
+			n.Items.validate(root, item, path+"/"+strconv.Itoa(i), errs)
+		}
+	}
+}
+
+// validateString checks string keywords
+func (n *schemaNode) validateString(s string, add func(string, ...any)) {
+	length := utf8.RuneCountInString(s)
+	if n.MinLength != nil && length < *n.MinLength {
+		add("must be at least %d characters", *n.MinLength)
+	}
+	if n.MaxLength != nil && length > *n.MaxLength {
+		add("must be at most %d characters", *n.MaxLength)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		add("must match pattern %s", n.Pattern)
+	}
+
+	switch n.Format {
+	case "email":
+		if !Email(s) {
+			add("must be a valid email address")
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, s); err != nil {
+			add("must be a date in YYYY-MM-DD format")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			add("must be an RFC 3339 date-time")
+		}
+	case "uri":
+		if !strings.Contains(s, ":") {
+			add("must be an absolute URI")
+		}
+	}
+}
+
+// validateNumber checks numeric keywords
+func (n *schemaNode) validateNumber(f float64, add func(string, ...any)) {
+	if n.Minimum != nil && f < *n.Minimum {
+		add("must be at least %v", *n.Minimum)
+	}
+	if n.Maximum != nil && f > *n.Maximum {
+		add("must be at most %v", *n.Maximum)
+	}
+	if n.ExclusiveMinimum != nil && f <= *n.ExclusiveMinimum {
+		add("must be greater than %v", *n.ExclusiveMinimum)
+	}
+	if n.ExclusiveMaximum != nil && f >= *n.ExclusiveMaximum {
+		add("must be less than %v", *n.ExclusiveMaximum)
+	}
+	if n.MultipleOf != nil && *n.MultipleOf != 0 {
+		if q := f / *n.MultipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			add("must be a multiple of %v", *n.MultipleOf)
+		}
+	}
+}
+
+// countMatches returns how many of the schemas v matches
+func countMatches(root *schemaNode, schemas []*schemaNode, v any, path string) int {
+	matches := 0
+	for _, sub := range schemas {
+		var subErrs SchemaErrors
+		sub.validate(root, v, path, &subErrs)
+		if len(subErrs) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// matchesAnyType reports whether v is one of the JSON Schema types
+func matchesAnyType(v any, types []string) bool {
+	actual := jsonTypeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf returns the JSON Schema type name of a decoded value
+func jsonTypeOf(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return "integer"
+		}
+		if f, err := val.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// jsonEqual compares two decoded JSON values, treating numbers by value
+func jsonEqual(a, b any) bool {
+	return reflect.DeepEqual(normalizeJSON(a), normalizeJSON(b))
+}
+
+// normalizeJSON converts json.Number values to float64 so values decoded differently compare equal
+func normalizeJSON(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		f, _ := val.Float64()
+		return f
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = normalizeJSON(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = normalizeJSON(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// formatEnum formats enum values for an error message
+func formatEnum(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		b, _ := json.Marshal(v)
+		parts[i] = string(b)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// escapePointer escapes a key for use in a JSON pointer
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,148 @@
+package check_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/patrickward/hop/check"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["email", "age"],
+	"additionalProperties": false,
+	"properties": {
+		"email": {"type": "string", "format": "email"},
+		"age": {"type": "integer", "minimum": 18},
+		"role": {"enum": ["admin", "member"]},
+		"tags": {"type": "array", "items": {"type": "string", "minLength": 1}, "maxItems": 2, "uniqueItems": true},
+		"address": {"$ref": "#/$defs/address"}
+	},
+	"$defs": {
+		"address": {"type": "object", "required": ["city"], "properties": {"city": {"type": "string"}}}
+	}
+}`
+
+func TestJSONSchema_ValidateJSON(t *testing.T) {
+	schema := check.MustParseJSONSchema([]byte(userSchema))
+
+	tests := []struct {
+		name      string
+		doc       string
+		wantPaths []string
+	}{
+		{
+			name: "valid",
+			doc:  `{"email": "jo@example.com", "age": 30, "role": "admin", "tags": ["a", "b"], "address": {"city": "Paris"}}`,
+		},
+		{
+			name:      "missing required",
+			doc:       `{"email": "jo@example.com"}`,
+			wantPaths: []string{"/age"},
+		},
+		{
+			name:      "wrong types and formats",
+			doc:       `{"email": "nope", "age": 12.5}`,
+			wantPaths: []string{"/age", "/email"},
+		},
+		{
+			name:      "minimum and enum",
+			doc:       `{"email": "jo@example.com", "age": 12, "role": "owner"}`,
+			wantPaths: []string{"/age", "/role"},
+		},
+		{
+			name:      "array rules",
+			doc:       `{"email": "jo@example.com", "age": 20, "tags": ["a", "a", ""]}`,
+			wantPaths: []string{"/tags", "/tags", "/tags/2"},
+		},
+		{
+			name:      "additional property",
+			doc:       `{"email": "jo@example.com", "age": 20, "admin": true}`,
+			wantPaths: []string{"/admin"},
+		},
+		{
+			name:      "reference",
+			doc:       `{"email": "jo@example.com", "age": 20, "address": {}}`,
+			wantPaths: []string{"/address/city"},
+		},
+		{
+			name:      "root type",
+			doc:       `[]`,
+			wantPaths: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.ValidateJSON([]byte(tt.doc))
+			if len(tt.wantPaths) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateJSON() unexpected error: %v", err)
+				}
+				return
+			}
+
+			var errs check.SchemaErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("ValidateJSON() error = %v, want SchemaErrors", err)
+			}
+			if len(errs) != len(tt.wantPaths) {
+				t.Fatalf("got %d errors (%v), want paths %v", len(errs), errs, tt.wantPaths)
+			}
+			for i, path := range tt.wantPaths {
+				if errs[i].Path != path {
+					t.Errorf("error %d path = %q, want %q (%v)", i, errs[i].Path, path, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSchema_Combinators(t *testing.T) {
+	schema := check.MustParseJSONSchema([]byte(`{
+		"oneOf": [
+			{"type": "string", "pattern": "^[a-z]+$"},
+			{"type": "number", "multipleOf": 5}
+		],
+		"not": {"const": "forbidden"}
+	}`))
+
+	valid := []string{`"abc"`, `10`}
+	invalid := []string{`"ABC"`, `7`, `"forbidden"`, `true`}
+
+	for _, doc := range valid {
+		if err := schema.ValidateJSON([]byte(doc)); err != nil {
+			t.Errorf("ValidateJSON(%s) unexpected error: %v", doc, err)
+		}
+	}
+	for _, doc := range invalid {
+		if err := schema.ValidateJSON([]byte(doc)); err == nil {
+			t.Errorf("ValidateJSON(%s) expected an error", doc)
+		}
+	}
+}
+
+func TestJSONSchema_Validate(t *testing.T) {
+	schema := check.MustParseJSONSchema([]byte(userSchema))
+
+	type user struct {
+		Email string `json:"email"`
+		Age   int    `json:"age"`
+	}
+
+	if err := schema.Validate(user{Email: "jo@example.com", Age: 40}); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+	if err := schema.Validate(user{Email: "jo@example.com", Age: 4}); err == nil {
+		t.Error("Validate() expected an error")
+	}
+}
+
+func TestParseJSONSchema_Invalid(t *testing.T) {
+	if _, err := check.ParseJSONSchema([]byte(`{"type": 1}`)); err == nil {
+		t.Error("expected error for invalid type keyword")
+	}
+	if _, err := check.ParseJSONSchema([]byte(`{"pattern": "("}`)); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
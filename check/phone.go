@@ -0,0 +1,183 @@
+package check
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidPhone is returned when a phone number cannot be normalized to E.164
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// callingCodes maps ISO 3166-1 alpha-2 country codes to international calling codes
+var callingCodes = map[string]string{
+	"AU": "61",
+	"BR": "55",
+	"CA": "1",
+	"CH": "41",
+	"DE": "49",
+	"ES": "34",
+	"FR": "33",
+	"GB": "44",
+	"IE": "353",
+	"IN": "91",
+	"IT": "39",
+	"JP": "81",
+	"MX": "52",
+	"NL": "31",
+	"NZ": "64",
+	"US": "1",
+}
+
+// CallingCode returns the international calling code for an ISO 3166-1 alpha-2 country code
+//
+// Example usage:
+// CallingCode("US") // returns "1", true
+// CallingCode("GB") // returns "44", true
+func CallingCode(country string) (string, bool) {
+	code, ok := callingCodes[strings.ToUpper(country)]
+	return code, ok
+}
+
+// NormalizePhone converts a phone number to E.164 format (e.g. "+15551234567"). Numbers that start
+// with "+" or "00" are treated as international; all other numbers are treated as national numbers
+// for the given default country, with any leading trunk prefix "0" removed.
+//
+// Example usage:
+// NormalizePhone("(555) 123-4567", "US") // returns "+15551234567", nil
+// NormalizePhone("020 7946 0958", "GB") // returns "+442079460958", nil
+// NormalizePhone("+49 30 123456", "") // returns "+4930123456", nil
+func NormalizePhone(value, defaultCountry string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", ErrInvalidPhone
+	}
+
+	international := strings.HasPrefix(value, "+")
+	var digits strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' || r == ' ' || r == '-' || r == '.' || r == '(' || r == ')' || r == '/':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	if !international && strings.HasPrefix(number, "00") {
+		international = true
+		number = number[2:]
+	}
+
+	if !international {
+		code, ok := CallingCode(defaultCountry)
+		if !ok {
+			return "", ErrInvalidPhone
+		}
+		if code == "1" {
+			// NANP numbers are often written with a leading 1
+			number = strings.TrimPrefix(number, "1")
+		} else {
+			number = strings.TrimPrefix(number, "0")
+		}
+		number = code + number
+	}
+
+	normalized := "+" + number
+	if !E164(normalized) {
+		return "", ErrInvalidPhone
+	}
+
+	return normalized, nil
+}
+
+// E164 validates that a phone number is already in E.164 format
+//
+// Example usage:
+// E164("+15551234567") // returns true
+// E164("555-123-4567") // returns false
+func E164(value any) bool {
+	str, ok := value.(string)
+	if !ok || len(str) < 9 || len(str) > 16 || str[0] != '+' || str[1] == '0' {
+		return false
+	}
+	for _, r := range str[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	// NANP numbers always have exactly ten national digits
+	if str[1] == '1' {
+		return len(str) == 12
+	}
+
+	return true
+}
+
+// PhoneIn returns a validation function that checks a phone number can be normalized to E.164,
+// treating national numbers as belonging to the given country
+//
+// Example usage:
+// PhoneIn("US")("555-123-4567") // returns true
+// PhoneIn("US")("123") // returns false
+func PhoneIn(country string) ValidationFunc {
+	return func(value any) bool {
+		str, ok := value.(string)
+		if !ok {
+			return false
+		}
+		_, err := NormalizePhone(str, country)
+		return err == nil
+	}
+}
+
+// FormatPhone formats an E.164 phone number for display. NANP numbers are formatted as
+// "+1 (555) 123-4567"; other numbers have their national part split into groups of digits.
+// Values that are not valid E.164 numbers are returned unchanged.
+//
+// Example usage:
+// FormatPhone("+15551234567") // returns "+1 (555) 123-4567"
+// FormatPhone("+442079460958") // returns "+44 207 946 0958"
+func FormatPhone(e164 string) string {
+	if !E164(e164) {
+		return e164
+	}
+
+	if e164[1] == '1' {
+		n := e164[2:]
+		return "+1 (" + n[:3] + ") " + n[3:6] + "-" + n[6:]
+	}
+
+	code := countryCodeOf(e164[1:])
+	national := e164[1+len(code):]
+
+	var groups []string
+	for len(national) > 4 {
+		size := 3
+		if len(national) == 5 {
+			size = 2
+		}
+		groups = append(groups, national[:size])
+		national = national[size:]
+	}
+	groups = append(groups, national)
+
+	return "+" + code + " " + strings.Join(groups, " ")
+}
+
+// countryCodeOf returns the known calling code that prefixes number, preferring the longest match.
+// Unknown numbers fall back to a two digit country code.
+func countryCodeOf(number string) string {
+	var match string
+	for _, code := range callingCodes {
+		if strings.HasPrefix(number, code) && len(code) > len(match) {
+			match = code
+		}
+	}
+	if match == "" {
+		match = number[:2]
+	}
+	return match
+}
@@ -0,0 +1,84 @@
+package check_test
+
+import (
+	"testing"
+
+	"github.com/patrickward/hop/check"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		country string
+		want    string
+		wantErr bool
+	}{
+		{name: "us national", value: "(555) 123-4567", country: "US", want: "+15551234567"},
+		{name: "us with leading 1", value: "1-555-123-4567", country: "US", want: "+15551234567"},
+		{name: "gb trunk prefix", value: "020 7946 0958", country: "GB", want: "+442079460958"},
+		{name: "international plus", value: "+49 30 123456", country: "", want: "+4930123456"},
+		{name: "international 00", value: "0033 1 23 45 67 89", country: "US", want: "+33123456789"},
+		{name: "unknown country", value: "555 1234", country: "ZZ", wantErr: true},
+		{name: "letters", value: "555-CALL-NOW", country: "US", wantErr: true},
+		{name: "too short", value: "123", country: "US", wantErr: true},
+		{name: "empty", value: "", country: "US", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := check.NormalizePhone(tt.value, tt.country)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizePhone() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizePhone() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestE164(t *testing.T) {
+	tests := []struct {
+		value any
+		want  bool
+	}{
+		{"+15551234567", true},
+		{"+442079460958", true},
+		{"+1555123456", false},
+		{"15551234567", false},
+		{"+05551234567", false},
+		{"+1555123456a", false},
+		{15551234567, false},
+	}
+
+	for _, tt := range tests {
+		if got := check.E164(tt.value); got != tt.want {
+			t.Errorf("E164(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPhoneIn(t *testing.T) {
+	if !check.PhoneIn("US")("555.123.4567") {
+		t.Error("PhoneIn(US) should accept a national US number")
+	}
+	if check.PhoneIn("US")("12") {
+		t.Error("PhoneIn(US) should reject a short number")
+	}
+}
+
+func TestFormatPhone(t *testing.T) {
+	tests := map[string]string{
+		"+15551234567":  "+1 (555) 123-4567",
+		"+442079460958": "+44 207 946 0958",
+		"+35312345678":  "+353 123 45 678",
+		"not a number":  "not a number",
+	}
+
+	for in, want := range tests {
+		if got := check.FormatPhone(in); got != want {
+			t.Errorf("FormatPhone(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
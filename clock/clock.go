@@ -0,0 +1,107 @@
+// Package clock abstracts telling the time and waiting, so time-dependent behavior such as session
+// lifetimes, rate limit windows, scheduled events and metric rates can be tested deterministically.
+// Components take a Clock, defaulting to Real; tests pass the fake clock from the hoptest package and
+// advance it instead of sleeping.
+//
+//	clk := hoptest.NewClock(time.Now())
+//	store := middleware.NewMemoryCounterStore()
+//	store.SetClock(clk)
+//	...
+//	clk.Advance(time.Minute) // the rate limit window resets
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time and creates timers
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Since returns the time elapsed since t
+	Since(t time.Time) time.Duration
+	// Until returns the duration until t
+	Until(t time.Time) time.Duration
+	// After waits for the duration to elapse and then sends the current time on the returned channel
+	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a Timer that sends the current time on its channel after at least d
+	NewTimer(d time.Duration) Timer
+	// NewTicker creates a Ticker that sends the current time on its channel every d
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a single event created by a Clock, like time.Timer
+type Timer interface {
+	// C returns the channel on which the time is delivered
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It reports whether the timer was stopped before it fired.
+	Stop() bool
+	// Reset changes the timer to expire after d. It reports whether the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered
+	C() <-chan time.Time
+	// Stop turns off the ticker
+	Stop()
+	// Reset stops the ticker and resets its period to d
+	Reset(d time.Duration)
+}
+
+// Real returns the Clock backed by the time package
+func Real() Clock {
+	return realClock{}
+}
+
+// OrReal returns c, or the real clock when c is nil
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return realClock{}
+	}
+	return c
+}
+
+// WithTimeout returns a copy of parent that is canceled once c has advanced by d, like
+// context.WithTimeout. With a clock other than Real, the context has no deadline and its Err is
+// context.Canceled when the time is up; context.Cause returns context.DeadlineExceeded.
+func WithTimeout(parent context.Context, c Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.(realClock); ok || c == nil {
+		return context.WithTimeout(parent, d)
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	timer := c.NewTimer(d)
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) Until(t time.Time) time.Duration        { return time.Until(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (r realTimer) C() <-chan time.Time        { return r.t.C }
+func (r realTimer) Stop() bool                 { return r.t.Stop() }
+func (r realTimer) Reset(d time.Duration) bool { return r.t.Reset(d) }
+
+type realTicker struct{ t *time.Ticker }
+
+func (r realTicker) C() <-chan time.Time   { return r.t.C }
+func (r realTicker) Stop()                 { r.t.Stop() }
+func (r realTicker) Reset(d time.Duration) { r.t.Reset(d) }
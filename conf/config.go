@@ -27,6 +27,8 @@ func (c *HopConfig) IsProduction() bool {
 type AppConfig struct {
 	Environment string `json:"environment" default:"development"`
 	Debug       bool   `json:"debug" default:"false"`
+	// Features lists the enabled feature flags, used to gate route groups with route.Group.IfFlag
+	Features conftype.StringList `json:"features"`
 }
 
 type EventsConfig struct {
@@ -39,11 +41,35 @@ type LogConfig struct {
 	IncludeTime bool   `json:"include_time" default:"false"`
 	Level       string `json:"level" default:"debug"`
 	Verbose     bool   `json:"verbose" default:"false"`
+	// Syslog ships logs to a syslog server when its address is set
+	Syslog LogSyslogConfig `json:"syslog"`
+	// OTLP ships logs to an OpenTelemetry collector when its endpoint is set
+	OTLP LogOTLPConfig `json:"otlp"`
+}
+
+type LogSyslogConfig struct {
+	// Network is "udp", "tcp" or "tls"
+	Network  string `json:"network" default:"udp"`
+	Address  string `json:"address" default:""`
+	AppName  string `json:"app_name" default:""`
+	Facility int    `json:"facility" default:"1"`
+}
+
+type LogOTLPConfig struct {
+	// Endpoint is the OTLP/HTTP logs endpoint, e.g. "http://localhost:4318/v1/logs"
+	Endpoint    string `json:"endpoint" default:""`
+	ServiceName string `json:"service_name" default:""`
+	// Headers are "key=value" pairs added to each request, e.g. "Authorization=Bearer token"
+	Headers conftype.StringList `json:"headers"`
 }
 
 type MaintenanceConfig struct {
 	Enabled bool   `json:"enabled" default:"false"`
 	Message string `json:"message" default:""`
+	// RetryAfter is sent in the Retry-After header of maintenance responses
+	RetryAfter conftype.Duration `json:"retry_after" default:"5m"`
+	// AllowPaths are path prefixes that stay available during maintenance, e.g. "/admin"
+	AllowPaths conftype.StringList `json:"allow_paths"`
 }
 
 type CsrfConfig struct {
@@ -52,6 +78,8 @@ type CsrfConfig struct {
 	MaxAge   int    `json:"max_age" default:"86400"`
 	SameSite string `json:"same_site" default:"Lax"`
 	Secure   bool   `json:"secure" default:"true"`
+	// ExemptPaths are request paths or glob patterns, e.g. "/webhooks/*", that skip the CSRF check
+	ExemptPaths conftype.StringList `json:"exempt_paths"`
 }
 
 type SessionConfig struct {
@@ -67,9 +95,18 @@ type SessionConfig struct {
 type ServerConfig struct {
 	BaseURL         string            `json:"base_url" default:"http://localhost:4444"`
 	Host            string            `json:"host" default:"localhost"`
-	Port            int               `json:"port" default:"4444"`
+	Port            int               `json:"port" default:"4444" validate:"min=0,max=65535"`
 	IdleTimeout     conftype.Duration `json:"idle_timeout" default:"120s"`
 	ReadTimeout     conftype.Duration `json:"read_timeout" default:"15s"`
 	WriteTimeout    conftype.Duration `json:"write_timeout" default:"15s"`
 	ShutdownTimeout conftype.Duration `json:"shutdown_timeout" default:"10s"`
+	// Network is the network the server listens on: "tcp" (default) or "unix".
+	Network string `json:"network" default:"tcp" validate:"oneof=tcp unix"`
+	// SocketPath is the path to the Unix domain socket when Network is "unix".
+	SocketPath string `json:"socket_path"`
+	// SocketMode is the octal file mode applied to the Unix domain socket (e.g. "0660").
+	SocketMode string `json:"socket_mode" default:"0660"`
+	// ReconnectAfter is how long streaming clients are told to wait before reconnecting when the
+	// server restarts.
+	ReconnectAfter conftype.Duration `json:"reconnect_after" default:"5s"`
 }
@@ -0,0 +1,150 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/patrickward/hop/conf/conftype"
+)
+
+// Sources of configuration values, as reported by Dump and Source
+const (
+	// SourceDefault is a value from a default tag, or the zero value
+	SourceDefault = "default"
+	// SourceFile is a value from a configuration file, reported as "file:<path>"
+	SourceFile = "file"
+	// SourceEnv is a value from an environment variable
+	SourceEnv = "env"
+	// SourceFlag is a value from a command-line flag
+	SourceFlag = "flag"
+)
+
+// Dump formats, see Manager.Dump
+const (
+	// DumpText prints one aligned "path = value  # source" line per field, like PrettyString
+	DumpText = "text"
+	// DumpJSON prints a JSON array of {"path", "value", "source", "secret"} objects
+	DumpJSON = "json"
+)
+
+// sourceTracker records which loading step last changed each field
+type sourceTracker struct {
+	prev    reflect.Value
+	sources map[string]string
+}
+
+func newSourceTracker(cfg interface{}) *sourceTracker {
+	return &sourceTracker{prev: copyConfig(cfg), sources: make(map[string]string)}
+}
+
+// mark attributes the fields changed since the previous step to source
+func (t *sourceTracker) mark(cfg interface{}, source string) {
+	for _, change := range Diff(t.prev.Interface(), cfg) {
+		t.sources[change.Path] = source
+	}
+	t.prev = copyConfig(cfg)
+}
+
+// copyConfig returns a pointer to a copy of the struct cfg points to
+func copyConfig(cfg interface{}) reflect.Value {
+	val := reflect.ValueOf(cfg).Elem()
+	cp := reflect.New(val.Type())
+	cp.Elem().Set(val)
+	return cp
+}
+
+// Source returns where the value of the field at path, e.g. "Hop.Server.Port", was last set: SourceDefault,
+// "file:<path>", SourceEnv or SourceFlag. A value set by a source to what it already was is attributed to
+// the earlier source.
+func (m *Manager) Source(path string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if source, ok := m.sources[path]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// dumpEntry is a field printed by Dump
+type dumpEntry struct {
+	Text   string `json:"-"`
+	Path   string `json:"path"`
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Dump writes the effective configuration to w, with the source of each value, to check what a deployment
+// actually runs with. Fields tagged `secret` or `conf:"secret"` are redacted. The format is DumpText
+// (the default when empty) or DumpJSON:
+//
+//	Hop.Server.Port                          = 8080                 # env
+//	Hop.Server.Host                          = "localhost"          # default
+//	Database.Password                        = [REDACTED] "s***t"   # file:config.json
+func (m *Manager) Dump(w io.Writer, format string) error {
+	m.mu.RLock()
+	var entries []dumpEntry
+	collectDumpEntries(reflect.ValueOf(m.config).Elem(), "", m.sources, &entries)
+	m.mu.RUnlock()
+
+	switch format {
+	case DumpText, "":
+		for _, e := range entries {
+			if _, err := fmt.Fprintf(w, "%-40s = %-20s # %s\n", e.Path, e.Text, e.Source); err != nil {
+				return err
+			}
+		}
+		return nil
+	case DumpJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	default:
+		return fmt.Errorf("unknown dump format %q", format)
+	}
+}
+
+// collectDumpEntries walks the fields of a struct like prettyPrint. Text values are formatted with
+// formatValue; JSON values are the raw values, except for secrets, which are masked.
+func collectDumpEntries(val reflect.Value, prefix string, sources map[string]string, entries *[]dumpEntry) {
+	typ := val.Type()
+
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		fieldType := typ.Field(i)
+
+		// Skip unexported fields
+		if !field.CanInterface() {
+			continue
+		}
+
+		fieldName := fieldType.Name
+		if prefix != "" {
+			fieldName = prefix + "." + fieldName
+		}
+
+		// Handle nested structs (except Duration which is a special case)
+		if field.Kind() == reflect.Struct && field.Type() != reflect.TypeOf(conftype.Duration{}) {
+			collectDumpEntries(field, fieldName, sources, entries)
+			continue
+		}
+
+		source, ok := sources[fieldName]
+		if !ok {
+			source = SourceDefault
+		}
+		entry := dumpEntry{
+			Text:   formatValue(field, fieldType),
+			Path:   fieldName,
+			Value:  field.Interface(),
+			Source: source,
+		}
+		if isSecret(fieldType) {
+			entry.Value, entry.Secret = maskValue(field), true
+		}
+		*entries = append(*entries, entry)
+	}
+}
@@ -0,0 +1,167 @@
+package conf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/patrickward/hop/conf/conftype"
+)
+
+// flagBinding is a command-line flag bound to a configuration field
+type flagBinding struct {
+	index []int
+	value *flagValue
+}
+
+// flagValue is a flag.Value recording the raw value of a flag, checked against the field type when set
+type flagValue struct {
+	typ    reflect.Type
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(s string) error {
+	if err := setFieldValue(reflect.New(v.typ).Elem(), s); err != nil {
+		return err
+	}
+	v.value, v.set = s, true
+	return nil
+}
+
+// IsBoolFlag lets boolean flags be passed without a value, e.g. --hop-app-debug
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// flagsConfig holds the options of WithFlags
+type flagsConfig struct {
+	fs       *flag.FlagSet
+	args     []string
+	bindings []flagBinding
+	parsed   bool
+}
+
+// WithFlags binds a command-line flag to every configuration field and parses args on Load. Flags take
+// precedence over environment variables, which take precedence over files and defaults.
+//
+// Flag names are derived like environment variable names, lowercased and with dashes, without the env
+// prefix: the field Hop.Server.Port, read from HOP_SERVER_PORT, is set with --hop-server-port. A field
+// can be renamed with a flag tag, or skipped with `flag:"-"`, and its help text set with a usage tag:
+//
+//	Port int `json:"port" flag:"port" usage:"port to listen on"`
+//
+// When fs is nil, flags are bound to flag.CommandLine, and when args is nil, os.Args[1:] is parsed. Load
+// returns the errors of parsing args, including flag.ErrHelp for -h.
+func WithFlags(fs *flag.FlagSet, args []string) Option {
+	return func(m *Manager) {
+		if fs == nil {
+			fs = flag.CommandLine
+		}
+		if args == nil {
+			args = os.Args[1:]
+		}
+		m.flags = &flagsConfig{fs: fs, args: args}
+		bindFlags(m.flags, reflect.TypeOf(m.config).Elem(), nil, "")
+	}
+}
+
+// bindFlags defines a flag for each field of a struct type, recursing into nested structs like the env
+// parser
+func bindFlags(fc *flagsConfig, typ reflect.Type, index []int, prefix string) {
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		name := strings.ToLower(strings.ReplaceAll(ToScreamingSnake(structField.Name), "_", "-"))
+		if prefix != "" {
+			name = prefix + "-" + name
+		}
+
+		tag := structField.Tag.Get("flag")
+		if tag == "-" {
+			continue
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		// Handle nested structs (except Duration which is a special case)
+		if structField.Type.Kind() == reflect.Struct && structField.Type != reflect.TypeOf(conftype.Duration{}) {
+			bindFlags(fc, structField.Type, fieldIndex, name)
+			continue
+		}
+		if !flagSupported(structField.Type) {
+			continue
+		}
+
+		if tag != "" {
+			name = tag
+		}
+		usage := structField.Tag.Get("usage")
+		if usage == "" {
+			usage = "sets " + structField.Name
+		}
+
+		value := &flagValue{typ: structField.Type, value: structField.Tag.Get("default"), isBool: structField.Type.Kind() == reflect.Bool}
+		fc.fs.Var(value, name, usage)
+		value.value = "" // the default is only shown in the usage message
+		fc.bindings = append(fc.bindings, flagBinding{index: fieldIndex, value: value})
+	}
+}
+
+// flagSupported reports whether setFieldValue can parse values of a type
+func flagSupported(typ reflect.Type) bool {
+	if reflect.PointerTo(typ).Implements(reflect.TypeOf((*StringParser)(nil)).Elem()) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// parseFlags parses the command-line arguments once
+func (m *Manager) parseFlags() error {
+	if m.flags == nil || m.flags.parsed {
+		return nil
+	}
+	m.flags.parsed = true
+	if m.flags.fs.Parsed() {
+		return nil
+	}
+	return m.flags.fs.Parse(m.flags.args)
+}
+
+// applyFlags sets the fields of the flags given on the command line
+func (m *Manager) applyFlags(cfg interface{}) error {
+	if m.flags == nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(cfg).Elem()
+	for _, b := range m.flags.bindings {
+		if !b.value.set {
+			continue
+		}
+		if err := setFieldValue(val.FieldByIndex(b.index), b.value.value); err != nil {
+			return fmt.Errorf("setting field from flag: %w", err)
+		}
+	}
+	return nil
+}
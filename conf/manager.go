@@ -3,6 +3,7 @@ package conf
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/patrickward/hop/clock"
 	"github.com/patrickward/hop/conf/conftype"
 )
 
@@ -21,6 +23,10 @@ type Manager struct {
 	envParser *EnvParser
 	validator *HopConfigValidator
 	discovery *configDiscovery
+	watch     watchConfig
+	flags     *flagsConfig
+	sources   map[string]string
+	subs      []chan Change
 }
 
 // Option is a functional option for Manager
@@ -33,6 +39,11 @@ func NewManager(config interface{}, opts ...Option) *Manager {
 		envParser: NewEnvParser(""),
 		validator: &HopConfigValidator{},
 		discovery: &configDiscovery{},
+		watch: watchConfig{
+			interval: DefaultWatchInterval,
+			logger:   slog.Default(),
+			clock:    clock.Real(),
+		},
 	}
 
 	for _, opt := range opts {
@@ -91,61 +102,93 @@ func WithEnvironment(env string) Option {
 // 1. Set defaults from struct tags
 // 2. Load JSON files in order specified
 // 3. Override with environment variables
-func (m *Manager) doLoad(cfg interface{}) error {
+// 4. Override with command-line flags, when bound with WithFlags
+func (m *Manager) doLoad(cfg interface{}) (map[string]string, error) {
+	sources := newSourceTracker(cfg)
+
 	// Set defaults first
 	if err := m.setDefaults(cfg); err != nil {
-		return fmt.Errorf("error setting defaults: %w", err)
+		return nil, fmt.Errorf("error setting defaults: %w", err)
 	}
+	sources.mark(cfg, SourceDefault)
 
 	// Load discovered files
 	if m.discovery != nil {
 		for _, path := range m.discovery.paths() {
-			if err := m.loadFile(path); err != nil {
-				return fmt.Errorf("error loading file %s: %w", path, err)
+			if err := m.loadFile(cfg, path); err != nil {
+				return nil, fmt.Errorf("error loading file %s: %w", path, err)
 			}
+			sources.mark(cfg, SourceFile+":"+path)
 		}
 	}
 
 	// Load JSON files in order
 	for _, file := range m.files {
-		if err := m.loadFile(file); err != nil {
-			return fmt.Errorf("error loading file %s: %w", file, err)
+		if err := m.loadFile(cfg, file); err != nil {
+			return nil, fmt.Errorf("error loading file %s: %w", file, err)
 		}
+		sources.mark(cfg, SourceFile+":"+file)
 	}
 
 	// Override with environment variables
 	if err := m.envParser.Parse(cfg); err != nil {
-		return fmt.Errorf("error parsing environment variables: %w", err)
+		return nil, fmt.Errorf("error parsing environment variables: %w", err)
+	}
+	sources.mark(cfg, SourceEnv)
+
+	// Override with command-line flags
+	if err := m.applyFlags(cfg); err != nil {
+		return nil, fmt.Errorf("error parsing flags: %w", err)
 	}
+	sources.mark(cfg, SourceFlag)
 
 	// Run validation after all loading is complete
 	if err := m.validator.Validate(cfg); err != nil {
-		return fmt.Errorf("error validating config: %w", err)
+		return nil, fmt.Errorf("error validating config: %w", err)
 	}
 
-	return nil
+	return sources.sources, nil
 }
 
 // Load performs initial load with lock
 func (m *Manager) Load() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.doLoad(m.config)
+	if err := m.parseFlags(); err != nil {
+		return fmt.Errorf("error parsing flags: %w", err)
+	}
+
+	sources, err := m.doLoad(m.config)
+	if err != nil {
+		return err
+	}
+	m.sources = sources
+	return nil
 }
 
-// Reload safely reloads config with new values
+// Reload safely reloads config with new values. When values changed, the change is sent to the
+// subscribers and emitted as EventConfigReloaded (see Subscribe and WithEvents). If the new configuration
+// fails to load or validate, the current one is kept and the error returned.
 func (m *Manager) Reload() error {
 	newCfg := reflect.New(reflect.TypeOf(m.config).Elem()).Interface()
 
-	if err := m.doLoad(newCfg); err != nil {
+	sources, err := m.doLoad(newCfg)
+	if err != nil {
 		return err
 	}
 
 	m.mu.Lock()
+	m.sources = sources
+	oldCfg := reflect.New(reflect.TypeOf(m.config).Elem())
+	oldCfg.Elem().Set(reflect.ValueOf(m.config).Elem())
 	// Copy values to existing config
 	reflect.ValueOf(m.config).Elem().Set(reflect.ValueOf(newCfg).Elem())
 	m.mu.Unlock()
 
+	if fields := Diff(oldCfg.Interface(), newCfg); len(fields) > 0 {
+		m.notify(Change{Old: oldCfg.Interface(), New: newCfg, Fields: fields})
+	}
+
 	return nil
 }
 
@@ -162,7 +205,7 @@ func (m *Manager) setDefaults(cfg interface{}) error {
 }
 
 // loadFile loads a single JSON file into the configuration struct
-func (m *Manager) loadFile(file string) error {
+func (m *Manager) loadFile(cfg interface{}, file string) error {
 	data, err := os.ReadFile(file)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -171,7 +214,7 @@ func (m *Manager) loadFile(file string) error {
 		return err
 	}
 
-	return json.Unmarshal(data, m.config)
+	return json.Unmarshal(data, cfg)
 }
 
 // Helper functions
@@ -1,9 +1,16 @@
 package conf_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +19,7 @@ import (
 
 	"github.com/patrickward/hop/conf"
 	"github.com/patrickward/hop/conf/conftype"
+	"github.com/patrickward/hop/hoptest"
 )
 
 type TestConfig struct {
@@ -225,3 +233,215 @@ func TestConfigManager_ReloadWithInvalidConfig(t *testing.T) {
 	// Clean up
 	os.Clearenv()
 }
+
+func TestConfigManager_Watch(t *testing.T) {
+	os.Clearenv()
+	t.Cleanup(os.Clearenv)
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	}
+	writeConfig(`{"api": {"endpoint": "https://one.example.com"}}`)
+
+	clk := hoptest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cfg := &TestConfig{}
+	mgr := conf.NewManager(cfg,
+		conf.WithConfigFile(file),
+		conf.WithClock(clk),
+		conf.WithWatchInterval(time.Second),
+		conf.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	require.NoError(t, mgr.Load())
+	assert.Equal(t, "https://one.example.com", cfg.API.Endpoint)
+
+	changes, unsubscribe := mgr.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- mgr.Watch(ctx) }()
+	clk.BlockUntil(1)
+
+	// Nothing changed: no reload
+	clk.Advance(time.Second)
+
+	writeConfig(`{"api": {"endpoint": "https://two.example.com", "max_retries": 5}}`)
+	clk.Advance(time.Second)
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, []conf.FieldChange{
+			{Path: "API.Endpoint", Old: "https://one.example.com", New: "https://two.example.com"},
+			{Path: "API.MaxRetries", Old: 3, New: 5},
+		}, change.Fields)
+		assert.True(t, change.Changed("API"))
+		assert.False(t, change.Changed("Hop.Log"))
+		assert.Equal(t, "https://one.example.com", change.Old.(*TestConfig).API.Endpoint)
+		assert.Equal(t, "https://two.example.com", change.New.(*TestConfig).API.Endpoint)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the config change")
+	}
+	assert.Equal(t, "https://two.example.com", mgr.Get().(*TestConfig).API.Endpoint)
+
+	// An invalid file is skipped, keeping the current configuration
+	writeConfig(`{"api": {"timeout": "not a duration"}}`)
+	clk.Advance(time.Second)
+	writeConfig(`{"api": {"endpoint": "https://two.example.com", "max_retries": 5}}`)
+	clk.Advance(time.Second)
+	assert.Equal(t, "https://two.example.com", mgr.Get().(*TestConfig).API.Endpoint)
+	assert.Empty(t, changes)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDiff(t *testing.T) {
+	type secrets struct {
+		Password string `secret:"true"`
+		Host     string
+	}
+
+	changes := conf.Diff(&secrets{Password: "hunter22", Host: "a"}, &secrets{Password: "correct horse", Host: "a"})
+	require.Len(t, changes, 1)
+	assert.True(t, changes[0].Secret)
+	assert.NotContains(t, changes[0].String(), "hunter22")
+	assert.NotContains(t, changes[0].String(), "correct horse")
+
+	assert.Empty(t, conf.Diff(&secrets{Host: "a"}, &secrets{Host: "a"}))
+}
+
+func TestConfigManager_WithFlags(t *testing.T) {
+	os.Clearenv()
+	t.Cleanup(os.Clearenv)
+	require.NoError(t, os.Setenv("API_ENDPOINT", "https://env.example.com"))
+	require.NoError(t, os.Setenv("API_MAX_RETRIES", "7"))
+
+	type FlagConfig struct {
+		Hop conf.HopConfig
+		API struct {
+			Endpoint   string            `json:"endpoint" default:"http://api.local"`
+			MaxRetries int               `json:"max_retries" default:"3"`
+			Timeout    conftype.Duration `json:"timeout" default:"30s" usage:"request timeout"`
+			Verbose    bool              `json:"verbose" flag:"verbose"`
+			Secret     string            `json:"secret" flag:"-"`
+		} `json:"api"`
+	}
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg := &FlagConfig{}
+	mgr := conf.NewManager(cfg, conf.WithFlags(fs, []string{
+		"--api-endpoint", "https://flag.example.com",
+		"--api-timeout=5s",
+		"--verbose",
+		"--hop-server-port", "8080",
+	}))
+	require.NoError(t, mgr.Load())
+
+	assert.Equal(t, "https://flag.example.com", cfg.API.Endpoint, "flags override env")
+	assert.Equal(t, 7, cfg.API.MaxRetries, "env applies when no flag is given")
+	assert.Equal(t, 5*time.Second, cfg.API.Timeout.Duration)
+	assert.True(t, cfg.API.Verbose)
+	assert.Equal(t, 8080, cfg.Hop.Server.Port)
+
+	// Flags keep applying on reload
+	require.NoError(t, mgr.Reload())
+	assert.Equal(t, "https://flag.example.com", cfg.API.Endpoint)
+
+	timeoutFlag := fs.Lookup("api-timeout")
+	require.NotNil(t, timeoutFlag)
+	assert.Equal(t, "request timeout", timeoutFlag.Usage)
+	assert.Equal(t, "30s", timeoutFlag.DefValue)
+	assert.Nil(t, fs.Lookup("api-secret"))
+
+	t.Run("invalid values", func(t *testing.T) {
+		fs := flag.NewFlagSet("app", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		mgr := conf.NewManager(&FlagConfig{}, conf.WithFlags(fs, []string{"--api-max-retries", "many"}))
+		err := mgr.Load()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "api-max-retries")
+	})
+
+	t.Run("help", func(t *testing.T) {
+		fs := flag.NewFlagSet("app", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		mgr := conf.NewManager(&FlagConfig{}, conf.WithFlags(fs, []string{"-h"}))
+		assert.ErrorIs(t, mgr.Load(), flag.ErrHelp)
+	})
+}
+
+func TestConfigManager_Dump(t *testing.T) {
+	os.Clearenv()
+	t.Cleanup(os.Clearenv)
+	require.NoError(t, os.Setenv("API_MAX_RETRIES", "7"))
+
+	type DumpConfig struct {
+		Hop conf.HopConfig
+		API struct {
+			Endpoint   string `json:"endpoint" default:"http://api.local"`
+			MaxRetries int    `json:"max_retries" default:"3"`
+			Token      string `json:"token" conf:"secret"`
+			Password   string `json:"password" secret:"true"`
+		} `json:"api"`
+	}
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"api": {"endpoint": "https://file.example.com", "token": "tok-123456", "password": "hunter22"}}`), 0o600))
+
+	mgr := conf.NewManager(&DumpConfig{}, conf.WithConfigFile(file))
+	require.NoError(t, mgr.Load())
+
+	assert.Equal(t, "file:"+file, mgr.Source("API.Endpoint"))
+	assert.Equal(t, conf.SourceEnv, mgr.Source("API.MaxRetries"))
+	assert.Equal(t, conf.SourceDefault, mgr.Source("Hop.Server.Port"))
+
+	var text strings.Builder
+	require.NoError(t, mgr.Dump(&text, conf.DumpText))
+	assert.Regexp(t, `API\.Endpoint\s+= "https://file\.example\.com"\s+# file:`, text.String())
+	assert.Regexp(t, `API\.MaxRetries\s+= 7\s+# env`, text.String())
+	assert.Regexp(t, `Hop\.Server\.Port\s+= 4444\s+# default`, text.String())
+	assert.NotContains(t, text.String(), "tok-123456")
+	assert.NotContains(t, text.String(), "hunter22")
+
+	var out bytes.Buffer
+	require.NoError(t, mgr.Dump(&out, conf.DumpJSON))
+	assert.NotContains(t, out.String(), "tok-123456")
+
+	var entries []struct {
+		Path   string `json:"path"`
+		Value  any    `json:"value"`
+		Source string `json:"source"`
+		Secret bool   `json:"secret"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entries))
+	for _, e := range entries {
+		switch e.Path {
+		case "API.MaxRetries":
+			assert.Equal(t, float64(7), e.Value)
+			assert.Equal(t, "env", e.Source)
+		case "API.Token":
+			assert.True(t, e.Secret)
+			assert.Equal(t, "file:"+file, e.Source)
+		}
+	}
+
+	assert.Error(t, mgr.Dump(io.Discard, "yaml"))
+}
+
+func TestConfigManager_ExplicitZeroOverridesDefault(t *testing.T) {
+	os.Clearenv()
+	t.Cleanup(os.Clearenv)
+	require.NoError(t, os.Setenv("API_ENABLE_CACHE", "false"))
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"api": {"max_retries": 0, "endpoint": ""}}`), 0o600))
+
+	cfg := &TestConfig{}
+	require.NoError(t, conf.NewManager(cfg, conf.WithConfigFile(file)).Load())
+
+	assert.Equal(t, 0, cfg.API.MaxRetries)
+	assert.Equal(t, "", cfg.API.Endpoint)
+	assert.False(t, cfg.API.EnableCache)
+	assert.Equal(t, 30*time.Second, cfg.API.Timeout.Duration, "fields left unset keep their default")
+}
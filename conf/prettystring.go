@@ -54,7 +54,7 @@ func prettyPrint(val reflect.Value, prefix string, sb *strings.Builder) {
 // formatValue returns the formatted value, masking sensitive data
 func formatValue(field reflect.Value, fieldType reflect.StructField) string {
 	// Check for secret tag
-	if _, isSecret := fieldType.Tag.Lookup("secret"); isSecret {
+	if isSecret(fieldType) {
 		return maskValue(field)
 	}
 
@@ -80,6 +80,19 @@ func formatValue(field reflect.Value, fieldType reflect.StructField) string {
 	}
 }
 
+// isSecret reports whether a field is tagged `secret` or `conf:"secret"`
+func isSecret(fieldType reflect.StructField) bool {
+	if _, ok := fieldType.Tag.Lookup("secret"); ok {
+		return true
+	}
+	for _, opt := range strings.Split(fieldType.Tag.Get("conf"), ",") {
+		if strings.TrimSpace(opt) == "secret" {
+			return true
+		}
+	}
+	return false
+}
+
 // maskValue returns a masked version of the value
 func maskValue(field reflect.Value) string {
 	switch field.Kind() {
@@ -0,0 +1,187 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickward/hop/conf/conftype"
+)
+
+// FieldError is a configuration value breaking a rule of its validate tag
+type FieldError struct {
+	// Path is the dotted path of the field, as printed by PrettyString, e.g. "Hop.Server.Port"
+	Path string
+	// Rule is the broken rule, e.g. "max=65535"
+	Rule string
+	// Message describes the problem
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors lists every field breaking its validate tag
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateStruct checks the fields of cfg, a struct or a pointer to one, against the rules of their
+// validate tags, recursing into nested structs. Rules are separated by commas:
+//
+//	Port    int               `validate:"required,min=1,max=65535"`
+//	Mode    string            `validate:"oneof=dev prod"`
+//	Timeout conftype.Duration `validate:"min=1s,max=5m"`
+//	Replica string            `validate:"omitempty,min=3"`
+//
+// The rules are:
+//
+//   - required: the value is not the zero value
+//   - omitempty: skip the other rules when the value is the zero value
+//   - min=N, max=N: bounds of numbers, durations (e.g. "30s") and byte sizes (e.g. "10MB"), or of the
+//     length of strings, slices and maps
+//   - oneof=a b c: the value, formatted with fmt, is one of the space-separated values
+//
+// Every broken rule is reported, as ValidationErrors, so all the problems of a configuration can be fixed
+// at once. Malformed tags are reported as errors too.
+func ValidateStruct(cfg interface{}) error {
+	val := reflect.ValueOf(cfg)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("configuration must be a struct")
+	}
+
+	var errs ValidationErrors
+	validateStructFields(val, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateStructFields checks the fields of a struct, recursing into nested structs like prettyPrint
+func validateStructFields(val reflect.Value, prefix string, errs *ValidationErrors) {
+	typ := val.Type()
+
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		fieldType := typ.Field(i)
+
+		// Skip unexported fields
+		if !field.CanInterface() {
+			continue
+		}
+
+		fieldName := fieldType.Name
+		if prefix != "" {
+			fieldName = prefix + "." + fieldName
+		}
+
+		if tag, ok := fieldType.Tag.Lookup("validate"); ok {
+			validateField(field, fieldName, tag, errs)
+		}
+
+		if field.Kind() == reflect.Struct && field.Type() != reflect.TypeOf(conftype.Duration{}) {
+			validateStructFields(field, fieldName, errs)
+		}
+	}
+}
+
+// validateField checks a value against the rules of its tag
+func validateField(field reflect.Value, path, tag string, errs *ValidationErrors) {
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		name, arg, _ := strings.Cut(rule, "=")
+
+		var msg string
+		switch name {
+		case "":
+			continue
+		case "omitempty":
+			if field.IsZero() {
+				return
+			}
+		case "required":
+			if field.IsZero() {
+				msg = "is required"
+			}
+		case "min", "max":
+			msg = checkBound(field, name, arg)
+		case "oneof":
+			msg = checkOneOf(field, arg)
+		default:
+			msg = fmt.Sprintf("unknown validation rule %q", name)
+		}
+
+		if msg != "" {
+			*errs = append(*errs, FieldError{Path: path, Rule: rule, Message: msg})
+		}
+	}
+}
+
+// checkBound checks a min or max rule, returning the problem or ""
+func checkBound(field reflect.Value, rule, arg string) string {
+	var value, bound float64
+	var unit string
+	var err error
+
+	switch {
+	case field.Type() == reflect.TypeOf(conftype.Duration{}):
+		var d time.Duration
+		d, err = time.ParseDuration(arg)
+		value, bound = float64(field.Interface().(conftype.Duration).Duration), float64(d)
+	case field.Type() == reflect.TypeOf(conftype.ByteSize(0)):
+		var b conftype.ByteSize
+		err = b.ParseString(arg)
+		value, bound = float64(field.Int()), float64(b)
+	default:
+		bound, err = strconv.ParseFloat(arg, 64)
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			value = float64(field.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value = float64(field.Uint())
+		case reflect.Float32, reflect.Float64:
+			value = field.Float()
+		case reflect.String, reflect.Slice, reflect.Map:
+			value = float64(field.Len())
+			unit = " in length"
+		default:
+			return fmt.Sprintf("rule %s does not apply to %s values", rule, field.Type())
+		}
+	}
+	if err != nil {
+		return fmt.Sprintf("invalid %s rule argument %q", rule, arg)
+	}
+
+	if rule == "min" && value < bound {
+		return fmt.Sprintf("must be at least %s%s", arg, unit)
+	}
+	if rule == "max" && value > bound {
+		return fmt.Sprintf("must be at most %s%s", arg, unit)
+	}
+	return ""
+}
+
+// checkOneOf checks a oneof rule, returning the problem or ""
+func checkOneOf(field reflect.Value, arg string) string {
+	allowed := strings.Fields(arg)
+	value := fmt.Sprint(field.Interface())
+	for _, a := range allowed {
+		if value == a {
+			return ""
+		}
+	}
+	return fmt.Sprintf("must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
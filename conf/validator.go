@@ -1,11 +1,14 @@
 package conf
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 )
 
-// Validator interface allows configs to implement their own validation
+// Validator interface allows configs to implement their own validation, for rules the validate tags can't
+// express, such as rules spanning several fields. It runs after the tags are checked, with their errors
+// joined to its own.
 type Validator interface {
 	Validate() error
 }
@@ -13,20 +16,27 @@ type Validator interface {
 // HopConfigValidator implements core framework validation
 type HopConfigValidator struct{}
 
+// Validate checks that cfg embeds HopConfig, then checks its validate tags (see ValidateStruct) and calls
+// its Validate method if it implements Validator
+
 func (v *HopConfigValidator) Validate(cfg interface{}) error {
 	// First check if config has framework configuration
 	if err := v.validateHopConfig(cfg); err != nil {
 		return fmt.Errorf("hop framework validation failed: %w", err)
 	}
 
-	// Then check if the config implements Validator interface
+	// Then check the validate tags of every field, and the Validator interface for rules spanning fields
+	var errs []error
+	if err := ValidateStruct(cfg); err != nil {
+		errs = append(errs, fmt.Errorf("invalid configuration: %w", err))
+	}
 	if validator, ok := cfg.(Validator); ok {
 		if err := validator.Validate(); err != nil {
-			return fmt.Errorf("application validation failed: %w", err)
+			errs = append(errs, fmt.Errorf("application validation failed: %w", err))
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // validateHopConfig ensures required Hop framework config is present
@@ -127,3 +127,111 @@ func TestConfigValidation(t *testing.T) {
 		})
 	}
 }
+
+type TaggedConfig struct {
+	Hop conf.HopConfig `json:"hop"`
+	API struct {
+		Endpoint string              `json:"endpoint" validate:"required"`
+		Mode     string              `json:"mode" default:"dev" validate:"oneof=dev prod"`
+		Retries  int                 `json:"retries" default:"3" validate:"min=1,max=10"`
+		Timeout  conftype.Duration   `json:"timeout" default:"30s" validate:"min=1s,max=5m"`
+		MaxBody  conftype.ByteSize   `json:"max_body" default:"1MB" validate:"max=10MB"`
+		Replica  string              `json:"replica" validate:"omitempty,min=3"`
+		Tags     conftype.StringList `json:"tags" validate:"max=2"`
+	} `json:"api"`
+	Limit int `json:"limit" default:"5"`
+	Burst int `json:"burst" default:"10"`
+}
+
+func (c *TaggedConfig) Validate() error {
+	if c.Burst < c.Limit {
+		return fmt.Errorf("burst must be at least the limit")
+	}
+	return nil
+}
+
+func TestConfigValidationTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		errors []string
+	}{
+		{
+			name: "valid",
+			env:  map[string]string{"API_ENDPOINT": "https://api.example.com"},
+		},
+		{
+			name: "every broken rule is reported",
+			env: map[string]string{
+				"API_MODE":     "staging",
+				"API_RETRIES":  "0",
+				"API_TIMEOUT":  "10m",
+				"API_MAX_BODY": "20MB",
+				"API_REPLICA":  "ab",
+				"API_TAGS":     "a,b,c",
+				"BURST":        "1",
+			},
+			errors: []string{
+				"API.Endpoint: is required",
+				`API.Mode: must be one of dev, prod, got "staging"`,
+				"API.Retries: must be at least 1",
+				"API.Timeout: must be at most 5m",
+				"API.MaxBody: must be at most 10MB",
+				"API.Replica: must be at least 3 in length",
+				"API.Tags: must be at most 2 in length",
+				"burst must be at least the limit",
+			},
+		},
+		{
+			name: "hop config rules",
+			env: map[string]string{
+				"API_ENDPOINT":       "https://api.example.com",
+				"HOP_SERVER_PORT":    "70000",
+				"HOP_SERVER_NETWORK": "udp",
+			},
+			errors: []string{
+				"Hop.Server.Port: must be at most 65535",
+				`Hop.Server.Network: must be one of tcp, unix, got "udp"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			t.Cleanup(os.Clearenv)
+			for k, v := range tt.env {
+				require.NoError(t, os.Setenv(k, v))
+			}
+
+			err := conf.NewManager(&TaggedConfig{}).Load()
+			if len(tt.errors) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			for _, msg := range tt.errors {
+				assert.Contains(t, err.Error(), msg)
+			}
+
+			var validationErrs conf.ValidationErrors
+			if assert.ErrorAs(t, err, &validationErrs) {
+				assert.NotEmpty(t, validationErrs[0].Rule)
+			}
+		})
+	}
+
+	t.Run("malformed rules", func(t *testing.T) {
+		var cfg struct {
+			A int  `validate:"min=abc"`
+			B bool `validate:"max=1"`
+			C int  `validate:"between=1 2"`
+		}
+		err := conf.ValidateStruct(&cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `A: invalid min rule argument "abc"`)
+		assert.Contains(t, err.Error(), "B: rule max does not apply to bool values")
+		assert.Contains(t, err.Error(), `C: unknown validation rule "between"`)
+	})
+}
@@ -0,0 +1,274 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+	"github.com/patrickward/hop/conf/conftype"
+	"github.com/patrickward/hop/dispatch"
+)
+
+// EventConfigReloaded is emitted with a Change payload when a reload changed the configuration
+const EventConfigReloaded = "config.reloaded"
+
+// DefaultWatchInterval is how often Watch checks the configuration files and environment by default
+const DefaultWatchInterval = 2 * time.Second
+
+// Change describes a reload that changed the configuration
+type Change struct {
+	// Old is a copy of the configuration before the reload
+	Old interface{}
+	// New is a copy of the configuration after the reload
+	New interface{}
+	// Fields lists the changed values
+	Fields []FieldChange
+}
+
+// Changed reports whether the value at path, or any value below it, changed, e.g. "Hop.Log.Level" or
+// "Hop.Log"
+func (c Change) Changed(path string) bool {
+	for _, f := range c.Fields {
+		if f.Path == path || (len(f.Path) > len(path) && f.Path[:len(path)] == path && f.Path[len(path)] == '.') {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldChange is a changed configuration value
+type FieldChange struct {
+	// Path is the dotted path of the field, as printed by PrettyString, e.g. "Hop.Log.Level"
+	Path string
+	// Old is the value before the reload
+	Old interface{}
+	// New is the value after the reload
+	New interface{}
+	// Secret is set for fields tagged `secret` or `conf:"secret"`, whose values String masks
+	Secret bool
+}
+
+// String describes the change, masking secret values
+func (f FieldChange) String() string {
+	if f.Secret {
+		return fmt.Sprintf("%s: %s -> %s", f.Path, maskValue(reflect.ValueOf(f.Old)), maskValue(reflect.ValueOf(f.New)))
+	}
+	return fmt.Sprintf("%s: %v -> %v", f.Path, f.Old, f.New)
+}
+
+// Diff returns the values that differ between two configurations of the same type
+func Diff(oldCfg, newCfg interface{}) []FieldChange {
+	oldVal, newVal := reflect.ValueOf(oldCfg), reflect.ValueOf(newCfg)
+	if oldVal.Kind() == reflect.Ptr {
+		oldVal = oldVal.Elem()
+	}
+	if newVal.Kind() == reflect.Ptr {
+		newVal = newVal.Elem()
+	}
+	if oldVal.Type() != newVal.Type() || oldVal.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var changes []FieldChange
+	diffStruct(oldVal, newVal, "", &changes)
+	return changes
+}
+
+// diffStruct appends the changed fields of two structs, recursing into nested structs like prettyPrint
+func diffStruct(oldVal, newVal reflect.Value, prefix string, changes *[]FieldChange) {
+	typ := oldVal.Type()
+
+	for i := 0; i < oldVal.NumField(); i++ {
+		oldField, newField := oldVal.Field(i), newVal.Field(i)
+		fieldType := typ.Field(i)
+
+		// Skip unexported fields
+		if !oldField.CanInterface() {
+			continue
+		}
+
+		fieldName := fieldType.Name
+		if prefix != "" {
+			fieldName = prefix + "." + fieldName
+		}
+
+		if oldField.Kind() == reflect.Struct && oldField.Type() != reflect.TypeOf(conftype.Duration{}) {
+			diffStruct(oldField, newField, fieldName, changes)
+			continue
+		}
+
+		if !reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			*changes = append(*changes, FieldChange{
+				Path:   fieldName,
+				Old:    oldField.Interface(),
+				New:    newField.Interface(),
+				Secret: isSecret(fieldType),
+			})
+		}
+	}
+}
+
+// watchConfig holds the options of Watch
+type watchConfig struct {
+	interval time.Duration
+	logger   *slog.Logger
+	clock    clock.Clock
+	events   *dispatch.Dispatcher
+}
+
+// WithWatchInterval sets how often Watch checks the configuration files and environment for changes.
+// Default is DefaultWatchInterval.
+func WithWatchInterval(d time.Duration) Option {
+	return func(m *Manager) {
+		if d > 0 {
+			m.watch.interval = d
+		}
+	}
+}
+
+// WithLogger sets the logger Watch reports reloads and failed reloads to. Default is slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Manager) {
+		if logger != nil {
+			m.watch.logger = logger
+		}
+	}
+}
+
+// WithClock sets the clock Watch waits with, e.g. a hoptest.Clock in tests
+func WithClock(c clock.Clock) Option {
+	return func(m *Manager) {
+		m.watch.clock = clock.OrReal(c)
+	}
+}
+
+// WithEvents emits EventConfigReloaded on the dispatcher when a reload changed the configuration
+func WithEvents(events *dispatch.Dispatcher) Option {
+	return func(m *Manager) {
+		m.watch.events = events
+	}
+}
+
+// Subscribe returns a channel receiving the changes of later reloads, and a function to unsubscribe. The
+// channel holds the latest change: when a subscriber falls behind, older undelivered changes are dropped,
+// so subscribers should read the current values from Change.New or Get.
+func (m *Manager) Subscribe() (<-chan Change, func()) {
+	ch := make(chan Change, 1)
+
+	m.mu.Lock()
+	m.subs = append(m.subs, ch)
+	m.mu.Unlock()
+
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, sub := range m.subs {
+			if sub == ch {
+				m.subs = append(m.subs[:i], m.subs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// notify sends a change to the subscribers and the dispatcher
+func (m *Manager) notify(change Change) {
+	m.mu.RLock()
+	for _, ch := range m.subs {
+		select {
+		case ch <- change:
+		default:
+			// Replace the undelivered change with the latest one
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- change:
+			default:
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	if m.watch.events != nil {
+		if err := m.watch.events.Emit(context.Background(), EventConfigReloaded, change); err != nil {
+			m.watch.logger.Error("Failed to emit config reload event", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// Watch checks the configuration files and environment variables for changes every watch interval until
+// ctx is done, and reloads the configuration when they change. Invalid configurations are logged and
+// skipped, keeping the current one, so a half-saved file can't take the app down. Changes are delivered
+// to the subscribers and as EventConfigReloaded, e.g. to apply a new log level without a restart:
+//
+//	go func() { _ = manager.Watch(ctx) }()
+//
+//	changes, unsubscribe := manager.Subscribe()
+//	defer unsubscribe()
+//	for change := range changes {
+//	    if change.Changed("Hop.Log.Level") {
+//	        levelVar.Set(parseLevel(change.New.(*Config).Hop.Log.Level))
+//	    }
+//	}
+//
+// Watch blocks and returns ctx's error once ctx is done.
+func (m *Manager) Watch(ctx context.Context) error {
+	last := m.fingerprint()
+
+	ticker := m.watch.clock.NewTicker(m.watch.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+
+		current := m.fingerprint()
+		if current == last {
+			continue
+		}
+		last = current
+
+		if err := m.Reload(); err != nil {
+			m.watch.logger.Error("Failed to reload config, keeping the current one", slog.String("error", err.Error()))
+			continue
+		}
+		m.watch.logger.Info("Config reloaded")
+	}
+}
+
+// fingerprint hashes the content of the configuration files and the environment, to detect changes
+// without parsing them
+func (m *Manager) fingerprint() uint64 {
+	h := fnv.New64a()
+
+	var paths []string
+	if m.discovery != nil {
+		paths = append(paths, m.discovery.paths()...)
+	}
+	paths = append(paths, m.files...)
+	for _, path := range paths {
+		_, _ = fmt.Fprintf(h, "%s\x00", path)
+		if data, err := os.ReadFile(path); err == nil {
+			_, _ = h.Write(data)
+		}
+	}
+
+	env := os.Environ()
+	sort.Strings(env)
+	for _, kv := range env {
+		_, _ = fmt.Fprintf(h, "%s\x00", kv)
+	}
+
+	return h.Sum64()
+}
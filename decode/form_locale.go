@@ -0,0 +1,237 @@
+package decode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/form/v4"
+)
+
+// localeDecoders caches a form decoder per locale tag
+var localeDecoders sync.Map
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// FormLocale decodes the form values in an HTTP request into a struct, parsing numbers and dates in
+// the request locale (see LocaleFromRequest). For example, "1.234,56" and "31.12.2025" are decoded
+// into a float64 and a time.Time for a de-DE request.
+//
+// A field can override the locale with a format tag. For time.Time fields the tag is a time layout,
+// and for numeric fields it is a locale tag:
+//
+//	type Invoice struct {
+//	    Amount float64   `form:"amount"`
+//	    Due    time.Time `form:"due" format:"2006-01-02"`
+//	    Rate   float64   `form:"rate" format:"en-US"`
+//	}
+func FormLocale(r *http.Request, dst any) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	return decodeLocalizedValues(r.Form, dst, LocaleFromRequest(r))
+}
+
+// PostFormLocale decodes the POST form values in an HTTP request into a struct, parsing numbers and
+// dates in the request locale. See FormLocale.
+func PostFormLocale(r *http.Request, dst any) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	return decodeLocalizedValues(r.PostForm, dst, LocaleFromRequest(r))
+}
+
+func decodeLocalizedValues(values url.Values, dst any, l Locale) error {
+	values, err := applyFieldFormats(values, reflect.TypeOf(dst), "", l)
+	if err != nil {
+		return err
+	}
+
+	err = localeDecoder(l).Decode(dst, values)
+	if err != nil {
+		var invalidDecoderError *form.InvalidDecoderError
+
+		if errors.As(err, &invalidDecoderError) {
+			panic(err)
+		}
+	}
+
+	return err
+}
+
+// localeDecoder returns a form decoder that parses numbers and dates in the locale
+func localeDecoder(l Locale) *form.Decoder {
+	if d, ok := localeDecoders.Load(l.Tag); ok {
+		return d.(*form.Decoder)
+	}
+
+	d := form.NewDecoder()
+	d.RegisterCustomTypeFunc(func(vals []string) (any, error) {
+		if vals[0] == "" {
+			return float64(0), nil
+		}
+		return l.ParseNumber(vals[0])
+	}, float64(0))
+	d.RegisterCustomTypeFunc(func(vals []string) (any, error) {
+		if vals[0] == "" {
+			return float32(0), nil
+		}
+		n, err := l.ParseNumber(vals[0])
+		return float32(n), err
+	}, float32(0))
+	for _, zero := range []any{int(0), int8(0), int16(0), int32(0), int64(0), uint(0), uint8(0), uint16(0), uint32(0), uint64(0)} {
+		typ := reflect.TypeOf(zero)
+		d.RegisterCustomTypeFunc(func(vals []string) (any, error) {
+			if vals[0] == "" {
+				return zero, nil
+			}
+			return parseInteger(l, vals[0], typ)
+		}, zero)
+	}
+	d.RegisterCustomTypeFunc(func(vals []string) (any, error) {
+		if vals[0] == "" {
+			return time.Time{}, nil
+		}
+		return l.ParseDate(vals[0])
+	}, time.Time{})
+
+	actual, _ := localeDecoders.LoadOrStore(l.Tag, d)
+	return actual.(*form.Decoder)
+}
+
+// parseInteger parses a whole number written in the locale, such as "1.234" in de-DE, into a value of typ
+func parseInteger(l Locale, s string, typ reflect.Type) (any, error) {
+	n, err := l.ParseNumber(s)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.New(typ).Elem()
+	switch {
+	case n != math.Trunc(n):
+		return nil, fmt.Errorf("%w: %q is not a whole number", ErrInvalidNumber, s)
+	case typ.Kind() >= reflect.Uint && typ.Kind() <= reflect.Uint64:
+		if n < 0 || v.OverflowUint(uint64(n)) || n >= math.MaxUint64 {
+			return nil, fmt.Errorf("%w: %q is out of range", ErrInvalidNumber, s)
+		}
+		v.SetUint(uint64(n))
+	default:
+		if n >= math.MaxInt64 || n < math.MinInt64 || v.OverflowInt(int64(n)) {
+			return nil, fmt.Errorf("%w: %q is out of range", ErrInvalidNumber, s)
+		}
+		v.SetInt(int64(n))
+	}
+	return v.Interface(), nil
+}
+
+// applyFieldFormats rewrites the values of fields with a format tag into a form the locale decoder
+// accepts, returning a copy of values when any field is rewritten
+func applyFieldFormats(values url.Values, t reflect.Type, prefix string, l Locale) (url.Values, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return values, nil
+	}
+
+	copied := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("form"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		name = prefix + name
+
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		format, hasFormat := field.Tag.Lookup("format")
+		if !hasFormat {
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				var err error
+				if values, err = applyFieldFormats(values, ft, name+".", l); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		raw, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		rewritten := make([]string, len(raw))
+		for j, v := range raw {
+			if strings.TrimSpace(v) == "" {
+				continue
+			}
+
+			switch {
+			case ft == timeType:
+				parsed, err := parseDate(strings.TrimSpace(v), format)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: %w", name, err)
+				}
+				rewritten[j] = parsed.Format(time.RFC3339)
+			case isNumericKind(ft.Kind()):
+				fieldLocale, ok := LookupLocale(format)
+				if !ok {
+					return nil, fmt.Errorf("field %s: unknown locale %q", name, format)
+				}
+				n, err := fieldLocale.ParseNumber(v)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: %w", name, err)
+				}
+				// Write the number back in the request locale so the decoder parses it unambiguously
+				rewritten[j] = l.FormatNumber(n, -1)
+			default:
+				rewritten[j] = v
+			}
+		}
+
+		if !copied {
+			values = cloneValues(values)
+			copied = true
+		}
+		values[name] = rewritten
+	}
+
+	return values, nil
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func cloneValues(values url.Values) url.Values {
+	out := make(url.Values, len(values))
+	for k, v := range values {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
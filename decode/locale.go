@@ -0,0 +1,278 @@
+package decode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// ErrInvalidNumber is returned when a value is not a valid number in the locale
+var ErrInvalidNumber = errors.New("invalid number")
+
+// ErrInvalidDate is returned when a value does not match any of the locale's date layouts
+var ErrInvalidDate = errors.New("invalid date")
+
+// Locale describes how numbers and dates are written in a locale
+type Locale struct {
+	// Tag is the BCP 47 language tag, e.g. "de-DE"
+	Tag string
+	// Decimal is the decimal separator, e.g. "," in "1.234,56"
+	Decimal string
+	// Group is the digit grouping separator, e.g. "." in "1.234,56"
+	Group string
+	// DateLayouts are the time layouts accepted for dates. The first layout is used for formatting.
+	DateLayouts []string
+}
+
+var (
+	localesMu sync.RWMutex
+	locales   = map[string]Locale{
+		"en-US": {Tag: "en-US", Decimal: ".", Group: ",", DateLayouts: []string{"01/02/2006", "1/2/2006"}},
+		"en-GB": {Tag: "en-GB", Decimal: ".", Group: ",", DateLayouts: []string{"02/01/2006", "2/1/2006"}},
+		"de-DE": {Tag: "de-DE", Decimal: ",", Group: ".", DateLayouts: []string{"02.01.2006", "2.1.2006"}},
+		"fr-FR": {Tag: "fr-FR", Decimal: ",", Group: "\u202f", DateLayouts: []string{"02/01/2006", "2/1/2006"}},
+		"es-ES": {Tag: "es-ES", Decimal: ",", Group: ".", DateLayouts: []string{"02/01/2006", "2/1/2006"}},
+		"it-IT": {Tag: "it-IT", Decimal: ",", Group: ".", DateLayouts: []string{"02/01/2006", "2/1/2006"}},
+		"nl-NL": {Tag: "nl-NL", Decimal: ",", Group: ".", DateLayouts: []string{"02-01-2006", "2-1-2006"}},
+		"pt-BR": {Tag: "pt-BR", Decimal: ",", Group: ".", DateLayouts: []string{"02/01/2006", "2/1/2006"}},
+		"ja-JP": {Tag: "ja-JP", Decimal: ".", Group: ",", DateLayouts: []string{"2006/01/02", "2006/1/2"}},
+	}
+)
+
+// DefaultLocale is used when no supported locale can be determined for a request
+var DefaultLocale = locales["en-US"]
+
+// RegisterLocale adds or replaces a locale
+func RegisterLocale(l Locale) {
+	localesMu.Lock()
+	defer localesMu.Unlock()
+	locales[l.Tag] = l
+	localeDecoders.Delete(l.Tag)
+}
+
+// LookupLocale returns the locale for a language tag. When there is no exact match, the first
+// registered locale with the same base language is used, so "de-AT" falls back to "de-DE".
+func LookupLocale(tag string) (Locale, bool) {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+
+	if l, ok := locales[tag]; ok {
+		return l, true
+	}
+
+	t, err := language.Parse(tag)
+	if err != nil {
+		return Locale{}, false
+	}
+	if l, ok := locales[t.String()]; ok {
+		return l, true
+	}
+
+	base, _ := t.Base()
+	var (
+		match Locale
+		found bool
+	)
+	for key, l := range locales {
+		if strings.HasPrefix(key, base.String()+"-") && (!found || key < match.Tag) {
+			match, found = l, true
+		}
+	}
+	return match, found
+}
+
+type localeContextKey struct{}
+
+// WithLocale returns a context that makes LocaleFromRequest use the given locale, e.g. from a user
+// preference, instead of the Accept-Language header
+func WithLocale(ctx context.Context, l Locale) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, l)
+}
+
+// LocaleFromRequest returns the locale for a request: the locale set with WithLocale, or the best
+// supported match from the Accept-Language header, or DefaultLocale.
+func LocaleFromRequest(r *http.Request) Locale {
+	if l, ok := r.Context().Value(localeContextKey{}).(Locale); ok {
+		return l
+	}
+
+	for _, tag := range acceptLanguages(r.Header.Get("Accept-Language")) {
+		if l, ok := LookupLocale(tag); ok {
+			return l
+		}
+	}
+
+	return DefaultLocale
+}
+
+// acceptLanguages returns the language tags in an Accept-Language header, ordered by quality
+func acceptLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil || parsed <= 0 {
+				continue
+			}
+			q = parsed
+		}
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.tag
+	}
+	return out
+}
+
+// ParseNumber parses a number written in the locale, such as "1.234,56" in de-DE. Digit grouping is
+// optional, but must be in groups of three when present. Plain numbers such as "1234.56", which browsers
+// send for number inputs, are accepted in every locale when they are not valid in the locale itself.
+func (l Locale) ParseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidNumber
+	}
+
+	if n, ok := l.parseLocalized(s); ok {
+		return n, nil
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidNumber, s)
+	}
+	return n, nil
+}
+
+// parseLocalized parses s using the locale separators, validating the digit grouping
+func (l Locale) parseLocalized(s string) (float64, bool) {
+	sign := ""
+	if s[0] == '-' || s[0] == '+' {
+		sign, s = s[:1], s[1:]
+	}
+
+	intPart, fracPart, hasDecimal := strings.Cut(s, l.Decimal)
+	if hasDecimal && (fracPart == "" || !isDigits(fracPart)) {
+		return 0, false
+	}
+
+	groups := []string{intPart}
+	if l.Group != "" {
+		groups = strings.Split(normalizeSpaces(intPart, l.Group), l.Group)
+	}
+	if len(groups) > 1 {
+		if len(groups[0]) == 0 || len(groups[0]) > 3 {
+			return 0, false
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				return 0, false
+			}
+		}
+	}
+
+	digits := strings.Join(groups, "")
+	if digits == "" || !isDigits(digits) {
+		return 0, false
+	}
+
+	canonical := sign + digits
+	if hasDecimal {
+		canonical += "." + fracPart
+	}
+
+	n, err := strconv.ParseFloat(canonical, 64)
+	return n, err == nil
+}
+
+// ParseDate parses a date written in the locale. ISO 8601 dates ("2006-01-02"), which browsers send
+// for date inputs, are accepted in every locale.
+func (l Locale) ParseDate(s string) (time.Time, error) {
+	return parseDate(strings.TrimSpace(s), l.DateLayouts...)
+}
+
+// FormatNumber formats a number in the locale with the given number of decimals, for redisplaying
+// a parsed value in a form
+func (l Locale) FormatNumber(n float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	intPart, fracPart, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if n < 0 {
+		b.WriteByte('-')
+	}
+	for i, d := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteRune(d)
+	}
+	if fracPart != "" {
+		b.WriteString(l.Decimal)
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+// FormatDate formats a date with the locale's first date layout
+func (l Locale) FormatDate(t time.Time) string {
+	if t.IsZero() || len(l.DateLayouts) == 0 {
+		return ""
+	}
+	return t.Format(l.DateLayouts[0])
+}
+
+// parseDate parses s with the ISO layouts, then each of the given layouts
+func parseDate(s string, layouts ...string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, ErrInvalidDate
+	}
+
+	for _, layout := range append([]string{time.RFC3339, time.DateOnly, "2006-01-02T15:04"}, layouts...) {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidDate, s)
+}
+
+// normalizeSpaces replaces the space variants commonly typed in place of a space group separator
+func normalizeSpaces(s, group string) string {
+	if strings.TrimSpace(group) != "" {
+		return s
+	}
+	return strings.NewReplacer(" ", group, "\u00a0", group, "\u202f", group).Replace(s)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,176 @@
+package decode_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/decode"
+)
+
+func mustLocale(t *testing.T, tag string) decode.Locale {
+	t.Helper()
+	l, ok := decode.LookupLocale(tag)
+	require.True(t, ok, tag)
+	return l
+}
+
+func TestLocale_ParseNumber(t *testing.T) {
+	tests := []struct {
+		locale  string
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{locale: "en-US", input: "1,234.56", want: 1234.56},
+		{locale: "en-US", input: "1234.56", want: 1234.56},
+		{locale: "en-US", input: "-12", want: -12},
+		{locale: "de-DE", input: "1.234,56", want: 1234.56},
+		{locale: "de-DE", input: "1234,5", want: 1234.5},
+		{locale: "de-DE", input: "1.234", want: 1234},
+		{locale: "de-DE", input: "1234.56", want: 1234.56},
+		{locale: "fr-FR", input: "1 234,56", want: 1234.56},
+		{locale: "fr-FR", input: "1 234,56", want: 1234.56},
+		{locale: "en-US", input: "12,34", wantErr: true},
+		{locale: "en-US", input: "abc", wantErr: true},
+		{locale: "en-US", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.input, func(t *testing.T) {
+			got, err := mustLocale(t, tt.locale).ParseNumber(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, decode.ErrInvalidNumber)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestLocale_ParseDate(t *testing.T) {
+	want := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale  string
+		input   string
+		wantErr bool
+	}{
+		{locale: "en-US", input: "12/31/2025"},
+		{locale: "en-GB", input: "31/12/2025"},
+		{locale: "de-DE", input: "31.12.2025"},
+		{locale: "de-DE", input: "2025-12-31"},
+		{locale: "en-US", input: "31/12/2025", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.input, func(t *testing.T) {
+			got, err := mustLocale(t, tt.locale).ParseDate(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, decode.ErrInvalidDate)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, want.Equal(got), "got %s", got)
+		})
+	}
+}
+
+func TestLocale_Format(t *testing.T) {
+	date := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale     string
+		number     float64
+		decimals   int
+		wantNumber string
+		wantDate   string
+	}{
+		{locale: "en-US", number: 1234567.891, decimals: 2, wantNumber: "1,234,567.89", wantDate: "12/31/2025"},
+		{locale: "de-DE", number: -1234.5, decimals: 2, wantNumber: "-1.234,50", wantDate: "31.12.2025"},
+		{locale: "fr-FR", number: 999, decimals: 0, wantNumber: "999", wantDate: "31/12/2025"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			l := mustLocale(t, tt.locale)
+			assert.Equal(t, tt.wantNumber, l.FormatNumber(tt.number, tt.decimals))
+			assert.Equal(t, tt.wantDate, l.FormatDate(date))
+
+			// Formatted values round-trip
+			n, err := l.ParseNumber(l.FormatNumber(tt.number, tt.decimals))
+			require.NoError(t, err)
+			assert.InDelta(t, tt.number, n, 0.01)
+		})
+	}
+}
+
+func TestLocaleFromRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "exact match", acceptLanguage: "de-DE,de;q=0.9", want: "de-DE"},
+		{name: "base language fallback", acceptLanguage: "de-AT", want: "de-DE"},
+		{name: "quality order", acceptLanguage: "zz;q=0.9, fr;q=0.8, en-GB;q=0.5", want: "fr-FR"},
+		{name: "no match", acceptLanguage: "zz", want: "en-US"},
+		{name: "no header", want: "en-US"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			assert.Equal(t, tt.want, decode.LocaleFromRequest(req).Tag)
+		})
+	}
+
+	t.Run("context locale wins", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", "de-DE")
+		req = req.WithContext(decode.WithLocale(context.Background(), mustLocale(t, "ja-JP")))
+		assert.Equal(t, "ja-JP", decode.LocaleFromRequest(req).Tag)
+	})
+}
+
+type invoiceForm struct {
+	Amount   float64   `form:"amount"`
+	Quantity int       `form:"quantity"`
+	Issued   time.Time `form:"issued"`
+	Due      time.Time `form:"due" format:"2006/01/02"`
+	Rate     float64   `form:"rate" format:"en-US"`
+}
+
+func TestPostFormLocale(t *testing.T) {
+	body := url.Values{
+		"amount":   {"1.234,56"},
+		"quantity": {"1.000"},
+		"issued":   {"31.12.2025"},
+		"due":      {"2026/01/31"},
+		"rate":     {"1,000.5"},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept-Language", "de-DE")
+
+	var dst invoiceForm
+	require.NoError(t, decode.PostFormLocale(req, &dst))
+
+	assert.InDelta(t, 1234.56, dst.Amount, 1e-9)
+	assert.Equal(t, 1000, dst.Quantity)
+	assert.Equal(t, "2025-12-31", dst.Issued.Format(time.DateOnly))
+	assert.Equal(t, "2026-01-31", dst.Due.Format(time.DateOnly))
+	assert.InDelta(t, 1000.5, dst.Rate, 1e-9)
+}
@@ -2,63 +2,114 @@ package dispatch
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/patrickward/hop/clock"
 )
 
-var eventID atomic.Uint64
+// eventID is seeded with the start time so IDs stay unique across restarts, which matters when
+// events are persisted to an EventStore
+var eventID = func() *atomic.Uint64 {
+	var id atomic.Uint64
+	id.Store(uint64(time.Now().UnixNano()))
+	return &id
+}()
 
 // Dispatcher manages event publishing and subscription
 type Dispatcher struct {
-	handlers map[string][]Handler // key is the event signature
-	logger   *slog.Logger
-	mu       sync.RWMutex
+	handlers   map[string][]registration // key is the event signature
+	logger     *slog.Logger
+	mu         sync.RWMutex
+	pool       *workerPool
+	overflow   OverflowPolicy
+	middleware []EventMiddleware
+	store      EventStore
+	done       chan struct{}
+	closeMu    sync.RWMutex
+	closeOnce  sync.Once
+	scheduled  sync.WaitGroup
+	inflight   sync.WaitGroup
+	clock      clock.Clock
+
+	schemas          *SchemaRegistry
+	validatePayloads bool
 }
 
 // NewDispatcher creates a new event bus/dispatcher
-func NewDispatcher(logger *slog.Logger) *Dispatcher {
+func NewDispatcher(logger *slog.Logger, opts ...Option) *Dispatcher {
 	if logger == nil {
 		panic("logger is required for event bus")
 	}
 
-	return &Dispatcher{
-		handlers: make(map[string][]Handler),
+	b := &Dispatcher{
+		handlers: make(map[string][]registration),
 		logger:   logger,
+		done:     make(chan struct{}),
 	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.clock = clock.OrReal(b.clock)
+
+	if b.pool != nil {
+		b.pool.start(b)
+	}
+
+	return b
 }
 
-// On registers a handler for an event signature
+// On registers a handler for an event signature and returns a subscription that can be used to remove it.
 // Supports wildcards: "hop.*" or "*.system.start"
-func (b *Dispatcher) On(signature string, handler Handler) {
+func (b *Dispatcher) On(signature string, handler Handler) *Subscription {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if b.handlers[signature] == nil {
-		b.handlers[signature] = []Handler{}
+	sub := &Subscription{
+		id:         subscriptionID.Add(1),
+		signature:  signature,
+		dispatcher: b,
 	}
-	b.handlers[signature] = append(b.handlers[signature], handler)
+	b.handlers[signature] = append(b.handlers[signature], registration{id: sub.id, handler: handler})
 
 	source, eventType := parseSignature(signature)
 	b.logger.Debug("event handler registered",
 		slog.String("signature", signature),
 		slog.String("source", source),
 		slog.String("type", eventType))
+
+	return sub
 }
 
-// Emit sends an event to all registered handlers asynchronously
-func (b *Dispatcher) Emit(ctx context.Context, signature string, payload any) {
-	event := NewEvent(signature, payload)
-	b.mu.RLock()
-	var matchingHandlers []Handler
-	for pattern, handlers := range b.handlers {
-		if matchSignature(pattern, event.Signature) {
-			matchingHandlers = append(matchingHandlers, handlers...)
-		}
+// Emit sends an event to all registered handlers asynchronously.
+//
+// By default, each handler runs in its own goroutine. When the dispatcher is configured with a worker pool,
+// handlers are queued and run by the pool, and the overflow policy decides what happens when the queue is
+// full. Emit returns an error when the queue is full under OverflowError, when the context is done
+// while blocked under OverflowBlock, or with ErrClosed after Shutdown.
+func (b *Dispatcher) Emit(ctx context.Context, signature string, payload any) error {
+	// Hold the read lock until the handlers are started or queued, so Shutdown waits for them
+	b.closeMu.RLock()
+	defer b.closeMu.RUnlock()
+
+	if b.isClosed() {
+		return ErrClosed
 	}
-	b.mu.RUnlock()
+
+	if err := b.validatePayload(signature, payload); err != nil {
+		return err
+	}
+
+	event := NewEvent(signature, payload)
+	event.Timestamp = b.clock.Now().UTC()
+	matchingHandlers := b.matchingHandlers(event.Signature)
 
 	source, eventType := parseSignature(event.Signature)
 	b.logger.Debug("emitting event",
@@ -69,61 +120,183 @@ func (b *Dispatcher) Emit(ctx context.Context, signature string, payload any) {
 	if len(matchingHandlers) == 0 {
 		b.logger.Debug("no handlers for event",
 			slog.String("signature", event.Signature))
-		return
+		return nil
 	}
 
+	if b.store != nil {
+		if err := b.store.Save(ctx, event); err != nil {
+			return fmt.Errorf("dispatch: saving event: %w", err)
+		}
+		matchingHandlers = b.trackDispatched(matchingHandlers, event)
+	}
+
+	if b.pool != nil {
+		return b.enqueue(ctx, matchingHandlers, event)
+	}
+
+	b.inflight.Add(len(matchingHandlers))
 	for _, handler := range matchingHandlers {
 		h := handler // Capture handler for goroutine
 		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					b.logger.Error("panic in event handler",
-						slog.Any("panic", r),
-						slog.String("signature", event.Signature))
-				}
-			}()
-
-			h(ctx, event)
+			defer b.inflight.Done()
+			b.invokeAsync(ctx, h, event)
 		}()
 	}
+
+	return nil
 }
 
-// EmitSync sends an event and waits for all handlers to complete
-func (b *Dispatcher) EmitSync(ctx context.Context, signature string, payload any) {
+// EmitSync sends an event and waits for all handlers to complete. It returns the errors of all
+// handlers registered with OnWithError that failed, and of any handler that panicked, combined
+// with errors.Join. After Shutdown, EmitSync returns ErrClosed.
+func (b *Dispatcher) EmitSync(ctx context.Context, signature string, payload any) error {
+	b.closeMu.RLock()
+	if b.isClosed() {
+		b.closeMu.RUnlock()
+		return ErrClosed
+	}
+	b.inflight.Add(1)
+	b.closeMu.RUnlock()
+	defer b.inflight.Done()
+
+	if err := b.validatePayload(signature, payload); err != nil {
+		return err
+	}
+
 	event := NewEvent(signature, payload)
-	b.mu.RLock()
-	var matchingHandlers []Handler
-	for pattern, handlers := range b.handlers {
-		if matchSignature(pattern, event.Signature) {
-			matchingHandlers = append(matchingHandlers, handlers...)
+	event.Timestamp = b.clock.Now().UTC()
+	matchingHandlers := b.matchingHandlers(event.Signature)
+
+	if len(matchingHandlers) == 0 {
+		return nil
+	}
+
+	if b.store != nil {
+		if err := b.store.Save(ctx, event); err != nil {
+			b.logger.Error("failed to save event",
+				slog.String("signature", event.Signature),
+				slog.String("error", err.Error()))
 		}
 	}
-	b.mu.RUnlock()
 
-	if len(matchingHandlers) == 0 {
-		return
+	err := b.dispatchSync(ctx, event, matchingHandlers)
+
+	if b.store != nil {
+		b.markDispatched(event)
+	}
+
+	return err
+}
+
+// Shutdown stops the dispatcher from accepting new events and waits for running and queued handlers to
+// complete, or for ctx to be done. Pending scheduled events are canceled. After Shutdown, Emit, EmitSync,
+// EmitAfter, and EmitAt return ErrClosed. It is safe to call Shutdown more than once.
+func (b *Dispatcher) Shutdown(ctx context.Context) error {
+	b.closeOnce.Do(func() {
+		b.closeMu.Lock()
+		defer b.closeMu.Unlock()
+
+		close(b.done)
+		if b.pool != nil {
+			// Workers exit once the remaining queued handlers have run
+			close(b.pool.queue)
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		b.scheduled.Wait()
+		b.inflight.Wait()
+		if b.pool != nil {
+			b.pool.wg.Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		b.logger.Warn("timeout waiting for event handlers to complete")
+		return ctx.Err()
+	}
+}
+
+// isClosed reports whether Shutdown has been called
+func (b *Dispatcher) isClosed() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
 	}
+}
 
+// dispatchSync runs the handlers concurrently, waits for them to complete, and returns their
+// joined errors
+func (b *Dispatcher) dispatchSync(ctx context.Context, event Event, matchingHandlers []Handler) error {
 	var wg sync.WaitGroup
 	wg.Add(len(matchingHandlers))
 
-	for _, handler := range matchingHandlers {
+	errs := make([]error, len(matchingHandlers))
+	for i, handler := range matchingHandlers {
 		h := handler
 		go func() {
 			defer wg.Done()
-			defer func() {
-				if r := recover(); r != nil {
-					b.logger.Error("panic in event handler",
-						slog.Any("panic", r),
-						slog.String("signature", event.Signature))
-				}
-			}()
-
-			h(ctx, event)
+			errs[i] = b.invoke(ctx, h, event)
 		}()
 	}
 
 	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// invoke runs a single handler and returns its error, recovering from and logging any panic
+func (b *Dispatcher) invoke(ctx context.Context, h Handler, event Event) (err error) {
+	slot := &errorSlot{}
+	ctx = context.WithValue(ctx, errorSlotKey{}, slot)
+
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Error("panic in event handler",
+				slog.Any("panic", r),
+				slog.String("signature", event.Signature))
+			err = fmt.Errorf("dispatch: panic in handler for %s: %v", event.Signature, r)
+			return
+		}
+		err = slot.err
+	}()
+
+	h(ctx, event)
+	return nil
+}
+
+// invokeAsync runs a handler for an asynchronously emitted event, logging any error
+func (b *Dispatcher) invokeAsync(ctx context.Context, h Handler, event Event) {
+	err := b.invoke(ctx, h, event)
+	if err != nil {
+		b.logger.Error("event handler failed",
+			slog.String("signature", event.Signature),
+			slog.String("error", err.Error()))
+	}
+}
+
+// matchingHandlers returns the handlers registered for patterns matching the signature,
+// each wrapped with the dispatcher's middleware
+func (b *Dispatcher) matchingHandlers(signature string) []Handler {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var matching []Handler
+	for pattern, registrations := range b.handlers {
+		if matchSignature(pattern, signature) {
+			for _, reg := range registrations {
+				matching = append(matching, wrap(reg.handler, b.middleware))
+			}
+		}
+	}
+	return matching
 }
 
 // parseSignature splits a signature into source and event type
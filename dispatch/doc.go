@@ -60,6 +60,13 @@ Events can be emitted either asynchronously (non-blocking) or synchronously (blo
 	// Sync emission (waits for all handlers to complete)
 	dispatcher.EmitSync(ctx, "user.created", userData)
 
+Async emission can be bounded with a worker pool. The overflow policy decides whether Emit blocks,
+drops handlers, or returns ErrQueueFull when the queue is full:
+
+	dispatcher := dispatch.NewDispatcher(logger,
+	    dispatch.WithWorkerPool(8, 1000),
+	    dispatch.WithOverflowPolicy(dispatch.OverflowError))
+
 Context Support:
 
 All event handlers receive a context.Context that can be used for cancellation,
@@ -0,0 +1,37 @@
+package dispatch
+
+import (
+	"context"
+)
+
+// HandlerWithError processes an event and reports whether it failed
+type HandlerWithError func(ctx context.Context, event Event) error
+
+// errorSlotKey is the context key for the error slot of a handler invocation
+type errorSlotKey struct{}
+
+// errorSlot receives the error returned by a HandlerWithError during a single invocation
+type errorSlot struct {
+	err error
+}
+
+// OnWithError registers a handler that can fail. Errors are returned from EmitSync, joined with the
+// errors of any other failing handlers, and logged when the event was emitted asynchronously.
+//
+//	dispatcher.OnWithError("order.created", func(ctx context.Context, event dispatch.Event) error {
+//	    return mailer.SendConfirmation(ctx, event.Payload)
+//	})
+//
+//	if err := dispatcher.EmitSync(ctx, "order.created", order); err != nil {
+//	    // One or more side effects failed
+//	}
+func (b *Dispatcher) OnWithError(signature string, handler HandlerWithError) *Subscription {
+	return b.On(signature, func(ctx context.Context, event Event) {
+		err := handler(ctx, event)
+
+		// The last call wins, so middleware that retries the handler reports the final outcome
+		if slot, ok := ctx.Value(errorSlotKey{}).(*errorSlot); ok {
+			slot.err = err
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package dispatch_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+func TestDispatcher_EmitSyncJoinsErrors(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	errMail := errors.New("mail failed")
+	errAudit := errors.New("audit failed")
+
+	bus.OnWithError("order.created", func(ctx context.Context, event dispatch.Event) error {
+		return errMail
+	})
+	bus.OnWithError("order.*", func(ctx context.Context, event dispatch.Event) error {
+		return errAudit
+	})
+	bus.OnWithError("order.created", func(ctx context.Context, event dispatch.Event) error {
+		return nil
+	})
+	bus.On("order.created", func(ctx context.Context, event dispatch.Event) {})
+
+	err := bus.EmitSync(context.Background(), "order.created", nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errMail)
+	assert.ErrorIs(t, err, errAudit)
+}
+
+func TestDispatcher_EmitSyncNoErrors(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	bus.OnWithError("test.event", func(ctx context.Context, event dispatch.Event) error {
+		return nil
+	})
+
+	assert.NoError(t, bus.EmitSync(context.Background(), "test.event", nil))
+	assert.NoError(t, bus.EmitSync(context.Background(), "other.event", nil))
+}
+
+func TestDispatcher_EmitSyncReportsPanics(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+		panic("boom")
+	})
+
+	err := bus.EmitSync(context.Background(), "test.event", nil)
+	assert.ErrorContains(t, err, "boom")
+}
+
+func TestDispatcher_OnWithErrorThroughMiddleware(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	// A retrying middleware reports the outcome of the final attempt
+	bus.Use(func(next dispatch.Handler) dispatch.Handler {
+		return func(ctx context.Context, event dispatch.Event) {
+			next(ctx, event)
+			next(ctx, event)
+		}
+	})
+
+	attempts := 0
+	bus.OnWithError("test.event", func(ctx context.Context, event dispatch.Event) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+
+	assert.NoError(t, bus.EmitSync(context.Background(), "test.event", nil))
+	assert.Equal(t, 2, attempts)
+}
+
+func TestDispatcher_EmitLogsHandlerErrors(t *testing.T) {
+	buf := &syncBuffer{}
+	bus := dispatch.NewDispatcher(newTestLogger(buf))
+
+	done := make(chan struct{})
+	bus.OnWithError("test.event", func(ctx context.Context, event dispatch.Event) error {
+		defer close(done)
+		return errors.New("async failure")
+	})
+
+	require.NoError(t, bus.Emit(context.Background(), "test.event", nil))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not run")
+	}
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "async failure")
+	}, time.Second, 10*time.Millisecond)
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,31 @@
+package dispatch
+
+// EventMiddleware wraps a Handler with additional behavior, such as logging, metrics, tracing, or
+// payload validation. A middleware may skip the wrapped handler entirely by not calling it.
+type EventMiddleware func(next Handler) Handler
+
+// Use adds middleware that wraps every handler invocation, including handlers registered before
+// Use was called. Middleware run in the order they are added, so the first middleware added is the
+// outermost.
+//
+//	dispatcher.Use(func(next dispatch.Handler) dispatch.Handler {
+//	    return func(ctx context.Context, event dispatch.Event) {
+//	        start := time.Now()
+//	        next(ctx, event)
+//	        logger.Info("event handled", "signature", event.Signature, "duration", time.Since(start))
+//	    }
+//	})
+func (b *Dispatcher) Use(middleware ...EventMiddleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.middleware = append(b.middleware, middleware...)
+}
+
+// wrap applies the middleware chain to a handler
+func wrap(h Handler, middleware []EventMiddleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
@@ -0,0 +1,81 @@
+package dispatch_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+func TestDispatcher_Use(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	var calls []string
+	record := func(name string) dispatch.EventMiddleware {
+		return func(next dispatch.Handler) dispatch.Handler {
+			return func(ctx context.Context, event dispatch.Event) {
+				calls = append(calls, name+":before")
+				next(ctx, event)
+				calls = append(calls, name+":after")
+			}
+		}
+	}
+
+	// Handlers registered before Use should still be wrapped
+	bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+		calls = append(calls, "handler")
+	})
+
+	bus.Use(record("first"), record("second"))
+	bus.EmitSync(context.Background(), "test.event", nil)
+
+	assert.Equal(t, []string{
+		"first:before",
+		"second:before",
+		"handler",
+		"second:after",
+		"first:after",
+	}, calls)
+}
+
+func TestDispatcher_UseCanSkipHandler(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	// Validation middleware that only allows string payloads
+	bus.Use(func(next dispatch.Handler) dispatch.Handler {
+		return func(ctx context.Context, event dispatch.Event) {
+			if _, ok := event.Payload.(string); !ok {
+				return
+			}
+			next(ctx, event)
+		}
+	})
+
+	var received []any
+	bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+		received = append(received, event.Payload)
+	})
+
+	bus.EmitSync(context.Background(), "test.event", "valid")
+	bus.EmitSync(context.Background(), "test.event", 42)
+
+	assert.Equal(t, []any{"valid"}, received)
+}
+
+func TestDispatcher_MiddlewarePanicRecovered(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	bus.Use(func(next dispatch.Handler) dispatch.Handler {
+		return func(ctx context.Context, event dispatch.Event) {
+			panic("middleware panic")
+		}
+	})
+	bus.On("test.event", func(ctx context.Context, event dispatch.Event) {})
+
+	assert.NotPanics(t, func() {
+		bus.EmitSync(context.Background(), "test.event", nil)
+	})
+}
@@ -0,0 +1,93 @@
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// EventStore persists events before they are dispatched (the outbox pattern), so events that were
+// emitted but not fully handled can be replayed after a restart. Delivery is at-least-once: a handler
+// may see the same event again if the process stops before the event is marked as dispatched.
+type EventStore interface {
+	// Save persists an event before it is dispatched
+	Save(ctx context.Context, event Event) error
+	// MarkDispatched records that all handlers for the event have completed
+	MarkDispatched(ctx context.Context, id string) error
+	// Pending returns events that were saved but not marked as dispatched, oldest first.
+	// Payloads are returned as json.RawMessage and decoded by PayloadAs.
+	Pending(ctx context.Context) ([]Event, error)
+}
+
+// WithEventStore persists events to store before they are dispatched
+func WithEventStore(store EventStore) Option {
+	return func(b *Dispatcher) {
+		b.store = store
+	}
+}
+
+// Replay dispatches events that were persisted but not marked as dispatched, for example because the
+// process stopped while handlers were running. It should be called once at startup, after all handlers
+// are registered. Each event is delivered synchronously to the handlers currently registered for it.
+// Replay returns the number of events replayed.
+func (b *Dispatcher) Replay(ctx context.Context) (int, error) {
+	if b.store == nil {
+		return 0, nil
+	}
+
+	events, err := b.store.Pending(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("dispatch: loading pending events: %w", err)
+	}
+
+	for i, event := range events {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+
+		b.logger.Info("replaying event",
+			slog.String("id", event.ID),
+			slog.String("signature", event.Signature))
+
+		if handlers := b.matchingHandlers(event.Signature); len(handlers) > 0 {
+			b.dispatchSync(ctx, event, handlers)
+		}
+		b.markDispatched(event)
+	}
+
+	return len(events), nil
+}
+
+// trackDispatched wraps the handlers so the event is marked as dispatched once all of them complete
+func (b *Dispatcher) trackDispatched(handlers []Handler, event Event) []Handler {
+	var remaining atomic.Int32
+	remaining.Store(int32(len(handlers)))
+
+	tracked := make([]Handler, len(handlers))
+	for i, h := range handlers {
+		tracked[i] = func(ctx context.Context, event Event) {
+			defer func() {
+				if remaining.Add(-1) == 0 {
+					b.markDispatched(event)
+				}
+			}()
+			h(ctx, event)
+		}
+	}
+	return tracked
+}
+
+// markDispatched marks the event as dispatched in the store, logging any failure
+func (b *Dispatcher) markDispatched(event Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := b.store.MarkDispatched(ctx, event.ID); err != nil {
+		b.logger.Error("failed to mark event as dispatched",
+			slog.String("id", event.ID),
+			slog.String("signature", event.Signature),
+			slog.String("error", err.Error()))
+	}
+}
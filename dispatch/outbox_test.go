@@ -0,0 +1,117 @@
+package dispatch_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+// memoryEventStore is a minimal in-memory EventStore for tests
+type memoryEventStore struct {
+	mu         sync.Mutex
+	events     []dispatch.Event
+	dispatched map[string]bool
+}
+
+func newMemoryEventStore() *memoryEventStore {
+	return &memoryEventStore{dispatched: make(map[string]bool)}
+}
+
+func (s *memoryEventStore) Save(_ context.Context, event dispatch.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+	event.Payload = json.RawMessage(payload)
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memoryEventStore) MarkDispatched(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatched[id] = true
+	return nil
+}
+
+func (s *memoryEventStore) Pending(_ context.Context) ([]dispatch.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []dispatch.Event
+	for _, e := range s.events {
+		if !s.dispatched[e.ID] {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func TestOutbox_EmitMarksDispatched(t *testing.T) {
+	store := newMemoryEventStore()
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard), dispatch.WithEventStore(store))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		bus.On("order.placed", func(ctx context.Context, event dispatch.Event) {
+			defer wg.Done()
+			time.Sleep(10 * time.Millisecond)
+		})
+	}
+
+	require.NoError(t, bus.Emit(context.Background(), "order.placed", map[string]any{"id": 1}))
+
+	pending, err := store.Pending(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, pending, 1, "event should be pending while handlers run")
+
+	wg.Wait()
+	assert.Eventually(t, func() bool {
+		pending, _ := store.Pending(context.Background())
+		return len(pending) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestOutbox_Replay(t *testing.T) {
+	type order struct {
+		ID int `json:"id"`
+	}
+
+	store := newMemoryEventStore()
+	require.NoError(t, store.Save(context.Background(), dispatch.NewEvent("order.placed", order{ID: 7})))
+
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard), dispatch.WithEventStore(store))
+
+	var got []order
+	bus.On("order.*", dispatch.HandlePayload(func(ctx context.Context, o order) {
+		got = append(got, o)
+	}))
+
+	n, err := bus.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []order{{ID: 7}}, got)
+
+	pending, err := store.Pending(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestOutbox_ReplayWithoutStore(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+	n, err := bus.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
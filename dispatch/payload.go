@@ -2,17 +2,30 @@ package dispatch
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 )
 
 // PayloadAs safely converts an event's payload to the specified type T.
 // Returns the typed payload and any conversion error.
+//
+// Events replayed from an EventStore carry their payload as json.RawMessage, which is decoded into T.
 func PayloadAs[T any](e Event) (T, error) {
 	var zero T
 	if e.Payload == nil {
 		return zero, fmt.Errorf("event payload is nil")
 	}
 
+	if raw, ok := e.Payload.(json.RawMessage); ok {
+		if _, wantRaw := any(zero).(json.RawMessage); !wantRaw {
+			var payload T
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return zero, fmt.Errorf("invalid payload: decoding %T: %w", zero, err)
+			}
+			return payload, nil
+		}
+	}
+
 	payload, ok := e.Payload.(T)
 	if !ok {
 		return zero, fmt.Errorf("invalid payload type: expected %T, got %T", zero, e.Payload)
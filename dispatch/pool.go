@@ -0,0 +1,130 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// ErrQueueFull is returned by Emit when the worker pool queue is full and the overflow policy is OverflowError
+var ErrQueueFull = errors.New("dispatch: event queue is full")
+
+// OverflowPolicy determines what happens when an event is emitted while the worker pool queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the caller until there is room in the queue or the context is done
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop drops the handler invocation and logs a warning
+	OverflowDrop
+	// OverflowError drops the handler invocation and returns ErrQueueFull from Emit
+	OverflowError
+)
+
+// String returns the name of the overflow policy
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDrop:
+		return "drop"
+	case OverflowError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Option configures a Dispatcher
+type Option func(*Dispatcher)
+
+// WithWorkerPool bounds asynchronous emission to the given number of concurrent handlers.
+// Handler invocations wait in a queue of queueSize entries until a worker is free. When workers
+// is zero or less, Emit starts a goroutine per handler (the default).
+func WithWorkerPool(workers, queueSize int) Option {
+	return func(b *Dispatcher) {
+		if workers <= 0 {
+			return
+		}
+		if queueSize < 0 {
+			queueSize = 0
+		}
+		b.pool = &workerPool{
+			workers: workers,
+			queue:   make(chan job, queueSize),
+		}
+	}
+}
+
+// WithOverflowPolicy sets the policy used when the worker pool queue is full. Defaults to OverflowBlock.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(b *Dispatcher) {
+		b.overflow = policy
+	}
+}
+
+// job is a single handler invocation waiting to be run by the worker pool
+type job struct {
+	ctx     context.Context
+	handler Handler
+	event   Event
+}
+
+// workerPool runs handler invocations on a fixed number of goroutines
+type workerPool struct {
+	workers int
+	queue   chan job
+	wg      sync.WaitGroup
+}
+
+// start launches the pool workers
+func (p *workerPool) start(b *Dispatcher) {
+	p.wg.Add(p.workers)
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for j := range p.queue {
+				b.invokeAsync(j.ctx, j.handler, j.event)
+			}
+		}()
+	}
+}
+
+// enqueue adds handler invocations to the pool queue according to the dispatcher's overflow policy
+func (b *Dispatcher) enqueue(ctx context.Context, handlers []Handler, event Event) error {
+	var dropped int
+	for _, h := range handlers {
+		j := job{ctx: ctx, handler: h, event: event}
+
+		if b.overflow == OverflowBlock {
+			select {
+			case b.pool.queue <- j:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+
+		select {
+		case b.pool.queue <- j:
+		default:
+			dropped++
+		}
+	}
+
+	if dropped == 0 {
+		return nil
+	}
+
+	b.logger.Warn("event queue full, handlers dropped",
+		slog.String("signature", event.Signature),
+		slog.Int("dropped", dropped),
+		slog.String("policy", b.overflow.String()))
+
+	if b.overflow == OverflowError {
+		return ErrQueueFull
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package dispatch_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+func TestWorkerPool_BoundsConcurrency(t *testing.T) {
+	const workers = 2
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard), dispatch.WithWorkerPool(workers, 100))
+
+	var (
+		running atomic.Int32
+		maxSeen atomic.Int32
+		wg      sync.WaitGroup
+	)
+
+	bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+		defer wg.Done()
+		n := running.Add(1)
+		for {
+			m := maxSeen.Load()
+			if n <= m || maxSeen.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+	})
+
+	wg.Add(20)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, bus.Emit(context.Background(), "test.event", nil))
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxSeen.Load(), int32(workers))
+}
+
+func TestWorkerPool_OverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  dispatch.OverflowPolicy
+		wantErr error
+	}{
+		{name: "drop", policy: dispatch.OverflowDrop},
+		{name: "error", policy: dispatch.OverflowError, wantErr: dispatch.ErrQueueFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := dispatch.NewDispatcher(newTestLogger(io.Discard),
+				dispatch.WithWorkerPool(1, 1),
+				dispatch.WithOverflowPolicy(tt.policy))
+
+			release := make(chan struct{})
+			started := make(chan struct{}, 10)
+			var calls atomic.Int32
+			bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+				calls.Add(1)
+				started <- struct{}{}
+				<-release
+			})
+
+			// Occupy the single worker, then fill the single queue slot
+			require.NoError(t, bus.Emit(context.Background(), "test.event", nil))
+			<-started
+			require.NoError(t, bus.Emit(context.Background(), "test.event", nil))
+
+			err := bus.Emit(context.Background(), "test.event", nil)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			close(release)
+			<-started
+			time.Sleep(20 * time.Millisecond)
+			assert.Equal(t, int32(2), calls.Load())
+		})
+	}
+}
+
+func TestWorkerPool_BlockRespectsContext(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard), dispatch.WithWorkerPool(1, 0))
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+		started <- struct{}{}
+		<-release
+	})
+
+	require.NoError(t, bus.Emit(context.Background(), "test.event", nil))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := bus.Emit(ctx, "test.event", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,462 @@
+// Package saga orchestrates multi-step processes with compensating actions. Each step has an action and
+// an optional compensation; when a step fails, the compensations of the steps that already completed run
+// in reverse order, so a workflow such as signup (create user -> send welcome email -> provision account)
+// is rolled back consistently.
+//
+// Progress can be persisted to a dispatch.EventStore so executions interrupted by a restart are rolled
+// back by Recover, and lifecycle events can be emitted on a dispatch.Dispatcher for auditing.
+//
+//	signup := saga.New[Signup]("signup").
+//	    Step("create_user", createUser, deleteUser).
+//	    Step("send_email", sendWelcomeEmail, nil).
+//	    Step("provision", provisionAccount, deprovisionAccount)
+//
+//	exec, err := signup.Run(ctx, &Signup{Email: email})
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+// Status is the state of a saga execution
+type Status string
+
+const (
+	// StatusRunning means steps are still being executed
+	StatusRunning Status = "running"
+	// StatusCompensating means a step failed and compensations are running
+	StatusCompensating Status = "compensating"
+	// StatusCompleted means every step succeeded
+	StatusCompleted Status = "completed"
+	// StatusCompensated means a step failed and every completed step was compensated
+	StatusCompensated Status = "compensated"
+	// StatusFailed means a step failed and the saga was halted, or a compensation failed.
+	// The execution needs manual intervention.
+	StatusFailed Status = "failed"
+)
+
+// FailurePolicy decides what happens when a step fails after exhausting its retries
+type FailurePolicy int
+
+const (
+	// Compensate runs the compensations of completed steps in reverse order (the default)
+	Compensate FailurePolicy = iota
+	// Halt stops the saga without compensating, leaving it failed for manual intervention
+	Halt
+)
+
+// Step is a single action in a saga, with an optional compensating action
+type Step[T any] struct {
+	// Name identifies the step in state, logs and events
+	Name string
+	// Do performs the step. It may modify data, for example to record a created ID.
+	Do func(ctx context.Context, data *T) error
+	// Compensate undoes the step. Nil means the step has nothing to undo.
+	Compensate func(ctx context.Context, data *T) error
+	// Retries is the number of times Do is retried before the step fails. Negative values use the saga
+	// retries, which is what Saga.Step does.
+	Retries int
+}
+
+// State is the persisted progress of a saga execution
+type State struct {
+	// ID identifies the execution
+	ID string `json:"id"`
+	// Saga is the saga name
+	Saga string `json:"saga"`
+	// Status is the execution status
+	Status Status `json:"status"`
+	// Completed lists the steps that completed, in order
+	Completed []string `json:"completed"`
+	// FailedStep is the step that failed, if any
+	FailedStep string `json:"failed_step,omitempty"`
+	// Error is the error message of the failure, if any
+	Error string `json:"error,omitempty"`
+	// Data is the saga data, encoded as JSON
+	Data json.RawMessage `json:"data,omitempty"`
+	// Version increases with every saved transition
+	Version int `json:"version"`
+	// UpdatedAt is when the state last changed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Execution is the outcome of running a saga
+type Execution struct {
+	State
+	// Err is the step error that caused the saga to fail, joined with any compensation errors
+	Err error `json:"-"`
+}
+
+// Option configures a Saga
+type Option func(*options)
+
+type options struct {
+	store      dispatch.EventStore
+	dispatcher *dispatch.Dispatcher
+	logger     *slog.Logger
+	retries    int
+	backoff    time.Duration
+	policy     FailurePolicy
+}
+
+// WithEventStore persists each state transition to store, so Recover can roll back executions that were
+// interrupted. Use a store dedicated to sagas: a dispatcher's Replay marks every pending event as
+// dispatched, which would discard saga progress.
+func WithEventStore(store dispatch.EventStore) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithDispatcher emits lifecycle events on d: "saga.<name>.started", "saga.<name>.step_completed",
+// "saga.<name>.completed", "saga.<name>.compensated", and "saga.<name>.failed". The payload is the State.
+func WithDispatcher(d *dispatch.Dispatcher) Option {
+	return func(o *options) {
+		o.dispatcher = d
+	}
+}
+
+// WithLogger sets the logger. Defaults to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRetries retries failing steps up to n times, waiting backoff before the first retry and doubling it
+// for each subsequent retry. Steps may override the number of retries.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = n
+		o.backoff = backoff
+	}
+}
+
+// WithFailurePolicy sets the failure policy. Defaults to Compensate.
+func WithFailurePolicy(policy FailurePolicy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// Saga is a named sequence of steps. Define a saga once, at startup, and run it many times.
+type Saga[T any] struct {
+	name  string
+	steps []Step[T]
+	opts  options
+}
+
+// executionID generates execution IDs that are unique across restarts
+var executionID = func() *atomic.Uint64 {
+	var id atomic.Uint64
+	id.Store(uint64(time.Now().UnixNano()))
+	return &id
+}()
+
+// New creates a saga with the given name
+func New[T any](name string, opts ...Option) *Saga[T] {
+	s := &Saga[T]{name: name}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+	if s.opts.logger == nil {
+		s.opts.logger = slog.Default()
+	}
+	return s
+}
+
+// Name returns the saga name
+func (s *Saga[T]) Name() string {
+	return s.name
+}
+
+// Step appends a step with an action and an optional compensation
+func (s *Saga[T]) Step(name string, do, compensate func(ctx context.Context, data *T) error) *Saga[T] {
+	return s.AddStep(Step[T]{Name: name, Do: do, Compensate: compensate, Retries: -1})
+}
+
+// AddStep appends a fully configured step. A negative Retries uses the saga retries.
+func (s *Saga[T]) AddStep(step Step[T]) *Saga[T] {
+	s.steps = append(s.steps, step)
+	return s
+}
+
+// Run executes the steps in order. If a step fails, the failure policy decides whether completed steps
+// are compensated. Run returns the execution and, when the saga did not complete, an error describing the
+// failed step.
+func (s *Saga[T]) Run(ctx context.Context, data *T) (*Execution, error) {
+	exec := &Execution{State: State{
+		ID:     "saga_" + strconv.FormatUint(executionID.Add(1), 10),
+		Saga:   s.name,
+		Status: StatusRunning,
+	}}
+
+	tracker := &persistence{opts: &s.opts}
+	if err := tracker.save(ctx, &exec.State, data); err != nil {
+		return exec, err
+	}
+	s.emit(ctx, "started", exec.State)
+
+	for _, step := range s.steps {
+		if err := s.runStep(ctx, step, data); err != nil {
+			exec.FailedStep = step.Name
+			exec.Err = fmt.Errorf("saga %s: step %s: %w", s.name, step.Name, err)
+			exec.Error = err.Error()
+			s.opts.logger.Warn("saga step failed",
+				slog.String("saga", s.name),
+				slog.String("id", exec.ID),
+				slog.String("step", step.Name),
+				slog.String("error", err.Error()))
+
+			if s.opts.policy == Halt {
+				exec.Status = StatusFailed
+				_ = tracker.finish(ctx, &exec.State, data)
+				s.emit(ctx, "failed", exec.State)
+				return exec, exec.Err
+			}
+
+			s.compensate(ctx, exec, data, tracker)
+			return exec, exec.Err
+		}
+
+		exec.Completed = append(exec.Completed, step.Name)
+		if err := tracker.save(ctx, &exec.State, data); err != nil {
+			s.opts.logger.Error("saving saga state", slog.String("saga", s.name), slog.String("error", err.Error()))
+		}
+		s.emit(ctx, "step_completed", exec.State)
+	}
+
+	exec.Status = StatusCompleted
+	_ = tracker.finish(ctx, &exec.State, data)
+	s.emit(ctx, "completed", exec.State)
+	return exec, nil
+}
+
+// Recover loads executions of this saga that were interrupted, for example by a restart, and compensates
+// their completed steps. It should be called once at startup. Recover returns the recovered executions.
+// It does nothing when the saga has no event store.
+func (s *Saga[T]) Recover(ctx context.Context) ([]*Execution, error) {
+	if s.opts.store == nil {
+		return nil, nil
+	}
+
+	events, err := s.opts.store.Pending(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("saga %s: loading pending state: %w", s.name, err)
+	}
+
+	// Keep the latest state of each execution, and every event holding it, so all can be marked done
+	latest := make(map[string]State)
+	eventIDs := make(map[string][]string)
+	for _, event := range events {
+		if event.Signature != stateSignature(s.name) {
+			continue
+		}
+		state, err := dispatch.PayloadAs[State](event)
+		if err != nil {
+			s.opts.logger.Error("decoding saga state", slog.String("saga", s.name), slog.String("error", err.Error()))
+			continue
+		}
+		eventIDs[state.ID] = append(eventIDs[state.ID], event.ID)
+		if current, ok := latest[state.ID]; !ok || state.Version > current.Version {
+			latest[state.ID] = state
+		}
+	}
+
+	ids := make([]string, 0, len(latest))
+	for id := range latest {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	recovered := make([]*Execution, 0, len(ids))
+	for _, id := range ids {
+		exec := &Execution{State: latest[id]}
+
+		var data T
+		if len(exec.Data) > 0 {
+			if err := json.Unmarshal(exec.Data, &data); err != nil {
+				return recovered, fmt.Errorf("saga %s: decoding data for %s: %w", s.name, id, err)
+			}
+		}
+
+		s.opts.logger.Info("recovering interrupted saga",
+			slog.String("saga", s.name),
+			slog.String("id", id),
+			slog.Any("completed", exec.Completed))
+
+		tracker := &persistence{opts: &s.opts, eventIDs: eventIDs[id]}
+		if exec.Error == "" {
+			exec.Error = "interrupted"
+		}
+		exec.Err = fmt.Errorf("saga %s: execution %s was interrupted", s.name, id)
+		s.compensate(ctx, exec, &data, tracker)
+		recovered = append(recovered, exec)
+	}
+
+	return recovered, nil
+}
+
+// runStep runs a step's action, retrying it according to the retry policy
+func (s *Saga[T]) runStep(ctx context.Context, step Step[T], data *T) error {
+	retries := step.Retries
+	if retries < 0 {
+		retries = s.opts.retries
+	}
+
+	backoff := s.opts.backoff
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return errors.Join(err, ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		if err = callStep(ctx, step.Do, data); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// compensate runs the compensations of the completed steps in reverse order. Compensation errors do not
+// stop the remaining compensations; they mark the execution as failed.
+func (s *Saga[T]) compensate(ctx context.Context, exec *Execution, data *T, tracker *persistence) {
+	exec.Status = StatusCompensating
+	_ = tracker.save(ctx, &exec.State, data)
+
+	steps := make(map[string]Step[T], len(s.steps))
+	for _, step := range s.steps {
+		steps[step.Name] = step
+	}
+
+	var errs []error
+	for i := len(exec.Completed) - 1; i >= 0; i-- {
+		step, ok := steps[exec.Completed[i]]
+		if !ok || step.Compensate == nil {
+			continue
+		}
+		if err := callStep(ctx, step.Compensate, data); err != nil {
+			s.opts.logger.Error("saga compensation failed",
+				slog.String("saga", s.name),
+				slog.String("id", exec.ID),
+				slog.String("step", step.Name),
+				slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("compensating %s: %w", step.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		exec.Status = StatusFailed
+		exec.Err = errors.Join(append([]error{exec.Err}, errs...)...)
+		exec.Error = exec.Err.Error()
+		_ = tracker.finish(ctx, &exec.State, data)
+		s.emit(ctx, "failed", exec.State)
+		return
+	}
+
+	exec.Status = StatusCompensated
+	_ = tracker.finish(ctx, &exec.State, data)
+	s.emit(ctx, "compensated", exec.State)
+}
+
+// emit sends a lifecycle event, if a dispatcher is configured
+func (s *Saga[T]) emit(ctx context.Context, action string, state State) {
+	if s.opts.dispatcher == nil {
+		return
+	}
+	if err := s.opts.dispatcher.Emit(ctx, "saga."+s.name+"."+action, state); err != nil {
+		s.opts.logger.Warn("emitting saga event", slog.String("saga", s.name), slog.String("error", err.Error()))
+	}
+}
+
+// callStep calls fn, converting a panic into an error
+func callStep[T any](ctx context.Context, fn func(context.Context, *T) error, data *T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx, data)
+}
+
+// stateSignature is the event signature used to persist the state of a saga
+func stateSignature(name string) string {
+	return "saga." + name + ".state"
+}
+
+// persistence saves state transitions to the event store. Each transition is saved as a new event
+// before the previous ones are marked as dispatched, so an interrupted execution always has at least
+// one pending event holding its latest state.
+type persistence struct {
+	opts     *options
+	eventIDs []string
+}
+
+// save persists the current state
+func (p *persistence) save(ctx context.Context, state *State, data any) error {
+	state.Version++
+	state.UpdatedAt = time.Now().UTC()
+
+	if p.opts.store == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("saga %s: encoding data: %w", state.Saga, err)
+	}
+	state.Data = encoded
+
+	event := dispatch.NewEvent(stateSignature(state.Saga), *state)
+	if err := p.opts.store.Save(ctx, event); err != nil {
+		return fmt.Errorf("saga %s: saving state: %w", state.Saga, err)
+	}
+
+	previous := p.eventIDs
+	p.eventIDs = []string{event.ID}
+	p.markDone(ctx, previous)
+	return nil
+}
+
+// finish records the final state and marks every event of the execution as dispatched
+func (p *persistence) finish(ctx context.Context, state *State, data any) error {
+	state.Version++
+	state.UpdatedAt = time.Now().UTC()
+
+	if p.opts.store == nil {
+		return nil
+	}
+
+	if encoded, err := json.Marshal(data); err == nil {
+		state.Data = encoded
+	}
+
+	p.markDone(ctx, p.eventIDs)
+	p.eventIDs = nil
+	return nil
+}
+
+// markDone marks events as dispatched, logging failures
+func (p *persistence) markDone(ctx context.Context, ids []string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, id := range ids {
+		if err := p.opts.store.MarkDispatched(ctx, id); err != nil {
+			p.opts.logger.Error("marking saga state as done", slog.String("id", id), slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package saga_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/dispatch/saga"
+)
+
+// memoryStore is an in-memory dispatch.EventStore that mimics the JSON round trip of a real store
+type memoryStore struct {
+	mu         sync.Mutex
+	events     []dispatch.Event
+	dispatched map[string]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{dispatched: make(map[string]bool)}
+}
+
+func (s *memoryStore) Save(_ context.Context, event dispatch.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+	event.Payload = json.RawMessage(payload)
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memoryStore) MarkDispatched(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatched[id] = true
+	return nil
+}
+
+func (s *memoryStore) Pending(_ context.Context) ([]dispatch.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pending []dispatch.Event
+	for _, e := range s.events {
+		if !s.dispatched[e.ID] {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+type signup struct {
+	Email  string `json:"email"`
+	UserID int    `json:"user_id"`
+}
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestSaga_Completes(t *testing.T) {
+	var calls []string
+	s := saga.New[signup]("signup", saga.WithLogger(quietLogger())).
+		Step("create_user", func(ctx context.Context, data *signup) error {
+			calls = append(calls, "create")
+			data.UserID = 42
+			return nil
+		}, func(ctx context.Context, data *signup) error {
+			calls = append(calls, "delete")
+			return nil
+		}).
+		Step("send_email", func(ctx context.Context, data *signup) error {
+			calls = append(calls, "email:"+data.Email)
+			return nil
+		}, nil)
+
+	data := &signup{Email: "jo@example.com"}
+	exec, err := s.Run(context.Background(), data)
+	require.NoError(t, err)
+
+	assert.Equal(t, saga.StatusCompleted, exec.Status)
+	assert.Equal(t, []string{"create_user", "send_email"}, exec.Completed)
+	assert.Equal(t, []string{"create", "email:jo@example.com"}, calls)
+	assert.Equal(t, 42, data.UserID)
+}
+
+func TestSaga_CompensatesInReverse(t *testing.T) {
+	var calls []string
+	errProvision := errors.New("provisioning failed")
+
+	step := func(name string, err error) (func(context.Context, *signup) error, func(context.Context, *signup) error) {
+		return func(ctx context.Context, data *signup) error {
+				calls = append(calls, name)
+				return err
+			}, func(ctx context.Context, data *signup) error {
+				calls = append(calls, "undo_"+name)
+				return nil
+			}
+	}
+
+	createDo, createUndo := step("create", nil)
+	emailDo, _ := step("email", nil)
+	provisionDo, provisionUndo := step("provision", errProvision)
+
+	s := saga.New[signup]("signup", saga.WithLogger(quietLogger())).
+		Step("create_user", createDo, createUndo).
+		Step("send_email", emailDo, nil).
+		Step("provision", provisionDo, provisionUndo)
+
+	exec, err := s.Run(context.Background(), &signup{})
+	require.ErrorIs(t, err, errProvision)
+
+	assert.Equal(t, saga.StatusCompensated, exec.Status)
+	assert.Equal(t, "provision", exec.FailedStep)
+	assert.Equal(t, []string{"create", "email", "provision", "undo_create"}, calls)
+}
+
+func TestSaga_Retries(t *testing.T) {
+	attempts := 0
+	s := saga.New[signup]("signup", saga.WithLogger(quietLogger()), saga.WithRetries(2, 0)).
+		Step("flaky", func(ctx context.Context, data *signup) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("temporary")
+			}
+			return nil
+		}, nil)
+
+	exec, err := s.Run(context.Background(), &signup{})
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusCompleted, exec.Status)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestSaga_HaltPolicyAndCompensationFailures(t *testing.T) {
+	compensated := false
+	fail := func(ctx context.Context, data *signup) error { return errors.New("boom") }
+	ok := func(ctx context.Context, data *signup) error { return nil }
+	undo := func(ctx context.Context, data *signup) error {
+		compensated = true
+		return nil
+	}
+
+	halted := saga.New[signup]("halted", saga.WithLogger(quietLogger()), saga.WithFailurePolicy(saga.Halt)).
+		Step("first", ok, undo).
+		Step("second", fail, nil)
+
+	exec, err := halted.Run(context.Background(), &signup{})
+	require.Error(t, err)
+	assert.Equal(t, saga.StatusFailed, exec.Status)
+	assert.False(t, compensated)
+
+	errUndo := errors.New("undo failed")
+	broken := saga.New[signup]("broken", saga.WithLogger(quietLogger())).
+		Step("first", ok, func(ctx context.Context, data *signup) error { return errUndo }).
+		Step("second", func(ctx context.Context, data *signup) error { panic("unexpected") }, nil)
+
+	exec, err = broken.Run(context.Background(), &signup{})
+	assert.ErrorIs(t, err, errUndo)
+	assert.ErrorContains(t, err, "panic: unexpected")
+	assert.Equal(t, saga.StatusFailed, exec.Status)
+}
+
+func TestSaga_EmitsLifecycleEvents(t *testing.T) {
+	bus := dispatch.NewDispatcher(quietLogger())
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	done := make(chan struct{})
+	bus.On("saga.signup.*", func(ctx context.Context, event dispatch.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event.Signature)
+		if event.Signature == "saga.signup.completed" {
+			close(done)
+		}
+	})
+
+	s := saga.New[signup]("signup", saga.WithLogger(quietLogger()), saga.WithDispatcher(bus)).
+		Step("create_user", func(ctx context.Context, data *signup) error { return nil }, nil)
+
+	_, err := s.Run(context.Background(), &signup{})
+	require.NoError(t, err)
+	<-done
+	require.NoError(t, bus.Shutdown(context.Background()))
+
+	assert.ElementsMatch(t, []string{"saga.signup.started", "saga.signup.step_completed", "saga.signup.completed"}, events)
+}
+
+func TestSaga_PersistsAndRecovers(t *testing.T) {
+	store := newMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var deleted []int
+	define := func() *saga.Saga[signup] {
+		return saga.New[signup]("signup", saga.WithLogger(quietLogger()), saga.WithEventStore(store)).
+			Step("create_user", func(ctx context.Context, data *signup) error {
+				data.UserID = 7
+				return nil
+			}, func(ctx context.Context, data *signup) error {
+				deleted = append(deleted, data.UserID)
+				return nil
+			}).
+			Step("provision", func(ctx context.Context, data *signup) error {
+				// Simulate the process stopping mid-step: the state stays pending in the store
+				cancel()
+				<-ctx.Done()
+				runtime.Goexit()
+				return nil
+			}, nil)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		_, _ = define().Run(ctx, &signup{Email: "jo@example.com"})
+	}()
+	<-finished
+
+	pending, err := store.Pending(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "only the latest state should be pending")
+
+	recovered, err := define().Recover(context.Background())
+	require.NoError(t, err)
+	require.Len(t, recovered, 1)
+	assert.Equal(t, saga.StatusCompensated, recovered[0].Status)
+	assert.Equal(t, []string{"create_user"}, recovered[0].Completed)
+	assert.Equal(t, []int{7}, deleted)
+
+	pending, err = store.Pending(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// Completed executions leave nothing to recover
+	_, err = saga.New[signup]("signup", saga.WithLogger(quietLogger()), saga.WithEventStore(store)).
+		Step("noop", func(ctx context.Context, data *signup) error { return nil }, nil).
+		Run(context.Background(), &signup{})
+	require.NoError(t, err)
+	recovered, err = define().Recover(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, recovered)
+}
@@ -0,0 +1,115 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+)
+
+// ErrClosed is returned when emitting or scheduling an event on a dispatcher that has been shut down
+var ErrClosed = errors.New("dispatch: dispatcher is shut down")
+
+// Scheduled event states
+const (
+	schedulePending int32 = iota
+	scheduleFired
+	scheduleCanceled
+)
+
+// WithClock sets the clock used to schedule events and timestamp them, e.g. a fake clock from the
+// hoptest package so scheduled events can be tested without waiting
+func WithClock(c clock.Clock) Option {
+	return func(b *Dispatcher) {
+		b.clock = c
+	}
+}
+
+// ScheduledEvent is a handle to an event scheduled with EmitAfter or EmitAt
+type ScheduledEvent struct {
+	signature string
+	at        time.Time
+	state     atomic.Int32
+	cancel    chan struct{}
+}
+
+// Signature returns the signature of the scheduled event
+func (s *ScheduledEvent) Signature() string {
+	return s.signature
+}
+
+// At returns the time the event is scheduled to be emitted
+func (s *ScheduledEvent) At() time.Time {
+	return s.at
+}
+
+// Cancel prevents the event from being emitted. It reports whether the event was still pending.
+func (s *ScheduledEvent) Cancel() bool {
+	if !s.state.CompareAndSwap(schedulePending, scheduleCanceled) {
+		return false
+	}
+	close(s.cancel)
+	return true
+}
+
+// EmitAfter schedules an event to be emitted asynchronously, as with Emit, after delay. The event is
+// canceled if ctx is done, the returned ScheduledEvent is canceled, or the dispatcher is shut down
+// before the delay elapses. To schedule an event from an HTTP handler, detach it from the request with
+// context.WithoutCancel, otherwise it is canceled when the request completes:
+//
+//	dispatcher.EmitAfter(context.WithoutCancel(r.Context()), "session.expiring", sessionID, 25*time.Minute)
+func (b *Dispatcher) EmitAfter(ctx context.Context, signature string, payload any, delay time.Duration) (*ScheduledEvent, error) {
+	return b.EmitAt(ctx, signature, payload, b.clock.Now().Add(delay))
+}
+
+// EmitAt schedules an event to be emitted asynchronously, as with Emit, at t. Times in the past emit
+// the event immediately. See EmitAfter for cancellation.
+func (b *Dispatcher) EmitAt(ctx context.Context, signature string, payload any, t time.Time) (*ScheduledEvent, error) {
+	b.closeMu.RLock()
+	defer b.closeMu.RUnlock()
+
+	if b.isClosed() {
+		return nil, ErrClosed
+	}
+
+	s := &ScheduledEvent{
+		signature: signature,
+		at:        t,
+		cancel:    make(chan struct{}),
+	}
+
+	b.scheduled.Add(1)
+	go func() {
+		defer b.scheduled.Done()
+
+		timer := b.clock.NewTimer(b.clock.Until(t))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C():
+		case <-s.cancel:
+			return
+		case <-ctx.Done():
+			s.state.CompareAndSwap(schedulePending, scheduleCanceled)
+			return
+		case <-b.done:
+			s.state.CompareAndSwap(schedulePending, scheduleCanceled)
+			return
+		}
+
+		if !s.state.CompareAndSwap(schedulePending, scheduleFired) {
+			return
+		}
+
+		if err := b.Emit(ctx, signature, payload); err != nil && !errors.Is(err, ErrClosed) {
+			b.logger.Error("failed to emit scheduled event",
+				slog.String("signature", signature),
+				slog.String("error", err.Error()))
+		}
+	}()
+
+	return s, nil
+}
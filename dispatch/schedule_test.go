@@ -0,0 +1,146 @@
+package dispatch_test
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/hoptest"
+)
+
+func TestDispatcher_EmitAfter(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	received := make(chan dispatch.Event, 1)
+	bus.On("session.expiring", func(ctx context.Context, event dispatch.Event) {
+		received <- event
+	})
+
+	start := time.Now()
+	s, err := bus.EmitAfter(context.Background(), "session.expiring", "abc", 20*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, "session.expiring", s.Signature())
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "abc", event.Payload)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("scheduled event was not emitted")
+	}
+
+	assert.False(t, s.Cancel(), "cancel after firing should report false")
+}
+
+func TestDispatcher_EmitAfterWithClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	clk := hoptest.NewClock(start)
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard), dispatch.WithClock(clk))
+
+	received := make(chan dispatch.Event, 1)
+	bus.On("session.expiring", func(ctx context.Context, event dispatch.Event) {
+		received <- event
+	})
+
+	s, err := bus.EmitAfter(context.Background(), "session.expiring", "abc", 25*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(25*time.Minute), s.At())
+	clk.BlockUntil(1)
+
+	clk.Advance(24 * time.Minute)
+	select {
+	case <-received:
+		t.Fatal("event emitted before it was due")
+	default:
+	}
+
+	clk.Advance(time.Minute)
+	select {
+	case event := <-received:
+		assert.Equal(t, start.Add(25*time.Minute), event.Timestamp)
+	case <-time.After(time.Second):
+		t.Fatal("scheduled event was not emitted")
+	}
+}
+
+func TestDispatcher_EmitAtPast(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	received := make(chan struct{}, 1)
+	bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+		received <- struct{}{}
+	})
+
+	_, err := bus.EmitAt(context.Background(), "test.event", nil, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("event scheduled in the past was not emitted")
+	}
+}
+
+func TestDispatcher_ScheduledEventCancellation(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel func(bus *dispatch.Dispatcher, s *dispatch.ScheduledEvent, cancelCtx context.CancelFunc)
+	}{
+		{
+			name: "cancel handle",
+			cancel: func(bus *dispatch.Dispatcher, s *dispatch.ScheduledEvent, cancelCtx context.CancelFunc) {
+				assert.True(t, s.Cancel())
+				assert.False(t, s.Cancel())
+			},
+		},
+		{
+			name: "cancel context",
+			cancel: func(bus *dispatch.Dispatcher, s *dispatch.ScheduledEvent, cancelCtx context.CancelFunc) {
+				cancelCtx()
+			},
+		},
+		{
+			name: "shutdown",
+			cancel: func(bus *dispatch.Dispatcher, s *dispatch.ScheduledEvent, cancelCtx context.CancelFunc) {
+				require.NoError(t, bus.Shutdown(context.Background()))
+				assert.False(t, s.Cancel())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+			var calls atomic.Int32
+			bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+				calls.Add(1)
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			s, err := bus.EmitAfter(ctx, "test.event", nil, 30*time.Millisecond)
+			require.NoError(t, err)
+
+			tt.cancel(bus, s, cancel)
+
+			time.Sleep(60 * time.Millisecond)
+			assert.Zero(t, calls.Load())
+		})
+	}
+}
+
+func TestDispatcher_ScheduleAfterShutdown(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+	require.NoError(t, bus.Shutdown(context.Background()))
+
+	_, err := bus.EmitAfter(context.Background(), "test.event", nil, time.Millisecond)
+	assert.ErrorIs(t, err, dispatch.ErrClosed)
+}
@@ -0,0 +1,227 @@
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/patrickward/hop/check"
+)
+
+// ErrPayloadMismatch is returned when a payload does not match the schema registered for its signature
+var ErrPayloadMismatch = errors.New("dispatch: payload does not match schema")
+
+// Schema describes the payload of an event signature
+type Schema struct {
+	// Signature is the event signature, without wildcards
+	Signature string
+	// Description documents when the event is emitted
+	Description string
+	// Type is the Go type of the payload, if registered with RegisterType
+	Type reflect.Type
+	// JSON is the JSON Schema of the payload, if registered with RegisterJSONSchema
+	JSON *check.JSONSchema
+}
+
+// MarshalJSON encodes the schema for documentation endpoints
+func (s Schema) MarshalJSON() ([]byte, error) {
+	var typeName string
+	if s.Type != nil {
+		typeName = s.Type.String()
+	}
+
+	return json.Marshal(struct {
+		Signature   string            `json:"signature"`
+		Description string            `json:"description,omitempty"`
+		Type        string            `json:"type,omitempty"`
+		Schema      *check.JSONSchema `json:"schema,omitempty"`
+	}{
+		Signature:   s.Signature,
+		Description: s.Description,
+		Type:        typeName,
+		Schema:      s.JSON,
+	})
+}
+
+// SchemaRegistry records the payload schema of each event signature, so payloads can be validated when
+// they are emitted and subscribers can check they expect the same type as emitters. This catches payload
+// drift between modules. A registry is safe for concurrent use.
+type SchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas map[string]Schema
+}
+
+// NewSchemaRegistry creates an empty schema registry
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: make(map[string]Schema)}
+}
+
+// WithSchemas attaches a schema registry to the dispatcher. When validate is true, Emit and EmitSync
+// reject payloads that do not match the registered schema with ErrPayloadMismatch. Validation is
+// typically enabled in development and tests only.
+func WithSchemas(registry *SchemaRegistry, validate bool) Option {
+	return func(b *Dispatcher) {
+		b.schemas = registry
+		b.validatePayloads = validate
+	}
+}
+
+// Schemas returns the dispatcher's schema registry, or nil if none is configured
+func (b *Dispatcher) Schemas() *SchemaRegistry {
+	return b.schemas
+}
+
+// Register adds a schema. Registering the same signature again with a different Go type returns an
+// error, so two modules cannot silently disagree about a payload.
+func (r *SchemaRegistry) Register(s Schema) error {
+	if s.Signature == "" || strings.Contains(s.Signature, "*") {
+		return fmt.Errorf("dispatch: invalid schema signature %q", s.Signature)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.schemas[s.Signature]; ok {
+		if existing.Type != nil && s.Type != nil && existing.Type != s.Type {
+			return fmt.Errorf("%w: %s is registered as %s, not %s", ErrPayloadMismatch, s.Signature, existing.Type, s.Type)
+		}
+		if s.Type == nil {
+			s.Type = existing.Type
+		}
+		if s.JSON == nil {
+			s.JSON = existing.JSON
+		}
+		if s.Description == "" {
+			s.Description = existing.Description
+		}
+	}
+
+	r.schemas[s.Signature] = s
+	return nil
+}
+
+// RegisterType registers T as the payload type of an event signature
+//
+//	dispatch.RegisterType[OrderCreated](registry, "order.created", "Emitted after an order is paid")
+func RegisterType[T any](r *SchemaRegistry, signature, description string) error {
+	return r.Register(Schema{
+		Signature:   signature,
+		Description: description,
+		Type:        reflect.TypeFor[T](),
+	})
+}
+
+// RegisterJSONSchema registers a JSON Schema for the payload of an event signature. This suits payloads
+// that are maps or that cross process boundaries.
+func (r *SchemaRegistry) RegisterJSONSchema(signature, description string, schema []byte) error {
+	js, err := check.ParseJSONSchema(schema)
+	if err != nil {
+		return fmt.Errorf("dispatch: schema for %s: %w", signature, err)
+	}
+	return r.Register(Schema{Signature: signature, Description: description, JSON: js})
+}
+
+// Lookup returns the schema registered for a signature
+func (r *SchemaRegistry) Lookup(signature string) (Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.schemas[signature]
+	return s, ok
+}
+
+// All returns every registered schema, sorted by signature. It is intended for generating documentation.
+func (r *SchemaRegistry) All() []Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schemas := make([]Schema, 0, len(r.schemas))
+	for _, s := range r.schemas {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Signature < schemas[j].Signature })
+	return schemas
+}
+
+// MarshalJSON encodes every registered schema, sorted by signature
+func (r *SchemaRegistry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.All())
+}
+
+// Validate checks a payload against the schema registered for signature. Signatures without a schema
+// are always valid. Payloads replayed from an EventStore as json.RawMessage are decoded into the
+// registered type before checking.
+func (r *SchemaRegistry) Validate(signature string, payload any) error {
+	s, ok := r.Lookup(signature)
+	if !ok {
+		return nil
+	}
+
+	if s.Type != nil {
+		if raw, isRaw := payload.(json.RawMessage); isRaw {
+			if err := json.Unmarshal(raw, reflect.New(s.Type).Interface()); err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrPayloadMismatch, signature, err)
+			}
+		} else if payload == nil || reflect.TypeOf(payload) != s.Type {
+			return fmt.Errorf("%w: %s expects %s, got %T", ErrPayloadMismatch, signature, s.Type, payload)
+		}
+	}
+
+	if s.JSON != nil {
+		if err := s.JSON.Validate(payload); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrPayloadMismatch, signature, err)
+		}
+	}
+
+	return nil
+}
+
+// checkType returns an error when a Go type is registered for signature and it is not T
+func checkType[T any](r *SchemaRegistry, signature string) error {
+	if r == nil {
+		return nil
+	}
+	s, ok := r.Lookup(signature)
+	if !ok || s.Type == nil {
+		return nil
+	}
+	if want := reflect.TypeFor[T](); s.Type != want {
+		return fmt.Errorf("%w: %s is registered as %s, not %s", ErrPayloadMismatch, signature, s.Type, want)
+	}
+	return nil
+}
+
+// OnTyped registers a handler that receives the payload as T. If the dispatcher has a schema registry
+// and a different type is registered for the signature, the handler is not registered and an error is
+// returned, so mismatched subscribers fail at startup rather than silently ignoring events.
+//
+//	sub, err := dispatch.OnTyped(dispatcher, "order.created", func(ctx context.Context, order OrderCreated) {
+//	    // ...
+//	})
+func OnTyped[T any](b *Dispatcher, signature string, handler func(context.Context, T)) (*Subscription, error) {
+	if err := checkType[T](b.schemas, signature); err != nil {
+		return nil, err
+	}
+	return b.On(signature, HandlePayload(handler)), nil
+}
+
+// EmitTyped emits an event whose payload type is checked at compile time by the caller and, when the
+// dispatcher has a schema registry, against the registered type.
+func EmitTyped[T any](ctx context.Context, b *Dispatcher, signature string, payload T) error {
+	if err := checkType[T](b.schemas, signature); err != nil {
+		return err
+	}
+	return b.Emit(ctx, signature, payload)
+}
+
+// validatePayload validates a payload when validation is enabled
+func (b *Dispatcher) validatePayload(signature string, payload any) error {
+	if !b.validatePayloads || b.schemas == nil {
+		return nil
+	}
+	return b.schemas.Validate(signature, payload)
+}
@@ -0,0 +1,122 @@
+package dispatch_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+type orderCreated struct {
+	ID    string  `json:"id"`
+	Total float64 `json:"total"`
+}
+
+func TestSchemaRegistry_Register(t *testing.T) {
+	registry := dispatch.NewSchemaRegistry()
+
+	require.NoError(t, dispatch.RegisterType[orderCreated](registry, "order.created", "Emitted after checkout"))
+	require.NoError(t, registry.RegisterJSONSchema("order.created", "", []byte(`{"type": "object", "required": ["id"]}`)))
+
+	s, ok := registry.Lookup("order.created")
+	require.True(t, ok)
+	assert.Equal(t, "Emitted after checkout", s.Description)
+	assert.NotNil(t, s.Type)
+	assert.NotNil(t, s.JSON)
+
+	err := dispatch.RegisterType[string](registry, "order.created", "")
+	assert.ErrorIs(t, err, dispatch.ErrPayloadMismatch)
+
+	assert.Error(t, dispatch.RegisterType[string](registry, "order.*", ""))
+	assert.Error(t, registry.RegisterJSONSchema("user.created", "", []byte(`{`)))
+}
+
+func TestSchemaRegistry_Validate(t *testing.T) {
+	registry := dispatch.NewSchemaRegistry()
+	require.NoError(t, dispatch.RegisterType[orderCreated](registry, "order.created", ""))
+	require.NoError(t, registry.RegisterJSONSchema("user.created", "", []byte(`{
+		"type": "object",
+		"required": ["email"],
+		"properties": {"email": {"type": "string", "format": "email"}}
+	}`)))
+
+	tests := []struct {
+		name      string
+		signature string
+		payload   any
+		wantErr   bool
+	}{
+		{name: "matching type", signature: "order.created", payload: orderCreated{ID: "1"}},
+		{name: "wrong type", signature: "order.created", payload: map[string]any{"id": "1"}, wantErr: true},
+		{name: "nil payload", signature: "order.created", payload: nil, wantErr: true},
+		{name: "raw payload", signature: "order.created", payload: json.RawMessage(`{"id": "1", "total": 2.5}`)},
+		{name: "bad raw payload", signature: "order.created", payload: json.RawMessage(`{"total": "lots"}`), wantErr: true},
+		{name: "matching json schema", signature: "user.created", payload: map[string]any{"email": "jo@example.com"}},
+		{name: "failing json schema", signature: "user.created", payload: map[string]any{"email": "nope"}, wantErr: true},
+		{name: "unregistered", signature: "other.event", payload: 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Validate(tt.signature, tt.payload)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, dispatch.ErrPayloadMismatch)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDispatcher_ValidatesPayloads(t *testing.T) {
+	registry := dispatch.NewSchemaRegistry()
+	require.NoError(t, dispatch.RegisterType[orderCreated](registry, "order.created", ""))
+
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard), dispatch.WithSchemas(registry, true))
+	var received []orderCreated
+	_, err := dispatch.OnTyped(bus, "order.created", func(ctx context.Context, order orderCreated) {
+		received = append(received, order)
+	})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, bus.EmitSync(context.Background(), "order.created", "not an order"), dispatch.ErrPayloadMismatch)
+	assert.ErrorIs(t, bus.Emit(context.Background(), "order.created", 42), dispatch.ErrPayloadMismatch)
+	require.NoError(t, bus.EmitSync(context.Background(), "order.created", orderCreated{ID: "1"}))
+	assert.Equal(t, []orderCreated{{ID: "1"}}, received)
+
+	// Without validation, mismatched payloads are emitted and skipped by typed handlers
+	unchecked := dispatch.NewDispatcher(newTestLogger(io.Discard), dispatch.WithSchemas(registry, false))
+	assert.NoError(t, unchecked.EmitSync(context.Background(), "order.created", "not an order"))
+}
+
+func TestOnTypedAndEmitTyped_DetectDrift(t *testing.T) {
+	registry := dispatch.NewSchemaRegistry()
+	require.NoError(t, dispatch.RegisterType[orderCreated](registry, "order.created", ""))
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard), dispatch.WithSchemas(registry, false))
+
+	_, err := dispatch.OnTyped(bus, "order.created", func(ctx context.Context, id string) {})
+	assert.ErrorIs(t, err, dispatch.ErrPayloadMismatch)
+
+	err = dispatch.EmitTyped(context.Background(), bus, "order.created", "order-1")
+	assert.ErrorIs(t, err, dispatch.ErrPayloadMismatch)
+
+	assert.NoError(t, dispatch.EmitTyped(context.Background(), bus, "order.created", orderCreated{ID: "1"}))
+}
+
+func TestSchemaRegistry_MarshalJSON(t *testing.T) {
+	registry := dispatch.NewSchemaRegistry()
+	require.NoError(t, registry.RegisterJSONSchema("user.created", "New user", []byte(`{"type":"object"}`)))
+	require.NoError(t, dispatch.RegisterType[orderCreated](registry, "order.created", "New order"))
+
+	data, err := json.Marshal(registry)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"signature": "order.created", "description": "New order", "type": "dispatch_test.orderCreated"},
+		{"signature": "user.created", "description": "New user", "schema": {"type": "object"}}
+	]`, string(data))
+}
@@ -0,0 +1,67 @@
+package dispatch_test
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+func TestDispatcher_ShutdownDrainsHandlers(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []dispatch.Option
+	}{
+		{name: "goroutine per handler"},
+		{name: "worker pool", opts: []dispatch.Option{dispatch.WithWorkerPool(1, 10)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := dispatch.NewDispatcher(newTestLogger(io.Discard), tt.opts...)
+
+			var completed atomic.Int32
+			bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+				time.Sleep(20 * time.Millisecond)
+				completed.Add(1)
+			})
+
+			for i := 0; i < 3; i++ {
+				require.NoError(t, bus.Emit(context.Background(), "test.event", nil))
+			}
+
+			require.NoError(t, bus.Shutdown(context.Background()))
+			assert.Equal(t, int32(3), completed.Load())
+
+			// New emissions are rejected
+			assert.ErrorIs(t, bus.Emit(context.Background(), "test.event", nil), dispatch.ErrClosed)
+			assert.ErrorIs(t, bus.EmitSync(context.Background(), "test.event", nil), dispatch.ErrClosed)
+
+			// Shutdown is idempotent
+			assert.NoError(t, bus.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestDispatcher_ShutdownTimeout(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	release := make(chan struct{})
+	defer close(release)
+
+	bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+		<-release
+	})
+	require.NoError(t, bus.Emit(context.Background(), "test.event", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, bus.Shutdown(ctx), context.DeadlineExceeded)
+}
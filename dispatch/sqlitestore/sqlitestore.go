@@ -0,0 +1,144 @@
+// Package sqlitestore provides a SQLite-backed dispatch.EventStore for persisting events before they
+// are dispatched. The store expects an events table with the following schema:
+//
+//	CREATE TABLE events (
+//		id TEXT PRIMARY KEY,
+//		signature TEXT NOT NULL,
+//		payload BLOB,
+//		timestamp INTEGER NOT NULL,
+//		dispatched_at INTEGER
+//	);
+//	CREATE INDEX events_dispatched_at_idx ON events(dispatched_at);
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+// SQLiteStore represents the event store.
+type SQLiteStore struct {
+	readDB      *sql.DB
+	writeDB     *sql.DB
+	stopCleanup chan bool
+}
+
+// NewSQLiteStore returns a new SQLiteStore instance, with a background cleanup goroutine
+// that runs every 5 minutes to remove dispatched events.
+func NewSQLiteStore(readDB *sql.DB, writeDB *sql.DB) *SQLiteStore {
+	return NewSQLiteStoreWithCleanupInterval(readDB, writeDB, 5*time.Minute)
+}
+
+// NewSQLiteStoreWithCleanupInterval returns a new SQLiteStore instance. The cleanupInterval
+// parameter controls how frequently dispatched events are removed by the background cleanup
+// goroutine. Setting it to 0 prevents the cleanup goroutine from running (i.e. dispatched
+// events are kept as an audit log).
+func NewSQLiteStoreWithCleanupInterval(readDB *sql.DB, writeDB *sql.DB, cleanupInterval time.Duration) *SQLiteStore {
+	p := &SQLiteStore{readDB: readDB, writeDB: writeDB}
+	if cleanupInterval > 0 {
+		p.stopCleanup = make(chan bool)
+		go p.startCleanup(cleanupInterval)
+	}
+	return p
+}
+
+// Save persists an event before it is dispatched. The payload is stored as JSON.
+func (p *SQLiteStore) Save(ctx context.Context, event dispatch.Event) error {
+	var payload []byte
+	if event.Payload != nil {
+		var err error
+		payload, err = json.Marshal(event.Payload)
+		if err != nil {
+			return fmt.Errorf("encoding payload: %w", err)
+		}
+	}
+
+	_, err := p.writeDB.ExecContext(ctx,
+		"INSERT INTO events (id, signature, payload, timestamp) VALUES ($1, $2, $3, $4)",
+		event.ID, event.Signature, payload, event.Timestamp.UnixNano())
+	return err
+}
+
+// MarkDispatched records that all handlers for the event have completed.
+func (p *SQLiteStore) MarkDispatched(ctx context.Context, id string) error {
+	_, err := p.writeDB.ExecContext(ctx,
+		"UPDATE events SET dispatched_at = $1 WHERE id = $2", time.Now().UnixNano(), id)
+	return err
+}
+
+// Pending returns the events that have not been dispatched, oldest first. Payloads are returned
+// as json.RawMessage.
+func (p *SQLiteStore) Pending(ctx context.Context) ([]dispatch.Event, error) {
+	rows, err := p.readDB.QueryContext(ctx,
+		"SELECT id, signature, payload, timestamp FROM events WHERE dispatched_at IS NULL ORDER BY timestamp, id")
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var events []dispatch.Event
+
+	for rows.Next() {
+		var (
+			event     dispatch.Event
+			payload   []byte
+			timestamp int64
+		)
+
+		err = rows.Scan(&event.ID, &event.Signature, &payload, &timestamp)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(payload) > 0 {
+			event.Payload = json.RawMessage(payload)
+		}
+		event.Timestamp = time.Unix(0, timestamp).UTC()
+
+		events = append(events, event)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (p *SQLiteStore) startCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for {
+		select {
+		case <-ticker.C:
+			err := p.deleteDispatched()
+			if err != nil {
+				log.Println(err)
+			}
+		case <-p.stopCleanup:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// StopCleanup terminates the background cleanup goroutine for the SQLiteStore instance.
+// This is mostly useful for transient stores, such as in tests.
+func (p *SQLiteStore) StopCleanup() {
+	if p.stopCleanup != nil {
+		p.stopCleanup <- true
+	}
+}
+
+func (p *SQLiteStore) deleteDispatched() error {
+	_, err := p.writeDB.Exec("DELETE FROM events WHERE dispatched_at IS NOT NULL")
+	return err
+}
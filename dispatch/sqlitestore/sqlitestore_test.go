@@ -0,0 +1,107 @@
+package sqlitestore_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3" // Import the SQLite driver
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/dispatch/sqlitestore"
+)
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func createDBWithEventsTable(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "events.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE events (
+		id TEXT PRIMARY KEY,
+		signature TEXT NOT NULL,
+		payload BLOB,
+		timestamp INTEGER NOT NULL,
+		dispatched_at INTEGER
+	);
+	CREATE INDEX events_dispatched_at_idx ON events(dispatched_at);`)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestSaveAndPending(t *testing.T) {
+	ctx := context.Background()
+	db := createDBWithEventsTable(t)
+	store := sqlitestore.NewSQLiteStoreWithCleanupInterval(db, db, 0)
+
+	first := dispatch.NewEvent("user.created", user{ID: "1", Name: "Ada"})
+	second := dispatch.NewEvent("system.ping", nil)
+	require.NoError(t, store.Save(ctx, first))
+	require.NoError(t, store.Save(ctx, second))
+
+	pending, err := store.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+
+	assert.Equal(t, first.ID, pending[0].ID)
+	assert.Equal(t, "user.created", pending[0].Signature)
+	assert.WithinDuration(t, first.Timestamp, pending[0].Timestamp, 0)
+
+	got, err := dispatch.PayloadAs[user](pending[0])
+	require.NoError(t, err)
+	assert.Equal(t, user{ID: "1", Name: "Ada"}, got)
+
+	assert.Nil(t, pending[1].Payload)
+
+	require.NoError(t, store.MarkDispatched(ctx, first.ID))
+	pending, err = store.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, second.ID, pending[0].ID)
+}
+
+func TestDispatcherReplay(t *testing.T) {
+	ctx := context.Background()
+	db := createDBWithEventsTable(t)
+	store := sqlitestore.NewSQLiteStoreWithCleanupInterval(db, db, 0)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Simulate an event that was saved but never completed before a restart
+	require.NoError(t, store.Save(ctx, dispatch.NewEvent("user.created", user{ID: "1", Name: "Ada"})))
+
+	bus := dispatch.NewDispatcher(logger, dispatch.WithEventStore(store))
+
+	var received []user
+	bus.On("user.created", dispatch.HandlePayload(func(ctx context.Context, u user) {
+		received = append(received, u)
+	}))
+
+	n, err := bus.Replay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []user{{ID: "1", Name: "Ada"}}, received)
+
+	// Newly emitted events are persisted and marked as dispatched once handled
+	bus.EmitSync(ctx, "user.created", user{ID: "2", Name: "Grace"})
+	assert.Len(t, received, 2)
+
+	pending, err := store.Pending(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	n, err = bus.Replay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
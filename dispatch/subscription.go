@@ -0,0 +1,86 @@
+package dispatch
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+var subscriptionID atomic.Uint64
+
+// registration pairs a handler with the ID of its subscription
+type registration struct {
+	id      uint64
+	handler Handler
+}
+
+// Subscription is a handle to a registered handler, returned by On and Once
+type Subscription struct {
+	id         uint64
+	signature  string
+	dispatcher *Dispatcher
+}
+
+// Signature returns the signature pattern the handler was registered for
+func (s *Subscription) Signature() string {
+	return s.signature
+}
+
+// Unsubscribe removes the handler from the dispatcher. It reports whether the handler was
+// still registered.
+func (s *Subscription) Unsubscribe() bool {
+	return s.dispatcher.Off(s)
+}
+
+// Off removes the handler for a subscription. It reports whether the handler was still registered.
+// Events that were already emitted may still be delivered to the handler.
+func (b *Dispatcher) Off(sub *Subscription) bool {
+	if sub == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	registrations := b.handlers[sub.signature]
+	for i, reg := range registrations {
+		if reg.id != sub.id {
+			continue
+		}
+
+		registrations = append(registrations[:i:i], registrations[i+1:]...)
+		if len(registrations) == 0 {
+			delete(b.handlers, sub.signature)
+		} else {
+			b.handlers[sub.signature] = registrations
+		}
+
+		b.logger.Debug("event handler removed",
+			slog.String("signature", sub.signature))
+		return true
+	}
+
+	return false
+}
+
+// Once registers a handler that is removed after it handles the first matching event.
+// Even when several matching events are emitted concurrently, the handler runs only once.
+func (b *Dispatcher) Once(signature string, handler Handler) *Subscription {
+	var (
+		fired atomic.Bool
+		sub   *Subscription
+		ready = make(chan struct{})
+	)
+
+	sub = b.On(signature, func(ctx context.Context, event Event) {
+		if !fired.CompareAndSwap(false, true) {
+			return
+		}
+		<-ready
+		b.Off(sub)
+		handler(ctx, event)
+	})
+	close(ready)
+
+	return sub
+}
@@ -0,0 +1,73 @@
+package dispatch_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+func TestDispatcher_Off(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	var first, second atomic.Int32
+	sub := bus.On("test.event", func(ctx context.Context, event dispatch.Event) {
+		first.Add(1)
+	})
+	bus.On("test.*", func(ctx context.Context, event dispatch.Event) {
+		second.Add(1)
+	})
+
+	assert.Equal(t, "test.event", sub.Signature())
+
+	bus.EmitSync(context.Background(), "test.event", nil)
+	assert.True(t, sub.Unsubscribe())
+	bus.EmitSync(context.Background(), "test.event", nil)
+
+	assert.Equal(t, int32(1), first.Load())
+	assert.Equal(t, int32(2), second.Load())
+
+	// Removing twice reports false
+	assert.False(t, bus.Off(sub))
+	assert.False(t, bus.Off(nil))
+}
+
+func TestDispatcher_Once(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	var calls atomic.Int32
+	bus.Once("test.event", func(ctx context.Context, event dispatch.Event) {
+		calls.Add(1)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bus.EmitSync(context.Background(), "test.event", nil)
+		}()
+	}
+	wg.Wait()
+
+	bus.EmitSync(context.Background(), "test.event", nil)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDispatcher_OnceUnsubscribeBeforeEvent(t *testing.T) {
+	bus := dispatch.NewDispatcher(newTestLogger(io.Discard))
+
+	var calls atomic.Int32
+	sub := bus.Once("test.event", func(ctx context.Context, event dispatch.Event) {
+		calls.Add(1)
+	})
+
+	assert.True(t, sub.Unsubscribe())
+	bus.EmitSync(context.Background(), "test.event", nil)
+	assert.Equal(t, int32(0), calls.Load())
+}
@@ -0,0 +1,340 @@
+// Package fanout provides helpers for handlers that call several internal services concurrently. It
+// standardizes parallel calls with per-call timeouts and retries, first-success hedging, and the
+// aggregation of partial failures into typed results.
+//
+// Call every service and collect all results:
+//
+//	results := fanout.All(r.Context(), []fanout.Call[Profile]{
+//	    {Name: "accounts", Fn: accounts.Profile},
+//	    {Name: "billing", Fn: billing.Profile},
+//	}, fanout.WithTimeout(500*time.Millisecond), fanout.WithRetries(2, 50*time.Millisecond))
+//
+//	for _, res := range results.Succeeded() {
+//	    // use res.Value
+//	}
+//	if err := results.Err(); err != nil {
+//	    // some calls failed; render what succeeded
+//	}
+//
+// Race replicas of the same service and use the first success:
+//
+//	res, err := fanout.First(r.Context(), calls, fanout.WithHedgeDelay(100*time.Millisecond))
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoCalls is returned by First when there are no calls to make
+var ErrNoCalls = errors.New("fanout: no calls")
+
+// Call is a single call to an internal service
+type Call[T any] struct {
+	// Name identifies the call in results and errors
+	Name string
+	// Fn performs the call. It must respect ctx cancellation.
+	Fn func(ctx context.Context) (T, error)
+	// Timeout overrides the timeout from WithTimeout for this call
+	Timeout time.Duration
+}
+
+// Result is the outcome of a single call
+type Result[T any] struct {
+	// Name is the name of the call
+	Name string
+	// Value is the value returned by a successful call
+	Value T
+	// Err is set when the call failed after all attempts
+	Err error
+	// Attempts is the number of times the call was made
+	Attempts int
+	// Duration is the total time spent on the call, including retries
+	Duration time.Duration
+}
+
+// OK reports whether the call succeeded
+func (r Result[T]) OK() bool {
+	return r.Err == nil
+}
+
+// Results holds the results of All, in the same order as the calls
+type Results[T any] []Result[T]
+
+// Succeeded returns the results of the calls that succeeded
+func (rs Results[T]) Succeeded() Results[T] {
+	var out Results[T]
+	for _, r := range rs {
+		if r.OK() {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Failed returns the results of the calls that failed
+func (rs Results[T]) Failed() Results[T] {
+	var out Results[T]
+	for _, r := range rs {
+		if !r.OK() {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Values returns the values of the calls that succeeded, keyed by call name
+func (rs Results[T]) Values() map[string]T {
+	out := make(map[string]T, len(rs))
+	for _, r := range rs {
+		if r.OK() {
+			out[r.Name] = r.Value
+		}
+	}
+	return out
+}
+
+// Err returns the errors of all failed calls joined with errors.Join, or nil if every call succeeded.
+// Each error is a *CallError, so the failing calls can be identified with errors.As.
+func (rs Results[T]) Err() error {
+	var errs []error
+	for _, r := range rs {
+		if !r.OK() {
+			errs = append(errs, &CallError{Name: r.Name, Err: r.Err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CallError is the error of a single failed call
+type CallError struct {
+	Name string
+	Err  error
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("fanout: %s: %v", e.Name, e.Err)
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// options configures All and First
+type options struct {
+	timeout        time.Duration
+	retries        int
+	backoff        time.Duration
+	maxConcurrency int
+	hedgeDelay     time.Duration
+	retryIf        func(error) bool
+}
+
+// Option configures All and First
+type Option func(*options)
+
+// WithTimeout sets the timeout for each attempt of each call
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRetries retries failed calls up to n more times, waiting backoff before the first retry and
+// doubling the wait for each retry after that
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = n
+		o.backoff = backoff
+	}
+}
+
+// WithRetryIf limits retries to errors for which fn returns true. By default, every error except
+// context cancellation of the parent context is retried.
+func WithRetryIf(fn func(error) bool) Option {
+	return func(o *options) {
+		o.retryIf = fn
+	}
+}
+
+// WithMaxConcurrency limits how many calls run at the same time in All. Zero means no limit.
+func WithMaxConcurrency(n int) Option {
+	return func(o *options) {
+		o.maxConcurrency = n
+	}
+}
+
+// WithHedgeDelay makes First start calls one at a time, starting the next call only if no call has
+// succeeded within d (or as soon as a call fails). Without a hedge delay, First starts every call at once.
+func WithHedgeDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.hedgeDelay = d
+	}
+}
+
+// All makes every call concurrently and waits for them to complete. Failed calls do not cancel the
+// others; their errors are available from the returned Results.
+func All[T any](ctx context.Context, calls []Call[T], opts ...Option) Results[T] {
+	o := newOptions(opts)
+	results := make(Results[T], len(calls))
+
+	var sem chan struct{}
+	if o.maxConcurrency > 0 {
+		sem = make(chan struct{}, o.maxConcurrency)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(calls))
+	for i, c := range calls {
+		go func() {
+			defer wg.Done()
+
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-ctx.Done():
+					results[i] = Result[T]{Name: c.Name, Err: ctx.Err()}
+					return
+				}
+			}
+
+			results[i] = run(ctx, c, o)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// First races the calls and returns the first successful result, canceling the calls that are still
+// running. If every call fails, First returns the error of each call joined with errors.Join.
+func First[T any](ctx context.Context, calls []Call[T], opts ...Option) (Result[T], error) {
+	if len(calls) == 0 {
+		return Result[T]{}, ErrNoCalls
+	}
+
+	o := newOptions(opts)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resultCh := make(chan Result[T], len(calls))
+	launch := func(c Call[T]) {
+		go func() { resultCh <- run(ctx, c, o) }()
+	}
+
+	next := 0
+	launchNext := func() {
+		if next < len(calls) {
+			launch(calls[next])
+			next++
+		}
+	}
+
+	if o.hedgeDelay <= 0 {
+		for next < len(calls) {
+			launchNext()
+		}
+	} else {
+		launchNext()
+	}
+
+	var (
+		failed  Results[T]
+		timer   *time.Timer
+		timerCh <-chan time.Time
+	)
+	if o.hedgeDelay > 0 && len(calls) > 1 {
+		timer = time.NewTimer(o.hedgeDelay)
+		defer timer.Stop()
+		timerCh = timer.C
+	}
+
+	for len(failed) < len(calls) {
+		select {
+		case res := <-resultCh:
+			if res.OK() {
+				return res, nil
+			}
+			failed = append(failed, res)
+			// Start the next call immediately instead of waiting for the hedge delay
+			launchNext()
+		case <-timerCh:
+			launchNext()
+			if next < len(calls) {
+				timer.Reset(o.hedgeDelay)
+			} else {
+				timerCh = nil
+			}
+		}
+	}
+
+	return Result[T]{}, failed.Err()
+}
+
+// newOptions applies the options to the defaults
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// run makes a single call, retrying failed attempts according to the options
+func run[T any](ctx context.Context, c Call[T], o *options) (res Result[T]) {
+	res.Name = c.Name
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	timeout := o.timeout
+	if c.Timeout > 0 {
+		timeout = c.Timeout
+	}
+
+	backoff := o.backoff
+	for attempt := 0; attempt <= o.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				res.Err = ctx.Err()
+				return res
+			}
+		}
+
+		res.Attempts++
+		res.Value, res.Err = callOnce(ctx, c.Fn, timeout)
+		if res.Err == nil {
+			return res
+		}
+
+		if ctx.Err() != nil || (o.retryIf != nil && !o.retryIf(res.Err)) {
+			return res
+		}
+	}
+
+	return res
+}
+
+// callOnce makes one attempt of a call with an optional timeout, recovering from panics
+func callOnce[T any](ctx context.Context, fn func(context.Context) (T, error), timeout time.Duration) (value T, err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn(ctx)
+}
@@ -0,0 +1,174 @@
+package fanout_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/fanout"
+)
+
+func value(v string, delay time.Duration) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		select {
+		case <-time.After(delay):
+			return v, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+func failure(err error) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		return "", err
+	}
+}
+
+func TestAll(t *testing.T) {
+	errBilling := errors.New("billing unavailable")
+
+	results := fanout.All(context.Background(), []fanout.Call[string]{
+		{Name: "accounts", Fn: value("alice", 0)},
+		{Name: "billing", Fn: failure(errBilling)},
+		{Name: "slow", Fn: value("late", time.Second), Timeout: 20 * time.Millisecond},
+		{Name: "panics", Fn: func(ctx context.Context) (string, error) { panic("boom") }},
+	})
+
+	require.Len(t, results, 4)
+	assert.Equal(t, map[string]string{"accounts": "alice"}, results.Values())
+	assert.Len(t, results.Succeeded(), 1)
+	assert.Len(t, results.Failed(), 3)
+
+	err := results.Err()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errBilling)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "panic: boom")
+
+	var callErr *fanout.CallError
+	require.ErrorAs(t, err, &callErr)
+	assert.Equal(t, "billing", callErr.Name)
+}
+
+func TestAll_Retries(t *testing.T) {
+	var attempts atomic.Int32
+	flaky := func(ctx context.Context) (string, error) {
+		if attempts.Add(1) < 3 {
+			return "", errors.New("transient")
+		}
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name         string
+		opts         []fanout.Option
+		wantOK       bool
+		wantAttempts int
+	}{
+		{name: "succeeds after retries", opts: []fanout.Option{fanout.WithRetries(2, time.Millisecond)}, wantOK: true, wantAttempts: 3},
+		{name: "not enough retries", opts: []fanout.Option{fanout.WithRetries(1, time.Millisecond)}, wantOK: false, wantAttempts: 2},
+		{
+			name: "retry condition",
+			opts: []fanout.Option{
+				fanout.WithRetries(5, time.Millisecond),
+				fanout.WithRetryIf(func(err error) bool { return false }),
+			},
+			wantOK:       false,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts.Store(0)
+			results := fanout.All(context.Background(), []fanout.Call[string]{{Name: "flaky", Fn: flaky}}, tt.opts...)
+			assert.Equal(t, tt.wantOK, results[0].OK())
+			assert.Equal(t, tt.wantAttempts, results[0].Attempts)
+		})
+	}
+}
+
+func TestAll_MaxConcurrency(t *testing.T) {
+	var running, maxSeen atomic.Int32
+	fn := func(ctx context.Context) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxSeen.Load()
+			if n <= m || maxSeen.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return int(n), nil
+	}
+
+	calls := make([]fanout.Call[int], 6)
+	for i := range calls {
+		calls[i] = fanout.Call[int]{Name: "call", Fn: fn}
+	}
+
+	results := fanout.All(context.Background(), calls, fanout.WithMaxConcurrency(2))
+	assert.NoError(t, results.Err())
+	assert.LessOrEqual(t, maxSeen.Load(), int32(2))
+}
+
+func TestFirst(t *testing.T) {
+	res, err := fanout.First(context.Background(), []fanout.Call[string]{
+		{Name: "slow", Fn: value("slow", time.Second)},
+		{Name: "fails", Fn: failure(errors.New("down"))},
+		{Name: "fast", Fn: value("fast", 10*time.Millisecond)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "fast", res.Name)
+	assert.Equal(t, "fast", res.Value)
+}
+
+func TestFirst_AllFail(t *testing.T) {
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+
+	_, err := fanout.First(context.Background(), []fanout.Call[string]{
+		{Name: "a", Fn: failure(errA)},
+		{Name: "b", Fn: failure(errB)},
+	})
+	assert.ErrorIs(t, err, errA)
+	assert.ErrorIs(t, err, errB)
+
+	_, err = fanout.First[string](context.Background(), nil)
+	assert.ErrorIs(t, err, fanout.ErrNoCalls)
+}
+
+func TestFirst_HedgeDelay(t *testing.T) {
+	var started atomic.Int32
+	track := func(fn func(context.Context) (string, error)) func(context.Context) (string, error) {
+		return func(ctx context.Context) (string, error) {
+			started.Add(1)
+			return fn(ctx)
+		}
+	}
+
+	// The primary answers before the hedge delay, so the backup is never started
+	res, err := fanout.First(context.Background(), []fanout.Call[string]{
+		{Name: "primary", Fn: track(value("primary", 5*time.Millisecond))},
+		{Name: "backup", Fn: track(value("backup", 0))},
+	}, fanout.WithHedgeDelay(100*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, "primary", res.Value)
+	assert.Equal(t, int32(1), started.Load())
+
+	// The primary is slow, so the backup is started after the hedge delay and wins
+	started.Store(0)
+	res, err = fanout.First(context.Background(), []fanout.Call[string]{
+		{Name: "primary", Fn: track(value("primary", time.Second))},
+		{Name: "backup", Fn: track(value("backup", 0))},
+	}, fanout.WithHedgeDelay(10*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, "backup", res.Value)
+	assert.Equal(t, int32(2), started.Load())
+}
@@ -0,0 +1,58 @@
+package hop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// LifecycleHook is a function run at a point of the app's lifecycle
+type LifecycleHook func(ctx context.Context) error
+
+// OnStart registers a hook run by StartModules once every module has started, e.g. to warm caches or run
+// migrations. Hooks run in the order they were registered, and all of them run even if one fails; their
+// errors are joined. The hooks are skipped when a module failed to start.
+func (a *App) OnStart(fn LifecycleHook) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onStart = append(a.onStart, fn)
+}
+
+// BeforeServerStart registers a hook run by Start after the modules and OnStart hooks, right before the
+// server starts listening, e.g. to announce readiness. Hooks run in the order they were registered; if any
+// fails, the server doesn't start and Start returns the joined errors.
+func (a *App) BeforeServerStart(fn LifecycleHook) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.beforeServerStart = append(a.beforeServerStart, fn)
+}
+
+// AfterStop registers a hook run by Stop once every shutdown phase has completed, e.g. to remove a pid
+// file or report the shutdown. Hooks run in the order they were registered, with the shutdown context;
+// their errors are joined with the errors of the shutdown phases.
+func (a *App) AfterStop(fn LifecycleHook) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.afterStop = append(a.afterStop, fn)
+}
+
+// runHooks runs hooks in order, logging and joining their errors
+func (a *App) runHooks(ctx context.Context, stage string, hooks []LifecycleHook) error {
+	var errs []error
+	for i, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			err = fmt.Errorf("%s hook %d: %w", stage, i+1, err)
+			errs = append(errs, err)
+			a.logger.Error("lifecycle hook failed", slog.String("stage", stage), slog.String("error", err.Error()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hooks returns a copy of a list of hooks, so they can run without holding the lock
+func (a *App) hooks(list *[]LifecycleHook) []LifecycleHook {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]LifecycleHook(nil), (*list)...)
+}
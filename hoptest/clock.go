@@ -0,0 +1,232 @@
+// Package hoptest provides helpers for testing hop applications and components.
+package hoptest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+)
+
+// Clock is a fake clock.Clock whose time only moves when the test advances it. Timers, tickers and
+// After channels fire as Advance moves past their deadlines, so time-dependent code can be tested
+// without sleeping.
+//
+//	clk := hoptest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	go worker.Run(clk)
+//	clk.BlockUntil(1)         // wait for the worker to start its ticker
+//	clk.Advance(time.Minute)  // the ticker fires
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+var _ clock.Clock = (*Clock)(nil)
+
+// waiter is a pending timer or ticker
+type waiter struct {
+	clock  *Clock
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewClock creates a fake clock set to now
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed since t
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Until returns the duration until t
+func (c *Clock) Until(t time.Time) time.Duration {
+	return t.Sub(c.Now())
+}
+
+// After returns a channel that receives the time once the clock has advanced by d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer creates a timer that fires once the clock has advanced by d
+func (c *Clock) NewTimer(d time.Duration) clock.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &waiter{clock: c, c: make(chan time.Time, 1)}
+	c.schedule(w, d)
+	return (*fakeTimer)(w)
+}
+
+// NewTicker creates a ticker that fires every time the clock advances by d. It panics if d is not
+// positive, like time.NewTicker.
+func (c *Clock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("hoptest: non-positive interval for NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &waiter{clock: c, period: d, c: make(chan time.Time, 1)}
+	c.schedule(w, d)
+	return (*fakeTicker)(w)
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers that are due along the way in
+// deadline order. Like real tickers, a ticker whose channel is full drops ticks.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.now.Add(d)
+	for {
+		next := c.nextDue(target)
+		if next == nil {
+			break
+		}
+		c.now = next.at
+		select {
+		case next.c <- c.now:
+		default:
+		}
+
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			c.remove(next)
+		}
+	}
+	if target.After(c.now) {
+		c.now = target
+	}
+}
+
+// Set moves the clock to t, firing the timers and tickers that are due. Times before the current time
+// are ignored.
+func (c *Clock) Set(t time.Time) {
+	c.Advance(t.Sub(c.Now()))
+}
+
+// BlockUntil blocks until at least n timers and tickers are waiting on the clock, so a test can make
+// sure the code under test is waiting before advancing the clock
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// Waiters returns the number of timers and tickers waiting on the clock
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// schedule makes w fire after d, firing it at once if d is not positive; c.mu must be held
+func (c *Clock) schedule(w *waiter, d time.Duration) {
+	if d <= 0 && w.period == 0 {
+		select {
+		case w.c <- c.now:
+		default:
+		}
+		return
+	}
+
+	w.at = c.now.Add(d)
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+}
+
+// nextDue returns the waiter with the earliest deadline no later than target; c.mu must be held
+func (c *Clock) nextDue(target time.Time) *waiter {
+	var next *waiter
+	for _, w := range c.waiters {
+		if w.at.After(target) {
+			continue
+		}
+		if next == nil || w.at.Before(next.at) {
+			next = w
+		}
+	}
+	return next
+}
+
+// remove stops w and reports whether it was waiting; c.mu must be held
+func (c *Clock) remove(w *waiter) bool {
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer waiter
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remove((*waiter)(t))
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	active := c.remove((*waiter)(t))
+	c.schedule((*waiter)(t), d)
+	return active
+}
+
+type fakeTicker waiter
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove((*waiter)(t))
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("hoptest: non-positive interval for Ticker.Reset")
+	}
+
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove((*waiter)(t))
+	t.period = d
+	c.schedule((*waiter)(t), d)
+}
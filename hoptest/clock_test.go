@@ -0,0 +1,150 @@
+package hoptest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/clock"
+	"github.com/patrickward/hop/hoptest"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// received returns the value waiting on c, if any
+func received(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case v := <-c:
+		return v, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestClock(t *testing.T) {
+	t.Run("time only moves when advanced", func(t *testing.T) {
+		clk := hoptest.NewClock(epoch)
+		assert.Equal(t, epoch, clk.Now())
+
+		clk.Advance(time.Hour)
+		assert.Equal(t, epoch.Add(time.Hour), clk.Now())
+		assert.Equal(t, time.Hour, clk.Since(epoch))
+		assert.Equal(t, time.Hour, clk.Until(epoch.Add(2*time.Hour)))
+
+		clk.Set(epoch.Add(3 * time.Hour))
+		assert.Equal(t, epoch.Add(3*time.Hour), clk.Now())
+		clk.Set(epoch)
+		assert.Equal(t, epoch.Add(3*time.Hour), clk.Now(), "the clock never moves back")
+	})
+
+	t.Run("timers fire at their deadline", func(t *testing.T) {
+		clk := hoptest.NewClock(epoch)
+		timer := clk.NewTimer(time.Minute)
+		after := clk.After(2 * time.Minute)
+		assert.Equal(t, 2, clk.Waiters())
+
+		clk.Advance(59 * time.Second)
+		_, fired := received(timer.C())
+		assert.False(t, fired)
+
+		clk.Advance(5 * time.Minute)
+		at, fired := received(timer.C())
+		assert.True(t, fired)
+		assert.Equal(t, epoch.Add(time.Minute), at, "timers receive their deadline, not the target time")
+		at, fired = received(after)
+		assert.True(t, fired)
+		assert.Equal(t, epoch.Add(2*time.Minute), at)
+		assert.Equal(t, 0, clk.Waiters())
+
+		assert.False(t, timer.Stop(), "the timer already fired")
+		assert.False(t, timer.Reset(time.Minute))
+		clk.Advance(time.Minute)
+		_, fired = received(timer.C())
+		assert.True(t, fired, "a reset timer fires again")
+	})
+
+	t.Run("stopped timers don't fire", func(t *testing.T) {
+		clk := hoptest.NewClock(epoch)
+		timer := clk.NewTimer(time.Minute)
+		assert.True(t, timer.Stop())
+
+		clk.Advance(time.Hour)
+		_, fired := received(timer.C())
+		assert.False(t, fired)
+	})
+
+	t.Run("tickers fire every period", func(t *testing.T) {
+		clk := hoptest.NewClock(epoch)
+		ticker := clk.NewTicker(10 * time.Second)
+
+		var ticks []time.Time
+		for range 3 {
+			clk.Advance(10 * time.Second)
+			at, fired := received(ticker.C())
+			require.True(t, fired)
+			ticks = append(ticks, at)
+		}
+		assert.Equal(t, []time.Time{epoch.Add(10 * time.Second), epoch.Add(20 * time.Second), epoch.Add(30 * time.Second)}, ticks)
+
+		clk.Advance(time.Minute)
+		_, fired := received(ticker.C())
+		assert.True(t, fired)
+		_, fired = received(ticker.C())
+		assert.False(t, fired, "ticks are dropped while the channel is full")
+
+		ticker.Reset(time.Hour)
+		clk.Advance(time.Minute)
+		_, fired = received(ticker.C())
+		assert.False(t, fired)
+
+		ticker.Stop()
+		assert.Equal(t, 0, clk.Waiters())
+	})
+
+	t.Run("block until code under test waits", func(t *testing.T) {
+		clk := hoptest.NewClock(epoch)
+		done := make(chan struct{})
+		go func() {
+			<-clk.After(time.Minute)
+			close(done)
+		}()
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("waiter did not wake up")
+		}
+	})
+}
+
+func TestWithTimeout(t *testing.T) {
+	t.Run("fake clock", func(t *testing.T) {
+		clk := hoptest.NewClock(epoch)
+		ctx, cancel := clock.WithTimeout(context.Background(), clk, time.Minute)
+		defer cancel()
+
+		clk.BlockUntil(1)
+		require.NoError(t, ctx.Err())
+
+		clk.Advance(time.Minute)
+		select {
+		case <-ctx.Done():
+			assert.ErrorIs(t, context.Cause(ctx), context.DeadlineExceeded)
+		case <-time.After(time.Second):
+			t.Fatal("context was not canceled")
+		}
+	})
+
+	t.Run("real clock", func(t *testing.T) {
+		ctx, cancel := clock.WithTimeout(context.Background(), clock.Real(), time.Millisecond)
+		defer cancel()
+
+		<-ctx.Done()
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	})
+}
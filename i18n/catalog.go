@@ -0,0 +1,284 @@
+package i18n
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+)
+
+// pluralForms maps the plural categories used in catalogs to their forms
+var pluralForms = map[string]plural.Form{
+	"zero":  plural.Zero,
+	"one":   plural.One,
+	"two":   plural.Two,
+	"few":   plural.Few,
+	"many":  plural.Many,
+	"other": plural.Other,
+}
+
+// parseJSON parses a JSON catalog
+func parseJSON(data []byte) (map[string]message, error) {
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return flatten(tree)
+}
+
+// parseTOML parses a TOML catalog into the same tree as a JSON catalog before flattening it
+func parseTOML(data []byte) (map[string]message, error) {
+	tree := make(map[string]any)
+	table := tree
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.LastIndexByte(line, ']')
+			if end < 0 || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table header", lineNo)
+			}
+			keys, err := parseTOMLKey(line[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			if table, err = subtable(tree, keys); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			continue
+		}
+
+		rawKey, rawValue, ok := cutTOMLAssignment(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = \"value\"", lineNo)
+		}
+		keys, err := parseTOMLKey(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		value, err := parseTOMLString(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		parent, err := subtable(table, keys[:len(keys)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		parent[keys[len(keys)-1]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return flatten(tree)
+}
+
+// cutTOMLAssignment splits a key/value line at the first "=" outside a quoted key
+func cutTOMLAssignment(line string) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseTOMLKey splits a bare, quoted or dotted key into its parts
+func parseTOMLKey(raw string) ([]string, error) {
+	var keys []string
+	for rest := strings.TrimSpace(raw); ; {
+		var key string
+		switch {
+		case rest == "":
+			return nil, errors.New("empty key")
+		case rest[0] == '"' || rest[0] == '\'':
+			end := strings.IndexByte(rest[1:], rest[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated key %s", raw)
+			}
+			key, rest = rest[1:end+1], rest[end+2:]
+		default:
+			end := strings.IndexByte(rest, '.')
+			if end < 0 {
+				end = len(rest)
+			}
+			key, rest = strings.TrimSpace(rest[:end]), rest[end:]
+			if key == "" {
+				return nil, fmt.Errorf("invalid key %s", raw)
+			}
+		}
+		keys = append(keys, key)
+
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			return keys, nil
+		}
+		if rest[0] != '.' {
+			return nil, fmt.Errorf("invalid key %s", raw)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+}
+
+// parseTOMLString parses a basic or literal single-line string, followed by an optional comment
+func parseTOMLString(raw string) (string, error) {
+	if raw == "" {
+		return "", errors.New("missing value")
+	}
+
+	switch raw[0] {
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 || strings.HasPrefix(raw, "'''") {
+			return "", errors.New("unsupported literal string")
+		}
+		return raw[1 : end+1], checkTrailing(raw[end+2:])
+	case '"':
+		if strings.HasPrefix(raw, `"""`) {
+			return "", errors.New("multi-line strings are not supported")
+		}
+		for i := 1; i < len(raw); i++ {
+			switch raw[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(raw[:i+1])
+				if err != nil {
+					return "", fmt.Errorf("invalid string %s", raw[:i+1])
+				}
+				return value, checkTrailing(raw[i+1:])
+			}
+		}
+		return "", errors.New("unterminated string")
+	default:
+		return "", fmt.Errorf("unsupported value %s: messages must be strings", raw)
+	}
+}
+
+// checkTrailing checks that only a comment follows a value
+func checkTrailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && rest[0] != '#' {
+		return fmt.Errorf("unexpected %s after value", rest)
+	}
+	return nil
+}
+
+// subtable returns the table at keys under tree, creating it if needed
+func subtable(tree map[string]any, keys []string) (map[string]any, error) {
+	table := tree
+	for _, key := range keys {
+		switch v := table[key].(type) {
+		case nil:
+			next := make(map[string]any)
+			table[key] = next
+			table = next
+		case map[string]any:
+			table = v
+		default:
+			return nil, fmt.Errorf("key %s is already a message", key)
+		}
+	}
+	return table, nil
+}
+
+// flatten turns a tree of tables into messages keyed by dotted IDs. Tables whose keys are all plural
+// categories become plural messages.
+func flatten(tree map[string]any) (map[string]message, error) {
+	messages := make(map[string]message)
+
+	var walk func(prefix string, table map[string]any) error
+	walk = func(prefix string, table map[string]any) error {
+		keys := make([]string, 0, len(table))
+		for key := range table {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			id := key
+			if prefix != "" {
+				id = prefix + "." + key
+			}
+
+			switch v := table[key].(type) {
+			case string:
+				messages[id] = message{text: v}
+			case map[string]any:
+				if forms, ok := pluralTable(v); ok {
+					msg, err := pluralMessage(forms)
+					if err != nil {
+						return fmt.Errorf("message %s: %w", id, err)
+					}
+					messages[id] = msg
+					continue
+				}
+				if err := walk(id, v); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("message %s: expected a string or a table, got %T", id, v)
+			}
+		}
+		return nil
+	}
+
+	if err := walk("", tree); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// pluralTable returns the forms of a table whose keys are all plural categories holding strings
+func pluralTable(table map[string]any) (map[string]string, bool) {
+	if len(table) == 0 {
+		return nil, false
+	}
+
+	forms := make(map[string]string, len(table))
+	for key, value := range table {
+		text, ok := value.(string)
+		if _, isForm := pluralForms[key]; !isForm || !ok {
+			return nil, false
+		}
+		forms[key] = text
+	}
+	return forms, true
+}
+
+// pluralMessage creates a plural message from forms keyed by plural category
+func pluralMessage(forms map[string]string) (message, error) {
+	msg := message{plural: make(map[plural.Form]string, len(forms))}
+	for category, text := range forms {
+		form, ok := pluralForms[category]
+		if !ok {
+			return message{}, fmt.Errorf("unknown plural category %s", category)
+		}
+		msg.plural[form] = text
+	}
+	if _, ok := msg.plural[plural.Other]; !ok {
+		return message{}, errors.New(`missing the "other" plural form`)
+	}
+	msg.text = msg.plural[plural.Other]
+	return msg, nil
+}
@@ -0,0 +1,86 @@
+package i18n
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"strconv"
+)
+
+// FuncMap returns the template functions translating messages with the Localizer of the request:
+//
+//   - t: translates a message, e.g. {{t .Page "greeting" "name" .User.Name}}
+//   - tn: translates a message with plural forms, e.g. {{tn .Page "cart.items" .Count}}
+//
+// The first argument gives access to the Localizer: the page data or anything else with a Context method,
+// a context.Context, an *http.Request or a *Localizer. Without a Localizer, the message ID is returned.
+// The render package includes these functions in its default function map.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"t": func(src any, id string, args ...any) string {
+			l := localizerFrom(src)
+			if l == nil {
+				return id
+			}
+			return l.T(id, args...)
+		},
+		"tn": func(src any, id string, count any, args ...any) string {
+			l := localizerFrom(src)
+			if l == nil {
+				return id
+			}
+			return l.TN(id, toInt(count), args...)
+		},
+	}
+}
+
+// localizerFrom returns the Localizer reachable from a template argument, or nil
+func localizerFrom(src any) *Localizer {
+	switch v := src.(type) {
+	case *Localizer:
+		return v
+	case context.Context:
+		return FromContext(v)
+	case *http.Request:
+		return FromContext(v.Context())
+	case interface{ Context() context.Context }:
+		if ctx := v.Context(); ctx != nil {
+			return FromContext(ctx)
+		}
+	}
+	return nil
+}
+
+// toInt converts a template count to an int, returning 0 for values that aren't numbers
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int8:
+		return int(n)
+	case int16:
+		return int(n)
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case uint:
+		return int(n)
+	case uint8:
+		return int(n)
+	case uint16:
+		return int(n)
+	case uint32:
+		return int(n)
+	case uint64:
+		return int(n)
+	case float32:
+		return int(n)
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
@@ -0,0 +1,219 @@
+// Package i18n localizes applications: message catalogs loaded from JSON or TOML files, locale negotiation
+// from the session, a cookie or the Accept-Language header, and plural rules from golang.org/x/text.
+//
+// Catalogs are files named after their language, holding messages keyed by ID. Nested tables are flattened
+// with dots, and a table whose keys are all plural categories (zero, one, two, few, many, other) is a
+// plural message:
+//
+//	# locales/en.toml
+//	greeting = "Hello, {name}!"
+//
+//	[cart.items]
+//	one = "{count} item"
+//	other = "{count} items"
+//
+// The Middleware stores a Localizer for the negotiated language in the request context, where handlers
+// read it with FromContext and templates use the t and tn functions:
+//
+//	bundle := i18n.NewBundle(language.English)
+//	if err := bundle.LoadFS(locales, "locales"); err != nil { ... }
+//	router.Use(bundle.Middleware(nil))
+//
+//	<h1>{{t .Page "greeting" "name" .User.Name}}</h1>
+//	<p>{{tn .Page "cart.items" .Count}}</p>
+package i18n
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// message is a translated message, with plural forms when it depends on a count
+type message struct {
+	text   string
+	plural map[plural.Form]string
+}
+
+// Bundle holds the message catalogs of every supported language
+type Bundle struct {
+	mu       sync.RWMutex
+	fallback language.Tag
+	tags     []language.Tag
+	catalogs map[language.Tag]map[string]message
+	matcher  language.Matcher
+}
+
+// NewBundle creates an empty Bundle. The fallback language is used when the client accepts none of the
+// supported languages, and for messages missing from the catalog of the negotiated language.
+func NewBundle(fallback language.Tag) *Bundle {
+	return &Bundle{
+		fallback: fallback,
+		tags:     []language.Tag{fallback},
+		catalogs: map[language.Tag]map[string]message{fallback: {}},
+	}
+}
+
+// Fallback returns the fallback language of the bundle
+func (b *Bundle) Fallback() language.Tag {
+	return b.fallback
+}
+
+// Languages returns the languages the bundle has catalogs for, the fallback language first
+func (b *Bundle) Languages() []language.Tag {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]language.Tag(nil), b.tags...)
+}
+
+// Add adds a message to the catalog of a language, replacing any message with the same ID
+func (b *Bundle) Add(tag language.Tag, id, text string) {
+	b.add(tag, map[string]message{id: {text: text}})
+}
+
+// AddPlural adds a plural message to the catalog of a language. Forms are keyed by plural category: zero,
+// one, two, few, many and other. The "other" form should always be present.
+func (b *Bundle) AddPlural(tag language.Tag, id string, forms map[string]string) error {
+	msg, err := pluralMessage(forms)
+	if err != nil {
+		return fmt.Errorf("i18n: message %s: %w", id, err)
+	}
+	b.add(tag, map[string]message{id: msg})
+	return nil
+}
+
+// ParseJSON adds the messages of a JSON catalog to the catalog of a language
+func (b *Bundle) ParseJSON(tag language.Tag, data []byte) error {
+	messages, err := parseJSON(data)
+	if err != nil {
+		return err
+	}
+	b.add(tag, messages)
+	return nil
+}
+
+// ParseTOML adds the messages of a TOML catalog to the catalog of a language. Only the parts of TOML that
+// catalogs need are supported: tables, dotted keys, comments and single-line strings.
+func (b *Bundle) ParseTOML(tag language.Tag, data []byte) error {
+	messages, err := parseTOML(data)
+	if err != nil {
+		return err
+	}
+	b.add(tag, messages)
+	return nil
+}
+
+// LoadFS loads every .json and .toml catalog in dir, e.g. "locales/en.json" or "locales/pt-BR.toml". The
+// file name, without its extension, is the language of the catalog.
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("i18n: reading catalogs: %w", err)
+	}
+
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".json" && ext != ".toml") {
+			continue
+		}
+
+		tag, err := language.Parse(strings.TrimSuffix(entry.Name(), ext))
+		if err != nil {
+			return fmt.Errorf("i18n: catalog %s: %w", entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("i18n: reading catalog %s: %w", entry.Name(), err)
+		}
+
+		if ext == ".json" {
+			err = b.ParseJSON(tag, data)
+		} else {
+			err = b.ParseTOML(tag, data)
+		}
+		if err != nil {
+			return fmt.Errorf("i18n: catalog %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Match returns the supported language that best matches the preferences, given in order as language tags
+// or Accept-Language header values. It returns the fallback language when none matches.
+func (b *Bundle) Match(preferences ...string) language.Tag {
+	b.mu.Lock()
+	if b.matcher == nil {
+		b.matcher = language.NewMatcher(b.tags)
+	}
+	matcher, tags := b.matcher, b.tags
+	b.mu.Unlock()
+
+	_, index, confidence := matcher.Match(parsePreferences(preferences)...)
+	if confidence == language.No {
+		return b.fallback
+	}
+	return tags[index]
+}
+
+// Localizer returns a Localizer translating messages to the language
+func (b *Bundle) Localizer(tag language.Tag) *Localizer {
+	return &Localizer{bundle: b, tag: tag}
+}
+
+// add merges messages into the catalog of a language
+func (b *Bundle) add(tag language.Tag, messages map[string]message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	catalog, ok := b.catalogs[tag]
+	if !ok {
+		catalog = make(map[string]message, len(messages))
+		b.catalogs[tag] = catalog
+		b.tags = append(b.tags, tag)
+		b.matcher = nil
+	}
+	for id, msg := range messages {
+		catalog[id] = msg
+	}
+}
+
+// lookup returns the message for the language, falling back to its parent languages, e.g. "en" for
+// "en-GB", and then to the fallback language
+func (b *Bundle) lookup(tag language.Tag, id string) (message, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for t := tag; ; t = t.Parent() {
+		if msg, ok := b.catalogs[t][id]; ok {
+			return msg, true
+		}
+		if t.IsRoot() {
+			break
+		}
+	}
+
+	msg, ok := b.catalogs[b.fallback][id]
+	return msg, ok
+}
+
+// parsePreferences parses language tags and Accept-Language values, skipping invalid ones
+func parsePreferences(preferences []string) []language.Tag {
+	var tags []language.Tag
+	for _, pref := range preferences {
+		if pref == "" {
+			continue
+		}
+		parsed, _, err := language.ParseAcceptLanguage(pref)
+		if err != nil {
+			continue
+		}
+		tags = append(tags, parsed...)
+	}
+	return tags
+}
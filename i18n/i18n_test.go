@@ -0,0 +1,176 @@
+package i18n_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+
+	"github.com/patrickward/hop/i18n"
+	"github.com/patrickward/hop/render"
+)
+
+var locales = fstest.MapFS{
+	"locales/en.json": {Data: []byte(`{
+		"greeting": "Hello, {name}!",
+		"nav": {"home": "Home", "about": "About"},
+		"cart": {"items": {"zero": "Your cart is empty", "one": "{count} item", "other": "{count} items"}}
+	}`)},
+	"locales/fr.toml": {Data: []byte(`# French
+greeting = "Bonjour, {name} !"
+nav.home = 'Accueil' # inline comment
+
+[cart.items]
+one = "{count} article"
+other = "{count} articles"
+`)},
+	"locales/pl.toml": {Data: []byte(`
+[files]
+one = "{count} plik"
+few = "{count} pliki"
+many = "{count} plików"
+other = "{count} pliku"
+`)},
+	"locales/README.md": {Data: []byte("ignored")},
+}
+
+func newBundle(t *testing.T) *i18n.Bundle {
+	t.Helper()
+	bundle := i18n.NewBundle(language.English)
+	require.NoError(t, bundle.LoadFS(locales, "locales"))
+	return bundle
+}
+
+func TestBundle(t *testing.T) {
+	bundle := newBundle(t)
+	assert.Equal(t, []language.Tag{language.English, language.French, language.Polish}, bundle.Languages())
+
+	t.Run("translates messages", func(t *testing.T) {
+		en := bundle.Localizer(language.English)
+		assert.Equal(t, "Hello, Ada!", en.T("greeting", "name", "Ada"))
+		assert.Equal(t, "About", en.T("nav.about"))
+		assert.Equal(t, "Hello, {name}!", en.T("greeting"), "placeholders without a value are kept")
+		assert.Equal(t, "missing.key", en.T("missing.key"))
+
+		fr := bundle.Localizer(language.French)
+		assert.Equal(t, "Bonjour, Ada !", fr.T("greeting", "name", "Ada"))
+		assert.Equal(t, "Accueil", fr.T("nav.home"))
+		assert.Equal(t, "About", fr.T("nav.about"), "missing messages come from the fallback language")
+
+		caFR := bundle.Localizer(language.MustParse("fr-CA"))
+		assert.Equal(t, "Accueil", caFR.T("nav.home"), "regional languages use their parent's catalog")
+	})
+
+	t.Run("selects plural forms", func(t *testing.T) {
+		en := bundle.Localizer(language.English)
+		assert.Equal(t, "Your cart is empty", en.TN("cart.items", 0))
+		assert.Equal(t, "1 item", en.TN("cart.items", 1))
+		assert.Equal(t, "5 items", en.TN("cart.items", 5))
+
+		fr := bundle.Localizer(language.French)
+		assert.Equal(t, "0 article", fr.TN("cart.items", 0), "zero is singular in French")
+		assert.Equal(t, "2 articles", fr.TN("cart.items", 2))
+
+		pl := bundle.Localizer(language.Polish)
+		assert.Equal(t, "1 plik", pl.TN("files", 1))
+		assert.Equal(t, "3 pliki", pl.TN("files", 3))
+		assert.Equal(t, "5 plików", pl.TN("files", 5))
+		assert.Equal(t, "22 pliki", pl.TN("files", 22))
+	})
+
+	t.Run("matches preferences", func(t *testing.T) {
+		assert.Equal(t, language.French, bundle.Match("fr-CH, en;q=0.5"))
+		assert.Equal(t, language.Polish, bundle.Match("", "pl", "fr"))
+		assert.Equal(t, language.English, bundle.Match("ja"))
+		assert.Equal(t, language.English, bundle.Match())
+	})
+
+	t.Run("rejects invalid catalogs", func(t *testing.T) {
+		b := i18n.NewBundle(language.English)
+		assert.Error(t, b.ParseJSON(language.English, []byte(`{"count": 1}`)))
+		assert.Error(t, b.ParseTOML(language.English, []byte(`key = 1`)))
+		assert.Error(t, b.ParseTOML(language.English, []byte("key = \"\"\"\nmulti\n\"\"\"")))
+		assert.Error(t, b.ParseTOML(language.English, []byte("a = \"x\"\na.b = \"y\"")))
+		assert.Error(t, b.AddPlural(language.English, "items", map[string]string{"one": "item"}))
+		assert.Error(t, b.LoadFS(fstest.MapFS{"locales/not a language.json": {Data: []byte(`{}`)}}, "locales"))
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	bundle := newBundle(t)
+
+	serve := func(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	greet := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(i18n.FromContext(r.Context()).T("nav.home")))
+	})
+
+	t.Run("accept language", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", "de, fr;q=0.8, en;q=0.5")
+		w := serve(bundle.Middleware(nil)(greet), r)
+		assert.Equal(t, "Accueil", w.Body.String())
+		assert.Equal(t, "fr", w.Header().Get("Content-Language"))
+	})
+
+	t.Run("cookie wins over accept language", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", "fr")
+		r.AddCookie(&http.Cookie{Name: i18n.DefaultCookieName, Value: "en"})
+		assert.Equal(t, "Home", serve(bundle.Middleware(nil)(greet), r).Body.String())
+	})
+
+	t.Run("session wins over cookie", func(t *testing.T) {
+		mw := bundle.Middleware(func(opts *i18n.MiddlewareOptions) {
+			opts.SessionLocale = func(r *http.Request) string { return "fr" }
+			opts.ContentLanguage = false
+		})
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: i18n.DefaultCookieName, Value: "en"})
+		w := serve(mw(greet), r)
+		assert.Equal(t, "Accueil", w.Body.String())
+		assert.Empty(t, w.Header().Get("Content-Language"))
+	})
+
+	t.Run("unsupported languages fall back", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", "ja")
+		r.AddCookie(&http.Cookie{Name: i18n.DefaultCookieName, Value: "xx"})
+		assert.Equal(t, "Home", serve(bundle.Middleware(nil)(greet), r).Body.String())
+	})
+}
+
+func TestFuncMap(t *testing.T) {
+	bundle := newBundle(t)
+	tm, err := render.NewTemplateManager(render.Sources{
+		"": fstest.MapFS{
+			"layouts/base.html": {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/cart.html": {Data: []byte(
+				`{{define "page:main"}}{{t .Page "greeting" "name" .Name}} {{tn .Page "cart.items" .Count}}{{end}}`)},
+		},
+	}, render.TemplateManagerOptions{Logger: slog.Default()})
+	require.NoError(t, err)
+
+	handler := bundle.Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tm.NewResponse().Path("cart").Data("Name", "Ada").Data("Count", 3).Render(w, r)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "fr")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "Bonjour, Ada ! 3 articles", w.Body.String())
+
+	// Without the middleware, message IDs are rendered
+	w = httptest.NewRecorder()
+	tm.NewResponse().Path("cart").Data("Name", "Ada").Data("Count", 3).Render(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "greeting cart.items", w.Body.String())
+}
@@ -0,0 +1,109 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// localizerKey is the context key for the Localizer
+type localizerKey struct{}
+
+// Localizer translates messages to a language. Messages missing from its catalog are taken from the
+// catalogs of its parent languages and then of the fallback language; unknown messages are returned as
+// their ID, so they stand out on the page.
+type Localizer struct {
+	bundle *Bundle
+	tag    language.Tag
+}
+
+// Language returns the language of the Localizer
+func (l *Localizer) Language() language.Tag {
+	return l.tag
+}
+
+// T translates a message. Args are pairs of placeholder names and values: T("greeting", "name", "Ada")
+// replaces {name} in the message with "Ada".
+func (l *Localizer) T(id string, args ...any) string {
+	msg, ok := l.bundle.lookup(l.tag, id)
+	if !ok {
+		return id
+	}
+	return interpolate(msg.text, args)
+}
+
+// TN translates a message that depends on a count, selecting its plural form with the plural rules of the
+// language. The count is available to the message as {count}, next to the placeholders in args.
+func (l *Localizer) TN(id string, count int, args ...any) string {
+	msg, ok := l.bundle.lookup(l.tag, id)
+	if !ok {
+		return id
+	}
+
+	text := msg.text
+	if msg.plural != nil {
+		n := count
+		if n < 0 {
+			n = -n
+		}
+		form := plural.Cardinal.MatchPlural(l.tag, n, 0, 0, 0, 0)
+		if _, ok := msg.plural[plural.Zero]; ok && count == 0 {
+			// An explicit zero form, e.g. "No items", wins over the rules of the language
+			form = plural.Zero
+		}
+		if formText, ok := msg.plural[form]; ok {
+			text = formText
+		}
+	}
+	return interpolate(text, append([]any{"count", count}, args...))
+}
+
+// WithLocalizer returns a copy of ctx that carries the Localizer
+func WithLocalizer(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, localizerKey{}, l)
+}
+
+// FromContext returns the Localizer stored in ctx by the Middleware, or nil if there is none
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(localizerKey{}).(*Localizer)
+	return l
+}
+
+// interpolate replaces the {name} placeholders of text with the values of args, given as name/value pairs.
+// Placeholders without a value are left as they are.
+func interpolate(text string, args []any) string {
+	if len(args) < 2 || !strings.Contains(text, "{") {
+		return text
+	}
+
+	values := make(map[string]string, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		values[fmt.Sprint(args[i])] = fmt.Sprint(args[i+1])
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		sb.WriteString(text[:start])
+		if value, ok := values[text[start+1:end]]; ok {
+			sb.WriteString(value)
+		} else {
+			sb.WriteString(text[start : end+1])
+		}
+		text = text[end+1:]
+	}
+	sb.WriteString(text)
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package i18n
+
+import (
+	"net/http"
+)
+
+// DefaultCookieName is the default name of the cookie holding the language chosen by the user
+const DefaultCookieName = "lang"
+
+// MiddlewareOptions configures the locale negotiation of the Middleware
+type MiddlewareOptions struct {
+	// CookieName is the cookie holding the language chosen by the user. Default is DefaultCookieName; "-"
+	// disables the cookie.
+	CookieName string
+
+	// SessionLocale, when set, returns the language stored in the user's session, which wins over the
+	// cookie and the Accept-Language header, e.g.
+	//
+	//	func(r *http.Request) string { return sm.GetString(r.Context(), "lang") }
+	SessionLocale func(r *http.Request) string
+
+	// ContentLanguage sets the Content-Language response header to the negotiated language. Default is
+	// true.
+	ContentLanguage bool
+}
+
+// Middleware returns middleware that negotiates the language of each request and stores a Localizer for
+// it in the request context. The language is taken, in order, from the session, the cookie and the
+// Accept-Language header, keeping the first one the bundle supports, and falls back to the bundle's
+// fallback language.
+func (b *Bundle) Middleware(optsFunc func(opts *MiddlewareOptions)) func(http.Handler) http.Handler {
+	opts := MiddlewareOptions{
+		CookieName:      DefaultCookieName,
+		ContentLanguage: true,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.CookieName == "" {
+		opts.CookieName = DefaultCookieName
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tag := b.Match(b.preferences(r, opts)...)
+			if opts.ContentLanguage {
+				w.Header().Set("Content-Language", tag.String())
+			}
+			next.ServeHTTP(w, r.WithContext(WithLocalizer(r.Context(), b.Localizer(tag))))
+		})
+	}
+}
+
+// preferences returns the language preferences of a request, most important first
+func (b *Bundle) preferences(r *http.Request, opts MiddlewareOptions) []string {
+	var prefs []string
+	if opts.SessionLocale != nil {
+		prefs = append(prefs, opts.SessionLocale(r))
+	}
+	if opts.CookieName != "-" {
+		if cookie, err := r.Cookie(opts.CookieName); err == nil {
+			prefs = append(prefs, cookie.Value)
+		}
+	}
+	return append(prefs, r.Header.Get("Accept-Language"))
+}
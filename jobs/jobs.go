@@ -0,0 +1,119 @@
+// Package jobs provides durable background jobs with at-least-once delivery. Jobs are stored in a Store
+// and claimed by workers with a lease that hides them from other workers for a visibility timeout. A job
+// is removed only when its handler succeeds; if a worker crashes mid-job, the lease expires and another
+// worker picks the job up again. Failed jobs are retried with a backoff, and jobs that keep failing, or
+// keep crashing their workers, are moved to a dead letter list instead of being retried forever.
+//
+// Implementations are provided for SQLite and Postgres (via database/sql) and memory (for tests).
+//
+//	manager := jobs.NewManager(jobs.NewSQLStore(db, jobs.DialectSQLite), func(opts *jobs.Options) {
+//	    opts.Workers = 4
+//	    opts.Metrics = collector
+//	})
+//	manager.Handle("send-invoice", func(ctx context.Context, job *jobs.Job) error {
+//	    var invoice Invoice
+//	    if err := job.Decode(&invoice); err != nil {
+//	        return jobs.Permanent(err)
+//	    }
+//	    return sendInvoice(ctx, invoice)
+//	})
+//
+//	id, err := manager.Enqueue(ctx, "send-invoice", invoice, jobs.Delay(time.Minute))
+//	app.RegisterShutdownPhase(hop.ShutdownStopJobs, "jobs", manager.Stop)
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrLeaseLost is returned when a job's lease expired and the job may have been claimed by another worker
+var ErrLeaseLost = errors.New("jobs: lease lost")
+
+// Job is a unit of background work
+type Job struct {
+	// ID uniquely identifies the job
+	ID string
+	// Queue is the name of the queue the job belongs to
+	Queue string
+	// Type selects the handler that runs the job
+	Type string
+	// Payload is the JSON encoded job data
+	Payload json.RawMessage
+	// Attempts is the number of times the job has been claimed, including the current run
+	Attempts int
+	// MaxAttempts is the number of attempts before the job is moved to the dead letter list
+	MaxAttempts int
+	// RunAt is when the job becomes available to workers
+	RunAt time.Time
+	// LastError is the error of the last failed attempt
+	LastError string
+	// CreatedAt is when the job was enqueued
+	CreatedAt time.Time
+	// Token identifies the lease of the current claim. Completing, retrying or extending the job requires it,
+	// so a worker whose lease expired can't act on a job another worker claimed since.
+	Token string
+}
+
+// Decode decodes the JSON payload into v
+func (j *Job) Decode(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Store persists jobs. Implementations must make each operation atomic across all workers sharing the
+// store. Operations taking a token must do nothing and report ErrLeaseLost when the token no longer
+// matches the job's current lease.
+type Store interface {
+	// Enqueue adds a job
+	Enqueue(ctx context.Context, job Job) error
+	// Claim leases the next job of the queue that is due, or whose lease expired, for the visibility
+	// timeout. It increments the job's attempts and sets a new token. It returns nil when no job is due.
+	Claim(ctx context.Context, queue string, visibility time.Duration) (*Job, error)
+	// Extend moves the end of the job's lease to visibility from now
+	Extend(ctx context.Context, id, token string, visibility time.Duration) error
+	// Complete removes a job that succeeded
+	Complete(ctx context.Context, id, token string) error
+	// Retry releases the job's lease so it runs again at runAt, recording the error
+	Retry(ctx context.Context, id, token string, runAt time.Time, lastError string) error
+	// Bury moves the job to the dead letter list, recording the error
+	Bury(ctx context.Context, id, token string, lastError string) error
+	// Dead returns the jobs of the queue in the dead letter list, oldest first
+	Dead(ctx context.Context, queue string) ([]Job, error)
+	// Requeue moves a job from the dead letter list back to its queue with its attempts reset. It reports
+	// whether the job was found.
+	Requeue(ctx context.Context, id string) (bool, error)
+}
+
+// permanentError marks a job error that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps a handler error so the job is moved to the dead letter list without being retried, e.g.
+// when its payload can't be decoded
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether the error was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// newID returns a random identifier for a job or lease
+func newID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,271 @@
+package jobs_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Import the SQLite driver
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/jobs"
+)
+
+func newSQLiteStore(t *testing.T) *jobs.SQLStore {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "jobs.db")+"?_busy_timeout=5000")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	store := jobs.NewSQLStore(db, jobs.DialectSQLite)
+	require.NoError(t, store.Migrate(context.Background()))
+	return store
+}
+
+func newJob(id string, runAt time.Time) jobs.Job {
+	return jobs.Job{
+		ID:          id,
+		Queue:       "default",
+		Type:        "email",
+		Payload:     []byte(`{"to":"ada@example.com"}`),
+		MaxAttempts: 3,
+		RunAt:       runAt,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) jobs.Store{
+		"memory": func(t *testing.T) jobs.Store { return jobs.NewMemoryStore() },
+		"sqlite": func(t *testing.T) jobs.Store { return newSQLiteStore(t) },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			t.Run("claims due jobs once", func(t *testing.T) {
+				store := newStore(t)
+				require.NoError(t, store.Enqueue(ctx, newJob("later", time.Now().Add(time.Hour))))
+				require.NoError(t, store.Enqueue(ctx, newJob("now", time.Now())))
+
+				job, err := store.Claim(ctx, "default", time.Minute)
+				require.NoError(t, err)
+				require.NotNil(t, job)
+				assert.Equal(t, "now", job.ID)
+				assert.Equal(t, 1, job.Attempts)
+				assert.NotEmpty(t, job.Token)
+				assert.JSONEq(t, `{"to":"ada@example.com"}`, string(job.Payload))
+
+				again, err := store.Claim(ctx, "default", time.Minute)
+				require.NoError(t, err)
+				assert.Nil(t, again, "leased and future jobs are hidden")
+
+				require.NoError(t, store.Extend(ctx, job.ID, job.Token, time.Minute))
+				require.NoError(t, store.Complete(ctx, job.ID, job.Token))
+				assert.ErrorIs(t, store.Complete(ctx, job.ID, job.Token), jobs.ErrLeaseLost)
+			})
+
+			t.Run("expired leases are claimed again", func(t *testing.T) {
+				store := newStore(t)
+				require.NoError(t, store.Enqueue(ctx, newJob("crash", time.Now())))
+
+				first, err := store.Claim(ctx, "default", time.Millisecond)
+				require.NoError(t, err)
+				require.NotNil(t, first)
+				time.Sleep(5 * time.Millisecond)
+
+				second, err := store.Claim(ctx, "default", time.Minute)
+				require.NoError(t, err)
+				require.NotNil(t, second)
+				assert.Equal(t, 2, second.Attempts)
+
+				assert.ErrorIs(t, store.Complete(ctx, first.ID, first.Token), jobs.ErrLeaseLost, "the old lease is void")
+				assert.ErrorIs(t, store.Extend(ctx, first.ID, first.Token, time.Minute), jobs.ErrLeaseLost)
+				require.NoError(t, store.Complete(ctx, second.ID, second.Token))
+			})
+
+			t.Run("retry and bury", func(t *testing.T) {
+				store := newStore(t)
+				require.NoError(t, store.Enqueue(ctx, newJob("flaky", time.Now())))
+
+				job, err := store.Claim(ctx, "default", time.Minute)
+				require.NoError(t, err)
+				require.NoError(t, store.Retry(ctx, job.ID, job.Token, time.Now(), "timeout"))
+
+				job, err = store.Claim(ctx, "default", time.Minute)
+				require.NoError(t, err)
+				require.NotNil(t, job)
+				assert.Equal(t, "timeout", job.LastError)
+				require.NoError(t, store.Bury(ctx, job.ID, job.Token, "still failing"))
+
+				job, err = store.Claim(ctx, "default", time.Minute)
+				require.NoError(t, err)
+				assert.Nil(t, job, "dead jobs aren't claimed")
+
+				dead, err := store.Dead(ctx, "default")
+				require.NoError(t, err)
+				require.Len(t, dead, 1)
+				assert.Equal(t, "still failing", dead[0].LastError)
+
+				ok, err := store.Requeue(ctx, "flaky")
+				require.NoError(t, err)
+				assert.True(t, ok)
+
+				job, err = store.Claim(ctx, "default", time.Minute)
+				require.NoError(t, err)
+				require.NotNil(t, job)
+				assert.Equal(t, 1, job.Attempts)
+			})
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	newManager := func(store jobs.Store, visibility time.Duration) *jobs.Manager {
+		return jobs.NewManager(store, func(opts *jobs.Options) {
+			opts.Workers = 2
+			opts.PollInterval = 5 * time.Millisecond
+			opts.VisibilityTimeout = visibility
+			opts.MaxAttempts = 3
+			opts.Backoff = func(int) time.Duration { return 0 }
+			opts.Logger = logger
+		})
+	}
+
+	t.Run("retries failed jobs until they succeed", func(t *testing.T) {
+		store := jobs.NewMemoryStore()
+		manager := newManager(store, time.Minute)
+
+		var attempts atomic.Int32
+		done := make(chan string, 1)
+		manager.Handle("email", func(ctx context.Context, job *jobs.Job) error {
+			if attempts.Add(1) < 3 {
+				return errors.New("smtp unavailable")
+			}
+			var payload struct{ To string }
+			if err := job.Decode(&payload); err != nil {
+				return jobs.Permanent(err)
+			}
+			done <- payload.To
+			return nil
+		})
+
+		_, err := manager.Enqueue(ctx, "email", map[string]string{"to": "ada@example.com"})
+		require.NoError(t, err)
+		require.NoError(t, manager.Start(ctx))
+		defer func() { _ = manager.Stop(ctx) }()
+
+		select {
+		case to := <-done:
+			assert.Equal(t, "ada@example.com", to)
+		case <-time.After(2 * time.Second):
+			t.Fatal("job did not complete")
+		}
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("poison jobs are moved to the dead letter list", func(t *testing.T) {
+		store := jobs.NewMemoryStore()
+		manager := newManager(store, time.Minute)
+		manager.Handle("email", func(ctx context.Context, job *jobs.Job) error {
+			return jobs.Permanent(errors.New("invalid address"))
+		})
+
+		id, err := manager.Enqueue(ctx, "email", nil)
+		require.NoError(t, err)
+		_, err = manager.Enqueue(ctx, "unknown", nil)
+		require.NoError(t, err)
+
+		require.NoError(t, manager.Start(ctx))
+		defer func() { _ = manager.Stop(ctx) }()
+
+		require.Eventually(t, func() bool {
+			dead, _ := manager.Dead(ctx)
+			return len(dead) == 2
+		}, 2*time.Second, 10*time.Millisecond)
+
+		dead, err := manager.Dead(ctx)
+		require.NoError(t, err)
+		for _, job := range dead {
+			if job.ID == id {
+				assert.Equal(t, "invalid address", job.LastError)
+				assert.Equal(t, 1, job.Attempts)
+			} else {
+				assert.Contains(t, job.LastError, "no handler registered")
+			}
+		}
+	})
+
+	t.Run("jobs whose workers keep crashing are buried", func(t *testing.T) {
+		store := jobs.NewMemoryStore()
+		require.NoError(t, store.Enqueue(ctx, jobs.Job{
+			ID: "crashy", Queue: "default", Type: "email", MaxAttempts: 2, RunAt: time.Now(), CreatedAt: time.Now(),
+		}))
+
+		// Simulate workers that crashed mid-job by claiming without settling
+		for range 2 {
+			job, err := store.Claim(ctx, "default", time.Millisecond)
+			require.NoError(t, err)
+			require.NotNil(t, job)
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		manager := newManager(store, time.Minute)
+		var ran atomic.Bool
+		manager.Handle("email", func(ctx context.Context, job *jobs.Job) error {
+			ran.Store(true)
+			return nil
+		})
+		require.NoError(t, manager.Start(ctx))
+		defer func() { _ = manager.Stop(ctx) }()
+
+		require.Eventually(t, func() bool {
+			dead, _ := manager.Dead(ctx)
+			return len(dead) == 1
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.False(t, ran.Load())
+	})
+
+	t.Run("extend keeps long jobs hidden", func(t *testing.T) {
+		store := jobs.NewMemoryStore()
+		manager := newManager(store, 50*time.Millisecond)
+
+		var runs atomic.Int32
+		manager.Handle("report", func(ctx context.Context, job *jobs.Job) error {
+			runs.Add(1)
+			for range 4 {
+				time.Sleep(30 * time.Millisecond)
+				if err := jobs.Extend(ctx, 50*time.Millisecond); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		_, err := manager.Enqueue(ctx, "report", nil)
+		require.NoError(t, err)
+		require.NoError(t, manager.Start(ctx))
+
+		require.Eventually(t, func() bool { return runs.Load() == 1 }, 2*time.Second, 5*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		job, err := store.Claim(ctx, "default", time.Minute)
+		require.NoError(t, err)
+		assert.Nil(t, job, "the lease was extended past the visibility timeout")
+		require.NoError(t, manager.Stop(ctx))
+
+		assert.Equal(t, int32(1), runs.Load(), "the job ran once")
+		assert.Error(t, jobs.Extend(ctx, time.Minute), "outside a handler")
+	})
+}
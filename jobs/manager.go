@@ -0,0 +1,409 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/pulse"
+)
+
+// Handler runs a job. Returning nil completes the job; returning an error retries it after a backoff,
+// unless the error is wrapped with Permanent or the job is out of attempts. A handler that runs longer
+// than the visibility timeout must call Extend, or the job becomes visible to other workers again.
+type Handler func(ctx context.Context, job *Job) error
+
+// Options configures a Manager
+type Options struct {
+	// Queue is the name of the queue jobs are enqueued to and claimed from. Default is "default".
+	Queue string
+	// Workers is the number of jobs run concurrently. Default is 4.
+	Workers int
+	// PollInterval is how long an idle worker waits before checking for due jobs again. Default is 1 second.
+	PollInterval time.Duration
+	// VisibilityTimeout is how long a claimed job stays hidden from other workers. If the worker crashes,
+	// the job runs again once it expires. Default is 5 minutes.
+	VisibilityTimeout time.Duration
+	// MaxAttempts is the default number of attempts of a job before it is moved to the dead letter list,
+	// counting attempts whose worker crashed. Default is 5.
+	MaxAttempts int
+	// Backoff returns the delay before retrying a job that failed its nth attempt. Default doubles from
+	// 1 second, up to 1 hour.
+	Backoff func(attempt int) time.Duration
+	// Logger receives job failures. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Metrics, when set, records the "jobs_completed_total", "jobs_retried_total", "jobs_dead_total" and
+	// "jobs_lease_lost_total" counters, a "jobs_running" gauge and a "jobs_duration_ms" histogram
+	Metrics pulse.Collector
+}
+
+// EnqueueOption configures an enqueued job
+type EnqueueOption func(job *Job)
+
+// Delay runs the job after the delay
+func Delay(d time.Duration) EnqueueOption {
+	return func(job *Job) {
+		job.RunAt = job.RunAt.Add(d)
+	}
+}
+
+// At runs the job at the given time
+func At(t time.Time) EnqueueOption {
+	return func(job *Job) {
+		job.RunAt = t
+	}
+}
+
+// MaxAttempts overrides the number of attempts of the job
+func MaxAttempts(n int) EnqueueOption {
+	return func(job *Job) {
+		job.MaxAttempts = n
+	}
+}
+
+// Manager enqueues jobs and runs them with a pool of workers
+type Manager struct {
+	store Store
+	opts  Options
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	stop   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	completed, retried, dead, leaseLost pulse.Counter
+	running                             pulse.Gauge
+	duration                            pulse.Histogram
+}
+
+// NewManager creates a new Manager for the store
+func NewManager(store Store, optsFunc func(opts *Options)) *Manager {
+	opts := Options{
+		Queue:             "default",
+		Workers:           4,
+		PollInterval:      time.Second,
+		VisibilityTimeout: 5 * time.Minute,
+		MaxAttempts:       5,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Queue == "" {
+		opts.Queue = "default"
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 5 * time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	m := &Manager{
+		store:    store,
+		opts:     opts,
+		handlers: make(map[string]Handler),
+	}
+
+	if opts.Metrics != nil {
+		m.completed = opts.Metrics.Counter("jobs_completed_total")
+		m.retried = opts.Metrics.Counter("jobs_retried_total")
+		m.dead = opts.Metrics.Counter("jobs_dead_total")
+		m.leaseLost = opts.Metrics.Counter("jobs_lease_lost_total")
+		m.running = opts.Metrics.Gauge("jobs_running")
+		m.duration = opts.Metrics.Histogram("jobs_duration_ms")
+	}
+
+	return m
+}
+
+// Handle registers the handler of a job type, replacing any handler registered before
+func (m *Manager) Handle(jobType string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[jobType] = h
+}
+
+// Enqueue adds a job of the given type, with the payload encoded as JSON, and returns its ID
+func (m *Manager) Enqueue(ctx context.Context, jobType string, payload any, opts ...EnqueueOption) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("jobs: encoding payload: %w", err)
+	}
+
+	now := time.Now()
+	job := Job{
+		ID:          newID(),
+		Queue:       m.opts.Queue,
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: m.opts.MaxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+	}
+	for _, opt := range opts {
+		opt(&job)
+	}
+
+	if err := m.store.Enqueue(ctx, job); err != nil {
+		return "", fmt.Errorf("jobs: enqueueing %s: %w", jobType, err)
+	}
+	return job.ID, nil
+}
+
+// Dead returns the jobs of the manager's queue in the dead letter list
+func (m *Manager) Dead(ctx context.Context) ([]Job, error) {
+	return m.store.Dead(ctx, m.opts.Queue)
+}
+
+// Requeue moves a dead job back to the queue with its attempts reset, e.g. once the cause of its failures
+// has been fixed. It reports whether the job was found.
+func (m *Manager) Requeue(ctx context.Context, id string) (bool, error) {
+	return m.store.Requeue(ctx, id)
+}
+
+// ID returns the module ID, so the manager can be registered as a hop module
+func (m *Manager) ID() string {
+	return "hop.jobs"
+}
+
+// Init implements hop.Module
+func (m *Manager) Init() error {
+	return nil
+}
+
+// Start starts the workers
+func (m *Manager) Start(ctx context.Context) error {
+	ctx, m.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	stop := make(chan struct{})
+	m.stop = stop
+
+	for i := 0; i < m.opts.Workers; i++ {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.work(ctx, stop)
+		}()
+	}
+	return nil
+}
+
+// Stop stops claiming jobs and waits for the running ones to finish. If ctx is done first, the running jobs'
+// contexts are canceled; their leases aren't released, so they run again once the visibility timeout expires.
+// It is safe to call more than once, and the manager can be started again.
+func (m *Manager) Stop(ctx context.Context) error {
+	if m.stop == nil {
+		return nil
+	}
+
+	close(m.stop)
+	m.stop = nil
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		return ctx.Err()
+	}
+}
+
+// work claims and runs jobs until stop is closed
+func (m *Manager) work(ctx context.Context, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		job, err := m.store.Claim(ctx, m.opts.Queue, m.opts.VisibilityTimeout)
+		if err != nil && ctx.Err() == nil {
+			m.opts.Logger.Error("failed to claim job", slog.String("queue", m.opts.Queue), slog.String("error", err.Error()))
+		}
+
+		if job == nil {
+			select {
+			case <-stop:
+				return
+			case <-time.After(m.opts.PollInterval):
+			}
+			continue
+		}
+
+		m.process(ctx, job)
+	}
+}
+
+// process runs a claimed job and records its outcome
+func (m *Manager) process(ctx context.Context, job *Job) {
+	// Settle the job even while stopping, so a finished job isn't run again
+	settleCtx := context.WithoutCancel(ctx)
+
+	if job.Attempts > job.MaxAttempts {
+		// The earlier attempts never settled, so the job keeps crashing or hanging its workers
+		m.bury(settleCtx, job, fmt.Sprintf("exceeded %d attempts without completing; last error: %s", job.MaxAttempts, job.LastError))
+		return
+	}
+
+	m.mu.RLock()
+	h, ok := m.handlers[job.Type]
+	m.mu.RUnlock()
+	if !ok {
+		m.bury(settleCtx, job, fmt.Sprintf("no handler registered for job type %q", job.Type))
+		return
+	}
+
+	start := time.Now()
+	err := m.run(ctx, job, h)
+	if m.duration != nil {
+		m.duration.Observe(float64(time.Since(start).Milliseconds()))
+	}
+
+	if err != nil && ctx.Err() != nil {
+		// Stopped before the job finished; it runs again once its lease expires
+		m.opts.Logger.Warn("job interrupted by shutdown", slog.String("job", job.ID), slog.String("type", job.Type))
+		return
+	}
+
+	switch {
+	case err == nil:
+		if m.settle(job, m.store.Complete(settleCtx, job.ID, job.Token)) && m.completed != nil {
+			m.completed.Inc()
+		}
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		m.bury(settleCtx, job, err.Error())
+	default:
+		delay := m.opts.Backoff(job.Attempts)
+		m.opts.Logger.Warn("job failed, retrying",
+			slog.String("job", job.ID),
+			slog.String("type", job.Type),
+			slog.Int("attempt", job.Attempts),
+			slog.Duration("retry_in", delay),
+			slog.String("error", err.Error()))
+		if m.settle(job, m.store.Retry(settleCtx, job.ID, job.Token, time.Now().Add(delay), err.Error())) && m.retried != nil {
+			m.retried.Inc()
+		}
+	}
+}
+
+// run calls the handler, turning a panic into an error
+func (m *Manager) run(ctx context.Context, job *Job, h Handler) (err error) {
+	if m.running != nil {
+		m.running.Add(1)
+		defer m.running.Sub(1)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			m.opts.Logger.Error("job panicked",
+				slog.String("job", job.ID),
+				slog.String("type", job.Type),
+				slog.Any("panic", p),
+				slog.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return h(withLease(ctx, m.store, job), job)
+}
+
+// bury moves a job to the dead letter list
+func (m *Manager) bury(ctx context.Context, job *Job, reason string) {
+	m.opts.Logger.Error("job moved to dead letter list",
+		slog.String("job", job.ID),
+		slog.String("type", job.Type),
+		slog.Int("attempts", job.Attempts),
+		slog.String("error", reason))
+	if m.settle(job, m.store.Bury(ctx, job.ID, job.Token, reason)) && m.dead != nil {
+		m.dead.Inc()
+	}
+}
+
+// settle logs the error of completing, retrying or burying a job, and reports whether it succeeded
+func (m *Manager) settle(job *Job, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrLeaseLost):
+		// Another worker claimed the job after its lease expired, so it will run again
+		m.opts.Logger.Warn("job lease expired before the job finished",
+			slog.String("job", job.ID),
+			slog.String("type", job.Type),
+			slog.Duration("visibility_timeout", m.opts.VisibilityTimeout))
+		if m.leaseLost != nil {
+			m.leaseLost.Inc()
+		}
+	default:
+		m.opts.Logger.Error("failed to update job", slog.String("job", job.ID), slog.String("error", err.Error()))
+	}
+	return false
+}
+
+// defaultBackoff doubles from 1 second, up to 1 hour
+func defaultBackoff(attempt int) time.Duration {
+	if attempt > 12 {
+		return time.Hour
+	}
+	return min(time.Second<<max(attempt-1, 0), time.Hour)
+}
+
+// leaseKey is the context key of the running job's lease
+type leaseKey struct{}
+
+type jobLease struct {
+	store Store
+	job   *Job
+}
+
+// withLease adds the job's lease to the context, for Extend
+func withLease(ctx context.Context, store Store, job *Job) context.Context {
+	return context.WithValue(ctx, leaseKey{}, jobLease{store: store, job: job})
+}
+
+// Extend is the heartbeat of long-running handlers: it moves the end of the running job's lease to d from
+// now, keeping it hidden from other workers. It returns ErrLeaseLost if the lease already expired and the
+// job may be running elsewhere, in which case the handler should stop. ctx must be the handler's context.
+//
+//	for i, row := range rows {
+//	    if i%1000 == 0 {
+//	        if err := jobs.Extend(ctx, 5*time.Minute); err != nil {
+//	            return err
+//	        }
+//	    }
+//	    process(row)
+//	}
+func Extend(ctx context.Context, d time.Duration) error {
+	lease, ok := ctx.Value(leaseKey{}).(jobLease)
+	if !ok {
+		return errors.New("jobs: Extend called outside a job handler")
+	}
+	return lease.store.Extend(ctx, lease.job.ID, lease.job.Token, d)
+}
@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory Store. Jobs are lost when the process exits, so it is only suitable for
+// tests and development.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]*memoryJob
+	now  func() time.Time
+}
+
+type memoryJob struct {
+	job       Job
+	visibleAt time.Time
+	dead      bool
+}
+
+// NewMemoryStore creates a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs: make(map[string]*memoryJob),
+		now:  time.Now,
+	}
+}
+
+// Enqueue adds a job
+func (s *MemoryStore) Enqueue(_ context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.Token = ""
+	s.jobs[job.ID] = &memoryJob{job: job, visibleAt: job.RunAt}
+	return nil
+}
+
+// Claim leases the next due job of the queue
+func (s *MemoryStore) Claim(_ context.Context, queue string, visibility time.Duration) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var next *memoryJob
+	for _, j := range s.jobs {
+		if j.dead || j.job.Queue != queue || j.visibleAt.After(now) {
+			continue
+		}
+		if next == nil || j.visibleAt.Before(next.visibleAt) ||
+			(j.visibleAt.Equal(next.visibleAt) && j.job.CreatedAt.Before(next.job.CreatedAt)) {
+			next = j
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	next.job.Attempts++
+	next.job.Token = newID()
+	next.visibleAt = now.Add(visibility)
+
+	job := next.job
+	return &job, nil
+}
+
+// Extend moves the end of the job's lease
+func (s *MemoryStore) Extend(_ context.Context, id, token string, visibility time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.leased(id, token)
+	if err != nil {
+		return err
+	}
+	j.visibleAt = s.now().Add(visibility)
+	return nil
+}
+
+// Complete removes a job that succeeded
+func (s *MemoryStore) Complete(_ context.Context, id, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.leased(id, token); err != nil {
+		return err
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+// Retry releases the job's lease so it runs again at runAt
+func (s *MemoryStore) Retry(_ context.Context, id, token string, runAt time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.leased(id, token)
+	if err != nil {
+		return err
+	}
+	j.job.Token = ""
+	j.job.RunAt = runAt
+	j.job.LastError = lastError
+	j.visibleAt = runAt
+	return nil
+}
+
+// Bury moves the job to the dead letter list
+func (s *MemoryStore) Bury(_ context.Context, id, token string, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.leased(id, token)
+	if err != nil {
+		return err
+	}
+	j.job.Token = ""
+	j.job.LastError = lastError
+	j.dead = true
+	return nil
+}
+
+// Dead returns the jobs of the queue in the dead letter list
+func (s *MemoryStore) Dead(_ context.Context, queue string) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var dead []Job
+	for _, j := range s.jobs {
+		if j.dead && j.job.Queue == queue {
+			dead = append(dead, j.job)
+		}
+	}
+	sort.Slice(dead, func(a, b int) bool { return dead[a].CreatedAt.Before(dead[b].CreatedAt) })
+	return dead, nil
+}
+
+// Requeue moves a job from the dead letter list back to its queue
+func (s *MemoryStore) Requeue(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok || !j.dead {
+		return false, nil
+	}
+	now := s.now()
+	j.dead = false
+	j.job.Attempts = 0
+	j.job.RunAt = now
+	j.visibleAt = now
+	return true, nil
+}
+
+// leased returns the job if token matches its current lease
+func (s *MemoryStore) leased(id, token string) (*memoryJob, error) {
+	j, ok := s.jobs[id]
+	if !ok || j.dead || token == "" || j.job.Token != token {
+		return nil, ErrLeaseLost
+	}
+	return j, nil
+}
@@ -0,0 +1,211 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Dialect selects the SQL dialect used by SQLStore
+type Dialect int
+
+const (
+	// DialectSQLite targets SQLite 3.35 or later
+	DialectSQLite Dialect = iota
+	// DialectPostgres targets PostgreSQL 9.5 or later
+	DialectPostgres
+)
+
+// SQLStore is a Store backed by a SQLite or Postgres database. Each job is a row in the jobs table. A
+// row's visible_at column holds when the job is due or, while it is leased, when the lease expires, so
+// claiming a job is a single atomic update.
+type SQLStore struct {
+	db      *sql.DB
+	dialect Dialect
+	now     func() time.Time
+}
+
+// NewSQLStore creates a new SQLStore using the given database and dialect
+func NewSQLStore(db *sql.DB, dialect Dialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect, now: time.Now}
+}
+
+// Migrate creates the jobs table if it does not exist
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS jobs (
+			id TEXT PRIMARY KEY,
+			queue TEXT NOT NULL,
+			type TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			max_attempts INTEGER NOT NULL,
+			run_at BIGINT NOT NULL,
+			visible_at BIGINT NOT NULL,
+			token TEXT NOT NULL DEFAULT '',
+			dead BOOLEAN NOT NULL DEFAULT FALSE,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at BIGINT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS jobs_claim ON jobs (queue, dead, visible_at)`,
+	}
+
+	for _, q := range queries {
+		if _, err := s.db.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("creating jobs table: %w", err)
+		}
+	}
+	return nil
+}
+
+// Enqueue adds a job
+func (s *SQLStore) Enqueue(ctx context.Context, job Job) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO jobs
+		(id, queue, type, payload, attempts, max_attempts, run_at, visible_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $8)`,
+		job.ID, job.Queue, job.Type, string(job.Payload), job.Attempts, job.MaxAttempts,
+		job.RunAt.UnixNano(), job.CreatedAt.UnixNano())
+	return err
+}
+
+// Claim leases the next due job of the queue
+func (s *SQLStore) Claim(ctx context.Context, queue string, visibility time.Duration) (*Job, error) {
+	now := s.now()
+	token := newID()
+
+	// Postgres skips rows other workers are claiming. SQLite serializes writers, and the outer condition
+	// makes the update a no-op if another worker claimed the row first.
+	lock := ""
+	if s.dialect == DialectPostgres {
+		lock = " FOR UPDATE SKIP LOCKED"
+	}
+
+	row := s.db.QueryRowContext(ctx, `UPDATE jobs SET attempts = attempts + 1, token = $1, visible_at = $2
+		WHERE id = (
+			SELECT id FROM jobs WHERE queue = $3 AND dead = FALSE AND visible_at <= $4
+			ORDER BY visible_at, created_at LIMIT 1`+lock+`
+		) AND dead = FALSE AND visible_at <= $4
+		RETURNING id, queue, type, payload, attempts, max_attempts, run_at, last_error, created_at`,
+		token, now.Add(visibility).UnixNano(), queue, now.UnixNano())
+
+	job, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.Token = token
+	return job, nil
+}
+
+// Extend moves the end of the job's lease
+func (s *SQLStore) Extend(ctx context.Context, id, token string, visibility time.Duration) error {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE jobs SET visible_at = $1 WHERE id = $2 AND token = $3 AND token <> '' AND dead = FALSE",
+		s.now().Add(visibility).UnixNano(), id, token)
+	if err != nil {
+		return err
+	}
+	return leaseAffected(res)
+}
+
+// Complete removes a job that succeeded
+func (s *SQLStore) Complete(ctx context.Context, id, token string) error {
+	res, err := s.db.ExecContext(ctx,
+		"DELETE FROM jobs WHERE id = $1 AND token = $2 AND token <> '' AND dead = FALSE", id, token)
+	if err != nil {
+		return err
+	}
+	return leaseAffected(res)
+}
+
+// Retry releases the job's lease so it runs again at runAt
+func (s *SQLStore) Retry(ctx context.Context, id, token string, runAt time.Time, lastError string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE jobs SET token = '', run_at = $1, visible_at = $1, last_error = $2
+		WHERE id = $3 AND token = $4 AND token <> '' AND dead = FALSE`,
+		runAt.UnixNano(), lastError, id, token)
+	if err != nil {
+		return err
+	}
+	return leaseAffected(res)
+}
+
+// Bury moves the job to the dead letter list
+func (s *SQLStore) Bury(ctx context.Context, id, token string, lastError string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE jobs SET token = '', dead = TRUE, last_error = $1
+		WHERE id = $2 AND token = $3 AND token <> '' AND dead = FALSE`,
+		lastError, id, token)
+	if err != nil {
+		return err
+	}
+	return leaseAffected(res)
+}
+
+// Dead returns the jobs of the queue in the dead letter list
+func (s *SQLStore) Dead(ctx context.Context, queue string) ([]Job, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, queue, type, payload, attempts, max_attempts, run_at, last_error, created_at
+		FROM jobs WHERE queue = $1 AND dead = TRUE ORDER BY created_at`, queue)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var dead []Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		dead = append(dead, *job)
+	}
+	return dead, rows.Err()
+}
+
+// Requeue moves a job from the dead letter list back to its queue
+func (s *SQLStore) Requeue(ctx context.Context, id string) (bool, error) {
+	now := s.now().UnixNano()
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE jobs SET dead = FALSE, attempts = 0, run_at = $1, visible_at = $1 WHERE id = $2 AND dead = TRUE",
+		now, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// scanJob scans the columns of a job row
+func scanJob(row interface{ Scan(dest ...any) error }) (*Job, error) {
+	var (
+		job              Job
+		payload          string
+		runAt, createdAt int64
+	)
+	if err := row.Scan(&job.ID, &job.Queue, &job.Type, &payload, &job.Attempts, &job.MaxAttempts,
+		&runAt, &job.LastError, &createdAt); err != nil {
+		return nil, err
+	}
+
+	job.Payload = []byte(payload)
+	job.RunAt = time.Unix(0, runAt)
+	job.CreatedAt = time.Unix(0, createdAt)
+	return &job, nil
+}
+
+// leaseAffected returns ErrLeaseLost when an update guarded by a lease token changed no rows
+func leaseAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+// Package kv provides a small, SQLite-backed key-value store that gives modules a standard place for
+// durable state without requiring a full database setup.
+//
+// Keys are grouped into namespaced buckets, values may expire after a TTL, and several operations can
+// be grouped into a transaction. Typed values are stored as JSON using the generic Get and Set helpers.
+//
+//	store, err := kv.New(db)
+//	if err != nil {
+//	    return err
+//	}
+//	defer store.Close()
+//
+//	settings := store.Bucket("settings")
+//	err = kv.Set(ctx, settings, "theme", Theme{Name: "dark"}, 0)
+//	theme, ok, err := kv.Get[Theme](ctx, settings, "theme")
+package kv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+)
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Store is a SQLite-backed key-value store
+type Store struct {
+	db              *sql.DB
+	table           string
+	cleanupInterval time.Duration
+	clock           clock.Clock
+	stopCleanup     chan struct{}
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithTableName sets the name of the table used to store values. Defaults to "kv".
+func WithTableName(name string) Option {
+	return func(s *Store) {
+		s.table = name
+	}
+}
+
+// WithCleanupInterval sets how often expired values are removed. Defaults to 5 minutes.
+// Setting it to 0 disables the background cleanup; expired values are still never returned.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		s.cleanupInterval = interval
+	}
+}
+
+// WithClock sets the clock used to tell when values expire and to schedule the cleanup, e.g. a fake
+// clock in tests. Defaults to the real clock.
+func WithClock(c clock.Clock) Option {
+	return func(s *Store) {
+		s.clock = clock.OrReal(c)
+	}
+}
+
+// New creates a new Store using the given database, creating the table if it does not exist.
+// A background goroutine periodically removes expired values until Close is called.
+func New(db *sql.DB, opts ...Option) (*Store, error) {
+	if db == nil {
+		return nil, errors.New("kv: database is required")
+	}
+
+	s := &Store{
+		db:              db,
+		table:           "kv",
+		cleanupInterval: 5 * time.Minute,
+		clock:           clock.Real(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if !validTableName.MatchString(s.table) {
+		return nil, fmt.Errorf("kv: invalid table name %q", s.table)
+	}
+
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+
+	if s.cleanupInterval > 0 {
+		s.stopCleanup = make(chan struct{})
+		go s.startCleanup(s.cleanupInterval, s.stopCleanup)
+	}
+
+	return s, nil
+}
+
+// Close stops the background cleanup goroutine. It does not close the underlying database.
+func (s *Store) Close() error {
+	if s.stopCleanup != nil {
+		close(s.stopCleanup)
+		s.stopCleanup = nil
+	}
+	return nil
+}
+
+// Bucket returns the bucket for a namespace
+func (s *Store) Bucket(namespace string) *Bucket {
+	return &Bucket{store: s, q: s.db, namespace: namespace}
+}
+
+// Update runs fn within a transaction. The transaction is committed if fn returns nil and
+// rolled back otherwise.
+func (s *Store) Update(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	sqlTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("kv: beginning transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(&Tx{store: s, tx: sqlTx}); err != nil {
+		_ = sqlTx.Rollback()
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("kv: committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes all expired values from the store
+func (s *Store) DeleteExpired(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx,
+		"DELETE FROM "+s.table+" WHERE expires_at IS NOT NULL AND expires_at <= $1", s.clock.Now().UnixNano())
+	return err
+}
+
+// Tx is a transaction spanning one or more buckets
+type Tx struct {
+	store *Store
+	tx    *sql.Tx
+}
+
+// Bucket returns the bucket for a namespace within the transaction
+func (t *Tx) Bucket(namespace string) *Bucket {
+	return &Bucket{store: t.store, q: t.tx, namespace: namespace}
+}
+
+// Bucket is a namespaced collection of keys
+type Bucket struct {
+	store     *Store
+	q         querier
+	namespace string
+}
+
+// Namespace returns the bucket's namespace
+func (b *Bucket) Namespace() string {
+	return b.namespace
+}
+
+// Get returns the raw value for a key. The exists flag is false if the key is missing or expired.
+func (b *Bucket) Get(ctx context.Context, key string) (value []byte, exists bool, err error) {
+	row := b.q.QueryRowContext(ctx,
+		"SELECT value FROM "+b.store.table+" WHERE namespace = $1 AND key = $2 AND (expires_at IS NULL OR expires_at > $3)",
+		b.namespace, key, b.store.clock.Now().UnixNano())
+
+	err = row.Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores the raw value for a key. If ttl is greater than zero, the value expires after ttl.
+func (b *Bucket) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expiresAt sql.NullInt64
+	if ttl > 0 {
+		expiresAt = sql.NullInt64{Int64: b.store.clock.Now().Add(ttl).UnixNano(), Valid: true}
+	}
+
+	_, err := b.q.ExecContext(ctx,
+		"REPLACE INTO "+b.store.table+" (namespace, key, value, expires_at) VALUES ($1, $2, $3, $4)",
+		b.namespace, key, value, expiresAt)
+	return err
+}
+
+// Delete removes a key. Deleting a missing key is not an error.
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	_, err := b.q.ExecContext(ctx,
+		"DELETE FROM "+b.store.table+" WHERE namespace = $1 AND key = $2", b.namespace, key)
+	return err
+}
+
+// Keys returns the keys in the bucket that have not expired, in ascending order
+func (b *Bucket) Keys(ctx context.Context) ([]string, error) {
+	rows, err := b.q.QueryContext(ctx,
+		"SELECT key FROM "+b.store.table+" WHERE namespace = $1 AND (expires_at IS NULL OR expires_at > $2) ORDER BY key",
+		b.namespace, b.store.clock.Now().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Clear removes every key in the bucket
+func (b *Bucket) Clear(ctx context.Context) error {
+	_, err := b.q.ExecContext(ctx,
+		"DELETE FROM "+b.store.table+" WHERE namespace = $1", b.namespace)
+	return err
+}
+
+// Get returns the value for a key decoded from JSON into T. The exists flag is false if the key is
+// missing or expired.
+func Get[T any](ctx context.Context, b *Bucket, key string) (value T, exists bool, err error) {
+	data, exists, err := b.Get(ctx, key)
+	if err != nil || !exists {
+		return value, exists, err
+	}
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, false, fmt.Errorf("kv: decoding %s/%s: %w", b.namespace, key, err)
+	}
+	return value, true, nil
+}
+
+// Set stores the value for a key encoded as JSON. If ttl is greater than zero, the value expires after ttl.
+func Set[T any](ctx context.Context, b *Bucket, key string, value T, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("kv: encoding %s/%s: %w", b.namespace, key, err)
+	}
+	return b.Set(ctx, key, data, ttl)
+}
+
+func (s *Store) migrate() error {
+	q := `CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+		namespace TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value BLOB NOT NULL,
+		expires_at INTEGER,
+		PRIMARY KEY (namespace, key)
+	);
+	CREATE INDEX IF NOT EXISTS ` + s.table + `_expires_at_idx ON ` + s.table + `(expires_at);`
+
+	if _, err := s.db.Exec(q); err != nil {
+		return fmt.Errorf("kv: creating table: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) startCleanup(interval time.Duration, stop <-chan struct{}) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			_ = s.DeleteExpired(context.Background())
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package kv_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Import the SQLite driver
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/kv"
+)
+
+func newTestStore(t *testing.T, opts ...kv.Option) *kv.Store {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "kv.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	store, err := kv.New(db, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func TestBucket_GetSetDelete(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	bucket := store.Bucket("settings")
+
+	_, ok, err := bucket.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, bucket.Set(ctx, "theme", []byte("dark"), 0))
+	value, ok, err := bucket.Get(ctx, "theme")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("dark"), value)
+
+	// Overwrite
+	require.NoError(t, bucket.Set(ctx, "theme", []byte("light"), 0))
+	value, _, _ = bucket.Get(ctx, "theme")
+	assert.Equal(t, []byte("light"), value)
+
+	require.NoError(t, bucket.Delete(ctx, "theme"))
+	_, ok, err = bucket.Get(ctx, "theme")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestBucket_Namespaces(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	a := store.Bucket("a")
+	b := store.Bucket("b")
+
+	require.NoError(t, a.Set(ctx, "key", []byte("from a"), 0))
+	require.NoError(t, a.Set(ctx, "other", []byte("from a"), 0))
+	require.NoError(t, b.Set(ctx, "key", []byte("from b"), 0))
+
+	value, _, _ := b.Get(ctx, "key")
+	assert.Equal(t, []byte("from b"), value)
+
+	keys, err := a.Keys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key", "other"}, keys)
+
+	require.NoError(t, a.Clear(ctx))
+	keys, err = a.Keys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, ok, _ := b.Get(ctx, "key")
+	assert.True(t, ok, "clearing one bucket should not affect another")
+}
+
+func TestBucket_TTL(t *testing.T) {
+	ctx := context.Background()
+	clk := hoptest.NewClock(time.Now())
+	store := newTestStore(t, kv.WithClock(clk), kv.WithCleanupInterval(0))
+	bucket := store.Bucket("cache")
+
+	require.NoError(t, bucket.Set(ctx, "short", []byte("x"), time.Minute))
+	require.NoError(t, bucket.Set(ctx, "forever", []byte("y"), 0))
+
+	_, ok, _ := bucket.Get(ctx, "short")
+	assert.True(t, ok)
+
+	clk.Advance(2 * time.Minute)
+
+	_, ok, _ = bucket.Get(ctx, "short")
+	assert.False(t, ok, "expired values should not be returned")
+
+	keys, err := bucket.Keys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"forever"}, keys)
+
+	require.NoError(t, store.DeleteExpired(ctx))
+	_, ok, _ = bucket.Get(ctx, "forever")
+	assert.True(t, ok)
+}
+
+func TestStore_BackgroundCleanup(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "kv.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	clk := hoptest.NewClock(time.Now())
+	store, err := kv.New(db, kv.WithClock(clk), kv.WithCleanupInterval(time.Minute))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	require.NoError(t, store.Bucket("cache").Set(ctx, "short", []byte("x"), 30*time.Second))
+
+	rows := func() int {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM kv").Scan(&n))
+		return n
+	}
+	assert.Equal(t, 1, rows())
+
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	assert.Eventually(t, func() bool { return rows() == 0 }, time.Second, time.Millisecond,
+		"the cleanup should run on the clock's ticks")
+}
+
+func TestGenericAccessors(t *testing.T) {
+	type theme struct {
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	}
+
+	ctx := context.Background()
+	store := newTestStore(t)
+	bucket := store.Bucket("settings")
+
+	require.NoError(t, kv.Set(ctx, bucket, "theme", theme{Name: "dark", Color: "#000"}, 0))
+	got, ok, err := kv.Get[theme](ctx, bucket, "theme")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, theme{Name: "dark", Color: "#000"}, got)
+
+	require.NoError(t, kv.Set(ctx, bucket, "count", 42, 0))
+	count, ok, err := kv.Get[int](ctx, bucket, "count")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 42, count)
+
+	_, _, err = kv.Get[int](ctx, bucket, "theme")
+	assert.Error(t, err)
+}
+
+func TestStore_Update(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	err := store.Update(ctx, func(tx *kv.Tx) error {
+		require.NoError(t, kv.Set(ctx, tx.Bucket("a"), "key", "committed", 0))
+		return kv.Set(ctx, tx.Bucket("b"), "key", "committed", 0)
+	})
+	require.NoError(t, err)
+
+	value, ok, err := kv.Get[string](ctx, store.Bucket("b"), "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "committed", value)
+
+	errRollback := errors.New("rollback")
+	err = store.Update(ctx, func(tx *kv.Tx) error {
+		require.NoError(t, kv.Set(ctx, tx.Bucket("a"), "key", "rolled back", 0))
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	value, _, _ = kv.Get[string](ctx, store.Bucket("a"), "key")
+	assert.Equal(t, "committed", value)
+}
+
+func TestNew_InvalidTableName(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "kv.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = kv.New(db, kv.WithTableName("kv; DROP TABLE x"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package lease
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Elector runs leader election for a single named lease. One instance at a time holds the lease and
+// is the leader; the others keep trying to acquire it and take over when the leader stops renewing.
+type Elector struct {
+	locker     *Locker
+	name       string
+	ttl        time.Duration
+	leader     atomic.Bool
+	onElected  func(ctx context.Context)
+	onRevoked  func()
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	leaderStop context.CancelFunc
+	// callbacks tracks the running OnElected callbacks, and callbackDone is closed when the latest returns
+	callbacks    sync.WaitGroup
+	callbackDone chan struct{}
+}
+
+// ElectorOption configures an Elector
+type ElectorOption func(*Elector)
+
+// OnElected sets a function that is called when this instance becomes the leader. It runs in its own
+// goroutine, so it may block for as long as the instance leads: the context is canceled when leadership
+// is lost or the elector stops, and Stop waits for the function to return.
+func OnElected(fn func(ctx context.Context)) ElectorOption {
+	return func(e *Elector) {
+		e.onElected = fn
+	}
+}
+
+// OnRevoked sets a function that is called when this instance stops being the leader
+func OnRevoked(fn func()) ElectorOption {
+	return func(e *Elector) {
+		e.onRevoked = fn
+	}
+}
+
+// NewElector creates a new Elector for the named lease. The leader renews the lease at a third of
+// the TTL, and followers retry at the same interval.
+func NewElector(locker *Locker, name string, ttl time.Duration, opts ...ElectorOption) *Elector {
+	e := &Elector{locker: locker, name: name, ttl: ttl}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// IsLeader reports whether this instance currently holds leadership
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Start begins campaigning for leadership in the background
+func (e *Elector) Start(ctx context.Context) error {
+	ctx, e.cancel = context.WithCancel(ctx)
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.run(ctx)
+	}()
+
+	return nil
+}
+
+// Stop stops campaigning, waits for the OnElected callback to return and releases leadership if held
+func (e *Elector) Stop(ctx context.Context) error {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.wg.Wait()
+
+	leader := e.leader.Load()
+	if leader {
+		e.revoke()
+	}
+	e.callbacks.Wait()
+
+	if leader {
+		return e.locker.store.Release(ctx, e.name, e.locker.owner)
+	}
+	return nil
+}
+
+func (e *Elector) run(ctx context.Context) {
+	interval := e.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.campaign(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign acquires or renews the lease and updates leadership accordingly
+func (e *Elector) campaign(ctx context.Context) {
+	var (
+		ok  bool
+		err error
+	)
+
+	if e.leader.Load() {
+		ok, err = e.locker.store.Renew(ctx, e.name, e.locker.owner, e.ttl)
+	} else {
+		ok, err = e.locker.store.Acquire(ctx, e.name, e.locker.owner, e.ttl)
+	}
+
+	if err != nil || !ok {
+		if e.leader.Load() {
+			e.revoke()
+		}
+		return
+	}
+
+	if !e.leader.Load() {
+		e.elect(ctx)
+	}
+}
+
+// elect makes this instance the leader and starts the OnElected callback. The callback runs outside the
+// campaign loop, so it can't hold up the renewals; it starts once the callback of a previous term returns.
+func (e *Elector) elect(ctx context.Context) {
+	e.leader.Store(true)
+	var leaderCtx context.Context
+	leaderCtx, e.leaderStop = context.WithCancel(ctx)
+	if e.onElected == nil {
+		return
+	}
+
+	prev, done := e.callbackDone, make(chan struct{})
+	e.callbackDone = done
+	e.callbacks.Add(1)
+	go func() {
+		defer e.callbacks.Done()
+		defer close(done)
+
+		if prev != nil {
+			<-prev
+		}
+		if leaderCtx.Err() == nil {
+			e.onElected(leaderCtx)
+		}
+	}()
+}
+
+func (e *Elector) revoke() {
+	e.leader.Store(false)
+	if e.leaderStop != nil {
+		e.leaderStop()
+	}
+	if e.onRevoked != nil {
+		e.onRevoked()
+	}
+}
@@ -0,0 +1,200 @@
+// Package lease provides distributed locks with expiring leases, used to coordinate work across
+// multiple replicas of an application. Scheduled jobs and queue sweeps can use a lease so they run
+// on exactly one instance at a time, and Elector builds long-lived leader election on top of it.
+//
+// Leases are held by an owner for a TTL and must be renewed before they expire. If an instance
+// crashes, its leases expire and another instance can take over. Implementations are provided for
+// SQLite and Postgres (via database/sql), Redis, and memory (for single-instance apps and tests).
+//
+//	locker := lease.NewLocker(lease.NewSQLStore(db, lease.DialectSQLite))
+//
+//	ran, err := locker.RunExclusive(ctx, "nightly-report", time.Minute, func(ctx context.Context) error {
+//	    return sendNightlyReport(ctx)
+//	})
+package lease
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNotAcquired is returned when a lease is held by another owner
+var ErrNotAcquired = errors.New("lease: held by another owner")
+
+// ErrLost is returned when a lease could not be renewed because it expired or was taken by another owner
+var ErrLost = errors.New("lease: lost")
+
+// Store persists leases. Implementations must make each operation atomic across all instances
+// sharing the store.
+type Store interface {
+	// Acquire takes the named lease for owner if it is free, expired, or already held by owner.
+	// It reports whether the lease was acquired.
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// Renew extends the named lease if it is still held by owner. It reports whether the lease was renewed.
+	Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// Release frees the named lease if it is held by owner
+	Release(ctx context.Context, name, owner string) error
+}
+
+// Locker acquires leases on behalf of a single owner, typically one per application instance
+type Locker struct {
+	store Store
+	owner string
+}
+
+// LockerOption configures a Locker
+type LockerOption func(*Locker)
+
+// WithOwner sets the owner identity used for leases. Defaults to the hostname and a random suffix.
+func WithOwner(owner string) LockerOption {
+	return func(l *Locker) {
+		l.owner = owner
+	}
+}
+
+// NewLocker creates a new Locker for the given store
+func NewLocker(store Store, opts ...LockerOption) *Locker {
+	l := &Locker{store: store}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.owner == "" {
+		l.owner = defaultOwner()
+	}
+
+	return l
+}
+
+// Owner returns the identity used by the locker when acquiring leases
+func (l *Locker) Owner() string {
+	return l.owner
+}
+
+// Acquire takes the named lease for ttl. It returns ErrNotAcquired if another owner holds the lease.
+func (l *Locker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		return nil, errors.New("lease: ttl must be greater than zero")
+	}
+
+	ok, err := l.store.Acquire(ctx, name, l.owner, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("lease: acquiring %s: %w", name, err)
+	}
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+
+	return &Lease{store: l.store, name: name, owner: l.owner, ttl: ttl}, nil
+}
+
+// RunExclusive runs fn while holding the named lease, renewing it in the background until fn returns.
+// If another owner holds the lease, fn is not run and RunExclusive returns false with a nil error.
+// The context passed to fn is canceled if the lease is lost while fn is running.
+func (l *Locker) RunExclusive(ctx context.Context, name string, ttl time.Duration, fn func(ctx context.Context) error) (bool, error) {
+	lease, err := l.Acquire(ctx, name, ttl)
+	if errors.Is(err, ErrNotAcquired) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := lease.keepAlive(runCtx, cancel)
+	fnErr := fn(runCtx)
+	stop()
+
+	releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer releaseCancel()
+
+	return true, errors.Join(fnErr, lease.Release(releaseCtx))
+}
+
+// Lease is a held lease
+type Lease struct {
+	store Store
+	name  string
+	owner string
+	ttl   time.Duration
+}
+
+// Name returns the name of the lease
+func (l *Lease) Name() string {
+	return l.name
+}
+
+// Renew extends the lease by its TTL. It returns ErrLost if the lease is no longer held.
+func (l *Lease) Renew(ctx context.Context) error {
+	ok, err := l.store.Renew(ctx, l.name, l.owner, l.ttl)
+	if err != nil {
+		return fmt.Errorf("lease: renewing %s: %w", l.name, err)
+	}
+	if !ok {
+		return ErrLost
+	}
+	return nil
+}
+
+// Release frees the lease so another owner may acquire it
+func (l *Lease) Release(ctx context.Context) error {
+	if err := l.store.Release(ctx, l.name, l.owner); err != nil {
+		return fmt.Errorf("lease: releasing %s: %w", l.name, err)
+	}
+	return nil
+}
+
+// keepAlive renews the lease at a third of its TTL until stopped. If the lease is lost, onLost is called.
+// The returned function stops renewal and waits for the renewal goroutine to exit.
+func (l *Lease) keepAlive(ctx context.Context, onLost func()) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := l.Renew(ctx); err != nil {
+					onLost()
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		wg.Wait()
+	}
+}
+
+// defaultOwner returns a unique owner identity for this process
+func defaultOwner() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,224 @@
+package lease_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Import the SQLite driver
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/clock"
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/lease"
+)
+
+func newSQLiteStore(t *testing.T) *lease.SQLStore {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "lease.db")+"?_busy_timeout=5000")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	store := lease.NewSQLStore(db, lease.DialectSQLite)
+	require.NoError(t, store.Migrate(context.Background()))
+	return store
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) lease.Store{
+		"memory": func(t *testing.T) lease.Store { return lease.NewMemoryStore() },
+		"sqlite": func(t *testing.T) lease.Store { return newSQLiteStore(t) },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+
+			ok, err := store.Acquire(ctx, "job", "a", time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok, "free lease should be acquired")
+
+			ok, err = store.Acquire(ctx, "job", "b", time.Minute)
+			require.NoError(t, err)
+			assert.False(t, ok, "held lease should not be acquired by another owner")
+
+			ok, err = store.Acquire(ctx, "job", "a", time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok, "owner should be able to re-acquire its lease")
+
+			ok, err = store.Renew(ctx, "job", "a", time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = store.Renew(ctx, "job", "b", time.Minute)
+			require.NoError(t, err)
+			assert.False(t, ok, "non-owner should not renew")
+
+			require.NoError(t, store.Release(ctx, "job", "b"))
+			ok, _ = store.Acquire(ctx, "job", "b", time.Minute)
+			assert.False(t, ok, "non-owner release should be ignored")
+
+			require.NoError(t, store.Release(ctx, "job", "a"))
+			ok, err = store.Acquire(ctx, "job", "b", time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok, "released lease should be acquired")
+		})
+	}
+}
+
+func TestStores_Expiry(t *testing.T) {
+	stores := map[string]interface {
+		lease.Store
+		SetClock(c clock.Clock)
+	}{
+		"memory": lease.NewMemoryStore(),
+		"sqlite": newSQLiteStore(t),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			clk := hoptest.NewClock(time.Now())
+			store.SetClock(clk)
+
+			ok, err := store.Acquire(ctx, "job", "a", time.Minute)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			clk.Advance(59 * time.Second)
+			ok, err = store.Acquire(ctx, "job", "b", time.Minute)
+			require.NoError(t, err)
+			assert.False(t, ok, "lease should be held until it expires")
+
+			clk.Advance(time.Second)
+
+			ok, err = store.Renew(ctx, "job", "a", time.Minute)
+			require.NoError(t, err)
+			assert.False(t, ok, "expired lease should not be renewed")
+
+			ok, err = store.Acquire(ctx, "job", "b", time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok, "expired lease should be acquired by another owner")
+		})
+	}
+}
+
+func TestLocker_RunExclusive(t *testing.T) {
+	ctx := context.Background()
+	store := lease.NewMemoryStore()
+	a := lease.NewLocker(store, lease.WithOwner("a"))
+	b := lease.NewLocker(store, lease.WithOwner("b"))
+
+	var ranB bool
+	ran, err := a.RunExclusive(ctx, "sweep", time.Minute, func(ctx context.Context) error {
+		ranB, err := b.RunExclusive(ctx, "sweep", time.Minute, func(ctx context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+		assert.False(t, ranB, "second owner should be skipped while the lease is held")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	ranB, err = b.RunExclusive(ctx, "sweep", time.Minute, func(ctx context.Context) error {
+		return errors.New("failed")
+	})
+	assert.True(t, ranB, "lease should be released after the first run")
+	assert.EqualError(t, err, "failed")
+}
+
+func TestLocker_Acquire(t *testing.T) {
+	ctx := context.Background()
+	store := lease.NewMemoryStore()
+	a := lease.NewLocker(store)
+	b := lease.NewLocker(store)
+
+	assert.NotEqual(t, a.Owner(), b.Owner(), "default owners should be unique")
+
+	l, err := a.Acquire(ctx, "job", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "job", l.Name())
+
+	_, err = b.Acquire(ctx, "job", time.Minute)
+	assert.ErrorIs(t, err, lease.ErrNotAcquired)
+
+	require.NoError(t, l.Renew(ctx))
+	require.NoError(t, l.Release(ctx))
+	assert.ErrorIs(t, l.Renew(ctx), lease.ErrLost)
+}
+
+func TestElector(t *testing.T) {
+	ctx := context.Background()
+	store := lease.NewMemoryStore()
+
+	var elected atomic.Int32
+	newElector := func(owner string) *lease.Elector {
+		return lease.NewElector(lease.NewLocker(store, lease.WithOwner(owner)), "leader", 30*time.Millisecond,
+			lease.OnElected(func(ctx context.Context) { elected.Add(1) }))
+	}
+
+	a := newElector("a")
+	b := newElector("b")
+
+	require.NoError(t, a.Start(ctx))
+	require.Eventually(t, a.IsLeader, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, b.Start(ctx))
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, b.IsLeader(), "only one instance should lead")
+
+	// When the leader stops, the follower takes over
+	require.NoError(t, a.Stop(ctx))
+	assert.False(t, a.IsLeader())
+	require.Eventually(t, b.IsLeader, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, b.Stop(ctx))
+	assert.Equal(t, int32(2), elected.Load())
+}
+
+func TestElector_BlockingCallback(t *testing.T) {
+	ctx := context.Background()
+	store := lease.NewMemoryStore()
+
+	var running, returned atomic.Int32
+	newElector := func(owner string) *lease.Elector {
+		return lease.NewElector(lease.NewLocker(store, lease.WithOwner(owner)), "leader", 90*time.Millisecond,
+			lease.OnElected(func(ctx context.Context) {
+				running.Add(1)
+				defer returned.Add(1)
+				<-ctx.Done()
+			}))
+	}
+
+	a := newElector("a")
+	b := newElector("b")
+	require.NoError(t, a.Start(ctx))
+	require.Eventually(t, a.IsLeader, time.Second, 5*time.Millisecond)
+	require.NoError(t, b.Start(ctx))
+
+	// The blocked callback must not stop the leader from renewing its lease
+	deadline := time.Now().Add(400 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		require.True(t, a.IsLeader(), "the leader should keep its lease while its callback blocks")
+		require.False(t, b.IsLeader(), "only one instance should lead")
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, int32(1), running.Load())
+
+	// Stop cancels the callback and waits for it to return, then the follower takes over
+	require.NoError(t, a.Stop(ctx))
+	assert.Equal(t, int32(1), returned.Load())
+	require.Eventually(t, b.IsLeader, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return running.Load() == 2 }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, b.Stop(ctx))
+	assert.Equal(t, int32(2), returned.Load())
+}
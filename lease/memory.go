@@ -0,0 +1,77 @@
+package lease
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+)
+
+// MemoryStore is an in-memory Store. It only coordinates within a single process, which makes it
+// useful for single-instance deployments and tests.
+type MemoryStore struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+	clock  clock.Clock
+}
+
+type memoryLease struct {
+	owner     string
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		leases: make(map[string]memoryLease),
+		clock:  clock.Real(),
+	}
+}
+
+// SetClock sets the clock used to tell when leases expire, e.g. a fake clock in tests
+func (s *MemoryStore) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = clock.OrReal(c)
+}
+
+// Acquire takes the named lease for owner if it is free, expired, or already held by owner
+func (s *MemoryStore) Acquire(_ context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if l, ok := s.leases[name]; ok && l.owner != owner && now.Before(l.expiresAt) {
+		return false, nil
+	}
+
+	s.leases[name] = memoryLease{owner: owner, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// Renew extends the named lease if it is still held by owner
+func (s *MemoryStore) Renew(_ context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	l, ok := s.leases[name]
+	if !ok || l.owner != owner || !now.Before(l.expiresAt) {
+		return false, nil
+	}
+
+	s.leases[name] = memoryLease{owner: owner, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// Release frees the named lease if it is held by owner
+func (s *MemoryStore) Release(_ context.Context, name, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, ok := s.leases[name]; ok && l.owner == owner {
+		delete(s.leases, name)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package lease
+
+import (
+	"context"
+	"time"
+)
+
+// RedisClient is the subset of a Redis client used by RedisStore. It can be satisfied with a small
+// adapter around any Redis client library, for example with go-redis:
+//
+//	type redisAdapter struct{ c *redis.Client }
+//
+//	func (a redisAdapter) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+//	    return a.c.SetNX(ctx, key, value, ttl).Result()
+//	}
+//
+//	func (a redisAdapter) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//	    return a.c.Eval(ctx, script, keys, args...).Result()
+//	}
+type RedisClient interface {
+	// SetNX sets key to value with a TTL only if the key does not exist
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// Eval runs a Lua script
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+const (
+	// redisRenewScript extends the key's TTL only if it still holds the owner's value
+	redisRenewScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+	// redisReleaseScript deletes the key only if it still holds the owner's value
+	redisReleaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+)
+
+// RedisStore is a Store backed by Redis. Each lease is a key holding the owner, with the lease TTL
+// as the key's expiry.
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore creates a new RedisStore. Lease keys are prefixed with "lease:".
+func NewRedisStore(client RedisClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "lease:"}
+}
+
+// Acquire takes the named lease for owner if it is free, expired, or already held by owner
+func (s *RedisStore) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	ok, err := s.client.SetNX(ctx, s.prefix+name, owner, ttl)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	// Re-acquiring a lease we already hold extends it
+	return s.Renew(ctx, name, owner, ttl)
+}
+
+// Renew extends the named lease if it is still held by owner
+func (s *RedisStore) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	res, err := s.client.Eval(ctx, redisRenewScript, []string{s.prefix + name}, owner, ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	return isOne(res), nil
+}
+
+// Release frees the named lease if it is held by owner
+func (s *RedisStore) Release(ctx context.Context, name, owner string) error {
+	_, err := s.client.Eval(ctx, redisReleaseScript, []string{s.prefix + name}, owner)
+	return err
+}
+
+// isOne reports whether a Redis integer reply is 1
+func isOne(v any) bool {
+	switch n := v.(type) {
+	case int64:
+		return n == 1
+	case int:
+		return n == 1
+	default:
+		return false
+	}
+}
@@ -0,0 +1,97 @@
+package lease
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+)
+
+// Dialect selects the SQL dialect used by SQLStore
+type Dialect int
+
+const (
+	// DialectSQLite targets SQLite 3.24 or later
+	DialectSQLite Dialect = iota
+	// DialectPostgres targets PostgreSQL 9.5 or later
+	DialectPostgres
+)
+
+// SQLStore is a Store backed by a SQLite or Postgres database. Each lease is a row in the leases
+// table, and acquisition relies on an atomic upsert so only one owner can hold a lease at a time.
+type SQLStore struct {
+	db      *sql.DB
+	dialect Dialect
+	clock   clock.Clock
+}
+
+// NewSQLStore creates a new SQLStore using the given database and dialect
+func NewSQLStore(db *sql.DB, dialect Dialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect, clock: clock.Real()}
+}
+
+// SetClock sets the clock used to tell when leases expire, e.g. a fake clock in tests. It must be called
+// before the store is used.
+func (s *SQLStore) SetClock(c clock.Clock) {
+	s.clock = clock.OrReal(c)
+}
+
+// Migrate creates the leases table if it does not exist
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	q := `CREATE TABLE IF NOT EXISTS leases (
+		name TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`
+
+	if _, err := s.db.ExecContext(ctx, q); err != nil {
+		return fmt.Errorf("creating leases table: %w", err)
+	}
+	return nil
+}
+
+// Acquire takes the named lease for owner if it is free, expired, or already held by owner
+func (s *SQLStore) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := s.clock.Now()
+
+	// The upsert only overwrites an existing row when the lease has expired or is already ours,
+	// so no rows are affected when another owner holds it.
+	res, err := s.db.ExecContext(ctx, `INSERT INTO leases (name, owner, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE leases.expires_at <= $4 OR leases.owner = excluded.owner`,
+		name, owner, now.Add(ttl).UnixNano(), now.UnixNano())
+	if err != nil {
+		return false, err
+	}
+
+	return affected(res)
+}
+
+// Renew extends the named lease if it is still held by owner
+func (s *SQLStore) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := s.clock.Now()
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE leases SET expires_at = $1 WHERE name = $2 AND owner = $3 AND expires_at > $4",
+		now.Add(ttl).UnixNano(), name, owner, now.UnixNano())
+	if err != nil {
+		return false, err
+	}
+
+	return affected(res)
+}
+
+// Release frees the named lease if it is held by owner
+func (s *SQLStore) Release(ctx context.Context, name, owner string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM leases WHERE name = $1 AND owner = $2", name, owner)
+	return err
+}
+
+func affected(res sql.Result) (bool, error) {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -2,4 +2,7 @@
 // The goal is to make it easier to create a new slog.Logger given a set of options. More specfically,
 // within the context of the project, we want to be able to create a new logger based on the config
 // options provided by the user via environment variables or the CLI.
+//
+// NewSyslogHandler and NewOTLPHandler create handlers that ship records to a syslog server or an
+// OpenTelemetry collector in the background, and NewMultiHandler combines them with a local handler.
 package log
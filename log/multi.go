@@ -0,0 +1,65 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// multiHandler passes records to several handlers
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+// NewMultiHandler returns a slog.Handler that passes each record to all the handlers enabled for its level,
+// e.g. to write logs to stderr and ship them to a remote collector.
+func NewMultiHandler(handlers ...slog.Handler) slog.Handler {
+	if len(handlers) == 1 {
+		return handlers[0]
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// Enabled reports whether any of the handlers is enabled for the level
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to each handler enabled for its level
+func (h *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, r.Level) {
+			if err := handler.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a handler with the attributes added to each handler
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// WithGroup returns a handler with the group added to each handler
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
+}
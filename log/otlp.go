@@ -0,0 +1,215 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// OTLPOptions configures an OpenTelemetry ShippingHandler
+type OTLPOptions struct {
+	ShipOptions
+	// Endpoint is the OTLP/HTTP logs endpoint of the collector. Default is "http://localhost:4318/v1/logs".
+	Endpoint string
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+	// ServiceName is the service.name resource attribute. Default is the name of the executable.
+	ServiceName string
+	// Client sends the requests. Default is a client with Timeout.
+	Client *http.Client
+	// Timeout limits each request when Client isn't set. Default is 10 seconds.
+	Timeout time.Duration
+}
+
+// NewOTLPHandler creates a ShippingHandler that sends records to an OpenTelemetry collector using OTLP/HTTP
+// with the JSON encoding. Record attributes become log record attributes, keeping their types. Requests that
+// fail with a 429 or 5xx status, or a network error, are retried; other errors drop the batch.
+//
+//	handler, err := log.NewOTLPHandler(func(opts *log.OTLPOptions) {
+//		opts.Endpoint = "https://otel.example.com/v1/logs"
+//		opts.Headers = map[string]string{"Authorization": "Bearer " + token}
+//		opts.ServiceName = "shop"
+//	})
+func NewOTLPHandler(optsFunc func(opts *OTLPOptions)) (*ShippingHandler, error) {
+	opts := OTLPOptions{
+		ShipOptions: defaultShipOptions(),
+		Endpoint:    "http://localhost:4318/v1/logs",
+		Timeout:     10 * time.Second,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Endpoint == "" {
+		return nil, errors.New("otlp: endpoint is required")
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = filepath.Base(os.Args[0])
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: opts.Timeout}
+	}
+
+	w := &otlpWriter{opts: opts}
+	return newShippingHandler(opts.ShipOptions, w.encode, w.send), nil
+}
+
+// otlpWriter encodes entries as OTLP log records and posts them to the collector
+type otlpWriter struct {
+	opts OTLPOptions
+}
+
+// otlpValue is an OTLP AnyValue; integers are encoded as strings, as the protobuf JSON mapping requires
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpAttr struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 otlpValue  `json:"body"`
+	Attributes           []otlpAttr `json:"attributes,omitempty"`
+}
+
+// encode formats an entry as an OTLP JSON log record
+func (w *otlpWriter) encode(e entry) []byte {
+	record := otlpRecord{
+		TimeUnixNano:         strconv.FormatInt(e.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       otlpSeverity(e.Level),
+		SeverityText:         e.Level.String(),
+		Body:                 otlpString(e.Message),
+		Attributes:           make([]otlpAttr, 0, len(e.Attrs)),
+	}
+	if e.Time.IsZero() {
+		record.TimeUnixNano = "0"
+	}
+	for _, a := range e.Attrs {
+		record.Attributes = append(record.Attributes, otlpAttr{Key: a.Key, Value: otlpAttrValue(a.Value)})
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		// Only reachable with values json can't encode, such as NaN; keep the message
+		b, _ = json.Marshal(otlpRecord{
+			TimeUnixNano:   record.TimeUnixNano,
+			SeverityNumber: record.SeverityNumber,
+			SeverityText:   record.SeverityText,
+			Body:           record.Body,
+		})
+	}
+	return b
+}
+
+// send posts a batch of encoded records to the collector
+func (w *otlpWriter) send(ctx context.Context, batch [][]byte) error {
+	records := make([]json.RawMessage, len(batch))
+	for i, b := range batch {
+		records[i] = b
+	}
+
+	payload := map[string]any{
+		"resourceLogs": []any{
+			map[string]any{
+				"resource": map[string]any{
+					"attributes": []otlpAttr{{Key: "service.name", Value: otlpString(w.opts.ServiceName)}},
+				},
+				"scopeLogs": []any{
+					map[string]any{
+						"scope":      map[string]string{"name": "github.com/patrickward/hop"},
+						"logRecords": records,
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return permanentError{fmt.Errorf("otlp: encoding request: %w", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{fmt.Errorf("otlp: creating request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp: sending request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("otlp: collector returned status %d", resp.StatusCode)
+	default:
+		return permanentError{fmt.Errorf("otlp: collector returned status %d", resp.StatusCode)}
+	}
+}
+
+// otlpSeverity maps a slog level to an OTLP severity number: DEBUG is 5, INFO 9, WARN 13 and ERROR 17
+func otlpSeverity(level slog.Level) int {
+	n := int(level) + 9
+	if n < 1 {
+		return 1
+	}
+	if n > 24 {
+		return 24
+	}
+	return n
+}
+
+func otlpString(s string) otlpValue {
+	return otlpValue{StringValue: &s}
+}
+
+// otlpAttrValue converts a slog value to an OTLP value, keeping numbers and booleans typed
+func otlpAttrValue(v slog.Value) otlpValue {
+	switch v.Kind() {
+	case slog.KindInt64:
+		s := strconv.FormatInt(v.Int64(), 10)
+		return otlpValue{IntValue: &s}
+	case slog.KindUint64:
+		s := strconv.FormatUint(v.Uint64(), 10)
+		return otlpValue{IntValue: &s}
+	case slog.KindFloat64:
+		f := v.Float64()
+		return otlpValue{DoubleValue: &f}
+	case slog.KindBool:
+		b := v.Bool()
+		return otlpValue{BoolValue: &b}
+	default:
+		return otlpString(v.String())
+	}
+}
@@ -0,0 +1,338 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShipOptions configures the buffering and retries of a ShippingHandler
+type ShipOptions struct {
+	// Level is the minimum level shipped. Default is slog.LevelInfo.
+	Level slog.Leveler
+	// BufferSize is the number of entries buffered while waiting to be sent. When the buffer is full, new
+	// entries are dropped rather than blocking the application. Default is 1024.
+	BufferSize int
+	// BatchSize is the maximum number of entries sent at once. Default is 100.
+	BatchSize int
+	// FlushInterval is how often buffered entries are sent when a batch isn't full. Default is 1 second.
+	FlushInterval time.Duration
+	// MaxRetries is the number of times a failed batch is retried before it is dropped; 0 disables retries.
+	// Default is 3.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled for each further retry. Default is 500ms.
+	RetryBackoff time.Duration
+	// OnError is called when a batch is dropped, or entries are dropped because the buffer is full. It must
+	// not log to a logger using the handler. Default writes to os.Stderr.
+	OnError func(err error)
+}
+
+// defaultShipOptions returns the options with their defaults, so a zero MaxRetries can disable retries
+func defaultShipOptions() ShipOptions {
+	return ShipOptions{
+		Level:         slog.LevelInfo,
+		BufferSize:    1024,
+		BatchSize:     100,
+		FlushInterval: time.Second,
+		MaxRetries:    3,
+		RetryBackoff:  500 * time.Millisecond,
+	}
+}
+
+// setDefaults fills in the invalid fields
+func (o *ShipOptions) setDefaults() {
+	if o.Level == nil {
+		o.Level = slog.LevelInfo
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = 1024
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = time.Second
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = 500 * time.Millisecond
+	}
+	if o.OnError == nil {
+		o.OnError = func(err error) {
+			_, _ = fmt.Fprintf(os.Stderr, "log shipping: %v\n", err)
+		}
+	}
+}
+
+// permanentError marks a send error that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// entry is a log record flattened for encoding
+type entry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	// Attrs are the record's attributes, with group names joined to their keys with dots
+	Attrs []slog.Attr
+}
+
+// ShippingHandler is a slog.Handler that ships records to a remote log collector, such as a syslog server or
+// an OpenTelemetry collector. Records are buffered and sent in batches from a background goroutine, with
+// retries, so logging never blocks on the network. Call Close on shutdown to send the buffered records.
+type ShippingHandler struct {
+	level  slog.Leveler
+	attrs  []slog.Attr
+	prefix string
+	core   *shipper
+}
+
+// newShippingHandler creates a handler sending batches of encoded entries with send
+func newShippingHandler(opts ShipOptions, encode func(e entry) []byte, send func(ctx context.Context, batch [][]byte) error) *ShippingHandler {
+	opts.setDefaults()
+
+	core := &shipper{
+		opts:    opts,
+		encode:  encode,
+		send:    send,
+		queue:   make(chan []byte, opts.BufferSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go core.run()
+
+	return &ShippingHandler{level: opts.Level, core: core}
+}
+
+// Enabled reports whether records at the level are shipped
+func (h *ShippingHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle encodes the record and buffers it for sending
+func (h *ShippingHandler) Handle(_ context.Context, r slog.Record) error {
+	e := entry{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   make([]slog.Attr, 0, len(h.attrs)+r.NumAttrs()),
+	}
+	e.Attrs = append(e.Attrs, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		e.Attrs = appendAttr(e.Attrs, h.prefix, a)
+		return true
+	})
+
+	h.core.enqueue(h.core.encode(e))
+	return nil
+}
+
+// WithAttrs returns a handler that adds the attributes to every record
+func (h *ShippingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = appendAttr(h2.attrs, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a handler that qualifies the keys of later attributes with the group name
+func (h *ShippingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Flush sends the buffered records and waits until they have been sent, or ctx is done
+func (h *ShippingHandler) Flush(ctx context.Context) error {
+	return h.core.flush(ctx)
+}
+
+// Close sends the buffered records and stops the background goroutine. Records logged after Close are
+// dropped. It is safe to call Close more than once.
+func (h *ShippingHandler) Close(ctx context.Context) error {
+	return h.core.close(ctx)
+}
+
+// Dropped returns the number of records dropped because the buffer was full or sending failed
+func (h *ShippingHandler) Dropped() int64 {
+	return h.core.dropped.Load()
+}
+
+// appendAttr resolves an attribute and appends it, flattening groups into dotted keys
+func appendAttr(attrs []slog.Attr, prefix string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = prefix + a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			attrs = appendAttr(attrs, groupPrefix, ga)
+		}
+		return attrs
+	}
+
+	a.Key = prefix + a.Key
+	return append(attrs, a)
+}
+
+// shipper buffers encoded entries and sends them in batches from a background goroutine
+type shipper struct {
+	opts   ShipOptions
+	encode func(e entry) []byte
+	send   func(ctx context.Context, batch [][]byte) error
+
+	queue     chan []byte
+	flushes   chan chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closed    atomic.Bool
+	dropped   atomic.Int64
+}
+
+// enqueue buffers an entry, dropping it when the buffer is full
+func (s *shipper) enqueue(b []byte) {
+	if s.closed.Load() {
+		s.dropped.Add(1)
+		return
+	}
+
+	select {
+	case s.queue <- b:
+	default:
+		// Report the first drop and then every thousandth, rather than every dropped entry
+		if n := s.dropped.Add(1); n == 1 || n%1000 == 0 {
+			s.opts.OnError(fmt.Errorf("buffer full, %d log entries dropped", n))
+		}
+	}
+}
+
+// run sends batches until the shipper is closed
+func (s *shipper) run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, s.opts.BatchSize)
+	for {
+		select {
+		case b := <-s.queue:
+			batch = append(batch, b)
+			if len(batch) >= s.opts.BatchSize {
+				batch = s.sendBatch(batch)
+			}
+		case <-ticker.C:
+			batch = s.sendBatch(batch)
+		case done := <-s.flushes:
+			batch = s.drain(batch)
+			close(done)
+		case <-s.done:
+			s.drain(batch)
+			return
+		}
+	}
+}
+
+// drain sends the batch and everything left in the queue
+func (s *shipper) drain(batch [][]byte) [][]byte {
+	for {
+		select {
+		case b := <-s.queue:
+			batch = append(batch, b)
+			if len(batch) >= s.opts.BatchSize {
+				batch = s.sendBatch(batch)
+			}
+		default:
+			return s.sendBatch(batch)
+		}
+	}
+}
+
+// sendBatch sends a batch with retries and returns the emptied batch for reuse
+func (s *shipper) sendBatch(batch [][]byte) [][]byte {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	backoff := s.opts.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= s.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-s.done:
+				// Closing: make one last attempt without waiting
+			}
+			backoff *= 2
+		}
+
+		if err = s.send(context.Background(), batch); err == nil {
+			return batch[:0]
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			break
+		}
+	}
+
+	s.dropped.Add(int64(len(batch)))
+	s.opts.OnError(fmt.Errorf("dropping %d log entries: %w", len(batch), err))
+	return batch[:0]
+}
+
+// flush asks the background goroutine to send everything buffered and waits for it
+func (s *shipper) flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case s.flushes <- done:
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops accepting entries, sends the buffered ones and waits for the background goroutine
+func (s *shipper) close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		s.closed.Store(true)
+		close(s.done)
+	})
+
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
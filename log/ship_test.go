@@ -0,0 +1,161 @@
+package log_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/log"
+)
+
+func TestSyslogHandler(t *testing.T) {
+	t.Run("requires an address", func(t *testing.T) {
+		_, err := log.NewSyslogHandler(nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("sends RFC 5424 messages over TCP", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+
+		received := make(chan string, 10)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			// Read octet-counted frames: "LEN SP MSG"
+			r := bufio.NewReader(conn)
+			for {
+				length, err := r.ReadString(' ')
+				if err != nil {
+					return
+				}
+				n, _ := strconv.Atoi(strings.TrimSpace(length))
+				msg := make([]byte, n)
+				if _, err := io.ReadFull(r, msg); err != nil {
+					return
+				}
+				received <- string(msg)
+			}
+		}()
+
+		handler, err := log.NewSyslogHandler(func(opts *log.SyslogOptions) {
+			opts.Network = "tcp"
+			opts.Address = ln.Addr().String()
+			opts.AppName = "shop"
+			opts.Hostname = "web1"
+		})
+		require.NoError(t, err)
+
+		logger := slog.New(handler)
+		logger.Warn("disk almost full", slog.Int("percent", 91), slog.Group("disk", slog.String("path", `/var/"data"`)))
+		logger.Debug("not shipped")
+		require.NoError(t, handler.Close(context.Background()))
+
+		select {
+		case msg := <-received:
+			assert.True(t, strings.HasPrefix(msg, "<12>1 "), msg)
+			assert.Contains(t, msg, " web1 shop ")
+			assert.Contains(t, msg, `[attrs@32473 percent="91" disk.path="/var/\"data\""]`)
+			assert.True(t, strings.HasSuffix(msg, " disk almost full"), msg)
+		case <-time.After(2 * time.Second):
+			t.Fatal("no message received")
+		}
+
+		select {
+		case msg := <-received:
+			t.Fatalf("unexpected message: %s", msg)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestOTLPHandler(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []map[string]any
+		failures = 1
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var payload map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		requests = append(requests, payload)
+	}))
+	defer server.Close()
+
+	handler, err := log.NewOTLPHandler(func(opts *log.OTLPOptions) {
+		opts.Endpoint = server.URL
+		opts.Headers = map[string]string{"Authorization": "Bearer secret"}
+		opts.ServiceName = "shop"
+		opts.RetryBackoff = time.Millisecond
+	})
+	require.NoError(t, err)
+
+	logger := slog.New(handler).With(slog.String("request_id", "abc"))
+	logger.Error("payment failed", slog.Int("amount", 42), slog.Bool("retry", true))
+	require.NoError(t, handler.Flush(context.Background()))
+	require.NoError(t, handler.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 1, "the failed request is retried")
+	assert.Zero(t, handler.Dropped())
+
+	resourceLogs := requests[0]["resourceLogs"].([]any)[0].(map[string]any)
+	resource := resourceLogs["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	assert.Equal(t, "service.name", resource["key"])
+	assert.Equal(t, map[string]any{"stringValue": "shop"}, resource["value"])
+
+	record := resourceLogs["scopeLogs"].([]any)[0].(map[string]any)["logRecords"].([]any)[0].(map[string]any)
+	assert.Equal(t, float64(17), record["severityNumber"])
+	assert.Equal(t, "ERROR", record["severityText"])
+	assert.Equal(t, map[string]any{"stringValue": "payment failed"}, record["body"])
+	assert.Equal(t, []any{
+		map[string]any{"key": "request_id", "value": map[string]any{"stringValue": "abc"}},
+		map[string]any{"key": "amount", "value": map[string]any{"intValue": "42"}},
+		map[string]any{"key": "retry", "value": map[string]any{"boolValue": true}},
+	}, record["attributes"])
+}
+
+func TestMultiHandler(t *testing.T) {
+	var a, b strings.Builder
+	logger := slog.New(log.NewMultiHandler(
+		slog.NewTextHandler(&a, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelWarn}),
+	))
+
+	logger.Debug("details")
+	logger.With(slog.String("user", "ada")).Warn("careful")
+
+	assert.Contains(t, a.String(), "msg=details")
+	assert.Contains(t, a.String(), "msg=careful user=ada")
+	assert.NotContains(t, b.String(), "details")
+	assert.Contains(t, b.String(), "msg=careful user=ada")
+}
@@ -0,0 +1,243 @@
+package log
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogOptions configures a syslog ShippingHandler
+type SyslogOptions struct {
+	ShipOptions
+	// Network is "udp", "tcp" or "tls". Default is "udp".
+	Network string
+	// Address is the address of the syslog server, e.g. "logs.example.com:514" (required)
+	Address string
+	// TLSConfig is used with the "tls" network. Default verifies the server with the system roots.
+	TLSConfig *tls.Config
+	// Facility is the syslog facility code. Default is 1 (user-level messages).
+	Facility int
+	// AppName is the APP-NAME field. Default is the name of the executable.
+	AppName string
+	// Hostname is the HOSTNAME field. Default is the name of the host.
+	Hostname string
+	// StructuredDataID is the SD-ID of the element holding the record attributes. Default is
+	// "attrs@32473", using the enterprise number reserved for documentation.
+	StructuredDataID string
+	// Timeout limits connecting and writing each batch. Default is 10 seconds.
+	Timeout time.Duration
+}
+
+// NewSyslogHandler creates a ShippingHandler that sends records to a syslog server in the RFC 5424 format,
+// over UDP, TCP or TLS. TCP and TLS messages are framed with octet counting (RFC 6587). Record attributes
+// are sent as structured data. The connection is made lazily and re-established after errors.
+//
+//	handler, err := log.NewSyslogHandler(func(opts *log.SyslogOptions) {
+//		opts.Network = "tls"
+//		opts.Address = "logs.example.com:6514"
+//	})
+//	app.RegisterShutdownPhase(hop.ShutdownFlush, "syslog", handler.Close)
+func NewSyslogHandler(optsFunc func(opts *SyslogOptions)) (*ShippingHandler, error) {
+	opts := SyslogOptions{
+		ShipOptions:      defaultShipOptions(),
+		Network:          "udp",
+		Facility:         1,
+		StructuredDataID: "attrs@32473",
+		Timeout:          10 * time.Second,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Address == "" {
+		return nil, errors.New("syslog: address is required")
+	}
+	switch opts.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("syslog: unsupported network %q: must be udp, tcp or tls", opts.Network)
+	}
+	if opts.Facility < 0 || opts.Facility > 23 {
+		return nil, fmt.Errorf("syslog: invalid facility %d", opts.Facility)
+	}
+	if opts.AppName == "" {
+		opts.AppName = filepath.Base(os.Args[0])
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	if opts.StructuredDataID == "" {
+		opts.StructuredDataID = "attrs@32473"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	w := &syslogWriter{opts: opts, pid: strconv.Itoa(os.Getpid())}
+	return newShippingHandler(opts.ShipOptions, w.encode, w.send), nil
+}
+
+// syslogWriter encodes entries as RFC 5424 messages and writes them to the server
+type syslogWriter struct {
+	opts SyslogOptions
+	pid  string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// encode formats an entry as an RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ELEMENT] MSG
+func (w *syslogWriter) encode(e entry) []byte {
+	var b strings.Builder
+	b.WriteString("<")
+	b.WriteString(strconv.Itoa(w.opts.Facility*8 + syslogSeverity(e.Level)))
+	b.WriteString(">1 ")
+	b.WriteString(e.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"))
+	b.WriteString(" ")
+	b.WriteString(syslogField(w.opts.Hostname, 255))
+	b.WriteString(" ")
+	b.WriteString(syslogField(w.opts.AppName, 48))
+	b.WriteString(" ")
+	b.WriteString(w.pid)
+	b.WriteString(" - ")
+
+	if len(e.Attrs) == 0 {
+		b.WriteString("-")
+	} else {
+		b.WriteString("[")
+		b.WriteString(w.opts.StructuredDataID)
+		for _, a := range e.Attrs {
+			b.WriteString(" ")
+			b.WriteString(syslogParamName(a.Key))
+			b.WriteString(`="`)
+			b.WriteString(syslogParamValue(a.Value.String()))
+			b.WriteString(`"`)
+		}
+		b.WriteString("]")
+	}
+
+	if e.Message != "" {
+		b.WriteString(" ")
+		b.WriteString(e.Message)
+	}
+	return []byte(b.String())
+}
+
+// send writes a batch of messages, reconnecting on the next batch after an error
+func (w *syslogWriter) send(ctx context.Context, batch [][]byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		conn, err := w.dial(ctx)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+
+	_ = w.conn.SetWriteDeadline(time.Now().Add(w.opts.Timeout))
+	for _, msg := range batch {
+		var err error
+		if w.opts.Network == "udp" {
+			_, err = w.conn.Write(msg)
+		} else {
+			_, err = w.conn.Write(append([]byte(strconv.Itoa(len(msg))+" "), msg...))
+		}
+		if err != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+			return fmt.Errorf("syslog: writing message: %w", err)
+		}
+	}
+	return nil
+}
+
+// dial connects to the syslog server
+func (w *syslogWriter) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: w.opts.Timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if w.opts.Network == "tls" {
+		config := w.opts.TLSConfig
+		if config == nil {
+			host, _, _ := net.SplitHostPort(w.opts.Address)
+			config = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: config}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", w.opts.Address)
+	} else {
+		conn, err = dialer.DialContext(ctx, w.opts.Network, w.opts.Address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("syslog: connecting: %w", err)
+	}
+	return conn, nil
+}
+
+// syslogSeverity maps a slog level to a syslog severity
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3 // error
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= slog.LevelInfo:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
+
+// syslogField returns a header field: printable ASCII without spaces, at most max characters, or "-"
+func syslogField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// syslogParamName returns a valid structured data parameter name, replacing invalid characters
+func syslogParamName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	if s == "" {
+		return "_"
+	}
+	return s
+}
+
+// syslogParamValue escapes a structured data parameter value
+func syslogParamValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
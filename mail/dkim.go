@@ -0,0 +1,205 @@
+package mail
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	gomail "github.com/wneessen/go-mail"
+)
+
+// dkimHeaders are the headers signed when present, in signing order
+var dkimHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID", "MIME-Version", "Content-Type",
+	"In-Reply-To", "References", "List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// DKIMSigner signs emails with DKIM (RFC 6376), so receivers can check they come from the signing domain.
+// Headers and body use relaxed canonicalization, which survives the whitespace changes of relays. Keys are
+// RSA (rsa-sha256) or Ed25519 (ed25519-sha256, RFC 8463).
+type DKIMSigner struct {
+	domain    string
+	selector  string
+	key       crypto.Signer
+	algorithm string
+	now       func() time.Time
+}
+
+// NewDKIMSigner creates a new DKIMSigner for the domain and selector, whose public key is published in DNS
+// at <selector>._domainkey.<domain>. The private key is PEM encoded, in PKCS #1 or PKCS #8 form.
+func NewDKIMSigner(domain, selector string, privateKeyPEM []byte) (*DKIMSigner, error) {
+	if domain == "" || selector == "" {
+		return nil, errors.New("DKIM signing requires a domain and a selector")
+	}
+
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("DKIM private key is not PEM encoded")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported DKIM private key type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DKIM private key: %w", err)
+	}
+
+	s := &DKIMSigner{domain: domain, selector: selector, now: time.Now}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s.key, s.algorithm = k, "rsa-sha256"
+	case ed25519.PrivateKey:
+		s.key, s.algorithm = k, "ed25519-sha256"
+	default:
+		return nil, fmt.Errorf("unsupported DKIM private key algorithm %T", key)
+	}
+	return s, nil
+}
+
+// Sign adds a DKIM-Signature header to the email. The email must not change afterwards: the signature
+// covers its body and headers as rendered now, including its Date, Message-ID and multipart boundaries,
+// which rendering fixes.
+func (s *DKIMSigner) Sign(email *gomail.Msg) error {
+	// The first rendering sets the default headers and boundaries, so the second one is what is sent
+	if _, err := email.WriteTo(io.Discard); err != nil {
+		return fmt.Errorf("failed to render email for DKIM: %w", err)
+	}
+	var buf bytes.Buffer
+	if _, err := email.WriteTo(&buf); err != nil {
+		return fmt.Errorf("failed to render email for DKIM: %w", err)
+	}
+
+	signature, err := s.signature(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	email.SetGenHeaderPreformatted("DKIM-Signature", signature)
+	return nil
+}
+
+// signature returns the DKIM-Signature header value of a raw message
+func (s *DKIMSigner) signature(raw []byte) (string, error) {
+	header, body, _ := bytes.Cut(raw, []byte("\r\n\r\n"))
+	fields := parseHeaderFields(string(header) + "\r\n")
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+
+	var names []string
+	hash := sha256.New()
+	for _, name := range dkimHeaders {
+		field, ok := lastHeaderField(fields, name)
+		if !ok {
+			continue
+		}
+		names = append(names, strings.ToLower(name))
+		hash.Write([]byte(relaxedHeader(field)))
+	}
+	if len(names) == 0 || names[0] != "from" {
+		return "", errors.New("DKIM signing requires a From header")
+	}
+
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		s.algorithm, s.domain, s.selector, s.now().Unix(), strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]))
+	hash.Write([]byte(strings.TrimSuffix(relaxedHeader("DKIM-Signature: "+value), "\r\n")))
+
+	opts := crypto.SignerOpts(crypto.SHA256)
+	if s.algorithm == "ed25519-sha256" {
+		opts = crypto.Hash(0)
+	}
+	sig, err := s.key.Sign(rand.Reader, hash.Sum(nil), opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign email: %w", err)
+	}
+
+	return value + base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// parseHeaderFields splits a header block into fields, keeping folded lines with their field
+func parseHeaderFields(header string) []string {
+	var fields []string
+	for _, line := range strings.SplitAfter(header, "\r\n") {
+		if line == "" || line == "\r\n" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	return fields
+}
+
+// lastHeaderField returns the last field with the name, the one DKIM signs first
+func lastHeaderField(fields []string, name string) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fieldName, _, ok := strings.Cut(fields[i], ":"); ok && strings.EqualFold(strings.TrimSpace(fieldName), name) {
+			return fields[i], true
+		}
+	}
+	return "", false
+}
+
+// relaxedHeader canonicalizes a header field: lowercase name, unfolded value with whitespace runs reduced
+// to a space and trimmed
+func relaxedHeader(field string) string {
+	name, value, _ := strings.Cut(field, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.TrimSpace(collapseWhitespace(value)) + "\r\n"
+}
+
+// relaxedBody canonicalizes a body: whitespace runs reduced to a space, trailing whitespace and empty lines
+// removed
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(collapseWhitespace(line), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// collapseWhitespace replaces runs of spaces and tabs with a single space
+func collapseWhitespace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package mail_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/mail"
+)
+
+var whitespaceRun = regexp.MustCompile(`[ \t]+`)
+
+// verifyDKIM checks the relaxed/relaxed DKIM signature of a raw message against a public key
+func verifyDKIM(t *testing.T, raw []byte, pub crypto.PublicKey) map[string]string {
+	t.Helper()
+
+	header, body, _ := strings.Cut(string(raw), "\r\n\r\n")
+	header = regexp.MustCompile(`\r\n[ \t]`).ReplaceAllString(header, " ")
+
+	fields := make(map[string]string)
+	var signature string
+	for _, line := range strings.Split(header, "\r\n") {
+		name, value, _ := strings.Cut(line, ":")
+		canonical := strings.ToLower(strings.TrimSpace(name)) + ":" + strings.TrimSpace(whitespaceRun.ReplaceAllString(value, " "))
+		if strings.EqualFold(name, "DKIM-Signature") {
+			signature = canonical
+			continue
+		}
+		fields[strings.ToLower(strings.TrimSpace(name))] = canonical
+	}
+	require.NotEmpty(t, signature, "missing DKIM-Signature")
+
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(strings.TrimPrefix(signature, "dkim-signature:"), ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(tag), "=")
+		tags[k] = v
+	}
+
+	lines := strings.Split(body, "\r\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(whitespaceRun.ReplaceAllString(lines[i], " "), " ")
+	}
+	canonicalBody := strings.TrimRight(strings.Join(lines, "\r\n"), "\r\n") + "\r\n"
+	bh := sha256.Sum256([]byte(canonicalBody))
+	require.Equal(t, base64.StdEncoding.EncodeToString(bh[:]), tags["bh"], "body hash")
+
+	hash := sha256.New()
+	for _, name := range strings.Split(tags["h"], ":") {
+		hash.Write([]byte(fields[name] + "\r\n"))
+	}
+	hash.Write([]byte(signature[:strings.LastIndex(signature, "b=")+2]))
+
+	sig, err := base64.StdEncoding.DecodeString(tags["b"])
+	require.NoError(t, err)
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		require.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, hash.Sum(nil), sig), "rsa signature")
+	case ed25519.PublicKey:
+		require.True(t, ed25519.Verify(key, hash.Sum(nil), sig), "ed25519 signature")
+	}
+	return tags
+}
+
+func TestMailer_DKIM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	edPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER})
+
+	tests := []struct {
+		name      string
+		key       []byte
+		pub       crypto.PublicKey
+		algorithm string
+	}{
+		{name: "rsa", key: rsaPEM, pub: &rsaKey.PublicKey, algorithm: "rsa-sha256"},
+		{name: "ed25519", key: edPEM, pub: edPub, algorithm: "ed25519-sha256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DKIMDomain = "example.com"
+			cfg.DKIMSelector = "mail"
+			cfg.DKIMPrivateKey = string(tt.key)
+
+			client := newMockSMTPClient()
+			mailer := mail.NewMailerWithClient(cfg, client)
+
+			msg, err := mail.NewMessage().
+				To("recipient@example.com").
+				Template("testdata/basic.tmpl").
+				WithData(map[string]string{"name": "John"}).
+				Attach("notes.txt", strings.NewReader("some notes")).
+				Build()
+			require.NoError(t, err)
+			require.NoError(t, mailer.Send(msg))
+
+			sent, err := client.LastMessage()
+			require.NoError(t, err)
+			var raw bytes.Buffer
+			_, err = sent.raw.WriteTo(&raw)
+			require.NoError(t, err)
+
+			tags := verifyDKIM(t, raw.Bytes(), tt.pub)
+			assert.Equal(t, tt.algorithm, tags["a"])
+			assert.Equal(t, "example.com", tags["d"])
+			assert.Equal(t, "mail", tags["s"])
+			assert.Equal(t, "relaxed/relaxed", tags["c"])
+			assert.True(t, strings.HasPrefix(tags["h"], "from:"), tags["h"])
+		})
+	}
+
+	t.Run("invalid key", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Transport = mail.TransportFile
+		cfg.FileDir = t.TempDir()
+		cfg.DKIMDomain = "example.com"
+		cfg.DKIMSelector = "mail"
+		cfg.DKIMPrivateKey = "not a key"
+
+		_, err := mail.NewMailer(cfg)
+		assert.ErrorContains(t, err, "invalid DKIM configuration")
+
+		err = mail.NewMailerWithClient(cfg, newMockSMTPClient()).Send(basicMessage(t))
+		assert.ErrorContains(t, err, "invalid DKIM configuration")
+	})
+}
@@ -0,0 +1,243 @@
+package inbound
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source delivers raw inbound messages to the module
+type Source interface {
+	// Poll calls handle for every new message. Messages for which handle returns nil are marked as
+	// processed and are not delivered again; failed messages are retried on the next poll.
+	Poll(ctx context.Context, handle func(ctx context.Context, raw []byte) error) error
+}
+
+// IMAPSource polls an IMAP mailbox for unseen messages. It implements the small subset of IMAP4rev1
+// needed to fetch messages and mark them as seen, so it has no external dependencies.
+type IMAPSource struct {
+	// Addr is the server address, e.g. "imap.example.com:993"
+	Addr string
+	// Username and Password are used to LOGIN
+	Username string
+	Password string
+	// Mailbox is the folder to poll. Defaults to "INBOX".
+	Mailbox string
+	// TLSConfig configures the TLS connection. Nil uses the default configuration for the server name.
+	TLSConfig *tls.Config
+	// Insecure connects without TLS. Only use this for local development servers.
+	Insecure bool
+	// Delete removes messages from the mailbox after they are processed instead of marking them seen
+	Delete bool
+	// Timeout is the deadline for the whole poll. Defaults to 1 minute.
+	Timeout time.Duration
+}
+
+// Poll fetches every unseen message and calls handle for each one
+func (s *IMAPSource) Poll(ctx context.Context, handle func(ctx context.Context, raw []byte) error) error {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("inbound: connecting to %s: %w", s.Addr, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+	if _, err := c.readLine(); err != nil {
+		return fmt.Errorf("inbound: reading greeting: %w", err)
+	}
+
+	if _, err := c.command("LOGIN %s %s", quote(s.Username), quote(s.Password)); err != nil {
+		return err
+	}
+	defer func() { _, _ = c.command("LOGOUT") }()
+
+	mailbox := s.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	if _, err := c.command("SELECT %s", quote(mailbox)); err != nil {
+		return err
+	}
+
+	untagged, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return err
+	}
+
+	var uids []string
+	for _, resp := range untagged {
+		if rest, ok := strings.CutPrefix(resp.line, "* SEARCH"); ok {
+			uids = append(uids, strings.Fields(rest)...)
+		}
+	}
+
+	var (
+		errs    []error
+		deleted bool
+	)
+	for _, uid := range uids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		resps, err := c.command("UID FETCH %s BODY.PEEK[]", uid)
+		if err != nil {
+			return err
+		}
+
+		var raw []byte
+		for _, resp := range resps {
+			if resp.literal != nil {
+				raw = resp.literal
+				break
+			}
+		}
+		if raw == nil {
+			continue
+		}
+
+		if err := handle(ctx, raw); err != nil {
+			errs = append(errs, fmt.Errorf("message %s: %w", uid, err))
+			continue
+		}
+
+		flag := `\Seen`
+		if s.Delete {
+			flag = `\Deleted`
+			deleted = true
+		}
+		if _, err := c.command("UID STORE %s +FLAGS.SILENT (%s)", uid, flag); err != nil {
+			return err
+		}
+	}
+
+	if deleted {
+		if _, err := c.command("EXPUNGE"); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// dial opens the connection to the server
+func (s *IMAPSource) dial(ctx context.Context) (net.Conn, error) {
+	if s.Insecure {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", s.Addr)
+	}
+
+	cfg := s.TLSConfig
+	if cfg == nil {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			host = s.Addr
+		}
+		cfg = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	}
+	d := tls.Dialer{Config: cfg}
+	return d.DialContext(ctx, "tcp", s.Addr)
+}
+
+// imapConn is a minimal IMAP protocol connection
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is an untagged response line, with the literal it carried, if any
+type imapResponse struct {
+	line    string
+	literal []byte
+}
+
+// command sends a tagged command and returns the untagged responses. It returns an error unless the
+// server answers OK.
+func (c *imapConn) command(format string, args ...any) ([]imapResponse, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	cmd := fmt.Sprintf(format, args...)
+
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, cmd); err != nil {
+		return nil, fmt.Errorf("inbound: sending command: %w", err)
+	}
+
+	var untagged []imapResponse
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("inbound: reading response: %w", err)
+		}
+
+		if rest, ok := strings.CutPrefix(line, tag+" "); ok {
+			if !strings.HasPrefix(rest, "OK") {
+				name, _, _ := strings.Cut(cmd, " ")
+				return nil, fmt.Errorf("inbound: %s failed: %s", name, rest)
+			}
+			return untagged, nil
+		}
+
+		resp := imapResponse{line: line}
+		// A literal is announced with {n} at the end of the line and followed by n bytes
+		if n, ok := literalSize(line); ok {
+			resp.literal = make([]byte, n)
+			if _, err := io.ReadFull(c.r, resp.literal); err != nil {
+				return nil, fmt.Errorf("inbound: reading literal: %w", err)
+			}
+			// The remainder of the response, usually ")", follows the literal
+			if _, err := c.readLine(); err != nil {
+				return nil, fmt.Errorf("inbound: reading response: %w", err)
+			}
+		}
+		untagged = append(untagged, resp)
+	}
+}
+
+// readLine reads a CRLF terminated line
+func (c *imapConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// literalSize returns the size of the literal announced at the end of line
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(line[open+1 : len(line)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// quote returns s as an IMAP quoted string
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,252 @@
+package inbound
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ErrInvalidMessage is returned when an inbound message cannot be parsed
+var ErrInvalidMessage = errors.New("inbound: invalid message")
+
+// maxPartDepth limits how deeply nested multipart messages are parsed
+const maxPartDepth = 10
+
+// Message is a normalized inbound email
+type Message struct {
+	// ID is the Message-ID header, without angle brackets
+	ID string `json:"id"`
+	// From is the sender
+	From *mail.Address `json:"from"`
+	// To are the primary recipients
+	To []*mail.Address `json:"to"`
+	// Cc are the carbon copy recipients
+	Cc []*mail.Address `json:"cc,omitempty"`
+	// ReplyTo are the addresses replies should be sent to, if set
+	ReplyTo []*mail.Address `json:"reply_to,omitempty"`
+	// Subject is the decoded subject
+	Subject string `json:"subject"`
+	// Date is when the message was sent
+	Date time.Time `json:"date"`
+	// InReplyTo is the Message-ID of the message being replied to, without angle brackets
+	InReplyTo string `json:"in_reply_to,omitempty"`
+	// References are the Message-IDs of the thread, without angle brackets
+	References []string `json:"references,omitempty"`
+	// Text is the plain text body
+	Text string `json:"text,omitempty"`
+	// HTML is the HTML body
+	HTML string `json:"html,omitempty"`
+	// Attachments are the attached and inline files
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Header contains every header of the message
+	Header mail.Header `json:"-"`
+	// Mailbox is the mailbox the message was routed to, e.g. "support"
+	Mailbox string `json:"mailbox"`
+	// Token is the sub-address of the recipient, e.g. "ticket-42" for "support+ticket-42@example.com".
+	// It is typically used to associate replies with a record.
+	Token string `json:"token,omitempty"`
+}
+
+// Attachment is a file attached to an inbound email
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	ContentID   string `json:"content_id,omitempty"`
+	Inline      bool   `json:"inline"`
+	Data        []byte `json:"data"`
+}
+
+// Parse reads a raw RFC 5322 message and normalizes it. Headers are decoded, transfer encodings are
+// removed, and multipart bodies are split into the text and HTML bodies and attachments. Mailbox and
+// Token are not set; they are filled in by the module when the message is routed.
+func Parse(r io.Reader) (*Message, error) {
+	raw, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+
+	dec := &mime.WordDecoder{CharsetReader: charsetReader}
+	msg := &Message{
+		ID:         trimID(raw.Header.Get("Message-Id")),
+		InReplyTo:  trimID(raw.Header.Get("In-Reply-To")),
+		References: splitIDs(raw.Header.Get("References")),
+		Header:     raw.Header,
+	}
+
+	if msg.Subject, err = dec.DecodeHeader(raw.Header.Get("Subject")); err != nil {
+		msg.Subject = raw.Header.Get("Subject")
+	}
+
+	if from, err := raw.Header.AddressList("From"); err == nil && len(from) > 0 {
+		msg.From = from[0]
+	}
+	msg.To, _ = raw.Header.AddressList("To")
+	msg.Cc, _ = raw.Header.AddressList("Cc")
+	msg.ReplyTo, _ = raw.Header.AddressList("Reply-To")
+
+	if date, err := raw.Header.Date(); err == nil {
+		msg.Date = date
+	}
+
+	if err := msg.parsePart(raw.Header, raw.Body, 0); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// textHeader is the subset of header access needed to parse a part
+type textHeader interface {
+	Get(key string) string
+}
+
+// parsePart parses a single MIME part, recursing into multipart bodies
+func (m *Message) parsePart(h textHeader, body io.Reader, depth int) error {
+	if depth > maxPartDepth {
+		return fmt.Errorf("%w: nested too deeply", ErrInvalidMessage)
+	}
+
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain; charset=us-ascii"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%w: reading multipart body: %w", ErrInvalidMessage, err)
+			}
+			if err := m.parsePart(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("%w: decoding body: %w", ErrInvalidMessage, err)
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if filename != "" {
+		dec := &mime.WordDecoder{CharsetReader: charsetReader}
+		if decoded, err := dec.DecodeHeader(filename); err == nil {
+			filename = decoded
+		}
+	}
+
+	isBody := disposition != "attachment" && filename == ""
+	switch {
+	case isBody && mediaType == "text/plain" && m.Text == "":
+		m.Text = decodeCharset(params["charset"], data)
+	case isBody && mediaType == "text/html" && m.HTML == "":
+		m.HTML = decodeCharset(params["charset"], data)
+	default:
+		m.Attachments = append(m.Attachments, Attachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			ContentID:   trimID(h.Get("Content-Id")),
+			Inline:      disposition == "inline",
+			Data:        data,
+		})
+	}
+
+	return nil
+}
+
+// decodeTransfer removes a Content-Transfer-Encoding
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &whitespaceStripper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// whitespaceStripper removes line breaks and spaces from base64 content
+type whitespaceStripper struct {
+	r io.Reader
+}
+
+// Read reads from the underlying reader, dropping whitespace
+func (w *whitespaceStripper) Read(p []byte) (int, error) {
+	for {
+		n, err := w.r.Read(p)
+		j := 0
+		for _, b := range p[:n] {
+			if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
+
+// decodeCharset converts text in the given charset to UTF-8. Unknown charsets are returned unchanged.
+func decodeCharset(charset string, data []byte) string {
+	if charset == "" || strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "us-ascii") {
+		return string(data)
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return string(data)
+	}
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+// charsetReader decodes encoded words in non UTF-8 charsets
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// trimID removes the angle brackets and whitespace around a message ID
+func trimID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
+
+// splitIDs splits a list of message IDs
+func splitIDs(s string) []string {
+	var ids []string
+	for _, field := range strings.Fields(s) {
+		if id := trimID(field); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
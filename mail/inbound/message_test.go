@@ -0,0 +1,80 @@
+package inbound_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/mail/inbound"
+)
+
+const multipartMessage = "From: Jo Doe <jo@example.com>\r\n" +
+	"To: support+ticket-42@example.org\r\n" +
+	"Cc: team@example.org\r\n" +
+	"Subject: =?UTF-8?q?Caf=C3=A9_order?=\r\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 -0700\r\n" +
+	"Message-ID: <abc@example.com>\r\n" +
+	"In-Reply-To: <orig@example.org>\r\n" +
+	"References: <root@example.org> <orig@example.org>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Caf=E9 reply\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Café reply</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/plain; name=\"notes.txt\"\r\n" +
+	"Content-Disposition: attachment; filename=\"notes.txt\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"aGVsbG8g\r\n" +
+	"d29ybGQ=\r\n" +
+	"--outer--\r\n"
+
+func TestParse(t *testing.T) {
+	msg, err := inbound.Parse(strings.NewReader(multipartMessage))
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc@example.com", msg.ID)
+	assert.Equal(t, "jo@example.com", msg.From.Address)
+	assert.Equal(t, "Jo Doe", msg.From.Name)
+	require.Len(t, msg.To, 1)
+	assert.Equal(t, "support+ticket-42@example.org", msg.To[0].Address)
+	require.Len(t, msg.Cc, 1)
+	assert.Equal(t, "Café order", msg.Subject)
+	assert.Equal(t, 2006, msg.Date.Year())
+	assert.Equal(t, "orig@example.org", msg.InReplyTo)
+	assert.Equal(t, []string{"root@example.org", "orig@example.org"}, msg.References)
+	assert.Equal(t, "Café reply", strings.TrimSpace(msg.Text))
+	assert.Equal(t, "<p>Café reply</p>", strings.TrimSpace(msg.HTML))
+
+	require.Len(t, msg.Attachments, 1)
+	assert.Equal(t, "notes.txt", msg.Attachments[0].Filename)
+	assert.Equal(t, "text/plain", msg.Attachments[0].ContentType)
+	assert.Equal(t, "hello world", string(msg.Attachments[0].Data))
+}
+
+func TestParse_SinglePart(t *testing.T) {
+	msg, err := inbound.Parse(strings.NewReader("From: a@example.com\r\nTo: b@example.com\r\nSubject: Hi\r\n\r\nJust text\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Just text\r\n", msg.Text)
+	assert.Empty(t, msg.HTML)
+	assert.Empty(t, msg.Attachments)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := inbound.Parse(strings.NewReader("not an email"))
+	assert.ErrorIs(t, err, inbound.ErrInvalidMessage)
+}
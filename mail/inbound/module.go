@@ -0,0 +1,283 @@
+// Package inbound provides a module that ingests inbound email, either by polling an IMAP mailbox or by
+// receiving provider webhooks, parses it into a normalized Message, and emits it on the application
+// dispatcher as "email.received.<mailbox>". This enables features such as reply-by-email.
+//
+//	mod := inbound.NewModule(&inbound.Config{
+//	    Source:        &inbound.IMAPSource{Addr: "imap.example.com:993", Username: user, Password: pass},
+//	    WebhookPath:   "/webhooks/inbound-email",
+//	    WebhookSecret: secret,
+//	})
+//
+//	dispatcher.OnWithError("email.received.support", func(ctx context.Context, e dispatch.Event) error {
+//	    msg := dispatch.MustPayloadAs[*inbound.Message](e)
+//	    return tickets.AddReply(ctx, msg.Token, msg.From.Address, msg.Text)
+//	})
+package inbound
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/route"
+)
+
+// EventPrefix is the prefix of the signature of events emitted for inbound messages
+const EventPrefix = "email.received."
+
+// Config configures the inbound email module
+type Config struct {
+	// Source is polled for new messages. Nil disables polling.
+	Source Source
+	// PollInterval is how often the source is polled. Defaults to 1 minute.
+	PollInterval time.Duration
+	// WebhookPath, when set, registers a POST route that accepts raw MIME messages from an email provider
+	WebhookPath string
+	// WebhookSecret must be sent by the provider in the X-Webhook-Secret header or the "secret" query
+	// parameter. It is required when WebhookPath is set.
+	WebhookSecret string
+	// MaxSize is the largest message accepted, in bytes. Defaults to 25 MB.
+	MaxSize int64
+	// Route returns the mailbox a message is delivered to. Defaults to the local part of the first
+	// recipient, without any sub-address, e.g. "support" for "support+ticket-42@example.com".
+	Route func(msg *Message) string
+	// Logger is used to log failures. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Module implements hop.Module for inbound email
+type Module struct {
+	config     *Config
+	dispatcher *dispatch.Dispatcher
+	done       chan struct{}
+	stopOnce   sync.Once
+	wg         sync.WaitGroup
+}
+
+// NewModule creates a new inbound email module
+func NewModule(config *Config) *Module {
+	if config == nil {
+		config = &Config{}
+	}
+
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Minute
+	}
+
+	if config.MaxSize <= 0 {
+		config.MaxSize = 25 << 20
+	}
+
+	if config.Route == nil {
+		config.Route = DefaultRoute
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	return &Module{
+		config: config,
+		done:   make(chan struct{}),
+	}
+}
+
+func (m *Module) ID() string {
+	return "hop.inbound"
+}
+
+func (m *Module) Init() error {
+	if m.config.WebhookPath != "" && m.config.WebhookSecret == "" {
+		return errors.New("inbound: a webhook secret is required when the webhook path is set")
+	}
+	return nil
+}
+
+// RegisterEvents captures the dispatcher that inbound messages are emitted on
+func (m *Module) RegisterEvents(events *dispatch.Dispatcher) {
+	m.dispatcher = events
+}
+
+// RegisterRoutes registers the webhook route, if configured
+func (m *Module) RegisterRoutes(router *route.Mux) {
+	if m.config.WebhookPath == "" {
+		return
+	}
+	router.Post(m.config.WebhookPath, m.WebhookHandler())
+}
+
+// Start begins polling the source on the configured interval
+func (m *Module) Start(ctx context.Context) error {
+	if m.config.Source == nil {
+		return nil
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := m.Poll(ctx); err != nil {
+				m.config.Logger.Error("polling inbound email", slog.String("error", err.Error()))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop halts polling
+func (m *Module) Stop(_ context.Context) error {
+	m.stopOnce.Do(func() { close(m.done) })
+	m.wg.Wait()
+	return nil
+}
+
+// Poll polls the source once
+func (m *Module) Poll(ctx context.Context) error {
+	if m.config.Source == nil {
+		return nil
+	}
+	return m.config.Source.Poll(ctx, func(ctx context.Context, raw []byte) error {
+		_, err := m.Receive(ctx, bytes.NewReader(raw))
+		return err
+	})
+}
+
+// Receive parses a raw message, routes it to a mailbox and emits it synchronously as
+// "email.received.<mailbox>". Errors returned by handlers registered with OnWithError are returned, so
+// sources can retry the message later.
+func (m *Module) Receive(ctx context.Context, r io.Reader) (*Message, error) {
+	msg, err := Parse(io.LimitReader(r, m.config.MaxSize))
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Mailbox = sanitizeMailbox(m.config.Route(msg))
+	if msg.Token == "" {
+		msg.Token = subAddress(msg)
+	}
+
+	if m.dispatcher == nil {
+		return msg, errors.New("inbound: no dispatcher registered")
+	}
+
+	m.config.Logger.Info("inbound email received",
+		slog.String("mailbox", msg.Mailbox),
+		slog.String("message_id", msg.ID))
+
+	if err := m.dispatcher.EmitSync(ctx, EventPrefix+msg.Mailbox, msg); err != nil {
+		return msg, fmt.Errorf("inbound: handling message %s: %w", msg.ID, err)
+	}
+	return msg, nil
+}
+
+// WebhookHandler returns the handler for provider webhooks. It accepts the raw MIME message as the
+// request body (Content-Type message/rfc822 or text/plain), or as a form field named "email" or
+// "body-mime", which covers the raw modes of common providers.
+func (m *Module) WebhookHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get("X-Webhook-Secret")
+		if secret == "" {
+			secret = r.URL.Query().Get("secret")
+		}
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(m.config.WebhookSecret)) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, m.config.MaxSize+1<<20)
+
+		var body io.Reader = r.Body
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded" {
+			if err := r.ParseMultipartForm(m.config.MaxSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+				http.Error(w, "invalid form", http.StatusBadRequest)
+				return
+			}
+			raw := r.FormValue("email")
+			if raw == "" {
+				raw = r.FormValue("body-mime")
+			}
+			if raw == "" {
+				http.Error(w, "missing email field", http.StatusBadRequest)
+				return
+			}
+			body = strings.NewReader(raw)
+		}
+
+		if _, err := m.Receive(r.Context(), body); err != nil {
+			m.config.Logger.Error("handling inbound email webhook", slog.String("error", err.Error()))
+			// A server error asks the provider to retry; a malformed message never succeeds
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrInvalidMessage) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// DefaultRoute returns the local part of the first To or Cc recipient, without any sub-address
+func DefaultRoute(msg *Message) string {
+	local, _, _ := strings.Cut(firstRecipientLocal(msg), "+")
+	return local
+}
+
+// firstRecipientLocal returns the local part of the first recipient
+func firstRecipientLocal(msg *Message) string {
+	recipients := append(append([]*mail.Address(nil), msg.To...), msg.Cc...)
+	if len(recipients) == 0 {
+		return ""
+	}
+	local, _, _ := strings.Cut(recipients[0].Address, "@")
+	return local
+}
+
+// subAddress returns the sub-address of the first recipient, e.g. "ticket-42" for
+// "support+ticket-42@example.com"
+func subAddress(msg *Message) string {
+	_, token, _ := strings.Cut(firstRecipientLocal(msg), "+")
+	return token
+}
+
+// sanitizeMailbox makes a mailbox safe to use as an event signature segment
+func sanitizeMailbox(mailbox string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(mailbox) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "unknown"
+	}
+	return b.String()
+}
@@ -0,0 +1,239 @@
+package inbound_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/mail/inbound"
+	"github.com/patrickward/hop/route"
+)
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func newTestModule(t *testing.T, config *inbound.Config) (*inbound.Module, *dispatch.Dispatcher) {
+	t.Helper()
+	config.Logger = quietLogger()
+	mod := inbound.NewModule(config)
+	require.NoError(t, mod.Init())
+
+	bus := dispatch.NewDispatcher(quietLogger())
+	mod.RegisterEvents(bus)
+	return mod, bus
+}
+
+func TestModule_ReceiveEmitsEvent(t *testing.T) {
+	mod, bus := newTestModule(t, &inbound.Config{})
+
+	var received *inbound.Message
+	bus.On("email.received.support", func(ctx context.Context, e dispatch.Event) {
+		received = dispatch.MustPayloadAs[*inbound.Message](e)
+	})
+
+	msg, err := mod.Receive(context.Background(), strings.NewReader(multipartMessage))
+	require.NoError(t, err)
+	require.NotNil(t, received)
+	assert.Same(t, msg, received)
+	assert.Equal(t, "support", received.Mailbox)
+	assert.Equal(t, "ticket-42", received.Token)
+}
+
+func TestModule_CustomRoute(t *testing.T) {
+	mod, bus := newTestModule(t, &inbound.Config{
+		Route: func(msg *inbound.Message) string { return "Help Desk" },
+	})
+
+	var signature string
+	bus.On("email.received.*", func(ctx context.Context, e dispatch.Event) {
+		signature = e.Signature
+	})
+
+	_, err := mod.Receive(context.Background(), strings.NewReader(multipartMessage))
+	require.NoError(t, err)
+	assert.Equal(t, "email.received.help_desk", signature)
+}
+
+func TestModule_Webhook(t *testing.T) {
+	mod, bus := newTestModule(t, &inbound.Config{
+		WebhookPath:   "/webhooks/email",
+		WebhookSecret: "s3cret",
+	})
+
+	var count int
+	bus.On("email.received.support", func(ctx context.Context, e dispatch.Event) { count++ })
+
+	mux := route.New()
+	mod.RegisterRoutes(mux)
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		secret      string
+		wantStatus  int
+	}{
+		{name: "raw body", target: "/webhooks/email", contentType: "message/rfc822", body: multipartMessage, secret: "s3cret", wantStatus: http.StatusNoContent},
+		{name: "form field", target: "/webhooks/email?secret=s3cret", contentType: "application/x-www-form-urlencoded", body: url.Values{"email": {multipartMessage}}.Encode(), wantStatus: http.StatusNoContent},
+		{name: "wrong secret", target: "/webhooks/email", contentType: "message/rfc822", body: multipartMessage, secret: "nope", wantStatus: http.StatusUnauthorized},
+		{name: "missing field", target: "/webhooks/email", contentType: "application/x-www-form-urlencoded", body: "other=1", secret: "s3cret", wantStatus: http.StatusBadRequest},
+		{name: "malformed message", target: "/webhooks/email", contentType: "message/rfc822", body: "garbage", secret: "s3cret", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.secret != "" {
+				req.Header.Set("X-Webhook-Secret", tt.secret)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+
+	assert.Equal(t, 2, count)
+}
+
+func TestModule_InitRequiresWebhookSecret(t *testing.T) {
+	mod := inbound.NewModule(&inbound.Config{WebhookPath: "/webhooks/email"})
+	assert.Error(t, mod.Init())
+}
+
+// fakeIMAPServer serves a scripted IMAP session with the given messages, keyed by UID
+type fakeIMAPServer struct {
+	mu       sync.Mutex
+	messages map[string]string
+	seen     map[string]bool
+	commands []string
+}
+
+func (s *fakeIMAPServer) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		tag, cmd, _ := strings.Cut(line, " ")
+
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		switch {
+		case strings.HasPrefix(cmd, "LOGIN"):
+			if cmd != `LOGIN "user" "pa\"ss"` {
+				fmt.Fprintf(conn, "%s NO invalid credentials\r\n", tag)
+				s.mu.Unlock()
+				continue
+			}
+		case cmd == "UID SEARCH UNSEEN":
+			var uids []string
+			for uid := range s.messages {
+				if !s.seen[uid] {
+					uids = append(uids, uid)
+				}
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(sortStrings(uids), " "))
+		case strings.HasPrefix(cmd, "UID FETCH"):
+			uid := strings.Fields(cmd)[2]
+			body := s.messages[uid]
+			fmt.Fprintf(conn, "* 1 FETCH (UID %s BODY[] {%d}\r\n%s)\r\n", uid, len(body), body)
+		case strings.HasPrefix(cmd, "UID STORE"):
+			s.seen[strings.Fields(cmd)[2]] = true
+		case cmd == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK done\r\n", tag)
+			s.mu.Unlock()
+			return
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+		s.mu.Unlock()
+	}
+}
+
+func sortStrings(s []string) []string {
+	for i := range s {
+		for j := i + 1; j < len(s); j++ {
+			if s[j] < s[i] {
+				s[i], s[j] = s[j], s[i]
+			}
+		}
+	}
+	return s
+}
+
+func TestIMAPSource_Poll(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	server := &fakeIMAPServer{
+		messages: map[string]string{
+			"1": multipartMessage,
+			"2": "From: a@example.com\r\nTo: sales@example.org\r\nSubject: Quote\r\n\r\nPrice?\r\n",
+		},
+		seen: make(map[string]bool),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(t, conn)
+		}
+	}()
+
+	source := &inbound.IMAPSource{Addr: ln.Addr().String(), Username: "user", Password: `pa"ss`, Insecure: true, Timeout: 5 * time.Second}
+	mod, bus := newTestModule(t, &inbound.Config{Source: source})
+
+	var mailboxes []string
+	bus.On("email.received.*", func(ctx context.Context, e dispatch.Event) {
+		mailboxes = append(mailboxes, dispatch.MustPayloadAs[*inbound.Message](e).Mailbox)
+	})
+	failSales := true
+	bus.OnWithError("email.received.sales", func(ctx context.Context, e dispatch.Event) error {
+		if failSales {
+			return errors.New("crm unavailable")
+		}
+		return nil
+	})
+
+	// The failing message stays unseen and is delivered again on the next poll
+	err = mod.Poll(context.Background())
+	assert.ErrorContains(t, err, "crm unavailable")
+	assert.ElementsMatch(t, []string{"support", "sales"}, mailboxes)
+	assert.True(t, server.seen["1"])
+	assert.False(t, server.seen["2"])
+
+	failSales = false
+	mailboxes = nil
+	require.NoError(t, mod.Poll(context.Background()))
+	assert.Equal(t, []string{"sales"}, mailboxes)
+	assert.True(t, server.seen["2"])
+
+	bad := &inbound.IMAPSource{Addr: ln.Addr().String(), Username: "user", Password: "wrong", Insecure: true}
+	err = bad.Poll(context.Background(), func(ctx context.Context, raw []byte) error { return nil })
+	assert.ErrorContains(t, err, "LOGIN failed")
+}
@@ -3,6 +3,7 @@ package mail
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
@@ -12,6 +13,8 @@ import (
 
 	gomail "github.com/wneessen/go-mail"
 
+	"github.com/patrickward/hop/clock"
+	"github.com/patrickward/hop/mail/processors"
 	"github.com/patrickward/hop/templates"
 )
 
@@ -20,13 +23,19 @@ var (
 	ErrNoSubject = errors.New("email must have a subject")
 )
 
-// SMTPClient defines the interface for an SMTP client, mainly used for testing
+// SMTPClient defines the interface for an SMTP client, used by SMTPTransport and mainly replaced for testing
 type SMTPClient interface {
 	DialAndSend(messages ...*gomail.Msg) error
 }
 
 // Config holds the mailer configuration
 type Config struct {
+	// Transport selects how emails are delivered: TransportSMTP (the default), TransportFile or TransportHTTP
+	Transport string
+	FileDir   string // Directory the file transport writes .eml files to
+	HTTPURL   string // URL the HTTP transport posts emails to
+	HTTPToken string // Bearer token of the HTTP transport's requests
+
 	// SMTP server configuration
 	Host      string // SMTP server host
 	Port      int    // SMTP server port
@@ -36,17 +45,29 @@ type Config struct {
 	AuthType  string // Type of SMTP authentication (see the go-mail package for options). Default is LOGIN.
 	TLSPolicy int    // TLS policy for the SMTP connection (see the go-mail package for options). Default is opportunistic.
 
+	// DKIM signing, applied to every email when DKIMDomain, DKIMSelector and DKIMPrivateKey are set
+	DKIMDomain     string // Signing domain (d=), aligned with the domain of the From address
+	DKIMSelector   string // Selector of the public key, published at <selector>._domainkey.<domain>
+	DKIMPrivateKey string // PEM encoded RSA or Ed25519 private key
+
 	// Template configuration
-	TemplateFS      fs.FS            // File system for templates
-	TemplatePath    string           // Path to the templates directory in the file system
-	TemplateFuncMap template.FuncMap // Template function map that gets merged with the default function map from render
+	TemplateFS      fs.FS                 // File system for templates
+	TemplatePath    string                // Path to the templates directory in the file system
+	TemplateFuncMap template.FuncMap      // Template function map that gets merged with the default function map from render
+	Components      *templates.Components // Components shared with web pages, available as "component:<name>" using their email version
 
 	// Retry configuration
 	RetryCount int           // Number of retry attempts for sending email
 	RetryDelay time.Duration // Delay between retry attempts
 
+	// Throttling
+	MaxPerMinute int         // Maximum number of emails sent per minute by Send and SendBatch. Default is 0, unlimited.
+	BatchSize    int         // Number of emails SendBatch sends over one SMTP connection. Default is 50.
+	Clock        clock.Clock // Clock the rate limit and retries wait with, e.g. a hoptest.Clock in tests. Default is the real clock.
+
 	// HTML processor for processing HTML content
 	HTMLProcessor HTMLProcessor // HTML processor for processing HTML content
+	InlineCSS     bool          // Inline the CSS of <style> elements into style attributes with processors.CSSInliner, after HTMLProcessor
 
 	// Company/Branding
 	BaseURL         string // Base URL of the website
@@ -81,55 +102,76 @@ type StringList = []string
 
 // Mailer handles email sending operations
 type Mailer struct {
-	config *Config
-	//client        *gomail.Client
-	client        SMTPClient
+	config        *Config
+	transport     Transport
 	funcMap       template.FuncMap
 	htmlProcessor HTMLProcessor
+	dkim          *DKIMSigner
+	dkimErr       error
+	limiter       *sendLimiter
+	clock         clock.Clock
 }
 
-// NewMailer creates a new Mailer instance using the provided configuration and the default SMTP client
+// NewMailer creates a new Mailer instance using the provided configuration and the transport it selects,
+// by default SMTP
 func NewMailer(cfg *Config) (*Mailer, error) {
-	authType := authTypeFromString(cfg.AuthType)
-	tlsPolicy := tlsPolicyFromInt(cfg.TLSPolicy)
-
-	client, err := gomail.NewClient(
-		cfg.Host,
-		gomail.WithTimeout(10*time.Second),
-		gomail.WithSMTPAuth(authType),
-		gomail.WithPort(cfg.Port),
-		gomail.WithUsername(cfg.Username),
-		gomail.WithPassword(cfg.Password),
-		gomail.WithTLSPolicy(tlsPolicy),
-	)
+	transport, err := newTransport(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create mail client: %w", err)
+		return nil, err
 	}
 
-	return NewMailerWithClient(cfg, client), nil
+	mailer := NewMailerWithTransport(cfg, transport)
+	if mailer.dkimErr != nil {
+		return nil, mailer.dkimErr
+	}
+	return mailer, nil
 }
 
 // NewMailerWithClient creates a new Mailer with a provided SMTP client
 func NewMailerWithClient(cfg *Config, client SMTPClient) *Mailer {
+	return NewMailerWithTransport(cfg, NewSMTPTransport(client))
+}
+
+// NewMailerWithTransport creates a new Mailer delivering with the provided transport
+func NewMailerWithTransport(cfg *Config, transport Transport) *Mailer {
 	if cfg.RetryCount == 0 {
 		cfg.RetryCount = 3
 	}
 	if cfg.RetryDelay == 0 {
 		cfg.RetryDelay = 2 * time.Second
 	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
 	if cfg.HTMLProcessor == nil {
 		cfg.HTMLProcessor = &DefaultHTMLProcessor{}
 	}
+	htmlProcessor := cfg.HTMLProcessor
+	if cfg.InlineCSS {
+		htmlProcessor = processors.NewCompositeProcessor(cfg.HTMLProcessor, processors.NewCSSInliner())
+	}
 
 	//funcMap := render.MergeFuncMaps(cfg.TemplateFuncMap)
 	funcMap := templates.MergeFuncMaps(templates.FuncMap(), cfg.TemplateFuncMap)
 
-	return &Mailer{
+	m := &Mailer{
 		config:        cfg,
-		client:        client,
+		transport:     transport,
 		funcMap:       funcMap,
-		htmlProcessor: cfg.HTMLProcessor,
+		htmlProcessor: htmlProcessor,
+		limiter:       newSendLimiter(cfg.MaxPerMinute, cfg.Clock),
+		clock:         clock.OrReal(cfg.Clock),
+	}
+
+	// A DKIM configuration error is returned by NewMailer, or by Send for mailers created with a client
+	if cfg.DKIMDomain != "" || cfg.DKIMSelector != "" || cfg.DKIMPrivateKey != "" {
+		m.dkim, m.dkimErr = NewDKIMSigner(cfg.DKIMDomain, cfg.DKIMSelector, []byte(cfg.DKIMPrivateKey))
+		if m.dkimErr != nil {
+			m.dkimErr = fmt.Errorf("invalid DKIM configuration: %w", m.dkimErr)
+		}
 	}
+
+	return m
 }
 
 // Config returns the mailer configuration
@@ -137,29 +179,56 @@ func (m *Mailer) Config() *Config {
 	return m.config
 }
 
-// Send sends an email using the provided template and data
+// Send sends an email using the provided template and data, waiting first when Config.MaxPerMinute emails
+// were sent in the last minute
 func (m *Mailer) Send(msg *Message) error {
+	email, err := m.build(msg)
+	if err != nil {
+		return err
+	}
+
+	_ = m.limiter.wait(context.Background())
+	return m.sendWithRetry(email)
+}
+
+// build renders a message into an email ready to send
+func (m *Mailer) build(msg *Message) (*gomail.Msg, error) {
 	email := gomail.NewMsg()
 
 	if err := m.setAddresses(email, msg); err != nil {
-		return err
+		return nil, err
 	}
 
+	m.setHeaders(email, msg)
+
 	if err := m.processTemplates(email, msg); err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := m.addAttachments(email, msg.Attachments); err != nil {
-		return err
+		return nil, err
 	}
 
-	return m.sendWithRetry(email)
+	if m.dkimErr != nil {
+		return nil, m.dkimErr
+	}
+	if m.dkim != nil {
+		if err := m.dkim.Sign(email); err != nil {
+			return nil, err
+		}
+	}
+
+	return email, nil
 }
 
 // setAddresses sets all address fields on the email
 func (m *Mailer) setAddresses(email *gomail.Msg, msg *Message) error {
-	// Set From address
-	if err := email.From(m.config.From); err != nil {
+	// Set From address, preferring the message's own
+	from := m.config.From
+	if msg.From != "" {
+		from = msg.From
+	}
+	if err := email.From(from); err != nil {
 		return fmt.Errorf("failed to set from address: %w", err)
 	}
 
@@ -192,6 +261,20 @@ func (m *Mailer) setAddresses(email *gomail.Msg, msg *Message) error {
 	return nil
 }
 
+// setHeaders sets the custom and priority headers of the message on the email
+func (m *Mailer) setHeaders(email *gomail.Msg, msg *Message) {
+	for key, value := range msg.Headers {
+		email.SetGenHeader(gomail.Header(key), value)
+	}
+
+	switch msg.Priority {
+	case PriorityLow:
+		email.SetImportance(gomail.ImportanceLow)
+	case PriorityHigh:
+		email.SetImportance(gomail.ImportanceHigh)
+	}
+}
+
 // NewTemplateData creates a new template data map with default values
 func (m *Mailer) NewTemplateData() TemplateData {
 	return NewTemplateData(m.config)
@@ -200,13 +283,26 @@ func (m *Mailer) NewTemplateData() TemplateData {
 func (m *Mailer) processTemplates(email *gomail.Msg, msg *Message) error {
 	templatePath := msg.Templates
 	if m.config.TemplatePath != "" {
-		// For each template, we need to prepend the template path
+		// For each template, we need to prepend the template path, without changing the message so it can
+		// be rendered again
+		templatePath = make([]string, len(msg.Templates))
 		for i, tmpl := range msg.Templates {
-			msg.Templates[i] = strings.TrimSuffix(m.config.TemplatePath, "/") + "/" + tmpl
+			templatePath[i] = strings.TrimSuffix(m.config.TemplatePath, "/") + "/" + tmpl
+		}
+	}
+
+	tmpl := template.New("").Funcs(m.funcMap)
+	if m.config.Components != nil {
+		if err := m.config.Components.AddTo(tmpl, templates.MediumEmail); err != nil {
+			return &TemplateError{
+				TemplateName: "components",
+				OriginalErr:  err,
+				Phase:        "parse",
+			}
 		}
 	}
 
-	tmpl, err := template.New("").Funcs(m.funcMap).ParseFS(m.config.TemplateFS, templatePath...)
+	tmpl, err := tmpl.ParseFS(m.config.TemplateFS, templatePath...)
 	if err != nil {
 		if templatePath == nil {
 			templatePath = []string{""}
@@ -333,10 +429,10 @@ func (m *Mailer) addAttachments(email *gomail.Msg, attachments []Attachment) err
 func (m *Mailer) sendWithRetry(email *gomail.Msg) error {
 	var lastErr error
 	for i := 0; i < m.config.RetryCount; i++ {
-		if err := m.client.DialAndSend(email); err != nil {
+		if err := m.transport.Send(context.Background(), email); err != nil {
 			lastErr = err
 			if i < m.config.RetryCount-1 {
-				time.Sleep(m.config.RetryDelay)
+				_ = sleep(context.Background(), m.clock, m.config.RetryDelay)
 				continue
 			}
 		} else {
@@ -3,12 +3,15 @@ package mail_test
 import (
 	"fmt"
 	"net/mail"
+	"sync"
 
 	gomail "github.com/wneessen/go-mail"
 )
 
 type mockSMTPClient struct {
+	mu           sync.Mutex
 	sentMessages []mockMessage
+	calls        int
 	shouldError  bool
 	errorMsg     string
 }
@@ -22,6 +25,7 @@ type mockMessage struct {
 	subject   string
 	bodyPlain string
 	bodyHTML  string
+	raw       *gomail.Msg
 }
 
 func newMockSMTPClient() *mockSMTPClient {
@@ -31,6 +35,10 @@ func newMockSMTPClient() *mockSMTPClient {
 }
 
 func (m *mockSMTPClient) DialAndSend(messages ...*gomail.Msg) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+
 	if m.shouldError {
 		if m.errorMsg != "" {
 			return fmt.Errorf("%s", m.errorMsg)
@@ -55,6 +63,7 @@ func (m *mockSMTPClient) DialAndSend(messages ...*gomail.Msg) error {
 			bcc:     msg.GetBcc(),
 			replyTo: replyTo,
 			subject: subject,
+			raw:     msg,
 		}
 
 		parts := msg.GetParts()
@@ -89,6 +98,20 @@ func (m *mockSMTPClient) LastMessage() (mockMessage, error) {
 	return m.sentMessages[len(m.sentMessages)-1], nil
 }
 
+// Calls returns the number of DialAndSend calls, one per SMTP connection
+func (m *mockSMTPClient) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// Sent returns a copy of the sent messages, safe to call while messages are sent concurrently
+func (m *mockSMTPClient) Sent() []mockMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mockMessage(nil), m.sentMessages...)
+}
+
 func (m *mockSMTPClient) SetError(err string) {
 	m.shouldError = true
 	m.errorMsg = err
@@ -15,6 +15,7 @@ import (
 	gomail "github.com/wneessen/go-mail"
 
 	"github.com/patrickward/hop/mail"
+	"github.com/patrickward/hop/templates"
 )
 
 //go:embed testdata/*
@@ -132,6 +133,61 @@ func TestMailer_Send(t *testing.T) {
 				assert.Equal(t, "custom@example.com", msg.from[0].Address)
 			},
 		},
+		{
+			name:   "per-message from address",
+			config: testConfig(),
+			buildMsg: func() (*mail.Message, error) {
+				return mail.NewMessage().
+					From("Billing <billing@example.com>").
+					To("recipient@example.com").
+					Template("testdata/basic.tmpl").
+					Build()
+			},
+			validate: func(t *testing.T, msg mockMessage) {
+				require.Len(t, msg.from, 1)
+				assert.Equal(t, "billing@example.com", msg.from[0].Address)
+				assert.Equal(t, "Billing", msg.from[0].Name)
+			},
+		},
+		{
+			name:   "custom headers and priority",
+			config: testConfig(),
+			buildMsg: func() (*mail.Message, error) {
+				return mail.NewMessage().
+					To("recipient@example.com").
+					Header("x-campaign-id", "spring-sale").
+					ListUnsubscribeOneClick("https://example.com/unsubscribe?token=abc").
+					Priority(mail.PriorityHigh).
+					Template("testdata/basic.tmpl").
+					Build()
+			},
+			validate: func(t *testing.T, msg mockMessage) {
+				assert.Equal(t, []string{"spring-sale"}, msg.raw.GetGenHeader("X-Campaign-Id"))
+				assert.Equal(t, []string{"<https://example.com/unsubscribe?token=abc>"}, msg.raw.GetGenHeader("List-Unsubscribe"))
+				assert.Equal(t, []string{"List-Unsubscribe=One-Click"}, msg.raw.GetGenHeader("List-Unsubscribe-Post"))
+				assert.Equal(t, []string{"1"}, msg.raw.GetGenHeader(gomail.HeaderXPriority))
+			},
+		},
+		{
+			name: "inlined css",
+			config: func() *mail.Config {
+				cfg := testConfig()
+				cfg.InlineCSS = true
+				return cfg
+			}(),
+			buildMsg: func() (*mail.Message, error) {
+				return mail.NewMessage().
+					To("recipient@example.com").
+					Template("testdata/with_styles.tmpl").
+					WithData(map[string]string{"name": "John"}).
+					Build()
+			},
+			validate: func(t *testing.T, msg mockMessage) {
+				assert.Contains(t, msg.bodyHTML, `<div class="header" style="color: blue">Hello John!</div>`)
+				assert.Contains(t, msg.bodyHTML, `<div class="content" style="margin: 20px 0">`)
+				assert.NotContains(t, msg.bodyHTML, "<style>")
+			},
+		},
 		{
 			name:   "with multiple recipients",
 			config: testConfig(),
@@ -463,3 +519,27 @@ func TestOpenFileAttachment(t *testing.T) {
 		})
 	}
 }
+
+func TestMailer_SendWithComponents(t *testing.T) {
+	components := templates.NewComponents().
+		Register("button", `<a class="btn" href="{{.url}}">Reset</a>`).
+		Override(templates.MediumEmail, "button", `<table role="presentation"><tr><td><a href="{{.url}}">Reset</a></td></tr></table>`)
+
+	cfg := testConfig()
+	cfg.Components = components
+
+	client := newMockSMTPClient()
+	mailer := mail.NewMailerWithClient(cfg, client)
+
+	msg, err := mail.NewMessage().
+		To("recipient@example.com").
+		Template("testdata/with_component.tmpl").
+		WithData(map[string]string{"url": "https://example.com/reset"}).
+		Build()
+	require.NoError(t, err)
+	require.NoError(t, mailer.Send(msg))
+
+	sent, err := client.LastMessage()
+	require.NoError(t, err)
+	assert.Contains(t, sent.bodyHTML, `<table role="presentation"><tr><td><a href="https://example.com/reset">Reset</a></td></tr></table>`)
+}
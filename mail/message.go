@@ -4,21 +4,58 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/mail"
+	"net/textproto"
+	"net/url"
 	"os"
 	"path"
+	"strings"
 
 	gomail "github.com/wneessen/go-mail"
 )
 
 // Message represents the content and recipients of an email message
 type Message struct {
-	To           StringList   // List of recipient email addresses
-	Cc           StringList   // List of CC email addresses
-	Bcc          StringList   // List of BCC email addresses
-	Templates    StringList   // List of template names to proccess
-	TemplateData any          // Data to be passed to the templates
-	Attachments  []Attachment // List of attachments
-	ReplyTo      string       // Reply-to email address
+	From         string            // From address, overriding the mailer's configured From address when set
+	To           StringList        // List of recipient email addresses
+	Cc           StringList        // List of CC email addresses
+	Bcc          StringList        // List of BCC email addresses
+	Templates    StringList        // List of template names to proccess
+	TemplateData any               // Data to be passed to the templates
+	Attachments  []Attachment      // List of attachments
+	ReplyTo      string            // Reply-to email address
+	Headers      map[string]string // Custom headers, keyed by canonical header name
+	Priority     Priority          // Priority of the message, normal by default
+}
+
+// Priority is the importance of a message, set as its Importance, Priority and X-Priority headers
+type Priority int
+
+const (
+	// PriorityNormal sets no priority headers
+	PriorityNormal Priority = iota
+	// PriorityLow marks bulk or non-urgent messages
+	PriorityLow
+	// PriorityHigh marks urgent messages, e.g. security alerts
+	PriorityHigh
+)
+
+// reservedHeaders are set by the mailer or by dedicated builder methods, and can't be set with Header
+var reservedHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Reply-To":                  true,
+	"Subject":                   true,
+	"Date":                      true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Importance":                true,
+	"Priority":                  true,
+	"X-Priority":                true,
+	"X-Msmail-Priority":         true,
 }
 
 // Attachment represents an email attachment
@@ -78,6 +115,59 @@ func (b *Builder) ReplyTo(address string) *Builder {
 	return b
 }
 
+// From sets the From address of the message, overriding the mailer's configured From address. It can
+// include a display name, e.g. "Billing <billing@example.com>".
+func (b *Builder) From(address string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.msg.From = address
+	return b
+}
+
+// Header sets a custom header, replacing any value set before. Headers set by the mailer, like Subject or
+// Content-Type, and the priority headers, set with Priority, are rejected at Build time.
+func (b *Builder) Header(key, value string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.msg.Headers == nil {
+		b.msg.Headers = make(map[string]string)
+	}
+	b.msg.Headers[textproto.CanonicalMIMEHeaderKey(key)] = value
+	return b
+}
+
+// Priority sets the priority of the message
+func (b *Builder) Priority(priority Priority) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.msg.Priority = priority
+	return b
+}
+
+// ListUnsubscribe sets the List-Unsubscribe header to the URLs recipients can unsubscribe with, https or
+// mailto, e.g. "https://example.com/unsubscribe?token=abc" or "mailto:unsubscribe@example.com". Mail
+// clients show an unsubscribe button for messages that have one, which bulk senders are required to set.
+func (b *Builder) ListUnsubscribe(urls ...string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	targets := make([]string, len(urls))
+	for i, u := range urls {
+		targets[i] = "<" + u + ">"
+	}
+	return b.Header("List-Unsubscribe", strings.Join(targets, ", "))
+}
+
+// ListUnsubscribeOneClick sets the List-Unsubscribe header to an https URL, and List-Unsubscribe-Post for
+// one-click unsubscribing (RFC 8058): the mail client POSTs "List-Unsubscribe=One-Click" to the URL, which
+// must unsubscribe the recipient without further confirmation.
+func (b *Builder) ListUnsubscribeOneClick(target string) *Builder {
+	return b.ListUnsubscribe(target).Header("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+}
+
 func (b *Builder) Template(names ...string) *Builder {
 	if b.err != nil {
 		return b
@@ -174,5 +264,45 @@ func (b *Builder) Build() (*Message, error) {
 	if len(b.msg.Templates) == 0 {
 		return nil, errors.New("email must have at least one template")
 	}
+	if b.msg.From != "" {
+		if _, err := mail.ParseAddress(b.msg.From); err != nil {
+			return nil, fmt.Errorf("invalid from address %q: %w", b.msg.From, err)
+		}
+	}
+	if b.msg.Priority < PriorityNormal || b.msg.Priority > PriorityHigh {
+		return nil, fmt.Errorf("invalid priority %d", b.msg.Priority)
+	}
+	for key, value := range b.msg.Headers {
+		if err := validateHeader(key, value); err != nil {
+			return nil, err
+		}
+	}
 	return b.msg, nil
 }
+
+// validateHeader checks a custom header's name and value
+func validateHeader(key, value string) error {
+	if key == "" || strings.IndexFunc(key, func(r rune) bool { return r <= ' ' || r > '~' || r == ':' }) >= 0 {
+		return fmt.Errorf("invalid header name %q", key)
+	}
+	if reservedHeaders[key] {
+		return fmt.Errorf("header %s can't be set as a custom header", key)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("header %s contains a line break", key)
+	}
+
+	if key == "List-Unsubscribe" {
+		for _, target := range strings.Split(value, ",") {
+			target = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(target), "<"), ">")
+			u, err := url.Parse(target)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "mailto") {
+				return fmt.Errorf("invalid List-Unsubscribe URL %q, must be https or mailto", target)
+			}
+		}
+	}
+	if key == "List-Unsubscribe-Post" && !strings.Contains(strings.ToLower(value), "one-click") {
+		return fmt.Errorf("invalid List-Unsubscribe-Post value %q", value)
+	}
+	return nil
+}
@@ -56,6 +56,65 @@ func TestMessageBuilder(t *testing.T) {
 				assert.Equal(t, "reply@example.com", msg.ReplyTo)
 			},
 		},
+		{
+			name: "message with from, headers and priority",
+			build: func(b *mail.Builder) {
+				b.From("Billing <billing@example.com>").
+					To("user@example.com").
+					Header("x-campaign-id", "spring-sale").
+					ListUnsubscribe("https://example.com/unsubscribe", "mailto:unsubscribe@example.com").
+					Priority(mail.PriorityLow).
+					Template("notify.tmpl")
+			},
+			validate: func(t *testing.T, msg *mail.Message) {
+				assert.Equal(t, "Billing <billing@example.com>", msg.From)
+				assert.Equal(t, map[string]string{
+					"X-Campaign-Id":    "spring-sale",
+					"List-Unsubscribe": "<https://example.com/unsubscribe>, <mailto:unsubscribe@example.com>",
+				}, msg.Headers)
+				assert.Equal(t, mail.PriorityLow, msg.Priority)
+			},
+		},
+		{
+			name: "invalid from address",
+			build: func(b *mail.Builder) {
+				b.From("not an address").To("user@example.com").Template("notify.tmpl")
+			},
+			wantErr:   true,
+			errString: "invalid from address",
+		},
+		{
+			name: "reserved header",
+			build: func(b *mail.Builder) {
+				b.To("user@example.com").Header("subject", "Hi").Template("notify.tmpl")
+			},
+			wantErr:   true,
+			errString: "header Subject can't be set as a custom header",
+		},
+		{
+			name: "header injection",
+			build: func(b *mail.Builder) {
+				b.To("user@example.com").Header("X-Tag", "a\r\nBcc: victim@example.com").Template("notify.tmpl")
+			},
+			wantErr:   true,
+			errString: "contains a line break",
+		},
+		{
+			name: "invalid header name",
+			build: func(b *mail.Builder) {
+				b.To("user@example.com").Header("X Tag", "a").Template("notify.tmpl")
+			},
+			wantErr:   true,
+			errString: "invalid header name",
+		},
+		{
+			name: "insecure unsubscribe url",
+			build: func(b *mail.Builder) {
+				b.To("user@example.com").ListUnsubscribe("http://example.com/unsubscribe").Template("notify.tmpl")
+			},
+			wantErr:   true,
+			errString: "must be https or mailto",
+		},
 		{
 			name: "missing recipient",
 			build: func(b *mail.Builder) {
@@ -0,0 +1,515 @@
+package processors
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// CSSInliner implements HTMLProcessor by moving the rules of <style> elements into the style attributes of
+// the elements they match, as many email clients ignore <style> elements. It has no dependencies, unlike
+// PremailerProcessor, and supports the selectors emails commonly use: type, class, ID and attribute
+// selectors, compounds of them, and the descendant and child combinators. The cascade follows CSS:
+// !important, then specificity, then source order, with existing style attributes winning over rules.
+//
+// Rules it can't inline, like @media queries and pseudo-classes such as :hover, are kept in a <style>
+// element, for the clients that support them.
+type CSSInliner struct{}
+
+// NewCSSInliner creates a new CSSInliner
+func NewCSSInliner() *CSSInliner {
+	return &CSSInliner{}
+}
+
+// cssDeclaration is a property of a rule or style attribute
+type cssDeclaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+// cssRule is an inlinable rule with a single selector
+type cssRule struct {
+	selector     []compoundSelector
+	specificity  [4]int // inline, IDs, classes and attributes, types
+	order        int
+	declarations []cssDeclaration
+}
+
+// compoundSelector is a sequence of simple selectors, e.g. "td.header[align=left]", and its combinator with
+// the compound before it: ' ' for descendant, '>' for child
+type compoundSelector struct {
+	combinator byte
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrSelector
+}
+
+// attrSelector is an [attr] or [attr=value] selector
+type attrSelector struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// Process inlines the CSS of the <style> elements of an HTML document or fragment
+func (p *CSSInliner) Process(doc string) (string, error) {
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		return "", err
+	}
+
+	var styles []*html.Node
+	walkElements(root, func(n *html.Node) {
+		if n.DataAtom == atom.Style {
+			styles = append(styles, n)
+		}
+	})
+	if len(styles) == 0 {
+		return doc, nil
+	}
+
+	var rules []cssRule
+	var leftover []string
+	for _, style := range styles {
+		var css strings.Builder
+		for c := style.FirstChild; c != nil; c = c.NextSibling {
+			css.WriteString(c.Data)
+		}
+		r, l := parseStylesheet(css.String(), len(rules))
+		rules = append(rules, r...)
+		leftover = append(leftover, l...)
+	}
+
+	// Keep the rules that can't be inlined in the first <style> element, and remove the others
+	for i, style := range styles {
+		if i == 0 && len(leftover) > 0 {
+			for c := style.FirstChild; c != nil; c = style.FirstChild {
+				style.RemoveChild(c)
+			}
+			style.AppendChild(&html.Node{Type: html.TextNode, Data: strings.Join(leftover, "\n")})
+			continue
+		}
+		style.Parent.RemoveChild(style)
+	}
+
+	// Only the body is styled, not the title or other elements of the head
+	if body := findElement(root, atom.Body); body != nil {
+		applyRules(body, rules)
+		walkElements(body, func(n *html.Node) {
+			applyRules(n, rules)
+		})
+	}
+
+	var buf bytes.Buffer
+	if strings.Contains(strings.ToLower(doc), "<html") {
+		if err := html.Render(&buf, root); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	// Render fragments without the html, head and body elements added by the parser
+	for _, container := range []atom.Atom{atom.Head, atom.Body} {
+		if n := findElement(root, container); n != nil {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if err := html.Render(&buf, c); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	return buf.String(), nil
+}
+
+// applyRules sets the style attribute of an element from the rules matching it and its existing style
+func applyRules(n *html.Node, rules []cssRule) {
+	var matched []cssRule
+	for _, rule := range rules {
+		if matchSelector(n, rule.selector, len(rule.selector)-1) {
+			matched = append(matched, rule)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	styleIndex := -1
+	for i, attr := range n.Attr {
+		if attr.Key == "style" {
+			styleIndex = i
+			matched = append(matched, cssRule{
+				specificity:  [4]int{1, 0, 0, 0},
+				order:        len(rules),
+				declarations: parseDeclarations(attr.Val),
+			})
+		}
+	}
+
+	type weighted struct {
+		cssDeclaration
+		specificity [4]int
+		order       int
+	}
+	var declarations []weighted
+	for _, rule := range matched {
+		for _, d := range rule.declarations {
+			declarations = append(declarations, weighted{d, rule.specificity, rule.order})
+		}
+	}
+	sort.SliceStable(declarations, func(i, j int) bool {
+		a, b := declarations[i], declarations[j]
+		if a.important != b.important {
+			return !a.important
+		}
+		if a.specificity != b.specificity {
+			for k := range a.specificity {
+				if a.specificity[k] != b.specificity[k] {
+					return a.specificity[k] < b.specificity[k]
+				}
+			}
+		}
+		return a.order < b.order
+	})
+
+	// Later declarations win, keeping the position of the first declaration of each property
+	var properties []string
+	values := make(map[string]string)
+	for _, d := range declarations {
+		if _, ok := values[d.property]; !ok {
+			properties = append(properties, d.property)
+		}
+		values[d.property] = d.value
+	}
+	parts := make([]string, len(properties))
+	for i, property := range properties {
+		parts[i] = property + ": " + values[property]
+	}
+	style := strings.Join(parts, "; ")
+
+	if styleIndex >= 0 {
+		n.Attr[styleIndex].Val = style
+	} else {
+		n.Attr = append(n.Attr, html.Attribute{Key: "style", Val: style})
+	}
+}
+
+// parseStylesheet splits CSS into inlinable rules, numbered from order, and the text of the rules that
+// can't be inlined
+func parseStylesheet(css string, order int) ([]cssRule, []string) {
+	css = cssComment.ReplaceAllString(css, "")
+
+	var rules []cssRule
+	var leftover []string
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			break
+		}
+
+		// At-rules, e.g. @media blocks or @import statements, are kept as is
+		if css[0] == '@' {
+			brace, semicolon := strings.IndexByte(css, '{'), strings.IndexByte(css, ';')
+			if semicolon >= 0 && (brace < 0 || semicolon < brace) {
+				leftover = append(leftover, css[:semicolon+1])
+				css = css[semicolon+1:]
+				continue
+			}
+			if brace < 0 {
+				break
+			}
+			end := matchingBrace(css, brace)
+			leftover = append(leftover, strings.TrimSpace(css[:end+1]))
+			css = css[end+1:]
+			continue
+		}
+
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			break
+		}
+		end := matchingBrace(css, open)
+		selectors, block := css[:open], css[open+1:end]
+		css = css[end+1:]
+
+		declarations := parseDeclarations(block)
+		for _, selector := range splitTopLevel(selectors, ',') {
+			selector = strings.TrimSpace(selector)
+			compounds, specificity, ok := parseSelector(selector)
+			if !ok {
+				leftover = append(leftover, selector+" { "+strings.TrimSpace(block)+" }")
+				continue
+			}
+			rules = append(rules, cssRule{
+				selector:     compounds,
+				specificity:  specificity,
+				order:        order,
+				declarations: declarations,
+			})
+			order++
+		}
+	}
+	return rules, leftover
+}
+
+// parseDeclarations parses the declarations of a rule block or style attribute
+func parseDeclarations(block string) []cssDeclaration {
+	var declarations []cssDeclaration
+	for _, decl := range splitTopLevel(block, ';') {
+		property, value, ok := strings.Cut(decl, ":")
+		property, value = strings.ToLower(strings.TrimSpace(property)), strings.TrimSpace(value)
+		if !ok || property == "" || value == "" {
+			continue
+		}
+
+		d := cssDeclaration{property: property, value: value}
+		if i := strings.LastIndex(value, "!"); i >= 0 && strings.EqualFold(strings.TrimSpace(value[i+1:]), "important") {
+			d.value, d.important = strings.TrimSpace(value[:i]), true
+		}
+		declarations = append(declarations, d)
+	}
+	return declarations
+}
+
+// parseSelector parses a complex selector, reporting false for the selectors it doesn't support
+func parseSelector(selector string) ([]compoundSelector, [4]int, bool) {
+	var specificity [4]int
+	var compounds []compoundSelector
+
+	combinator := byte(' ')
+	for _, field := range strings.Fields(strings.ReplaceAll(selector, ">", " > ")) {
+		if field == ">" {
+			if len(compounds) == 0 {
+				return nil, specificity, false
+			}
+			combinator = '>'
+			continue
+		}
+
+		compound, ok := parseCompound(field)
+		if !ok {
+			return nil, specificity, false
+		}
+		compound.combinator = combinator
+		combinator = ' '
+
+		if compound.id != "" {
+			specificity[1]++
+		}
+		specificity[2] += len(compound.classes) + len(compound.attrs)
+		if compound.tag != "" {
+			specificity[3]++
+		}
+		compounds = append(compounds, compound)
+	}
+	if len(compounds) == 0 || combinator == '>' {
+		return nil, specificity, false
+	}
+	return compounds, specificity, true
+}
+
+// parseCompound parses a compound selector, e.g. "td.header#top[align=left]"
+func parseCompound(s string) (compoundSelector, bool) {
+	var c compoundSelector
+
+	i := 0
+	if s[0] == '*' {
+		i = 1
+	} else {
+		c.tag, i = readIdent(s, 0)
+		c.tag = strings.ToLower(c.tag)
+	}
+
+	for i < len(s) {
+		switch s[i] {
+		case '#':
+			var id string
+			id, i = readIdent(s, i+1)
+			if id == "" || c.id != "" {
+				return c, false
+			}
+			c.id = id
+		case '.':
+			var class string
+			class, i = readIdent(s, i+1)
+			if class == "" {
+				return c, false
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return c, false
+			}
+			name, value, hasValue := strings.Cut(s[i+1:i+end], "=")
+			if strings.ContainsAny(name, "~|^$*") {
+				return c, false
+			}
+			c.attrs = append(c.attrs, attrSelector{
+				name:     strings.ToLower(strings.TrimSpace(name)),
+				value:    strings.Trim(strings.TrimSpace(value), `"'`),
+				hasValue: hasValue,
+			})
+			i += end + 1
+		default:
+			// Pseudo-classes, pseudo-elements and the sibling combinators can't be inlined
+			return c, false
+		}
+	}
+	return c, true
+}
+
+// readIdent reads a CSS identifier starting at i, returning it and the index after it
+func readIdent(s string, i int) (string, int) {
+	start := i
+	for i < len(s) {
+		ch := s[i]
+		if ch == '-' || ch == '_' || ch >= 0x80 || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') {
+			i++
+			continue
+		}
+		break
+	}
+	return s[start:i], i
+}
+
+// matchSelector reports whether an element matches the compounds up to i, checking its ancestors for the
+// compounds before i
+func matchSelector(n *html.Node, compounds []compoundSelector, i int) bool {
+	if !matchCompound(n, compounds[i]) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	if compounds[i].combinator == '>' {
+		parent := n.Parent
+		return parent != nil && parent.Type == html.ElementNode && matchSelector(parent, compounds, i-1)
+	}
+	for ancestor := n.Parent; ancestor != nil && ancestor.Type == html.ElementNode; ancestor = ancestor.Parent {
+		if matchSelector(ancestor, compounds, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCompound reports whether an element matches every simple selector of a compound
+func matchCompound(n *html.Node, c compoundSelector) bool {
+	if c.tag != "" && n.Data != c.tag {
+		return false
+	}
+	if c.id != "" && attrValue(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(attrValue(n, "class"))
+		for _, class := range c.classes {
+			found := false
+			for _, have := range classes {
+				if have == class {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, attr := range c.attrs {
+		value, ok := lookupAttr(n, attr.name)
+		if !ok || (attr.hasValue && value != attr.value) {
+			return false
+		}
+	}
+	return true
+}
+
+func attrValue(n *html.Node, key string) string {
+	value, _ := lookupAttr(n, key)
+	return value
+}
+
+func lookupAttr(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// walkElements calls fn for each element below n, in document order
+func walkElements(n *html.Node, fn func(*html.Node)) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode {
+			fn(c)
+		}
+		walkElements(c, fn)
+		c = next
+	}
+}
+
+// findElement returns the first element of a type below n
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walkElements(n, func(c *html.Node) {
+		if found == nil && c.DataAtom == a {
+			found = c
+		}
+	})
+	return found
+}
+
+// matchingBrace returns the index of the brace closing the one at open, or the last index when unbalanced
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s) - 1
+}
+
+// splitTopLevel splits s on sep, ignoring separators within parentheses, brackets and quotes
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case ch == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
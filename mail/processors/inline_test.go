@@ -0,0 +1,83 @@
+package processors_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/mail/processors"
+)
+
+func TestCSSInliner_Process(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "inlines type, class and id selectors",
+			html: `<html><head><style>p { color: red; } .note { font-size: 12px; } #top { margin: 0; }</style></head>` +
+				`<body><p class="note" id="top">Hi</p></body></html>`,
+			contains: []string{`<p class="note" id="top" style="color: red; font-size: 12px; margin: 0">Hi</p>`},
+			excludes: []string{"<style>"},
+		},
+		{
+			name: "applies specificity, then source order",
+			html: `<html><head><style>.a { color: blue; } p { color: red; } p { color: green; }</style></head>` +
+				`<body><p class="a">A</p><p>B</p></body></html>`,
+			contains: []string{`<p class="a" style="color: blue">A</p>`, `<p style="color: green">B</p>`},
+		},
+		{
+			name: "keeps existing style attributes over rules, except important ones",
+			html: `<html><head><style>p { color: red; margin: 0 !important; padding: 1px; }</style></head>` +
+				`<body><p style="color: black; margin: 5px">A</p></body></html>`,
+			contains: []string{`style="color: black; padding: 1px; margin: 0"`},
+		},
+		{
+			name: "matches descendant, child and attribute selectors",
+			html: `<html><head><style>table td { padding: 4px; } div > span { color: red; } td[align=right] { font-weight: bold; }</style></head>` +
+				`<body><table><tr><td align="right">1</td></tr></table><div><p><span>x</span></p><span>y</span></div></body></html>`,
+			contains: []string{
+				`<td align="right" style="padding: 4px; font-weight: bold">1</td>`,
+				`<p><span>x</span></p><span style="color: red">y</span>`,
+			},
+		},
+		{
+			name: "keeps media queries and pseudo-classes in a style element",
+			html: `<html><head><style>a { color: blue; } a:hover { color: red; } @media (max-width: 600px) { .col { width: 100%; } }</style></head>` +
+				`<body><a href="#">link</a></body></html>`,
+			contains: []string{
+				`<a href="#" style="color: blue">link</a>`,
+				`a:hover { color: red; }`,
+				`@media (max-width: 600px) { .col { width: 100%; } }`,
+			},
+		},
+		{
+			name:     "renders fragments without added elements",
+			html:     `<style>b { color: red; }</style><b>bold</b>`,
+			contains: []string{`<b style="color: red">bold</b>`},
+			excludes: []string{"<html>", "<body>"},
+		},
+		{
+			name:     "leaves documents without style elements unchanged",
+			html:     `<p style="color: red">unchanged</p>`,
+			contains: []string{`<p style="color: red">unchanged</p>`},
+		},
+	}
+
+	inliner := processors.NewCSSInliner()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := inliner.Process(tt.html)
+			require.NoError(t, err)
+			for _, s := range tt.contains {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tt.excludes {
+				assert.NotContains(t, out, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	gomail "github.com/wneessen/go-mail"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+const (
+	// EventMailSent is emitted with a QueueEvent payload when a queued message was sent
+	EventMailSent = "mail.sent"
+	// EventMailFailed is emitted with a QueueEvent payload when a queued message failed every attempt
+	EventMailFailed = "mail.failed"
+)
+
+// ErrQueueStopped is returned by SendAsync once the queue is stopped
+var ErrQueueStopped = errors.New("mail queue is stopped")
+
+// QueueEvent is the payload of EventMailSent and EventMailFailed
+type QueueEvent struct {
+	ID        string
+	To        []string
+	Templates []string
+	Error     string // The last send error, for EventMailFailed
+}
+
+// QueueOptions configures a Queue
+type QueueOptions struct {
+	// Workers is the number of messages sent concurrently. Default is 2.
+	Workers int
+	// Buffer is the number of messages waiting for a worker before SendAsync blocks. Default is 100.
+	Buffer int
+	// Spool persists the messages until they are sent. Default is a MemorySpool, which doesn't survive a
+	// restart; use a SQLiteSpool for crash safety.
+	Spool Spool
+	// Events, when set, receives EventMailSent and EventMailFailed
+	Events *dispatch.Dispatcher
+	// Logger receives send failures. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Queue sends messages in the background with a pool of workers, using the mailer and its retries. Messages
+// are saved to a spool before SendAsync returns, and removed once sent or failed, so the messages of a
+// crashed process are sent when the queue starts again. A message can be sent twice if the process crashes
+// between sending and removing it.
+//
+// Queue implements hop.Module: register it with the app, or call Start and Stop.
+type Queue struct {
+	mailer *Mailer
+	opts   QueueOptions
+	ch     chan *queuedMessage
+
+	mu     sync.Mutex
+	queued map[string]struct{}
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewQueue creates a new Queue sending with the mailer
+func NewQueue(mailer *Mailer, optsFunc func(opts *QueueOptions)) *Queue {
+	opts := QueueOptions{
+		Workers: 2,
+		Buffer:  100,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 100
+	}
+	if opts.Spool == nil {
+		opts.Spool = NewMemorySpool()
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	return &Queue{
+		mailer: mailer,
+		opts:   opts,
+		ch:     make(chan *queuedMessage, opts.Buffer),
+		queued: make(map[string]struct{}),
+		stop:   make(chan struct{}),
+	}
+}
+
+// SendAsync queues a message and returns its ID, which identifies it in the queue events. The message is
+// rendered first, so template errors are returned right away. Its template data is stored as JSON, and
+// rendered from the decoded JSON when sent: structs become maps and numbers float64. Attachments are read
+// into memory.
+//
+// Messages queued before Start wait for the workers. SendAsync blocks while the buffer is full, until ctx is
+// done, and returns ErrQueueStopped once the queue is stopped.
+func (q *Queue) SendAsync(ctx context.Context, msg *Message) (string, error) {
+	q.mu.Lock()
+	stop := q.stop
+	q.mu.Unlock()
+	select {
+	case <-stop:
+		return "", ErrQueueStopped
+	default:
+	}
+
+	data, err := encodeMessage(newQueueID(), msg)
+	if err != nil {
+		return "", err
+	}
+	qm, err := decodeMessage(data)
+	if err != nil {
+		return "", err
+	}
+	if _, err := q.mailer.build(qm.message()); err != nil {
+		return "", err
+	}
+
+	if err := q.opts.Spool.Save(ctx, qm.ID, data); err != nil {
+		return "", fmt.Errorf("failed to spool message: %w", err)
+	}
+
+	if !q.enqueue(ctx, stop, qm) {
+		if err := q.opts.Spool.Remove(context.WithoutCancel(ctx), qm.ID); err != nil {
+			q.opts.Logger.Error("Failed to remove unqueued message from spool", slog.String("id", qm.ID), slog.String("error", err.Error()))
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", ErrQueueStopped
+	}
+
+	return qm.ID, nil
+}
+
+// enqueue hands a message to the workers, reporting false when ctx is done or the queue stopped first
+func (q *Queue) enqueue(ctx context.Context, stop chan struct{}, qm *queuedMessage) bool {
+	q.mu.Lock()
+	if _, ok := q.queued[qm.ID]; ok {
+		q.mu.Unlock()
+		return true
+	}
+	q.queued[qm.ID] = struct{}{}
+	q.mu.Unlock()
+
+	select {
+	case q.ch <- qm:
+		return true
+	case <-ctx.Done():
+	case <-stop:
+	}
+
+	q.mu.Lock()
+	delete(q.queued, qm.ID)
+	q.mu.Unlock()
+	return false
+}
+
+// ID implements hop.Module
+func (q *Queue) ID() string {
+	return "hop.mail.queue"
+}
+
+// Init implements hop.Module
+func (q *Queue) Init() error {
+	return nil
+}
+
+// Start starts the workers, and queues the messages left in the spool by a previous run
+func (q *Queue) Start(ctx context.Context) error {
+	pending, err := q.opts.Spool.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read mail spool: %w", err)
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	q.mu.Lock()
+	select {
+	case <-q.stop:
+		q.stop = make(chan struct{})
+	default:
+	}
+	stop := q.stop
+	q.mu.Unlock()
+
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			q.work(ctx, stop)
+		}()
+	}
+
+	// Queue the spooled messages in the background, as they may not fit in the buffer
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		for _, sm := range pending {
+			qm, err := decodeMessage(sm.Data)
+			if err != nil {
+				q.opts.Logger.Error("Failed to decode spooled message", slog.String("id", sm.ID), slog.String("error", err.Error()))
+				continue
+			}
+			if !q.enqueue(ctx, stop, qm) {
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the workers once they finish the messages being sent. Queued messages that weren't sent stay
+// in the spool, and are sent when the queue starts again.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	select {
+	case <-q.stop:
+	default:
+		close(q.stop)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work sends queued messages until the queue stops
+func (q *Queue) work(ctx context.Context, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case qm := <-q.ch:
+			q.deliver(ctx, qm)
+		}
+	}
+}
+
+// deliver sends a queued message, removes it from the spool and reports the outcome
+func (q *Queue) deliver(ctx context.Context, qm *queuedMessage) {
+	err := q.mailer.Send(qm.message())
+
+	if rmErr := q.opts.Spool.Remove(ctx, qm.ID); rmErr != nil {
+		q.opts.Logger.Error("Failed to remove message from spool", slog.String("id", qm.ID), slog.String("error", rmErr.Error()))
+	}
+
+	q.mu.Lock()
+	delete(q.queued, qm.ID)
+	q.mu.Unlock()
+
+	event := QueueEvent{ID: qm.ID, To: qm.To, Templates: qm.Templates}
+	signature := EventMailSent
+	if err != nil {
+		q.opts.Logger.Error("Failed to send queued email", slog.String("id", qm.ID), slog.String("error", err.Error()))
+		event.Error = err.Error()
+		signature = EventMailFailed
+	}
+
+	if q.opts.Events != nil {
+		if err := q.opts.Events.Emit(ctx, signature, event); err != nil {
+			q.opts.Logger.Error("Failed to emit mail event", slog.String("id", qm.ID), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// queuedMessage is the spooled form of a Message
+type queuedMessage struct {
+	ID           string             `json:"id"`
+	From         string             `json:"from,omitempty"`
+	To           []string           `json:"to"`
+	Cc           []string           `json:"cc,omitempty"`
+	Bcc          []string           `json:"bcc,omitempty"`
+	ReplyTo      string             `json:"reply_to,omitempty"`
+	Templates    []string           `json:"templates"`
+	TemplateData json.RawMessage    `json:"template_data,omitempty"`
+	Attachments  []queuedAttachment `json:"attachments,omitempty"`
+	Headers      map[string]string  `json:"headers,omitempty"`
+	Priority     Priority           `json:"priority,omitempty"`
+}
+
+// queuedAttachment is the spooled form of an Attachment
+type queuedAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Data        []byte `json:"data"`
+}
+
+// encodeMessage encodes a message for the spool, reading its attachments
+func encodeMessage(id string, msg *Message) ([]byte, error) {
+	qm := queuedMessage{
+		ID:        id,
+		From:      msg.From,
+		To:        msg.To,
+		Cc:        msg.Cc,
+		Bcc:       msg.Bcc,
+		ReplyTo:   msg.ReplyTo,
+		Templates: msg.Templates,
+		Headers:   msg.Headers,
+		Priority:  msg.Priority,
+	}
+
+	if msg.TemplateData != nil {
+		data, err := json.Marshal(msg.TemplateData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode template data: %w", err)
+		}
+		qm.TemplateData = data
+	}
+
+	for _, att := range msg.Attachments {
+		if att.Data == nil {
+			return nil, fmt.Errorf("nil reader for attachment %s", att.Filename)
+		}
+		data, err := io.ReadAll(att.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment %s: %w", att.Filename, err)
+		}
+		qm.Attachments = append(qm.Attachments, queuedAttachment{
+			Filename:    att.Filename,
+			ContentType: string(att.ContentType),
+			Data:        data,
+		})
+	}
+
+	return json.Marshal(qm)
+}
+
+// decodeMessage decodes a spooled message
+func decodeMessage(data []byte) (*queuedMessage, error) {
+	var qm queuedMessage
+	if err := json.Unmarshal(data, &qm); err != nil {
+		return nil, fmt.Errorf("failed to decode queued message: %w", err)
+	}
+	return &qm, nil
+}
+
+// message rebuilds the Message to send. Attachments get fresh readers, so it can be sent more than once.
+func (qm *queuedMessage) message() *Message {
+	msg := &Message{
+		From:      qm.From,
+		To:        qm.To,
+		Cc:        qm.Cc,
+		Bcc:       qm.Bcc,
+		ReplyTo:   qm.ReplyTo,
+		Templates: qm.Templates,
+		Headers:   qm.Headers,
+		Priority:  qm.Priority,
+	}
+
+	if len(qm.TemplateData) > 0 {
+		var data map[string]any
+		if err := json.Unmarshal(qm.TemplateData, &data); err == nil {
+			msg.TemplateData = data
+		} else {
+			var value any
+			_ = json.Unmarshal(qm.TemplateData, &value)
+			msg.TemplateData = value
+		}
+	}
+
+	for _, att := range qm.Attachments {
+		msg.Attachments = append(msg.Attachments, Attachment{
+			Filename:    att.Filename,
+			Data:        bytes.NewReader(att.Data),
+			ContentType: gomail.ContentType(att.ContentType),
+		})
+	}
+
+	return msg
+}
+
+// newQueueID returns a random identifier for a queued message
+func newQueueID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,182 @@
+package mail_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Import the SQLite driver
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/mail"
+)
+
+func newSQLiteSpool(t *testing.T) *mail.SQLiteSpool {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "mail.db")+"?_busy_timeout=5000")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	spool := mail.NewSQLiteSpool(db)
+	require.NoError(t, spool.Migrate(context.Background()))
+	return spool
+}
+
+func basicMessage(t *testing.T) *mail.Message {
+	t.Helper()
+
+	msg, err := mail.NewMessage().
+		To("recipient@example.com").
+		Template("testdata/basic.tmpl").
+		WithData(map[string]string{"name": "John"}).
+		Build()
+	require.NoError(t, err)
+	return msg
+}
+
+// collectEvents records the mail events of a dispatcher
+func collectEvents(t *testing.T) (*dispatch.Dispatcher, chan dispatch.Event) {
+	t.Helper()
+
+	events := dispatch.NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	received := make(chan dispatch.Event, 10)
+	events.On("mail.*", func(_ context.Context, event dispatch.Event) {
+		received <- event
+	})
+	return events, received
+}
+
+func TestQueue_SendAsync(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sends in the background and emits events", func(t *testing.T) {
+		client := newMockSMTPClient()
+		events, received := collectEvents(t)
+		q := mail.NewQueue(mail.NewMailerWithClient(testConfig(), client), func(opts *mail.QueueOptions) {
+			opts.Events = events
+		})
+		require.NoError(t, q.Start(ctx))
+		t.Cleanup(func() { _ = q.Stop(ctx) })
+
+		id, err := q.SendAsync(ctx, basicMessage(t))
+		require.NoError(t, err)
+		assert.NotEmpty(t, id)
+
+		select {
+		case event := <-received:
+			assert.Equal(t, mail.EventMailSent, event.Signature)
+			payload := event.Payload.(mail.QueueEvent)
+			assert.Equal(t, id, payload.ID)
+			assert.Equal(t, []string{"recipient@example.com"}, payload.To)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no mail event")
+		}
+
+		sent := client.Sent()
+		require.Len(t, sent, 1)
+		assert.Equal(t, "Test Email", sent[0].subject)
+		assert.Contains(t, sent[0].bodyPlain, "Hello John!")
+	})
+
+	t.Run("emits failures", func(t *testing.T) {
+		client := newMockSMTPClient()
+		client.SetError("connection refused")
+		events, received := collectEvents(t)
+		spool := mail.NewMemorySpool()
+		q := mail.NewQueue(mail.NewMailerWithClient(testConfig(), client), func(opts *mail.QueueOptions) {
+			opts.Events = events
+			opts.Spool = spool
+			opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		})
+		require.NoError(t, q.Start(ctx))
+		t.Cleanup(func() { _ = q.Stop(ctx) })
+
+		id, err := q.SendAsync(ctx, basicMessage(t))
+		require.NoError(t, err)
+
+		select {
+		case event := <-received:
+			assert.Equal(t, mail.EventMailFailed, event.Signature)
+			payload := event.Payload.(mail.QueueEvent)
+			assert.Equal(t, id, payload.ID)
+			assert.Contains(t, payload.Error, "connection refused")
+		case <-time.After(5 * time.Second):
+			t.Fatal("no mail event")
+		}
+
+		pending, err := spool.Pending(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("returns template errors right away", func(t *testing.T) {
+		q := mail.NewQueue(mail.NewMailerWithClient(testConfig(), newMockSMTPClient()), nil)
+
+		msg, err := mail.NewMessage().
+			To("recipient@example.com").
+			Template("testdata/missing_subject.tmpl").
+			Build()
+		require.NoError(t, err)
+
+		_, err = q.SendAsync(ctx, msg)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects messages once stopped", func(t *testing.T) {
+		q := mail.NewQueue(mail.NewMailerWithClient(testConfig(), newMockSMTPClient()), nil)
+		require.NoError(t, q.Start(ctx))
+		require.NoError(t, q.Stop(ctx))
+
+		_, err := q.SendAsync(ctx, basicMessage(t))
+		assert.ErrorIs(t, err, mail.ErrQueueStopped)
+	})
+}
+
+func TestQueue_SpoolSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	spool := newSQLiteSpool(t)
+
+	// The first queue is never started, like a process that crashed before sending
+	first := mail.NewQueue(mail.NewMailerWithClient(testConfig(), newMockSMTPClient()), func(opts *mail.QueueOptions) {
+		opts.Spool = spool
+	})
+	id, err := first.SendAsync(ctx, basicMessage(t))
+	require.NoError(t, err)
+	require.NoError(t, first.Stop(ctx))
+
+	pending, err := spool.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, id, pending[0].ID)
+
+	client := newMockSMTPClient()
+	events, received := collectEvents(t)
+	second := mail.NewQueue(mail.NewMailerWithClient(testConfig(), client), func(opts *mail.QueueOptions) {
+		opts.Spool = spool
+		opts.Events = events
+	})
+	require.NoError(t, second.Start(ctx))
+	t.Cleanup(func() { _ = second.Stop(ctx) })
+
+	select {
+	case event := <-received:
+		assert.Equal(t, mail.EventMailSent, event.Signature)
+		assert.Equal(t, id, event.Payload.(mail.QueueEvent).ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("spooled message was not sent")
+	}
+
+	require.Len(t, client.Sent(), 1)
+	assert.Contains(t, client.Sent()[0].bodyHTML, "Hello John!")
+
+	pending, err = spool.Pending(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
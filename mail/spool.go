@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Spool persists the messages of a Queue until they are sent, so they survive a crash or restart
+type Spool interface {
+	// Save stores an encoded message under its ID
+	Save(ctx context.Context, id string, data []byte) error
+	// Remove deletes a message once it was sent or failed for good. Removing an unknown ID is not an error.
+	Remove(ctx context.Context, id string) error
+	// Pending returns the stored messages, oldest first
+	Pending(ctx context.Context) ([]SpooledMessage, error)
+}
+
+// SpooledMessage is an encoded message stored in a Spool
+type SpooledMessage struct {
+	ID   string
+	Data []byte
+}
+
+// MemorySpool is a Spool that keeps messages in memory. Messages are lost when the process exits, so it
+// only suits tests and development.
+type MemorySpool struct {
+	mu       sync.Mutex
+	messages []SpooledMessage
+}
+
+// NewMemorySpool creates a new, empty MemorySpool
+func NewMemorySpool() *MemorySpool {
+	return &MemorySpool{}
+}
+
+// Save implements Spool
+func (s *MemorySpool) Save(_ context.Context, id string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, SpooledMessage{ID: id, Data: append([]byte(nil), data...)})
+	return nil
+}
+
+// Remove implements Spool
+func (s *MemorySpool) Remove(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, msg := range s.messages {
+		if msg.ID == id {
+			s.messages = append(s.messages[:i], s.messages[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Pending implements Spool
+func (s *MemorySpool) Pending(_ context.Context) ([]SpooledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SpooledMessage(nil), s.messages...), nil
+}
+
+// SQLiteSpool is a Spool backed by a SQLite database. Each message is a row in the mail_spool table.
+type SQLiteSpool struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewSQLiteSpool creates a new SQLiteSpool using the given database
+func NewSQLiteSpool(db *sql.DB) *SQLiteSpool {
+	return &SQLiteSpool{db: db, now: time.Now}
+}
+
+// Migrate creates the mail_spool table if it does not exist
+func (s *SQLiteSpool) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS mail_spool (
+		id TEXT PRIMARY KEY,
+		data BLOB NOT NULL,
+		created_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating mail_spool table: %w", err)
+	}
+	return nil
+}
+
+// Save implements Spool
+func (s *SQLiteSpool) Save(ctx context.Context, id string, data []byte) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO mail_spool (id, data, created_at) VALUES (?, ?, ?)`,
+		id, data, s.now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("saving message %s: %w", id, err)
+	}
+	return nil
+}
+
+// Remove implements Spool
+func (s *SQLiteSpool) Remove(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM mail_spool WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("removing message %s: %w", id, err)
+	}
+	return nil
+}
+
+// Pending implements Spool
+func (s *SQLiteSpool) Pending(ctx context.Context) ([]SpooledMessage, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, data FROM mail_spool ORDER BY created_at, rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("listing spooled messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var messages []SpooledMessage
+	for rows.Next() {
+		var msg SpooledMessage
+		if err := rows.Scan(&msg.ID, &msg.Data); err != nil {
+			return nil, fmt.Errorf("scanning spooled message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
@@ -0,0 +1,182 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	gomail "github.com/wneessen/go-mail"
+
+	"github.com/patrickward/hop/clock"
+)
+
+// BatchTransport is implemented by transports that deliver several emails at once, like SMTPTransport,
+// which sends them over one connection
+type BatchTransport interface {
+	Transport
+	SendBatch(ctx context.Context, emails []*gomail.Msg) error
+}
+
+// SendBatch implements BatchTransport, sending the emails in one SMTP session
+func (t *SMTPTransport) SendBatch(_ context.Context, emails []*gomail.Msg) error {
+	return t.client.DialAndSend(emails...)
+}
+
+// sendLimiter allows at most limit sends in any minute, remembering the times of the last limit sends
+type sendLimiter struct {
+	mu    sync.Mutex
+	limit int
+	clock clock.Clock
+	sent  []time.Time
+	next  int
+}
+
+func newSendLimiter(limit int, c clock.Clock) *sendLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &sendLimiter{limit: limit, clock: clock.OrReal(c)}
+}
+
+// wait blocks until a send is allowed, and records it. It returns the context error when ctx is done first.
+func (l *sendLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for {
+		now := l.clock.Now()
+		if len(l.sent) < l.limit {
+			l.sent = append(l.sent, now)
+			return nil
+		}
+
+		// The oldest of the last limit sends must be a minute old
+		if d := l.clock.Until(l.sent[l.next].Add(time.Minute)); d > 0 {
+			l.mu.Unlock()
+			err := sleep(ctx, l.clock, d)
+			l.mu.Lock()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		l.sent[l.next] = now
+		l.next = (l.next + 1) % l.limit
+		return nil
+	}
+}
+
+// sleep waits for d on clk, returning the context error when ctx is done first
+func sleep(ctx context.Context, clk clock.Clock, d time.Duration) error {
+	timer := clk.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendBatch sends messages in batches of Config.BatchSize, each over a single SMTP connection, so bulk
+// notifications don't open a connection per message. Like Send, it waits for Config.MaxPerMinute. Messages
+// that fail to render are skipped, and a batch that fails is retried like Send, without the messages the
+// server already accepted. The errors of every message and batch are joined, prefixed with the indexes of
+// the messages.
+func (m *Mailer) SendBatch(msgs []*Message) error {
+	return m.SendBatchContext(context.Background(), msgs)
+}
+
+// SendBatchContext is like SendBatch, passing ctx to the transport. When ctx is done, it stops waiting for
+// the rate limit or a retry and returns, with the context error for the messages it didn't send.
+func (m *Mailer) SendBatchContext(ctx context.Context, msgs []*Message) error {
+	var errs []error
+
+	type built struct {
+		index int
+		email *gomail.Msg
+	}
+	var emails []built
+	for i, msg := range msgs {
+		email, err := m.build(msg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message %d: %w", i, err))
+			continue
+		}
+		emails = append(emails, built{index: i, email: email})
+	}
+
+	for start := 0; start < len(emails); start += m.config.BatchSize {
+		batch := emails[start:min(start+m.config.BatchSize, len(emails))]
+
+		batchEmails := make([]*gomail.Msg, len(batch))
+		for i, b := range batch {
+			if err := m.limiter.wait(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("messages %d-%d: %w", b.index, emails[len(emails)-1].index, err))
+				return errors.Join(errs...)
+			}
+			batchEmails[i] = b.email
+		}
+
+		if err := m.sendBatchWithRetry(ctx, batchEmails); err != nil {
+			errs = append(errs, fmt.Errorf("messages %d-%d: %w", batch[0].index, batch[len(batch)-1].index, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sendBatchWithRetry sends a batch, retrying the emails that weren't delivered after Config.RetryDelay on
+// the mailer clock
+func (m *Mailer) sendBatchWithRetry(ctx context.Context, emails []*gomail.Msg) error {
+	var lastErr error
+	for i := 0; i < m.config.RetryCount; i++ {
+		emails, lastErr = m.sendBatch(ctx, emails)
+		if lastErr == nil {
+			return nil
+		}
+		if i < m.config.RetryCount-1 {
+			if err := sleep(ctx, m.clock, m.config.RetryDelay); err != nil {
+				return fmt.Errorf("failed to send %d emails after %d attempts: %w", len(emails), i+1, errors.Join(err, lastErr))
+			}
+		}
+	}
+	return fmt.Errorf("failed to send %d emails after %d attempts: %w", len(emails), m.config.RetryCount, lastErr)
+}
+
+// sendBatch sends emails with the transport, one at a time when it can't send batches, and returns the
+// emails that failed
+func (m *Mailer) sendBatch(ctx context.Context, emails []*gomail.Msg) ([]*gomail.Msg, error) {
+	var failed []*gomail.Msg
+	var errs []error
+
+	if bt, ok := m.transport.(BatchTransport); ok {
+		err := bt.SendBatch(ctx, emails)
+		if err == nil {
+			return nil, nil
+		}
+		for _, email := range emails {
+			if !email.IsDelivered() {
+				failed = append(failed, email)
+			}
+		}
+		if len(failed) == 0 {
+			return nil, nil
+		}
+		return failed, err
+	}
+
+	for _, email := range emails {
+		if err := m.transport.Send(ctx, email); err != nil {
+			failed = append(failed, email)
+			errs = append(errs, err)
+		}
+	}
+	return failed, errors.Join(errs...)
+}
@@ -0,0 +1,140 @@
+package mail_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/mail"
+)
+
+func TestMailer_SendBatch(t *testing.T) {
+	t.Run("sends batches over one connection each", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.BatchSize = 2
+		client := newMockSMTPClient()
+		mailer := mail.NewMailerWithClient(cfg, client)
+
+		msgs := make([]*mail.Message, 5)
+		for i := range msgs {
+			msgs[i] = basicMessage(t)
+		}
+
+		require.NoError(t, mailer.SendBatch(msgs))
+		assert.Len(t, client.Sent(), 5)
+		assert.Equal(t, 3, client.Calls())
+	})
+
+	t.Run("skips messages that fail to render", func(t *testing.T) {
+		client := newMockSMTPClient()
+		mailer := mail.NewMailerWithClient(testConfig(), client)
+
+		broken, err := mail.NewMessage().To("recipient@example.com").Template("testdata/missing_subject.tmpl").Build()
+		require.NoError(t, err)
+
+		err = mailer.SendBatch([]*mail.Message{basicMessage(t), broken, basicMessage(t)})
+		assert.ErrorContains(t, err, "message 1:")
+		assert.Len(t, client.Sent(), 2)
+	})
+
+	t.Run("reports failed batches", func(t *testing.T) {
+		client := newMockSMTPClient()
+		client.SetError("too many connections")
+		mailer := mail.NewMailerWithClient(testConfig(), client)
+
+		err := mailer.SendBatch([]*mail.Message{basicMessage(t), basicMessage(t)})
+		assert.ErrorContains(t, err, "messages 0-1:")
+		assert.ErrorContains(t, err, "too many connections")
+	})
+}
+
+func TestMailer_SendBatchRetry(t *testing.T) {
+	newMailer := func(client *mockSMTPClient) (*mail.Mailer, *hoptest.Clock) {
+		clk := hoptest.NewClock(time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC))
+		cfg := testConfig()
+		cfg.RetryCount = 2
+		cfg.RetryDelay = time.Minute
+		cfg.Clock = clk
+		return mail.NewMailerWithClient(cfg, client), clk
+	}
+
+	t.Run("waits for the retry delay on the clock", func(t *testing.T) {
+		client := newMockSMTPClient()
+		client.SetError("too many connections")
+		mailer, clk := newMailer(client)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- mailer.SendBatch([]*mail.Message{basicMessage(t), basicMessage(t)})
+		}()
+
+		clk.BlockUntil(1)
+		assert.Equal(t, 1, client.Calls())
+		client.Reset()
+
+		clk.Advance(time.Minute)
+		require.NoError(t, <-done)
+		assert.Len(t, client.Sent(), 2)
+	})
+
+	t.Run("stops retrying when the context is done", func(t *testing.T) {
+		client := newMockSMTPClient()
+		client.SetError("too many connections")
+		mailer, clk := newMailer(client)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- mailer.SendBatchContext(ctx, []*mail.Message{basicMessage(t), basicMessage(t)})
+		}()
+
+		clk.BlockUntil(1)
+		cancel()
+
+		err := <-done
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorContains(t, err, "messages 0-1:")
+		assert.ErrorContains(t, err, "too many connections")
+		assert.Equal(t, 1, client.Calls())
+	})
+}
+
+func TestMailer_MaxPerMinute(t *testing.T) {
+	clk := hoptest.NewClock(time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC))
+	cfg := testConfig()
+	cfg.MaxPerMinute = 2
+	cfg.Clock = clk
+	client := newMockSMTPClient()
+	mailer := mail.NewMailerWithClient(cfg, client)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- mailer.SendBatch([]*mail.Message{basicMessage(t), basicMessage(t), basicMessage(t)})
+	}()
+
+	// The third message waits until the first one is a minute old
+	clk.BlockUntil(1)
+	assert.Empty(t, client.Sent(), "the batch is sent once every message may be sent")
+
+	clk.Advance(30 * time.Second)
+	assert.Equal(t, 1, clk.Waiters())
+	clk.Advance(30 * time.Second)
+
+	require.NoError(t, <-done)
+	assert.Len(t, client.Sent(), 3)
+
+	// The second message's slot is free now, but the next Send waits for the third message's
+	require.NoError(t, mailer.Send(basicMessage(t)))
+	go func() {
+		done <- mailer.Send(basicMessage(t))
+	}()
+	clk.BlockUntil(1)
+	assert.Len(t, client.Sent(), 4)
+	clk.Advance(time.Minute)
+	require.NoError(t, <-done)
+	assert.Len(t, client.Sent(), 5)
+}
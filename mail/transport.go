@@ -0,0 +1,232 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	gomail "github.com/wneessen/go-mail"
+)
+
+// Transports selectable with Config.Transport
+const (
+	// TransportSMTP sends through the SMTP server of the config. It is the default.
+	TransportSMTP = "smtp"
+	// TransportFile writes each email as an .eml file to Config.FileDir, for development
+	TransportFile = "file"
+	// TransportHTTP posts each email as JSON to Config.HTTPURL, e.g. a provider API or a webhook
+	TransportHTTP = "http"
+)
+
+// Transport delivers rendered emails
+type Transport interface {
+	Send(ctx context.Context, email *gomail.Msg) error
+}
+
+// newTransport creates the transport selected by the config
+func newTransport(cfg *Config) (Transport, error) {
+	switch cfg.Transport {
+	case TransportSMTP, "":
+		client, err := gomail.NewClient(
+			cfg.Host,
+			gomail.WithTimeout(10*time.Second),
+			gomail.WithSMTPAuth(authTypeFromString(cfg.AuthType)),
+			gomail.WithPort(cfg.Port),
+			gomail.WithUsername(cfg.Username),
+			gomail.WithPassword(cfg.Password),
+			gomail.WithTLSPolicy(tlsPolicyFromInt(cfg.TLSPolicy)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mail client: %w", err)
+		}
+		return NewSMTPTransport(client), nil
+	case TransportFile:
+		if cfg.FileDir == "" {
+			return nil, fmt.Errorf("the %s transport requires a FileDir", TransportFile)
+		}
+		return NewFileTransport(cfg.FileDir), nil
+	case TransportHTTP:
+		if cfg.HTTPURL == "" {
+			return nil, fmt.Errorf("the %s transport requires an HTTPURL", TransportHTTP)
+		}
+		return NewHTTPTransport(cfg.HTTPURL, cfg.HTTPToken), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+	}
+}
+
+// SMTPTransport sends emails with an SMTP client
+type SMTPTransport struct {
+	client SMTPClient
+}
+
+// NewSMTPTransport creates a new SMTPTransport using the client, e.g. a go-mail client
+func NewSMTPTransport(client SMTPClient) *SMTPTransport {
+	return &SMTPTransport{client: client}
+}
+
+// Send implements Transport
+func (t *SMTPTransport) Send(_ context.Context, email *gomail.Msg) error {
+	return t.client.DialAndSend(email)
+}
+
+// FileTransport writes each email as an .eml file to a directory instead of sending it, to read the emails
+// of a development environment with a mail client. Bcc recipients are not part of the file.
+type FileTransport struct {
+	dir string
+}
+
+// NewFileTransport creates a new FileTransport writing to dir, which is created when missing
+func NewFileTransport(dir string) *FileTransport {
+	return &FileTransport{dir: dir}
+}
+
+// Send implements Transport
+func (t *FileTransport) Send(_ context.Context, email *gomail.Msg) error {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	name := filepath.Join(t.dir, time.Now().Format("20060102-150405.000000000")+"-"+hex.EncodeToString(suffix)+".eml")
+
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	if _, err := email.WriteTo(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return f.Close()
+}
+
+// HTTPPayload is the JSON body posted by HTTPTransport
+type HTTPPayload struct {
+	From        []string         `json:"from"`
+	To          []string         `json:"to"`
+	Cc          []string         `json:"cc,omitempty"`
+	Bcc         []string         `json:"bcc,omitempty"`
+	ReplyTo     string           `json:"reply_to,omitempty"`
+	Subject     string           `json:"subject"`
+	Text        string           `json:"text,omitempty"`
+	HTML        string           `json:"html,omitempty"`
+	Attachments []HTTPAttachment `json:"attachments,omitempty"`
+}
+
+// HTTPAttachment is an attachment of an HTTPPayload. Content is base64 encoded in the JSON body.
+type HTTPAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Content     []byte `json:"content"`
+}
+
+// HTTPTransport posts each email as an HTTPPayload to a URL, with the token as a bearer token. The payload
+// is generic, for webhooks and relays that accept it as is; a provider API with its own format needs a
+// Transport of its own, which can build on NewHTTPPayload.
+type HTTPTransport struct {
+	// URL receives the POST requests
+	URL string
+	// Token, when set, is sent as "Authorization: Bearer <token>"
+	Token string
+	// Client sends the requests. Default is an http.Client with a 10 second timeout.
+	Client *http.Client
+}
+
+// NewHTTPTransport creates a new HTTPTransport posting to url
+func NewHTTPTransport(url, token string) *HTTPTransport {
+	return &HTTPTransport{
+		URL:    url,
+		Token:  token,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send implements Transport. Responses other than 2xx are errors.
+func (t *HTTPTransport) Send(ctx context.Context, email *gomail.Msg) error {
+	payload, err := NewHTTPPayload(email)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode email: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.Token)
+	}
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post email: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to post email: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// NewHTTPPayload converts a rendered email to an HTTPPayload
+func NewHTTPPayload(email *gomail.Msg) (*HTTPPayload, error) {
+	payload := &HTTPPayload{
+		From: email.GetFromString(),
+		To:   email.GetToString(),
+		Cc:   email.GetCcString(),
+		Bcc:  email.GetBccString(),
+	}
+	if subjects := email.GetGenHeader(gomail.HeaderSubject); len(subjects) > 0 {
+		payload.Subject = subjects[0]
+	}
+	if replyTos := email.GetGenHeader(gomail.HeaderReplyTo); len(replyTos) > 0 {
+		payload.ReplyTo = replyTos[0]
+	}
+
+	for _, part := range email.GetParts() {
+		content, err := part.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read email body: %w", err)
+		}
+		switch part.GetContentType() {
+		case gomail.TypeTextPlain:
+			payload.Text = string(content)
+		case gomail.TypeTextHTML:
+			payload.HTML = string(content)
+		}
+	}
+
+	for _, file := range email.GetAttachments() {
+		var buf bytes.Buffer
+		if _, err := file.Writer(&buf); err != nil {
+			return nil, fmt.Errorf("failed to read attachment %s: %w", file.Name, err)
+		}
+		payload.Attachments = append(payload.Attachments, HTTPAttachment{
+			Filename:    file.Name,
+			ContentType: string(file.ContentType),
+			Content:     buf.Bytes(),
+		})
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,107 @@
+package mail_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/mail"
+)
+
+func TestNewMailer_Transports(t *testing.T) {
+	t.Run("file transport writes eml files", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "outbox")
+		cfg := testConfig()
+		cfg.Transport = mail.TransportFile
+		cfg.FileDir = dir
+
+		mailer, err := mail.NewMailer(cfg)
+		require.NoError(t, err)
+		require.NoError(t, mailer.Send(basicMessage(t)))
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		data, err := os.ReadFile(files[0])
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "Subject: Test Email")
+		assert.Contains(t, string(data), "recipient@example.com")
+	})
+
+	t.Run("http transport posts json", func(t *testing.T) {
+		var payload mail.HTTPPayload
+		var auth string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth = r.Header.Get("Authorization")
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		cfg := testConfig()
+		cfg.Transport = mail.TransportHTTP
+		cfg.HTTPURL = server.URL
+		cfg.HTTPToken = "secret"
+
+		mailer, err := mail.NewMailer(cfg)
+		require.NoError(t, err)
+
+		msg, err := mail.NewMessage().
+			To("recipient@example.com").
+			Bcc("bcc@example.com").
+			Template("testdata/basic.tmpl").
+			WithData(map[string]string{"name": "John"}).
+			Attach("test.txt", strings.NewReader("attached text")).
+			Build()
+		require.NoError(t, err)
+		require.NoError(t, mailer.Send(msg))
+
+		assert.Equal(t, "Bearer secret", auth)
+		assert.Equal(t, []string{"<test@example.com>"}, payload.From)
+		assert.Equal(t, []string{"<recipient@example.com>"}, payload.To)
+		assert.Equal(t, []string{"<bcc@example.com>"}, payload.Bcc)
+		assert.Equal(t, "Test Email", payload.Subject)
+		assert.Contains(t, payload.Text, "Hello John!")
+		assert.Contains(t, payload.HTML, "<p>Hello John!</p>")
+		require.Len(t, payload.Attachments, 1)
+		assert.Equal(t, "test.txt", payload.Attachments[0].Filename)
+		assert.Equal(t, "attached text", string(payload.Attachments[0].Content))
+	})
+
+	t.Run("http transport reports error responses", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "invalid recipient", http.StatusUnprocessableEntity)
+		}))
+		defer server.Close()
+
+		cfg := testConfig()
+		cfg.Transport = mail.TransportHTTP
+		cfg.HTTPURL = server.URL
+
+		mailer, err := mail.NewMailer(cfg)
+		require.NoError(t, err)
+
+		err = mailer.Send(basicMessage(t))
+		require.Error(t, err)
+		assert.True(t, strings.Contains(err.Error(), "422") && strings.Contains(err.Error(), "invalid recipient"), err.Error())
+	})
+
+	t.Run("rejects unknown and incomplete transports", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Transport = "carrier-pigeon"
+		_, err := mail.NewMailer(cfg)
+		assert.ErrorContains(t, err, "unknown mail transport")
+
+		cfg.Transport = mail.TransportFile
+		_, err = mail.NewMailer(cfg)
+		assert.ErrorContains(t, err, "FileDir")
+	})
+}
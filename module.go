@@ -2,12 +2,29 @@ package hop
 
 import (
 	"context"
+	"io"
+	"io/fs"
 	"net/http"
 
 	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/render"
 	"github.com/patrickward/hop/route"
 )
 
+// Events emitted by App.RestartModule and App.ReplaceModule, with a ModuleEvent payload
+const (
+	// EventModuleStopped is emitted once a module has been stopped for a restart or replacement
+	EventModuleStopped = "module.stopped"
+	// EventModuleStarted is emitted once a restarted or replacement module has been started
+	EventModuleStarted = "module.started"
+)
+
+// ModuleEvent is the payload of the module lifecycle events
+type ModuleEvent struct {
+	// ID is the ID of the module
+	ID string
+}
+
 // Module is the base interface that all modules must implement. It provides
 // identification and initialization capabilities for the module system.
 type Module interface {
@@ -49,6 +66,51 @@ type HTTPModule interface {
 	RegisterRoutes(router *route.Mux)
 }
 
+// NamespacedModule is implemented by modules that ship their own templates, static assets and routes, such
+// as third-party hop modules. The App mounts them under the module's ID so they can be dropped into any
+// application without clashing with it:
+//
+//   - templates are available as "<id>:<path>", e.g. "blog:views/index"
+//   - routes are registered under Prefix, "/<id>" by default
+//   - static assets are served under "<prefix>/static/"
+//   - data from OnTemplateData, if the module is also a TemplateDataModule, is scoped under .Modules.<id>
+type NamespacedModule interface {
+	Module
+	// Namespace returns the templates, static assets and routes the module provides
+	Namespace() ModuleNamespace
+}
+
+// ModuleNamespace describes what a NamespacedModule mounts into the App. Any field may be left empty.
+type ModuleNamespace struct {
+	// Prefix is the URL prefix of the module's routes and static assets. Default is "/<id>".
+	Prefix string
+	// Templates holds the module's views, layouts and partials, mounted as the "<id>:" template source.
+	// Partial names must not clash with those of other sources, e.g. "partial:<id>:nav".
+	Templates fs.FS
+	// Static holds the module's static assets, served at "<prefix>/static/"
+	Static fs.FS
+	// Routes registers the module's routes on a group with the module's prefix
+	Routes route.GroupFunc
+}
+
+// TemplateModule is implemented by modules that contribute template sources, such as a shared component
+// library, without mounting a full namespace. Each source is available as "<namespace>:<path>", where an
+// empty namespace, or "-", stands for the module's ID. Its layouts and partials join the shared set, so
+// partial names must not clash with those of other sources.
+type TemplateModule interface {
+	Module
+	// Templates returns the template sources the module provides, keyed by namespace
+	Templates() render.Sources
+}
+
+// AssetModule is implemented by modules that serve static files. Each file system is served under its URL
+// prefix, e.g. "/assets/admin/", which must not clash with other routes.
+type AssetModule interface {
+	Module
+	// Assets returns the static file systems the module provides, keyed by URL prefix
+	Assets() map[string]fs.FS
+}
+
 // DispatcherModule is implemented by modules that handle application events.
 // The RegisterEvents method is called after initialization to set up any
 // event handlers the module provides.
@@ -78,3 +140,16 @@ type ConfigurableModule interface {
 	// specific configuration type
 	Configure(ctx context.Context, config any) error
 }
+
+// BackupModule is implemented by modules that own data, such as sessions, SQLite stores, or uploads,
+// that should be included in application backups. The App combines the output of every
+// BackupModule into a single archive (see App.Backup) and hands each module its own data
+// back on restore (see App.Restore). SQLiteBackup, sqlitestore.Backup and uploads.Backup implement it for
+// SQLite databases, sessions and uploaded files.
+type BackupModule interface {
+	Module
+	// Backup writes the module's data to w
+	Backup(ctx context.Context, w io.Writer) error
+	// Restore replaces the module's data with the data read from r
+	Restore(ctx context.Context, r io.Reader) error
+}
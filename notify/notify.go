@@ -0,0 +1,109 @@
+// Package notify delivers short notifications to users over channels such as SMS and Web Push.
+//
+// Each channel implements Channel, so application code can send the same Notification over whichever
+// channels a user has enabled:
+//
+//	sms := notify.NewSMSChannel(&notify.TwilioProvider{AccountSID: sid, AuthToken: token, From: "+15005550006"}, "US")
+//	push, err := notify.NewWebPushChannel(&notify.WebPushConfig{Keys: keys, Subject: "mailto:ops@example.com"})
+//
+//	n, err := notify.Templates{
+//	    "order.shipped": {Title: "Order shipped", Body: "Order {{.Number}} is on its way", URL: "/orders/{{.Number}}"},
+//	}.Render("order.shipped", user.ID, order)
+//	err = push.Send(ctx, n)
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/template"
+)
+
+var (
+	// ErrNoRecipient is returned when a notification has no recipient
+	ErrNoRecipient = errors.New("notify: notification has no recipient")
+	// ErrTemplateNotFound is returned when a named notification template is not registered
+	ErrTemplateNotFound = errors.New("notify: template not found")
+)
+
+// Notification is a short message delivered to a single recipient
+type Notification struct {
+	// To identifies the recipient. Its meaning depends on the channel: a phone number for SMS, or the
+	// owner of the push subscriptions for Web Push.
+	To string `json:"-"`
+	// Title is the headline, shown by push notifications. SMS messages prefix the body with it.
+	Title string `json:"title,omitempty"`
+	// Body is the main text
+	Body string `json:"body"`
+	// URL is opened when the notification is clicked. SMS messages append it to the body.
+	URL string `json:"url,omitempty"`
+	// Icon is the URL of an icon shown with push notifications
+	Icon string `json:"icon,omitempty"`
+	// Tag groups push notifications, so a newer notification replaces an older one with the same tag
+	Tag string `json:"tag,omitempty"`
+	// Data is passed to the service worker with push notifications
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Channel delivers notifications
+type Channel interface {
+	// Name returns the channel name, e.g. "sms" or "webpush"
+	Name() string
+	// Send delivers the notification to its recipient
+	Send(ctx context.Context, n *Notification) error
+}
+
+// Template describes a notification using text/template sources for its text fields
+type Template struct {
+	Title string
+	Body  string
+	URL   string
+	Icon  string
+	Tag   string
+}
+
+// Execute renders the template with data and returns a notification for the recipient
+func (t Template) Execute(to string, data any) (*Notification, error) {
+	n := &Notification{To: to}
+	fields := []struct {
+		name   string
+		source string
+		dst    *string
+	}{
+		{"title", t.Title, &n.Title},
+		{"body", t.Body, &n.Body},
+		{"url", t.URL, &n.URL},
+		{"icon", t.Icon, &n.Icon},
+		{"tag", t.Tag, &n.Tag},
+	}
+
+	for _, f := range fields {
+		if f.source == "" {
+			continue
+		}
+		tmpl, err := template.New(f.name).Option("missingkey=error").Parse(f.source)
+		if err != nil {
+			return nil, fmt.Errorf("notify: parsing %s template: %w", f.name, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("notify: rendering %s template: %w", f.name, err)
+		}
+		*f.dst = buf.String()
+	}
+
+	return n, nil
+}
+
+// Templates is a set of named notification templates
+type Templates map[string]Template
+
+// Render renders the named template for the recipient
+func (ts Templates) Render(name, to string, data any) (*Notification, error) {
+	t, ok := ts[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return t.Execute(to, data)
+}
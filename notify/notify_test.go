@@ -0,0 +1,35 @@
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/notify"
+)
+
+func TestTemplates_Render(t *testing.T) {
+	templates := notify.Templates{
+		"order.shipped": {
+			Title: "Order shipped",
+			Body:  "Order {{.Number}} is on its way",
+			URL:   "/orders/{{.Number}}",
+		},
+	}
+
+	n, err := templates.Render("order.shipped", "user-1", map[string]any{"Number": "A42"})
+	require.NoError(t, err)
+	assert.Equal(t, &notify.Notification{
+		To:    "user-1",
+		Title: "Order shipped",
+		Body:  "Order A42 is on its way",
+		URL:   "/orders/A42",
+	}, n)
+
+	_, err = templates.Render("missing", "user-1", nil)
+	assert.ErrorIs(t, err, notify.ErrTemplateNotFound)
+
+	_, err = templates.Render("order.shipped", "user-1", map[string]any{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/patrickward/hop/check"
+)
+
+// SMSProvider sends text messages through an SMS gateway
+type SMSProvider interface {
+	// SendSMS sends body to the E.164 phone number to
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// SMSChannel sends notifications as text messages
+type SMSChannel struct {
+	provider       SMSProvider
+	defaultCountry string
+}
+
+// NewSMSChannel creates an SMS channel. Recipients are normalized to E.164, using defaultCountry, e.g.
+// "US", for numbers without an international prefix.
+func NewSMSChannel(provider SMSProvider, defaultCountry string) *SMSChannel {
+	return &SMSChannel{provider: provider, defaultCountry: defaultCountry}
+}
+
+// Name returns "sms"
+func (c *SMSChannel) Name() string {
+	return "sms"
+}
+
+// Send sends the notification as a text message. The title, when set, prefixes the body and the URL,
+// when set, is appended to it.
+func (c *SMSChannel) Send(ctx context.Context, n *Notification) error {
+	if n.To == "" {
+		return ErrNoRecipient
+	}
+
+	to, err := check.NormalizePhone(n.To, c.defaultCountry)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+
+	return c.provider.SendSMS(ctx, to, SMSBody(n))
+}
+
+// SMSBody returns the text message body for a notification
+func SMSBody(n *Notification) string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{n.Title, n.Body, n.URL} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// TwilioProvider sends text messages with the Twilio Messages API. Other providers that implement the
+// same API can be used by changing BaseURL.
+type TwilioProvider struct {
+	// AccountSID and AuthToken authenticate the requests
+	AccountSID string
+	AuthToken  string
+	// From is the sending phone number. Either From or MessagingServiceSID is required.
+	From string
+	// MessagingServiceSID sends through a messaging service instead of a single number
+	MessagingServiceSID string
+	// BaseURL is the API root. Defaults to "https://api.twilio.com".
+	BaseURL string
+	// Client is the HTTP client used for requests. Defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+// TwilioError is the error returned by the Twilio API
+type TwilioError struct {
+	Status   int    `json:"status"`
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"more_info"`
+}
+
+// Error implements the error interface
+func (e *TwilioError) Error() string {
+	return fmt.Sprintf("notify: twilio error %d (status %d): %s", e.Code, e.Status, e.Message)
+}
+
+// defaultHTTPClient is used by providers without a configured client
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// SendSMS sends a text message
+func (p *TwilioProvider) SendSMS(ctx context.Context, to, body string) error {
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.twilio.com"
+	}
+	client := p.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+
+	form := url.Values{"To": {to}, "Body": {body}}
+	if p.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", p.MessagingServiceSID)
+	} else {
+		form.Set("From", p.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimRight(baseURL, "/"), url.PathEscape(p.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("notify: creating twilio request: %w", err)
+	}
+	req.SetBasicAuth(p.AccountSID, p.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify: sending sms: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	apiErr := &TwilioError{Status: resp.StatusCode}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	apiErr.Status = resp.StatusCode
+	return apiErr
+}
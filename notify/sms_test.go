@@ -0,0 +1,72 @@
+package notify_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/check"
+	"github.com/patrickward/hop/notify"
+)
+
+func TestSMSChannel_Twilio(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		got = r
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"SM1"}`))
+	}))
+	defer server.Close()
+
+	channel := notify.NewSMSChannel(&notify.TwilioProvider{
+		AccountSID: "AC123",
+		AuthToken:  "secret",
+		From:       "+15005550006",
+		BaseURL:    server.URL,
+	}, "US")
+
+	err := channel.Send(context.Background(), &notify.Notification{
+		To:    "(415) 555-2671",
+		Title: "Order shipped",
+		Body:  "Order A42 is on its way",
+		URL:   "https://example.com/orders/A42",
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, got)
+	assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", got.URL.Path)
+	user, pass, ok := got.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "AC123", user)
+	assert.Equal(t, "secret", pass)
+	assert.Equal(t, "+14155552671", got.PostForm.Get("To"))
+	assert.Equal(t, "+15005550006", got.PostForm.Get("From"))
+	assert.Equal(t, "Order shipped\nOrder A42 is on its way\nhttps://example.com/orders/A42", got.PostForm.Get("Body"))
+}
+
+func TestSMSChannel_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":21211,"message":"The 'To' number is not a valid phone number.","status":400}`))
+	}))
+	defer server.Close()
+
+	channel := notify.NewSMSChannel(&notify.TwilioProvider{AccountSID: "AC123", From: "+15005550006", BaseURL: server.URL}, "US")
+
+	err := channel.Send(context.Background(), &notify.Notification{To: "+14155552671", Body: "hi"})
+	var apiErr *notify.TwilioError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 21211, apiErr.Code)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+
+	err = channel.Send(context.Background(), &notify.Notification{Body: "hi"})
+	assert.ErrorIs(t, err, notify.ErrNoRecipient)
+
+	err = channel.Send(context.Background(), &notify.Notification{To: "not a number", Body: "hi"})
+	assert.ErrorIs(t, err, check.ErrInvalidPhone)
+}
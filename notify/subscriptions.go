@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SubscriptionKeys are the browser's keys for encrypting push payloads
+type SubscriptionKeys struct {
+	// P256dh is the browser's public key, base64url encoded
+	P256dh string `json:"p256dh"`
+	// Auth is the authentication secret, base64url encoded
+	Auth string `json:"auth"`
+}
+
+// Subscription is a browser push subscription, as returned by PushSubscription.toJSON(), together with
+// the owner it was registered for
+type Subscription struct {
+	Endpoint  string           `json:"endpoint"`
+	Keys      SubscriptionKeys `json:"keys"`
+	Owner     string           `json:"owner,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// SubscriptionStore persists push subscriptions. Subscriptions are identified by their endpoint.
+type SubscriptionStore interface {
+	// Save adds or replaces a subscription
+	Save(ctx context.Context, sub Subscription) error
+	// Delete removes the subscription with the endpoint, if it exists
+	Delete(ctx context.Context, endpoint string) error
+	// ForOwner returns the subscriptions of an owner
+	ForOwner(ctx context.Context, owner string) ([]Subscription, error)
+}
+
+// MemorySubscriptionStore keeps subscriptions in memory. It is intended for development and tests.
+type MemorySubscriptionStore struct {
+	mu   sync.RWMutex
+	subs map[string]Subscription
+}
+
+// NewMemorySubscriptionStore creates an empty in-memory store
+func NewMemorySubscriptionStore() *MemorySubscriptionStore {
+	return &MemorySubscriptionStore{subs: make(map[string]Subscription)}
+}
+
+// Save adds or replaces a subscription
+func (s *MemorySubscriptionStore) Save(_ context.Context, sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub.Endpoint] = sub
+	return nil
+}
+
+// Delete removes the subscription with the endpoint
+func (s *MemorySubscriptionStore) Delete(_ context.Context, endpoint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, endpoint)
+	return nil
+}
+
+// ForOwner returns the subscriptions of an owner, oldest first
+func (s *MemorySubscriptionStore) ForOwner(_ context.Context, owner string) ([]Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var subs []Subscription
+	for _, sub := range s.subs {
+		if sub.Owner == owner {
+			subs = append(subs, sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.Before(subs[j].CreatedAt) })
+	return subs, nil
+}
+
+// PublicKeyHandler returns a handler that responds with the VAPID public key as JSON, e.g.
+// {"public_key": "BNc..."}, for use as the applicationServerKey when subscribing in the browser
+func (c *WebPushChannel) PublicKeyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"public_key": c.config.Keys.PublicKey})
+	})
+}
+
+// SubscribeHandler returns a handler that stores the subscription posted as JSON by the browser for the
+// owner of the request
+//
+//	const sub = await registration.pushManager.subscribe({userVisibleOnly: true, applicationServerKey})
+//	await fetch("/push/subscriptions", {method: "POST", body: JSON.stringify(sub), headers: {"Content-Type": "application/json"}})
+func (c *WebPushChannel) SubscribeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owner := c.owner(r)
+		if owner == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		var sub Subscription
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 8<<10)).Decode(&sub); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid subscription"})
+			return
+		}
+
+		if !validSubscription(sub) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid subscription"})
+			return
+		}
+
+		sub.Owner = owner
+		sub.CreatedAt = time.Now()
+		if err := c.config.Store.Save(r.Context(), sub); err != nil {
+			c.config.Logger.Error("saving push subscription", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not save subscription"})
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	})
+}
+
+// UnsubscribeHandler returns a handler that deletes the subscription whose endpoint is posted as JSON,
+// e.g. {"endpoint": "https://..."}, if it belongs to the owner of the request
+func (c *WebPushChannel) UnsubscribeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owner := c.owner(r)
+		if owner == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		var body struct {
+			Endpoint string `json:"endpoint"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 8<<10)).Decode(&body); err != nil || body.Endpoint == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid subscription"})
+			return
+		}
+
+		subs, err := c.config.Store.ForOwner(r.Context(), owner)
+		if err != nil {
+			c.config.Logger.Error("loading push subscriptions", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not delete subscription"})
+			return
+		}
+
+		for _, sub := range subs {
+			if sub.Endpoint != body.Endpoint {
+				continue
+			}
+			if err := c.config.Store.Delete(r.Context(), sub.Endpoint); err != nil {
+				c.config.Logger.Error("deleting push subscription", "error", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not delete subscription"})
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// owner returns the owner of the request
+func (c *WebPushChannel) owner(r *http.Request) string {
+	if c.config.Owner == nil {
+		return ""
+	}
+	return c.config.Owner(r)
+}
+
+// validSubscription reports whether the subscription has an HTTPS endpoint and valid keys
+func validSubscription(sub Subscription) bool {
+	u, err := url.Parse(sub.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return false
+	}
+	if key, err := decodeKey(sub.Keys.P256dh); err != nil || len(key) != 65 {
+		return false
+	}
+	if auth, err := decodeKey(sub.Keys.Auth); err != nil || len(auth) != 16 {
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
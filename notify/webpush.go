@@ -0,0 +1,368 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrSubscriptionGone is returned when the push service reports that a subscription has expired or
+// been revoked. The channel deletes such subscriptions from the store.
+var ErrSubscriptionGone = errors.New("notify: push subscription is gone")
+
+// maxPushPayload is the largest payload push services are required to accept
+const maxPushPayload = 4096 - 16 - 4 - 1 - 65 - 16 - 1
+
+// Urgency is the Web Push urgency of a message, which lets devices save battery for less urgent messages
+type Urgency string
+
+const (
+	UrgencyVeryLow Urgency = "very-low"
+	UrgencyLow     Urgency = "low"
+	UrgencyNormal  Urgency = "normal"
+	UrgencyHigh    Urgency = "high"
+)
+
+// VAPIDKeys is the application server key pair used to identify the application to push services. Both
+// keys are unpadded base64url encoded: the public key as an uncompressed P-256 point and the private key
+// as its 32 byte scalar. The public key is the applicationServerKey passed to pushManager.subscribe.
+type VAPIDKeys struct {
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+}
+
+// GenerateVAPIDKeys generates a new VAPID key pair. Generate it once and store it in the configuration;
+// changing it invalidates every existing subscription.
+func GenerateVAPIDKeys() (VAPIDKeys, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return VAPIDKeys{}, fmt.Errorf("notify: generating vapid keys: %w", err)
+	}
+	return VAPIDKeys{
+		PublicKey:  base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		PrivateKey: base64.RawURLEncoding.EncodeToString(key.Bytes()),
+	}, nil
+}
+
+// WebPushConfig configures a WebPushChannel
+type WebPushConfig struct {
+	// Keys is the VAPID key pair (required)
+	Keys VAPIDKeys
+	// Subject is a contact URI for the application, e.g. "mailto:ops@example.com" (required)
+	Subject string
+	// Store holds the subscriptions. Defaults to a MemorySubscriptionStore.
+	Store SubscriptionStore
+	// Owner returns the owner of subscriptions created through SubscribeHandler, typically the user ID
+	// from the session. Requests for which it returns "" are rejected. Required to use the handlers.
+	Owner func(r *http.Request) string
+	// TTL is how long push services keep undelivered messages. Defaults to 24 hours.
+	TTL time.Duration
+	// Urgency is the urgency of messages. Defaults to UrgencyNormal.
+	Urgency Urgency
+	// Client is the HTTP client used to reach push services. Defaults to a client with a 10 second timeout.
+	Client *http.Client
+	// Logger is used to log delivery failures. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// WebPushChannel sends notifications with the Web Push protocol to every subscription of the recipient.
+// Payloads are encrypted as described in RFC 8291 and requests are authenticated with VAPID (RFC 8292).
+type WebPushChannel struct {
+	config     *WebPushConfig
+	signingKey *ecdsa.PrivateKey
+	publicKey  []byte
+}
+
+// NewWebPushChannel creates a Web Push channel
+func NewWebPushChannel(config *WebPushConfig) (*WebPushChannel, error) {
+	if config == nil {
+		config = &WebPushConfig{}
+	}
+
+	if config.Subject == "" {
+		return nil, errors.New("notify: a vapid subject is required")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(config.Keys.PrivateKey, "="))
+	if err != nil {
+		return nil, fmt.Errorf("notify: decoding vapid private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("notify: invalid vapid private key: %w", err)
+	}
+	// x509 converts the ECDH key into the ECDSA key used to sign the VAPID token
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("notify: invalid vapid private key: %w", err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("notify: invalid vapid private key: %w", err)
+	}
+	signingKey, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("notify: invalid vapid private key")
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemorySubscriptionStore()
+	}
+
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+
+	if config.Urgency == "" {
+		config.Urgency = UrgencyNormal
+	}
+
+	if config.Client == nil {
+		config.Client = defaultHTTPClient
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	return &WebPushChannel{
+		config:     config,
+		signingKey: signingKey,
+		publicKey:  key.PublicKey().Bytes(),
+	}, nil
+}
+
+// Name returns "webpush"
+func (c *WebPushChannel) Name() string {
+	return "webpush"
+}
+
+// Store returns the subscription store
+func (c *WebPushChannel) Store() SubscriptionStore {
+	return c.config.Store
+}
+
+// Send delivers the notification, encoded as JSON, to every subscription owned by n.To. Subscriptions
+// the push service reports as gone are deleted. An error is returned only if no subscription received
+// the notification.
+func (c *WebPushChannel) Send(ctx context.Context, n *Notification) error {
+	if n.To == "" {
+		return ErrNoRecipient
+	}
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("notify: encoding push payload: %w", err)
+	}
+
+	subs, err := c.config.Store.ForOwner(ctx, n.To)
+	if err != nil {
+		return fmt.Errorf("notify: loading push subscriptions: %w", err)
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	var errs []error
+	delivered := 0
+	for _, sub := range subs {
+		err := c.Push(ctx, sub, payload)
+		switch {
+		case err == nil:
+			delivered++
+		case errors.Is(err, ErrSubscriptionGone):
+			if err := c.config.Store.Delete(ctx, sub.Endpoint); err != nil {
+				c.config.Logger.Error("deleting expired push subscription", slog.String("error", err.Error()))
+			}
+		default:
+			c.config.Logger.Warn("push delivery failed",
+				slog.String("owner", n.To),
+				slog.String("error", err.Error()))
+			errs = append(errs, err)
+		}
+	}
+
+	if delivered == 0 && len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// Push encrypts payload for the subscription and sends it to its push service
+func (c *WebPushChannel) Push(ctx context.Context, sub Subscription, payload []byte) error {
+	if len(payload) > maxPushPayload {
+		return fmt.Errorf("notify: push payload is %d bytes, the maximum is %d", len(payload), maxPushPayload)
+	}
+
+	body, err := encryptPayload(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	token, err := c.vapidToken(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("notify: creating push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(c.config.TTL.Seconds())))
+	req.Header.Set("Urgency", string(c.config.Urgency))
+	req.Header.Set("Authorization", "vapid t="+token+", k="+base64.RawURLEncoding.EncodeToString(c.publicKey))
+
+	resp, err := c.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify: sending push: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	default:
+		return fmt.Errorf("notify: push service responded %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+}
+
+// vapidToken returns a signed VAPID JWT for the push service of the endpoint
+func (c *WebPushChannel) vapidToken(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("notify: invalid push endpoint %q", endpoint)
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": c.config.Subject,
+	})
+	if err != nil {
+		return "", fmt.Errorf("notify: encoding vapid claims: %w", err)
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, c.signingKey, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("notify: signing vapid token: %w", err)
+	}
+	// JWS uses the fixed width r || s encoding rather than ASN.1
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// encryptPayload encrypts payload for the subscription using the aes128gcm content encoding (RFC 8291)
+func encryptPayload(sub Subscription, payload []byte) ([]byte, error) {
+	uaPublic, err := decodeKey(sub.Keys.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("notify: invalid subscription key: %w", err)
+	}
+	authSecret, err := decodeKey(sub.Keys.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("notify: invalid subscription auth secret: %w", err)
+	}
+
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("notify: invalid subscription key: %w", err)
+	}
+
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("notify: generating push key: %w", err)
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	shared, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, fmt.Errorf("notify: deriving push secret: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("notify: generating salt: %w", err)
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm, err := derive(shared, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := derive(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := derive(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, fmt.Errorf("notify: creating cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("notify: creating cipher: %w", err)
+	}
+
+	// A single record: the payload followed by the last record delimiter
+	plaintext := append(append(make([]byte, 0, len(payload)+1), payload...), 0x02)
+
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, 4096)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// derive runs HKDF-SHA-256 and returns length bytes
+func derive(secret, salt, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, fmt.Errorf("notify: deriving key: %w", err)
+	}
+	return out, nil
+}
+
+// decodeKey decodes a base64url key, with or without padding, as browsers may send either
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
@@ -0,0 +1,226 @@
+package notify_test
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/patrickward/hop/notify"
+)
+
+// browser simulates a subscribed browser's key material
+type browser struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newBrowser(t *testing.T) *browser {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	auth := make([]byte, 16)
+	_, err = rand.Read(auth)
+	require.NoError(t, err)
+	return &browser{key: key, auth: auth}
+}
+
+func (b *browser) subscription(endpoint string) notify.Subscription {
+	return notify.Subscription{
+		Endpoint: endpoint,
+		Keys: notify.SubscriptionKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(b.key.PublicKey().Bytes()),
+			Auth:   base64.RawURLEncoding.EncodeToString(b.auth),
+		},
+	}
+}
+
+// decrypt reverses the aes128gcm content encoding
+func (b *browser) decrypt(t *testing.T, body []byte) []byte {
+	salt, idLen := body[:16], int(body[20])
+	asPublic := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	require.NoError(t, err)
+	shared, err := b.key.ECDH(asKey)
+	require.NoError(t, err)
+
+	read := func(secret, salt []byte, info string, n int) []byte {
+		out := make([]byte, n)
+		_, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), out)
+		require.NoError(t, err)
+		return out
+	}
+	ikm := read(shared, b.auth, "WebPush: info\x00"+string(b.key.PublicKey().Bytes())+string(asPublic), 32)
+	cek := read(ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce := read(ikm, salt, "Content-Encoding: nonce\x00", 12)
+
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+// verifyVAPID checks the signature of the VAPID authorization header and returns its claims
+func verifyVAPID(t *testing.T, header string) map[string]any {
+	params := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(header, "vapid "), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		params[k] = v
+	}
+
+	rawKey, err := base64.RawURLEncoding.DecodeString(params["k"])
+	require.NoError(t, err)
+	ecdhKey, err := ecdh.P256().NewPublicKey(rawKey)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(ecdhKey)
+	require.NoError(t, err)
+	pub, err := x509.ParsePKIXPublicKey(der)
+	require.NoError(t, err)
+
+	parts := strings.Split(params["t"], ".")
+	require.Len(t, parts, 3)
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	require.True(t, ecdsa.Verify(pub.(*ecdsa.PublicKey), digest[:], r, s))
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(rawClaims, &claims))
+	return claims
+}
+
+func TestWebPushChannel_Send(t *testing.T) {
+	b := newBrowser(t)
+	keys, err := notify.GenerateVAPIDKeys()
+	require.NoError(t, err)
+
+	var (
+		payload []byte
+		claims  map[string]any
+		headers http.Header
+	)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		payload = b.decrypt(t, body)
+		claims = verifyVAPID(t, r.Header.Get("Authorization"))
+		headers = r.Header
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	store := notify.NewMemorySubscriptionStore()
+	channel, err := notify.NewWebPushChannel(&notify.WebPushConfig{
+		Keys:    keys,
+		Subject: "mailto:ops@example.com",
+		Store:   store,
+		Client:  server.Client(),
+	})
+	require.NoError(t, err)
+
+	live := b.subscription(server.URL + "/live")
+	live.Owner = "user-1"
+	gone := b.subscription(server.URL + "/gone")
+	gone.Owner = "user-1"
+	require.NoError(t, store.Save(context.Background(), live))
+	require.NoError(t, store.Save(context.Background(), gone))
+
+	err = channel.Send(context.Background(), &notify.Notification{To: "user-1", Title: "Hello", Body: "World", URL: "/inbox"})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"title":"Hello","body":"World","url":"/inbox"}`, string(payload))
+	assert.Equal(t, server.URL, claims["aud"])
+	assert.Equal(t, "mailto:ops@example.com", claims["sub"])
+	assert.Equal(t, "aes128gcm", headers.Get("Content-Encoding"))
+	assert.Equal(t, "86400", headers.Get("TTL"))
+	assert.Equal(t, "normal", headers.Get("Urgency"))
+
+	subs, err := store.ForOwner(context.Background(), "user-1")
+	require.NoError(t, err)
+	require.Len(t, subs, 1, "the gone subscription is deleted")
+	assert.Equal(t, live.Endpoint, subs[0].Endpoint)
+}
+
+func TestWebPushChannel_Handlers(t *testing.T) {
+	keys, err := notify.GenerateVAPIDKeys()
+	require.NoError(t, err)
+
+	store := notify.NewMemorySubscriptionStore()
+	channel, err := notify.NewWebPushChannel(&notify.WebPushConfig{
+		Keys:    keys,
+		Subject: "mailto:ops@example.com",
+		Store:   store,
+		Owner: func(r *http.Request) string {
+			return r.Header.Get("X-User")
+		},
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	channel.PublicKeyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/push/key", nil))
+	assert.JSONEq(t, `{"public_key":"`+keys.PublicKey+`"}`, rec.Body.String())
+
+	sub := newBrowser(t).subscription("https://push.example.com/abc")
+	body, err := json.Marshal(sub)
+	require.NoError(t, err)
+
+	subscribe := func(user, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/push/subscriptions", strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		channel.SubscribeHandler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, subscribe("", string(body)))
+	assert.Equal(t, http.StatusBadRequest, subscribe("user-1", `{"endpoint":"http://insecure.example.com"}`))
+	assert.Equal(t, http.StatusCreated, subscribe("user-1", string(body)))
+
+	subs, err := store.ForOwner(context.Background(), "user-1")
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "user-1", subs[0].Owner)
+
+	unsubscribe := func(user string) {
+		req := httptest.NewRequest(http.MethodPost, "/push/subscriptions/delete", strings.NewReader(`{"endpoint":"https://push.example.com/abc"}`))
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		channel.UnsubscribeHandler().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	unsubscribe("user-2")
+	subs, _ = store.ForOwner(context.Background(), "user-1")
+	assert.Len(t, subs, 1, "other users cannot delete the subscription")
+
+	unsubscribe("user-1")
+	subs, _ = store.ForOwner(context.Background(), "user-1")
+	assert.Empty(t, subs)
+}
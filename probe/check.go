@@ -0,0 +1,132 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// Check describes a single endpoint that is requested on every probe interval
+type Check struct {
+	// Name identifies the check in metrics and alerts. Defaults to the method and path.
+	Name string
+	// Method is the HTTP method to use. Defaults to GET.
+	Method string
+	// Path is the path (and optional query string) to request, e.g. "/healthz"
+	Path string
+	// Header contains additional headers sent with the request
+	Header http.Header
+	// ExpectedStatus is the status code that indicates success. Defaults to 200.
+	ExpectedStatus int
+	// Contains, when set, must appear in the response body for the check to succeed
+	Contains string
+	// Timeout is the maximum time the request may take. Defaults to the module timeout.
+	Timeout time.Duration
+}
+
+// Result is the outcome of a single check run
+type Result struct {
+	// Check is the name of the check
+	Check string
+	// StatusCode is the response status code, or zero if the request failed
+	StatusCode int
+	// Latency is the time taken to receive the response
+	Latency time.Duration
+	// Err is set when the check failed
+	Err error
+	// Time is when the check ran
+	Time time.Time
+}
+
+// OK reports whether the check succeeded
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+// normalize fills in check defaults
+func (c Check) normalize(timeout time.Duration) Check {
+	if c.Method == "" {
+		c.Method = http.MethodGet
+	}
+	if c.ExpectedStatus == 0 {
+		c.ExpectedStatus = http.StatusOK
+	}
+	if c.Timeout == 0 {
+		c.Timeout = timeout
+	}
+	if c.Name == "" {
+		c.Name = c.Method + " " + c.Path
+	}
+	return c
+}
+
+// run performs the check. When client is nil, the request is served in-process by handler,
+// which exercises the full middleware stack without going over the network.
+func (c Check) run(ctx context.Context, baseURL string, client *http.Client, handler http.Handler) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	result := Result{Check: c.Name, Time: time.Now()}
+
+	req, err := http.NewRequestWithContext(ctx, c.Method, strings.TrimSuffix(baseURL, "/")+c.Path, nil)
+	if err != nil {
+		result.Err = fmt.Errorf("creating request: %w", err)
+		return result
+	}
+	req.Header.Set("User-Agent", "hop-probe")
+	for key, values := range c.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+
+	var (
+		status int
+		body   string
+	)
+
+	start := time.Now()
+	if client != nil {
+		resp, err := client.Do(req)
+		if err != nil {
+			result.Latency = time.Since(start)
+			result.Err = fmt.Errorf("request failed: %w", err)
+			return result
+		}
+		b, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			result.Latency = time.Since(start)
+			result.Err = fmt.Errorf("reading response: %w", err)
+			return result
+		}
+		status, body = resp.StatusCode, string(b)
+	} else {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		status, body = rec.Code, rec.Body.String()
+	}
+	result.Latency = time.Since(start)
+	result.StatusCode = status
+
+	if err := ctx.Err(); err != nil {
+		result.Err = fmt.Errorf("check timed out: %w", err)
+		return result
+	}
+
+	if status != c.ExpectedStatus {
+		result.Err = fmt.Errorf("unexpected status %d, want %d", status, c.ExpectedStatus)
+		return result
+	}
+
+	if c.Contains != "" && !strings.Contains(body, c.Contains) {
+		result.Err = fmt.Errorf("response does not contain %q", c.Contains)
+		return result
+	}
+
+	return result
+}
@@ -0,0 +1,283 @@
+// Package probe provides a synthetic self-check module that periodically requests internal endpoints
+// through the application's own HTTP stack, records their latency and status, and raises alerts when
+// checks start or stop failing. It acts as a lightweight uptime monitor for single-binary deployments.
+package probe
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/route"
+)
+
+// AlertHandler is called when a check changes state, either from passing to failing (after
+// FailureThreshold consecutive failures) or from failing back to passing.
+type AlertHandler func(result Result, failing bool)
+
+// Config configures the probe module
+type Config struct {
+	// Checks are the endpoints to probe
+	Checks []Check
+	// Interval is how often the checks run. Defaults to 1 minute.
+	Interval time.Duration
+	// Timeout is the default timeout for each check. Defaults to 10 seconds.
+	Timeout time.Duration
+	// BaseURL, when set, sends checks over the network to this URL (e.g. "http://localhost:4444").
+	// When empty, checks are served in-process by the application router, including all middleware.
+	BaseURL string
+	// Client is the HTTP client used when BaseURL is set. Defaults to http.DefaultClient.
+	Client *http.Client
+	// FailureThreshold is the number of consecutive failures before a check is considered failing. Defaults to 1.
+	FailureThreshold int
+	// Collector, when set, records check latency, status, and failures as pulse metrics
+	Collector pulse.Collector
+	// OnAlert is called when a check starts or stops failing
+	OnAlert AlertHandler
+	// Logger is used to log check failures. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// checkState tracks the running state of a single check
+type checkState struct {
+	failures int
+	failing  bool
+	last     Result
+}
+
+// Module implements hop.Module for synthetic endpoint checks
+type Module struct {
+	config  *Config
+	checks  []Check
+	handler http.Handler
+	mu      sync.RWMutex
+	states  map[string]*checkState
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewModule creates a new probe module
+func NewModule(config *Config) *Module {
+	if config == nil {
+		config = &Config{}
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 1
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	if config.BaseURL != "" && config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	checks := make([]Check, 0, len(config.Checks))
+	for _, c := range config.Checks {
+		checks = append(checks, c.normalize(config.Timeout))
+	}
+
+	return &Module{
+		config: config,
+		checks: checks,
+		states: make(map[string]*checkState),
+	}
+}
+
+func (m *Module) ID() string {
+	return "hop.probe"
+}
+
+func (m *Module) Init() error {
+	for _, c := range m.checks {
+		if !strings.HasPrefix(c.Path, "/") {
+			return errors.New("probe: check path must start with /: " + c.Path)
+		}
+	}
+	return nil
+}
+
+// RegisterRoutes captures the application router so checks can be served in-process.
+// The probe module does not register any routes of its own.
+func (m *Module) RegisterRoutes(router *route.Mux) {
+	m.handler = router
+}
+
+// Start begins running the checks on the configured interval
+func (m *Module) Start(ctx context.Context) error {
+	if len(m.checks) == 0 {
+		return nil
+	}
+
+	if m.config.BaseURL == "" && m.handler == nil {
+		return errors.New("probe: no router registered and no base URL configured")
+	}
+
+	done := make(chan struct{})
+	m.done = done
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				m.RunChecks(ctx)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop halts the checks. It is safe to call more than once, and the module can be started again.
+func (m *Module) Stop(_ context.Context) error {
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+	}
+	m.wg.Wait()
+	return nil
+}
+
+// RunChecks runs every check once and returns the results
+func (m *Module) RunChecks(ctx context.Context) []Result {
+	results := make([]Result, 0, len(m.checks))
+	for _, c := range m.checks {
+		result := c.run(ctx, m.config.BaseURL, m.config.Client, m.handler)
+		m.record(result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// Results returns the most recent result of every check that has run
+func (m *Module) Results() []Result {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make([]Result, 0, len(m.checks))
+	for _, c := range m.checks {
+		if state, ok := m.states[c.Name]; ok {
+			results = append(results, state.last)
+		}
+	}
+	return results
+}
+
+// record updates the check state, metrics, and fires alerts on state changes
+func (m *Module) record(result Result) {
+	m.recordMetrics(result)
+
+	m.mu.Lock()
+	state, ok := m.states[result.Check]
+	if !ok {
+		state = &checkState{}
+		m.states[result.Check] = state
+	}
+	state.last = result
+
+	var changed bool
+	if result.OK() {
+		state.failures = 0
+		if state.failing {
+			state.failing = false
+			changed = true
+		}
+	} else {
+		state.failures++
+		if !state.failing && state.failures >= m.config.FailureThreshold {
+			state.failing = true
+			changed = true
+		}
+	}
+	failing := state.failing
+	m.mu.Unlock()
+
+	if !result.OK() {
+		m.config.Logger.Warn("probe check failed",
+			slog.String("check", result.Check),
+			slog.Int("status", result.StatusCode),
+			slog.Duration("latency", result.Latency),
+			slog.String("error", result.Err.Error()))
+	}
+
+	if !changed {
+		return
+	}
+
+	if failing {
+		m.config.Logger.Error("probe check is failing", slog.String("check", result.Check))
+	} else {
+		m.config.Logger.Info("probe check recovered", slog.String("check", result.Check))
+	}
+
+	if m.config.OnAlert != nil {
+		m.config.OnAlert(result, failing)
+	}
+}
+
+// recordMetrics records the result in the pulse collector, if configured
+func (m *Module) recordMetrics(result Result) {
+	if m.config.Collector == nil {
+		return
+	}
+
+	name := metricName(result.Check)
+	m.config.Collector.Histogram("probe_" + name + "_latency_ms").Observe(float64(result.Latency.Milliseconds()))
+	m.config.Collector.Gauge("probe_" + name + "_status").Set(float64(result.StatusCode))
+
+	up := m.config.Collector.Gauge(UpMetric(result.Check))
+	if result.OK() {
+		up.Set(1)
+	} else {
+		up.Set(0)
+		m.config.Collector.Counter("probe_" + name + "_failures_total").Inc()
+	}
+}
+
+// UpMetric returns the name of the pulse gauge that records whether a check is up (1) or down (0)
+func UpMetric(check string) string {
+	return "probe_" + metricName(check) + "_up"
+}
+
+// metricName converts a check name into a snake_case metric name
+func metricName(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			underscore = false
+			continue
+		}
+		if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
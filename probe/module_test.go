@@ -0,0 +1,138 @@
+package probe_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/probe"
+	"github.com/patrickward/hop/route"
+)
+
+func newTestRouter(healthy *bool) *route.Mux {
+	mux := route.New()
+	mux.Get("/healthz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !*healthy {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	return mux
+}
+
+func TestModule_RunChecksInProcess(t *testing.T) {
+	healthy := true
+	mux := newTestRouter(&healthy)
+
+	mod := probe.NewModule(&probe.Config{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Checks: []probe.Check{
+			{Path: "/healthz", Contains: "ok"},
+			{Name: "missing", Path: "/missing"},
+		},
+	})
+	require.NoError(t, mod.Init())
+	mod.RegisterRoutes(mux)
+
+	results := mod.RunChecks(context.Background())
+	require.Len(t, results, 2)
+
+	assert.Equal(t, "GET /healthz", results[0].Check)
+	assert.True(t, results[0].OK())
+	assert.Equal(t, http.StatusOK, results[0].StatusCode)
+
+	assert.Equal(t, "missing", results[1].Check)
+	assert.False(t, results[1].OK())
+	assert.Equal(t, http.StatusNotFound, results[1].StatusCode)
+
+	assert.Equal(t, results, mod.Results())
+}
+
+func TestModule_RunChecksOverNetwork(t *testing.T) {
+	healthy := true
+	srv := httptest.NewServer(newTestRouter(&healthy))
+	defer srv.Close()
+
+	mod := probe.NewModule(&probe.Config{
+		BaseURL: srv.URL,
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Checks:  []probe.Check{{Path: "/healthz"}},
+	})
+	require.NoError(t, mod.Init())
+
+	results := mod.RunChecks(context.Background())
+	require.Len(t, results, 1)
+	assert.True(t, results[0].OK())
+}
+
+func TestModule_Alerts(t *testing.T) {
+	healthy := true
+	mux := newTestRouter(&healthy)
+
+	type alert struct {
+		check   string
+		failing bool
+	}
+	var alerts []alert
+
+	mod := probe.NewModule(&probe.Config{
+		FailureThreshold: 2,
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Checks:           []probe.Check{{Name: "health", Path: "/healthz"}},
+		OnAlert: func(result probe.Result, failing bool) {
+			alerts = append(alerts, alert{check: result.Check, failing: failing})
+		},
+	})
+	require.NoError(t, mod.Init())
+	mod.RegisterRoutes(mux)
+
+	mod.RunChecks(context.Background())
+	assert.Empty(t, alerts, "passing checks should not alert")
+
+	healthy = false
+	mod.RunChecks(context.Background())
+	assert.Empty(t, alerts, "should not alert before the failure threshold")
+
+	mod.RunChecks(context.Background())
+	assert.Equal(t, []alert{{check: "health", failing: true}}, alerts)
+
+	mod.RunChecks(context.Background())
+	assert.Len(t, alerts, 1, "should only alert on state changes")
+
+	healthy = true
+	mod.RunChecks(context.Background())
+	assert.Equal(t, []alert{{check: "health", failing: true}, {check: "health", failing: false}}, alerts)
+}
+
+func TestModule_InitValidatesPaths(t *testing.T) {
+	mod := probe.NewModule(&probe.Config{
+		Checks: []probe.Check{{Path: "healthz"}},
+	})
+	assert.Error(t, mod.Init())
+}
+
+func TestModule_StopTwiceAndRestart(t *testing.T) {
+	healthy := true
+	mod := probe.NewModule(&probe.Config{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Checks: []probe.Check{{Path: "/healthz"}},
+	})
+	require.NoError(t, mod.Init())
+	mod.RegisterRoutes(newTestRouter(&healthy))
+
+	// Stop before Start, as on shutdown after a failed start
+	require.NoError(t, mod.Stop(context.Background()))
+
+	for range 2 {
+		require.NoError(t, mod.Start(context.Background()))
+		require.NoError(t, mod.Stop(context.Background()))
+		require.NoError(t, mod.Stop(context.Background()))
+	}
+}
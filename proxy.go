@@ -0,0 +1,175 @@
+package hop
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/patrickward/hop/route"
+	"github.com/patrickward/hop/route/middleware"
+)
+
+// ProxyProfile describes how a hosting platform's edge proxy forwards requests to the application
+type ProxyProfile struct {
+	// Name is the platform name, e.g. "cloudflare"
+	Name string
+	// TrustedProxies are the networks the platform's proxies connect from
+	TrustedProxies []netip.Prefix
+	// ClientIPHeaders are the headers carrying the client address, in order of preference
+	ClientIPHeaders []string
+	// ProtoHeader carries the scheme of the original request, "http" or "https"
+	ProtoHeader string
+	// RequestIDHeader carries the platform's request ID, which is reused so logs can be correlated with
+	// the platform's logs
+	RequestIDHeader string
+}
+
+// privateNetworks are the loopback and private ranges that platforms running the application inside a
+// private network connect from
+var privateNetworks = []string{
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7",
+}
+
+// proxyProfilesMu guards proxyProfiles, as profiles can be registered while requests are being served
+var proxyProfilesMu sync.RWMutex
+
+// proxyProfiles are the built-in platform profiles and those added with RegisterProxyProfile
+var proxyProfiles = map[string]ProxyProfile{
+	// Cloudflare publishes its ranges at https://www.cloudflare.com/ips/
+	"cloudflare": {
+		Name: "cloudflare",
+		TrustedProxies: middleware.MustParsePrefixes(
+			"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
+			"141.101.64.0/18", "108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20",
+			"197.234.240.0/22", "198.41.128.0/17", "162.158.0.0/15", "104.16.0.0/13",
+			"104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+			"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32",
+			"2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+		),
+		ClientIPHeaders: []string{"CF-Connecting-IP", "X-Forwarded-For"},
+		ProtoHeader:     "X-Forwarded-Proto",
+		RequestIDHeader: "CF-Ray",
+	},
+	// Heroku's router connects from inside the private network and appends to X-Forwarded-For
+	"heroku": {
+		Name:            "heroku",
+		TrustedProxies:  middleware.MustParsePrefixes(privateNetworks...),
+		ClientIPHeaders: []string{"X-Forwarded-For"},
+		ProtoHeader:     "X-Forwarded-Proto",
+		RequestIDHeader: "X-Request-ID",
+	},
+	// Fly's proxy connects over its private network and sets Fly-Client-IP
+	"fly": {
+		Name:            "fly",
+		TrustedProxies:  middleware.MustParsePrefixes(privateNetworks...),
+		ClientIPHeaders: []string{"Fly-Client-IP", "X-Forwarded-For"},
+		ProtoHeader:     "Fly-Forwarded-Proto",
+		RequestIDHeader: "Fly-Request-Id",
+	},
+}
+
+// RegisterProxyProfile adds or replaces a platform profile used by BehindProxy. It is safe to call
+// concurrently; middleware already returned by BehindProxy keeps the profile it was created with.
+func RegisterProxyProfile(profile ProxyProfile) {
+	proxyProfilesMu.Lock()
+	defer proxyProfilesMu.Unlock()
+	proxyProfiles[strings.ToLower(profile.Name)] = profile
+}
+
+// LookupProxyProfile returns the profile of a platform: "cloudflare", "heroku", "fly", or one added with
+// RegisterProxyProfile
+func LookupProxyProfile(platform string) (ProxyProfile, bool) {
+	proxyProfilesMu.RLock()
+	defer proxyProfilesMu.RUnlock()
+	profile, ok := proxyProfiles[strings.ToLower(platform)]
+	return profile, ok
+}
+
+// BehindProxy returns middleware that configures the application for running behind a platform's edge
+// proxy in one step:
+//
+//   - the client IP is resolved from the platform's headers, only when the request comes from the
+//     platform's networks, and is available through middleware.ClientIP
+//   - HTTPS is detected from the platform's scheme header; the request URL scheme and X-Forwarded-Proto
+//     are set accordingly, while forwarded headers sent by untrusted peers are removed
+//   - the platform's request ID is reused as the request ID
+//
+// It panics if the platform is unknown. Register it before other middleware:
+//
+//	app.Router().Use(hop.BehindProxy("fly"))
+func BehindProxy(platform string) route.Middleware {
+	profile, ok := LookupProxyProfile(platform)
+	if !ok {
+		panic(fmt.Sprintf("hop: unknown proxy platform %q, expected one of %s", platform, strings.Join(proxyProfileNames(), ", ")))
+	}
+	return profile.Middleware()
+}
+
+// proxyProfileNames returns the names of the known platform profiles, sorted
+func proxyProfileNames() []string {
+	proxyProfilesMu.RLock()
+	defer proxyProfilesMu.RUnlock()
+
+	names := make([]string, 0, len(proxyProfiles))
+	for name := range proxyProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Middleware returns the middleware described by BehindProxy for the profile
+func (p ProxyProfile) Middleware() route.Middleware {
+	realIP := middleware.RealIP(func(opts *middleware.RealIPOptions) {
+		opts.TrustedProxies = p.TrustedProxies
+		opts.Headers = p.ClientIPHeaders
+	})
+
+	requestID := middleware.RequestID(func(opts *middleware.RequestIDOptions) {
+		if p.RequestIDHeader != "" {
+			opts.Header = p.RequestIDHeader
+		}
+	})
+
+	return func(next http.Handler) http.Handler {
+		inner := realIP(requestID(next))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !p.trusted(r) {
+				// Only the platform's proxies may claim the original scheme, host or request ID
+				r.Header.Del("X-Forwarded-Proto")
+				r.Header.Del("X-Forwarded-Host")
+				r.Header.Del("X-Forwarded-Port")
+				if p.RequestIDHeader != "" {
+					r.Header.Del(p.RequestIDHeader)
+				}
+			} else if proto := strings.ToLower(strings.TrimSpace(r.Header.Get(p.ProtoHeader))); proto == "https" || proto == "http" {
+				r.URL.Scheme = proto
+				r.Header.Set("X-Forwarded-Proto", proto)
+			}
+			inner.ServeHTTP(w, r)
+		})
+	}
+}
+
+// trusted reports whether the request was received from one of the platform's proxies
+func (p ProxyProfile) trusted(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range p.TrustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
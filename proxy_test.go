@@ -0,0 +1,142 @@
+package hop_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/patrickward/hop"
+	"github.com/patrickward/hop/route/middleware"
+)
+
+func TestBehindProxy(t *testing.T) {
+	var gotIP, gotScheme, gotProto, gotID string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIP = middleware.ClientIP(r)
+		gotScheme = r.URL.Scheme
+		gotProto = r.Header.Get("X-Forwarded-Proto")
+		gotID = middleware.RequestIDFromContext(r.Context())
+	})
+
+	tests := []struct {
+		name         string
+		platform     string
+		remoteAddr   string
+		headers      map[string]string
+		expectIP     string
+		expectScheme string
+		expectProto  string
+		expectID     string
+	}{
+		{
+			name:       "cloudflare edge",
+			platform:   "cloudflare",
+			remoteAddr: "104.16.0.10:443",
+			headers: map[string]string{
+				"CF-Connecting-IP":  "203.0.113.7",
+				"X-Forwarded-Proto": "https",
+				"CF-Ray":            "8a1b2c3d4e5f-AMS",
+			},
+			expectIP:     "203.0.113.7",
+			expectScheme: "https",
+			expectProto:  "https",
+			expectID:     "8a1b2c3d4e5f-AMS",
+		},
+		{
+			name:       "cloudflare headers from an untrusted peer",
+			platform:   "cloudflare",
+			remoteAddr: "198.51.100.20:5000",
+			headers: map[string]string{
+				"CF-Connecting-IP":  "203.0.113.7",
+				"X-Forwarded-Proto": "https",
+				"CF-Ray":            "spoofed",
+			},
+			expectIP: "198.51.100.20",
+		},
+		{
+			name:       "heroku router",
+			platform:   "heroku",
+			remoteAddr: "10.1.2.3:1234",
+			headers: map[string]string{
+				"X-Forwarded-For":   "203.0.113.7, 10.1.2.4",
+				"X-Forwarded-Proto": "https",
+				"X-Request-ID":      "heroku-req-1",
+			},
+			expectIP:     "203.0.113.7",
+			expectScheme: "https",
+			expectProto:  "https",
+			expectID:     "heroku-req-1",
+		},
+		{
+			name:       "fly proxy",
+			platform:   "Fly",
+			remoteAddr: "[fdaa:0:1::3]:8080",
+			headers: map[string]string{
+				"Fly-Client-IP":       "2001:db8::7",
+				"Fly-Forwarded-Proto": "https",
+				"Fly-Request-Id":      "01H-fly",
+			},
+			expectIP:     "2001:db8::7",
+			expectScheme: "https",
+			expectProto:  "https",
+			expectID:     "01H-fly",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			gotIP, gotScheme, gotProto, gotID = "", "", "", ""
+			hop.BehindProxy(tt.platform)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expectIP, gotIP)
+			assert.Equal(t, tt.expectScheme, gotScheme)
+			assert.Equal(t, tt.expectProto, gotProto)
+			if tt.expectID != "" {
+				assert.Equal(t, tt.expectID, gotID)
+			} else {
+				assert.NotEmpty(t, gotID)
+				assert.NotEqual(t, "spoofed", gotID)
+			}
+		})
+	}
+
+	t.Run("unknown platform", func(t *testing.T) {
+		assert.Panics(t, func() { hop.BehindProxy("nowhere") })
+	})
+}
+
+func TestRegisterProxyProfile(t *testing.T) {
+	// Profiles can be registered while other goroutines look them up
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			hop.RegisterProxyProfile(hop.ProxyProfile{
+				Name:            fmt.Sprintf("Test-Platform-%d", i),
+				ClientIPHeaders: []string{"X-Forwarded-For"},
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = hop.LookupProxyProfile("fly")
+			assert.NotNil(t, hop.BehindProxy("heroku"))
+		}()
+	}
+	wg.Wait()
+
+	for i := range 8 {
+		profile, ok := hop.LookupProxyProfile(fmt.Sprintf("test-platform-%d", i))
+		assert.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("Test-Platform-%d", i), profile.Name)
+	}
+}
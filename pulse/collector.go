@@ -37,6 +37,29 @@ type Collector interface {
 	Handler() http.Handler
 }
 
+// SLOChecker is implemented by collectors that track service level objectives.
+// The pulse module calls CheckSLOs on every collection interval so alerts fire without a dashboard visit.
+type SLOChecker interface {
+	CheckSLOs()
+}
+
+// RenderRecorder is implemented by collectors that track the size and duration of rendered templates.
+// The render package reports every page it renders, so the heaviest pages can be found.
+type RenderRecorder interface {
+	// RecordRender records a rendered template. Size is the uncompressed body size in bytes, and compressed
+	// reports whether the response was sent with a Content-Encoding.
+	RecordRender(path string, size int, duration time.Duration, compressed bool)
+}
+
+// TemplateRecorder is implemented by render recorders that also track the template cache and template
+// errors. The render package checks for it on the RenderRecorder it is given.
+type TemplateRecorder interface {
+	// RecordTemplateCache records a template lookup, and whether the parsed template was cached
+	RecordTemplateCache(path string, hit bool)
+	// RecordTemplateError records a template that failed to load or execute
+	RecordTemplateError(path string, err error)
+}
+
 // Counter is for cumulative metrics that only increase
 type Counter interface {
 	Inc()
@@ -0,0 +1,251 @@
+package pulse
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// PercentileHistogram is implemented by histograms that can compute percentiles of their observations
+type PercentileHistogram interface {
+	Histogram
+	// Percentile returns the value below which p percent of the observations fall, with p from 0 to 100
+	Percentile(p float64) float64
+}
+
+// HDROptions configures an HDRHistogram
+type HDROptions struct {
+	// SignificantFigures is the number of significant decimal digits kept for each value, from 1 to 5.
+	// Percentiles are within 10^-SignificantFigures of the true value, e.g. 0.1% for the default of 3.
+	SignificantFigures int
+	// Resolution is the number of recorded units per observed unit. Values are recorded as whole units, so
+	// the default of 1000 records milliseconds with microsecond resolution.
+	Resolution float64
+}
+
+// HistogramSnapshot is a point-in-time copy of an HDRHistogram, for export to other systems
+type HistogramSnapshot struct {
+	Count uint64  `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	P999  float64 `json:"p999"`
+	// Buckets are the non-empty buckets in ascending order
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+// HistogramBucket is the number of observations at or below an upper bound, and above the previous bucket's
+type HistogramBucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+// HDRHistogram is a high dynamic range histogram. It keeps every observation in log-linear buckets, so
+// percentiles are accurate to a fixed number of significant figures across the full runtime rather than
+// over a window of recent samples, in memory that grows with the logarithm of the largest value.
+type HDRHistogram struct {
+	mu         sync.Mutex
+	resolution float64
+
+	// Bucket layout, as in the original HdrHistogram: each bucket covers twice the range of the previous
+	// one with the same number of sub-buckets
+	subBucketHalfCountMagnitude int
+	subBucketHalfCount          int64
+	subBucketMask               uint64
+
+	counts []uint64
+	count  uint64
+	sum    float64
+	min    int64
+	max    int64
+}
+
+// NewHDRHistogram creates an HDRHistogram
+func NewHDRHistogram(optsFunc func(opts *HDROptions)) *HDRHistogram {
+	opts := &HDROptions{
+		SignificantFigures: 3,
+		Resolution:         1000,
+	}
+
+	if optsFunc != nil {
+		optsFunc(opts)
+	}
+
+	if opts.SignificantFigures < 1 || opts.SignificantFigures > 5 {
+		opts.SignificantFigures = 3
+	}
+
+	if opts.Resolution <= 0 {
+		opts.Resolution = 1000
+	}
+
+	// The sub-buckets must distinguish values that differ in the last significant figure
+	largestSingleUnitValue := 2 * int64(math.Pow10(opts.SignificantFigures))
+	subBucketCountMagnitude := bits.Len64(uint64(largestSingleUnitValue - 1))
+	subBucketCount := int64(1) << subBucketCountMagnitude
+
+	return &HDRHistogram{
+		resolution:                  opts.Resolution,
+		subBucketHalfCountMagnitude: subBucketCountMagnitude - 1,
+		subBucketHalfCount:          subBucketCount / 2,
+		subBucketMask:               uint64(subBucketCount - 1),
+		min:                         math.MaxInt64,
+	}
+}
+
+// Observe records a new observation. Negative values are recorded as zero.
+func (h *HDRHistogram) Observe(value float64) {
+	v := int64(0)
+	if value > 0 {
+		v = int64(math.Min(math.Round(value*h.resolution), math.MaxInt64/2))
+	}
+
+	idx := h.countsIndex(v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if idx >= len(h.counts) {
+		// Grow to the end of the value's bucket, so neighbouring values don't grow it again
+		size := (idx>>h.subBucketHalfCountMagnitude + 1) << h.subBucketHalfCountMagnitude
+		counts := make([]uint64, size)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+
+	h.counts[idx]++
+	h.count++
+	h.sum += max(value, 0)
+	h.min = min(h.min, v)
+	h.max = max(h.max, v)
+}
+
+// Count returns the number of observations
+func (h *HDRHistogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// Sum returns the sum of all observations
+func (h *HDRHistogram) Sum() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sum
+}
+
+// Percentile returns the value below which p percent of the observations fall, with p from 0 to 100.
+// It returns 0 when there are no observations.
+func (h *HDRHistogram) Percentile(p float64) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.percentile(p)
+}
+
+// Snapshot returns a copy of the histogram's statistics and non-empty buckets
+func (h *HDRHistogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := HistogramSnapshot{
+		Count: h.count,
+		Sum:   h.sum,
+		P50:   h.percentile(50),
+		P90:   h.percentile(90),
+		P95:   h.percentile(95),
+		P99:   h.percentile(99),
+		P999:  h.percentile(99.9),
+	}
+
+	if h.count == 0 {
+		return s
+	}
+
+	s.Min = float64(h.lowestEquivalentValue(h.min)) / h.resolution
+	s.Max = float64(h.highestEquivalentValue(h.max)) / h.resolution
+	s.Mean = h.sum / float64(h.count)
+
+	for idx, count := range h.counts {
+		if count > 0 {
+			s.Buckets = append(s.Buckets, HistogramBucket{
+				UpperBound: float64(h.highestEquivalentValue(h.valueFromIndex(idx))) / h.resolution,
+				Count:      count,
+			})
+		}
+	}
+
+	return s
+}
+
+// Reset removes all observations
+func (h *HDRHistogram) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clear(h.counts)
+	h.count = 0
+	h.sum = 0
+	h.min = math.MaxInt64
+	h.max = 0
+}
+
+// percentile returns the highest value equivalent to the observation at percentile p
+func (h *HDRHistogram) percentile(p float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+
+	p = math.Min(math.Max(p, 0), 100)
+	target := uint64(math.Ceil(p / 100 * float64(h.count)))
+	target = max(target, 1)
+
+	var seen uint64
+	for idx, count := range h.counts {
+		seen += count
+		if seen >= target {
+			v := h.highestEquivalentValue(h.valueFromIndex(idx))
+			return float64(min(v, h.max)) / h.resolution
+		}
+	}
+	return float64(h.max) / h.resolution
+}
+
+// countsIndex returns the index in counts of the bucket holding v
+func (h *HDRHistogram) countsIndex(v int64) int {
+	bucketIdx := h.bucketIndex(v)
+	subBucketIdx := v >> uint(bucketIdx)
+	return int((int64(bucketIdx)+1)<<h.subBucketHalfCountMagnitude + subBucketIdx - h.subBucketHalfCount)
+}
+
+// bucketIndex returns the power of two bucket holding v
+func (h *HDRHistogram) bucketIndex(v int64) int {
+	pow2Ceiling := bits.Len64(uint64(v) | h.subBucketMask)
+	return pow2Ceiling - (h.subBucketHalfCountMagnitude + 1)
+}
+
+// valueFromIndex returns the lowest value in the bucket at index idx of counts
+func (h *HDRHistogram) valueFromIndex(idx int) int64 {
+	bucketIdx := (idx >> h.subBucketHalfCountMagnitude) - 1
+	subBucketIdx := int64(idx)&(h.subBucketHalfCount-1) + h.subBucketHalfCount
+	if bucketIdx < 0 {
+		subBucketIdx -= h.subBucketHalfCount
+		bucketIdx = 0
+	}
+	return subBucketIdx << uint(bucketIdx)
+}
+
+// lowestEquivalentValue returns the lowest value recorded in the same bucket as v
+func (h *HDRHistogram) lowestEquivalentValue(v int64) int64 {
+	bucketIdx := h.bucketIndex(v)
+	return (v >> uint(bucketIdx)) << uint(bucketIdx)
+}
+
+// highestEquivalentValue returns the highest value recorded in the same bucket as v
+func (h *HDRHistogram) highestEquivalentValue(v int64) int64 {
+	return h.lowestEquivalentValue(v) + int64(1)<<uint(h.bucketIndex(v)) - 1
+}
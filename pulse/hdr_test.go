@@ -0,0 +1,97 @@
+package pulse_test
+
+import (
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/pulse"
+)
+
+func TestHDRHistogram_Percentile(t *testing.T) {
+	h := pulse.NewHDRHistogram(nil)
+	assert.Zero(t, h.Percentile(99), "empty histogram")
+
+	// 1ms to 10s in 1ms steps
+	for i := 1; i <= 10000; i++ {
+		h.Observe(float64(i))
+	}
+
+	assert.Equal(t, uint64(10000), h.Count())
+	assert.InDelta(t, 50005000, h.Sum(), 1e-6)
+	for _, p := range []float64{50, 90, 95, 99, 99.9, 100} {
+		assert.InEpsilon(t, p*100, h.Percentile(p), 0.001, "p%v", p)
+	}
+	assert.InEpsilon(t, 1, h.Percentile(0), 0.001)
+}
+
+func TestHDRHistogram_Accuracy(t *testing.T) {
+	h := pulse.NewHDRHistogram(func(opts *pulse.HDROptions) { opts.SignificantFigures = 2 })
+
+	// Log-normal latencies from fractions of a millisecond to minutes
+	rng := rand.New(rand.NewPCG(1, 2))
+	values := make([]float64, 100000)
+	for i := range values {
+		values[i] = rng.ExpFloat64() * rng.ExpFloat64() * 50
+		h.Observe(values[i])
+	}
+	sort.Float64s(values)
+
+	for _, p := range []float64{50, 95, 99, 99.9} {
+		want := values[int(p/100*float64(len(values)))-1]
+		assert.InEpsilon(t, want, h.Percentile(p), 0.01, "p%v", p)
+	}
+}
+
+func TestHDRHistogram_Snapshot(t *testing.T) {
+	h := pulse.NewHDRHistogram(func(opts *pulse.HDROptions) { opts.Resolution = 1 })
+	assert.Equal(t, pulse.HistogramSnapshot{}, h.Snapshot())
+
+	h.Observe(-5)
+	h.Observe(3)
+	h.Observe(3)
+	h.Observe(5000)
+
+	s := h.Snapshot()
+	assert.Equal(t, uint64(4), s.Count)
+	assert.InDelta(t, 5006, s.Sum, 1e-9)
+	assert.Zero(t, s.Min, "negative values are recorded as zero")
+	assert.InEpsilon(t, 5000, s.Max, 0.001)
+	assert.InDelta(t, 1251.5, s.Mean, 1e-9)
+	assert.Equal(t, float64(3), s.P50)
+	assert.InEpsilon(t, 5000, s.P99, 0.001)
+
+	require.Len(t, s.Buckets, 3)
+	assert.Equal(t, pulse.HistogramBucket{UpperBound: 0, Count: 1}, s.Buckets[0])
+	assert.Equal(t, pulse.HistogramBucket{UpperBound: 3, Count: 2}, s.Buckets[1])
+	assert.Equal(t, uint64(1), s.Buckets[2].Count)
+	assert.GreaterOrEqual(t, s.Buckets[2].UpperBound, float64(5000))
+
+	h.Reset()
+	assert.Equal(t, pulse.HistogramSnapshot{}, h.Snapshot())
+	h.Observe(7)
+	assert.Equal(t, float64(7), h.Snapshot().Min)
+}
+
+func TestHDRHistogram_Concurrent(t *testing.T) {
+	h := pulse.NewHDRHistogram(nil)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				h.Observe(float64(i))
+				_ = h.Percentile(99)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(8000), h.Count())
+}
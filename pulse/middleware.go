@@ -0,0 +1,155 @@
+package pulse
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/route"
+)
+
+// HTTPCountRecorder is implemented by collectors that can record an HTTP request without observing its
+// duration. The metrics middleware uses it for requests whose observation is skipped, such as static assets,
+// so they still count towards the request and error totals.
+type HTTPCountRecorder interface {
+	RecordHTTPRequestCount(method, path string, statusCode int)
+}
+
+// MiddlewareOptions configures the metrics middleware
+type MiddlewareOptions struct {
+	// UnobservedPrefixes are path prefixes, such as "/static/", whose requests are counted but not observed
+	// in the duration histogram, response time percentiles or SLOs
+	UnobservedPrefixes []string
+	// SkipObservation reports whether a request is counted but not observed, for rules the prefixes can't
+	// express. Handlers can also skip observation of their own request with SkipObservation.
+	SkipObservation func(r *http.Request) bool
+}
+
+// Middleware returns route.Middleware that records the method, status and duration of every request with
+// the collector. It is built for the hot path: response writer wrappers are pooled, and a request doesn't
+// allocate beyond what the collector does.
+//
+// Handlers must not keep the response writer after they return, as the wrapper is reused.
+func Middleware(collector Collector, optsFunc func(opts *MiddlewareOptions)) route.Middleware {
+	opts := &MiddlewareOptions{}
+	if optsFunc != nil {
+		optsFunc(opts)
+	}
+
+	counter, _ := collector.(HTTPCountRecorder)
+	prefixes := append([]string(nil), opts.UnobservedPrefixes...)
+
+	observe := func(r *http.Request) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return false
+			}
+		}
+		return opts.SkipObservation == nil || !opts.SkipObservation(r)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			// Track concurrent requests
+			collector.IncrementConcurrentRequests()
+			defer collector.DecrementConcurrentRequests()
+
+			// Wrap response writer to capture status code
+			rw := acquireResponseWriter(w)
+			rw.observe = observe(r)
+			defer releaseResponseWriter(rw)
+
+			next.ServeHTTP(rw, r)
+
+			switch {
+			case rw.observe:
+				collector.RecordHTTPRequest(r.Method, r.URL.Path, time.Since(start), rw.statusCode)
+			case counter != nil:
+				counter.RecordHTTPRequestCount(r.Method, r.URL.Path, rw.statusCode)
+			}
+		})
+	}
+}
+
+// SkipObservation makes the metrics middleware count the request written to w without observing its
+// duration. Call it from a handler, or use NoObservation on a route or group.
+func SkipObservation(w http.ResponseWriter) {
+	for {
+		switch rw := w.(type) {
+		case *responseWriter:
+			rw.observe = false
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+// NoObservation returns route.Middleware that skips duration observation for the routes it wraps, e.g. a
+// group serving static assets. The requests are still counted.
+func NoObservation() route.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			SkipObservation(w)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// responseWriterPool holds response writer wrappers for reuse across requests
+var responseWriterPool = sync.Pool{
+	New: func() any { return &responseWriter{} },
+}
+
+// responseWriter wraps http.ResponseWriter to capture status code
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	observe     bool
+}
+
+func acquireResponseWriter(w http.ResponseWriter) *responseWriter {
+	rw := responseWriterPool.Get().(*responseWriter)
+	rw.ResponseWriter = w
+	rw.statusCode = http.StatusOK
+	rw.wroteHeader = false
+	rw.observe = true
+	return rw
+}
+
+func releaseResponseWriter(rw *responseWriter) {
+	rw.ResponseWriter = nil
+	responseWriterPool.Put(rw)
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader && code >= http.StatusOK {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush sends buffered data to the client, if the underlying writer supports it
+func (rw *responseWriter) Flush() {
+	rw.wroteHeader = true
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,226 @@
+package pulse_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/pulse"
+)
+
+// request is an HTTP request recorded by a collector
+type request struct {
+	method, path string
+	status       int
+}
+
+// requestCollector is a pulse.Collector recording the requests it is given
+type requestCollector struct {
+	pulse.Collector
+	mu         sync.Mutex
+	observed   []request
+	concurrent int
+}
+
+func (c *requestCollector) RecordHTTPRequest(method, path string, _ time.Duration, status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observed = append(c.observed, request{method, path, status})
+}
+
+func (c *requestCollector) IncrementConcurrentRequests() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.concurrent++
+}
+
+func (c *requestCollector) DecrementConcurrentRequests() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.concurrent--
+}
+
+// countingCollector is a requestCollector that also records requests without observing them
+type countingCollector struct {
+	requestCollector
+	counted []request
+}
+
+func (c *countingCollector) RecordHTTPRequestCount(method, path string, status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counted = append(c.counted, request{method, path, status})
+}
+
+// wrappingWriter is a response writer wrapper from another middleware
+type wrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func serve(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	collector := &requestCollector{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/created", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusConflict)
+	})
+	mux.HandleFunc("/written", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+		w.WriteHeader(http.StatusTeapot)
+	})
+	mux.HandleFunc("/flushed", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, http.NewResponseController(w).Flush())
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := pulse.Middleware(collector, nil)(mux)
+
+	serve(handler, http.MethodPost, "/created")
+	serve(handler, http.MethodGet, "/written")
+	rec := serve(handler, http.MethodGet, "/flushed")
+	assert.True(t, rec.Flushed, "flushes reach the underlying writer")
+
+	assert.Equal(t, []request{
+		{http.MethodPost, "/created", http.StatusCreated},
+		{http.MethodGet, "/written", http.StatusOK},
+		{http.MethodGet, "/flushed", http.StatusOK},
+	}, collector.observed)
+	assert.Zero(t, collector.concurrent)
+}
+
+func TestMiddleware_PooledWritersAreReset(t *testing.T) {
+	collector := &countingCollector{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/skipped", func(w http.ResponseWriter, r *http.Request) {
+		pulse.SkipObservation(w)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {})
+	handler := pulse.Middleware(collector, nil)(mux)
+
+	// Each request reuses the wrapper released by the previous one, which must not carry over its
+	// status or skipped observation
+	for range 3 {
+		serve(handler, http.MethodGet, "/skipped")
+		serve(handler, http.MethodGet, "/empty")
+	}
+
+	skipped := request{http.MethodGet, "/skipped", http.StatusNotFound}
+	empty := request{http.MethodGet, "/empty", http.StatusOK}
+	assert.Equal(t, []request{empty, empty, empty}, collector.observed)
+	assert.Equal(t, []request{skipped, skipped, skipped}, collector.counted)
+}
+
+func TestMiddleware_SkipObservation(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(opts *pulse.MiddlewareOptions)
+		handler http.Handler
+		path    string
+	}{
+		{
+			name: "unobserved prefix",
+			opts: func(opts *pulse.MiddlewareOptions) { opts.UnobservedPrefixes = []string{"/static/"} },
+			path: "/static/app.css",
+		},
+		{
+			name: "skip observation option",
+			opts: func(opts *pulse.MiddlewareOptions) {
+				opts.SkipObservation = func(r *http.Request) bool { return r.URL.Query().Has("skip") }
+			},
+			path: "/page?skip",
+		},
+		{
+			name: "skipped by the handler",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pulse.SkipObservation(&wrappingWriter{w})
+			}),
+			path: "/page",
+		},
+		{
+			name:    "no observation middleware",
+			handler: pulse.NoObservation()(http.NotFoundHandler()),
+			path:    "/page",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.handler
+			if handler == nil {
+				handler = http.NotFoundHandler()
+			}
+
+			collector := &countingCollector{}
+			serve(pulse.Middleware(collector, tt.opts)(handler), http.MethodGet, tt.path)
+			assert.Empty(t, collector.observed)
+			require.Len(t, collector.counted, 1)
+			assert.Equal(t, http.MethodGet, collector.counted[0].method)
+			assert.Zero(t, collector.concurrent)
+
+			// Collectors that can't count requests without observing them don't record them at all
+			plain := &requestCollector{}
+			serve(pulse.Middleware(plain, tt.opts)(handler), http.MethodGet, tt.path)
+			assert.Empty(t, plain.observed)
+		})
+	}
+
+	t.Run("other requests are observed", func(t *testing.T) {
+		collector := &countingCollector{}
+		handler := pulse.Middleware(collector, func(opts *pulse.MiddlewareOptions) {
+			opts.UnobservedPrefixes = []string{"/static/"}
+			opts.SkipObservation = func(r *http.Request) bool { return false }
+		})(http.NotFoundHandler())
+
+		serve(handler, http.MethodGet, "/page")
+		assert.Equal(t, []request{{http.MethodGet, "/page", http.StatusNotFound}}, collector.observed)
+		assert.Empty(t, collector.counted)
+	})
+}
+
+// nopCollector is a pulse.Collector that records nothing
+type nopCollector struct {
+	pulse.Collector
+}
+
+func (nopCollector) RecordHTTPRequest(string, string, time.Duration, int) {}
+func (nopCollector) IncrementConcurrentRequests()                         {}
+func (nopCollector) DecrementConcurrentRequests()                         {}
+
+// nopWriter is a response writer that discards the response
+type nopWriter struct {
+	header http.Header
+}
+
+func (w *nopWriter) Header() http.Header         { return w.header }
+func (w *nopWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *nopWriter) WriteHeader(int)             {}
+
+func TestMiddleware_Allocations(t *testing.T) {
+	handler := pulse.Middleware(nopCollector{}, func(opts *pulse.MiddlewareOptions) {
+		opts.UnobservedPrefixes = []string{"/static/"}
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	w := &nopWriter{header: make(http.Header)}
+	r := httptest.NewRequest(http.MethodGet, "/page", nil)
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		handler.ServeHTTP(w, r)
+	})
+	assert.Zero(t, allocs, "the middleware should not allocate")
+}
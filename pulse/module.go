@@ -29,6 +29,11 @@ type Config struct {
 	EnablePprof bool
 	// CollectionInterval is how often to collect system metrics
 	CollectionInterval time.Duration
+	// UnobservedPrefixes are path prefixes, such as "/static/", whose requests are counted but not
+	// observed in the response time metrics
+	UnobservedPrefixes []string
+	// SkipObservation reports whether a request is counted but not observed in the response time metrics
+	SkipObservation func(r *http.Request) bool
 }
 
 func NewModule(collector Collector, config *Config) *Module {
@@ -51,7 +56,6 @@ func NewModule(collector Collector, config *Config) *Module {
 	return &Module{
 		collector: collector,
 		config:    config,
-		done:      make(chan struct{}),
 	}
 }
 
@@ -92,17 +96,22 @@ func (m *Module) Start(ctx context.Context) error {
 	m.collector.RecordGoroutineCount()
 
 	m.ticker = time.NewTicker(m.config.CollectionInterval)
+	ticker, done := m.ticker, make(chan struct{})
+	m.done = done
 
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-m.done:
+			case <-done:
 				return
-			case <-m.ticker.C:
+			case <-ticker.C:
 				m.collector.RecordMemStats()
 				m.collector.RecordGoroutineCount()
+				if checker, ok := m.collector.(SLOChecker); ok {
+					checker.CheckSLOs()
+				}
 			}
 		}
 	}()
@@ -110,34 +119,24 @@ func (m *Module) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop halts metric collection
+// Stop halts metric collection. The module can be started again.
 func (m *Module) Stop(ctx context.Context) error {
 	if m.ticker != nil {
 		m.ticker.Stop()
 	}
-	close(m.done)
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+	}
 	return nil
 }
 
-// MetricsMiddleware creates route.Middleware for collecting HTTP metrics
+// MetricsMiddleware creates route.Middleware for collecting HTTP metrics. See Middleware.
 func (m *Module) MetricsMiddleware() route.Middleware {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-
-			// Track concurrent requests
-			m.collector.IncrementConcurrentRequests()
-			defer m.collector.DecrementConcurrentRequests()
-
-			// Wrap response writer to capture status code
-			rw := newResponseWriter(w)
-
-			next.ServeHTTP(rw, r)
-
-			duration := time.Since(start)
-			m.collector.RecordHTTPRequest(r.Method, r.URL.Path, duration, rw.statusCode)
-		})
-	}
+	return Middleware(m.collector, func(opts *MiddlewareOptions) {
+		opts.UnobservedPrefixes = m.config.UnobservedPrefixes
+		opts.SkipObservation = m.config.SkipObservation
+	})
 }
 
 // AuthMiddleware creates route.Middleware for authenticating requests to the metrics endpoint
@@ -166,21 +165,6 @@ func (m *Module) AuthMiddleware() route.Middleware {
 	}
 }
 
-// responseWriter wraps http.ResponseWriter to capture status code
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-func newResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-}
-
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
-
 func unauthorized(w http.ResponseWriter) {
 	w.Header().Set("WWW-Authenticate", `Basic realm="Pulse Restricted"`)
 	http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -0,0 +1,171 @@
+package pulse
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// heaviestPagesLimit is the number of pages shown in the dashboard's heaviest pages table
+const heaviestPagesLimit = 10
+
+// RenderStat summarizes the renders of a template path
+type RenderStat struct {
+	// Path is the template path, e.g. "views/home"
+	Path string
+	// Renders is the number of times the template was rendered
+	Renders uint64
+	// Compressed is the number of renders sent with a Content-Encoding
+	Compressed uint64
+	// TotalBytes is the uncompressed size of all renders
+	TotalBytes uint64
+	// MaxBytes is the largest render
+	MaxBytes int
+	// TotalDuration is the time spent rendering and writing the template
+	TotalDuration time.Duration
+	// CacheHits is the number of renders that used the cached parsed template
+	CacheHits uint64
+	// CacheMisses is the number of renders that parsed the template
+	CacheMisses uint64
+	// Errors is the number of renders that failed to load or execute the template
+	Errors uint64
+}
+
+// AvgBytes returns the average uncompressed size of a render
+func (s RenderStat) AvgBytes() float64 {
+	if s.Renders == 0 {
+		return 0
+	}
+	return float64(s.TotalBytes) / float64(s.Renders)
+}
+
+// AvgDuration returns the average duration of a render
+func (s RenderStat) AvgDuration() time.Duration {
+	if s.Renders == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Renders)
+}
+
+// CacheHitRate returns the share of template lookups served from the cache, from 0 to 1
+func (s RenderStat) CacheHitRate() float64 {
+	lookups := s.CacheHits + s.CacheMisses
+	if lookups == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(lookups)
+}
+
+// renderStats tracks RenderStat per template path
+type renderStats struct {
+	mu    sync.Mutex
+	paths map[string]*RenderStat
+}
+
+// stat returns the RenderStat of path, creating it if needed; mu must be held
+func (s *renderStats) stat(path string) *RenderStat {
+	if s.paths == nil {
+		s.paths = make(map[string]*RenderStat)
+	}
+	stat, ok := s.paths[path]
+	if !ok {
+		stat = &RenderStat{Path: path}
+		s.paths[path] = stat
+	}
+	return stat
+}
+
+// RecordRender records a rendered template, implementing RenderRecorder
+func (c *StandardCollector) RecordRender(path string, size int, duration time.Duration, compressed bool) {
+	c.renders.mu.Lock()
+	defer c.renders.mu.Unlock()
+
+	stat := c.renders.stat(path)
+	stat.Renders++
+	stat.TotalBytes += uint64(size)
+	stat.TotalDuration += duration
+	if size > stat.MaxBytes {
+		stat.MaxBytes = size
+	}
+	if compressed {
+		stat.Compressed++
+	}
+}
+
+// RecordTemplateCache records a template lookup, implementing TemplateRecorder
+func (c *StandardCollector) RecordTemplateCache(path string, hit bool) {
+	c.renders.mu.Lock()
+	defer c.renders.mu.Unlock()
+
+	stat := c.renders.stat(path)
+	if hit {
+		stat.CacheHits++
+	} else {
+		stat.CacheMisses++
+	}
+}
+
+// RecordTemplateError records a template that failed to load or execute, implementing TemplateRecorder
+func (c *StandardCollector) RecordTemplateError(path string, err error) {
+	c.renders.mu.Lock()
+	defer c.renders.mu.Unlock()
+
+	c.renders.stat(path).Errors++
+}
+
+// RenderStats returns the render statistics of every template path, heaviest (by average size) first
+func (c *StandardCollector) RenderStats() []RenderStat {
+	c.renders.mu.Lock()
+	stats := make([]RenderStat, 0, len(c.renders.paths))
+	for _, stat := range c.renders.paths {
+		stats = append(stats, *stat)
+	}
+	c.renders.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].AvgBytes() != stats[j].AvgBytes() {
+			return stats[i].AvgBytes() > stats[j].AvgBytes()
+		}
+		return stats[i].Path < stats[j].Path
+	})
+	return stats
+}
+
+// renderRow is a row of the dashboard's heaviest pages table
+type renderRow struct {
+	Path        string
+	Renders     string
+	AvgSize     string
+	MaxSize     string
+	AvgDuration string
+	Compressed  string
+	CacheHits   string
+	Errors      string
+}
+
+func (c *StandardCollector) formatRenderMetrics() []renderRow {
+	stats := c.RenderStats()
+	if len(stats) > heaviestPagesLimit {
+		stats = stats[:heaviestPagesLimit]
+	}
+
+	rows := make([]renderRow, 0, len(stats))
+	for _, stat := range stats {
+		compressed := 0.0
+		if stat.Renders > 0 {
+			compressed = float64(stat.Compressed) / float64(stat.Renders) * 100
+		}
+		rows = append(rows, renderRow{
+			Path:        stat.Path,
+			Renders:     formatCount(float64(stat.Renders)),
+			AvgSize:     formatBytes(stat.AvgBytes()),
+			MaxSize:     formatBytes(float64(stat.MaxBytes)),
+			AvgDuration: formatDuration(float64(stat.AvgDuration().Microseconds()) / 1000),
+			Compressed:  fmt.Sprintf("%.0f%%", compressed),
+			CacheHits:   fmt.Sprintf("%.0f%%", stat.CacheHitRate()*100),
+			Errors:      formatCount(float64(stat.Errors)),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,315 @@
+package pulse
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sloShortWindow is the short window used for burn-rate calculations
+	sloShortWindow = 5 * time.Minute
+	// sloLongWindow is the long window used for burn-rate calculations
+	sloLongWindow = time.Hour
+	// sloDefaultWindow is the default window over which the error budget is measured
+	sloDefaultWindow = 24 * time.Hour
+
+	// sloCriticalBurnRate is the burn rate at which the budget for a 30-day period would be spent in about 2 days
+	sloCriticalBurnRate = 14.4
+	// sloWarningBurnRate is the burn rate at which the budget for a 30-day period would be spent in about 5 days
+	sloWarningBurnRate = 6.0
+)
+
+// SLO defines a service level objective for a group of routes.
+//
+// An SLO may define an availability target, a latency target, or both. A request counts against the
+// availability objective when it results in a 5xx status code, and against the latency objective when it
+// takes longer than LatencyThreshold.
+type SLO struct {
+	// Name is the display name of the objective (e.g. "API")
+	Name string
+	// PathPrefix limits the objective to requests whose path starts with this prefix. Empty matches all requests.
+	PathPrefix string
+	// AvailabilityTarget is the percentage of requests that must succeed (e.g. 99.9). Zero disables the objective.
+	AvailabilityTarget float64
+	// LatencyThreshold is the duration under which a request is considered fast enough
+	LatencyThreshold time.Duration
+	// LatencyTarget is the percentage of requests that must complete within LatencyThreshold (e.g. 99.0).
+	// Zero disables the objective.
+	LatencyTarget float64
+	// Window is the period over which the error budget is measured. Defaults to 24 hours.
+	Window time.Duration
+}
+
+// SLOStatus reports the current state of a single SLO objective
+type SLOStatus struct {
+	// Name is the name of the SLO
+	Name string
+	// Objective is either "availability" or "latency"
+	Objective string
+	// Target is the objective's target percentage
+	Target float64
+	// Total is the number of requests observed within the window
+	Total uint64
+	// Bad is the number of requests that did not meet the objective within the window
+	Bad uint64
+	// SLI is the percentage of good requests within the window
+	SLI float64
+	// BudgetRemaining is the percentage of the error budget left within the window. It may be negative
+	// once the budget has been exhausted.
+	BudgetRemaining float64
+	// ShortBurnRate is the rate at which the error budget is being spent over the last 5 minutes
+	ShortBurnRate float64
+	// LongBurnRate is the rate at which the error budget is being spent over the last hour
+	LongBurnRate float64
+	// Level is the alert level derived from the burn rates and remaining budget
+	Level ThresholdLevel
+}
+
+// SLOAlertHandler is called when the alert level of an SLO objective changes
+type SLOAlertHandler func(status SLOStatus)
+
+// WithSLOs sets the service level objectives tracked by the collector
+func WithSLOs(slos ...SLO) StandardCollectorOption {
+	return func(c *StandardCollector) {
+		for _, slo := range slos {
+			c.slos = append(c.slos, newSLOTracker(slo))
+		}
+	}
+}
+
+// WithSLOAlertHandler sets a handler that is called whenever an SLO objective changes alert level
+func WithSLOAlertHandler(handler SLOAlertHandler) StandardCollectorOption {
+	return func(c *StandardCollector) {
+		c.sloAlertHandler = handler
+	}
+}
+
+// SLOStatuses returns the current status of every tracked SLO objective
+func (c *StandardCollector) SLOStatuses() []SLOStatus {
+	now := c.clock.Now()
+	var statuses []SLOStatus
+	for _, t := range c.slos {
+		statuses = append(statuses, t.statuses(now)...)
+	}
+	return statuses
+}
+
+// CheckSLOs evaluates all SLO objectives and calls the alert handler for any objective whose
+// alert level has changed since the last check.
+func (c *StandardCollector) CheckSLOs() {
+	now := c.clock.Now()
+	for _, t := range c.slos {
+		for _, status := range t.statuses(now) {
+			if t.levelChanged(status.Objective, status.Level) && c.sloAlertHandler != nil {
+				c.sloAlertHandler(status)
+			}
+		}
+	}
+}
+
+// recordSLOs records a request against every SLO matching the request path
+func (c *StandardCollector) recordSLOs(path string, duration time.Duration, statusCode int) {
+	if len(c.slos) == 0 {
+		return
+	}
+
+	now := c.clock.Now()
+	for _, t := range c.slos {
+		if strings.HasPrefix(path, t.slo.PathPrefix) {
+			t.record(now, duration, statusCode)
+		}
+	}
+}
+
+func (c *StandardCollector) formatSLOMetrics() []metricData {
+	var metrics []metricData
+	for _, status := range c.SLOStatuses() {
+		metrics = append(metrics, metricData{
+			Name:        fmt.Sprintf("%s (%s)", status.Name, status.Objective),
+			Value:       fmt.Sprintf("%.3f%% (%.1f%% budget left)", status.SLI, status.BudgetRemaining),
+			Description: fmt.Sprintf("%s of %s requests met the objective. Burn rate is %.1fx over 5 minutes and %.1fx over 1 hour.", formatCount(float64(status.Total-status.Bad)), formatCount(float64(status.Total)), status.ShortBurnRate, status.LongBurnRate),
+			Level:       status.Level,
+			Threshold:   fmt.Sprintf("%.3f%%", status.Target),
+		})
+	}
+	return metrics
+}
+
+// sloTracker tracks the good and bad requests for a single SLO
+type sloTracker struct {
+	slo          SLO
+	availability *sloWindow
+	latency      *sloWindow
+	mu           sync.Mutex
+	levels       map[string]ThresholdLevel
+}
+
+func newSLOTracker(slo SLO) *sloTracker {
+	if slo.Window <= 0 {
+		slo.Window = sloDefaultWindow
+	}
+	if slo.Window < sloLongWindow {
+		slo.Window = sloLongWindow
+	}
+
+	t := &sloTracker{
+		slo:    slo,
+		levels: make(map[string]ThresholdLevel),
+	}
+
+	if slo.AvailabilityTarget > 0 {
+		t.availability = newSLOWindow(slo.Window)
+	}
+
+	if slo.LatencyTarget > 0 && slo.LatencyThreshold > 0 {
+		t.latency = newSLOWindow(slo.Window)
+	}
+
+	return t
+}
+
+func (t *sloTracker) record(now time.Time, duration time.Duration, statusCode int) {
+	if t.availability != nil {
+		t.availability.record(now, statusCode >= 500)
+	}
+	if t.latency != nil {
+		t.latency.record(now, duration > t.slo.LatencyThreshold)
+	}
+}
+
+func (t *sloTracker) statuses(now time.Time) []SLOStatus {
+	var statuses []SLOStatus
+	if t.availability != nil {
+		statuses = append(statuses, t.status(now, "availability", t.slo.AvailabilityTarget, t.availability))
+	}
+	if t.latency != nil {
+		statuses = append(statuses, t.status(now, "latency", t.slo.LatencyTarget, t.latency))
+	}
+	return statuses
+}
+
+func (t *sloTracker) status(now time.Time, objective string, target float64, w *sloWindow) SLOStatus {
+	total, bad := w.sum(now, t.slo.Window)
+	budget := 1 - target/100
+
+	status := SLOStatus{
+		Name:            t.slo.Name,
+		Objective:       objective,
+		Target:          target,
+		Total:           total,
+		Bad:             bad,
+		SLI:             100,
+		BudgetRemaining: 100,
+		ShortBurnRate:   burnRate(w, now, sloShortWindow, budget),
+		LongBurnRate:    burnRate(w, now, sloLongWindow, budget),
+		Level:           ThresholdOK,
+	}
+
+	if total > 0 {
+		errorRate := float64(bad) / float64(total)
+		status.SLI = (1 - errorRate) * 100
+		if budget > 0 {
+			status.BudgetRemaining = (1 - errorRate/budget) * 100
+		} else if bad > 0 {
+			status.BudgetRemaining = 0
+		}
+	}
+
+	// Multi-window burn-rate alerting: both windows must be burning to avoid alerting on short spikes
+	// or on long-past incidents that have already recovered.
+	switch {
+	case status.BudgetRemaining <= 0 && bad > 0,
+		status.ShortBurnRate >= sloCriticalBurnRate && status.LongBurnRate >= sloCriticalBurnRate:
+		status.Level = ThresholdCritical
+	case status.ShortBurnRate >= sloWarningBurnRate && status.LongBurnRate >= sloWarningBurnRate:
+		status.Level = ThresholdWarning
+	}
+
+	return status
+}
+
+// levelChanged records the level for an objective and reports whether it differs from the previous one
+func (t *sloTracker) levelChanged(objective string, level ThresholdLevel) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, ok := t.levels[objective]
+	t.levels[objective] = level
+	if !ok {
+		return level > ThresholdOK
+	}
+	return previous != level
+}
+
+// burnRate calculates how fast the error budget is being spent over the given period.
+// A burn rate of 1 means the budget will be spent exactly at the end of the SLO window.
+func burnRate(w *sloWindow, now time.Time, period time.Duration, budget float64) float64 {
+	total, bad := w.sum(now, period)
+	if total == 0 {
+		return 0
+	}
+
+	errorRate := float64(bad) / float64(total)
+	if budget <= 0 {
+		if bad > 0 {
+			return sloCriticalBurnRate
+		}
+		return 0
+	}
+	return errorRate / budget
+}
+
+// sloBucket holds the request counts for a single minute
+type sloBucket struct {
+	minute int64
+	total  uint64
+	bad    uint64
+}
+
+// sloWindow is a ring buffer of per-minute request counts
+type sloWindow struct {
+	mu      sync.Mutex
+	buckets []sloBucket
+}
+
+func newSLOWindow(window time.Duration) *sloWindow {
+	return &sloWindow{
+		buckets: make([]sloBucket, int(window/time.Minute)),
+	}
+}
+
+func (w *sloWindow) record(now time.Time, bad bool) {
+	minute := now.Unix() / 60
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b := &w.buckets[minute%int64(len(w.buckets))]
+	if b.minute != minute {
+		*b = sloBucket{minute: minute}
+	}
+
+	b.total++
+	if bad {
+		b.bad++
+	}
+}
+
+// sum returns the total and bad request counts over the given period ending now
+func (w *sloWindow) sum(now time.Time, period time.Duration) (total, bad uint64) {
+	current := now.Unix() / 60
+	oldest := current - int64(period/time.Minute)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, b := range w.buckets {
+		if b.minute > oldest && b.minute <= current {
+			total += b.total
+			bad += b.bad
+		}
+	}
+	return total, bad
+}
@@ -0,0 +1,301 @@
+package pulse
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/hoptest"
+)
+
+// apiSLO has an availability budget of 1% and a latency budget of 10%
+var apiSLO = SLO{
+	Name:               "API",
+	PathPrefix:         "/api",
+	AvailabilityTarget: 99,
+	LatencyThreshold:   100 * time.Millisecond,
+	LatencyTarget:      90,
+}
+
+// sloStep advances the clock, then records requests to the API
+type sloStep struct {
+	advance time.Duration
+	good    int
+	failed  int
+	slow    int
+}
+
+// newSLOCollector creates a collector tracking only SLOs. NewStandardCollector publishes its metrics with
+// expvar, which allows a single collector per process.
+func newSLOCollector(clk *hoptest.Clock, opts ...StandardCollectorOption) *StandardCollector {
+	c := &StandardCollector{clock: clk}
+	for _, opt := range append([]StandardCollectorOption{WithSLOs(apiSLO)}, opts...) {
+		opt(c)
+	}
+	return c
+}
+
+func runSLOSteps(clk *hoptest.Clock, c *StandardCollector, steps []sloStep) {
+	for _, step := range steps {
+		clk.Advance(step.advance)
+		for range step.good {
+			c.recordSLOs("/api/orders", 10*time.Millisecond, http.StatusOK)
+		}
+		for range step.failed {
+			c.recordSLOs("/api/orders", 10*time.Millisecond, http.StatusInternalServerError)
+		}
+		for range step.slow {
+			c.recordSLOs("/api/orders", 200*time.Millisecond, http.StatusOK)
+		}
+	}
+}
+
+// sloStatus returns the status of an objective of the API SLO
+func sloStatus(t *testing.T, c *StandardCollector, objective string) SLOStatus {
+	t.Helper()
+	for _, status := range c.SLOStatuses() {
+		if status.Objective == objective {
+			return status
+		}
+	}
+	require.Failf(t, "no status", "objective %q", objective)
+	return SLOStatus{}
+}
+
+func TestSLO_Availability(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      []sloStep
+		wantTotal  uint64
+		wantBad    uint64
+		wantSLI    float64
+		wantBudget float64
+		wantShort  float64
+		wantLong   float64
+		wantLevel  ThresholdLevel
+	}{
+		{
+			name:       "no traffic",
+			wantSLI:    100,
+			wantBudget: 100,
+			wantLevel:  ThresholdOK,
+		},
+		{
+			name:       "half the budget spent",
+			steps:      []sloStep{{good: 995, failed: 5}},
+			wantTotal:  1000,
+			wantBad:    5,
+			wantSLI:    99.5,
+			wantBudget: 50,
+			wantShort:  0.5,
+			wantLong:   0.5,
+			wantLevel:  ThresholdOK,
+		},
+		{
+			name:       "budget exhausted",
+			steps:      []sloStep{{good: 98, failed: 2}},
+			wantTotal:  100,
+			wantBad:    2,
+			wantSLI:    98,
+			wantBudget: -100,
+			wantShort:  2,
+			wantLong:   2,
+			wantLevel:  ThresholdCritical,
+		},
+		{
+			name:       "warning burn rate in both windows",
+			steps:      []sloStep{{good: 100_000}, {advance: 2 * time.Hour, good: 90, failed: 10}},
+			wantTotal:  100_100,
+			wantBad:    10,
+			wantSLI:    100 - 1000.0/100_100,
+			wantBudget: 100 - 100_000.0/100_100,
+			wantShort:  10,
+			wantLong:   10,
+			wantLevel:  ThresholdWarning,
+		},
+		{
+			name:       "critical burn rate in both windows",
+			steps:      []sloStep{{good: 100_000}, {advance: 2 * time.Hour, good: 80, failed: 20}},
+			wantTotal:  100_100,
+			wantBad:    20,
+			wantSLI:    100 - 2000.0/100_100,
+			wantBudget: 100 - 200_000.0/100_100,
+			wantShort:  20,
+			wantLong:   20,
+			wantLevel:  ThresholdCritical,
+		},
+		{
+			name: "short spike only",
+			steps: []sloStep{
+				{good: 100_000},
+				{advance: 90 * time.Minute, good: 1000},
+				{advance: 30 * time.Minute, good: 80, failed: 20},
+			},
+			wantTotal:  101_100,
+			wantBad:    20,
+			wantSLI:    100 - 2000.0/101_100,
+			wantBudget: 100 - 200_000.0/101_100,
+			wantShort:  20,
+			wantLong:   20.0 / 1100 / 0.01,
+			wantLevel:  ThresholdOK,
+		},
+		{
+			name: "recovered incident",
+			steps: []sloStep{
+				{good: 100_000},
+				{advance: 90 * time.Minute, good: 80, failed: 20},
+				{advance: 30 * time.Minute, good: 100},
+			},
+			wantTotal:  100_200,
+			wantBad:    20,
+			wantSLI:    100 - 2000.0/100_200,
+			wantBudget: 100 - 200_000.0/100_200,
+			wantShort:  0,
+			wantLong:   10,
+			wantLevel:  ThresholdOK,
+		},
+		{
+			name:       "bad requests count until the window has passed",
+			steps:      []sloStep{{failed: 10}, {advance: 24*time.Hour - time.Minute, good: 90}},
+			wantTotal:  100,
+			wantBad:    10,
+			wantSLI:    90,
+			wantBudget: -900,
+			wantLevel:  ThresholdCritical,
+		},
+		{
+			name:       "window rollover reuses the bucket",
+			steps:      []sloStep{{failed: 10}, {advance: 24 * time.Hour, good: 100}},
+			wantTotal:  100,
+			wantSLI:    100,
+			wantBudget: 100,
+			wantLevel:  ThresholdOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := hoptest.NewClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			c := newSLOCollector(clk)
+			runSLOSteps(clk, c, tt.steps)
+
+			status := sloStatus(t, c, "availability")
+			assert.Equal(t, "API", status.Name)
+			assert.Equal(t, 99.0, status.Target)
+			assert.Equal(t, tt.wantTotal, status.Total)
+			assert.Equal(t, tt.wantBad, status.Bad)
+			assert.InDelta(t, tt.wantSLI, status.SLI, 1e-9)
+			assert.InDelta(t, tt.wantBudget, status.BudgetRemaining, 1e-9)
+			assert.InDelta(t, tt.wantShort, status.ShortBurnRate, 1e-9)
+			assert.InDelta(t, tt.wantLong, status.LongBurnRate, 1e-9)
+			assert.Equal(t, tt.wantLevel, status.Level)
+		})
+	}
+}
+
+func TestSLO_Latency(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      []sloStep
+		wantTotal  uint64
+		wantBad    uint64
+		wantBudget float64
+		wantLevel  ThresholdLevel
+	}{
+		{
+			name:       "server errors don't count against latency",
+			steps:      []sloStep{{good: 10, failed: 10}},
+			wantTotal:  20,
+			wantBudget: 100,
+			wantLevel:  ThresholdOK,
+		},
+		{
+			name:       "half the budget spent",
+			steps:      []sloStep{{good: 19, slow: 1}},
+			wantTotal:  20,
+			wantBad:    1,
+			wantBudget: 50,
+			wantLevel:  ThresholdOK,
+		},
+		{
+			name:       "budget exhausted",
+			steps:      []sloStep{{good: 9, slow: 1}},
+			wantTotal:  10,
+			wantBad:    1,
+			wantBudget: 0,
+			wantLevel:  ThresholdCritical,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := hoptest.NewClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			c := newSLOCollector(clk)
+			runSLOSteps(clk, c, tt.steps)
+
+			status := sloStatus(t, c, "latency")
+			assert.Equal(t, 90.0, status.Target)
+			assert.Equal(t, tt.wantTotal, status.Total)
+			assert.Equal(t, tt.wantBad, status.Bad)
+			assert.InDelta(t, tt.wantBudget, status.BudgetRemaining, 1e-9)
+			assert.Equal(t, tt.wantLevel, status.Level)
+		})
+	}
+}
+
+func TestSLO_PathPrefix(t *testing.T) {
+	clk := hoptest.NewClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := newSLOCollector(clk)
+
+	c.recordSLOs("/admin", time.Second, http.StatusInternalServerError)
+	c.recordSLOs("/api/orders", 10*time.Millisecond, http.StatusOK)
+
+	status := sloStatus(t, c, "availability")
+	assert.Equal(t, uint64(1), status.Total)
+	assert.Equal(t, uint64(0), status.Bad)
+}
+
+func TestSLO_CheckSLOs(t *testing.T) {
+	clk := hoptest.NewClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	var alerts []ThresholdLevel
+	c := &StandardCollector{clock: clk}
+	WithSLOs(SLO{Name: "API", PathPrefix: "/api", AvailabilityTarget: 99})(c)
+	WithSLOAlertHandler(func(status SLOStatus) {
+		assert.Equal(t, "availability", status.Objective)
+		alerts = append(alerts, status.Level)
+	})(c)
+
+	steps := []struct {
+		name       string
+		step       sloStep
+		wantAlerts []ThresholdLevel
+	}{
+		{name: "healthy objectives don't alert", step: sloStep{good: 100_000}},
+		{
+			name:       "burning the budget raises a warning",
+			step:       sloStep{advance: 2 * time.Hour, good: 90, failed: 10},
+			wantAlerts: []ThresholdLevel{ThresholdWarning},
+		},
+		{
+			name:       "unchanged levels don't alert again",
+			step:       sloStep{},
+			wantAlerts: []ThresholdLevel{ThresholdWarning},
+		},
+		{
+			name:       "burning faster escalates to critical",
+			step:       sloStep{good: 70, failed: 30},
+			wantAlerts: []ThresholdLevel{ThresholdWarning, ThresholdCritical},
+		},
+		{
+			name:       "recovering returns to ok",
+			step:       sloStep{advance: 2 * time.Hour},
+			wantAlerts: []ThresholdLevel{ThresholdWarning, ThresholdCritical, ThresholdOK},
+		},
+	}
+	for _, s := range steps {
+		runSLOSteps(clk, c, []sloStep{s.step})
+		c.CheckSLOs()
+		assert.Equal(t, s.wantAlerts, alerts, s.name)
+	}
+}
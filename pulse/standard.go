@@ -8,6 +8,8 @@ import (
 	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/patrickward/hop/clock"
 )
 
 // ThresholdLevel is an enumeration of threshold levels
@@ -54,14 +56,18 @@ var DefaultThresholds = Thresholds{
 type StandardCollector struct {
 	mu         sync.RWMutex
 	serverName string
+	clock      clock.Clock
 	startTime  time.Time
 	counters   map[string]*standardCounter
 	gauges     map[string]*standardGauge
-	histograms map[string]*standardHistogram
+	histograms map[string]Histogram
+
+	// hdrOptions configures HDR histograms, or nil for the standard bucketed histograms
+	hdrOptions func(opts *HDROptions)
 
 	// Pre-allocated metrics for performance
 	httpRequests     *standardCounter
-	httpDurations    *standardHistogram
+	httpDurations    Histogram
 	httpServerErrors *standardCounter
 	httpClientErrors *standardCounter
 
@@ -70,7 +76,7 @@ type StandardCollector struct {
 	//memAlloc   *standardGauge
 	//memTotal   *standardGauge
 	//memSys     *standardGauge
-	gcPauses *standardHistogram
+	gcPauses Histogram
 
 	// Enhanced memory metrics
 	heapInuse    *standardGauge
@@ -109,6 +115,13 @@ type StandardCollector struct {
 	requestsByMethod    map[string]*standardCounter
 	concurrentRequests  *standardGauge
 	lastMinuteCheck     time.Time
+
+	// Service level objectives
+	slos            []*sloTracker
+	sloAlertHandler SLOAlertHandler
+
+	// Rendered template sizes and durations
+	renders renderStats
 }
 
 // StandardCollectorOption is a functional option for configuring a StandardCollector
@@ -128,20 +141,37 @@ func WithThresholds(thresholds Thresholds) StandardCollectorOption {
 	}
 }
 
+// WithClock sets the clock used to compute rates and uptime, e.g. a fake clock from the hoptest package
+func WithClock(clk clock.Clock) StandardCollectorOption {
+	return func(c *StandardCollector) {
+		c.clock = clk
+	}
+}
+
+// WithHDRHistograms makes the collector create HDR histograms instead of histograms with static buckets,
+// including the HTTP request duration histogram. Response time percentiles are then computed over every
+// request since startup rather than the last 1000, to the configured number of significant figures.
+func WithHDRHistograms(optsFunc func(opts *HDROptions)) StandardCollectorOption {
+	return func(c *StandardCollector) {
+		c.hdrOptions = func(opts *HDROptions) {
+			if optsFunc != nil {
+				optsFunc(opts)
+			}
+		}
+	}
+}
+
 // NewStandardCollector creates a new StandardCollector
 func NewStandardCollector(opts ...StandardCollectorOption) *StandardCollector {
 	c := &StandardCollector{
 		serverName:          "HOP Server",
-		startTime:           time.Now(),
 		counters:            make(map[string]*standardCounter),
 		gauges:              make(map[string]*standardGauge),
-		histograms:          make(map[string]*standardHistogram),
+		histograms:          make(map[string]Histogram),
 		thresholds:          DefaultThresholds,
-		lastStatsTime:       time.Now(),
 		responseTimeTracker: newResponseTimeTracker(1000), // Keep last 1000 samples
 		requestsByMethod:    make(map[string]*standardCounter),
 		concurrentRequests:  nil,
-		lastMinuteCheck:     time.Now(),
 	}
 
 	// Apply options
@@ -149,6 +179,11 @@ func NewStandardCollector(opts ...StandardCollectorOption) *StandardCollector {
 		opt(c)
 	}
 
+	c.clock = clock.OrReal(c.clock)
+	c.startTime = c.clock.Now()
+	c.lastStatsTime = c.startTime
+	c.lastMinuteCheck = c.startTime
+
 	// Initialize CPU metrics
 	c.cpuUser = c.getOrCreateGauge("cpu_user_percent")
 	c.cpuSystem = c.getOrCreateGauge("cpu_system_percent")
@@ -284,7 +319,7 @@ func (c *StandardCollector) RecordMemStats() {
 	c.heapReleased.Set(float64(ms.HeapReleased))
 
 	// Calculate heap growth rate
-	now := time.Now()
+	now := c.clock.Now()
 	if !c.lastHeapStats.timestamp.IsZero() {
 		duration := now.Sub(c.lastHeapStats.timestamp).Seconds()
 		if duration > 0 {
@@ -352,7 +387,7 @@ func (c *StandardCollector) checkMemoryMetrics() map[string]MemoryStatus {
 	status["gc_pause"] = gcPauseStatus
 
 	// Calculate GC frequency (per minute)
-	gcFrequency := float64(ms.NumGC) / time.Since(c.startTime).Minutes()
+	gcFrequency := float64(ms.NumGC) / c.clock.Since(c.startTime).Minutes()
 	gcFrequencyStatus := MemoryStatus{
 		Level:     ThresholdOK,
 		Current:   gcFrequency,
@@ -385,9 +420,19 @@ func (c *StandardCollector) DecrementConcurrentRequests() {
 
 // RecordHTTPRequest records metrics about an HTTP request
 func (c *StandardCollector) RecordHTTPRequest(method, path string, duration time.Duration, statusCode int) {
+	ms := float64(duration) / float64(time.Millisecond)
+	c.httpDurations.Observe(ms)
+	if _, ok := c.httpDurations.(PercentileHistogram); !ok {
+		c.responseTimeTracker.Record(ms)
+	}
+	c.RecordHTTPRequestCount(method, path, statusCode)
+	c.recordSLOs(path, duration, statusCode)
+}
+
+// RecordHTTPRequestCount records an HTTP request in the request, method and error totals without observing
+// its duration
+func (c *StandardCollector) RecordHTTPRequestCount(method, _ string, statusCode int) {
 	c.httpRequests.Inc()
-	c.httpDurations.Observe(float64(duration.Milliseconds()))
-	c.responseTimeTracker.Record(float64(duration.Milliseconds()))
 
 	// Track requests by method
 	if counter, exists := c.requestsByMethod[method]; exists {
@@ -402,7 +447,7 @@ func (c *StandardCollector) RecordHTTPRequest(method, path string, duration time
 	}
 
 	// Update recent requests atomically
-	now := time.Now()
+	now := c.clock.Now()
 	c.mu.Lock()
 	atomic.AddUint64(&c.requestsLastMinute, 1)
 
@@ -432,7 +477,7 @@ func (c *StandardCollector) RecordCPUStats() {
 		return
 	}
 
-	now := time.Now()
+	now := c.clock.Now()
 	duration := now.Sub(c.lastStatsTime).Seconds()
 
 	if duration > 0 {
@@ -523,7 +568,7 @@ func (c *StandardCollector) getOrCreateGauge(name string) *standardGauge {
 	return gauge
 }
 
-func (c *StandardCollector) getOrCreateHistogram(name string) *standardHistogram {
+func (c *StandardCollector) getOrCreateHistogram(name string) Histogram {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -531,6 +576,18 @@ func (c *StandardCollector) getOrCreateHistogram(name string) *standardHistogram
 		return hist
 	}
 
+	if c.hdrOptions != nil {
+		hist := NewHDRHistogram(c.hdrOptions)
+		c.histograms[name] = hist
+
+		// Register with expvar for exposure
+		expvar.Publish(name, expvar.Func(func() interface{} {
+			return hist.Snapshot()
+		}))
+
+		return hist
+	}
+
 	// Default buckets for latency-style metrics
 	hist := &standardHistogram{
 		buckets: map[float64]uint64{
@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
-	"time"
 )
 
 //go:embed templates/*.html
@@ -32,6 +31,8 @@ type presentedMetrics struct {
 	CustomMetrics  []metricData
 	CPUMetrics     []metricData
 	DiskMetrics    []metricData
+	SLOMetrics     []metricData
+	RenderMetrics  []renderRow
 }
 
 // Handler returns an http.Handler for the metrics endpoint as an HTML page
@@ -56,7 +57,7 @@ func (c *StandardCollector) Handler() http.Handler {
 
 		data := presentedMetrics{
 			ServerName: c.serverName,
-			Timestamp:  time.Now().Format("2006-01-02 15:04:05 MST"),
+			Timestamp:  c.clock.Now().Format("2006-01-02 15:04:05 MST"),
 		}
 
 		data.HTTPMetrics = c.formatHTTPMetrics()
@@ -64,6 +65,8 @@ func (c *StandardCollector) Handler() http.Handler {
 		data.RuntimeMetrics = c.formatRuntimeMetrics()
 		data.CPUMetrics = c.formatCPUMetrics()
 		data.DiskMetrics = c.formatDiskMetrics()
+		data.SLOMetrics = c.formatSLOMetrics()
+		data.RenderMetrics = c.formatRenderMetrics()
 
 		w.Header().Set("Content-Type", "text/html")
 		if err := tmpl.Execute(w, data); err != nil {
@@ -187,6 +190,13 @@ func (c *StandardCollector) formatHTTPMetrics() []metricData {
 	p95 := c.responseTimeTracker.GetPercentile(95)
 	p99 := c.responseTimeTracker.GetPercentile(99)
 	avg := c.responseTimeTracker.GetAverage()
+	if hist, ok := c.httpDurations.(PercentileHistogram); ok {
+		p95 = hist.Percentile(95)
+		p99 = hist.Percentile(99)
+		if count := hist.Count(); count > 0 {
+			avg = hist.Sum() / float64(count)
+		}
+	}
 
 	// Add method breakdown
 	var methodStats []string
@@ -200,7 +210,7 @@ func (c *StandardCollector) formatHTTPMetrics() []metricData {
 
 	// Calculate request rates
 	recentRate := c.recentRequests.Value()
-	overallRate := float64(reqCount) / time.Since(c.startTime).Seconds()
+	overallRate := float64(reqCount) / c.clock.Since(c.startTime).Seconds()
 
 	metrics := []metricData{
 		{
@@ -358,7 +368,7 @@ func (c *StandardCollector) formatRuntimeMetrics() []metricData {
 		},
 		{
 			Name:        "Uptime",
-			Value:       formatDuration(float64(time.Since(c.startTime).Milliseconds())),
+			Value:       formatDuration(float64(c.clock.Since(c.startTime).Milliseconds())),
 			Description: "Time elapsed since the application started. Useful for monitoring application restarts and calculating average metrics over time.",
 			Level:       ThresholdInfo,
 		},
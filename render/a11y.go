@@ -0,0 +1,179 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Accessibility rules checked by AuditHTML
+const (
+	// A11yImageAlt flags images without an alt attribute. Decorative images should use alt="".
+	A11yImageAlt = "image-alt"
+	// A11yInputLabel flags form controls without a label, aria-label, aria-labelledby or title
+	A11yInputLabel = "input-label"
+	// A11yDuplicateID flags id attributes used by more than one element
+	A11yDuplicateID = "duplicate-id"
+)
+
+// A11yIssue is an accessibility problem found in rendered HTML
+type A11yIssue struct {
+	// Rule identifies the check that failed, e.g. A11yImageAlt
+	Rule string
+	// Message describes the problem
+	Message string
+	// Element is the offending start tag, e.g. `<img src="/logo.png">`
+	Element string
+}
+
+// String returns the issue as "rule: message: element"
+func (i A11yIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Rule, i.Message, i.Element)
+}
+
+// A11yReporter receives the issues found in a rendered template
+type A11yReporter func(r *http.Request, path string, issues []A11yIssue)
+
+// unlabeledInputTypes are input types that don't need a label, because they are hidden or carry their
+// own text
+var unlabeledInputTypes = map[string]bool{
+	"hidden": true,
+	"submit": true,
+	"reset":  true,
+	"button": true,
+	"image":  true,
+}
+
+// AuditHTML checks HTML for common accessibility issues: images without alt text, form controls without
+// labels and duplicate ids. It is a quick development aid, not a replacement for a full audit.
+func AuditHTML(r io.Reader) []A11yIssue {
+	var issues []A11yIssue
+
+	type control struct {
+		id      string
+		element string
+	}
+	var controls []control
+	labelled := make(map[string]bool)
+	ids := make(map[string]int)
+	labelDepth := 0
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		token := z.Token()
+		if tt == html.EndTagToken {
+			if token.Data == "label" && labelDepth > 0 {
+				labelDepth--
+			}
+			continue
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		attrs := make(map[string]string, len(token.Attr))
+		for _, attr := range token.Attr {
+			attrs[attr.Key] = attr.Val
+		}
+
+		if id := attrs["id"]; id != "" {
+			ids[id]++
+			if ids[id] == 2 {
+				issues = append(issues, A11yIssue{
+					Rule:    A11yDuplicateID,
+					Message: fmt.Sprintf("id %q is used more than once", id),
+					Element: token.String(),
+				})
+			}
+		}
+
+		if hidden(attrs) {
+			continue
+		}
+
+		switch token.Data {
+		case "label":
+			if tt == html.StartTagToken {
+				labelDepth++
+			}
+			if target := attrs["for"]; target != "" {
+				labelled[target] = true
+			}
+		case "img":
+			if _, ok := attrs["alt"]; !ok {
+				issues = append(issues, A11yIssue{
+					Rule:    A11yImageAlt,
+					Message: "image has no alt attribute",
+					Element: token.String(),
+				})
+			}
+		case "input", "select", "textarea":
+			if token.Data == "input" && unlabeledInputTypes[strings.ToLower(attrs["type"])] {
+				continue
+			}
+			if labelDepth > 0 || attrs["aria-label"] != "" || attrs["aria-labelledby"] != "" || attrs["title"] != "" {
+				continue
+			}
+			// Labels may come after the control, so controls with an id are checked at the end
+			controls = append(controls, control{id: attrs["id"], element: token.String()})
+		}
+	}
+
+	for _, c := range controls {
+		if c.id != "" && labelled[c.id] {
+			continue
+		}
+		issues = append(issues, A11yIssue{
+			Rule:    A11yInputLabel,
+			Message: "form control has no label",
+			Element: c.element,
+		})
+	}
+
+	return issues
+}
+
+// hidden reports whether an element is hidden from assistive technology
+func hidden(attrs map[string]string) bool {
+	if attrs["aria-hidden"] == "true" {
+		return true
+	}
+	role := attrs["role"]
+	return role == "presentation" || role == "none"
+}
+
+// auditRender audits a rendered template and reports the issues, setting the A11yIssuesHeader so they
+// also show up in the browser's developer tools
+func (tm *TemplateManager) auditRender(w http.ResponseWriter, r *http.Request, path string, buf *bytes.Buffer) {
+	issues := AuditHTML(bytes.NewReader(buf.Bytes()))
+	if len(issues) == 0 {
+		return
+	}
+
+	w.Header().Set(A11yIssuesHeader, fmt.Sprintf("%d", len(issues)))
+	if tm.a11yReporter != nil {
+		tm.a11yReporter(r, path, issues)
+		return
+	}
+	if tm.logger == nil {
+		return
+	}
+	for _, issue := range issues {
+		tm.logger.Warn("Accessibility issue",
+			slog.String("path", path),
+			slog.String("url", r.URL.Path),
+			slog.String("rule", issue.Rule),
+			slog.String("message", issue.Message),
+			slog.String("element", issue.Element))
+	}
+}
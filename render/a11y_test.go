@@ -0,0 +1,98 @@
+package render_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render"
+)
+
+func TestAuditHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		rules []string
+	}{
+		{
+			name: "accessible page",
+			html: `<img src="/logo.png" alt="Logo"><img src="/line.png" alt="">
+<label for="email">Email</label><input id="email" type="email">
+<label>Name <input name="name"></label>
+<input type="hidden" name="csrf"><input type="submit" value="Save">
+<textarea aria-label="Comment"></textarea>`,
+		},
+		{
+			name:  "image without alt",
+			html:  `<img src="/logo.png">`,
+			rules: []string{render.A11yImageAlt},
+		},
+		{
+			name:  "input without label",
+			html:  `<input name="q"><select id="size"></select>`,
+			rules: []string{render.A11yInputLabel, render.A11yInputLabel},
+		},
+		{
+			name: "label after the control",
+			html: `<input id="q"><label for="q">Search</label>`,
+		},
+		{
+			name:  "duplicate id",
+			html:  `<div id="main"></div><p id="main"></p><span id="main"></span>`,
+			rules: []string{render.A11yDuplicateID},
+		},
+		{
+			name: "hidden from assistive technology",
+			html: `<img src="/icon.svg" aria-hidden="true"><img src="/spacer.gif" role="presentation">`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := render.AuditHTML(strings.NewReader(tt.html))
+
+			var rules []string
+			for _, issue := range issues {
+				rules = append(rules, issue.Rule)
+			}
+			assert.Equal(t, tt.rules, rules)
+		})
+	}
+}
+
+func TestTemplateManager_A11yAudit(t *testing.T) {
+	sources := render.Sources{
+		"": fstest.MapFS{
+			"layouts/base.gtml": {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/home.gtml":   {Data: []byte(`{{define "page:main"}}<img src="/logo.png"><input name="q">{{end}}`)},
+		},
+	}
+
+	var reported []render.A11yIssue
+	tm, err := render.NewTemplateManager(sources, render.TemplateManagerOptions{
+		Extension: ".gtml",
+		Logger:    slog.New(slog.NewTextHandler(httptest.NewRecorder(), nil)),
+		A11yAudit: true,
+		A11yReporter: func(r *http.Request, path string, issues []render.A11yIssue) {
+			assert.Equal(t, "views/home", path)
+			reported = issues
+		},
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	tm.NewResponse().Path("home").Render(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(render.A11yIssuesHeader))
+	require.Len(t, reported, 2)
+	assert.Equal(t, render.A11yImageAlt, reported[0].Rule)
+	assert.Equal(t, `<img src="/logo.png">`, reported[0].Element)
+	assert.Equal(t, render.A11yInputLabel, reported[1].Rule)
+}
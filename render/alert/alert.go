@@ -0,0 +1,69 @@
+// Package alert provides the alerts shown to users, like "Settings saved", and flash messages: alerts
+// stored in the session to be shown on the next page, typically after a redirect.
+//
+//	alert.Flash(ctx, session, alert.TypeSuccess, "Settings saved")
+//	// on the next request
+//	for _, a := range alert.Flashes(ctx, session) { ... }
+package alert
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// SessionKey is the session key holding the flash messages
+const SessionKey = "alert.flashes"
+
+// Type is the type of alert, typically used for its styling
+type Type string
+
+const (
+	TypeSuccess Type = "success"
+	TypeInfo    Type = "info"
+	TypeWarning Type = "warning"
+	TypeError   Type = "error"
+)
+
+// Alert is a message shown to the user
+type Alert struct {
+	Type    Type   `json:"type"`
+	Message string `json:"message"`
+}
+
+// New creates an alert
+func New(typ Type, message string) Alert {
+	return Alert{Type: typ, Message: message}
+}
+
+// Session is the subset of *scs.SessionManager used to store flash messages
+type Session interface {
+	GetString(ctx context.Context, key string) string
+	PopString(ctx context.Context, key string) string
+	Put(ctx context.Context, key string, val interface{})
+}
+
+// Flash adds an alert to the flash messages of the session, after those already added
+func Flash(ctx context.Context, session Session, typ Type, message string) {
+	alerts := decode(session.GetString(ctx, SessionKey))
+	alerts = append(alerts, New(typ, message))
+	// Stored as a string, which the session manager's codec encodes without registering a type
+	b, _ := json.Marshal(alerts)
+	session.Put(ctx, SessionKey, string(b))
+}
+
+// Flashes returns the flash messages of the session and removes them, so they are shown once
+func Flashes(ctx context.Context, session Session) []Alert {
+	return decode(session.PopString(ctx, SessionKey))
+}
+
+// decode returns the alerts of a session value, or nil for an empty or invalid value
+func decode(value string) []Alert {
+	if value == "" {
+		return nil
+	}
+	var alerts []Alert
+	if err := json.Unmarshal([]byte(value), &alerts); err != nil {
+		return nil
+	}
+	return alerts
+}
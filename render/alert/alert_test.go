@@ -0,0 +1,44 @@
+package alert_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/patrickward/hop/render/alert"
+)
+
+// memorySession is a single browser's session
+type memorySession map[string]any
+
+func (s memorySession) GetString(_ context.Context, key string) string {
+	v, _ := s[key].(string)
+	return v
+}
+
+func (s memorySession) PopString(ctx context.Context, key string) string {
+	v := s.GetString(ctx, key)
+	delete(s, key)
+	return v
+}
+
+func (s memorySession) Put(_ context.Context, key string, val interface{}) { s[key] = val }
+
+func TestFlash(t *testing.T) {
+	ctx := context.Background()
+	session := memorySession{}
+
+	assert.Empty(t, alert.Flashes(ctx, session))
+
+	alert.Flash(ctx, session, alert.TypeSuccess, "Saved")
+	alert.Flash(ctx, session, alert.TypeWarning, "Check your email")
+	assert.Equal(t, []alert.Alert{
+		{Type: alert.TypeSuccess, Message: "Saved"},
+		{Type: alert.TypeWarning, Message: "Check your email"},
+	}, alert.Flashes(ctx, session))
+	assert.Empty(t, alert.Flashes(ctx, session), "flashes are removed once read")
+
+	session[alert.SessionKey] = "not json"
+	assert.Empty(t, alert.Flashes(ctx, session))
+}
@@ -0,0 +1,50 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// DefaultMaxPooledBufferSize is the default size above which render buffers are dropped instead of reused
+const DefaultMaxPooledBufferSize = 256 << 10
+
+// bufferPool reuses the buffers pages are rendered to, dropping those that grew past maxSize so that a
+// single huge page doesn't pin its memory for the life of the process
+type bufferPool struct {
+	pool    sync.Pool
+	maxSize int
+}
+
+func newBufferPool(maxSize int) *bufferPool {
+	return &bufferPool{
+		pool:    sync.Pool{New: func() any { return new(bytes.Buffer) }},
+		maxSize: maxSize,
+	}
+}
+
+// get returns an empty buffer
+func (p *bufferPool) get() *bytes.Buffer {
+	return p.pool.Get().(*bytes.Buffer)
+}
+
+// put returns buf to the pool, unless pooling is disabled or buf is too large
+func (p *bufferPool) put(buf *bytes.Buffer) {
+	if p.maxSize < 0 || buf.Cap() > p.maxSize {
+		return
+	}
+	buf.Reset()
+	p.pool.Put(buf)
+}
+
+// countingWriter counts the bytes written to a streamed page
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
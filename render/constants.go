@@ -18,4 +18,8 @@ const (
 
 	// NonceContextKey is the key used for the a front-end nonce
 	NonceContextKey = "hyperview_nonce"
+
+	// A11yIssuesHeader is the response header holding the number of accessibility issues found when
+	// TemplateManagerOptions.A11yAudit is enabled
+	A11yIssuesHeader = "X-A11y-Issues"
 )
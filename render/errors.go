@@ -15,4 +15,13 @@ const (
 
 	// ErrTempRender is returned when a template cannot be rendered.
 	ErrTempRender = hyperViewError("template render error")
+
+	// ErrTempRecursion is returned when templates include each other recursively.
+	ErrTempRecursion = hyperViewError("template recursion")
+
+	// ErrTempDepth is returned when templates include each other too deeply.
+	ErrTempDepth = hyperViewError("template include depth exceeded")
+
+	// ErrTempDuplicate is returned when two template sources define a partial with the same name.
+	ErrTempDuplicate = hyperViewError("template defined by more than one source")
 )
@@ -0,0 +1,25 @@
+package htmx
+
+import "net/http"
+
+// HistoryRestore is middleware for history restore requests, which htmx sends with HX-Request when a
+// page the user navigates back or forward to is missing from its history cache. htmx expects the full
+// page in response, so the htmx request headers are removed from these requests and handlers that
+// render partials for htmx requests render the full page instead. The Vary header is set to HX-Request
+// on every response so that caches keep partial and full responses apart.
+//
+//	router.Use(htmx.HistoryRestore)
+func HistoryRestore(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", HXRequest)
+
+		if IsHistoryRestoreRequest(r) {
+			r = r.Clone(r.Context())
+			for _, header := range []string{HXRequest, HXBoosted, HXTarget, HXTrigger, HXTriggerName} {
+				r.Header.Del(header)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
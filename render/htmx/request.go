@@ -70,3 +70,43 @@ func TriggerName(r *http.Request) (string, bool) {
 
 	return r.Header.Get(HXTriggerName), true
 }
+
+// Details holds the htmx headers of a request, so handlers and templates can branch on the htmx context
+// without reading the headers one by one, e.g. {{if eq .HTMX.Target "results"}} in a template.
+type Details struct {
+	// IsHTMX is true for htmx requests that aren't boosted, see IsHtmxRequest
+	IsHTMX bool
+	// IsBoosted is true for requests of boosted links and forms
+	IsBoosted bool
+	// IsHistoryRestore is true when htmx requests the whole page after a miss in its history cache
+	IsHistoryRestore bool
+	// Target is the id of the target element
+	Target string
+	// Trigger is the id of the element that triggered the request
+	Trigger string
+	// TriggerName is the name of the element that triggered the request
+	TriggerName string
+	// CurrentURL is the URL of the browser
+	CurrentURL string
+	// Prompt is the user's response to an hx-prompt
+	Prompt string
+}
+
+// IsAny returns true for htmx requests, boosted or not
+func (d Details) IsAny() bool {
+	return d.IsHTMX || d.IsBoosted
+}
+
+// RequestDetails returns the htmx details of a request. Headers that aren't set are empty.
+func RequestDetails(r *http.Request) Details {
+	return Details{
+		IsHTMX:           IsHtmxRequest(r),
+		IsBoosted:        IsBoostedRequest(r),
+		IsHistoryRestore: IsHistoryRestoreRequest(r),
+		Target:           r.Header.Get(HXTarget),
+		Trigger:          r.Header.Get(HXTrigger),
+		TriggerName:      r.Header.Get(HXTriggerName),
+		CurrentURL:       r.Header.Get(HXCurrentURL),
+		Prompt:           r.Header.Get(HXPrompt),
+	}
+}
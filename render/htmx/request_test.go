@@ -0,0 +1,70 @@
+package htmx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/patrickward/hop/render/htmx"
+)
+
+func TestRequestDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    htmx.Details
+		wantAny bool
+	}{
+		{
+			name: "plain request",
+			want: htmx.Details{},
+		},
+		{
+			name: "htmx request",
+			headers: map[string]string{
+				htmx.HXRequest:     "true",
+				htmx.HXTarget:      "results",
+				htmx.HXTrigger:     "search",
+				htmx.HXTriggerName: "q",
+				htmx.HXCurrentURL:  "https://example.com/items",
+				htmx.HXPrompt:      "yes",
+			},
+			want: htmx.Details{
+				IsHTMX:      true,
+				Target:      "results",
+				Trigger:     "search",
+				TriggerName: "q",
+				CurrentURL:  "https://example.com/items",
+				Prompt:      "yes",
+			},
+			wantAny: true,
+		},
+		{
+			name:    "boosted request",
+			headers: map[string]string{htmx.HXRequest: "true", htmx.HXBoosted: "true"},
+			want:    htmx.Details{IsBoosted: true},
+			wantAny: true,
+		},
+		{
+			name:    "history restore",
+			headers: map[string]string{htmx.HXRequest: "true", htmx.HXHistoryRestoreRequest: "true"},
+			want:    htmx.Details{IsHTMX: true, IsHistoryRestore: true},
+			wantAny: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			details := htmx.RequestDetails(r)
+			assert.Equal(t, tt.want, details)
+			assert.Equal(t, tt.wantAny, details.IsAny())
+		})
+	}
+}
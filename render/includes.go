@@ -0,0 +1,149 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// DefaultMaxIncludeDepth is the default limit on how deeply templates may include each other
+const DefaultMaxIncludeDepth = 32
+
+// checkIncludes inspects the {{template}} calls of every template in the set. It returns an
+// ErrTempRecursion error naming the cycle when templates include each other, unless recursion is
+// allowed, and an ErrTempDepth error naming the chain when includes nest deeper than maxDepth.
+func checkIncludes(tmpl *template.Template, allowRecursion bool, maxDepth int) error {
+	graph := includeGraph(tmpl)
+
+	names := make([]string, 0, len(graph))
+	for name := range graph {
+		names = append(names, name)
+	}
+	// Sorted, so the reported path is deterministic
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(graph))
+	// depth is the length of the longest include chain starting at a template; next is its first step
+	depth := make(map[string]int, len(graph))
+	next := make(map[string]string, len(graph))
+	var stack []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		state[name] = visiting
+		stack = append(stack, name)
+		defer func() { stack = stack[:len(stack)-1] }()
+
+		for _, callee := range graph[name] {
+			switch state[callee] {
+			case visiting:
+				if allowRecursion {
+					continue
+				}
+				start := 0
+				for i, n := range stack {
+					if n == callee {
+						start = i
+						break
+					}
+				}
+				cycle := append(append([]string{}, stack[start:]...), callee)
+				return fmt.Errorf("%w: %s", ErrTempRecursion, strings.Join(cycle, " -> "))
+			case unvisited:
+				if err := visit(callee); err != nil {
+					return err
+				}
+			}
+
+			if d := depth[callee] + 1; d > depth[name] {
+				depth[name] = d
+				next[name] = callee
+			}
+		}
+
+		state[name] = done
+		return nil
+	}
+
+	for _, name := range names {
+		if state[name] != unvisited {
+			continue
+		}
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+
+	if maxDepth <= 0 {
+		return nil
+	}
+	for _, name := range names {
+		if depth[name] <= maxDepth {
+			continue
+		}
+		chain := []string{name}
+		for n := name; next[n] != "" && len(chain) <= maxDepth+1; n = next[n] {
+			chain = append(chain, next[n])
+		}
+		return fmt.Errorf("%w: includes nest %d levels, the maximum is %d: %s ...",
+			ErrTempDepth, depth[name], maxDepth, strings.Join(chain, " -> "))
+	}
+
+	return nil
+}
+
+// includeGraph maps each defined template to the defined templates it includes
+func includeGraph(tmpl *template.Template) map[string][]string {
+	defined := make(map[string]*parse.Tree)
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && t.Tree.Root != nil {
+			defined[t.Name()] = t.Tree
+		}
+	}
+
+	graph := make(map[string][]string, len(defined))
+	for name, tree := range defined {
+		seen := make(map[string]bool)
+		var callees []string
+		walkTemplateCalls(tree.Root, func(callee string) {
+			if _, ok := defined[callee]; ok && !seen[callee] {
+				seen[callee] = true
+				callees = append(callees, callee)
+			}
+		})
+		sort.Strings(callees)
+		graph[name] = callees
+	}
+	return graph
+}
+
+// walkTemplateCalls calls fn with the name of every {{template}} call under node
+func walkTemplateCalls(node parse.Node, fn func(name string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkTemplateCalls(child, fn)
+		}
+	case *parse.TemplateNode:
+		fn(n.Name)
+	case *parse.IfNode:
+		walkTemplateCalls(n.List, fn)
+		walkTemplateCalls(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkTemplateCalls(n.List, fn)
+		walkTemplateCalls(n.ElseList, fn)
+	case *parse.WithNode:
+		walkTemplateCalls(n.List, fn)
+		walkTemplateCalls(n.ElseList, fn)
+	}
+}
@@ -0,0 +1,349 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FieldCase is the naming policy applied to the keys of JSON objects written by a JSONWriter
+type FieldCase int
+
+const (
+	// FieldCaseKeep writes keys as encoding/json produces them
+	FieldCaseKeep FieldCase = iota
+	// FieldCaseSnake writes keys in snake_case, e.g. "user_id"
+	FieldCaseSnake
+	// FieldCaseCamel writes keys in camelCase, e.g. "userId"
+	FieldCaseCamel
+)
+
+// PaginationPlacement is where a JSONWriter puts pagination metadata
+type PaginationPlacement int
+
+const (
+	// PaginationInMeta nests pagination under the meta key: {"data": [...], "meta": {"pagination": {...}}}
+	PaginationInMeta PaginationPlacement = iota
+	// PaginationInBody puts pagination next to the data: {"data": [...], "pagination": {...}}
+	PaginationInBody
+	// PaginationInHeaders sends pagination as X-Page, X-Per-Page, X-Total-Count and X-Total-Pages headers
+	PaginationInHeaders
+)
+
+// Pagination describes the page of a collection in a JSON response
+type Pagination struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// NewPagination computes the total number of pages for a page of a collection
+func NewPagination(page, perPage, total int) Pagination {
+	p := Pagination{Page: page, PerPage: perPage, Total: total}
+	if perPage > 0 {
+		p.TotalPages = (total + perPage - 1) / perPage
+	}
+	return p
+}
+
+// JSONOptions configures a JSONWriter
+type JSONOptions struct {
+	// DataKey wraps successful responses in an object under this key, e.g. {"data": ...}. Default is
+	// "data"; set it to "-" to write data unwrapped.
+	DataKey string
+	// ErrorKey wraps error responses in an object under this key, e.g. {"error": {"message": ...}}.
+	// Default is "error"; set it to "-" to write the error object unwrapped.
+	ErrorKey string
+	// MetaKey is the key of the metadata object. Default is "meta".
+	MetaKey string
+	// FieldCase is the naming policy for object keys, applied to the whole document including struct
+	// fields and map keys. Default is FieldCaseKeep.
+	FieldCase FieldCase
+	// Pagination is where pagination metadata is placed. Default is PaginationInMeta. Unwrapped data
+	// always uses headers.
+	Pagination PaginationPlacement
+	// Indent, when set, indents the output with the given string
+	Indent string
+}
+
+// JSONWriter writes JSON API responses following a consistent envelope and field naming policy, so an
+// application can match an existing API convention in one place.
+//
+//	api := render.NewJSONWriter(func(opts *render.JSONOptions) {
+//		opts.FieldCase = render.FieldCaseCamel
+//	})
+//	api.Data(w, http.StatusOK, user)                 // {"data": {"userId": 1, ...}}
+//	api.Page(w, http.StatusOK, users, pagination)    // {"data": [...], "meta": {"pagination": {...}}}
+//	api.Error(w, http.StatusUnprocessableEntity, "invalid input", map[string]string{"email": "is required"})
+type JSONWriter struct {
+	opts JSONOptions
+}
+
+// DefaultJSON is the JSONWriter used by WriteJSON and WriteJSONError
+var DefaultJSON = NewJSONWriter(nil)
+
+// NewJSONWriter creates a JSONWriter
+func NewJSONWriter(optsFunc func(opts *JSONOptions)) *JSONWriter {
+	opts := JSONOptions{
+		DataKey:  "data",
+		ErrorKey: "error",
+		MetaKey:  "meta",
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.MetaKey == "" {
+		opts.MetaKey = "meta"
+	}
+
+	return &JSONWriter{opts: opts}
+}
+
+// Data writes data with the status code, wrapped under DataKey
+func (j *JSONWriter) Data(w http.ResponseWriter, status int, data any) error {
+	return j.write(w, status, j.wrapData(data, nil))
+}
+
+// Meta writes data with additional metadata under MetaKey. Metadata is dropped when data is unwrapped.
+func (j *JSONWriter) Meta(w http.ResponseWriter, status int, data any, meta map[string]any) error {
+	return j.write(w, status, j.wrapData(data, meta))
+}
+
+// Page writes a page of a collection with its pagination metadata
+func (j *JSONWriter) Page(w http.ResponseWriter, status int, data any, pagination Pagination) error {
+	return j.page(w, status, data, pagination, nil)
+}
+
+// page writes a page of a collection with its pagination and additional metadata
+func (j *JSONWriter) page(w http.ResponseWriter, status int, data any, pagination Pagination, meta map[string]any) error {
+	if j.opts.DataKey == "-" || j.opts.Pagination == PaginationInHeaders {
+		h := w.Header()
+		h.Set("X-Page", strconv.Itoa(pagination.Page))
+		h.Set("X-Per-Page", strconv.Itoa(pagination.PerPage))
+		h.Set("X-Total-Count", strconv.Itoa(pagination.Total))
+		h.Set("X-Total-Pages", strconv.Itoa(pagination.TotalPages))
+		return j.Meta(w, status, data, meta)
+	}
+
+	if j.opts.Pagination == PaginationInBody {
+		body := j.wrapData(data, meta).(map[string]any)
+		body["pagination"] = pagination
+		return j.write(w, status, body)
+	}
+
+	merged := map[string]any{"pagination": pagination}
+	for k, v := range meta {
+		merged[k] = v
+	}
+	return j.Meta(w, status, data, merged)
+}
+
+// Error writes an error message with optional field errors, wrapped under ErrorKey:
+// {"error": {"message": "...", "fields": {"email": "..."}}}
+func (j *JSONWriter) Error(w http.ResponseWriter, status int, message string, fields map[string]string) error {
+	body := map[string]any{"message": message}
+	if len(fields) > 0 {
+		body["fields"] = fields
+	}
+	if j.opts.ErrorKey != "-" {
+		return j.write(w, status, map[string]any{j.opts.ErrorKey: body})
+	}
+	return j.write(w, status, body)
+}
+
+// Encode returns v encoded with the writer's field naming policy and indentation, without an envelope
+func (j *JSONWriter) Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	out := buf.Bytes()
+	if j.opts.FieldCase != FieldCaseKeep {
+		var err error
+		if out, err = renameKeys(out, j.caseFunc()); err != nil {
+			return nil, err
+		}
+	}
+
+	if j.opts.Indent != "" {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, out, "", j.opts.Indent); err != nil {
+			return nil, err
+		}
+		out = indented.Bytes()
+	}
+
+	return bytes.TrimRight(out, "\n"), nil
+}
+
+// wrapData wraps data and metadata in the envelope
+func (j *JSONWriter) wrapData(data any, meta map[string]any) any {
+	if j.opts.DataKey == "-" {
+		return data
+	}
+	body := map[string]any{j.opts.DataKey: data}
+	if len(meta) > 0 {
+		body[j.opts.MetaKey] = meta
+	}
+	return body
+}
+
+// write encodes v and writes it with the status code. Encoding happens before anything is written, so an
+// encoding error can still be turned into an error response by the caller.
+func (j *JSONWriter) write(w http.ResponseWriter, status int, v any) error {
+	out, err := j.Encode(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// caseFunc returns the key conversion for the field naming policy
+func (j *JSONWriter) caseFunc() func(string) string {
+	if j.opts.FieldCase == FieldCaseCamel {
+		return toCamelCase
+	}
+	return toSnakeCase
+}
+
+// WriteJSON writes data with DefaultJSON
+func WriteJSON(w http.ResponseWriter, status int, data any) error {
+	return DefaultJSON.Data(w, status, data)
+}
+
+// WriteJSONError writes an error with DefaultJSON
+func WriteJSONError(w http.ResponseWriter, status int, message string, fields map[string]string) error {
+	return DefaultJSON.Error(w, status, message, fields)
+}
+
+// renameKeys rewrites the keys of every object in a JSON document, preserving key order and values
+func renameKeys(src []byte, rename func(string) string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+
+	// Each open container counts the tokens written into it; in objects, odd counts are keys
+	type container struct {
+		object bool
+		count  int
+	}
+	var (
+		out     bytes.Buffer
+		scratch bytes.Buffer
+		stack   []container
+	)
+	strEnc := json.NewEncoder(&scratch)
+	strEnc.SetEscapeHTML(false)
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		isKey := false
+		closing := tok == json.Delim('}') || tok == json.Delim(']')
+		if n := len(stack); n > 0 && !closing {
+			top := &stack[n-1]
+			if top.count > 0 {
+				if top.object && top.count%2 == 1 {
+					out.WriteByte(':')
+				} else {
+					out.WriteByte(',')
+				}
+			}
+			top.count++
+			isKey = top.object && top.count%2 == 1
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteRune(rune(v))
+			if closing {
+				stack = stack[:len(stack)-1]
+			} else {
+				stack = append(stack, container{object: v == '{'})
+			}
+		case string:
+			if isKey {
+				v = rename(v)
+			}
+			scratch.Reset()
+			_ = strEnc.Encode(v)
+			out.Write(bytes.TrimRight(scratch.Bytes(), "\n"))
+		case json.Number:
+			out.WriteString(v.String())
+		case bool:
+			out.WriteString(strconv.FormatBool(v))
+		case nil:
+			out.WriteString("null")
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// splitWords splits a key into words at underscores, hyphens, spaces and case changes, keeping acronyms
+// together: "UserID" and "user_id" both become ["user", "id"]
+func splitWords(s string) []string {
+	var (
+		words   []string
+		current []rune
+	)
+	runes := []rune(s)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == ' ':
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// toSnakeCase converts a key to snake_case
+func toSnakeCase(s string) string {
+	return strings.Join(splitWords(s), "_")
+}
+
+// toCamelCase converts a key to camelCase
+func toCamelCase(s string) string {
+	words := splitWords(s)
+	for i := 1; i < len(words); i++ {
+		r := []rune(words[i])
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, "")
+}
@@ -0,0 +1,320 @@
+package render
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/patrickward/hop/render/htmx"
+	"github.com/patrickward/hop/render/htmx/trigger"
+	"github.com/patrickward/hop/render/request"
+)
+
+// JSONResponse builds a JSON API response. Like Response, it uses a fluent interface, so methods can be
+// called in any order before the response is sent with Send, Error, DecodeError or Stream.
+//
+//	render.JSON().
+//		Status(http.StatusCreated).
+//		Header("Location", "/users/42").
+//		HxTrigger("user-created", user.ID).
+//		Send(w, user)
+type JSONResponse struct {
+	// The writer used to encode the response (default: DefaultJSON)
+	writer *JSONWriter
+	// The status code of the response (default: http.StatusOK)
+	statusCode int
+	// The headers to be passed to the response (default: empty)
+	headers http.Header
+	// The htmx triggers to be passed to the response (default: empty)
+	triggers *trigger.Triggers
+	// The metadata written next to the data (default: empty)
+	meta map[string]any
+	// The pagination of the data, if it is a page of a collection (default: nil)
+	pagination *Pagination
+	// Whether the data is written without the envelope (default: false)
+	unwrapped bool
+}
+
+// JSON creates a JSONResponse that is encoded with DefaultJSON
+func JSON() *JSONResponse {
+	return DefaultJSON.Response()
+}
+
+// Response creates a JSONResponse that is encoded with the writer
+func (j *JSONWriter) Response() *JSONResponse {
+	return &JSONResponse{
+		writer:     j,
+		statusCode: http.StatusOK,
+		headers:    make(http.Header),
+		triggers:   trigger.NewTriggers(),
+	}
+}
+
+// Status sets the status code of the response
+func (resp *JSONResponse) Status(code int) *JSONResponse {
+	resp.statusCode = code
+	return resp
+}
+
+// Header sets a response header
+func (resp *JSONResponse) Header(key, value string) *JSONResponse {
+	resp.headers.Set(key, value)
+	return resp
+}
+
+// HxTrigger sets an htmx event to trigger on the client when the response is received, sent in the
+// HX-Trigger header.
+//
+// For more information, see: https://htmx.org/headers/hx-trigger
+func (resp *JSONResponse) HxTrigger(name string, value any) *JSONResponse {
+	resp.triggers.Set(name, value)
+	return resp
+}
+
+// HxTriggerAfterSwap sets an htmx event to trigger on the client after the swap step, sent in the
+// HX-Trigger-After-Swap header.
+//
+// For more information, see: https://htmx.org/headers/hx-trigger
+func (resp *JSONResponse) HxTriggerAfterSwap(name string, value any) *JSONResponse {
+	resp.triggers.SetAfterSwap(name, value)
+	return resp
+}
+
+// HxTriggerAfterSettle sets an htmx event to trigger on the client after the settle step, sent in the
+// HX-Trigger-After-Settle header.
+//
+// For more information, see: https://htmx.org/headers/hx-trigger
+func (resp *JSONResponse) HxTriggerAfterSettle(name string, value any) *JSONResponse {
+	resp.triggers.SetAfterSettle(name, value)
+	return resp
+}
+
+// Meta adds a metadata value, written under the writer's MetaKey. Metadata is dropped when the data is
+// unwrapped.
+func (resp *JSONResponse) Meta(key string, value any) *JSONResponse {
+	if resp.meta == nil {
+		resp.meta = make(map[string]any)
+	}
+	resp.meta[key] = value
+	return resp
+}
+
+// Page marks the data as a page of a collection, placing the pagination as configured on the writer
+func (resp *JSONResponse) Page(pagination Pagination) *JSONResponse {
+	resp.pagination = &pagination
+	return resp
+}
+
+// Unwrapped writes the data without the envelope, whatever the writer's DataKey
+func (resp *JSONResponse) Unwrapped() *JSONResponse {
+	resp.unwrapped = true
+	return resp
+}
+
+// Send writes data as the response
+func (resp *JSONResponse) Send(w http.ResponseWriter, data any) error {
+	j := resp.jsonWriter()
+	if err := resp.writeHeaders(w); err != nil {
+		return err
+	}
+
+	if resp.pagination != nil {
+		return j.page(w, resp.statusCode, data, *resp.pagination, resp.meta)
+	}
+	return j.Meta(w, resp.statusCode, data, resp.meta)
+}
+
+// Error writes an error message with optional field errors as the response. The status code defaults to
+// 500 Internal Server Error unless an error status was set with Status.
+func (resp *JSONResponse) Error(w http.ResponseWriter, message string, fields map[string]string) error {
+	if err := resp.writeHeaders(w); err != nil {
+		return err
+	}
+
+	status := resp.statusCode
+	if status < http.StatusBadRequest {
+		status = http.StatusInternalServerError
+	}
+	return resp.writer.Error(w, status, message, fields)
+}
+
+// DecodeError writes an error returned by request.DecodeJSON as the response, with the status code and
+// message of the *request.DecodeError. Any other error is written as a 500 Internal Server Error without
+// exposing its message.
+//
+//	if err := request.DecodeJSON(w, r, &input, nil); err != nil {
+//		_ = render.JSON().DecodeError(w, err)
+//		return
+//	}
+func (resp *JSONResponse) DecodeError(w http.ResponseWriter, err error) error {
+	var decodeErr *request.DecodeError
+	if !errors.As(err, &decodeErr) {
+		return resp.Status(http.StatusInternalServerError).Error(w, http.StatusText(http.StatusInternalServerError), nil)
+	}
+
+	var fields map[string]string
+	if decodeErr.Field != "" {
+		fields = map[string]string{decodeErr.Field: decodeErr.Message}
+	}
+	return resp.Status(decodeErr.Status).Error(w, decodeErr.Message, fields)
+}
+
+// Stream writes a collection as it is produced, without holding it in memory, e.g. while iterating over
+// database rows. The items are written in an array under the writer's DataKey, followed by the metadata;
+// pagination set with Page is ignored. The output is never indented.
+//
+// The status code and headers are sent before fn runs, so if fn returns an error it can no longer be
+// reported to the client: the document is left unterminated, making the failure visible as invalid JSON
+// rather than a silently truncated collection.
+//
+//	err := render.JSON().Stream(w, func(enc *render.JSONStream) error {
+//		for rows.Next() {
+//			...
+//			if err := enc.Encode(order); err != nil {
+//				return err
+//			}
+//		}
+//		return rows.Err()
+//	})
+func (resp *JSONResponse) Stream(w http.ResponseWriter, fn func(enc *JSONStream) error) error {
+	j := *resp.jsonWriter()
+	j.opts.Indent = ""
+	if err := resp.writeHeaders(w); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(resp.statusCode)
+
+	wrapped := j.opts.DataKey != "-"
+	if wrapped {
+		if err := resp.writeKey(w, &j, "{", j.opts.DataKey); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	stream := &JSONStream{w: w, rc: http.NewResponseController(w), writer: &j}
+	if err := fn(stream); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, "]"); err != nil {
+		return err
+	}
+	if wrapped {
+		if len(resp.meta) > 0 {
+			if err := resp.writeKey(w, &j, ",", j.opts.MetaKey); err != nil {
+				return err
+			}
+			meta, err := j.Encode(resp.meta)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(meta); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "}"); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return err
+	}
+	return stream.Flush()
+}
+
+// jsonWriter returns the writer to encode the response with
+func (resp *JSONResponse) jsonWriter() *JSONWriter {
+	if !resp.unwrapped {
+		return resp.writer
+	}
+	j := *resp.writer
+	j.opts.DataKey = "-"
+	return &j
+}
+
+// writeHeaders sets the headers and htmx triggers of the response
+func (resp *JSONResponse) writeHeaders(w http.ResponseWriter) error {
+	h := w.Header()
+	for key, values := range resp.headers {
+		h[key] = values
+	}
+
+	if resp.triggers.HasTriggers() {
+		val, err := resp.triggers.TriggerHeader()
+		if err != nil {
+			return err
+		}
+		h.Set(htmx.HXTrigger, val)
+	}
+	if resp.triggers.HasAfterSwapTriggers() {
+		val, err := resp.triggers.TriggerAfterSwapHeader()
+		if err != nil {
+			return err
+		}
+		h.Set(htmx.HXTriggerAfterSwap, val)
+	}
+	if resp.triggers.HasAfterSettleTriggers() {
+		val, err := resp.triggers.TriggerAfterSettleHeader()
+		if err != nil {
+			return err
+		}
+		h.Set(htmx.HXTriggerAfterSettle, val)
+	}
+	return nil
+}
+
+// writeKey writes a delimiter and an object key, named with the writer's field naming policy
+func (resp *JSONResponse) writeKey(w io.Writer, j *JSONWriter, delim, key string) error {
+	if j.opts.FieldCase != FieldCaseKeep {
+		key = j.caseFunc()(key)
+	}
+	encoded, err := j.Encode(key)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, delim+string(encoded)+":")
+	return err
+}
+
+// JSONStream writes the items of a collection streamed by JSONResponse.Stream
+type JSONStream struct {
+	w      io.Writer
+	rc     *http.ResponseController
+	writer *JSONWriter
+	count  int
+}
+
+// Encode writes an item of the collection, encoded with the writer's field naming policy
+func (s *JSONStream) Encode(v any) error {
+	out, err := s.writer.Encode(v)
+	if err != nil {
+		return err
+	}
+	if s.count > 0 {
+		out = append([]byte{','}, out...)
+	}
+	if _, err := s.w.Write(out); err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+// Flush sends the items written so far to the client. Flushing is optional: items are sent as the
+// response buffer fills up.
+func (s *JSONStream) Flush() error {
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// Len returns the number of items written
+func (s *JSONStream) Len() int {
+	return s.count
+}
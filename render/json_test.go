@@ -0,0 +1,270 @@
+package render_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render"
+	"github.com/patrickward/hop/render/request"
+)
+
+type apiUser struct {
+	UserID    int    `json:"user_id"`
+	FirstName string `json:"firstName"`
+	HTMLBio   string
+	Tags      []string
+}
+
+func TestJSONWriter(t *testing.T) {
+	user := apiUser{UserID: 7, FirstName: "Ada", HTMLBio: "<b>hi</b>", Tags: []string{"a"}}
+
+	tests := []struct {
+		name       string
+		optsFunc   func(opts *render.JSONOptions)
+		write      func(j *render.JSONWriter, w http.ResponseWriter) error
+		expectBody string
+		expectHdr  map[string]string
+	}{
+		{
+			name:       "default envelope",
+			write:      func(j *render.JSONWriter, w http.ResponseWriter) error { return j.Data(w, http.StatusOK, user) },
+			expectBody: `{"data":{"user_id":7,"firstName":"Ada","HTMLBio":"<b>hi</b>","Tags":["a"]}}`,
+		},
+		{
+			name:       "snake case",
+			optsFunc:   func(opts *render.JSONOptions) { opts.FieldCase = render.FieldCaseSnake },
+			write:      func(j *render.JSONWriter, w http.ResponseWriter) error { return j.Data(w, http.StatusOK, user) },
+			expectBody: `{"data":{"user_id":7,"first_name":"Ada","html_bio":"<b>hi</b>","tags":["a"]}}`,
+		},
+		{
+			name: "camel case without envelope",
+			optsFunc: func(opts *render.JSONOptions) {
+				opts.FieldCase = render.FieldCaseCamel
+				opts.DataKey = "-"
+			},
+			write:      func(j *render.JSONWriter, w http.ResponseWriter) error { return j.Data(w, http.StatusOK, user) },
+			expectBody: `{"userId":7,"firstName":"Ada","htmlBio":"<b>hi</b>","tags":["a"]}`,
+		},
+		{
+			name: "pagination in meta",
+			write: func(j *render.JSONWriter, w http.ResponseWriter) error {
+				return j.Page(w, http.StatusOK, []int{1, 2}, render.NewPagination(2, 2, 5))
+			},
+			expectBody: `{"data":[1,2],"meta":{"pagination":{"page":2,"per_page":2,"total":5,"total_pages":3}}}`,
+		},
+		{
+			name: "pagination in body with camel case",
+			optsFunc: func(opts *render.JSONOptions) {
+				opts.Pagination = render.PaginationInBody
+				opts.FieldCase = render.FieldCaseCamel
+			},
+			write: func(j *render.JSONWriter, w http.ResponseWriter) error {
+				return j.Page(w, http.StatusOK, []int{1, 2}, render.NewPagination(1, 2, 5))
+			},
+			expectBody: `{"data":[1,2],"pagination":{"page":1,"perPage":2,"total":5,"totalPages":3}}`,
+		},
+		{
+			name:     "pagination in headers",
+			optsFunc: func(opts *render.JSONOptions) { opts.Pagination = render.PaginationInHeaders },
+			write: func(j *render.JSONWriter, w http.ResponseWriter) error {
+				return j.Page(w, http.StatusOK, []int{1}, render.NewPagination(1, 1, 3))
+			},
+			expectBody: `{"data":[1]}`,
+			expectHdr:  map[string]string{"X-Total-Count": "3", "X-Total-Pages": "3", "X-Page": "1"},
+		},
+		{
+			name: "error",
+			write: func(j *render.JSONWriter, w http.ResponseWriter) error {
+				return j.Error(w, http.StatusUnprocessableEntity, "invalid input", map[string]string{"email": "is required"})
+			},
+			expectBody: `{"error":{"fields":{"email":"is required"},"message":"invalid input"}}`,
+		},
+		{
+			name:     "unwrapped error",
+			optsFunc: func(opts *render.JSONOptions) { opts.ErrorKey = "-" },
+			write: func(j *render.JSONWriter, w http.ResponseWriter) error {
+				return j.Error(w, http.StatusNotFound, "not found", nil)
+			},
+			expectBody: `{"message":"not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			require.NoError(t, tt.write(render.NewJSONWriter(tt.optsFunc), rec))
+
+			assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectBody+"\n", rec.Body.String())
+			for k, v := range tt.expectHdr {
+				assert.Equal(t, v, rec.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestJSONWriter_EncodeError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := render.WriteJSON(rec, http.StatusOK, map[string]any{"bad": func() {}})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String(), "nothing is written when encoding fails")
+}
+
+func TestJSONResponse(t *testing.T) {
+	t.Run("status, headers and triggers", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := render.JSON().
+			Status(http.StatusCreated).
+			Header("Location", "/users/7").
+			HxTrigger("user-created", 7).
+			Meta("version", 2).
+			Send(rec, map[string]int{"id": 7})
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "/users/7", rec.Header().Get("Location"))
+		assert.JSONEq(t, `{"user-created":7}`, rec.Header().Get("HX-Trigger"))
+		assert.Equal(t, `{"data":{"id":7},"meta":{"version":2}}`+"\n", rec.Body.String())
+	})
+
+	t.Run("page with metadata", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := render.JSON().Meta("sort", "name").Page(render.NewPagination(1, 2, 3)).Send(rec, []int{1, 2})
+		require.NoError(t, err)
+		assert.Equal(t, `{"data":[1,2],"meta":{"pagination":{"page":1,"per_page":2,"total":3,"total_pages":2},"sort":"name"}}`+"\n", rec.Body.String())
+	})
+
+	t.Run("unwrapped", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		require.NoError(t, render.JSON().Unwrapped().Page(render.NewPagination(1, 2, 3)).Send(rec, []int{1, 2}))
+		assert.Equal(t, "[1,2]\n", rec.Body.String())
+		assert.Equal(t, "3", rec.Header().Get("X-Total-Count"))
+	})
+
+	t.Run("errors default to 500", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		require.NoError(t, render.JSON().Error(rec, "something went wrong", nil))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, `{"error":{"message":"something went wrong"}}`+"\n", rec.Body.String())
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		writer := render.NewJSONWriter(func(opts *render.JSONOptions) {
+			opts.FieldCase = render.FieldCaseCamel
+			opts.DataKey = "items"
+			opts.Indent = "  "
+		})
+		err := writer.Response().Meta("next_cursor", "abc").Stream(rec, func(enc *render.JSONStream) error {
+			for i := range 3 {
+				if err := enc.Encode(apiUser{UserID: i}); err != nil {
+					return err
+				}
+			}
+			assert.Equal(t, 3, enc.Len())
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"items":[`+
+			`{"userId":0,"firstName":"","htmlBio":"","tags":null},`+
+			`{"userId":1,"firstName":"","htmlBio":"","tags":null},`+
+			`{"userId":2,"firstName":"","htmlBio":"","tags":null}`+
+			`],"meta":{"nextCursor":"abc"}}`+"\n", rec.Body.String())
+	})
+
+	t.Run("failed streams are left unterminated", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := render.JSON().Stream(rec, func(enc *render.JSONStream) error {
+			require.NoError(t, enc.Encode(1))
+			return assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, `{"data":[1`, rec.Body.String())
+	})
+}
+
+func TestDecodeJSON(t *testing.T) {
+	type input struct {
+		Email string `json:"email"`
+		Age   int    `json:"age"`
+	}
+
+	tests := []struct {
+		name          string
+		contentType   string
+		body          string
+		optsFunc      func(opts *request.DecodeJSONOptions)
+		expectStatus  int
+		expectMessage string
+		expectField   string
+	}{
+		{name: "valid", body: `{"email":"ada@example.com","age":36}`},
+		{name: "vendor media type", contentType: "application/vnd.api+json", body: `{"age":1}`},
+		{name: "wrong content type", contentType: "text/plain", body: `{}`, expectStatus: http.StatusUnsupportedMediaType, expectMessage: "Content-Type must be application/json"},
+		{name: "empty", body: ``, expectStatus: http.StatusBadRequest, expectMessage: "request body must not be empty"},
+		{name: "malformed", body: `{"email":}`, expectStatus: http.StatusBadRequest, expectMessage: "request body contains badly-formed JSON (at position 10)"},
+		{name: "truncated", body: `{"email":"ada`, expectStatus: http.StatusBadRequest, expectMessage: "request body contains badly-formed JSON"},
+		{name: "wrong type", body: `{"age":"old"}`, expectStatus: http.StatusBadRequest, expectMessage: `request body contains an invalid value for the "age" field`, expectField: "age"},
+		{name: "unknown field", body: `{"name":"Ada"}`, expectStatus: http.StatusBadRequest, expectMessage: `request body contains unknown field "name"`, expectField: "name"},
+		{name: "unknown field allowed", body: `{"name":"Ada"}`, optsFunc: func(opts *request.DecodeJSONOptions) { opts.AllowUnknownFields = true }},
+		{name: "multiple values", body: `{} {}`, expectStatus: http.StatusBadRequest, expectMessage: "request body must only contain a single JSON value"},
+		{
+			name:          "too large",
+			body:          `{"email":"ada@example.com"}`,
+			optsFunc:      func(opts *request.DecodeJSONOptions) { opts.MaxBytes = 8 },
+			expectStatus:  http.StatusRequestEntityTooLarge,
+			expectMessage: "request body must not be larger than 8 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+
+			var dst input
+			err := request.DecodeJSON(rec, req, &dst, tt.optsFunc)
+			if tt.expectStatus == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var decodeErr *request.DecodeError
+			require.ErrorAs(t, err, &decodeErr)
+			assert.Equal(t, tt.expectStatus, decodeErr.Status)
+			assert.Equal(t, tt.expectMessage, decodeErr.Message)
+			assert.Equal(t, tt.expectField, decodeErr.Field)
+
+			require.NoError(t, render.JSON().DecodeError(rec, err))
+			assert.Equal(t, tt.expectStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"message"`)
+		})
+	}
+
+	t.Run("non-pointer destinations are programming errors", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		err := request.DecodeJSON(rec, req, input{}, nil)
+		require.Error(t, err)
+		var decodeErr *request.DecodeError
+		assert.False(t, errors.As(err, &decodeErr))
+
+		require.NoError(t, render.JSON().DecodeError(rec, err))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
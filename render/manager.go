@@ -1,7 +1,6 @@
 package render
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"html/template"
@@ -9,16 +8,35 @@ import (
 	"log/slog"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"text/template/parse"
+	"time"
 
+	"github.com/patrickward/hop/i18n"
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/render/alert"
 	"github.com/patrickward/hop/templates"
 )
 
 const defaultFSKey = "DEFAULT_FS"
 
+// Sources maps source IDs to the file systems holding templates. The source with the empty ID, or "-",
+// is the default one; views in the others are rendered with "<id>:<path>", e.g. "admin:dashboard".
 type Sources map[string]fs.FS
 
+// WithSource adds a named source and returns the Sources, so modules can ship their own templates:
+//
+//	sources := render.Sources{"": app.Templates}.WithSource("admin", admin.Templates)
+func (s Sources) WithSource(id string, fsys fs.FS) Sources {
+	if s == nil {
+		s = make(Sources)
+	}
+	s[id] = fsys
+	return s
+}
+
 // TemplateManager is a template adapter for the HyperView framework that uses the Go html/template package.
 type TemplateManager struct {
 	baseLayout    string
@@ -27,6 +45,25 @@ type TemplateManager struct {
 	fileSystemMap map[string]fs.FS
 	logger        *slog.Logger
 	funcMap       template.FuncMap
+	components    *templates.Components
+	// allowRecursion and maxIncludeDepth configure the include checks run when templates are parsed
+	allowRecursion  bool
+	maxIncludeDepth int
+	// navigationLayout is used for htmx requests rendered with Response.HxNavigate
+	navigationLayout string
+	// a11yAudit and a11yReporter configure the accessibility audit of rendered templates
+	a11yAudit    bool
+	a11yReporter A11yReporter
+	// metrics receives the size and duration of rendered templates, and templateMetrics the cache lookups
+	// and errors, when metrics also implements pulse.TemplateRecorder
+	metrics         pulse.RenderRecorder
+	templateMetrics pulse.TemplateRecorder
+	// slowRender is the render duration above which a warning is logged
+	slowRender time.Duration
+	// buffers holds the buffers pages are rendered to before they are written
+	buffers *bufferPool
+	// session stores the flash messages of Response.RedirectWithFlash
+	session alert.Session
 	//templates     map[string]*template.Template
 
 	templateCache      sync.Map
@@ -46,11 +83,64 @@ type TemplateManagerOptions struct {
 	// Extension is the file extension for the templates. Default is ".html".
 	Extension string
 
-	// Funcs is a map of functions to add to default set of template functions made available. See the `templates/funcmap` package for a list of default functions.
+	// Funcs is a map of functions to add to default set of template functions made available. See the `templates/funcmap` package for a list of default functions,
+	// and the `i18n` package for the t and tn translation functions.
 	Funcs template.FuncMap
 
+	// URLFor, when set, is available to templates as urlFor, to generate URLs from route names, e.g.
+	// {{urlFor "user.show" "id" .User.ID}}. The App sets it to its router's URL method.
+	URLFor func(name string, params ...any) (string, error)
+
 	// Logger is the logger to use for logging errors. Default is nil.
 	Logger *slog.Logger
+
+	// Components is an optional registry of components shared with the mail package. The web version of
+	// each component is available to every template as "component:<name>".
+	Components *templates.Components
+
+	// MaxIncludeDepth limits how deeply templates may include each other through {{template}} calls.
+	// Deeper chains fail to load with ErrTempDepth. Default is DefaultMaxIncludeDepth; -1 disables the limit.
+	MaxIncludeDepth int
+
+	// AllowRecursion permits templates that include themselves, directly or through other templates, such
+	// as a partial rendering a tree. By default, recursive includes fail to load with ErrTempRecursion,
+	// which catches partials that accidentally include each other.
+	AllowRecursion bool
+
+	// NavigationLayout is the layout used for htmx requests rendered with Response.HxNavigate, so that a
+	// partial swap receives only the page content. It should render a <title> element with {{.Page.Title}},
+	// which htmx uses to update the document title. Default is "", which keeps the response's layout.
+	NavigationLayout string
+
+	// A11yAudit checks every rendered page for common accessibility issues, such as images without alt
+	// text, form controls without labels and duplicate ids. It is meant for development: the App enables
+	// it in the development environment. Issues are logged as warnings, or passed to A11yReporter, and
+	// counted in the X-A11y-Issues response header.
+	A11yAudit bool
+
+	// A11yReporter, when set, receives the issues found by A11yAudit instead of the logger
+	A11yReporter A11yReporter
+
+	// Metrics, when set, records the uncompressed size and the render duration of every rendered page, per
+	// template path. When it also implements pulse.TemplateRecorder, it records template cache hits and
+	// misses and template errors too. A pulse.StandardCollector shows the heaviest pages on its dashboard.
+	Metrics pulse.RenderRecorder
+
+	// SlowRenderThreshold, when set, logs a warning for every page that takes longer to render and write,
+	// with its template path and duration. Default is 0, which disables the warning.
+	SlowRenderThreshold time.Duration
+
+	// MaxPooledBufferSize is the size above which the buffers pages are rendered to are dropped instead of
+	// reused, so that rendering a huge page doesn't keep its memory around. Pages are rendered to a buffer
+	// first, so a template error results in the error page instead of a truncated page; very large pages
+	// can be streamed instead with Response.Stream. Default is DefaultMaxPooledBufferSize; -1 disables
+	// buffer reuse.
+	MaxPooledBufferSize int
+
+	// Session stores the flash messages set by Response.RedirectWithFlash. Rendered pages receive them as
+	// "Flash", a []alert.Alert that is removed from the session once rendered. The App sets it to its
+	// session manager.
+	Session alert.Session
 }
 
 // NewTemplateManager creates a new TemplateManager.
@@ -58,7 +148,10 @@ type TemplateManagerOptions struct {
 // For sources, if the string key is empty or "-", it will be treated as the default file system. Otherwise, the key is used as the file system ID.
 // e.g., "foo:bar" for a template named "bar" in the "foo" file system.
 func NewTemplateManager(sources Sources, opts TemplateManagerOptions) (*TemplateManager, error) {
-	funcMap := templates.MergeFuncMaps(templates.FuncMap(), opts.Funcs)
+	funcMap := templates.MergeFuncMaps(templates.FuncMap(), i18n.FuncMap(), opts.Funcs)
+	if opts.URLFor != nil {
+		funcMap["urlFor"] = opts.URLFor
+	}
 
 	// Set default extension if not provided
 	if opts.Extension == "" {
@@ -80,6 +173,14 @@ func NewTemplateManager(sources Sources, opts TemplateManagerOptions) (*Template
 		opts.SystemLayout = opts.BaseLayout
 	}
 
+	if opts.MaxIncludeDepth == 0 {
+		opts.MaxIncludeDepth = DefaultMaxIncludeDepth
+	}
+
+	if opts.MaxPooledBufferSize == 0 {
+		opts.MaxPooledBufferSize = DefaultMaxPooledBufferSize
+	}
+
 	// Normalize the filesystem map to use our default key
 	normalizedSources := make(Sources)
 	for k, v := range sources {
@@ -97,8 +198,20 @@ func NewTemplateManager(sources Sources, opts TemplateManagerOptions) (*Template
 		systemLayout:  opts.SystemLayout,
 		extension:     opts.Extension,
 		funcMap:       funcMap,
+		components:    opts.Components,
 		templateCache: sync.Map{},
+
+		allowRecursion:   opts.AllowRecursion,
+		maxIncludeDepth:  opts.MaxIncludeDepth,
+		navigationLayout: opts.NavigationLayout,
+		a11yAudit:        opts.A11yAudit,
+		a11yReporter:     opts.A11yReporter,
+		metrics:          opts.Metrics,
+		slowRender:       opts.SlowRenderThreshold,
+		buffers:          newBufferPool(opts.MaxPooledBufferSize),
+		session:          opts.Session,
 	}
+	tm.templateMetrics, _ = opts.Metrics.(pulse.TemplateRecorder)
 
 	return tm, tm.Initialize()
 }
@@ -150,6 +263,77 @@ func (tm *TemplateManager) Initialize() error {
 	return nil
 }
 
+// AddSource adds a template source after the TemplateManager was created, e.g. for a module that ships its
+// own templates. Its views are available as "<id>:<path>", and its layouts and partials join the shared
+// set. Layouts of the default source win, while its own views use its own layouts first. Partials must not
+// clash with those of other sources, so they are best named after the source, e.g. "partial:<id>:nav";
+// a clash returns ErrTempDuplicate and leaves the source out. It must be called before templates are
+// rendered.
+func (tm *TemplateManager) AddSource(id string, fsys fs.FS) error {
+	if id == "" || id == "-" {
+		id = defaultFSKey
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if _, exists := tm.fileSystemMap[id]; exists {
+		return fmt.Errorf("template source already exists: %s", id)
+	}
+
+	tm.fileSystemMap[id] = fsys
+	common, err := tm.loadLayoutsAndPartials()
+	if err != nil {
+		delete(tm.fileSystemMap, id)
+		return fmt.Errorf("failed to load layouts and partials: %w", err)
+	}
+
+	tm.layoutsAndPartials = common
+	tm.templateCache.Clear()
+	return nil
+}
+
+// CheckAll parses every view in every source, so parse errors, recursive includes and excessive include
+// nesting are reported at startup instead of on the first request for the view. It returns every failure,
+// each naming the template it occurred in.
+func (tm *TemplateManager) CheckAll() error {
+	fsIDs := make([]string, 0, len(tm.fileSystemMap))
+	for fsID := range tm.fileSystemMap {
+		fsIDs = append(fsIDs, fsID)
+	}
+	sort.Strings(fsIDs)
+
+	var errs []error
+	for _, fsID := range fsIDs {
+		fsys := tm.fileSystemMap[fsID]
+		if _, err := fsys.Open(ViewsDir); err != nil {
+			continue
+		}
+
+		err := fs.WalkDir(fsys, ViewsDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(path) != tm.extension {
+				return nil
+			}
+
+			if fsID != defaultFSKey {
+				path = fsID + ":" + path
+			}
+			if _, err := tm.getTemplate(path); err != nil {
+				errs = append(errs, err)
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // parseTemplatePath splits a template path into filesystem ID and relative path
 func (tm *TemplateManager) parseTemplatePath(path string) (string, string) {
 	parts := strings.SplitN(path, ":", 2)
@@ -165,11 +349,23 @@ func (tm *TemplateManager) parseTemplatePath(path string) (string, string) {
 
 // getTemplate gets or loads a template with embedded error handling
 func (tm *TemplateManager) getTemplate(path string) (*template.Template, error) {
+	tmpl, _, err := tm.lookupTemplate(path)
+	return tmpl, err
+}
+
+// lookupTemplate gets or loads a template, reporting whether it was cached
+func (tm *TemplateManager) lookupTemplate(path string) (*template.Template, bool, error) {
 	// Check cache first
 	if tmpl, ok := tm.templateCache.Load(path); ok {
-		return tmpl.(*template.Template), nil
+		return tmpl.(*template.Template), true, nil
 	}
 
+	tmpl, err := tm.loadTemplate(path)
+	return tmpl, false, err
+}
+
+// loadTemplate parses a template and caches it
+func (tm *TemplateManager) loadTemplate(path string) (*template.Template, error) {
 	// Find the appropriate filesystem and relative path
 	fsID, relPath := tm.parseTemplatePath(path)
 
@@ -190,12 +386,24 @@ func (tm *TemplateManager) getTemplate(path string) (*template.Template, error)
 
 	// Clone and parse the template
 	tm.mu.RLock()
-	tmpl, err := template.Must(tm.layoutsAndPartials.Clone()).ParseFS(fsys, relPath)
+	tmpl := template.Must(tm.layoutsAndPartials.Clone())
 	tm.mu.RUnlock()
-	if err != nil {
+
+	// Views of a named source see its own layouts first, over those of the same name in other sources
+	if fsID != defaultFSKey {
+		if err := tm.parseLayoutsAndPartials(tmpl, fsys); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrTempParse, err)
+		}
+	}
+
+	if _, err := tmpl.ParseFS(fsys, relPath); err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrTempParse, err)
 	}
 
+	if err := checkIncludes(tmpl, tm.allowRecursion, tm.maxIncludeDepth); err != nil {
+		return nil, fmt.Errorf("%s: %w", relPath, err)
+	}
+
 	// Cache the template
 	actual, loaded := tm.templateCache.LoadOrStore(path, tmpl)
 	if loaded {
@@ -210,42 +418,128 @@ func (tm *TemplateManager) getTemplate(path string) (*template.Template, error)
 func (tm *TemplateManager) loadLayoutsAndPartials() (*template.Template, error) {
 	commonTemplates := template.New("_common_").Funcs(tm.funcMap)
 
-	for _, fsys := range tm.fileSystemMap {
-		// First, load layouts into the common template
-		layoutPath := LayoutsDir + "/*" + tm.extension
-		_, err := commonTemplates.ParseFS(fsys, layoutPath)
-		if err != nil {
+	// Add shared components first, so partials can use them and sources can redefine them
+	if tm.components != nil {
+		if err := tm.components.AddTo(commonTemplates, templates.MediumWeb); err != nil {
 			return nil, err
 		}
+	}
 
-		processPartials := func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-
-			if !d.IsDir() && filepath.Ext(path) == tm.extension {
-				fullPath := path
+	// The default source is loaded last, so the app can redefine the layouts and partials of modules
+	fsIDs := make([]string, 0, len(tm.fileSystemMap))
+	for fsID := range tm.fileSystemMap {
+		if fsID != defaultFSKey {
+			fsIDs = append(fsIDs, fsID)
+		}
+	}
+	sort.Strings(fsIDs)
+	if _, ok := tm.fileSystemMap[defaultFSKey]; ok {
+		fsIDs = append(fsIDs, defaultFSKey)
+	}
 
-				// Parse the partial template in the common template
-				_, err := commonTemplates.ParseFS(fsys, fullPath)
-				if err != nil {
-					return err
-				}
+	// A partial defined by two sources would replace the other in views that use both, e.g. an app layout
+	// rendering a module view
+	owners := make(map[string]string)
+	for _, fsID := range fsIDs {
+		names, err := tm.partialNames(tm.fileSystemMap[fsID])
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if owner, ok := owners[name]; ok {
+				return nil, fmt.Errorf("%w: partial %q is defined by %s and %s", ErrTempDuplicate, name, sourceName(owner), sourceName(fsID))
 			}
-			return nil
+			owners[name] = fsID
 		}
+	}
 
-		// If the "partials" directory exists, parse it
-		if _, err := fsys.Open(PartialsDir); err == nil {
-			if err := fs.WalkDir(fsys, PartialsDir, processPartials); err != nil {
-				return nil, err
-			}
+	for _, fsID := range fsIDs {
+		if err := tm.parseLayoutsAndPartials(commonTemplates, tm.fileSystemMap[fsID]); err != nil {
+			return nil, err
 		}
 	}
 
+	if err := checkIncludes(commonTemplates, tm.allowRecursion, tm.maxIncludeDepth); err != nil {
+		return nil, err
+	}
+
 	return commonTemplates, nil
 }
 
+// parseLayoutsAndPartials parses the layouts and partials of a source into tmpl
+func (tm *TemplateManager) parseLayoutsAndPartials(tmpl *template.Template, fsys fs.FS) error {
+	// First, load layouts. Sources added with AddSource may have no layouts.
+	layoutPath := LayoutsDir + "/*" + tm.extension
+	if matches, _ := fs.Glob(fsys, layoutPath); len(matches) > 0 {
+		if _, err := tmpl.ParseFS(fsys, layoutPath); err != nil {
+			return err
+		}
+	}
+
+	paths, err := tm.partialPaths(fsys)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if _, err := tmpl.ParseFS(fsys, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// partialPaths returns the paths of the partials of a source, if it has a "partials" directory
+func (tm *TemplateManager) partialPaths(fsys fs.FS) ([]string, error) {
+	if _, err := fsys.Open(PartialsDir); err != nil {
+		return nil, nil
+	}
+
+	var paths []string
+	err := fs.WalkDir(fsys, PartialsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == tm.extension {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// partialNames returns the names of the templates the partials of a source define, leaving out files that
+// only hold {{define}} blocks
+func (tm *TemplateManager) partialNames(fsys fs.FS) ([]string, error) {
+	paths, err := tm.partialPaths(fsys)
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+
+	tmpl := template.New("_partials_").Funcs(tm.funcMap)
+	for _, path := range paths {
+		if _, err := tmpl.ParseFS(fsys, path); err != nil {
+			return nil, err
+		}
+	}
+
+	var names []string
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && !parse.IsEmptyTree(t.Tree.Root) {
+			names = append(names, t.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// sourceName describes a template source in errors
+func sourceName(fsID string) string {
+	if fsID == defaultFSKey {
+		return "the default source"
+	}
+	return fmt.Sprintf("source %q", fsID)
+}
+
 //func (tm *TemplateManager) LogTemplateNames() {
 //	for name, tmpl := range tm.templates {
 //		tm.logger.Info("Template", slog.String("name", name))
@@ -258,9 +552,15 @@ func (tm *TemplateManager) loadLayoutsAndPartials() (*template.Template, error)
 
 // render renders a response using the template manager
 func (tm *TemplateManager) render(w http.ResponseWriter, r *http.Request, resp *Response) {
+	start := time.Now()
+	resp.applyNavigation(r, tm.navigationLayout)
 	path := resp.GetTemplatePath()
-	tmpl, err := tm.getTemplate(path)
+	tmpl, cached, err := tm.lookupTemplate(path)
+	if tm.templateMetrics != nil {
+		tm.templateMetrics.RecordTemplateCache(path, cached)
+	}
 	if err != nil {
+		tm.recordTemplateError(path, err)
 		switch {
 		case errors.Is(err, ErrTempNotFound):
 			tm.renderSystemError(w, r, resp, 404, err)
@@ -272,10 +572,21 @@ func (tm *TemplateManager) render(w http.ResponseWriter, r *http.Request, resp *
 		return
 	}
 
-	buf := new(bytes.Buffer)
+	tm.addFlashes(r, resp)
 	layout := fmt.Sprintf("layout:%s", resp.GetTemplateLayout())
+	if resp.stream {
+		tm.stream(w, r, resp, tmpl, layout, start)
+		return
+	}
+
+	buf := tm.buffers.get()
+	defer tm.buffers.put(buf)
 	err = tmpl.ExecuteTemplate(buf, layout, resp.PageData(r).Data())
+	if err == nil {
+		err = tm.renderOOB(buf, r, resp, tmpl)
+	}
 	if err != nil {
+		tm.recordTemplateError(path, err)
 		tm.renderSystemError(w, r, resp, 500, err)
 		return
 	}
@@ -284,12 +595,67 @@ func (tm *TemplateManager) render(w http.ResponseWriter, r *http.Request, resp *
 	for key, value := range resp.GetHeaders() {
 		w.Header().Set(key, value)
 	}
+	if tm.a11yAudit {
+		tm.auditRender(w, r, path, buf)
+	}
 	w.WriteHeader(resp.GetStatusCode())
+	size := buf.Len()
 	if _, err := buf.WriteTo(w); err != nil {
 		tm.logger.Error("Failed to write response",
 			slog.String("path", path),
 			slog.String("error", err.Error()))
 	}
+
+	tm.recordRender(w, path, size, start)
+}
+
+// stream executes the template straight to w, for responses rendered with Response.Stream. The status
+// code is written first, so an error midway through can only be logged and leaves the page truncated.
+func (tm *TemplateManager) stream(w http.ResponseWriter, r *http.Request, resp *Response, tmpl *template.Template, layout string, start time.Time) {
+	path := resp.GetTemplatePath()
+	for key, value := range resp.GetHeaders() {
+		w.Header().Set(key, value)
+	}
+	w.WriteHeader(resp.GetStatusCode())
+
+	cw := &countingWriter{w: w}
+	err := tmpl.ExecuteTemplate(cw, layout, resp.PageData(r).Data())
+	if err == nil {
+		err = tm.renderOOB(cw, r, resp, tmpl)
+	}
+	if err != nil {
+		tm.recordTemplateError(path, err)
+		tm.logger.Error("Failed to stream response",
+			slog.String("path", path),
+			slog.Int("written", cw.n),
+			slog.String("error", err.Error()))
+		return
+	}
+
+	tm.recordRender(w, path, cw.n, start)
+}
+
+// recordRender records the size and duration of a rendered page, and warns about slow renders
+func (tm *TemplateManager) recordRender(w http.ResponseWriter, path string, size int, start time.Time) {
+	duration := time.Since(start)
+	if tm.metrics != nil {
+		// Compression middleware sets the Content-Encoding once it decides to compress the body
+		compressed := w.Header().Get("Content-Encoding") != ""
+		tm.metrics.RecordRender(path, size, duration, compressed)
+	}
+	if tm.slowRender > 0 && duration >= tm.slowRender {
+		tm.logger.Warn("Slow template render",
+			slog.String("path", path),
+			slog.Duration("duration", duration),
+			slog.Duration("threshold", tm.slowRender))
+	}
+}
+
+// recordTemplateError records a template that failed to load or execute
+func (tm *TemplateManager) recordTemplateError(path string, err error) {
+	if tm.templateMetrics != nil {
+		tm.templateMetrics.RecordTemplateError(path, err)
+	}
 }
 
 // viewsPath helper function to construct template paths
@@ -308,8 +674,12 @@ func errorPageFromStatus(status int) string {
 		return "405"
 	case http.StatusNotFound:
 		return "404"
+	case http.StatusRequestEntityTooLarge:
+		return "413"
 	case http.StatusServiceUnavailable:
 		return "503"
+	case http.StatusGatewayTimeout:
+		return "504"
 	default:
 		return "500"
 	}
@@ -327,13 +697,14 @@ func (tm *TemplateManager) renderSystemError(w http.ResponseWriter, r *http.Requ
 	errorTmpl, err := tm.getTemplate(errorPath)
 	if err != nil {
 		// Fallback to basic error response if error template fails
-		http.Error(w, originalErr.Error(), http.StatusInternalServerError)
+		http.Error(w, originalErr.Error(), status)
 		return
 	}
 
 	// Render the error template
 	resp.Path(errorPath).Status(status)
-	buf := new(bytes.Buffer)
+	buf := tm.buffers.get()
+	defer tm.buffers.put(buf)
 	layout := fmt.Sprintf("layout:%s", tm.systemLayout)
 	if err := errorTmpl.ExecuteTemplate(buf, layout, resp.PageData(r).Data()); err != nil {
 		// Fallback if error template rendering fails
@@ -1,11 +1,15 @@
 package render_test
 
 import (
+	"fmt"
 	"html/template"
 	"log/slog"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,6 +17,7 @@ import (
 	template2 "github.com/patrickward/hop/render"
 	"github.com/patrickward/hop/render/testdata/source1"
 	"github.com/patrickward/hop/render/testdata/source2"
+	"github.com/patrickward/hop/templates"
 )
 
 type TestData struct {
@@ -235,3 +240,377 @@ func TestTemplateManager(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateManager_Components(t *testing.T) {
+	sources := template2.Sources{
+		"": fstest.MapFS{
+			"layouts/base.gtml": {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/page.gtml":   {Data: []byte(`{{define "page:main"}}{{template "component:button" .}}{{end}}`)},
+		},
+	}
+
+	components := templates.NewComponents().
+		Register("button", `<a class="btn" href="{{.URL}}">Go</a>`).
+		Override(templates.MediumEmail, "button", `<table><tr><td>Go</td></tr></table>`)
+
+	tm, err := template2.NewTemplateManager(sources, template2.TemplateManagerOptions{
+		Extension:  ".gtml",
+		Logger:     slog.Default(),
+		Components: components,
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	tm.NewResponse().
+		Path("page").
+		WithData(map[string]any{"URL": "/next"}).
+		Render(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, `<a class="btn" href="/next">Go</a>`, strings.TrimSpace(w.Body.String()))
+}
+
+func TestTemplateManager_IncludeGuards(t *testing.T) {
+	base := `{{define "layout:base"}}{{template "page:main" .}}{{end}}`
+
+	t.Run("partials including each other fail to load", func(t *testing.T) {
+		_, err := template2.NewTemplateManager(template2.Sources{
+			"": fstest.MapFS{
+				"layouts/base.html": {Data: []byte(base)},
+				"partials/a.html":   {Data: []byte(`{{define "partial:a"}}{{if .}}{{template "partial:b" .}}{{end}}{{end}}`)},
+				"partials/b.html":   {Data: []byte(`{{define "partial:b"}}{{range .}}{{template "partial:a" .}}{{end}}{{end}}`)},
+			},
+		}, template2.TemplateManagerOptions{Logger: slog.Default()})
+
+		require.ErrorIs(t, err, template2.ErrTempRecursion)
+		assert.Contains(t, err.Error(), "partial:a -> partial:b -> partial:a")
+	})
+
+	t.Run("recursion can be allowed", func(t *testing.T) {
+		tm, err := template2.NewTemplateManager(template2.Sources{
+			"": fstest.MapFS{
+				"layouts/base.html": {Data: []byte(base)},
+				"partials/tree.html": {Data: []byte(
+					`{{define "partial:tree"}}{{.Name}}{{range .Children}}({{template "partial:tree" .}}){{end}}{{end}}`)},
+				"views/tree.html": {Data: []byte(`{{define "page:main"}}{{template "partial:tree" .tree}}{{end}}`)},
+			},
+		}, template2.TemplateManagerOptions{Logger: slog.Default(), AllowRecursion: true})
+		require.NoError(t, err)
+		require.NoError(t, tm.CheckAll())
+
+		type node struct {
+			Name     string
+			Children []node
+		}
+		w := httptest.NewRecorder()
+		tm.NewResponse().Path("tree").
+			WithData(map[string]any{"tree": node{Name: "a", Children: []node{{Name: "b"}}}}).
+			Render(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, "a(b)", w.Body.String())
+	})
+
+	t.Run("excessive nesting fails to load", func(t *testing.T) {
+		_, err := template2.NewTemplateManager(template2.Sources{
+			"": fstest.MapFS{
+				"layouts/base.html": {Data: []byte(base)},
+				"partials/a.html":   {Data: []byte(`{{define "partial:a"}}{{template "partial:b"}}{{end}}`)},
+				"partials/b.html":   {Data: []byte(`{{define "partial:b"}}{{template "partial:c"}}{{end}}`)},
+				"partials/c.html":   {Data: []byte(`{{define "partial:c"}}c{{end}}`)},
+			},
+		}, template2.TemplateManagerOptions{Logger: slog.Default(), MaxIncludeDepth: 1})
+
+		require.ErrorIs(t, err, template2.ErrTempDepth)
+		assert.Contains(t, err.Error(), "partial:a -> partial:b -> partial:c")
+	})
+
+	t.Run("check all reports views that include themselves", func(t *testing.T) {
+		tm, err := template2.NewTemplateManager(template2.Sources{
+			"": fstest.MapFS{
+				"layouts/base.html": {Data: []byte(base)},
+				"views/ok.html":     {Data: []byte(`{{define "page:main"}}ok{{end}}`)},
+				"views/loop.html":   {Data: []byte(`{{define "page:main"}}{{template "page:main" .}}{{end}}`)},
+			},
+		}, template2.TemplateManagerOptions{Logger: slog.Default()})
+		require.NoError(t, err)
+
+		err = tm.CheckAll()
+		require.ErrorIs(t, err, template2.ErrTempRecursion)
+		assert.Contains(t, err.Error(), "views/loop.html")
+		assert.NotContains(t, err.Error(), "views/ok.html")
+	})
+}
+
+func TestResponse_HxNavigate(t *testing.T) {
+	sources := template2.Sources{
+		"": fstest.MapFS{
+			"layouts/base.gtml":    {Data: []byte(`{{define "layout:base"}}<title>{{.Page.Title}}</title><nav>menu</nav>{{template "page:main" .}}{{end}}`)},
+			"layouts/content.gtml": {Data: []byte(`{{define "layout:content"}}<title>{{.Page.Title}}</title>{{template "page:main" .}}{{end}}`)},
+			"views/post.gtml":      {Data: []byte(`{{define "page:main"}}<article>Post</article>{{end}}`)},
+		},
+	}
+
+	tm, err := template2.NewTemplateManager(sources, template2.TemplateManagerOptions{
+		Extension:        ".gtml",
+		Logger:           slog.New(slog.NewTextHandler(httptest.NewRecorder(), nil)),
+		NavigationLayout: "content",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		headers       map[string]string
+		expectPushURL string
+		expectBody    string
+	}{
+		{name: "full page", expectBody: "<title>Post 42</title><nav>menu</nav><article>Post</article>"},
+		{name: "htmx navigation", headers: map[string]string{"HX-Request": "true"}, expectPushURL: "/posts/42", expectBody: "<title>Post 42</title><article>Post</article>"},
+		{name: "boosted", headers: map[string]string{"HX-Request": "true", "HX-Boosted": "true"}, expectPushURL: "/posts/42", expectBody: "<title>Post 42</title><nav>menu</nav><article>Post</article>"},
+		{name: "history restore", headers: map[string]string{"HX-Request": "true", "HX-History-Restore-Request": "true"}, expectBody: "<title>Post 42</title><nav>menu</nav><article>Post</article>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/posts/42", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			tm.NewResponse().
+				Layout("base").
+				Path("post").
+				HxNavigate("/posts/42", "Post 42").
+				Render(w, req)
+
+			assert.Equal(t, tt.expectPushURL, w.Header().Get("HX-Push-Url"))
+			assert.Equal(t, "HX-Request", w.Header().Get("Vary"))
+			assert.Equal(t, tt.expectBody, w.Body.String())
+		})
+	}
+}
+
+func TestTemplateManager_URLFor(t *testing.T) {
+	sources := template2.Sources{
+		"": fstest.MapFS{
+			"layouts/base.gtml": {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/user.gtml":   {Data: []byte(`{{define "page:main"}}<a href="{{urlFor "user.show" "id" 42}}">Ada</a>{{end}}`)},
+		},
+	}
+
+	tm, err := template2.NewTemplateManager(sources, template2.TemplateManagerOptions{
+		Extension: ".gtml",
+		Logger:    slog.New(slog.NewTextHandler(httptest.NewRecorder(), nil)),
+		URLFor: func(name string, params ...any) (string, error) {
+			return fmt.Sprintf("/%s/%v", name, params[1]), nil
+		},
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	tm.NewResponse().Path("user").Render(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, `<a href="/user.show/42">Ada</a>`, w.Body.String())
+}
+
+type renderRecord struct {
+	path       string
+	size       int
+	compressed bool
+}
+
+type fakeRenderRecorder struct {
+	records []renderRecord
+}
+
+func (f *fakeRenderRecorder) RecordRender(path string, size int, duration time.Duration, compressed bool) {
+	f.records = append(f.records, renderRecord{path: path, size: size, compressed: compressed})
+}
+
+func TestTemplateManager_Metrics(t *testing.T) {
+	sources := template2.Sources{
+		"": fstest.MapFS{
+			"layouts/base.gtml": {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/home.gtml":   {Data: []byte(`{{define "page:main"}}<h1>Home</h1>{{end}}`)},
+		},
+	}
+
+	recorder := &fakeRenderRecorder{}
+	tm, err := template2.NewTemplateManager(sources, template2.TemplateManagerOptions{
+		Extension: ".gtml",
+		Logger:    slog.New(slog.NewTextHandler(httptest.NewRecorder(), nil)),
+		Metrics:   recorder,
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	tm.NewResponse().Path("home").Render(w, httptest.NewRequest("GET", "/", nil))
+
+	w = httptest.NewRecorder()
+	w.Header().Set("Content-Encoding", "gzip")
+	tm.NewResponse().Path("home").Render(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, []renderRecord{
+		{path: "views/home", size: len("<h1>Home</h1>")},
+		{path: "views/home", size: len("<h1>Home</h1>"), compressed: true},
+	}, recorder.records)
+}
+
+type fakeTemplateRecorder struct {
+	fakeRenderRecorder
+	hits, misses int
+	errors       []string
+}
+
+func (f *fakeTemplateRecorder) RecordTemplateCache(path string, hit bool) {
+	if hit {
+		f.hits++
+	} else {
+		f.misses++
+	}
+}
+
+func (f *fakeTemplateRecorder) RecordTemplateError(path string, err error) {
+	f.errors = append(f.errors, path)
+}
+
+func TestTemplateManager_TemplateMetrics(t *testing.T) {
+	sources := template2.Sources{
+		"": fstest.MapFS{
+			"layouts/base.html": {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/home.html":   {Data: []byte(`{{define "page:main"}}<h1>Home</h1>{{end}}`)},
+			"views/broken.html": {Data: []byte(`{{define "page:main"}}{{.Missing.Field}}{{end}}`)},
+		},
+	}
+
+	var logs strings.Builder
+	recorder := &fakeTemplateRecorder{}
+	tm, err := template2.NewTemplateManager(sources, template2.TemplateManagerOptions{
+		Logger:              slog.New(slog.NewTextHandler(&logs, nil)),
+		Metrics:             recorder,
+		SlowRenderThreshold: time.Nanosecond,
+	})
+	require.NoError(t, err)
+
+	for range 2 {
+		tm.NewResponse().Path("home").Render(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	assert.Equal(t, 1, recorder.misses)
+	assert.Equal(t, 1, recorder.hits)
+	assert.Len(t, recorder.records, 2)
+	assert.Contains(t, logs.String(), `msg="Slow template render" path=views/home`)
+
+	tm.NewResponse().Path("broken").WithData(map[string]any{"Missing": 1}).
+		Render(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	tm.NewResponse().Path("nope").Render(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, []string{"views/broken", "views/nope"}, recorder.errors)
+	assert.Len(t, recorder.records, 2, "failed renders are not recorded as renders")
+}
+
+func TestTemplateManager_NamedSources(t *testing.T) {
+	sources := template2.Sources{
+		"": fstest.MapFS{
+			"layouts/base.html":  {Data: []byte(`{{define "layout:base"}}[{{template "partial:nav" .}}]{{template "page:main" .}}{{end}}`)},
+			"partials/nav.html":  {Data: []byte(`{{define "partial:nav"}}app nav{{end}}`)},
+			"views/home.html":    {Data: []byte(`{{define "page:main"}}home{{end}}`)},
+			"views/widgets.html": {Data: []byte(`{{define "page:main"}}{{template "partial:widget" .}}{{end}}`)},
+		},
+	}.WithSource("admin", fstest.MapFS{
+		"layouts/admin.html":   {Data: []byte(`{{define "layout:admin"}}<admin>{{template "page:main" .}}</admin>{{end}}`)},
+		"partials/nav.html":    {Data: []byte(`{{define "partial:admin:nav"}}admin nav{{end}}`)},
+		"partials/widget.html": {Data: []byte(`{{define "partial:widget"}}widget{{end}}`)},
+		"views/dashboard.html": {Data: []byte(`{{define "page:main"}}{{template "partial:admin:nav" .}} dashboard{{end}}`)},
+	})
+
+	tm, err := template2.NewTemplateManager(sources, template2.TemplateManagerOptions{Logger: slog.Default()})
+	require.NoError(t, err)
+	require.NoError(t, tm.CheckAll())
+
+	render := func(resp *template2.Response) string {
+		w := httptest.NewRecorder()
+		resp.Render(w, httptest.NewRequest("GET", "/", nil))
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "[app nav]home", render(tm.NewResponse().Path("home")),
+		"the default source's partials win in its own views")
+	assert.Equal(t, "[app nav]admin nav dashboard", render(tm.NewResponse().Path("admin:dashboard")),
+		"views of a named source keep the app's partials in its layouts")
+	assert.Equal(t, "<admin>admin nav dashboard</admin>", render(tm.NewResponse().Layout("admin").Path("admin:dashboard")))
+	assert.Equal(t, "[app nav]widget", render(tm.NewResponse().Path("widgets")),
+		"partials of named sources are shared")
+
+	t.Run("clashing partials", func(t *testing.T) {
+		clashing := fstest.MapFS{
+			"partials/nav.html": {Data: []byte(`{{define "partial:nav"}}blog nav{{end}}`)},
+			"views/posts.html":  {Data: []byte(`{{define "page:main"}}posts{{end}}`)},
+		}
+
+		_, err := template2.NewTemplateManager(sources.WithSource("blog", clashing), template2.TemplateManagerOptions{Logger: slog.Default()})
+		assert.ErrorIs(t, err, template2.ErrTempDuplicate)
+		assert.ErrorContains(t, err, `partial "partial:nav" is defined by source "blog" and the default source`)
+
+		err = tm.AddSource("blog", clashing)
+		assert.ErrorIs(t, err, template2.ErrTempDuplicate)
+		assert.Equal(t, "[app nav]home", render(tm.NewResponse().Path("home")), "the app's partial is kept")
+
+		// Files without {{define}} blocks are partials named after the file
+		require.NoError(t, tm.AddSource("inline", fstest.MapFS{
+			"partials/inline.html": {Data: []byte(`other inline`)},
+		}))
+		err = tm.AddSource("blog", fstest.MapFS{"partials/inline.html": {Data: []byte(`inline`)}})
+		assert.ErrorContains(t, err, `partial "inline.html" is defined by source "blog" and source "inline"`)
+
+		require.NoError(t, tm.AddSource("blog", fstest.MapFS{
+			"partials/nav.html": {Data: []byte(`{{define "partial:blog:nav"}}blog nav{{end}}`)},
+			"views/posts.html":  {Data: []byte(`{{define "page:main"}}{{template "partial:blog:nav"}} posts{{end}}`)},
+		}))
+		assert.Equal(t, "[app nav]blog nav posts", render(tm.NewResponse().Path("blog:posts")))
+	})
+}
+
+func TestResponse_Stream(t *testing.T) {
+	sources := template2.Sources{
+		"": fstest.MapFS{
+			"layouts/base.html": {Data: []byte(`{{define "layout:base"}}<body>{{template "page:main" .}}</body>{{end}}`)},
+			"views/rows.html":   {Data: []byte(`{{define "page:main"}}{{range .Rows}}<p>{{.}}</p>{{end}}{{end}}`)},
+			"views/broken.html": {Data: []byte(`{{define "page:main"}}<p>before</p>{{.Missing.Field}}{{end}}`)},
+		},
+	}
+
+	recorder := &fakeRenderRecorder{}
+	tm, err := template2.NewTemplateManager(sources, template2.TemplateManagerOptions{
+		Logger:              slog.New(slog.NewTextHandler(httptest.NewRecorder(), nil)),
+		Metrics:             recorder,
+		MaxPooledBufferSize: 16,
+	})
+	require.NoError(t, err)
+
+	t.Run("buffered pages render the error page on failure", func(t *testing.T) {
+		for range 2 {
+			w := httptest.NewRecorder()
+			tm.NewResponse().Path("rows").Data("Rows", []int{1, 2, 3}).Render(w, httptest.NewRequest("GET", "/", nil))
+			assert.Equal(t, "<body><p>1</p><p>2</p><p>3</p></body>", w.Body.String())
+		}
+
+		w := httptest.NewRecorder()
+		tm.NewResponse().Path("broken").Data("Missing", 1).Render(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "before")
+	})
+
+	t.Run("streamed pages are written as they render", func(t *testing.T) {
+		recorder.records = nil
+		w := httptest.NewRecorder()
+		tm.NewResponse().Stream().Status(http.StatusAccepted).Header("X-Test", "1").Path("rows").Data("Rows", []int{1, 2}).
+			Render(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "1", w.Header().Get("X-Test"))
+		assert.Equal(t, "<body><p>1</p><p>2</p></body>", w.Body.String())
+		assert.Equal(t, []renderRecord{{path: "views/rows", size: w.Body.Len()}}, recorder.records)
+	})
+
+	t.Run("streamed pages are truncated on failure", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		tm.NewResponse().Stream().Path("broken").Data("Missing", 1).Render(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "<body><p>before</p>", w.Body.String())
+	})
+}
@@ -0,0 +1,213 @@
+package render
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/patrickward/hop/render/htmx"
+)
+
+// ErrNotAcceptable is returned by Negotiation.Send when none of the offered formats is acceptable to the
+// client. A 406 Not Acceptable response has been written by then.
+var ErrNotAcceptable = errors.New("render: no acceptable format")
+
+// Negotiation selects the format of a response from the formats a handler offers, based on the Accept
+// header, so a single handler can serve both browsers and API clients. Like Response, it uses a fluent
+// interface. htmx requests always get the HTML format, when offered.
+//
+//	err := render.Negotiate(w, r).
+//		HTML(app.NewResponse(r).Layout("base").Path("users/show").Data("User", user)).
+//		JSON(user).
+//		Text(user.Name).
+//		Send()
+//
+// When the client accepts several formats equally, as browsers sending "*/*" do, the first format
+// offered wins.
+type Negotiation struct {
+	w          http.ResponseWriter
+	r          *http.Request
+	offers     []offer
+	statusCode int
+}
+
+// offer is a format offered by a handler
+type offer struct {
+	mediaType string
+	html      bool
+	write     func(w http.ResponseWriter, status int) error
+}
+
+// Negotiate starts negotiating the format of the response to r
+func Negotiate(w http.ResponseWriter, r *http.Request) *Negotiation {
+	return &Negotiation{w: w, r: r}
+}
+
+// Status sets the status code of the response, whatever the format selected. Without it, HTML responses
+// use the status of their Response and other formats use 200 OK.
+func (n *Negotiation) Status(code int) *Negotiation {
+	n.statusCode = code
+	return n
+}
+
+// HTML offers the response rendered from its template as text/html
+func (n *Negotiation) HTML(resp *Response) *Negotiation {
+	n.offers = append(n.offers, offer{
+		mediaType: "text/html",
+		html:      true,
+		write: func(w http.ResponseWriter, status int) error {
+			if status != 0 {
+				resp.Status(status)
+			}
+			resp.Render(w, n.r)
+			return nil
+		},
+	})
+	return n
+}
+
+// JSON offers data as application/json, written with DefaultJSON
+func (n *Negotiation) JSON(data any) *Negotiation {
+	return n.JSONResponse(JSON(), data)
+}
+
+// JSONResponse offers data as application/json, written with the JSONResponse
+func (n *Negotiation) JSONResponse(resp *JSONResponse, data any) *Negotiation {
+	n.offers = append(n.offers, offer{
+		mediaType: "application/json",
+		write: func(w http.ResponseWriter, status int) error {
+			if status != 0 {
+				resp.Status(status)
+			}
+			return resp.Send(w, data)
+		},
+	})
+	return n
+}
+
+// Text offers text as text/plain
+func (n *Negotiation) Text(text string) *Negotiation {
+	return n.Offer("text/plain; charset=utf-8", func(w io.Writer) error {
+		_, err := io.WriteString(w, text)
+		return err
+	})
+}
+
+// Offer offers a custom format, e.g. text/csv. The Content-Type header is set to contentType and the status
+// code is written before write is called.
+func (n *Negotiation) Offer(contentType string, write func(w io.Writer) error) *Negotiation {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+
+	n.offers = append(n.offers, offer{
+		mediaType: mediaType,
+		write: func(w http.ResponseWriter, status int) error {
+			if status == 0 {
+				status = http.StatusOK
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(status)
+			return write(w)
+		},
+	})
+	return n
+}
+
+// Send writes the response in the format the client prefers. It writes a 406 Not Acceptable response and
+// returns ErrNotAcceptable when the client accepts none of the offered formats.
+func (n *Negotiation) Send() error {
+	n.w.Header().Add("Vary", "Accept")
+
+	selected := n.selectOffer()
+	if selected == nil {
+		http.Error(n.w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return ErrNotAcceptable
+	}
+	return selected.write(n.w, n.statusCode)
+}
+
+// selectOffer returns the offer the client prefers, or nil when none is acceptable
+func (n *Negotiation) selectOffer() *offer {
+	if len(n.offers) == 0 {
+		return nil
+	}
+
+	if htmx.IsAnyHtmxRequest(n.r) {
+		for i := range n.offers {
+			if n.offers[i].html {
+				return &n.offers[i]
+			}
+		}
+	}
+
+	accept := n.r.Header.Get("Accept")
+	if accept == "" {
+		return &n.offers[0]
+	}
+
+	ranges := parseAccept(accept)
+	var (
+		best  *offer
+		bestQ float64
+	)
+	for i := range n.offers {
+		if q := acceptQuality(ranges, n.offers[i].mediaType); q > bestQ {
+			best, bestQ = &n.offers[i], q
+		}
+	}
+	return best
+}
+
+// acceptRange is a media range of an Accept header with its quality
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses an Accept header, skipping invalid media ranges
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality the client gives to a media type: that of the most specific range
+// matching it, or 0 if none does
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch r.mediaType {
+		case mediaType:
+			s = 2
+		case mainType + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
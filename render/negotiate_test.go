@@ -0,0 +1,94 @@
+package render_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render"
+)
+
+func TestNegotiate(t *testing.T) {
+	tm, err := render.NewTemplateManager(render.Sources{
+		"": fstest.MapFS{
+			"layouts/base.gtml": {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/user.gtml":   {Data: []byte(`{{define "page:main"}}<h1>{{.User}}</h1>{{end}}`)},
+		},
+	}, render.TemplateManagerOptions{
+		Extension: ".gtml",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		expectStatus int
+		expectType   string
+		expectBody   string
+	}{
+		{name: "browser", headers: map[string]string{"Accept": "text/html,application/xhtml+xml,*/*;q=0.8"}, expectStatus: http.StatusCreated, expectBody: "<h1>Ada</h1>"},
+		{name: "api client", headers: map[string]string{"Accept": "application/json"}, expectStatus: http.StatusCreated, expectType: "application/json; charset=utf-8", expectBody: `{"data":{"name":"Ada"}}` + "\n"},
+		{name: "plain text", headers: map[string]string{"Accept": "text/plain"}, expectStatus: http.StatusCreated, expectType: "text/plain; charset=utf-8", expectBody: "Ada"},
+		{name: "quality", headers: map[string]string{"Accept": "text/html;q=0.5, application/json;q=0.9"}, expectStatus: http.StatusCreated, expectType: "application/json; charset=utf-8", expectBody: `{"data":{"name":"Ada"}}` + "\n"},
+		{name: "specific ranges win over wildcards", headers: map[string]string{"Accept": "text/*;q=0.9, text/html;q=0.1, application/json;q=0.5"}, expectStatus: http.StatusCreated, expectType: "text/plain; charset=utf-8", expectBody: "Ada"},
+		{name: "anything", headers: map[string]string{"Accept": "*/*"}, expectStatus: http.StatusCreated, expectBody: "<h1>Ada</h1>"},
+		{name: "no accept header", expectStatus: http.StatusCreated, expectBody: "<h1>Ada</h1>"},
+		{name: "htmx", headers: map[string]string{"Accept": "application/json", "HX-Request": "true"}, expectStatus: http.StatusCreated, expectBody: "<h1>Ada</h1>"},
+		{name: "not acceptable", headers: map[string]string{"Accept": "image/png"}, expectStatus: http.StatusNotAcceptable, expectType: "text/plain; charset=utf-8", expectBody: "Not Acceptable\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+
+			err := render.Negotiate(w, req).
+				Status(http.StatusCreated).
+				HTML(tm.NewResponse().Path("user").Data("User", "Ada")).
+				JSON(map[string]string{"name": "Ada"}).
+				Text("Ada").
+				Send()
+
+			if tt.expectStatus == http.StatusNotAcceptable {
+				assert.ErrorIs(t, err, render.ErrNotAcceptable)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectStatus, w.Code)
+			assert.Equal(t, tt.expectBody, w.Body.String())
+			if tt.expectType != "" {
+				assert.Equal(t, tt.expectType, w.Header().Get("Content-Type"))
+			}
+			assert.Contains(t, w.Header().Values("Vary"), "Accept")
+		})
+	}
+}
+
+func TestNegotiate_Offer(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	req.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+
+	err := render.Negotiate(w, req).
+		JSON([]int{1, 2}).
+		Offer("text/csv; charset=utf-8", func(w io.Writer) error {
+			_, err := io.WriteString(w, "1\n2\n")
+			return err
+		}).
+		Send()
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "1\n2\n", w.Body.String())
+}
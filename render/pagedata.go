@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/patrickward/hop/render/htmx"
 	"github.com/patrickward/hop/render/request"
 )
 
@@ -12,6 +13,7 @@ const (
 	PageDataPageKey   = "Page"
 	PageDataErrorKey  = "Error"
 	PageDataErrorsKey = "Errors"
+	PageDataFlashKey  = "Flash"
 )
 
 // PageData is the struct that all view models must implement. It provides common data for all templates
@@ -194,6 +196,11 @@ func (v *PageData) RequestPath() string {
 	return request.URLPath(v.request)
 }
 
+// HTMX returns the htmx details of the request, e.g. {{.Page.HTMX.Target}}.
+func (v *PageData) HTMX() htmx.Details {
+	return htmx.RequestDetails(v.request)
+}
+
 // RequestMethod returns the method of the request.
 func (v *PageData) RequestMethod() string {
 	return request.Method(v.request)
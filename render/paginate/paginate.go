@@ -0,0 +1,287 @@
+// Package paginate computes pages of a collection, parses pagination parameters from requests and builds
+// page links that keep the rest of the query string.
+//
+//	params := paginate.Parse(r, nil)
+//	items, total, err := store.List(ctx, params.Limit(), params.Offset())
+//	...
+//	resp.WithData(map[string]any{"Items": items, "Pager": params.Paginate(total)})
+//
+// In a template, with the request URL available as .RequestURL:
+//
+//	{{range .Pager.Links .RequestURL 2}}
+//		{{if .Gap}}<span>…</span>
+//		{{else if .Current}}<span aria-current="page">{{.Page}}</span>
+//		{{else}}<a href="{{.URL}}">{{.Page}}</a>{{end}}
+//	{{end}}
+package paginate
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/patrickward/hop/render"
+)
+
+const (
+	// DefaultPageParam is the query parameter holding the page number
+	DefaultPageParam = "page"
+	// DefaultPerPageParam is the query parameter holding the number of items per page
+	DefaultPerPageParam = "per_page"
+	// DefaultPerPage is the number of items per page when the request doesn't ask for one
+	DefaultPerPage = 20
+	// DefaultMaxPerPage is the largest number of items per page a request can ask for
+	DefaultMaxPerPage = 100
+)
+
+// Options configures Parse
+type Options struct {
+	// PageParam is the query parameter holding the page number. Default is "page".
+	PageParam string
+	// PerPageParam is the query parameter holding the number of items per page. Default is "per_page";
+	// set it to "-" to always use PerPage.
+	PerPageParam string
+	// PerPage is the number of items per page when the request doesn't ask for one. Default is 20.
+	PerPage int
+	// MaxPerPage caps the number of items per page a request can ask for. Default is 100.
+	MaxPerPage int
+}
+
+// Params are the pagination parameters of a request, before the size of the collection is known
+type Params struct {
+	// Page is the requested page, starting at 1
+	Page int
+	// PerPage is the number of items per page
+	PerPage int
+
+	pageParam string
+}
+
+// Parse reads the pagination parameters from the query string of r. Missing, malformed and out of range
+// values fall back to sane bounds: the page is at least 1, and the number of items per page is between 1
+// and MaxPerPage.
+func Parse(r *http.Request, optsFunc func(opts *Options)) Params {
+	opts := &Options{
+		PageParam:    DefaultPageParam,
+		PerPageParam: DefaultPerPageParam,
+		PerPage:      DefaultPerPage,
+		MaxPerPage:   DefaultMaxPerPage,
+	}
+
+	if optsFunc != nil {
+		optsFunc(opts)
+	}
+
+	if opts.PageParam == "" {
+		opts.PageParam = DefaultPageParam
+	}
+
+	if opts.PerPageParam == "" {
+		opts.PerPageParam = DefaultPerPageParam
+	}
+
+	if opts.MaxPerPage <= 0 {
+		opts.MaxPerPage = DefaultMaxPerPage
+	}
+
+	if opts.PerPage <= 0 {
+		opts.PerPage = min(DefaultPerPage, opts.MaxPerPage)
+	}
+
+	query := r.URL.Query()
+	params := Params{
+		Page:      positiveInt(query.Get(opts.PageParam), 1),
+		PerPage:   opts.PerPage,
+		pageParam: opts.PageParam,
+	}
+
+	if opts.PerPageParam != "-" {
+		params.PerPage = positiveInt(query.Get(opts.PerPageParam), opts.PerPage)
+	}
+	params.PerPage = min(params.PerPage, opts.MaxPerPage)
+
+	return params
+}
+
+// Offset returns the number of items before the requested page, for a query's OFFSET clause
+func (p Params) Offset() int {
+	return (p.Page - 1) * p.PerPage
+}
+
+// Limit returns the number of items per page, for a query's LIMIT clause
+func (p Params) Limit() int {
+	return p.PerPage
+}
+
+// Paginate returns the Paginator for the requested page of a collection of total items
+func (p Params) Paginate(total int) Paginator {
+	pager := New(p.Page, p.PerPage, total)
+	pager.pageParam = p.pageParam
+	return pager
+}
+
+// Paginator describes a page of a collection
+type Paginator struct {
+	// Page is the current page, starting at 1
+	Page int
+	// PerPage is the number of items per page
+	PerPage int
+	// Total is the number of items in the collection
+	Total int
+	// TotalPages is the number of pages in the collection
+	TotalPages int
+
+	pageParam string
+}
+
+// New returns the Paginator for a page of a collection of total items. Page and perPage are raised to 1
+// if lower. A page past the last one is kept, so it shows up as empty rather than as a different page.
+func New(page, perPage, total int) Paginator {
+	page = max(page, 1)
+	perPage = max(perPage, 1)
+	total = max(total, 0)
+
+	return Paginator{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+}
+
+// Offset returns the number of items before the current page
+func (p Paginator) Offset() int {
+	return (p.Page - 1) * p.PerPage
+}
+
+// From returns the position of the first item on the current page, starting at 1, or 0 if the page is empty
+func (p Paginator) From() int {
+	if p.Offset() >= p.Total {
+		return 0
+	}
+	return p.Offset() + 1
+}
+
+// To returns the position of the last item on the current page, or 0 if the page is empty
+func (p Paginator) To() int {
+	if p.From() == 0 {
+		return 0
+	}
+	return min(p.Offset()+p.PerPage, p.Total)
+}
+
+// HasPages reports whether the collection spans more than one page
+func (p Paginator) HasPages() bool {
+	return p.TotalPages > 1
+}
+
+// HasPrev reports whether there is a page before the current one
+func (p Paginator) HasPrev() bool {
+	return p.Page > 1 && p.TotalPages > 0
+}
+
+// HasNext reports whether there is a page after the current one
+func (p Paginator) HasNext() bool {
+	return p.Page < p.TotalPages
+}
+
+// PrevPage returns the page before the current one, or the last page if the current one is past it
+func (p Paginator) PrevPage() int {
+	return max(min(p.Page-1, p.TotalPages), 1)
+}
+
+// NextPage returns the page after the current one, or the last page if there is none
+func (p Paginator) NextPage() int {
+	return max(min(p.Page+1, p.TotalPages), 1)
+}
+
+// Pages returns the page numbers to link to: the first and last pages, and the pages within window of the
+// current one. A gap in the sequence is marked with a 0.
+//
+//	New(10, 10, 200).Pages(2) // [1 0 8 9 10 11 12 0 20]
+func (p Paginator) Pages(window int) []int {
+	window = max(window, 0)
+
+	var pages []int
+	for page := 1; page <= p.TotalPages; page++ {
+		if page == 1 || page == p.TotalPages || (page >= p.Page-window && page <= p.Page+window) {
+			if len(pages) > 0 && pages[len(pages)-1] != page-1 {
+				pages = append(pages, 0)
+			}
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// Link is a link to a page of a collection
+type Link struct {
+	// Page is the page number, or 0 for a gap
+	Page int
+	// URL is the link to the page
+	URL *url.URL
+	// Current reports whether this is the current page
+	Current bool
+	// Gap reports whether this stands for skipped pages rather than a page
+	Gap bool
+}
+
+// Links returns the links for Pages(window), built from u with the page parameter set
+func (p Paginator) Links(u *url.URL, window int) []Link {
+	pages := p.Pages(window)
+	links := make([]Link, 0, len(pages))
+	for _, page := range pages {
+		if page == 0 {
+			links = append(links, Link{Gap: true})
+			continue
+		}
+		links = append(links, Link{Page: page, URL: p.URL(u, page), Current: page == p.Page})
+	}
+	return links
+}
+
+// URL returns a copy of u with the page parameter set to page. Other query parameters, such as filters and
+// sorting, are kept.
+func (p Paginator) URL(u *url.URL, page int) *url.URL {
+	nu := *u
+	values := nu.Query()
+
+	values.Set(p.param(), strconv.Itoa(page))
+
+	nu.RawQuery = values.Encode()
+	return &nu
+}
+
+// PrevURL returns the URL of the page before the current one
+func (p Paginator) PrevURL(u *url.URL) *url.URL {
+	return p.URL(u, p.PrevPage())
+}
+
+// NextURL returns the URL of the page after the current one
+func (p Paginator) NextURL(u *url.URL) *url.URL {
+	return p.URL(u, p.NextPage())
+}
+
+// Pagination returns the pagination metadata for a JSON response
+//
+//	render.JSON().Page(pager.Pagination()).Send(w, items)
+func (p Paginator) Pagination() render.Pagination {
+	return render.NewPagination(p.Page, p.PerPage, p.Total)
+}
+
+// param returns the query parameter holding the page number
+func (p Paginator) param() string {
+	if p.pageParam == "" {
+		return DefaultPageParam
+	}
+	return p.pageParam
+}
+
+// positiveInt parses s as an integer of at least 1, returning fallback if it isn't one
+func positiveInt(s string, fallback int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,147 @@
+package paginate_test
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render"
+	"github.com/patrickward/hop/render/paginate"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		optsFunc    func(opts *paginate.Options)
+		wantPage    int
+		wantPerPage int
+	}{
+		{name: "defaults", wantPage: 1, wantPerPage: paginate.DefaultPerPage},
+		{name: "valid values", query: "page=3&per_page=50", wantPage: 3, wantPerPage: 50},
+		{name: "malformed values", query: "page=abc&per_page=x", wantPage: 1, wantPerPage: paginate.DefaultPerPage},
+		{name: "negative values", query: "page=-2&per_page=0", wantPage: 1, wantPerPage: paginate.DefaultPerPage},
+		{name: "per page capped", query: "per_page=10000", wantPage: 1, wantPerPage: paginate.DefaultMaxPerPage},
+		{
+			name:  "custom params",
+			query: "p=4&size=15&page=9",
+			optsFunc: func(opts *paginate.Options) {
+				opts.PageParam = "p"
+				opts.PerPageParam = "size"
+				opts.PerPage = 5
+				opts.MaxPerPage = 10
+			},
+			wantPage:    4,
+			wantPerPage: 10,
+		},
+		{
+			name:        "fixed per page",
+			query:       "per_page=50",
+			optsFunc:    func(opts *paginate.Options) { opts.PerPageParam = "-"; opts.PerPage = 25 },
+			wantPage:    1,
+			wantPerPage: 25,
+		},
+		{
+			name:        "default per page within the cap",
+			optsFunc:    func(opts *paginate.Options) { opts.MaxPerPage = 10 },
+			wantPage:    1,
+			wantPerPage: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil)
+			params := paginate.Parse(r, tt.optsFunc)
+			assert.Equal(t, tt.wantPage, params.Page)
+			assert.Equal(t, tt.wantPerPage, params.PerPage)
+			assert.Equal(t, tt.wantPerPage, params.Limit())
+			assert.Equal(t, (tt.wantPage-1)*tt.wantPerPage, params.Offset())
+		})
+	}
+}
+
+func TestPaginator(t *testing.T) {
+	p := paginate.New(2, 10, 25)
+	assert.Equal(t, 3, p.TotalPages)
+	assert.Equal(t, 10, p.Offset())
+	assert.Equal(t, 11, p.From())
+	assert.Equal(t, 20, p.To())
+	assert.True(t, p.HasPages())
+	assert.True(t, p.HasPrev())
+	assert.True(t, p.HasNext())
+	assert.Equal(t, 1, p.PrevPage())
+	assert.Equal(t, 3, p.NextPage())
+	assert.Equal(t, render.Pagination{Page: 2, PerPage: 10, Total: 25, TotalPages: 3}, p.Pagination())
+
+	last := paginate.New(3, 10, 25)
+	assert.Equal(t, 21, last.From())
+	assert.Equal(t, 25, last.To())
+	assert.False(t, last.HasNext())
+	assert.Equal(t, 3, last.NextPage())
+
+	past := paginate.New(7, 10, 25)
+	assert.Equal(t, 0, past.From())
+	assert.Equal(t, 0, past.To())
+	assert.True(t, past.HasPrev())
+	assert.Equal(t, 3, past.PrevPage(), "the previous page of a page past the end is the last page")
+
+	empty := paginate.New(0, 0, 0)
+	assert.Equal(t, 1, empty.Page)
+	assert.Equal(t, 1, empty.PerPage)
+	assert.Equal(t, 0, empty.TotalPages)
+	assert.False(t, empty.HasPages())
+	assert.False(t, empty.HasPrev())
+	assert.False(t, empty.HasNext())
+	assert.Empty(t, empty.Pages(2))
+}
+
+func TestPaginator_Pages(t *testing.T) {
+	tests := []struct {
+		page   int
+		total  int
+		window int
+		want   []int
+	}{
+		{page: 1, total: 30, window: 2, want: []int{1, 2, 3}},
+		{page: 10, total: 200, window: 2, want: []int{1, 0, 8, 9, 10, 11, 12, 0, 20}},
+		{page: 1, total: 200, window: 2, want: []int{1, 2, 3, 0, 20}},
+		{page: 4, total: 200, window: 2, want: []int{1, 2, 3, 4, 5, 6, 0, 20}},
+		{page: 20, total: 200, window: 1, want: []int{1, 0, 19, 20}},
+		{page: 5, total: 100, window: 0, want: []int{1, 0, 5, 0, 10}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, paginate.New(tt.page, 10, tt.total).Pages(tt.window))
+	}
+}
+
+func TestPaginator_Links(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?q=go&p=2&sort=name", nil)
+	pager := paginate.Parse(r, func(opts *paginate.Options) { opts.PageParam = "p"; opts.PerPage = 10 }).Paginate(45)
+
+	assert.Equal(t, "/items?p=1&q=go&sort=name", pager.PrevURL(r.URL).String())
+	assert.Equal(t, "/items?p=3&q=go&sort=name", pager.NextURL(r.URL).String())
+	assert.Equal(t, "/items?q=go&p=2&sort=name", r.URL.String(), "the request URL is unchanged")
+
+	links := pager.Links(r.URL, 0)
+	require.Len(t, links, 4)
+	assert.Equal(t, 1, links[0].Page)
+	assert.True(t, links[1].Current)
+	assert.Equal(t, "/items?p=2&q=go&sort=name", links[1].URL.String())
+	assert.True(t, links[2].Gap)
+	assert.Nil(t, links[2].URL)
+	assert.Equal(t, 5, links[3].Page)
+
+	tmpl := template.Must(template.New("pager").Parse(
+		`{{range .Pager.Links .URL 1}}{{if .Gap}}…{{else if .Current}}[{{.Page}}]{{else}}<a href="{{.URL}}">{{.Page}}</a>{{end}}{{end}}`))
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, map[string]any{"Pager": paginate.New(1, 10, 45), "URL": &url.URL{Path: "/items"}}))
+	assert.Equal(t, `[1]<a href="/items?page=2">2</a>…<a href="/items?page=5">5</a>`, buf.String())
+}
@@ -0,0 +1,244 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/patrickward/hop/render/htmx"
+	"github.com/patrickward/hop/render/request"
+)
+
+// ProblemContentType is the media type of problem details documents
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document describing an error in an HTTP API. It implements
+// error, so handlers and services can return one and let RenderError turn it into either a problem
+// document or the HTML error page, depending on what the client accepts.
+//
+//	return render.NewProblem(http.StatusConflict, "the email address is already registered").
+//		WithType("https://example.com/problems/duplicate-email").
+//		WithField("email", "is already registered")
+type Problem struct {
+	// Type is a URI identifying the problem type. Default is "about:blank", meaning the problem is
+	// described by its status code alone.
+	Type string
+	// Title is a short summary of the problem type. Default is the status text.
+	Title string
+	// Status is the HTTP status code
+	Status int
+	// Detail explains this occurrence of the problem to the client
+	Detail string
+	// Instance is a URI identifying this occurrence of the problem. RenderError sets it to the request
+	// path when empty.
+	Instance string
+	// Errors holds field errors, written as the "errors" extension member
+	Errors map[string]string
+	// Extensions holds additional members written at the top level of the document
+	Extensions map[string]any
+}
+
+// NewProblem creates a Problem with the status code and detail, titled with the status text
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Error returns the detail of the problem, or its title when there is no detail
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// WithType sets the problem type URI and returns the Problem
+func (p *Problem) WithType(uri string) *Problem {
+	p.Type = uri
+	return p
+}
+
+// WithTitle sets the title and returns the Problem
+func (p *Problem) WithTitle(title string) *Problem {
+	p.Title = title
+	return p
+}
+
+// WithInstance sets the URI of this occurrence and returns the Problem
+func (p *Problem) WithInstance(uri string) *Problem {
+	p.Instance = uri
+	return p
+}
+
+// WithField adds a field error and returns the Problem
+func (p *Problem) WithField(field, message string) *Problem {
+	if p.Errors == nil {
+		p.Errors = make(map[string]string)
+	}
+	p.Errors[field] = message
+	return p
+}
+
+// With adds an extension member and returns the Problem. Members named after the standard members are
+// ignored when the document is written.
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// MarshalJSON writes the problem document, with the extension members next to the standard members
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]any, len(p.Extensions)+6)
+	for k, v := range p.Extensions {
+		switch k {
+		case "type", "title", "status", "detail", "instance", "errors":
+		default:
+			doc[k] = v
+		}
+	}
+
+	doc["type"] = p.Type
+	if p.Type == "" {
+		doc["type"] = "about:blank"
+	}
+	if p.Title != "" {
+		doc["title"] = p.Title
+	}
+	if p.Status != 0 {
+		doc["status"] = p.Status
+	}
+	if p.Detail != "" {
+		doc["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		doc["instance"] = p.Instance
+	}
+	if len(p.Errors) > 0 {
+		doc["errors"] = p.Errors
+	}
+	return json.Marshal(doc)
+}
+
+// Write writes the problem as an application/problem+json response
+func (p *Problem) Write(w http.ResponseWriter) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)+1))
+	w.WriteHeader(status)
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+// ProblemFrom returns the Problem describing err. A *Problem in the error chain is returned as is, and a
+// *request.DecodeError becomes a problem with its status and message. Any other error becomes a 500
+// Internal Server Error problem that doesn't expose the error's message.
+func ProblemFrom(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem
+	}
+
+	var decodeErr *request.DecodeError
+	if errors.As(err, &decodeErr) {
+		p := NewProblem(decodeErr.Status, decodeErr.Message)
+		if decodeErr.Field != "" {
+			p.WithField(decodeErr.Field, decodeErr.Message)
+		}
+		return p
+	}
+
+	return NewProblem(http.StatusInternalServerError, "")
+}
+
+// WantsProblem reports whether the client prefers a JSON error document to an HTML page: the Accept
+// header ranks a JSON media type above HTML, or there is no Accept header and the request body is JSON.
+// htmx requests always get HTML, so the response can be swapped into the page.
+func WantsProblem(r *http.Request) bool {
+	if htmx.IsAnyHtmxRequest(r) {
+		return false
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		return isJSONMediaType(mediaType)
+	}
+
+	jsonQ, htmlQ := -1.0, -1.0
+	for _, r := range parseAccept(accept) {
+		switch {
+		case isJSONMediaType(r.mediaType):
+			jsonQ = max(jsonQ, r.q)
+		case r.mediaType == "text/html" || r.mediaType == "application/xhtml+xml":
+			htmlQ = max(htmlQ, r.q)
+		}
+	}
+	return jsonQ > 0 && jsonQ > htmlQ
+}
+
+// isJSONMediaType reports whether the media type is JSON, including structured syntax suffixes such as
+// application/problem+json
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// RenderError renders err for the client: as a problem document for clients that prefer JSON (see
+// WantsProblem), or as the HTML error page for its status otherwise, with the problem detail and field
+// errors in the page data. Errors are described with ProblemFrom, so errors that aren't a *Problem are
+// rendered as a 500 Internal Server Error without exposing their message, and are logged.
+//
+//	if err := users.Register(ctx, input); err != nil {
+//		app.NewResponse(r).RenderError(w, r, err)
+//		return
+//	}
+func (resp *Response) RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := ProblemFrom(err)
+	status := problem.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if status >= http.StatusInternalServerError && resp.tm.logger != nil {
+		resp.tm.logger.Error("Server error",
+			slog.String("path", r.URL.Path),
+			slog.String("err", err.Error()))
+	}
+
+	if WantsProblem(r) {
+		doc := *problem
+		if doc.Instance == "" {
+			doc.Instance = r.URL.Path
+		}
+		for key, value := range resp.GetHeaders() {
+			w.Header().Set(key, value)
+		}
+		if writeErr := doc.Write(w); writeErr != nil && resp.tm.logger != nil {
+			resp.tm.logger.Error("Failed to write problem response", slog.String("error", writeErr.Error()))
+		}
+		return
+	}
+
+	resp.data.Set(PageDataErrorKey, problem.Error())
+	if len(problem.Errors) > 0 {
+		resp.data.Set(PageDataErrorsKey, problem.Errors)
+	}
+	resp.tm.renderSystemError(w, r, resp, status, problem)
+}
@@ -0,0 +1,165 @@
+package render_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render"
+	"github.com/patrickward/hop/render/request"
+)
+
+func TestProblem_MarshalJSON(t *testing.T) {
+	p := render.NewProblem(http.StatusConflict, "the email address is already registered").
+		WithType("https://example.com/problems/duplicate-email").
+		WithInstance("/users").
+		WithField("email", "is already registered").
+		With("balance", 30).
+		With("status", 200)
+
+	body, err := p.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "https://example.com/problems/duplicate-email",
+		"title": "Conflict",
+		"status": 409,
+		"detail": "the email address is already registered",
+		"instance": "/users",
+		"errors": {"email": "is already registered"},
+		"balance": 30
+	}`, string(body))
+
+	assert.Equal(t, "the email address is already registered", p.Error())
+}
+
+func TestProblemFrom(t *testing.T) {
+	conflict := render.NewProblem(http.StatusConflict, "taken")
+	assert.Same(t, conflict, render.ProblemFrom(fmt.Errorf("registering: %w", conflict)))
+
+	decodeErr := render.ProblemFrom(&request.DecodeError{Status: http.StatusBadRequest, Message: "invalid age", Field: "age"})
+	assert.Equal(t, http.StatusBadRequest, decodeErr.Status)
+	assert.Equal(t, map[string]string{"age": "invalid age"}, decodeErr.Errors)
+
+	internal := render.ProblemFrom(errors.New("connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, internal.Status)
+	assert.Empty(t, internal.Detail, "unexpected errors are not exposed")
+}
+
+func TestWantsProblem(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		expect  bool
+	}{
+		{name: "browser", headers: map[string]string{"Accept": "text/html,application/xhtml+xml,*/*;q=0.8"}, expect: false},
+		{name: "api client", headers: map[string]string{"Accept": "application/json"}, expect: true},
+		{name: "problem documents", headers: map[string]string{"Accept": "application/problem+json"}, expect: true},
+		{name: "prefers json", headers: map[string]string{"Accept": "text/html;q=0.5, application/json"}, expect: true},
+		{name: "prefers html", headers: map[string]string{"Accept": "application/json;q=0.5, text/html"}, expect: false},
+		{name: "json body without accept", headers: map[string]string{"Content-Type": "application/json; charset=utf-8"}, expect: true},
+		{name: "no headers", expect: false},
+		{name: "htmx", headers: map[string]string{"Accept": "application/json", "HX-Request": "true"}, expect: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			assert.Equal(t, tt.expect, render.WantsProblem(req))
+		})
+	}
+}
+
+func TestResponse_RenderError(t *testing.T) {
+	sources := render.Sources{
+		"": fstest.MapFS{
+			"layouts/base.gtml":     {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/system/404.gtml": {Data: []byte(`{{define "page:main"}}<h1>Not found</h1><p>{{.Error}}</p>{{end}}`)},
+			"views/system/500.gtml": {Data: []byte(`{{define "page:main"}}<h1>Error</h1><p>{{.Error}}</p>{{range $field, $msg := .Errors}}<li>{{$field}}: {{$msg}}</li>{{end}}{{end}}`)},
+			"views/system/503.gtml": {Data: []byte(`{{define "page:main"}}<h1>Unavailable</h1>{{end}}`)},
+		},
+	}
+	tm, err := render.NewTemplateManager(sources, render.TemplateManagerOptions{
+		Extension: ".gtml",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	require.NoError(t, err)
+
+	notFound := render.NewProblem(http.StatusNotFound, "no user with ID 42")
+	invalid := render.NewProblem(http.StatusUnprocessableEntity, "invalid input").WithField("email", "is required")
+
+	tests := []struct {
+		name         string
+		accept       string
+		err          error
+		expectStatus int
+		expectType   string
+		expectBody   string
+	}{
+		{
+			name:         "problem document",
+			accept:       "application/json",
+			err:          notFound,
+			expectStatus: http.StatusNotFound,
+			expectType:   render.ProblemContentType,
+			expectBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"no user with ID 42","instance":"/users/42"}`,
+		},
+		{
+			name:         "html page",
+			accept:       "text/html",
+			err:          notFound,
+			expectStatus: http.StatusNotFound,
+			expectBody:   "<h1>Not found</h1><p>no user with ID 42</p>",
+		},
+		{
+			name:         "field errors on the html page",
+			accept:       "text/html",
+			err:          invalid,
+			expectStatus: http.StatusUnprocessableEntity,
+			expectBody:   "<h1>Error</h1><p>invalid input</p><li>email: is required</li>",
+		},
+		{
+			name:         "unexpected errors as problem documents",
+			accept:       "application/json",
+			err:          errors.New("connection refused"),
+			expectStatus: http.StatusInternalServerError,
+			expectType:   render.ProblemContentType,
+			expectBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/users/42"}`,
+		},
+		{
+			name:         "unexpected errors as html pages",
+			accept:       "text/html",
+			err:          errors.New("connection refused"),
+			expectStatus: http.StatusInternalServerError,
+			expectBody:   "<h1>Error</h1><p>Internal Server Error</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+
+			tm.NewResponse().RenderError(w, req, tt.err)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+			if tt.expectType != "" {
+				assert.Equal(t, tt.expectType, w.Header().Get("Content-Type"))
+				assert.JSONEq(t, tt.expectBody, w.Body.String())
+				return
+			}
+			assert.Equal(t, tt.expectBody, w.Body.String())
+		})
+	}
+}
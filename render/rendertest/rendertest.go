@@ -0,0 +1,244 @@
+// Package rendertest provides golden-file testing for rendered templates. Pages are rendered with fixed
+// data, volatile values such as CSP nonces and CSRF tokens are replaced with placeholders, and the output
+// is compared against a snapshot stored under testdata. When the output changes, the test fails with a
+// line diff of the HTML, formatted with one tag per line so template regressions are easy to spot.
+//
+//	func TestHomePage(t *testing.T) {
+//	    tm, _ := render.NewTemplateManager(render.Sources{"": templates.FS}, render.TemplateManagerOptions{})
+//	    resp := tm.NewResponse().Layout("base").Path("home").WithData(map[string]any{"Title": "Home"})
+//	    html := rendertest.Render(t, resp, httptest.NewRequest(http.MethodGet, "/", nil))
+//	    rendertest.AssertGolden(t, "home", html, nil)
+//	}
+//
+// Snapshots are written or refreshed by running the tests with HOP_UPDATE_GOLDEN=1.
+package rendertest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/patrickward/hop/render"
+)
+
+// UpdateEnv is the environment variable that, when set to a true value, rewrites golden files with the
+// current output instead of comparing against them
+const UpdateEnv = "HOP_UPDATE_GOLDEN"
+
+// Normalizer rewrites volatile parts of rendered output so that it is stable between runs
+type Normalizer func(html string) string
+
+// ReplacePattern returns a Normalizer that replaces every match of the regular expression with
+// replacement, which may refer to submatches as in regexp.ReplaceAllString
+func ReplacePattern(pattern, replacement string) Normalizer {
+	re := regexp.MustCompile(pattern)
+	return func(html string) string {
+		return re.ReplaceAllString(html, replacement)
+	}
+}
+
+var (
+	csrfInputPattern = regexp.MustCompile(`<input[^>]*name="csrf_token"[^>]*>`)
+	valueAttrPattern = regexp.MustCompile(`value="[^"]*"`)
+)
+
+// DefaultNormalizers replace CSP nonces, CSRF tokens in hidden inputs, meta tags and hx-headers, and
+// cache buster query parameters
+var DefaultNormalizers = []Normalizer{
+	ReplacePattern(`nonce="[^"]*"`, `nonce="[nonce]"`),
+	func(html string) string {
+		return csrfInputPattern.ReplaceAllStringFunc(html, func(tag string) string {
+			return valueAttrPattern.ReplaceAllString(tag, `value="[csrf]"`)
+		})
+	},
+	ReplacePattern(`(<meta[^>]*name="csrf-token"[^>]*content=")[^"]*"`, `${1}[csrf]"`),
+	ReplacePattern(`("X-CSRF-Token"\s*:\s*")[^"]*"`, `${1}[csrf]"`),
+	ReplacePattern(`([?&]v=)\d{14}\b`, `${1}[cachebuster]`),
+}
+
+// Options configures AssertGolden
+type Options struct {
+	// Dir is the directory holding golden files. Defaults to "testdata/golden".
+	Dir string
+	// Update writes the output to the golden file instead of comparing. Defaults to true when the
+	// HOP_UPDATE_GOLDEN environment variable is set to a true value.
+	Update bool
+	// Normalizers are applied to the output before it is compared. Defaults to DefaultNormalizers;
+	// append to them to keep the defaults.
+	Normalizers []Normalizer
+}
+
+// Render renders the response for the request and returns the body. The test fails if rendering
+// produced a server error.
+func Render(t testing.TB, resp *render.Response, r *http.Request) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	resp.Render(rec, r)
+	if rec.Code >= http.StatusInternalServerError {
+		t.Fatalf("rendertest: rendering %s failed with status %d:\n%s", r.URL.Path, rec.Code, rec.Body.String())
+	}
+	return rec.Body.String()
+}
+
+// AssertGolden compares the normalized, formatted output against the golden file name.html and fails the
+// test with a diff when they differ. A missing golden file is an error unless updating.
+func AssertGolden(t testing.TB, name string, got string, optsFunc func(opts *Options)) {
+	t.Helper()
+
+	opts := Options{
+		Dir:         filepath.Join("testdata", "golden"),
+		Update:      updateFromEnv(),
+		Normalizers: DefaultNormalizers,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	got = FormatHTML(Normalize(got, opts.Normalizers...))
+	path := filepath.Join(opts.Dir, name+".html")
+
+	if opts.Update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("rendertest: creating golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("rendertest: writing golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("rendertest: golden file %s does not exist; run the tests with %s=1 to create it", path, UpdateEnv)
+		return
+	}
+	if err != nil {
+		t.Fatalf("rendertest: reading golden file: %v", err)
+		return
+	}
+
+	if diff := Diff(string(want), got); diff != "" {
+		t.Errorf("rendertest: output does not match %s (-want +got):\n%s\nRun the tests with %s=1 to update it.", path, diff, UpdateEnv)
+	}
+}
+
+// Normalize applies the normalizers to html in order
+func Normalize(html string, normalizers ...Normalizer) string {
+	for _, normalize := range normalizers {
+		html = normalize(html)
+	}
+	return html
+}
+
+// tagBoundary matches the whitespace between two tags, or the boundary between adjacent tags
+var tagBoundary = regexp.MustCompile(`>\s*<`)
+
+// FormatHTML puts each tag on its own line and trims indentation and blank lines, so that output can be
+// compared and diffed line by line without depending on template whitespace
+func FormatHTML(html string) string {
+	html = tagBoundary.ReplaceAllString(html, ">\n<")
+
+	var b strings.Builder
+	for _, line := range strings.Split(html, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 2
+
+// Diff returns a line diff between want and got, with "-" marking lines only in want and "+" marking
+// lines only in got, or an empty string when they are equal
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type edit struct {
+		op   byte
+		line string
+		num  int // line number in got, or in want for removed lines
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], j + 1})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i + 1})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], j + 1})
+			j++
+		}
+	}
+
+	nearChange := func(k int) bool {
+		for n := max(0, k-diffContext); n <= min(len(edits)-1, k+diffContext); n++ {
+			if edits[n].op != ' ' {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Print the changes with a few lines of context, separating distant hunks
+	var out strings.Builder
+	last := -1
+	for k, e := range edits {
+		if e.op == ' ' && !nearChange(k) {
+			continue
+		}
+		if last >= 0 && k > last+1 {
+			out.WriteString("...\n")
+		}
+		fmt.Fprintf(&out, "%c %4d | %s\n", e.op, e.num, e.line)
+		last = k
+	}
+	return out.String()
+}
+
+// updateFromEnv reports whether UpdateEnv requests updating golden files
+func updateFromEnv() bool {
+	switch strings.ToLower(os.Getenv(UpdateEnv)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
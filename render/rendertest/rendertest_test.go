@@ -0,0 +1,116 @@
+package rendertest_test
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render"
+	"github.com/patrickward/hop/render/rendertest"
+	"github.com/patrickward/hop/render/testdata/source1"
+)
+
+// recordingTB records failures instead of failing the test
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestNormalize(t *testing.T) {
+	html := `<script nonce="abc123"></script>` +
+		`<input type="hidden" name="csrf_token" value="tok1">` +
+		`<meta name="csrf-token" content="tok2">` +
+		`<body hx-headers='{"X-CSRF-Token": "tok3"}'>` +
+		`<link href="/app.css?v=20241016093000">`
+
+	expected := `<script nonce="[nonce]"></script>` +
+		`<input type="hidden" name="csrf_token" value="[csrf]">` +
+		`<meta name="csrf-token" content="[csrf]">` +
+		`<body hx-headers='{"X-CSRF-Token": "[csrf]"}'>` +
+		`<link href="/app.css?v=[cachebuster]">`
+
+	assert.Equal(t, expected, rendertest.Normalize(html, rendertest.DefaultNormalizers...))
+}
+
+func TestFormatHTML(t *testing.T) {
+	html := "\n  <div class=\"a\">\n\n    <p>Hello, <b>world</b>!</p></div>\n"
+	assert.Equal(t, "<div class=\"a\">\n<p>Hello, <b>world</b>!</p>\n</div>\n", rendertest.FormatHTML(html))
+}
+
+func TestDiff(t *testing.T) {
+	assert.Empty(t, rendertest.Diff("a\nb\n", "a\nb\n"))
+
+	want := "<ul>\n<li>1</li>\n<li>2</li>\n<li>3</li>\n<li>4</li>\n<li>5</li>\n<li>6</li>\n</ul>\n"
+	got := "<ul>\n<li>1</li>\n<li>2</li>\n<li>3</li>\n<li>4</li>\n<li>five</li>\n<li>6</li>\n</ul>\n"
+	expected := "" +
+		"     4 | <li>3</li>\n" +
+		"     5 | <li>4</li>\n" +
+		"-    6 | <li>5</li>\n" +
+		"+    6 | <li>five</li>\n" +
+		"     7 | <li>6</li>\n" +
+		"     8 | </ul>\n"
+	assert.Equal(t, expected, rendertest.Diff(want, got))
+}
+
+func TestAssertGolden(t *testing.T) {
+	tm, err := render.NewTemplateManager(render.Sources{"": source1.FS}, render.TemplateManagerOptions{
+		Extension: ".gtml",
+		Logger:    slog.Default(),
+	})
+	require.NoError(t, err)
+
+	page := func(title string) string {
+		resp := tm.NewResponse().Layout("base").Path("home").WithData(map[string]any{
+			"Title":      title,
+			"Content":    "Main content here",
+			"User":       "John Doe",
+			"Navigation": []string{"Home", "About"},
+		})
+		return rendertest.Render(t, resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	dir := t.TempDir()
+	inDir := func(opts *rendertest.Options) { opts.Dir = dir }
+
+	// A missing snapshot fails with instructions
+	tb := &recordingTB{TB: t}
+	rendertest.AssertGolden(tb, "home", page("Welcome"), inDir)
+	require.Len(t, tb.failures, 1)
+	assert.Contains(t, tb.failures[0], rendertest.UpdateEnv+"=1")
+
+	// Updating writes the formatted snapshot, which then matches
+	rendertest.AssertGolden(t, "home", page("Welcome"), func(opts *rendertest.Options) {
+		opts.Dir = dir
+		opts.Update = true
+	})
+	golden, err := os.ReadFile(filepath.Join(dir, "home.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(golden), "<title>Welcome</title>\n")
+
+	rendertest.AssertGolden(t, "home", page("Welcome"), inDir)
+
+	// A change fails with a diff
+	tb = &recordingTB{TB: t}
+	rendertest.AssertGolden(tb, "home", page("Hello"), inDir)
+	require.Len(t, tb.failures, 1)
+	assert.Contains(t, tb.failures[0], "- ")
+	assert.Contains(t, tb.failures[0], "<title>Welcome</title>")
+	assert.Contains(t, tb.failures[0], "<title>Hello</title>")
+}
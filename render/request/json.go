@@ -0,0 +1,174 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DecodeJSONOptions configures DecodeJSON
+type DecodeJSONOptions struct {
+	// MaxBytes is the largest request body accepted, in bytes. Default is 1 MB.
+	MaxBytes int64
+	// AllowUnknownFields accepts object keys that don't match a field of the destination. By default they
+	// are rejected, so typos in client requests don't go unnoticed.
+	AllowUnknownFields bool
+	// AllowAnyContentType skips the check that the request has a JSON Content-Type
+	AllowAnyContentType bool
+}
+
+// DecodeError is returned by DecodeJSON when the request body can't be decoded. Its message is written for
+// the client, and Status is the status code to respond with.
+type DecodeError struct {
+	// Status is the HTTP status code for the error: 400, 413 or 415
+	Status int
+	// Message describes the problem to the client
+	Message string
+	// Field is the JSON field with an invalid value or the unknown field, if any
+	Field string
+	// Err is the underlying error
+	Err error
+}
+
+// Error returns the message of the error
+func (e *DecodeError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeJSON decodes a JSON request body into dst. The body must be a single JSON value no larger than
+// MaxBytes. Problems with the request are returned as a *DecodeError; any other error is a programming
+// error, such as a non-pointer dst.
+//
+//	var input struct {
+//		Email string `json:"email"`
+//	}
+//	if err := request.DecodeJSON(w, r, &input, nil); err != nil {
+//		var decodeErr *request.DecodeError
+//		if errors.As(err, &decodeErr) {
+//			_ = render.WriteJSONError(w, decodeErr.Status, decodeErr.Message, nil)
+//			return
+//		}
+//		...
+//	}
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any, optsFunc func(opts *DecodeJSONOptions)) error {
+	opts := DecodeJSONOptions{
+		MaxBytes: 1 << 20,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if !opts.AllowAnyContentType {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+			return &DecodeError{
+				Status:  http.StatusUnsupportedMediaType,
+				Message: "Content-Type must be application/json",
+			}
+		}
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, opts.MaxBytes))
+	if !opts.AllowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(dst); err != nil {
+		// A non-pointer destination is a programming error, not the client's fault
+		var invalidErr *json.InvalidUnmarshalError
+		if errors.As(err, &invalidErr) {
+			return err
+		}
+		return decodeError(err)
+	}
+
+	// Anything but whitespace after the value is an error
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return decodeError(err)
+		}
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: "request body must only contain a single JSON value",
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// decodeError turns an error from the JSON decoder into a DecodeError
+func decodeError(err error) *DecodeError {
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("request body contains badly-formed JSON (at position %d)", syntaxErr.Offset),
+			Err:     err,
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: "request body contains badly-formed JSON",
+			Err:     err,
+		}
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return &DecodeError{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("request body contains an invalid value for the %q field", typeErr.Field),
+				Field:   typeErr.Field,
+				Err:     err,
+			}
+		}
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("request body contains an invalid value (at position %d)", typeErr.Offset),
+			Err:     err,
+		}
+	case errors.Is(err, io.EOF):
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: "request body must not be empty",
+			Err:     err,
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("request body contains unknown field %q", field),
+			Field:   field,
+			Err:     err,
+		}
+	case errors.As(err, &maxBytesErr):
+		return &DecodeError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit),
+			Err:     err,
+		}
+	default:
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: "request body could not be decoded",
+			Err:     err,
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultTimezone is used when no timezone can be determined for a request
+var DefaultTimezone = time.UTC
+
+// TimezoneCookie is the cookie Timezone reads an IANA timezone name from, e.g. one set by a script with
+// Intl.DateTimeFormat().resolvedOptions().timeZone
+const TimezoneCookie = "tz"
+
+type timezoneContextKey struct{}
+
+// WithTimezone returns a context that makes Timezone use the given location, e.g. from a user preference
+func WithTimezone(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, timezoneContextKey{}, loc)
+}
+
+// Timezone returns the timezone for a request: the location set with WithTimezone, or the valid IANA
+// timezone in the TimezoneCookie cookie, or DefaultTimezone.
+func Timezone(r *http.Request) *time.Location {
+	if loc, ok := r.Context().Value(timezoneContextKey{}).(*time.Location); ok && loc != nil {
+		return loc
+	}
+
+	if c, err := r.Cookie(TimezoneCookie); err == nil && c.Value != "" && c.Value != "Local" {
+		if loc, err := time.LoadLocation(c.Value); err == nil {
+			return loc
+		}
+	}
+
+	return DefaultTimezone
+}
@@ -31,6 +31,12 @@ type Response struct {
 	data *PageData
 	// The template manager to be used for rendering templates
 	tm *TemplateManager
+	// The URL pushed to the browser history for htmx navigations (default: empty, see HxNavigate)
+	navigateURL string
+	// Whether the template is executed straight to the ResponseWriter (default: false, see Stream)
+	stream bool
+	// The out-of-band fragments rendered after the template for htmx requests (default: none, see OOB)
+	oob []oobFragment
 }
 
 func NewResponse(tm *TemplateManager) *Response {
@@ -210,6 +216,15 @@ func (resp *Response) Path(path string) *Response {
 	return resp
 }
 
+// Stream executes the template straight to the ResponseWriter instead of rendering it to a buffer first,
+// so very large pages start reaching the client sooner and don't need to be held in memory. The status code
+// is sent before the template runs, so a template error leaves the page truncated instead of rendering the
+// error page; the error is logged. Pages aren't audited for accessibility when streamed.
+func (resp *Response) Stream() *Response {
+	resp.stream = true
+	return resp
+}
+
 // Layout sets the template layout. It updates the layout value in the Response struct.
 // Then it returns the updated Response struct itself for method chaining.
 func (resp *Response) Layout(layout string) *Response {
@@ -45,6 +45,44 @@ func (resp *Response) HxNoPushURL() *Response {
 	return resp
 }
 
+// HxNavigate marks the response as a page navigation for htmx applications. It sets the page title and,
+// when the response is rendered:
+//
+//   - for htmx requests, pushes path to the browser history with HX-Push-Url and renders the template
+//     manager's NavigationLayout instead of the response's layout, so only the page content is swapped
+//   - for history restore requests, which htmx sends when a page is missing from its history cache,
+//     renders the full layout without pushing a URL
+//   - for boosted and regular requests, renders the full layout
+//
+// The Vary header is set to HX-Request so that caches keep partial and full responses apart.
+//
+//	app.NewResponse(r).Layout("base").Path("posts/show").HxNavigate("/posts/42", post.Title).Render(w, r)
+func (resp *Response) HxNavigate(path, title string) *Response {
+	resp.navigateURL = path
+	resp.title = title
+	return resp
+}
+
+// applyNavigation applies HxNavigate for the request, using navigationLayout for htmx requests
+func (resp *Response) applyNavigation(r *http.Request, navigationLayout string) {
+	if resp.navigateURL == "" {
+		return
+	}
+
+	resp.headers["Vary"] = htmx.HXRequest
+	if htmx.IsHistoryRestoreRequest(r) {
+		return
+	}
+
+	if htmx.IsAnyHtmxRequest(r) {
+		resp.headers[htmx.HXPushURL] = resp.navigateURL
+	}
+
+	if htmx.IsHtmxRequest(r) && navigationLayout != "" {
+		resp.layout = navigationLayout
+	}
+}
+
 // HxRedirect sets the HX-Redirect header, which instructs the browser to navigate to the given path (this will reload the page).
 //
 // For more information, see: https://htmx.org/reference/#response_headers
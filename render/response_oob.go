@@ -0,0 +1,100 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/patrickward/hop/render/htmx"
+	"github.com/patrickward/hop/render/htmx/swap"
+)
+
+// oobFragment is a template rendered after the page for an out-of-band swap
+type oobFragment struct {
+	name string
+	data any
+	swap string
+}
+
+// OOB adds an out-of-band fragment to the response, so a single htmx response can update several regions
+// of the page. The template name is one defined in a partial, e.g. {{define "partial:cart_badge"}}, and
+// is executed with data. Its root element gets an hx-swap-oob attribute with the swap style, and htmx
+// swaps it into the element with the same id, so the root element needs an id. A nil style swaps with
+// outerHTML.
+//
+//	app.NewResponse(r).Path("cart/item").
+//		OOB("partial:cart_badge", cart, swap.OuterHTML()).
+//		Render(w, r)
+//
+// Fragments are only rendered for htmx requests, as a full page load has no use for them.
+//
+// For more information, see: https://htmx.org/attributes/hx-swap-oob
+func (resp *Response) OOB(name string, data any, style *swap.Style) *Response {
+	value := "true"
+	if style != nil {
+		value = style.String()
+	}
+	resp.oob = append(resp.oob, oobFragment{name: name, data: data, swap: value})
+	return resp
+}
+
+// OOBTarget is like OOB, but swaps the fragment into the elements matching the CSS selector instead
+// of the element with the fragment's id, e.g. OOBTarget("partial:notice", data, swap.BeforeEnd(), "#notices").
+func (resp *Response) OOBTarget(name string, data any, style *swap.Style, selector string) *Response {
+	value := "outerHTML"
+	if style != nil {
+		value = style.String()
+	}
+	resp.oob = append(resp.oob, oobFragment{name: name, data: data, swap: value + ":" + selector})
+	return resp
+}
+
+// renderOOB executes the out-of-band fragments of the response to w, for htmx requests
+func (tm *TemplateManager) renderOOB(w io.Writer, r *http.Request, resp *Response, tmpl *template.Template) error {
+	if len(resp.oob) == 0 || !htmx.IsAnyHtmxRequest(r) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, fragment := range resp.oob {
+		buf.Reset()
+		if err := tmpl.ExecuteTemplate(&buf, fragment.name, fragment.data); err != nil {
+			return fmt.Errorf("%w: out-of-band fragment %s: %s", ErrTempRender, fragment.name, err)
+		}
+		html, err := addSwapOOB(buf.String(), fragment.swap)
+		if err != nil {
+			return fmt.Errorf("%w: out-of-band fragment %s: %s", ErrTempRender, fragment.name, err)
+		}
+		if _, err := io.WriteString(w, html); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addSwapOOB adds an hx-swap-oob attribute to the first element of html, unless it has one already.
+// html/template strips comments, so the first tag is the root element.
+func addSwapOOB(html, value string) (string, error) {
+	start := strings.IndexByte(html, '<')
+	if start < 0 {
+		return "", errors.New("no root element")
+	}
+
+	// The attribute goes after the tag name
+	nameEnd := strings.IndexAny(html[start:], " \t\r\n/>")
+	if nameEnd <= 1 {
+		return "", errors.New("no root element")
+	}
+	nameEnd += start
+
+	tagEnd := strings.IndexByte(html[nameEnd:], '>')
+	if tagEnd >= 0 && strings.Contains(html[nameEnd:nameEnd+tagEnd], "hx-swap-oob") {
+		return html, nil
+	}
+
+	return html[:nameEnd] + ` hx-swap-oob="` + template.HTMLEscapeString(value) + `"` + html[nameEnd:], nil
+}
@@ -0,0 +1,81 @@
+package render_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render"
+	"github.com/patrickward/hop/render/htmx"
+	"github.com/patrickward/hop/render/htmx/swap"
+)
+
+func TestResponse_OOB(t *testing.T) {
+	tm, err := render.NewTemplateManager(render.Sources{
+		"": fstest.MapFS{
+			"layouts/base.html": {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"partials/cart.html": {Data: []byte(`{{define "partial:cart_badge"}}<!-- badge --><span id="cart-badge" class="badge">{{.}}</span>{{end}}` +
+				`{{define "partial:notice"}}<p>{{.}}</p>{{end}}` +
+				`{{define "partial:flagged"}}<div id="flagged" hx-swap-oob="innerHTML">{{.}}</div>{{end}}` +
+				`{{define "partial:text"}}just text{{end}}`)},
+			"views/item.html": {Data: []byte(`{{define "page:main"}}<li>item</li>{{end}}`)},
+		},
+	}, render.TemplateManagerOptions{Logger: slog.Default()})
+	require.NoError(t, err)
+
+	htmxRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/cart", nil)
+		r.Header.Set(htmx.HXRequest, "true")
+		return r
+	}
+
+	t.Run("appends fragments with hx-swap-oob attributes", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		tm.NewResponse().Path("item").
+			OOB("partial:cart_badge", 3, swap.OuterHTML()).
+			OOB("partial:cart_badge", 4, nil).
+			OOBTarget("partial:notice", "Added", swap.BeforeEnd(), "#notices").
+			OOB("partial:flagged", "kept", swap.OuterHTML()).
+			Render(w, htmxRequest())
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `<li>item</li>`+
+			`<span hx-swap-oob="outerHTML" id="cart-badge" class="badge">3</span>`+
+			`<span hx-swap-oob="true" id="cart-badge" class="badge">4</span>`+
+			`<p hx-swap-oob="beforeend:#notices">Added</p>`+
+			`<div id="flagged" hx-swap-oob="innerHTML">kept</div>`, w.Body.String())
+	})
+
+	t.Run("skips fragments for full page loads", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		tm.NewResponse().Path("item").
+			OOB("partial:cart_badge", 3, swap.OuterHTML()).
+			Render(w, httptest.NewRequest(http.MethodGet, "/cart", nil))
+
+		assert.Equal(t, `<li>item</li>`, w.Body.String())
+	})
+
+	t.Run("streams fragments", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		tm.NewResponse().Path("item").Stream().
+			OOB("partial:cart_badge", 3, swap.OuterHTML()).
+			Render(w, htmxRequest())
+
+		assert.Contains(t, w.Body.String(), `<span hx-swap-oob="outerHTML" id="cart-badge"`)
+	})
+
+	t.Run("fails for fragments without a root element", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		tm.NewResponse().Path("item").
+			OOB("partial:text", nil, swap.OuterHTML()).
+			Render(w, htmxRequest())
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "no root element")
+	})
+}
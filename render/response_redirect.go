@@ -2,21 +2,54 @@ package render
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
 
+	"github.com/patrickward/hop/render/alert"
 	"github.com/patrickward/hop/render/htmx"
 	"github.com/patrickward/hop/render/request"
 )
 
-// RedirectWithHTMX sends an HX-Redirect header to the client
+// RedirectWithHTMX sends an HX-Redirect header to the client. The status is 200, as htmx ignores the
+// headers of 3xx responses, which the browser follows before htmx sees them.
 func (resp *Response) RedirectWithHTMX(w http.ResponseWriter, url string) {
 	w.Header().Set(htmx.HXRedirect, url)
-	w.WriteHeader(http.StatusSeeOther)
+	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("redirecting..."))
 }
 
 // Redirect sends a redirect response to the client
 func (resp *Response) Redirect(w http.ResponseWriter, r *http.Request, url string) {
+	resp.redirect(w, r, url, http.StatusFound)
+}
+
+// SeeOther sends a 303 See Other redirect, which the browser follows with a GET. It is the redirect of
+// the Post/Redirect/Get pattern: after a form is submitted, reloading the page doesn't submit it again.
+// Like Redirect, it sends an HX-Redirect header to htmx requests.
+func (resp *Response) SeeOther(w http.ResponseWriter, r *http.Request, url string) {
+	resp.redirect(w, r, url, http.StatusSeeOther)
+}
+
+// RedirectWithFlash adds a flash message to the session and redirects with SeeOther, so the message
+// is shown by the next rendered page, e.g.
+//
+//	resp.RedirectWithFlash(w, r, "/settings", alert.TypeSuccess, "Settings saved")
+//
+// The message is dropped, and an error logged, when the TemplateManager has no Session.
+func (resp *Response) RedirectWithFlash(w http.ResponseWriter, r *http.Request, url string, typ alert.Type, message string) {
+	if resp.tm != nil && resp.tm.session != nil {
+		alert.Flash(r.Context(), resp.tm.session, typ, message)
+	} else {
+		resp.logger().Error("Flash message dropped: the template manager has no session",
+			slog.String("url", url),
+			slog.String("message", message))
+	}
+	resp.SeeOther(w, r, url)
+}
+
+// redirect sends a redirect with the status, an HX-Redirect header to htmx requests or a JSON body to
+// other XMLHttpRequests
+func (resp *Response) redirect(w http.ResponseWriter, r *http.Request, url string, status int) {
 	if htmx.IsHtmxRequest(r) {
 		resp.RedirectWithHTMX(w, url)
 		return
@@ -36,5 +69,35 @@ func (resp *Response) Redirect(w http.ResponseWriter, r *http.Request, url strin
 	}
 
 	// Otherwise, send a standard redirect
-	http.Redirect(w, r, url, http.StatusFound)
+	http.Redirect(w, r, url, status)
+}
+
+// logger returns the logger of the TemplateManager, or the default logger
+func (resp *Response) logger() *slog.Logger {
+	if resp.tm != nil && resp.tm.logger != nil {
+		return resp.tm.logger
+	}
+	return slog.Default()
+}
+
+// addFlashes moves the flash messages of the session to the page data, unless the response set its own
+func (tm *TemplateManager) addFlashes(r *http.Request, resp *Response) {
+	if tm.session == nil {
+		return
+	}
+	if _, ok := resp.data.data[PageDataFlashKey]; ok {
+		return
+	}
+	resp.data.Set(PageDataFlashKey, tm.popFlashes(r))
+}
+
+// popFlashes returns the flash messages of the request's session, or nil when the request has no
+// session, as the session manager panics for requests outside its LoadAndSave middleware
+func (tm *TemplateManager) popFlashes(r *http.Request) (flashes []alert.Alert) {
+	defer func() {
+		if recover() != nil {
+			flashes = nil
+		}
+	}()
+	return alert.Flashes(r.Context(), tm.session)
 }
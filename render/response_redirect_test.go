@@ -0,0 +1,134 @@
+package render_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render"
+	"github.com/patrickward/hop/render/alert"
+	"github.com/patrickward/hop/render/htmx"
+)
+
+func TestResponse_Redirects(t *testing.T) {
+	tests := []struct {
+		name       string
+		redirect   func(resp *render.Response, w http.ResponseWriter, r *http.Request)
+		headers    map[string]string
+		wantStatus int
+		wantHeader string
+		wantValue  string
+	}{
+		{
+			name:       "redirect",
+			redirect:   func(resp *render.Response, w http.ResponseWriter, r *http.Request) { resp.Redirect(w, r, "/next") },
+			wantStatus: http.StatusFound,
+			wantHeader: "Location",
+			wantValue:  "/next",
+		},
+		{
+			name:       "see other",
+			redirect:   func(resp *render.Response, w http.ResponseWriter, r *http.Request) { resp.SeeOther(w, r, "/next") },
+			wantStatus: http.StatusSeeOther,
+			wantHeader: "Location",
+			wantValue:  "/next",
+		},
+		{
+			name:       "see other from htmx",
+			redirect:   func(resp *render.Response, w http.ResponseWriter, r *http.Request) { resp.SeeOther(w, r, "/next") },
+			headers:    map[string]string{htmx.HXRequest: "true"},
+			wantStatus: http.StatusOK,
+			wantHeader: htmx.HXRedirect,
+			wantValue:  "/next",
+		},
+		{
+			name:       "see other from boosted htmx",
+			redirect:   func(resp *render.Response, w http.ResponseWriter, r *http.Request) { resp.SeeOther(w, r, "/next") },
+			headers:    map[string]string{htmx.HXRequest: "true", htmx.HXBoosted: "true"},
+			wantStatus: http.StatusSeeOther,
+			wantHeader: "Location",
+			wantValue:  "/next",
+		},
+		{
+			name:       "see other from XMLHttpRequest",
+			redirect:   func(resp *render.Response, w http.ResponseWriter, r *http.Request) { resp.SeeOther(w, r, "/next") },
+			headers:    map[string]string{"X-Requested-With": "XMLHttpRequest"},
+			wantStatus: http.StatusOK,
+			wantHeader: "Content-Type",
+			wantValue:  "application/json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/form", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			tt.redirect(render.NewResponse(nil), w, r)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantValue, w.Header().Get(tt.wantHeader))
+		})
+	}
+}
+
+func TestResponse_RedirectWithFlash(t *testing.T) {
+	session := scs.New()
+	tm, err := render.NewTemplateManager(render.Sources{
+		"": fstest.MapFS{
+			"layouts/base.html": {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/page.html":   {Data: []byte(`{{define "page:main"}}{{range .Flash}}[{{.Type}}: {{.Message}}]{{end}}{{end}}`)},
+		},
+	}, render.TemplateManagerOptions{Logger: slog.Default(), Session: session})
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /settings", func(w http.ResponseWriter, r *http.Request) {
+		alert.Flash(r.Context(), session, alert.TypeInfo, "Checking")
+		tm.NewResponse().RedirectWithFlash(w, r, "/settings", alert.TypeSuccess, "Settings saved")
+	})
+	mux.HandleFunc("GET /settings", func(w http.ResponseWriter, r *http.Request) {
+		tm.NewResponse().Path("page").Render(w, r)
+	})
+	handler := session.LoadAndSave(mux)
+
+	var cookie *http.Cookie
+	do := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/settings", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		for _, c := range w.Result().Cookies() {
+			cookie = c
+		}
+		return w
+	}
+
+	w := do(http.MethodPost, nil)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/settings", w.Header().Get("Location"))
+
+	w = do(http.MethodGet, nil)
+	assert.Equal(t, "[info: Checking][success: Settings saved]", w.Body.String())
+
+	w = do(http.MethodGet, nil)
+	assert.Empty(t, w.Body.String(), "flash messages are shown once")
+
+	w = do(http.MethodPost, map[string]string{htmx.HXRequest: "true"})
+	assert.Equal(t, "/settings", w.Header().Get(htmx.HXRedirect))
+	w = do(http.MethodGet, nil)
+	assert.Equal(t, "[info: Checking][success: Settings saved]", w.Body.String())
+}
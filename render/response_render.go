@@ -38,11 +38,27 @@ func (resp *Response) RenderNotFound(w http.ResponseWriter, r *http.Request) {
 	resp.tm.renderSystemError(w, r, resp, http.StatusNotFound, fmt.Errorf("not found"))
 }
 
+// RenderRequestTooLarge renders the 413 Request Entity Too Large page
+func (resp *Response) RenderRequestTooLarge(w http.ResponseWriter, r *http.Request) {
+	resp.tm.renderSystemError(w, r, resp, http.StatusRequestEntityTooLarge, fmt.Errorf("request entity too large"))
+}
+
 // RenderMaintenance renders the 503 Service Unavailable page
 func (resp *Response) RenderMaintenance(w http.ResponseWriter, r *http.Request) {
 	resp.tm.renderSystemError(w, r, resp, http.StatusServiceUnavailable, fmt.Errorf("service Unavailable"))
 }
 
+// RenderGatewayTimeout renders the 504 Gateway Timeout page
+func (resp *Response) RenderGatewayTimeout(w http.ResponseWriter, r *http.Request) {
+	resp.tm.renderSystemError(w, r, resp, http.StatusGatewayTimeout, fmt.Errorf("gateway timeout"))
+}
+
+// RenderServerError renders the 500 Internal Server Error page without logging a stack trace. It is
+// intended for callers, such as panic recovery middleware, that have already logged the error.
+func (resp *Response) RenderServerError(w http.ResponseWriter, r *http.Request) {
+	resp.tm.renderSystemError(w, r, resp, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+}
+
 // RenderSystemError renders the 500 Internal Server Error page
 func (resp *Response) RenderSystemError(w http.ResponseWriter, r *http.Request, err error) {
 	// Get the stack trace and output to the log
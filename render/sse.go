@@ -0,0 +1,437 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/serve"
+)
+
+// SSEEvent is a server-sent event
+type SSEEvent struct {
+	// ID is the event ID. Browsers send the last ID they received in the Last-Event-ID header when they
+	// reconnect, so the stream can resume.
+	ID string
+	// Event is the event name; browsers dispatch unnamed events as "message"
+	Event string
+	// Data is the event data. Strings and byte slices are sent as they are, e.g. HTML for the htmx SSE
+	// extension; other values are encoded as JSON.
+	Data any
+	// Retry, when set, tells the browser how long to wait before reconnecting
+	Retry time.Duration
+}
+
+// SSEOptions configures an SSE stream
+type SSEOptions struct {
+	// Heartbeat is how often a comment is sent to keep the connection open through proxies and to detect
+	// clients that went away. Default is 15 seconds; a negative value disables it.
+	Heartbeat time.Duration
+	// Retry, when set, is sent when the stream opens to set the browser's reconnection delay
+	Retry time.Duration
+}
+
+// SSE is an open server-sent events stream. It is safe for concurrent use.
+type SSE struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	ctx         context.Context
+	lastEventID string
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewSSE starts a server-sent events stream: it sends the event stream headers, and then a heartbeat
+// comment at every heartbeat interval until the client disconnects or Close is called. It returns an error
+// when the response writer can't be flushed.
+//
+//	stream, err := render.NewSSE(w, r, nil)
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusInternalServerError)
+//		return
+//	}
+//	defer stream.Close()
+//
+//	for {
+//		select {
+//		case <-stream.Done():
+//			return
+//		case order := <-orders:
+//			_ = stream.Send(render.SSEEvent{Event: "order", Data: order})
+//		}
+//	}
+func NewSSE(w http.ResponseWriter, r *http.Request, optsFunc func(opts *SSEOptions)) (*SSE, error) {
+	opts := SSEOptions{
+		Heartbeat: 15 * time.Second,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	s := &SSE{
+		w:           w,
+		rc:          http.NewResponseController(w),
+		ctx:         r.Context(),
+		lastEventID: lastEventID(r),
+		done:        make(chan struct{}),
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// Disable response buffering in nginx
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if opts.Retry > 0 {
+		_, _ = fmt.Fprintf(w, "retry: %d\n\n", opts.Retry.Milliseconds())
+	}
+	if err := s.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("sse: streaming is not supported: %w", err)
+	}
+
+	go s.watch(opts.Heartbeat)
+
+	return s, nil
+}
+
+// LastEventID returns the ID of the last event the client received before reconnecting, from the
+// Last-Event-ID header or the lastEventId query parameter, or "" on the first connection
+func (s *SSE) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done returns a channel that is closed when the client disconnects or the stream is closed
+func (s *SSE) Done() <-chan struct{} {
+	return s.done
+}
+
+// Send writes an event and flushes it to the client
+func (s *SSE) Send(e SSEEvent) error {
+	data, err := sseData(e.Data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + sseField(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + sseField(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Close stops the heartbeat and marks the stream as done. The response ends when the handler returns.
+func (s *SSE) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+// closeLocked marks the stream as done; s.mu must be held
+func (s *SSE) closeLocked() {
+	s.closed = true
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// write writes and flushes a chunk of the stream, closing the stream when the client is gone
+func (s *SSE) write(chunk string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errSSEClosed
+	}
+	if err := s.ctx.Err(); err != nil {
+		s.closeLocked()
+		return errSSEClosed
+	}
+
+	if _, err := s.w.Write([]byte(chunk)); err != nil {
+		s.closeLocked()
+		return err
+	}
+	if err := s.rc.Flush(); err != nil {
+		s.closeLocked()
+		return err
+	}
+	return nil
+}
+
+// watch closes the stream when the client disconnects, sending a heartbeat comment at every interval
+// until then
+func (s *SSE) watch(heartbeat time.Duration) {
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.ctx.Done():
+			s.Close()
+			return
+		case <-tick:
+			if err := s.write(": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+var errSSEClosed = errors.New("sse: stream closed")
+
+// lastEventID returns the ID of the last event received by a reconnecting client
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
+
+// sseData returns the data of an event as text
+func sseData(data any) (string, error) {
+	switch d := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return "", fmt.Errorf("sse: encoding data: %w", err)
+		}
+		return string(b), nil
+	}
+}
+
+// sseField strips line breaks, which would end the field
+func sseField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// SSEBroadcasterOptions configures an SSEBroadcaster
+type SSEBroadcasterOptions struct {
+	// SSEOptions configures the stream of each client
+	SSEOptions
+	// HistorySize is the number of recent events kept to resume reconnecting clients from their
+	// Last-Event-ID. Default is 100; a negative value disables resuming.
+	HistorySize int
+	// ClientBuffer is the number of events queued for each client. A client whose queue is full is too
+	// slow to keep up and is disconnected; the browser reconnects and resumes. Default is 32.
+	ClientBuffer int
+	// Server, when set, sends the server's restart notice to every stream as a server.restarting event
+	// when it begins a graceful shutdown, with a retry hint, then ends the stream, so open streams don't
+	// hold up the shutdown and browsers reconnect once the server is back
+	Server *serve.Server
+}
+
+// SSEBroadcaster streams published events to every connected browser. It is an http.Handler: each
+// request opens a stream, which first replays the events the client missed since its Last-Event-ID.
+// Events get increasing IDs. An SSEBroadcaster is safe for concurrent use.
+//
+//	notifications := render.NewSSEBroadcaster(func(opts *render.SSEBroadcasterOptions) {
+//	    opts.Server = app.Server()
+//	})
+//	notifications.Subscribe(app.Dispatcher(), "orders.*")
+//	router.HandleFunc("GET /events", notifications)
+//
+//	<div hx-ext="sse" sse-connect="/events" sse-swap="orders.created"></div>
+type SSEBroadcaster struct {
+	opts SSEBroadcasterOptions
+
+	mu      sync.Mutex
+	clients map[chan SSEEvent]struct{}
+	history []SSEEvent
+	nextID  uint64
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// NewSSEBroadcaster creates a new broadcaster
+func NewSSEBroadcaster(optsFunc func(opts *SSEBroadcasterOptions)) *SSEBroadcaster {
+	opts := SSEBroadcasterOptions{
+		SSEOptions:   SSEOptions{Heartbeat: 15 * time.Second},
+		HistorySize:  100,
+		ClientBuffer: 32,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.ClientBuffer <= 0 {
+		opts.ClientBuffer = 32
+	}
+
+	return &SSEBroadcaster{
+		opts:    opts,
+		clients: make(map[chan SSEEvent]struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Publish sends an event to every connected client and returns its ID
+func (b *SSEBroadcaster) Publish(event string, data any) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	e := SSEEvent{ID: strconv.FormatUint(b.nextID, 10), Event: event, Data: data}
+
+	if b.opts.HistorySize > 0 {
+		b.history = append(b.history, e)
+		if len(b.history) > b.opts.HistorySize {
+			b.history = b.history[len(b.history)-b.opts.HistorySize:]
+		}
+	}
+
+	for client := range b.clients {
+		select {
+		case client <- e:
+		default:
+			// Too slow to keep up: disconnect it, so it reconnects and resumes from the history
+			delete(b.clients, client)
+			close(client)
+		}
+	}
+
+	return e.ID
+}
+
+// Subscribe streams the dispatch events matching the signature to the clients, named after the event
+// signature, with the payload as data. Unsubscribe the returned subscription to stop.
+func (b *SSEBroadcaster) Subscribe(d *dispatch.Dispatcher, signature string) *dispatch.Subscription {
+	return d.On(signature, func(_ context.Context, e dispatch.Event) {
+		b.Publish(e.Signature, e.Payload)
+	})
+}
+
+// Len returns the number of connected clients
+func (b *SSEBroadcaster) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+// Close ends every stream and refuses new ones. Set Server to end the streams when the server shuts down.
+func (b *SSEBroadcaster) Close(_ context.Context) error {
+	b.once.Do(func() {
+		close(b.closed)
+	})
+	return nil
+}
+
+// ServeHTTP streams the events to the client until it disconnects or the broadcaster is closed
+func (b *SSEBroadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-b.closed:
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	stream, err := NewSSE(w, r, func(opts *SSEOptions) {
+		*opts = b.opts.SSEOptions
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer stream.Close()
+
+	var restart <-chan serve.RestartNotice
+	if b.opts.Server != nil {
+		sub := b.opts.Server.SubscribeRestart()
+		defer sub.Close()
+		restart = sub.C
+	}
+
+	events, missed := b.subscribe(stream.LastEventID())
+	defer b.unsubscribe(events)
+
+	for _, e := range missed {
+		if err := stream.Send(e); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Done():
+			return
+		case <-b.closed:
+			return
+		case notice := <-restart:
+			_ = stream.Send(SSEEvent{Event: serve.RestartEvent, Data: notice, Retry: notice.ReconnectAfter})
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := stream.Send(e); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe registers a client and returns the events it missed since lastID
+func (b *SSEBroadcaster) subscribe(lastID string) (chan SSEEvent, []SSEEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan SSEEvent, b.opts.ClientBuffer)
+	b.clients[events] = struct{}{}
+
+	var missed []SSEEvent
+	if last, err := strconv.ParseUint(lastID, 10, 64); err == nil {
+		for _, e := range b.history {
+			if id, _ := strconv.ParseUint(e.ID, 10, 64); id > last {
+				missed = append(missed, e)
+			}
+		}
+	}
+	return events, missed
+}
+
+// unsubscribe removes a client, unless a slow publish already did
+func (b *SSEBroadcaster) unsubscribe(events chan SSEEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.clients[events]; ok {
+		delete(b.clients, events)
+		close(events)
+	}
+}
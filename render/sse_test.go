@@ -0,0 +1,211 @@
+package render_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/conf"
+	"github.com/patrickward/hop/conf/conftype"
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/render"
+	"github.com/patrickward/hop/route"
+	"github.com/patrickward/hop/serve"
+)
+
+// readEvent reads the lines of the next event, skipping comments
+func readEvent(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(lines) > 0 {
+				return lines
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+}
+
+func openStream(t *testing.T, url, lastEventID string) *bufio.Reader {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+	return bufio.NewReader(resp.Body)
+}
+
+func TestSSE(t *testing.T) {
+	t.Run("sends events", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			stream, err := render.NewSSE(w, r, func(opts *render.SSEOptions) {
+				opts.Heartbeat = 5 * time.Millisecond
+				opts.Retry = 3 * time.Second
+			})
+			require.NoError(t, err)
+			defer stream.Close()
+
+			assert.Equal(t, "41", stream.LastEventID())
+			assert.NoError(t, stream.Send(render.SSEEvent{ID: "42", Event: "update", Data: "<li>one</li>\n<li>two</li>"}))
+			assert.NoError(t, stream.Send(render.SSEEvent{Data: map[string]int{"count": 2}}))
+			<-stream.Done()
+		}))
+		t.Cleanup(srv.Close)
+
+		events := openStream(t, srv.URL, "41")
+		assert.Equal(t, []string{"retry: 3000"}, readEvent(t, events))
+		assert.Equal(t, []string{"id: 42", "event: update", "data: <li>one</li>", "data: <li>two</li>"}, readEvent(t, events))
+		assert.Equal(t, []string{`data: {"count":2}`}, readEvent(t, events))
+
+		// The heartbeat keeps coming until the client goes away
+		line, err := events.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, ": ping\n", line)
+	})
+
+	t.Run("detects disconnected clients", func(t *testing.T) {
+		done := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			stream, err := render.NewSSE(w, r, nil)
+			require.NoError(t, err)
+			<-stream.Done()
+			close(done)
+		}))
+		t.Cleanup(srv.Close)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		cancel()
+		_ = resp.Body.Close()
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("stream was not closed")
+		}
+	})
+}
+
+func TestSSEBroadcaster(t *testing.T) {
+	b := render.NewSSEBroadcaster(func(opts *render.SSEBroadcasterOptions) {
+		opts.HistorySize = 2
+	})
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { _ = b.Close(context.Background()) })
+
+	events := openStream(t, srv.URL, "")
+	require.Eventually(t, func() bool { return b.Len() == 1 }, time.Second, 5*time.Millisecond)
+
+	d := dispatch.NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	sub := b.Subscribe(d, "orders.*")
+	defer sub.Unsubscribe()
+
+	require.NoError(t, d.EmitSync(context.Background(), "orders.created", map[string]int{"id": 1}))
+	assert.Equal(t, []string{"id: 1", "event: orders.created", `data: {"id":1}`}, readEvent(t, events))
+
+	assert.Equal(t, "2", b.Publish("notice", "two"))
+	assert.Equal(t, "3", b.Publish("notice", "three"))
+	assert.Equal(t, []string{"id: 2", "event: notice", "data: two"}, readEvent(t, events))
+	assert.Equal(t, []string{"id: 3", "event: notice", "data: three"}, readEvent(t, events))
+
+	t.Run("resumes from the last event ID", func(t *testing.T) {
+		resumed := openStream(t, srv.URL, "1")
+		assert.Equal(t, []string{"id: 2", "event: notice", "data: two"}, readEvent(t, resumed))
+		assert.Equal(t, []string{"id: 3", "event: notice", "data: three"}, readEvent(t, resumed))
+	})
+
+	t.Run("refuses streams once closed", func(t *testing.T) {
+		require.NoError(t, b.Close(context.Background()))
+
+		resp, err := http.Get(srv.URL)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+}
+
+func TestSSEBroadcaster_Restart(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	cfg := &conf.HopConfig{}
+	cfg.Server.ShutdownTimeout = conftype.Duration{Duration: 10 * time.Second}
+	cfg.Server.ReconnectAfter = conftype.Duration{Duration: 2 * time.Second}
+
+	router := route.New()
+	srv := serve.NewServer(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), router)
+	srv.SetListener(ln)
+
+	b := render.NewSSEBroadcaster(func(opts *render.SSEBroadcasterOptions) {
+		opts.Server = srv
+	})
+	router.Get("/events", b)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Start() }()
+
+	var events *bufio.Reader
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/events")
+		if err != nil {
+			return false
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		events = bufio.NewReader(resp.Body)
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return b.Len() == 1 }, time.Second, 5*time.Millisecond)
+
+	started := time.Now()
+	go func() { _ = srv.Shutdown(context.Background()) }()
+
+	assert.Equal(t, []string{
+		"event: server.restarting",
+		"retry: 2000",
+		`data: {"type":"server.restarting","reconnect_after_ms":2000,"message":"Server is restarting"}`,
+	}, readEvent(t, events))
+
+	_, err = events.ReadString('\n')
+	assert.ErrorIs(t, err, io.EOF, "the stream ends after the notice")
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+		assert.Less(t, time.Since(started), 5*time.Second, "open streams should not hold up the shutdown")
+	case <-time.After(10 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
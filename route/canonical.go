@@ -0,0 +1,143 @@
+package route
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// TrailingSlash is the trailing slash policy of canonical paths
+type TrailingSlash int
+
+const (
+	// TrailingSlashIgnore leaves trailing slashes alone
+	TrailingSlashIgnore TrailingSlash = iota
+	// TrailingSlashStrip redirects /foo/ to /foo
+	TrailingSlashStrip
+	// TrailingSlashAdd redirects /foo to /foo/. Paths whose last segment has an extension, such as
+	// /app.css, are left alone.
+	TrailingSlashAdd
+)
+
+// CanonicalOptions configures the canonical path redirects of a Mux
+type CanonicalOptions struct {
+	// TrailingSlash is the trailing slash policy. Default is TrailingSlashIgnore.
+	TrailingSlash TrailingSlash
+	// Lowercase redirects paths with uppercase letters to their lowercase form
+	Lowercase bool
+	// CleanSlashes collapses duplicate slashes, e.g. /foo//bar to /foo/bar
+	CleanSlashes bool
+}
+
+// Canonicalize makes the mux redirect requests for non-canonical paths, e.g. /Foo/ to /foo, so apps get
+// canonical URLs without catch-all handlers. GET and HEAD requests are redirected with 301 Moved
+// Permanently, other methods with 308 Permanent Redirect so the method and body are kept. The query
+// string is preserved.
+//
+// A request is only redirected when its path doesn't match a route and its canonical path does, so routes
+// registered with a trailing slash, and wildcards matching mixed-case values, keep working.
+//
+//	router.Canonicalize(func(opts *route.CanonicalOptions) {
+//		opts.TrailingSlash = route.TrailingSlashStrip
+//		opts.Lowercase = true
+//		opts.CleanSlashes = true
+//	})
+func (m *Mux) Canonicalize(optsFunc func(opts *CanonicalOptions)) {
+	opts := CanonicalOptions{}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	m.canonical = &opts
+}
+
+// ServeHTTP redirects non-canonical paths when Canonicalize is enabled, and dispatches the request to
+// the matching route
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.canonical != nil {
+		if target, ok := m.canonicalRedirect(r); ok {
+			status := http.StatusPermanentRedirect
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				status = http.StatusMovedPermanently
+			}
+			http.Redirect(w, r, target, status)
+			return
+		}
+	}
+
+	m.ServeMux.ServeHTTP(w, r)
+}
+
+// canonicalRedirect returns the URL to redirect the request to, if its path isn't canonical and the
+// canonical path matches a route
+func (m *Mux) canonicalRedirect(r *http.Request) (string, bool) {
+	canonical := m.canonical.path(r.URL.Path)
+	if canonical == r.URL.Path || m.matches(r, r.URL.Path) || !m.matches(r, canonical) {
+		return "", false
+	}
+
+	u := url.URL{Path: canonical, RawQuery: r.URL.RawQuery}
+	return u.String(), true
+}
+
+// matches reports whether the request, with the given path, matches a route other than the catch-all.
+// Paths the ServeMux would itself redirect, because they are unclean or lack the trailing slash of a
+// subtree route, don't match.
+func (m *Mux) matches(r *http.Request, p string) bool {
+	clean := path.Clean(p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	if clean != p {
+		return false
+	}
+
+	probe := *r
+	u := *r.URL
+	u.Path = p
+	u.RawPath = ""
+	probe.URL = &u
+
+	_, pattern := m.ServeMux.Handler(&probe)
+	if pattern == "" || pattern == "/" {
+		return false
+	}
+
+	// A subtree route such as "/docs/" is reported for /docs, which the ServeMux redirects to /docs/
+	_, routePath := splitPattern(pattern)
+	if i := strings.Index(routePath, "/"); i > 0 {
+		routePath = routePath[i:]
+	}
+	return strings.HasSuffix(p, "/") || routePath != p+"/"
+}
+
+// path returns the canonical form of a path
+func (opts *CanonicalOptions) path(p string) string {
+	if opts.CleanSlashes {
+		for strings.Contains(p, "//") {
+			p = strings.ReplaceAll(p, "//", "/")
+		}
+	}
+	if opts.Lowercase {
+		p = strings.ToLower(p)
+	}
+
+	if p == "/" || p == "" {
+		return p
+	}
+
+	switch opts.TrailingSlash {
+	case TrailingSlashStrip:
+		p = strings.TrimRight(p, "/")
+		if p == "" {
+			p = "/"
+		}
+	case TrailingSlashAdd:
+		if !strings.HasSuffix(p, "/") && path.Ext(p) == "" {
+			p += "/"
+		}
+	}
+	return p
+}
@@ -3,16 +3,22 @@ package route
 import (
 	"net/http"
 	"path"
+	"slices"
 	"strings"
 )
 
 // Group represents a collection of routes with a common prefix and middleware
 type Group struct {
 	mux         *Mux
+	host        string // host the group's routes are restricted to, if any
 	prefix      string
 	middleware  Chain
 	parent      *Group // Track parent group for middleware inheritance
 	independent bool   // If true, this group will not inherit middleware from parent
+	onlyIn      []string
+	flags       []string
+	// methodMiddleware holds middleware that only applies to routes registered for a given method
+	methodMiddleware map[string]Chain
 }
 
 // Independent marks the group as independent, meaning it will not inherit middleware from the parent
@@ -22,9 +28,48 @@ func (g *Group) Independent() *Group {
 	return g
 }
 
+// OnlyIn restricts the group's routes, including those of nested groups, to the given environments, as set
+// with Mux.SetEnvironment. In other environments the routes are not registered at all, so they are absent
+// from the routing table and ListRoutes. Call it before registering routes.
+//
+//	router.PrefixGroup("/debug", func(g *route.Group) {
+//		g.OnlyIn("development")
+//		g.Get("/templates", templatesHandler)
+//	})
+func (g *Group) OnlyIn(environments ...string) *Group {
+	g.onlyIn = append(g.onlyIn, environments...)
+	return g
+}
+
+// IfFlag restricts the group's routes, including those of nested groups, to when every given feature flag is
+// enabled, as reported by the function set with Mux.SetFlags. Otherwise the routes are not registered at
+// all. Call it before registering routes.
+func (g *Group) IfFlag(flags ...string) *Group {
+	g.flags = append(g.flags, flags...)
+	return g
+}
+
+// enabled reports whether the environment and feature flag conditions of the group and its parents are met
+func (g *Group) enabled() bool {
+	if len(g.onlyIn) > 0 && !slices.Contains(g.onlyIn, g.mux.environment) {
+		return false
+	}
+
+	for _, flag := range g.flags {
+		if g.mux.flagEnabled == nil || !g.mux.flagEnabled(flag) {
+			return false
+		}
+	}
+
+	if g.parent != nil {
+		return g.parent.enabled()
+	}
+	return true
+}
+
 // HandleFunc registers a handler without method restrictions
-func (g *Group) HandleFunc(pattern string, handler http.Handler) {
-	g.handle(pattern, handler)
+func (g *Group) HandleFunc(pattern string, handler http.Handler, middleware ...Middleware) {
+	g.handle(pattern, handler, middleware...)
 }
 
 // Use registers middleware with the group
@@ -32,44 +77,66 @@ func (g *Group) Use(middleware ...Middleware) {
 	g.middleware = g.middleware.Append(middleware...)
 }
 
+// UseMethod registers middleware that only applies to the group's routes for the given method, including
+// those of nested groups, e.g. to require authentication for POST routes while leaving GET routes public.
+// It runs after the group middleware.
+//
+//	g.UseMethod(http.MethodPost, requireAuth)
+func (g *Group) UseMethod(method string, middleware ...Middleware) {
+	if g.methodMiddleware == nil {
+		g.methodMiddleware = make(map[string]Chain)
+	}
+	g.methodMiddleware[method] = g.methodMiddleware[method].Append(middleware...)
+}
+
+// With returns an inline group with the same prefix whose routes use the given middleware in addition to
+// the group middleware. It protects individual routes without creating a group for each of them.
+//
+//	g.With(requireAdmin).Delete("/{id}", deleteHandler)
+func (g *Group) With(middleware ...Middleware) *Group {
+	return g.Group(func(sub *Group) {
+		sub.Use(middleware...)
+	})
+}
+
 // Get registers a GET handler within the group
-func (g *Group) Get(pattern string, handler http.Handler) {
-	g.handle("GET "+pattern, handler)
+func (g *Group) Get(pattern string, handler http.Handler, middleware ...Middleware) {
+	g.handle("GET "+pattern, handler, middleware...)
 }
 
 // GetHandler registers a GET handler within the group with a handler that returns an error
-func (g *Group) GetHandler(pattern string, handler http.Handler) {
-	g.handle("GET "+pattern, handler)
+func (g *Group) GetHandler(pattern string, handler http.Handler, middleware ...Middleware) {
+	g.handle("GET "+pattern, handler, middleware...)
 }
 
 // Post registers a POST handler within the group
-func (g *Group) Post(pattern string, handler http.Handler) {
-	g.handle("POST "+pattern, handler)
+func (g *Group) Post(pattern string, handler http.Handler, middleware ...Middleware) {
+	g.handle("POST "+pattern, handler, middleware...)
 }
 
 // Put registers a PUT handler within the group
-func (g *Group) Put(pattern string, handler http.Handler) {
-	g.handle("PUT "+pattern, handler)
+func (g *Group) Put(pattern string, handler http.Handler, middleware ...Middleware) {
+	g.handle("PUT "+pattern, handler, middleware...)
 }
 
 // Delete registers a DELETE handler within the group
-func (g *Group) Delete(pattern string, handler http.Handler) {
-	g.handle("DELETE "+pattern, handler)
+func (g *Group) Delete(pattern string, handler http.Handler, middleware ...Middleware) {
+	g.handle("DELETE "+pattern, handler, middleware...)
 }
 
 // Patch registers a PATCH handler within the group
-func (g *Group) Patch(pattern string, handler http.Handler) {
-	g.handle("PATCH "+pattern, handler)
+func (g *Group) Patch(pattern string, handler http.Handler, middleware ...Middleware) {
+	g.handle("PATCH "+pattern, handler, middleware...)
 }
 
 // Options registers an OPTIONS handler within the group
-func (g *Group) Options(pattern string, handler http.Handler) {
-	g.handle("OPTIONS "+pattern, handler)
+func (g *Group) Options(pattern string, handler http.Handler, middleware ...Middleware) {
+	g.handle("OPTIONS "+pattern, handler, middleware...)
 }
 
 // Head registers a HEAD handler within the group
-func (g *Group) Head(pattern string, handler http.Handler) {
-	g.handle("HEAD "+pattern, handler)
+func (g *Group) Head(pattern string, handler http.Handler, middleware ...Middleware) {
+	g.handle("HEAD "+pattern, handler, middleware...)
 }
 
 // getMiddlewareChain returns all middleware in the chain from root to this group
@@ -89,8 +156,24 @@ func (g *Group) getMiddlewareChain() Chain {
 	return g.parent.getMiddlewareChain().Extend(g.middleware)
 }
 
-// handle registers a handler with the group's prefix and middleware chain
-func (g *Group) handle(pattern string, handler http.Handler) {
+// getMethodMiddleware returns the method-specific middleware from the outermost group to this group,
+// stopping at an independent group
+func (g *Group) getMethodMiddleware(method string) Chain {
+	var chain Chain
+	if g.parent != nil && !g.independent {
+		chain = g.parent.getMethodMiddleware(method)
+	}
+	return chain.Extend(g.methodMiddleware[method])
+}
+
+// handle registers a handler with the group's prefix and middleware chain, followed by the method-specific
+// and route middleware
+func (g *Group) handle(pattern string, handler http.Handler, middleware ...Middleware) {
+	// Routes of disabled groups are left out of the routing table entirely
+	if !g.enabled() {
+		return
+	}
+
 	// Extract method if present
 	var method string
 	if len(pattern) > 0 && pattern[0] != '/' {
@@ -101,8 +184,8 @@ func (g *Group) handle(pattern string, handler http.Handler) {
 		}
 	}
 
-	// Combine group prefix with pattern
-	fullPattern := path.Join(g.prefix, pattern)
+	// Combine group host and prefix with pattern
+	fullPattern := g.host + path.Join(g.prefix, pattern)
 
 	if method != "" {
 		// Register the route with the registry
@@ -111,6 +194,9 @@ func (g *Group) handle(pattern string, handler http.Handler) {
 		fullPattern = method + " " + fullPattern
 	}
 
+	// Route middleware runs closest to the handler, after any middleware for the method
+	handler = g.getMethodMiddleware(method).Append(middleware...).Then(handler)
+
 	// Get the combined middleware chain based on independence
 	var h http.Handler
 	if g.independent {
@@ -128,6 +214,7 @@ func (g *Group) handle(pattern string, handler http.Handler) {
 func (g *Group) PrefixGroup(prefix string, group GroupFunc) *Group {
 	subGroup := &Group{
 		mux:        g.mux,
+		host:       g.host,
 		prefix:     path.Join(g.prefix, prefix),
 		middleware: NewChain(),
 		parent:     g, // Set this group as parent
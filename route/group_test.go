@@ -3,6 +3,7 @@ package route_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -177,6 +178,77 @@ func TestGroupWithParentMiddleware(t *testing.T) {
 	assert.Equal(t, "1.0", w.Header().Get("X-V1"))
 }
 
+// TestRootGroupMuxMiddleware checks that routes of a root group run the mux middleware once. Root groups
+// used to copy the mux middleware and then also get it from the mux when routes were registered, so it ran
+// twice for middleware added before the group; independent root groups never ran it.
+func TestRootGroupMuxMiddleware(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(m *route.Mux, mw route.Middleware, handler http.Handler)
+		want  int
+	}{
+		{
+			name: "mux middleware added before the group",
+			setup: func(m *route.Mux, mw route.Middleware, handler http.Handler) {
+				m.Use(mw)
+				m.Group(func(g *route.Group) {
+					g.Get("/test", handler)
+				})
+			},
+			want: 1,
+		},
+		{
+			name: "mux middleware added after the group",
+			setup: func(m *route.Mux, mw route.Middleware, handler http.Handler) {
+				g := m.Group(nil)
+				m.Use(mw)
+				g.Get("/test", handler)
+			},
+			want: 1,
+		},
+		{
+			name: "inline group",
+			setup: func(m *route.Mux, mw route.Middleware, handler http.Handler) {
+				m.Use(mw)
+				m.With().Get("/test", handler)
+			},
+			want: 1,
+		},
+		{
+			name: "independent root group",
+			setup: func(m *route.Mux, mw route.Middleware, handler http.Handler) {
+				m.Use(mw)
+				m.Group(func(g *route.Group) {
+					g.Independent()
+					g.Get("/test", handler)
+				})
+			},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			mw := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls++
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			m := route.New()
+			tt.setup(m, mw, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, calls)
+		})
+	}
+}
+
 func TestIndependentGroups(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -313,3 +385,203 @@ func TestIndependentGroups(t *testing.T) {
 		})
 	}
 }
+
+func TestGroupConditions(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	setup := func(env string, flags ...string) *route.Mux {
+		mux := route.New()
+		mux.SetEnvironment(env)
+		mux.SetFlags(func(flag string) bool {
+			for _, f := range flags {
+				if f == flag {
+					return true
+				}
+			}
+			return false
+		})
+
+		mux.PrefixGroup("/debug", func(g *route.Group) {
+			g.OnlyIn("development", "test")
+			g.Get("/templates", handler)
+
+			g.PrefixGroup("/beta", func(g *route.Group) {
+				g.IfFlag("beta-api")
+				g.Get("/status", handler)
+			})
+		})
+
+		mux.PrefixGroup("/api", func(g *route.Group) {
+			g.IfFlag("beta-api")
+			g.Get("/v2", handler)
+		})
+
+		mux.Get("/always", handler)
+		return mux
+	}
+
+	patterns := func(mux *route.Mux) []string {
+		var list []string
+		for _, r := range mux.ListRoutes() {
+			list = append(list, r.Pattern)
+		}
+		sort.Strings(list)
+		return list
+	}
+
+	tests := []struct {
+		name     string
+		env      string
+		flags    []string
+		expected []string
+	}{
+		{
+			name:     "production without flags",
+			env:      "production",
+			expected: []string{"/always"},
+		},
+		{
+			name:     "production with flag",
+			env:      "production",
+			flags:    []string{"beta-api"},
+			expected: []string{"/always", "/api/v2"},
+		},
+		{
+			name:     "development without flags",
+			env:      "development",
+			expected: []string{"/always", "/debug/templates"},
+		},
+		{
+			name:     "development with flag",
+			env:      "development",
+			flags:    []string{"beta-api"},
+			expected: []string{"/always", "/api/v2", "/debug/beta/status", "/debug/templates"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := setup(tt.env, tt.flags...)
+			assert.Equal(t, tt.expected, patterns(mux))
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/templates", nil))
+			if tt.env == "development" {
+				assert.Equal(t, http.StatusOK, rec.Code)
+			} else {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	tag := func(name string) route.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Order", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	mux := route.New()
+	mux.Use(tag("mux"))
+	mux.Get("/public", emptyHandler())
+	mux.Get("/route", emptyHandler(), tag("route"))
+	mux.With(tag("with")).Get("/with", emptyHandler())
+
+	mux.PrefixGroup("/posts", func(g *route.Group) {
+		g.Use(tag("group"))
+		g.UseMethod(http.MethodPost, tag("post"))
+		g.Get("", emptyHandler())
+		g.Post("", emptyHandler(), tag("route"))
+		g.With(tag("with")).Delete("/{id}", emptyHandler())
+
+		g.PrefixGroup("/drafts", func(g *route.Group) {
+			g.Post("", emptyHandler())
+		})
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		order  []string
+	}{
+		{http.MethodGet, "/public", []string{"mux"}},
+		{http.MethodGet, "/route", []string{"mux", "route"}},
+		{http.MethodGet, "/with", []string{"mux", "with"}},
+		{http.MethodGet, "/posts", []string{"mux", "group"}},
+		{http.MethodPost, "/posts", []string{"mux", "group", "post", "route"}},
+		{http.MethodDelete, "/posts/1", []string{"mux", "group", "with"}},
+		{http.MethodPost, "/posts/drafts", []string{"mux", "group", "post"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.order, w.Header().Values("X-Order"))
+		})
+	}
+}
+
+func TestHostGroups(t *testing.T) {
+	write := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body))
+		})
+	}
+
+	mux := route.New()
+	mux.Get("/{$}", write("site"))
+
+	admin := mux.Host("Admin.Example.com")
+	admin.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Admin", "true")
+			next.ServeHTTP(w, r)
+		})
+	})
+	admin.Get("/{$}", write("dashboard"))
+	admin.PrefixGroup("/users", func(g *route.Group) {
+		g.Post("", write("user created"))
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		expectCode  int
+		expectBody  string
+		expectAdmin bool
+	}{
+		{name: "host route", method: http.MethodGet, target: "http://admin.example.com/", expectCode: http.StatusOK, expectBody: "dashboard", expectAdmin: true},
+		{name: "host route with port", method: http.MethodGet, target: "http://admin.example.com:8080/", expectCode: http.StatusOK, expectBody: "dashboard", expectAdmin: true},
+		{name: "nested host group", method: http.MethodPost, target: "http://admin.example.com/users", expectCode: http.StatusOK, expectBody: "user created", expectAdmin: true},
+		{name: "other host", method: http.MethodGet, target: "http://www.example.com/", expectCode: http.StatusOK, expectBody: "site"},
+		{name: "host routes are not served on other hosts", method: http.MethodPost, target: "http://www.example.com/users", expectCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			if tt.expectBody != "" {
+				assert.Equal(t, tt.expectBody, w.Body.String())
+			}
+			assert.Equal(t, tt.expectAdmin, w.Header().Get("X-Admin") == "true")
+		})
+	}
+
+	// OPTIONS reports the methods of the host's routes
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "http://admin.example.com/users", nil))
+	assert.Equal(t, "POST", w.Header().Get("Allow"))
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// AccessLogOptions configures the AccessLog middleware
+type AccessLogOptions struct {
+	// Logger receives the access log records. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Level is the level used for successful requests. Defaults to slog.LevelInfo.
+	Level slog.Level
+	// ErrorLevel is the level used for responses with a 5xx status. Defaults to slog.LevelError.
+	ErrorLevel slog.Level
+	// SampleRate is the fraction of requests that are logged, between 0 and 1. Defaults to 1 (log everything).
+	SampleRate float64
+	// AlwaysLogErrors logs responses with a 5xx status even when they are sampled out. Defaults to true.
+	AlwaysLogErrors bool
+	// SkipPaths lists exact request paths that are never logged. Defaults to "/healthz" and "/readyz".
+	SkipPaths []string
+	// Skip, when set, is called for every request; returning true skips logging it
+	Skip func(r *http.Request) bool
+}
+
+// AccessLog returns middleware that writes a structured access log record for each request, including
+// the method, matched route pattern, status, bytes written, duration, remote IP and user agent.
+// Records are logged with the request context, so request IDs are included when the logger uses
+// NewRequestIDLogHandler.
+//
+// Example:
+//
+//	router.Use(middleware.AccessLog(func(opts *middleware.AccessLogOptions) {
+//		opts.Logger = logger
+//		opts.SampleRate = 0.25
+//		opts.SkipPaths = append(opts.SkipPaths, "/metrics")
+//	}))
+func AccessLog(optsFunc func(opts *AccessLogOptions)) func(http.Handler) http.Handler {
+	opts := AccessLogOptions{
+		Level:           slog.LevelInfo,
+		ErrorLevel:      slog.LevelError,
+		SampleRate:      1,
+		AlwaysLogErrors: true,
+		SkipPaths:       []string{"/healthz", "/readyz"},
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	skipPaths := make(map[string]bool, len(opts.SkipPaths))
+	for _, p := range opts.SkipPaths {
+		skipPaths[p] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipPaths[r.URL.Path] || (opts.Skip != nil && opts.Skip(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sampled := opts.SampleRate >= 1 || (opts.SampleRate > 0 && rand.Float64() < opts.SampleRate)
+			if !sampled && !opts.AlwaysLogErrors {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w}
+
+			next.ServeHTTP(rw, r)
+
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := opts.Level
+			if status >= http.StatusInternalServerError {
+				level = opts.ErrorLevel
+			} else if !sampled {
+				return
+			}
+
+			// The pattern is set by http.ServeMux when the request is routed
+			pattern := r.Pattern
+			if pattern == "" {
+				pattern = r.URL.Path
+			}
+
+			opts.Logger.LogAttrs(r.Context(), level, "http request",
+				slog.String("method", r.Method),
+				slog.String("route", pattern),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", rw.written),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote_ip", ClientIP(r)),
+				slog.String("user_agent", r.UserAgent()),
+			)
+		})
+	}
+}
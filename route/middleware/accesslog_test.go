@@ -0,0 +1,112 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/route"
+	"github.com/patrickward/hop/route/middleware"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	mux := route.New(middleware.AccessLog(func(opts *middleware.AccessLogOptions) {
+		opts.Logger = logger
+	}))
+	mux.Get("/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	mux.Get("/healthz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("User-Agent", "test-agent")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	records := decodeLogLines(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "GET", records[0]["method"])
+	assert.Equal(t, "GET /users/{id}", records[0]["route"])
+	assert.Equal(t, "/users/42", records[0]["path"])
+	assert.Equal(t, float64(http.StatusCreated), records[0]["status"])
+	assert.Equal(t, float64(5), records[0]["bytes"])
+	assert.Equal(t, "192.0.2.1", records[0]["remote_ip"])
+	assert.Equal(t, "test-agent", records[0]["user_agent"])
+}
+
+func TestAccessLog_Sampling(t *testing.T) {
+	tests := []struct {
+		name            string
+		alwaysLogErrors bool
+		status          int
+		wantLogged      bool
+	}{
+		{name: "sampled out", alwaysLogErrors: true, status: http.StatusOK, wantLogged: false},
+		{name: "errors always logged", alwaysLogErrors: true, status: http.StatusInternalServerError, wantLogged: true},
+		{name: "errors sampled out", alwaysLogErrors: false, status: http.StatusInternalServerError, wantLogged: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := middleware.AccessLog(func(opts *middleware.AccessLogOptions) {
+				opts.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+				opts.SampleRate = 0
+				opts.AlwaysLogErrors = tt.alwaysLogErrors
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			records := decodeLogLines(t, &buf)
+			if tt.wantLogged {
+				require.Len(t, records, 1)
+				assert.Equal(t, "ERROR", records[0]["level"])
+			} else {
+				assert.Empty(t, records)
+			}
+		})
+	}
+}
+
+func TestAccessLog_Skip(t *testing.T) {
+	var buf bytes.Buffer
+	handler := middleware.AccessLog(func(opts *middleware.AccessLogOptions) {
+		opts.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+		opts.Skip = func(r *http.Request) bool { return strings.HasPrefix(r.URL.Path, "/static/") }
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/static/app.css", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	records := decodeLogLines(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "/page", records[0]["route"])
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidToken is returned by a TokenValidator when a bearer token is unknown, expired or revoked
+var ErrInvalidToken = errors.New("invalid token")
+
+type authPrincipalContextKey struct{}
+
+// AuthPrincipal returns the principal authenticated by BasicAuth or BearerAuth: the username for basic
+// authentication, or the value returned by the TokenValidator for bearer tokens
+func AuthPrincipal(r *http.Request) any {
+	return r.Context().Value(authPrincipalContextKey{})
+}
+
+// BasicAuthOptions configures the BasicAuth middleware
+type BasicAuthOptions struct {
+	// Realm is sent in the WWW-Authenticate header. Default value is "Restricted".
+	Realm string
+	// Credentials maps usernames to passwords. Passwords are compared in constant time.
+	Credentials map[string]string
+	// Validate, when set, is called instead of checking Credentials, e.g. to look users up in a database.
+	// Implementations should compare secrets in constant time.
+	Validate func(r *http.Request, username, password string) bool
+	// UnauthorizedHandler is called when authentication fails, after the WWW-Authenticate header is set.
+	// Default value responds with 401 Unauthorized.
+	UnauthorizedHandler http.Handler
+}
+
+// BasicAuth returns middleware that requires HTTP Basic authentication. The authenticated username is
+// available to handlers with AuthPrincipal.
+//
+// Example:
+//
+//	admin.Use(middleware.BasicAuth(func(opts *middleware.BasicAuthOptions) {
+//		opts.Realm = "Admin"
+//		opts.Credentials = map[string]string{"ops": cfg.AdminPassword}
+//	}))
+func BasicAuth(optsFunc func(opts *BasicAuthOptions)) func(http.Handler) http.Handler {
+	opts := BasicAuthOptions{
+		Realm:               "Restricted",
+		UnauthorizedHandler: unauthorizedHandler(),
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	validate := opts.Validate
+	if validate == nil {
+		// Hash the stored passwords once, so comparisons take the same time whatever their length
+		hashed := make(map[string][32]byte, len(opts.Credentials))
+		for user, pass := range opts.Credentials {
+			hashed[user] = sha256.Sum256([]byte(pass))
+		}
+		validate = func(_ *http.Request, username, password string) bool {
+			expected, ok := hashed[username]
+			given := sha256.Sum256([]byte(password))
+			// Compare even for unknown users, so response times do not reveal which usernames exist
+			match := subtle.ConstantTimeCompare(given[:], expected[:]) == 1
+			return ok && match
+		}
+	}
+
+	challenge := `Basic realm=` + strconv.Quote(opts.Realm) + `, charset="UTF-8"`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok || !validate(r, username, password) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				opts.UnauthorizedHandler.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), authPrincipalContextKey{}, username)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TokenValidator validates bearer tokens
+type TokenValidator interface {
+	// ValidateToken returns the principal the token belongs to, or an error wrapping ErrInvalidToken when
+	// the token is not valid. Other errors are treated as server errors.
+	ValidateToken(ctx context.Context, token string) (any, error)
+}
+
+// TokenValidatorFunc adapts a function to the TokenValidator interface
+type TokenValidatorFunc func(ctx context.Context, token string) (any, error)
+
+// ValidateToken calls f(ctx, token)
+func (f TokenValidatorFunc) ValidateToken(ctx context.Context, token string) (any, error) {
+	return f(ctx, token)
+}
+
+// StaticTokens is a TokenValidator for a fixed set of tokens, mapped to their principals. Tokens are
+// compared in constant time.
+type StaticTokens map[string]any
+
+// ValidateToken returns the principal of the token
+func (s StaticTokens) ValidateToken(_ context.Context, token string) (any, error) {
+	given := sha256.Sum256([]byte(token))
+	var (
+		principal any
+		found     bool
+	)
+	// Every token is compared, so response times do not depend on which token matched
+	for t, p := range s {
+		expected := sha256.Sum256([]byte(t))
+		if subtle.ConstantTimeCompare(given[:], expected[:]) == 1 {
+			principal, found = p, true
+		}
+	}
+	if !found {
+		return nil, ErrInvalidToken
+	}
+	return principal, nil
+}
+
+// BearerAuthOptions configures the BearerAuth middleware
+type BearerAuthOptions struct {
+	// Realm is sent in the WWW-Authenticate header. Default value is "api".
+	Realm string
+	// Validator validates tokens (required)
+	Validator TokenValidator
+	// UnauthorizedHandler is called when the token is missing or invalid, after the WWW-Authenticate
+	// header is set. Default value responds with 401 Unauthorized.
+	UnauthorizedHandler http.Handler
+	// ErrorHandler is called when the validator fails with an error other than ErrInvalidToken.
+	// Default value responds with 500 Internal Server Error.
+	ErrorHandler ErrorHandler
+}
+
+// BearerAuth returns middleware that requires a bearer token in the Authorization header, as described
+// in RFC 6750. The principal returned by the validator is available to handlers with AuthPrincipal.
+//
+// Example:
+//
+//	api.Use(middleware.BearerAuth(func(opts *middleware.BearerAuthOptions) {
+//		opts.Validator = middleware.TokenValidatorFunc(tokens.Lookup)
+//	}))
+func BearerAuth(optsFunc func(opts *BearerAuthOptions)) func(http.Handler) http.Handler {
+	opts := BearerAuthOptions{
+		Realm:               "api",
+		UnauthorizedHandler: unauthorizedHandler(),
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		},
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Validator == nil {
+		panic("middleware: BearerAuth requires a token validator")
+	}
+
+	realm := `Bearer realm=` + strconv.Quote(opts.Realm)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			token = strings.TrimSpace(token)
+			if !strings.EqualFold(scheme, "Bearer") || token == "" {
+				// No credentials were sent, so no error code is included
+				w.Header().Set("WWW-Authenticate", realm)
+				opts.UnauthorizedHandler.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := opts.Validator.ValidateToken(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, ErrInvalidToken) {
+					w.Header().Set("WWW-Authenticate", realm+`, error="invalid_token"`)
+					opts.UnauthorizedHandler.ServeHTTP(w, r)
+					return
+				}
+				opts.ErrorHandler(w, r, err)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), authPrincipalContextKey{}, principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// unauthorizedHandler responds with 401 Unauthorized
+func unauthorizedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
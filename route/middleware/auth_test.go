@@ -0,0 +1,112 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/patrickward/hop/route/middleware"
+)
+
+// principalHandler writes the authenticated principal
+var principalHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if s, ok := middleware.AuthPrincipal(r).(string); ok {
+		_, _ = w.Write([]byte(s))
+	}
+})
+
+func TestBasicAuth(t *testing.T) {
+	static := middleware.BasicAuth(func(opts *middleware.BasicAuthOptions) {
+		opts.Realm = "Admin"
+		opts.Credentials = map[string]string{"ops": "s3cret"}
+	})(principalHandler)
+
+	callback := middleware.BasicAuth(func(opts *middleware.BasicAuthOptions) {
+		opts.Validate = func(r *http.Request, username, password string) bool {
+			return username == "api" && password == "key"
+		}
+	})(principalHandler)
+
+	tests := []struct {
+		name         string
+		handler      http.Handler
+		user, pass   string
+		noAuth       bool
+		expectStatus int
+		expectBody   string
+		expectHeader string
+	}{
+		{name: "valid static credentials", handler: static, user: "ops", pass: "s3cret", expectStatus: http.StatusOK, expectBody: "ops"},
+		{name: "wrong password", handler: static, user: "ops", pass: "nope", expectStatus: http.StatusUnauthorized, expectHeader: `Basic realm="Admin", charset="UTF-8"`},
+		{name: "unknown user", handler: static, user: "eve", pass: "s3cret", expectStatus: http.StatusUnauthorized, expectHeader: `Basic realm="Admin", charset="UTF-8"`},
+		{name: "missing credentials", handler: static, noAuth: true, expectStatus: http.StatusUnauthorized, expectHeader: `Basic realm="Admin", charset="UTF-8"`},
+		{name: "valid callback credentials", handler: callback, user: "api", pass: "key", expectStatus: http.StatusOK, expectBody: "api"},
+		{name: "invalid callback credentials", handler: callback, user: "api", pass: "bad", expectStatus: http.StatusUnauthorized, expectHeader: `Basic realm="Restricted", charset="UTF-8"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if !tt.noAuth {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+			assert.Equal(t, tt.expectHeader, rec.Header().Get("WWW-Authenticate"))
+			if tt.expectBody != "" {
+				assert.Equal(t, tt.expectBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	static := middleware.BearerAuth(func(opts *middleware.BearerAuthOptions) {
+		opts.Validator = middleware.StaticTokens{"token-1": "service-a"}
+	})(principalHandler)
+
+	failing := middleware.BearerAuth(func(opts *middleware.BearerAuthOptions) {
+		opts.Validator = middleware.TokenValidatorFunc(func(ctx context.Context, token string) (any, error) {
+			return nil, errors.New("token store unavailable")
+		})
+	})(principalHandler)
+
+	tests := []struct {
+		name         string
+		handler      http.Handler
+		header       string
+		expectStatus int
+		expectBody   string
+		expectHeader string
+	}{
+		{name: "valid token", handler: static, header: "Bearer token-1", expectStatus: http.StatusOK, expectBody: "service-a"},
+		{name: "scheme is case insensitive", handler: static, header: "bearer token-1", expectStatus: http.StatusOK, expectBody: "service-a"},
+		{name: "missing token", handler: static, expectStatus: http.StatusUnauthorized, expectHeader: `Bearer realm="api"`},
+		{name: "other scheme", handler: static, header: "Basic abc", expectStatus: http.StatusUnauthorized, expectHeader: `Bearer realm="api"`},
+		{name: "invalid token", handler: static, header: "Bearer token-2", expectStatus: http.StatusUnauthorized, expectHeader: `Bearer realm="api", error="invalid_token"`},
+		{name: "validator failure", handler: failing, header: "Bearer token-1", expectStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+			assert.Equal(t, tt.expectHeader, rec.Header().Get("WWW-Authenticate"))
+			if tt.expectBody != "" {
+				assert.Equal(t, tt.expectBody, rec.Body.String())
+			}
+		})
+	}
+}
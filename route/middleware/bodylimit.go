@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/patrickward/hop/render"
+)
+
+// BodyLimitOptions configures the BodyLimit middleware
+type BodyLimitOptions struct {
+	// MaxBytes is the largest request body accepted, in bytes. Defaults to 10 MB.
+	MaxBytes int64
+	// Routes overrides MaxBytes for individual routes, keyed by the matched route pattern, e.g.
+	// "POST /uploads". A value of 0 or less disables the limit for the route.
+	Routes map[string]int64
+	// Templates, when set, is used to render the 413 system error template for HTML requests
+	Templates *render.TemplateManager
+}
+
+// BodyLimit returns middleware that limits the size of request bodies. Requests that declare a
+// Content-Length over the limit are rejected with 413 Request Entity Too Large before the handler runs,
+// as JSON when the client prefers it, otherwise as the 413 template or plain text. Bodies without a
+// Content-Length are wrapped with http.MaxBytesReader, so reads past the limit fail with a
+// *http.MaxBytesError that handlers can turn into a 413 with BodyTooLarge.
+//
+// Example:
+//
+//	router.Use(middleware.BodyLimit(func(opts *middleware.BodyLimitOptions) {
+//		opts.MaxBytes = 1 << 20
+//		opts.Routes = map[string]int64{"POST /uploads": 100 << 20}
+//		opts.Templates = tm
+//	}))
+func BodyLimit(optsFunc func(opts *BodyLimitOptions)) func(http.Handler) http.Handler {
+	opts := BodyLimitOptions{
+		MaxBytes: 10 << 20,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := opts.MaxBytes
+			if override, ok := opts.Routes[r.Pattern]; ok {
+				limit = override
+			}
+
+			if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > limit {
+				bodyTooLarge(w, r, limit, opts.Templates)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BodyTooLarge writes a 413 Request Entity Too Large response, as JSON when the client prefers it,
+// otherwise as plain text. Handlers can call it when reading the body fails with a *http.MaxBytesError.
+//
+// Example:
+//
+//	if err := r.ParseForm(); err != nil {
+//		var tooLarge *http.MaxBytesError
+//		if errors.As(err, &tooLarge) {
+//			middleware.BodyTooLarge(w, r, tooLarge.Limit)
+//			return
+//		}
+//	}
+func BodyTooLarge(w http.ResponseWriter, r *http.Request, limit int64) {
+	bodyTooLarge(w, r, limit, nil)
+}
+
+// bodyTooLarge writes the 413 response, rendering the template when tm is set
+func bodyTooLarge(w http.ResponseWriter, r *http.Request, limit int64, tm *render.TemplateManager) {
+	// The rest of the body is not read, so the connection cannot be reused
+	w.Header().Set("Connection", "close")
+
+	switch {
+	case wantsJSON(r):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error":     "request body too large",
+			"max_bytes": limit,
+		})
+	case tm != nil:
+		tm.NewResponse().RenderRequestTooLarge(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+	}
+}
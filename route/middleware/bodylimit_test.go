@@ -0,0 +1,119 @@
+package middleware_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render"
+	"github.com/patrickward/hop/route/middleware"
+)
+
+func TestBodyLimit(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				middleware.BodyTooLarge(w, r, tooLarge.Limit)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(body)
+	})
+
+	tm, err := render.NewTemplateManager(render.Sources{
+		"": fstest.MapFS{
+			"layouts/base.html":     {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+			"views/system/413.html": {Data: []byte(`{{define "page:main"}}<h1>Too large</h1>{{end}}`)},
+		},
+	}, render.TemplateManagerOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	limited := middleware.BodyLimit(func(opts *middleware.BodyLimitOptions) {
+		opts.MaxBytes = 10
+		opts.Routes = map[string]int64{"POST /uploads": 100}
+		opts.Templates = tm
+	})
+	mux.Handle("POST /messages", limited(echo))
+	mux.Handle("POST /uploads", limited(echo))
+
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		accept       string
+		chunked      bool
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			name:         "within limit",
+			path:         "/messages",
+			body:         "hello",
+			expectStatus: http.StatusOK,
+			expectBody:   "hello",
+		},
+		{
+			name:         "over limit renders the template",
+			path:         "/messages",
+			body:         strings.Repeat("a", 11),
+			accept:       "text/html",
+			expectStatus: http.StatusRequestEntityTooLarge,
+			expectBody:   "<h1>Too large</h1>",
+		},
+		{
+			name:         "over limit as json",
+			path:         "/messages",
+			body:         strings.Repeat("a", 11),
+			accept:       "application/json",
+			expectStatus: http.StatusRequestEntityTooLarge,
+			expectBody:   `{"error":"request body too large","max_bytes":10}`,
+		},
+		{
+			name:         "route override",
+			path:         "/uploads",
+			body:         strings.Repeat("a", 50),
+			expectStatus: http.StatusOK,
+			expectBody:   strings.Repeat("a", 50),
+		},
+		{
+			name:         "chunked body over limit",
+			path:         "/messages",
+			body:         strings.Repeat("a", 20),
+			accept:       "application/json",
+			chunked:      true,
+			expectStatus: http.StatusRequestEntityTooLarge,
+			expectBody:   `{"error":"request body too large","max_bytes":10}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+			assert.Equal(t, tt.expectBody, strings.TrimSpace(rec.Body.String()))
+		})
+	}
+}
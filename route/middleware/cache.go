@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachedResponse is a response stored by ResponseCache
+type CachedResponse struct {
+	Status   int
+	Header   http.Header
+	Body     []byte
+	StoredAt time.Time
+}
+
+// CacheStore stores cached responses. MemoryCacheStore keeps them in the process; SQLCacheStore keeps
+// them in a SQLite or Postgres database, so they survive restarts and are shared by replicas.
+type CacheStore interface {
+	// Get returns the response stored under key, or false if there is none or it has expired
+	Get(ctx context.Context, key string) (*CachedResponse, bool, error)
+	// Set stores the response under key until ttl has elapsed
+	Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error
+	// DeletePrefix removes every response whose key starts with prefix. An empty prefix removes all.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// ResponseCacheOptions configures a ResponseCache
+type ResponseCacheOptions struct {
+	// Store holds the cached responses. Defaults to a MemoryCacheStore of 1000 entries.
+	Store CacheStore
+	// TTL is how long responses are cached. Defaults to 1 minute.
+	TTL time.Duration
+	// Routes overrides TTL for individual routes, keyed by the matched route pattern, e.g. "GET /posts/{id}".
+	// A value of 0 or less disables caching for the route.
+	Routes map[string]time.Duration
+	// VaryHeaders are request headers that are part of the cache key, so requests that differ in them are
+	// cached separately. Defaults to Accept, Accept-Encoding, Accept-Language, HX-Request and HX-Target,
+	// so htmx fragments and full pages are not mixed up. Add "Cookie" to cache pages of requests with
+	// cookies, per set of cookies.
+	VaryHeaders []string
+	// MaxBodySize is the largest response body that is cached, in bytes. Defaults to 1 MB.
+	MaxBodySize int64
+	// Skip, when set, bypasses the cache for requests for which it returns true
+	Skip func(r *http.Request) bool
+	// Logger logs cache store failures. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// ResponseCache caches complete responses to GET and HEAD requests on the server. Only 200 responses
+// without Set-Cookie and without Cache-Control "private" or "no-store" are cached. Requests with an
+// Authorization header are never served from the cache, and neither are requests with cookies unless
+// Cookie is one of the VaryHeaders, so pages of a logged-in user are never served to someone else.
+//
+// Only the headers set by the handlers inside the cache are stored, not those set by outer middleware such
+// as a request ID, and a cached response never replaces a header already set on the response. Responses
+// served from the cache carry an "X-Cache: HIT" header and an Age header; others carry "X-Cache: MISS".
+type ResponseCache struct {
+	opts ResponseCacheOptions
+	vary []string
+}
+
+// NewResponseCache creates a ResponseCache
+//
+// Example:
+//
+//	cache := middleware.NewResponseCache(func(opts *middleware.ResponseCacheOptions) {
+//		opts.TTL = 5 * time.Minute
+//		opts.Store = middleware.NewSQLCacheStore(db)
+//	})
+//	router.Get("/posts/{id}", showPost, cache.Middleware)
+//
+//	// after updating a post
+//	_ = cache.Invalidate(ctx, "/posts/42")
+func NewResponseCache(optsFunc func(opts *ResponseCacheOptions)) *ResponseCache {
+	opts := ResponseCacheOptions{
+		TTL:         time.Minute,
+		VaryHeaders: []string{"Accept", "Accept-Encoding", "Accept-Language", "HX-Request", "HX-Target"},
+		MaxBodySize: 1 << 20,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Store == nil {
+		opts.Store = NewMemoryCacheStore(1000)
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	vary := make([]string, len(opts.VaryHeaders))
+	for i, h := range opts.VaryHeaders {
+		vary[i] = http.CanonicalHeaderKey(h)
+	}
+
+	return &ResponseCache{opts: opts, vary: vary}
+}
+
+// Middleware serves cached responses and caches new ones
+func (c *ResponseCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ttl := c.opts.TTL
+		if override, ok := c.opts.Routes[r.Pattern]; ok {
+			ttl = override
+		}
+
+		if ttl <= 0 || !c.cacheable(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		key := c.key(r)
+
+		cached, ok, err := c.opts.Store.Get(ctx, key)
+		if err != nil {
+			c.opts.Logger.ErrorContext(ctx, "reading response cache", slog.String("error", err.Error()))
+		}
+		if ok {
+			c.serve(w, r, cached)
+			return
+		}
+
+		rec := &cacheRecorder{ResponseWriter: w, status: http.StatusOK, limit: c.opts.MaxBodySize}
+		w.Header().Set("X-Cache", "MISS")
+		outer := w.Header().Clone()
+		next.ServeHTTP(rec, r)
+
+		// HEAD responses have no body to cache
+		if r.Method != http.MethodGet || rec.overflow || !storable(rec.status, w.Header()) {
+			return
+		}
+
+		header := handlerHeader(outer, w.Header())
+		err = c.opts.Store.Set(context.WithoutCancel(ctx), key, &CachedResponse{
+			Status:   rec.status,
+			Header:   header,
+			Body:     rec.body.Bytes(),
+			StoredAt: time.Now(),
+		}, ttl)
+		if err != nil {
+			c.opts.Logger.ErrorContext(ctx, "writing response cache", slog.String("error", err.Error()))
+		}
+	})
+}
+
+// Invalidate removes the cached responses for a path, for every query string and vary header value
+func (c *ResponseCache) Invalidate(ctx context.Context, path string) error {
+	return c.opts.Store.DeletePrefix(ctx, path+"?")
+}
+
+// InvalidatePrefix removes the cached responses for every path starting with prefix, e.g. "/posts/"
+func (c *ResponseCache) InvalidatePrefix(ctx context.Context, prefix string) error {
+	return c.opts.Store.DeletePrefix(ctx, prefix)
+}
+
+// Purge removes every cached response
+func (c *ResponseCache) Purge(ctx context.Context) error {
+	return c.opts.Store.DeletePrefix(ctx, "")
+}
+
+// cacheable reports whether the request may be served from the cache
+func (c *ResponseCache) cacheable(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.Header.Get("Authorization") != "" {
+		return false
+	}
+	if r.Header.Get("Cookie") != "" && !slices.Contains(c.vary, "Cookie") {
+		return false
+	}
+	return c.opts.Skip == nil || !c.opts.Skip(r)
+}
+
+// key builds the cache key from the path, query string and vary headers. The path comes first so that
+// Invalidate can remove a path by prefix.
+func (c *ResponseCache) key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	b.WriteByte('?')
+	b.WriteString(r.URL.Query().Encode())
+	for _, h := range c.vary {
+		b.WriteByte('\n')
+		b.WriteString(h)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+	return b.String()
+}
+
+// serve writes a cached response. Headers already set on the response, e.g. by outer middleware, are kept.
+func (c *ResponseCache) serve(w http.ResponseWriter, r *http.Request, cached *CachedResponse) {
+	header := w.Header()
+	for k, v := range cached.Header {
+		if _, ok := header[k]; !ok {
+			header[k] = slices.Clone(v)
+		}
+	}
+	header.Set("X-Cache", "HIT")
+	header.Set("Age", strconv.Itoa(int(time.Since(cached.StoredAt).Seconds())))
+	w.WriteHeader(cached.Status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(cached.Body)
+	}
+}
+
+// handlerHeader returns a copy of the headers of after that were added or changed since before, i.e. those
+// set by the handler
+func handlerHeader(before, after http.Header) http.Header {
+	header := make(http.Header)
+	for k, v := range after {
+		if !slices.Equal(before[k], v) {
+			header[k] = slices.Clone(v)
+		}
+	}
+	return header
+}
+
+// storable reports whether a response may be cached
+func storable(status int, header http.Header) bool {
+	if status != http.StatusOK || header.Get("Set-Cookie") != "" || header.Get("Vary") == "*" {
+		return false
+	}
+	cacheControl := strings.ToLower(header.Get("Cache-Control"))
+	return !strings.Contains(cacheControl, "no-store") && !strings.Contains(cacheControl, "private")
+}
+
+// cacheRecorder copies the response body while it is written, up to a limit
+type cacheRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+func (rec *cacheRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *cacheRecorder) Write(b []byte) (int, error) {
+	if !rec.overflow {
+		if int64(rec.body.Len()+len(b)) > rec.limit {
+			rec.overflow = true
+			rec.body = bytes.Buffer{}
+		} else {
+			rec.body.Write(b)
+		}
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so http.ResponseController can reach it
+func (rec *cacheRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryCacheStore is an in-memory CacheStore that evicts the least recently used response once it
+// holds its maximum number of entries
+type MemoryCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // front is most recently used
+	entries    map[string]*list.Element
+	now        func() time.Time
+}
+
+type memoryCacheEntry struct {
+	key     string
+	resp    *CachedResponse
+	expires time.Time
+}
+
+// NewMemoryCacheStore creates a MemoryCacheStore holding at most maxEntries responses. A maxEntries of 0
+// or less means no limit.
+func NewMemoryCacheStore(maxEntries int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+// Get returns the response stored under key
+func (s *MemoryCacheStore) Get(_ context.Context, key string) (*CachedResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*memoryCacheEntry)
+	if !s.now().Before(entry.expires) {
+		s.remove(el)
+		return nil, false, nil
+	}
+	s.order.MoveToFront(el)
+	return entry.resp, true, nil
+}
+
+// Set stores the response under key
+func (s *MemoryCacheStore) Set(_ context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &memoryCacheEntry{key: key, resp: resp, expires: s.now().Add(ttl)}
+	if el, ok := s.entries[key]; ok {
+		el.Value = entry
+		s.order.MoveToFront(el)
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(entry)
+	for s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// DeletePrefix removes every response whose key starts with prefix
+func (s *MemoryCacheStore) DeletePrefix(_ context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, el := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			s.remove(el)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of stored responses, including expired ones not yet evicted
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// remove deletes an entry. The caller must hold the lock.
+func (s *MemoryCacheStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.entries, el.Value.(*memoryCacheEntry).key)
+}
+
+// SQLCacheStore is a CacheStore backed by a SQLite or Postgres database, so cached responses survive
+// restarts and are shared by replicas using the same database. Call Migrate to create the
+// response_cache table, and DeleteExpired periodically to remove expired responses.
+type SQLCacheStore struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewSQLCacheStore creates a new SQLCacheStore
+func NewSQLCacheStore(db *sql.DB) *SQLCacheStore {
+	return &SQLCacheStore{db: db, now: time.Now}
+}
+
+// Migrate creates the response_cache table if it does not exist
+func (s *SQLCacheStore) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS response_cache (
+		key TEXT PRIMARY KEY,
+		status INTEGER NOT NULL,
+		header TEXT NOT NULL,
+		body BLOB NOT NULL,
+		stored_at BIGINT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating response_cache table: %w", err)
+	}
+	return nil
+}
+
+// Get returns the response stored under key
+func (s *SQLCacheStore) Get(ctx context.Context, key string) (*CachedResponse, bool, error) {
+	var (
+		resp     CachedResponse
+		header   string
+		storedAt int64
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT status, header, body, stored_at FROM response_cache
+		WHERE key = $1 AND expires_at > $2`, key, s.now().UnixNano()).Scan(&resp.Status, &header, &resp.Body, &storedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp.Header = http.Header{}
+	if err := json.Unmarshal([]byte(header), &resp.Header); err != nil {
+		return nil, false, fmt.Errorf("decoding cached header: %w", err)
+	}
+	resp.StoredAt = time.Unix(0, storedAt)
+	return &resp, true, nil
+}
+
+// Set stores the response under key
+func (s *SQLCacheStore) Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return fmt.Errorf("encoding cached header: %w", err)
+	}
+
+	body := resp.Body
+	if body == nil {
+		body = []byte{}
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO response_cache (key, status, header, body, stored_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (key) DO UPDATE SET
+			status = excluded.status,
+			header = excluded.header,
+			body = excluded.body,
+			stored_at = excluded.stored_at,
+			expires_at = excluded.expires_at`,
+		key, resp.Status, string(header), body, resp.StoredAt.UnixNano(), s.now().Add(ttl).UnixNano())
+	return err
+}
+
+// DeletePrefix removes every response whose key starts with prefix
+func (s *SQLCacheStore) DeletePrefix(ctx context.Context, prefix string) error {
+	// substr avoids escaping LIKE wildcards in the prefix
+	_, err := s.db.ExecContext(ctx, "DELETE FROM response_cache WHERE substr(key, 1, length($1)) = $1", prefix)
+	return err
+}
+
+// DeleteExpired removes expired responses
+func (s *SQLCacheStore) DeleteExpired(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM response_cache WHERE expires_at <= $1", s.now().UnixNano())
+	return err
+}
@@ -0,0 +1,236 @@
+package middleware_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/route/middleware"
+)
+
+func TestResponseCache(t *testing.T) {
+	calls := 0
+	cache := middleware.NewResponseCache(nil)
+	handler := cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private")
+		case "/cookie":
+			http.SetCookie(w, &http.Cookie{Name: "a", Value: "b"})
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = fmt.Fprintf(w, "%s %d", r.URL.Path, calls)
+	}))
+
+	get := func(method, target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(http.MethodGet, "/posts?page=1", nil)
+	assert.Equal(t, "MISS", rec.Header().Get("X-Cache"))
+	assert.Equal(t, "/posts 1", rec.Body.String())
+
+	rec = get(http.MethodGet, "/posts?page=1", nil)
+	assert.Equal(t, "HIT", rec.Header().Get("X-Cache"))
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "/posts 1", rec.Body.String())
+	assert.Equal(t, 1, calls)
+
+	rec = get(http.MethodHead, "/posts?page=1", nil)
+	assert.Equal(t, "HIT", rec.Header().Get("X-Cache"))
+	assert.Empty(t, rec.Body.String())
+
+	// Query strings and vary headers are part of the key
+	assert.Equal(t, "/posts 2", get(http.MethodGet, "/posts?page=2", nil).Body.String())
+	assert.Equal(t, "/posts 3", get(http.MethodGet, "/posts?page=1", map[string]string{"HX-Request": "true"}).Body.String())
+
+	// Requests with credentials, unsafe methods and uncacheable responses bypass the cache
+	assert.Equal(t, "/posts 4", get(http.MethodGet, "/posts?page=1", map[string]string{"Authorization": "Bearer x"}).Body.String())
+	assert.Equal(t, "/posts 5", get(http.MethodPost, "/posts?page=1", nil).Body.String())
+	for _, path := range []string{"/private", "/cookie", "/missing"} {
+		get(http.MethodGet, path, nil)
+		assert.Equal(t, "MISS", get(http.MethodGet, path, nil).Header().Get("X-Cache"), path)
+	}
+
+	// Invalidating a path removes all of its variants
+	require.NoError(t, cache.Invalidate(context.Background(), "/posts"))
+	assert.Equal(t, "MISS", get(http.MethodGet, "/posts?page=1", nil).Header().Get("X-Cache"))
+	assert.Equal(t, "MISS", get(http.MethodGet, "/posts?page=2", nil).Header().Get("X-Cache"))
+}
+
+func TestResponseCache_Headers(t *testing.T) {
+	cache := middleware.NewResponseCache(nil)
+	ids := 0
+	handler := middleware.RequestID(nil)(cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids++
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Add("Link", "</a.css>; rel=preload")
+		_, _ = w.Write([]byte("page"))
+	})))
+	// outer sets a header of its own on every response, after the cache has run
+	outer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		handler.ServeHTTP(w, r)
+	})
+
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		outer.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/page", nil))
+		return rec
+	}
+
+	miss := get()
+	require.Equal(t, "MISS", miss.Header().Get("X-Cache"))
+
+	hit := get()
+	require.Equal(t, "HIT", hit.Header().Get("X-Cache"))
+	assert.Equal(t, 1, ids)
+	assert.NotEmpty(t, hit.Header().Get("X-Request-ID"))
+	assert.NotEqual(t, miss.Header().Get("X-Request-ID"), hit.Header().Get("X-Request-ID"),
+		"headers of outer middleware should not be cached")
+	assert.Equal(t, "text/html", hit.Header().Get("Content-Type"), "headers already set should be kept")
+
+	// Changing the headers of a served response doesn't change the cached response
+	hit.Header()["Link"][0] = "changed"
+	assert.Equal(t, "</a.css>; rel=preload", get().Header().Get("Link"))
+}
+
+func TestResponseCache_Cookies(t *testing.T) {
+	newHandler := func(cache *middleware.ResponseCache) (http.Handler, *int) {
+		calls := 0
+		return cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			_, _ = fmt.Fprintf(w, "%d", calls)
+		})), &calls
+	}
+	get := func(h http.Handler, cookie string) string {
+		req := httptest.NewRequest(http.MethodGet, "/account", nil)
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Header().Get("X-Cache")
+	}
+
+	// By default, requests with cookies bypass the cache
+	h, calls := newHandler(middleware.NewResponseCache(nil))
+	get(h, "session=alice")
+	assert.Empty(t, get(h, "session=alice"))
+	assert.Empty(t, get(h, "session=bob"))
+	assert.Equal(t, 3, *calls)
+
+	// With Cookie as a vary header, each set of cookies is cached separately
+	h, calls = newHandler(middleware.NewResponseCache(func(opts *middleware.ResponseCacheOptions) {
+		opts.VaryHeaders = append(opts.VaryHeaders, "Cookie")
+	}))
+	get(h, "session=alice")
+	assert.Equal(t, "HIT", get(h, "session=alice"))
+	assert.Equal(t, "MISS", get(h, "session=bob"))
+	assert.Equal(t, 2, *calls)
+}
+
+func TestResponseCache_Routes(t *testing.T) {
+	cache := middleware.NewResponseCache(func(opts *middleware.ResponseCacheOptions) {
+		opts.Routes = map[string]time.Duration{"GET /live": 0}
+	})
+
+	calls := 0
+	mux := http.NewServeMux()
+	mux.Handle("GET /live", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+	mux.Handle("GET /static", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+
+	// Route patterns are only known once the mux has matched the request
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		r.Pattern = pattern
+		cache.Middleware(h).ServeHTTP(w, r)
+	})
+
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/live", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/static", nil))
+	}
+	assert.Equal(t, 3, calls)
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	ctx := context.Background()
+	store := middleware.NewMemoryCacheStore(2)
+
+	require.NoError(t, store.Set(ctx, "/a?", &middleware.CachedResponse{Status: 200, Body: []byte("a")}, time.Hour))
+	require.NoError(t, store.Set(ctx, "/b?", &middleware.CachedResponse{Status: 200, Body: []byte("b")}, time.Hour))
+
+	// Reading /a makes /b the least recently used entry, so it is evicted
+	_, ok, _ := store.Get(ctx, "/a?")
+	assert.True(t, ok)
+	require.NoError(t, store.Set(ctx, "/c?", &middleware.CachedResponse{Status: 200, Body: []byte("c")}, time.Hour))
+	assert.Equal(t, 2, store.Len())
+	_, ok, _ = store.Get(ctx, "/b?")
+	assert.False(t, ok)
+
+	require.NoError(t, store.Set(ctx, "/expired?", &middleware.CachedResponse{Status: 200}, -time.Second))
+	_, ok, _ = store.Get(ctx, "/expired?")
+	assert.False(t, ok)
+
+	require.NoError(t, store.DeletePrefix(ctx, ""))
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestSQLCacheStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	ctx := context.Background()
+	store := middleware.NewSQLCacheStore(db)
+	require.NoError(t, store.Migrate(ctx))
+
+	stored := time.Now().Truncate(time.Second)
+	resp := &middleware.CachedResponse{
+		Status:   http.StatusOK,
+		Header:   http.Header{"Content-Type": {"text/html"}},
+		Body:     []byte("<p>hi</p>"),
+		StoredAt: stored,
+	}
+	require.NoError(t, store.Set(ctx, "/posts/1?", resp, time.Hour))
+	require.NoError(t, store.Set(ctx, "/posts/2?", resp, time.Hour))
+	require.NoError(t, store.Set(ctx, "/pages_1?", resp, time.Hour))
+
+	got, ok, err := store.Get(ctx, "/posts/1?")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, resp.Header, got.Header)
+	assert.Equal(t, resp.Body, got.Body)
+	assert.True(t, stored.Equal(got.StoredAt))
+
+	// Prefixes are matched literally, without LIKE wildcards
+	require.NoError(t, store.DeletePrefix(ctx, "/posts/"))
+	_, ok, _ = store.Get(ctx, "/posts/2?")
+	assert.False(t, ok)
+	require.NoError(t, store.DeletePrefix(ctx, "/pages%"))
+	_, ok, _ = store.Get(ctx, "/pages_1?")
+	assert.True(t, ok)
+
+	require.NoError(t, store.Set(ctx, "/old?", resp, -time.Second))
+	_, ok, _ = store.Get(ctx, "/old?")
+	assert.False(t, ok)
+	require.NoError(t, store.DeleteExpired(ctx))
+}
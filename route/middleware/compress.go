@@ -0,0 +1,328 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder creates compressing writers for a content encoding
+type Encoder struct {
+	// Encoding is the Content-Encoding token, e.g. "gzip" or "br"
+	Encoding string
+	// NewWriter returns a writer that compresses to w
+	NewWriter func(w io.Writer) io.WriteCloser
+}
+
+// GzipEncoder returns a gzip Encoder using the given compression level. Writers are pooled.
+func GzipEncoder(level int) Encoder {
+	pool := sync.Pool{
+		New: func() any {
+			gz, err := gzip.NewWriterLevel(io.Discard, level)
+			if err != nil {
+				gz = gzip.NewWriter(io.Discard)
+			}
+			return gz
+		},
+	}
+
+	return Encoder{
+		Encoding: "gzip",
+		NewWriter: func(w io.Writer) io.WriteCloser {
+			gz := pool.Get().(*gzip.Writer)
+			gz.Reset(w)
+			return &pooledGzipWriter{Writer: gz, pool: &pool}
+		},
+	}
+}
+
+// pooledGzipWriter returns its gzip.Writer to the pool when closed
+type pooledGzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+// Close finishes the gzip stream and returns the writer to the pool
+func (w *pooledGzipWriter) Close() error {
+	err := w.Writer.Close()
+	w.pool.Put(w.Writer)
+	return err
+}
+
+// CompressOptions configures the Compress middleware
+type CompressOptions struct {
+	// Level is the gzip compression level. Defaults to gzip.DefaultCompression.
+	Level int
+	// MinSize is the smallest response body, in bytes, that is compressed. Defaults to 1024.
+	MinSize int
+	// ContentTypes lists the media types that are compressed. Entries ending in "/*" match a whole type,
+	// e.g. "text/*". Defaults to common text, JSON, JavaScript, XML and SVG types.
+	ContentTypes []string
+	// Encoders are the supported encodings in order of preference. Defaults to gzip at Level. Other
+	// encodings, such as Brotli, can be added by providing an Encoder from a third-party package.
+	Encoders []Encoder
+}
+
+// Compress returns middleware that compresses response bodies with gzip, or another configured encoding,
+// when the client accepts it. Responses are only compressed when their content type is allowed and the
+// body reaches MinSize; smaller bodies are sent unchanged with their original Content-Length. Responses
+// to HEAD requests, responses that already have a Content-Encoding, and partial content responses are
+// never compressed. Flushing a response, as streaming handlers do, compresses and flushes what has been
+// written so far.
+//
+// Example:
+//
+//	router.Use(middleware.Compress(func(opts *middleware.CompressOptions) {
+//		opts.MinSize = 512
+//	}))
+func Compress(optsFunc func(opts *CompressOptions)) func(http.Handler) http.Handler {
+	opts := CompressOptions{
+		Level:   gzip.DefaultCompression,
+		MinSize: 1024,
+		ContentTypes: []string{
+			"text/*",
+			"application/json",
+			"application/problem+json",
+			"application/javascript",
+			"application/xml",
+			"application/manifest+json",
+			"image/svg+xml",
+		},
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if len(opts.Encoders) == 0 {
+		opts.Encoders = []Encoder{GzipEncoder(opts.Level)}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoder, ok := negotiateEncoding(r.Header.Get("Accept-Encoding"), opts.Encoders)
+			if !ok || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoder:        encoder,
+				opts:           &opts,
+			}
+			defer func() { _ = cw.finish() }()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter buffers the start of a response until it can decide whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	encoder Encoder
+	opts    *CompressOptions
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	compressing bool
+	buf         []byte
+	enc         io.WriteCloser
+}
+
+// WriteHeader records the status; the header is sent once compression has been decided
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	// Informational responses are sent immediately and do not affect the final response
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	cw.wroteHeader = true
+}
+
+// Write buffers data until MinSize is reached, then compresses or passes it through
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.decided {
+		if cw.compressing {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.opts.MinSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush decides on compression with the data written so far and flushes it to the client
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			cw.WriteHeader(http.StatusOK)
+		}
+		_ = cw.decide(true)
+	}
+
+	if f, ok := cw.enc.(interface{ Flush() error }); ok && cw.compressing {
+		_ = f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack allows protocols such as WebSockets to take over the connection
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		cw.decided = true
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("compress: underlying ResponseWriter does not support hijacking")
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so http.ResponseController can reach it
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide sends the header, compressed or not, followed by any buffered data. When large is false the
+// body is known to be smaller than MinSize, so it is never compressed.
+func (cw *compressWriter) decide(large bool) error {
+	cw.decided = true
+	cw.compressing = large && cw.shouldCompress()
+
+	h := cw.Header()
+	if cw.compressing {
+		h.Set("Content-Encoding", cw.encoder.Encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The compressed representation is not byte-for-byte identical
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.ResponseWriter.WriteHeader(cw.status)
+		cw.enc = cw.encoder.NewWriter(cw.ResponseWriter)
+		_, err := cw.enc.Write(cw.buf)
+		cw.buf = nil
+		return err
+	}
+
+	if !large && h.Get("Content-Length") == "" && bodyAllowed(cw.status) {
+		h.Set("Content-Length", strconv.Itoa(len(cw.buf)))
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	_, err := cw.ResponseWriter.Write(cw.buf)
+	cw.buf = nil
+	return err
+}
+
+// finish completes the response after the handler returns
+func (cw *compressWriter) finish() error {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// The handler wrote nothing; let the server send its default response
+			return nil
+		}
+		return cw.decide(false)
+	}
+	if cw.compressing {
+		return cw.enc.Close()
+	}
+	return nil
+}
+
+// shouldCompress reports whether the response can be compressed
+func (cw *compressWriter) shouldCompress() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" || !bodyAllowed(cw.status) {
+		return false
+	}
+
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buf)
+		h.Set("Content-Type", contentType)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range cw.opts.ContentTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyAllowed reports whether a response with the status may include a body
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified && status >= 200
+}
+
+// negotiateEncoding returns the first encoder, in preference order, that the Accept-Encoding header allows
+func negotiateEncoding(header string, encoders []Encoder) (Encoder, bool) {
+	if header == "" {
+		return Encoder{}, false
+	}
+
+	accepted := make(map[string]bool)
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+
+		if name == "*" {
+			wildcard = q > 0
+			continue
+		}
+		accepted[name] = q > 0
+	}
+
+	for _, e := range encoders {
+		allowed, listed := accepted[e.Encoding]
+		if allowed || (!listed && wildcard) {
+			return e, true
+		}
+	}
+	return Encoder{}, false
+}
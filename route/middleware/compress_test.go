@@ -0,0 +1,173 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/route/middleware"
+)
+
+func gunzip(t *testing.T, body io.Reader) string {
+	t.Helper()
+	gz, err := gzip.NewReader(body)
+	require.NoError(t, err)
+	b, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("hello world ", 200)
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		contentType    string
+		contentLength  bool
+		body           string
+		wantCompressed bool
+	}{
+		{name: "compresses html", acceptEncoding: "gzip, deflate", contentType: "text/html; charset=utf-8", body: large, wantCompressed: true},
+		{name: "compresses json", acceptEncoding: "gzip", contentType: "application/json", body: large, wantCompressed: true},
+		{name: "sniffs content type", acceptEncoding: "gzip", body: large, wantCompressed: true},
+		{name: "no accept encoding", contentType: "text/html", body: large},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0, *", contentType: "text/html", body: large},
+		{name: "wildcard accepted", acceptEncoding: "*", contentType: "text/html", body: large, wantCompressed: true},
+		{name: "below minimum size", acceptEncoding: "gzip", contentType: "text/html", body: "small"},
+		{name: "content type not allowed", acceptEncoding: "gzip", contentType: "image/png", body: large},
+		{name: "keeps content length when not compressed", acceptEncoding: "gzip", contentType: "image/png", contentLength: true, body: large},
+		{name: "head request", method: http.MethodHead, acceptEncoding: "gzip", contentType: "text/html", body: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.Compress(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.contentLength {
+					w.Header().Set("Content-Length", strconv.Itoa(len(tt.body)))
+				}
+				// Write in chunks to exercise buffering
+				for i := 0; i < len(tt.body); i += 100 {
+					_, _ = w.Write([]byte(tt.body[i:min(i+100, len(tt.body))]))
+				}
+			}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Contains(t, rec.Header().Values("Vary"), "Accept-Encoding")
+			if tt.wantCompressed {
+				assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+				assert.Empty(t, rec.Header().Get("Content-Length"))
+				assert.Equal(t, tt.body, gunzip(t, rec.Body))
+				return
+			}
+
+			assert.Empty(t, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, tt.body, rec.Body.String())
+			if method == http.MethodGet && (tt.contentLength || len(tt.body) < 1024) {
+				assert.Equal(t, strconv.Itoa(len(tt.body)), rec.Header().Get("Content-Length"))
+			}
+		})
+	}
+}
+
+func TestCompress_Status(t *testing.T) {
+	handler := middleware.Compress(func(opts *middleware.CompressOptions) {
+		opts.MinSize = 1
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `W/"v1"`, rec.Header().Get("ETag"))
+	assert.Equal(t, "created", gunzip(t, rec.Body))
+}
+
+func TestCompress_SkipsEncodedAndEmptyResponses(t *testing.T) {
+	handler := middleware.Compress(func(opts *middleware.CompressOptions) {
+		opts.MinSize = 1
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/encoded":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = w.Write([]byte("already compressed"))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	for _, path := range []string{"/encoded", "/empty"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.NotEqual(t, "gzip", rec.Header().Get("Content-Encoding"), path)
+	}
+}
+
+func TestCompress_Flush(t *testing.T) {
+	flushed := make(chan struct{})
+	resume := make(chan struct{})
+
+	srv := httptest.NewServer(middleware.Compress(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		close(flushed)
+		<-resume
+		_, _ = w.Write([]byte("data: second\n\n"))
+	})))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	<-flushed
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+	gz, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	first := make([]byte, len("data: first\n\n"))
+	_, err = io.ReadFull(gz, first)
+	require.NoError(t, err)
+	assert.Equal(t, "data: first\n\n", string(first))
+
+	close(resume)
+	rest, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "data: second\n\n", string(rest))
+}
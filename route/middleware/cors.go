@@ -11,9 +11,14 @@ import (
 type CORSOptions struct {
 	// AllowOrigins is a list of origins a cross-domain request can be executed from.
 	// If the special "*" value is present in the list, all origins will be allowed.
+	// An origin may contain a single "*" wildcard to match subdomains, e.g. "https://*.example.com".
 	// Default value is []string{"*"}
 	AllowOrigins []string
 
+	// AllowOriginFunc is an optional function to decide whether an origin is allowed. It is
+	// checked after AllowOrigins, so it can allow origins the list does not.
+	AllowOriginFunc func(origin string) bool
+
 	// AllowMethods is a list of methods the client is allowed to use with
 	// cross-domain requests. Default value is simple methods (GET, POST, HEAD).
 	AllowMethods []string
@@ -43,33 +48,52 @@ type CORSOptions struct {
 // CORS provides Cross-Origin Resource Sharing middleware
 // For more information, see https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS
 //
+// Preflight requests from allowed origins are answered directly. Other OPTIONS requests, including
+// preflights from origins that are not allowed, are passed to the next handler, so the Mux can still
+// answer them with its Allow header.
+//
 // Example:
 //
 //	router.Use(middleware.CORS(func(opts *middleware.CORSOptions) {
-//		opts.AllowOrigins = []string{"https://example.com"}
+//		opts.AllowOrigins = []string{"https://example.com", "https://*.example.com"}
 //	}))
 func CORS(optsFunc func(opts *CORSOptions)) func(http.Handler) http.Handler {
+	// Set up default options
+	opts := CORSOptions{
+		AllowOrigins:         []string{"*"},
+		AllowMethods:         []string{"GET", "POST", "HEAD"},
+		AllowHeaders:         []string{},
+		ExposeHeaders:        []string{},
+		AllowCredentials:     false,
+		MaxAge:               12 * time.Hour,
+		OptionsSuccessStatus: http.StatusNoContent,
+	}
+
+	// Apply custom options if provided
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Set up default options
-			opts := CORSOptions{
-				AllowOrigins:         []string{"*"},
-				AllowMethods:         []string{"GET", "POST", "HEAD"},
-				AllowHeaders:         []string{},
-				ExposeHeaders:        []string{},
-				AllowCredentials:     false,
-				MaxAge:               12 * time.Hour,
-				OptionsSuccessStatus: http.StatusNoContent,
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
 			}
 
-			// Apply custom options if provided
-			if optsFunc != nil {
-				optsFunc(&opts)
-			}
+			// The response depends on the origin, so caches must key on it
+			w.Header().Add("Vary", "Origin")
 
 			// Handle preflight requests
-			if r.Method == http.MethodOptions {
-				handlePreflight(w, r, &opts)
+			if isPreflight(r) {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				if isOriginAllowed(origin, &opts) {
+					handlePreflight(w, r, &opts)
+					return
+				}
+				next.ServeHTTP(w, r)
 				return
 			}
 
@@ -80,19 +104,13 @@ func CORS(optsFunc func(opts *CORSOptions)) func(http.Handler) http.Handler {
 	}
 }
 
-func handlePreflight(w http.ResponseWriter, r *http.Request, opts *CORSOptions) {
-	origin := r.Header.Get("Origin")
-
-	// Check if origin is allowed
-	if !isOriginAllowed(origin, opts.AllowOrigins) {
-		return
-	}
+// isPreflight reports whether the request is a CORS preflight request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
 
-	// Set CORS headers for preflight
-	w.Header().Set("Access-Control-Allow-Origin", origin)
-	if opts.AllowCredentials {
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-	}
+func handlePreflight(w http.ResponseWriter, r *http.Request, opts *CORSOptions) {
+	setAllowOrigin(w, r.Header.Get("Origin"), opts)
 
 	// Handle Access-Control-Request-Method
 	if requestMethod := r.Header.Get("Access-Control-Request-Method"); requestMethod != "" {
@@ -102,14 +120,10 @@ func handlePreflight(w http.ResponseWriter, r *http.Request, opts *CORSOptions)
 	}
 
 	// Handle Access-Control-Request-Headers
-	if requestHeaders := r.Header.Get("Access-Control-Request-Headers"); requestHeaders != "" {
+	if requestHeaders := r.Header.Get("Access-Control-Request-Headers"); requestHeaders != "" && len(opts.AllowHeaders) > 0 {
 		w.Header().Set("Access-Control-Allow-Headers", strings.Join(opts.AllowHeaders, ", "))
 	}
 
-	if len(opts.ExposeHeaders) > 0 {
-		w.Header().Set("Access-Control-Expose-Headers", strings.Join(opts.ExposeHeaders, ", "))
-	}
-
 	if opts.MaxAge > 0 {
 		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
 	}
@@ -121,37 +135,48 @@ func handleActual(w http.ResponseWriter, r *http.Request, opts *CORSOptions) {
 	origin := r.Header.Get("Origin")
 
 	// Check if origin is allowed
-	if !isOriginAllowed(origin, opts.AllowOrigins) {
+	if !isOriginAllowed(origin, opts) {
 		return
 	}
 
-	// Set CORS headers for actual request
-	w.Header().Set("Access-Control-Allow-Origin", origin)
-	if opts.AllowCredentials {
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-	}
+	setAllowOrigin(w, origin, opts)
 
 	if len(opts.ExposeHeaders) > 0 {
 		w.Header().Set("Access-Control-Expose-Headers", strings.Join(opts.ExposeHeaders, ", "))
 	}
 }
 
-// Helper functions for CORS checks
-func isOriginAllowed(origin string, allowedOrigins []string) bool {
-	if len(allowedOrigins) == 0 {
-		return false
+// setAllowOrigin sets the allowed origin and credentials headers. The request origin is echoed back,
+// since browsers reject the "*" value for requests with credentials.
+func setAllowOrigin(w http.ResponseWriter, origin string, opts *CORSOptions) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if opts.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
 	}
+}
 
-	for _, allowedOrigin := range allowedOrigins {
-		if allowedOrigin == "*" {
-			return true
-		}
-		if allowedOrigin == origin {
+// Helper functions for CORS checks
+func isOriginAllowed(origin string, opts *CORSOptions) bool {
+	for _, allowedOrigin := range opts.AllowOrigins {
+		if allowedOrigin == "*" || matchOrigin(allowedOrigin, origin) {
 			return true
 		}
 	}
 
-	return false
+	return opts.AllowOriginFunc != nil && opts.AllowOriginFunc(origin)
+}
+
+// matchOrigin reports whether origin matches pattern, which may contain a single "*" wildcard
+func matchOrigin(pattern, origin string) bool {
+	prefix, suffix, found := strings.Cut(strings.ToLower(pattern), "*")
+	origin = strings.ToLower(origin)
+	if !found {
+		return prefix == origin
+	}
+
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) &&
+		strings.HasSuffix(origin, suffix)
 }
 
 func isMethodAllowed(method string, allowedMethods []string) bool {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/patrickward/hop/route"
 	"github.com/patrickward/hop/route/middleware"
 )
 
@@ -115,3 +116,141 @@ func TestCORS(t *testing.T) {
 		})
 	}
 }
+
+func TestCORS_OriginMatching(t *testing.T) {
+	tests := []struct {
+		name    string
+		options func(*middleware.CORSOptions)
+		origin  string
+		allowed bool
+	}{
+		{
+			name: "subdomain wildcard",
+			options: func(opts *middleware.CORSOptions) {
+				opts.AllowOrigins = []string{"https://*.example.com"}
+			},
+			origin:  "https://app.example.com",
+			allowed: true,
+		},
+		{
+			name: "wildcard does not match bare domain",
+			options: func(opts *middleware.CORSOptions) {
+				opts.AllowOrigins = []string{"https://*.example.com"}
+			},
+			origin:  "https://example.com",
+			allowed: false,
+		},
+		{
+			name: "wildcard does not match other scheme",
+			options: func(opts *middleware.CORSOptions) {
+				opts.AllowOrigins = []string{"https://*.example.com"}
+			},
+			origin:  "http://app.example.com",
+			allowed: false,
+		},
+		{
+			name: "wildcard does not match suffix attack",
+			options: func(opts *middleware.CORSOptions) {
+				opts.AllowOrigins = []string{"https://*.example.com"}
+			},
+			origin:  "https://app.example.com.evil.com",
+			allowed: false,
+		},
+		{
+			name: "origin func",
+			options: func(opts *middleware.CORSOptions) {
+				opts.AllowOrigins = nil
+				opts.AllowOriginFunc = func(origin string) bool { return origin == "https://partner.com" }
+			},
+			origin:  "https://partner.com",
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.CORS(tt.options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if tt.allowed {
+				assert.Equal(t, tt.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+			} else {
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+			}
+			assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+		})
+	}
+}
+
+func TestCORS_WithMux(t *testing.T) {
+	mux := route.New()
+	mux.Use(middleware.CORS(func(opts *middleware.CORSOptions) {
+		opts.AllowOrigins = []string{"https://example.com"}
+		opts.AllowMethods = []string{"GET", "PUT"}
+	}))
+	mux.Get("/api/users", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		origin        string
+		preflight     bool
+		expectStatus  int
+		expectHeaders map[string]string
+	}{
+		{
+			name:         "preflight from allowed origin",
+			origin:       "https://example.com",
+			preflight:    true,
+			expectStatus: http.StatusNoContent,
+			expectHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://example.com",
+				"Access-Control-Allow-Methods": "GET, PUT",
+			},
+		},
+		{
+			name:         "preflight from other origin falls through to mux",
+			origin:       "https://other.com",
+			preflight:    true,
+			expectStatus: http.StatusNoContent,
+			expectHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Allow":                       "GET, HEAD",
+			},
+		},
+		{
+			name:         "plain OPTIONS request",
+			expectStatus: http.StatusNoContent,
+			expectHeaders: map[string]string{
+				"Allow": "GET, HEAD",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/api/users", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "PUT")
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+			for k, v := range tt.expectHeaders {
+				assert.Equal(t, v, rec.Header().Get(k), k)
+			}
+		})
+	}
+}
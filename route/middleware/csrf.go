@@ -1,37 +1,171 @@
 package middleware
 
 import (
+	"encoding/json"
+	"log/slog"
 	"net/http"
 
 	"github.com/justinas/nosurf"
 
+	"github.com/patrickward/hop/render"
 	"github.com/patrickward/hop/route"
 	"github.com/patrickward/hop/utils"
 )
 
-// PreventCSRFOptions provides options for PreventCSRF
-type PreventCSRFOptions struct {
+// CSRFOptions configures the CSRF middleware
+type CSRFOptions struct {
+	// HTTPOnly sets the HttpOnly attribute of the CSRF cookie
 	HTTPOnly bool
-	Path     string
-	MaxAge   int
+	// Path sets the Path attribute of the CSRF cookie. Defaults to "/".
+	Path string
+	// MaxAge sets the Max-Age attribute of the CSRF cookie, in seconds
+	MaxAge int
+	// SameSite sets the SameSite attribute of the CSRF cookie: "lax", "strict" or "none". Defaults to "lax".
 	SameSite string
-	Secure   bool
+	// Secure sets the Secure attribute of the CSRF cookie
+	Secure bool
+	// ExemptPaths are exact request paths that are not checked, e.g. "/webhooks/stripe"
+	ExemptPaths []string
+	// ExemptGlobs are path patterns that are not checked, e.g. "/api/*"
+	ExemptGlobs []string
+	// ExemptFunc, when set, exempts requests for which it returns true
+	ExemptFunc func(r *http.Request) bool
+	// FailureHandler handles requests that fail the check. Defaults to a 403 Forbidden response: JSON when
+	// the client prefers it, otherwise the 403 template when Templates is set, or plain text.
+	FailureHandler http.Handler
+	// Templates, when set, is used to render the 403 system error template for HTML requests
+	Templates *render.TemplateManager
+	// Logger logs failed checks. Defaults to slog.Default().
+	Logger *slog.Logger
 }
 
-// PreventCSRF prevents CSRF attacks by setting a CSRF cookie.
-func PreventCSRF(opts PreventCSRFOptions) route.Middleware {
+// CSRF returns middleware that protects unsafe requests (POST, PUT, PATCH, DELETE) against cross-site
+// request forgery using nosurf. A token is stored in a cookie and must be sent back with each unsafe
+// request, in the "csrf_token" form field or the X-CSRF-Token header. The token for the current request
+// is available with nosurf.Token, and is added to template data as CSRFToken.
+//
+// Example:
+//
+//	router.Use(middleware.CSRF(func(opts *middleware.CSRFOptions) {
+//		opts.Secure = true
+//		opts.ExemptGlobs = []string{"/webhooks/*"}
+//		opts.Templates = tm
+//	}))
+func CSRF(optsFunc func(opts *CSRFOptions)) route.Middleware {
+	opts := CSRFOptions{
+		HTTPOnly: true,
+		Path:     "/",
+		MaxAge:   nosurf.MaxAge,
+		SameSite: "lax",
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	if opts.FailureHandler == nil {
+		opts.FailureHandler = csrfFailure(opts.Templates)
+	}
+
 	return func(next http.Handler) http.Handler {
 		csrfHandler := nosurf.New(next)
 
-		sameSite := utils.SameSiteFromString(opts.SameSite)
 		csrfHandler.SetBaseCookie(http.Cookie{
 			HttpOnly: opts.HTTPOnly,
 			Path:     opts.Path,
 			MaxAge:   opts.MaxAge,
-			SameSite: sameSite,
+			SameSite: utils.SameSiteFromString(opts.SameSite),
 			Secure:   opts.Secure,
 		})
 
-		return csrfHandler
+		csrfHandler.SetFailureHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reason := "unknown"
+			if err := nosurf.Reason(r); err != nil {
+				reason = err.Error()
+			}
+			opts.Logger.WarnContext(r.Context(), "csrf check failed",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("reason", reason))
+			opts.FailureHandler.ServeHTTP(w, r)
+		}))
+
+		if len(opts.ExemptPaths) > 0 {
+			csrfHandler.ExemptPaths(opts.ExemptPaths...)
+		}
+		if len(opts.ExemptGlobs) > 0 {
+			csrfHandler.ExemptGlobs(opts.ExemptGlobs...)
+		}
+		if opts.ExemptFunc != nil {
+			csrfHandler.ExemptFunc(opts.ExemptFunc)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			csrfHandler.ServeHTTP(w, withAbsoluteURL(r))
+		})
+	}
+}
+
+// withAbsoluteURL returns r with an absolute https URL when it was made over TLS, directly or through a
+// TLS-terminating proxy that set the scheme. nosurf only checks the Referer of requests whose URL has the
+// https scheme, and compares it with the URL's host, which server requests leave empty.
+func withAbsoluteURL(r *http.Request) *http.Request {
+	if r.TLS == nil && r.URL.Scheme != "https" {
+		return r
+	}
+	if r.URL.Scheme == "https" && r.URL.Host != "" {
+		return r
 	}
+
+	u := *r.URL
+	u.Scheme = "https"
+	if u.Host == "" {
+		u.Host = r.Host
+	}
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = &u
+	return r2
+}
+
+// PreventCSRFOptions provides options for PreventCSRF
+type PreventCSRFOptions struct {
+	HTTPOnly bool
+	Path     string
+	MaxAge   int
+	SameSite string
+	Secure   bool
+}
+
+// PreventCSRF prevents CSRF attacks by setting a CSRF cookie. It is kept for compatibility; use CSRF for
+// exemptions and a custom failure handler.
+func PreventCSRF(opts PreventCSRFOptions) route.Middleware {
+	return CSRF(func(o *CSRFOptions) {
+		o.HTTPOnly = opts.HTTPOnly
+		o.Path = opts.Path
+		o.MaxAge = opts.MaxAge
+		o.SameSite = opts.SameSite
+		o.Secure = opts.Secure
+	})
+}
+
+// csrfFailure returns the default failure handler, rendering the 403 template when tm is set
+func csrfFailure(tm *render.TemplateManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case wantsJSON(r):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid csrf token"})
+		case tm != nil:
+			tm.NewResponse().RenderForbidden(w, r)
+		default:
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		}
+	})
 }
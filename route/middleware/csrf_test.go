@@ -1,10 +1,15 @@
 package middleware_test
 
 import (
+	"crypto/tls"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/justinas/nosurf"
+
 	"github.com/patrickward/hop/route/middleware"
 )
 
@@ -46,3 +51,99 @@ func TestPreventCSRF(t *testing.T) {
 		t.Errorf("Cookie MaxAge is not 86400")
 	}
 }
+
+func TestCSRF(t *testing.T) {
+	handler := middleware.CSRF(func(opts *middleware.CSRFOptions) {
+		opts.ExemptPaths = []string{"/webhooks/stripe"}
+		opts.ExemptGlobs = []string{"/api/*"}
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		accept       string
+		expectStatus int
+		expectType   string
+	}{
+		{name: "safe method", method: http.MethodGet, path: "/", expectStatus: http.StatusOK},
+		{name: "missing token", method: http.MethodPost, path: "/account", expectStatus: http.StatusForbidden, expectType: "text/plain; charset=utf-8"},
+		{name: "missing token json", method: http.MethodPost, path: "/account", accept: "application/json", expectStatus: http.StatusForbidden, expectType: "application/json"},
+		{name: "exempt path", method: http.MethodPost, path: "/webhooks/stripe", expectStatus: http.StatusOK},
+		{name: "exempt glob", method: http.MethodDelete, path: "/api/items", expectStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.expectStatus {
+				t.Errorf("Status is %d, expected %d", w.Code, tt.expectStatus)
+			}
+			if tt.expectType != "" && w.Header().Get("Content-Type") != tt.expectType {
+				t.Errorf("Content-Type is %q, expected %q", w.Header().Get("Content-Type"), tt.expectType)
+			}
+		})
+	}
+}
+
+func TestCSRF_TLSReferer(t *testing.T) {
+	handler := middleware.CSRF(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(nosurf.Token(r)))
+	}))
+
+	// Get a token and its cookie
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/account", nil))
+	token := w.Body.String()
+	cookie := w.Result().Cookies()[0]
+
+	tests := []struct {
+		name         string
+		tls          bool
+		forwarded    bool
+		referer      string
+		expectStatus int
+	}{
+		{name: "plain http without referer", expectStatus: http.StatusOK},
+		{name: "tls without referer", tls: true, expectStatus: http.StatusForbidden},
+		{name: "tls with foreign referer", tls: true, referer: "https://evil.example/form", expectStatus: http.StatusForbidden},
+		{name: "tls with same origin referer", tls: true, referer: "https://example.com/account", expectStatus: http.StatusOK},
+		{name: "proxied tls with same origin referer", forwarded: true, referer: "https://example.com/account", expectStatus: http.StatusOK},
+		{name: "proxied tls with foreign referer", forwarded: true, referer: "https://evil.example/form", expectStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"csrf_token": {token}}
+			r := httptest.NewRequest(http.MethodPost, "/account", strings.NewReader(form.Encode()))
+			r.Host = "example.com"
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.AddCookie(cookie)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.forwarded {
+				// As set by middleware that trusts X-Forwarded-Proto
+				r.URL.Scheme = "https"
+			}
+			if tt.referer != "" {
+				r.Header.Set("Referer", tt.referer)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.expectStatus {
+				t.Errorf("Status is %d, expected %d", w.Code, tt.expectStatus)
+			}
+		})
+	}
+}
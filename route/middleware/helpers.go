@@ -1,6 +1,10 @@
 package middleware
 
-import "net/http"
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
 
 type responseWriter struct {
 	http.ResponseWriter
@@ -18,3 +22,32 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	rw.written += int64(n)
 	return n, err
 }
+
+// Unwrap returns the underlying http.ResponseWriter, so http.ResponseController can reach it
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// wantsJSON reports whether the Accept header prefers a JSON response over HTML
+func wantsJSON(r *http.Request) bool {
+	var jsonQ, htmlQ float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			jsonQ = max(jsonQ, q)
+		case mediaType == "text/html":
+			htmlQ = max(htmlQ, q)
+		}
+	}
+	return jsonQ > 0 && jsonQ >= htmlQ
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// IPFilterOptions configures the IPFilter middleware
+type IPFilterOptions struct {
+	// Allow lists the networks that may access the handler. When empty, every address not in Deny is
+	// allowed.
+	Allow []netip.Prefix
+	// Deny lists networks that may never access the handler. Deny takes precedence over Allow.
+	Deny []netip.Prefix
+	// DeniedHandler is called for rejected requests. Default value responds with 403 Forbidden.
+	DeniedHandler http.Handler
+}
+
+// IPFilter returns middleware that restricts access by client IP address, as returned by ClientIP. Use
+// RealIP in front of it when the application runs behind a proxy, so the filter sees the address of the
+// client rather than the proxy. Requests whose address cannot be determined are rejected when an Allow
+// list is set.
+//
+// Example:
+//
+//	admin.Use(middleware.IPFilter(func(opts *middleware.IPFilterOptions) {
+//		opts.Allow = middleware.MustParsePrefixes("10.0.0.0/8", "192.0.2.10")
+//	}))
+func IPFilter(optsFunc func(opts *IPFilterOptions)) func(http.Handler) http.Handler {
+	opts := IPFilterOptions{
+		DeniedHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		}),
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !ipAllowed(clientAddr(r), &opts) {
+				opts.DeniedHandler.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ipAllowed reports whether the options permit the address
+func ipAllowed(ip netip.Addr, opts *IPFilterOptions) bool {
+	if !ip.IsValid() {
+		return len(opts.Allow) == 0
+	}
+	if containsIP(opts.Deny, ip) {
+		return false
+	}
+	return len(opts.Allow) == 0 || containsIP(opts.Allow, ip)
+}
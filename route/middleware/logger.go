@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"context"
 	"log/slog"
 	"net/http"
 	"time"
@@ -22,7 +21,7 @@ func Logger(l *slog.Logger, level slog.Level) func(http.Handler) http.Handler {
 
 			next.ServeHTTP(rw, r)
 
-			l.LogAttrs(context.Background(), level, "http request",
+			l.LogAttrs(r.Context(), level, "http request",
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
 				slog.String("remote_addr", r.RemoteAddr),
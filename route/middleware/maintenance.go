@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickward/hop/render"
+)
+
+// MaintenanceOptions configures the Maintenance middleware
+type MaintenanceOptions struct {
+	// Enabled reports whether maintenance mode is on. It is called for every request (required).
+	Enabled func() bool
+	// Message is shown to users, as MaintenanceMessage in the 503 template data and in JSON responses
+	Message string
+	// RetryAfter is sent in the Retry-After header. Defaults to 5 minutes.
+	RetryAfter time.Duration
+	// AllowPaths are path prefixes that stay available during maintenance, e.g. "/admin" or "/healthz"
+	AllowPaths []string
+	// Allow, when set, lets requests for which it returns true through during maintenance
+	Allow func(r *http.Request) bool
+	// Templates, when set, is used to render the 503 system error template for HTML requests
+	Templates *render.TemplateManager
+}
+
+// Maintenance returns middleware that answers requests with 503 Service Unavailable and a Retry-After
+// header while maintenance mode is enabled, as JSON when the client prefers it, otherwise as the 503
+// template or plain text. Requests for AllowPaths, or accepted by Allow, are served normally.
+//
+// Example:
+//
+//	router.Use(middleware.Maintenance(func(opts *middleware.MaintenanceOptions) {
+//		opts.Enabled = maintenance.Load
+//		opts.AllowPaths = []string{"/admin", "/healthz"}
+//		opts.Templates = tm
+//	}))
+func Maintenance(optsFunc func(opts *MaintenanceOptions)) func(http.Handler) http.Handler {
+	opts := MaintenanceOptions{
+		RetryAfter: 5 * time.Minute,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Enabled == nil {
+		panic("middleware: Maintenance requires an Enabled function")
+	}
+
+	retryAfter := strconv.Itoa(int(opts.RetryAfter.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !opts.Enabled() || maintenanceAllowed(r, &opts) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Retry-After", retryAfter)
+			w.Header().Set("Cache-Control", "no-store")
+
+			switch {
+			case wantsJSON(r):
+				message := opts.Message
+				if message == "" {
+					message = "service unavailable for maintenance"
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"error":       message,
+					"retry_after": int(opts.RetryAfter.Seconds()),
+				})
+			case opts.Templates != nil:
+				opts.Templates.NewResponse().
+					WithData(map[string]any{"MaintenanceEnabled": true, "MaintenanceMessage": opts.Message}).
+					RenderMaintenance(w, r)
+			default:
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+// maintenanceAllowed reports whether the request is served during maintenance
+func maintenanceAllowed(r *http.Request, opts *MaintenanceOptions) bool {
+	for _, prefix := range opts.AllowPaths {
+		if r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return opts.Allow != nil && opts.Allow(r)
+}
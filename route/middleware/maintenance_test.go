@@ -0,0 +1,58 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/patrickward/hop/route/middleware"
+)
+
+func TestMaintenance(t *testing.T) {
+	var enabled atomic.Bool
+	handler := middleware.Maintenance(func(opts *middleware.MaintenanceOptions) {
+		opts.Enabled = enabled.Load
+		opts.Message = "Back soon"
+		opts.RetryAfter = 2 * time.Minute
+		opts.AllowPaths = []string{"/admin"}
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	tests := []struct {
+		name         string
+		enabled      bool
+		path         string
+		accept       string
+		expectStatus int
+		expectBody   string
+	}{
+		{name: "disabled", path: "/", expectStatus: http.StatusOK, expectBody: "ok"},
+		{name: "enabled", enabled: true, path: "/", expectStatus: http.StatusServiceUnavailable, expectBody: "Service Unavailable\n"},
+		{name: "enabled json", enabled: true, path: "/api", accept: "application/json", expectStatus: http.StatusServiceUnavailable, expectBody: `{"error":"Back soon","retry_after":120}` + "\n"},
+		{name: "allowed path", enabled: true, path: "/admin/users", expectStatus: http.StatusOK, expectBody: "ok"},
+		{name: "similar path is not allowed", enabled: true, path: "/administrator", expectStatus: http.StatusServiceUnavailable, expectBody: "Service Unavailable\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enabled.Store(tt.enabled)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+			assert.Equal(t, tt.expectBody, rec.Body.String())
+			if tt.expectStatus == http.StatusServiceUnavailable {
+				assert.Equal(t, "120", rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
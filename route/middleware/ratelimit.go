@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+)
+
+// CounterStore stores rate limit counters. Implementations backed by shared storage, such as
+// RedisCounterStore or SQLCounterStore, allow limits to hold across multiple replicas.
+type CounterStore interface {
+	// Increment adds delta to the counter for key in the current fixed window of the given length,
+	// starting a new window if the previous one has ended. It returns the new count and the time
+	// the window resets.
+	Increment(ctx context.Context, key string, delta int64, window time.Duration) (count int64, reset time.Time, err error)
+}
+
+// RateLimitOptions contains the configuration for rate limiting middleware
+type RateLimitOptions struct {
+	// Limit is the maximum number of requests allowed per key within Window.
+	// Default value is 100.
+	Limit int
+
+	// Window is the length of the fixed rate limit window.
+	// Default value is 1 minute.
+	Window time.Duration
+
+	// Store holds the counters. Default value is an in-memory store, which only limits
+	// requests within a single instance.
+	Store CounterStore
+
+	// KeyFunc returns the key requests are counted against.
+	// Default value is the client IP address, as returned by ClientIP.
+	KeyFunc func(r *http.Request) string
+
+	// LimitedHandler is called when a request exceeds the limit.
+	// Default value responds with 429 Too Many Requests.
+	LimitedHandler http.Handler
+
+	// ErrorHandler is called when the store returns an error. When nil, the request is allowed
+	// through (fail open), so an unavailable store does not take the application down.
+	ErrorHandler ErrorHandler
+
+	// Clock tells the time for the Retry-After header and the default store.
+	// Default value is the real clock; tests can pass a fake clock from the hoptest package.
+	Clock clock.Clock
+}
+
+// RateLimit provides fixed-window rate limiting middleware. It sets the X-RateLimit-Limit,
+// X-RateLimit-Remaining, and X-RateLimit-Reset headers on every response, and Retry-After
+// on limited responses.
+//
+// Example:
+//
+//	router.Use(middleware.RateLimit(func(opts *middleware.RateLimitOptions) {
+//		opts.Limit = 60
+//		opts.Window = time.Minute
+//		opts.Store = middleware.NewCachedCounterStore(middleware.NewRedisCounterStore(client), 250*time.Millisecond)
+//	}))
+func RateLimit(optsFunc func(opts *RateLimitOptions)) func(http.Handler) http.Handler {
+	opts := RateLimitOptions{
+		Limit:   100,
+		Window:  time.Minute,
+		KeyFunc: ClientIP,
+		LimitedHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}),
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	opts.Clock = clock.OrReal(opts.Clock)
+	if opts.Store == nil {
+		store := NewMemoryCounterStore()
+		store.SetClock(opts.Clock)
+		opts.Store = store
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := opts.KeyFunc(r)
+
+			count, reset, err := opts.Store.Increment(r.Context(), key, 1, opts.Window)
+			if err != nil {
+				if opts.ErrorHandler != nil {
+					opts.ErrorHandler(w, r, err)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			remaining := int64(opts.Limit) - count
+			if remaining < 0 {
+				remaining = 0
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(opts.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+			if count > int64(opts.Limit) {
+				retryAfter := int(math.Ceil(opts.Clock.Until(reset).Seconds()))
+				if retryAfter < 1 {
+					retryAfter = 1
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				opts.LimitedHandler.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// windowStart returns the start of the fixed window containing t
+func windowStart(t time.Time, window time.Duration) time.Time {
+	return t.Truncate(window)
+}
+
+// MemoryCounterStore is an in-memory CounterStore. Limits only apply within a single process.
+type MemoryCounterStore struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+	clock    clock.Clock
+	sweeps   int
+}
+
+type memoryCounter struct {
+	count int64
+	reset time.Time
+}
+
+// NewMemoryCounterStore creates a new MemoryCounterStore
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{
+		counters: make(map[string]memoryCounter),
+		clock:    clock.Real(),
+	}
+}
+
+// SetClock sets the clock used to tell the current window, e.g. a fake clock in tests
+func (s *MemoryCounterStore) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = clock.OrReal(c)
+}
+
+// Increment adds delta to the counter for key in the current window
+func (s *MemoryCounterStore) Increment(_ context.Context, key string, delta int64, window time.Duration) (int64, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.reset) {
+		c = memoryCounter{reset: windowStart(now, window).Add(window)}
+	}
+	c.count += delta
+	s.counters[key] = c
+
+	// Periodically drop expired counters so the map does not grow without bound
+	s.sweeps++
+	if s.sweeps >= 1000 {
+		s.sweeps = 0
+		for k, v := range s.counters {
+			if !now.Before(v.reset) {
+				delete(s.counters, k)
+			}
+		}
+	}
+
+	return c.count, c.reset, nil
+}
+
+// CachedCounterStore wraps a shared CounterStore with a local cache, so most requests are counted
+// locally and only periodically synchronized with the backend. This keeps per-request latency low
+// at the cost of slightly looser limits: each instance may admit up to SyncInterval worth of extra
+// requests before it learns the shared count.
+type CachedCounterStore struct {
+	store        CounterStore
+	syncInterval time.Duration
+	mu           sync.Mutex
+	entries      map[string]*cachedCounter
+	clock        clock.Clock
+}
+
+type cachedCounter struct {
+	remote   int64     // last count returned by the backend
+	pending  int64     // local increments not yet sent to the backend
+	reset    time.Time // when the current window resets
+	lastSync time.Time // when the backend was last contacted
+}
+
+// NewCachedCounterStore creates a CachedCounterStore that synchronizes each key with store at most
+// once per syncInterval
+func NewCachedCounterStore(store CounterStore, syncInterval time.Duration) *CachedCounterStore {
+	return &CachedCounterStore{
+		store:        store,
+		syncInterval: syncInterval,
+		entries:      make(map[string]*cachedCounter),
+		clock:        clock.Real(),
+	}
+}
+
+// SetClock sets the clock used to tell when the cached values are stale, e.g. a fake clock in tests
+func (s *CachedCounterStore) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = clock.OrReal(c)
+}
+
+// Increment adds delta to the counter for key, contacting the backend only when the cached value is stale
+func (s *CachedCounterStore) Increment(ctx context.Context, key string, delta int64, window time.Duration) (int64, time.Time, error) {
+	s.mu.Lock()
+	now := s.clock.Now()
+	e, ok := s.entries[key]
+	if ok && now.Before(e.reset) && now.Sub(e.lastSync) < s.syncInterval {
+		e.pending += delta
+		count, reset := e.remote+e.pending, e.reset
+		s.mu.Unlock()
+		return count, reset, nil
+	}
+
+	// Carry over pending increments from the current window only
+	var pending int64
+	if ok && now.Before(e.reset) {
+		pending = e.pending
+	}
+	if !ok {
+		e = &cachedCounter{}
+		s.entries[key] = e
+	}
+	e.pending = 0
+	e.lastSync = now
+	s.mu.Unlock()
+
+	count, reset, err := s.store.Increment(ctx, key, pending+delta, window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		// Keep the increments so they are sent with the next sync
+		e.pending += pending + delta
+		return 0, time.Time{}, err
+	}
+
+	e.remote = count
+	e.reset = reset
+	return count + e.pending, reset, nil
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+)
+
+// SQLCounterStore is a CounterStore backed by a SQLite (3.35 or later) or Postgres database, so
+// limits hold across replicas sharing the database. Call Migrate to create the rate_limits table.
+type SQLCounterStore struct {
+	db    *sql.DB
+	clock clock.Clock
+}
+
+// NewSQLCounterStore creates a new SQLCounterStore
+func NewSQLCounterStore(db *sql.DB) *SQLCounterStore {
+	return &SQLCounterStore{db: db, clock: clock.Real()}
+}
+
+// SetClock sets the clock used to tell the current window, e.g. a fake clock in tests. It must be
+// called before the store is used.
+func (s *SQLCounterStore) SetClock(c clock.Clock) {
+	s.clock = clock.OrReal(c)
+}
+
+// Migrate creates the rate_limits table if it does not exist
+func (s *SQLCounterStore) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS rate_limits (
+		key TEXT PRIMARY KEY,
+		count BIGINT NOT NULL,
+		reset_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating rate_limits table: %w", err)
+	}
+	return nil
+}
+
+// Increment adds delta to the counter for key in the current window
+func (s *SQLCounterStore) Increment(ctx context.Context, key string, delta int64, window time.Duration) (int64, time.Time, error) {
+	now := s.clock.Now()
+	reset := windowStart(now, window).Add(window)
+
+	var (
+		count   int64
+		resetAt int64
+	)
+
+	// A single upsert either starts a new window or adds to the current one
+	err := s.db.QueryRowContext(ctx, `INSERT INTO rate_limits (key, count, reset_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limits.reset_at <= $4 THEN excluded.count ELSE rate_limits.count + excluded.count END,
+			reset_at = CASE WHEN rate_limits.reset_at <= $4 THEN excluded.reset_at ELSE rate_limits.reset_at END
+		RETURNING count, reset_at`,
+		key, delta, reset.UnixNano(), now.UnixNano()).Scan(&count, &resetAt)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return count, time.Unix(0, resetAt), nil
+}
+
+// DeleteExpired removes counters whose window has ended
+func (s *SQLCounterStore) DeleteExpired(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM rate_limits WHERE reset_at <= $1", s.clock.Now().UnixNano())
+	return err
+}
+
+// RedisEvaler is the subset of a Redis client used by RedisCounterStore. It can be satisfied with a
+// small adapter around any Redis client library, for example with go-redis:
+//
+//	type redisAdapter struct{ c *redis.Client }
+//
+//	func (a redisAdapter) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//	    return a.c.Eval(ctx, script, keys, args...).Result()
+//	}
+type RedisEvaler interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// redisIncrementScript increments the counter and sets its expiry when the window starts,
+// returning the new count and the remaining TTL in milliseconds
+const redisIncrementScript = `local count = redis.call("INCRBY", KEYS[1], ARGV[1])
+if count == tonumber(ARGV[1]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+local ttl = redis.call("PTTL", KEYS[1])
+return {count, ttl}`
+
+// RedisCounterStore is a CounterStore backed by Redis, so limits hold across replicas sharing the
+// Redis server. Each counter is a key that expires when its window ends.
+type RedisCounterStore struct {
+	client RedisEvaler
+	prefix string
+	clock  clock.Clock
+}
+
+// NewRedisCounterStore creates a new RedisCounterStore. Keys are prefixed with "ratelimit:".
+func NewRedisCounterStore(client RedisEvaler) *RedisCounterStore {
+	return &RedisCounterStore{client: client, prefix: "ratelimit:", clock: clock.Real()}
+}
+
+// SetClock sets the clock used to tell the current window, e.g. a fake clock in tests. It must be
+// called before the store is used.
+func (s *RedisCounterStore) SetClock(c clock.Clock) {
+	s.clock = clock.OrReal(c)
+}
+
+// Increment adds delta to the counter for key in the current window
+func (s *RedisCounterStore) Increment(ctx context.Context, key string, delta int64, window time.Duration) (int64, time.Time, error) {
+	now := s.clock.Now()
+	start := windowStart(now, window)
+
+	// Include the window start in the key so a new window always starts a new counter
+	redisKey := fmt.Sprintf("%s%s:%d", s.prefix, key, start.Unix())
+	ttl := start.Add(window).Sub(now)
+
+	res, err := s.client.Eval(ctx, redisIncrementScript, []string{redisKey}, delta, ttl.Milliseconds()+1)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	values, ok := res.([]any)
+	if !ok || len(values) != 2 {
+		return 0, time.Time{}, fmt.Errorf("unexpected redis reply: %v", res)
+	}
+
+	count, ok := values[0].(int64)
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("unexpected redis count: %v", values[0])
+	}
+
+	return count, start.Add(window), nil
+}
@@ -0,0 +1,220 @@
+package middleware_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/route/middleware"
+)
+
+// countingStore wraps a store and counts backend calls
+type countingStore struct {
+	middleware.CounterStore
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (s *countingStore) Increment(ctx context.Context, key string, delta int64, window time.Duration) (int64, time.Time, error) {
+	s.mu.Lock()
+	s.calls++
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return s.CounterStore.Increment(ctx, key, delta, window)
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := middleware.RateLimit(func(opts *middleware.RateLimitOptions) {
+		opts.Limit = 2
+		opts.Window = time.Hour
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name            string
+		remoteAddr      string
+		expectStatus    int
+		expectRemaining string
+	}{
+		{name: "first request", remoteAddr: "10.0.0.1:1234", expectStatus: http.StatusOK, expectRemaining: "1"},
+		{name: "second request", remoteAddr: "10.0.0.1:5678", expectStatus: http.StatusOK, expectRemaining: "0"},
+		{name: "over limit", remoteAddr: "10.0.0.1:1234", expectStatus: http.StatusTooManyRequests, expectRemaining: "0"},
+		{name: "other client", remoteAddr: "10.0.0.2:1234", expectStatus: http.StatusOK, expectRemaining: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+			assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+			assert.Equal(t, tt.expectRemaining, rec.Header().Get("X-RateLimit-Remaining"))
+			assert.NotEmpty(t, rec.Header().Get("X-RateLimit-Reset"))
+			if tt.expectStatus == http.StatusTooManyRequests {
+				assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestRateLimit_StoreError(t *testing.T) {
+	store := &countingStore{CounterStore: middleware.NewMemoryCounterStore(), err: errors.New("unavailable")}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("fails open by default", func(t *testing.T) {
+		handler := middleware.RateLimit(func(opts *middleware.RateLimitOptions) {
+			opts.Store = store
+		})(next)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("uses error handler", func(t *testing.T) {
+		handler := middleware.RateLimit(func(opts *middleware.RateLimitOptions) {
+			opts.Store = store
+			opts.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			}
+		})(next)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
+
+func TestCachedCounterStore(t *testing.T) {
+	backend := &countingStore{CounterStore: middleware.NewMemoryCounterStore()}
+	cached := middleware.NewCachedCounterStore(backend, time.Hour)
+	ctx := context.Background()
+
+	for i := int64(1); i <= 5; i++ {
+		count, _, err := cached.Increment(ctx, "key", 1, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, i, count)
+	}
+	assert.Equal(t, 1, backend.calls, "only the first increment should reach the backend")
+
+	// A second instance sharing the backend sees the synchronized count
+	other := middleware.NewCachedCounterStore(backend, 0)
+	count, _, err := other.Increment(ctx, "key", 1, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Pending increments are flushed on the next sync
+	count, _, err = other.Increment(ctx, "key", 1, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestSQLCounterStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	store := middleware.NewSQLCounterStore(db)
+	ctx := context.Background()
+	require.NoError(t, store.Migrate(ctx))
+
+	count, reset, err := store.Increment(ctx, "client", 1, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, reset.After(time.Now()))
+
+	count, _, err = store.Increment(ctx, "client", 3, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	count, _, err = store.Increment(ctx, "other", 1, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// A window that has already ended starts a new count
+	count, _, err = store.Increment(ctx, "short", 1, time.Nanosecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	count, _, err = store.Increment(ctx, "short", 1, time.Nanosecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	require.NoError(t, store.DeleteExpired(ctx))
+}
+
+// fakeRedis implements RedisEvaler with the semantics of the increment script
+type fakeRedis struct {
+	counts map[string]int64
+}
+
+func (f *fakeRedis) Eval(_ context.Context, _ string, keys []string, args ...any) (any, error) {
+	f.counts[keys[0]] += args[0].(int64)
+	return []any{f.counts[keys[0]], args[1]}, nil
+}
+
+func TestRedisCounterStore(t *testing.T) {
+	store := middleware.NewRedisCounterStore(&fakeRedis{counts: make(map[string]int64)})
+	ctx := context.Background()
+
+	count, reset, err := store.Increment(ctx, "client", 1, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, reset.After(time.Now()))
+
+	count, _, err = store.Increment(ctx, "client", 2, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestRateLimit_WindowResets(t *testing.T) {
+	clk := hoptest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	handler := middleware.RateLimit(func(opts *middleware.RateLimitOptions) {
+		opts.Limit = 1
+		opts.Window = time.Minute
+		opts.Clock = clk
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve().Code)
+
+	rec := serve()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	clk.Advance(45 * time.Second)
+	rec = serve()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "15", rec.Header().Get("Retry-After"))
+
+	clk.Advance(15 * time.Second)
+	assert.Equal(t, http.StatusOK, serve().Code, "a new window started")
+}
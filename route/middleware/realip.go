@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type realIPContextKey struct{}
+
+// RealIPOptions configures the RealIP middleware
+type RealIPOptions struct {
+	// TrustedProxies are the networks of the proxies and load balancers in front of the application.
+	// Forwarding headers are only honored on requests from these networks. Defaults to none, so the
+	// connection's remote address is always used.
+	TrustedProxies []netip.Prefix
+	// Headers are checked in order for the client address. X-Forwarded-For is walked from the right,
+	// skipping trusted proxies, so clients cannot spoof it by sending their own header. Defaults to
+	// X-Forwarded-For and X-Real-IP.
+	Headers []string
+}
+
+// RealIP returns middleware that resolves the client IP address of each request and stores it in the
+// request context, where ClientIP reads it. Other middleware, such as RateLimit, AccessLog and IPFilter,
+// use ClientIP, so they all see the same address.
+//
+// Example:
+//
+//	router.Use(middleware.RealIP(func(opts *middleware.RealIPOptions) {
+//		opts.TrustedProxies = middleware.MustParsePrefixes("10.0.0.0/8", "fd00::/8")
+//	}))
+func RealIP(optsFunc func(opts *RealIPOptions)) func(http.Handler) http.Handler {
+	opts := RealIPOptions{
+		Headers: []string{"X-Forwarded-For", "X-Real-IP"},
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := resolveIP(r, &opts); ip.IsValid() {
+				r = r.WithContext(context.WithValue(r.Context(), realIPContextKey{}, ip))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the client IP address resolved by the RealIP middleware, falling back to the
+// host of the request's RemoteAddr when RealIP is not in use
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(realIPContextKey{}).(netip.Addr); ok {
+		return ip.String()
+	}
+	return remoteHost(r)
+}
+
+// clientAddr returns the client IP address as a netip.Addr, which is invalid if it cannot be parsed
+func clientAddr(r *http.Request) netip.Addr {
+	if ip, ok := r.Context().Value(realIPContextKey{}).(netip.Addr); ok {
+		return ip
+	}
+	ip, _ := netip.ParseAddr(remoteHost(r))
+	return ip.Unmap()
+}
+
+// ParsePrefixes parses IP addresses and CIDR networks, e.g. "10.0.0.0/8" or "192.0.2.1". Single
+// addresses are treated as networks of one address.
+func ParsePrefixes(values ...string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if strings.Contains(v, "/") {
+			p, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", v, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q: %w", v, err)
+		}
+		ip = ip.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// MustParsePrefixes is like ParsePrefixes but panics if a value is invalid
+func MustParsePrefixes(values ...string) []netip.Prefix {
+	prefixes, err := ParsePrefixes(values...)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
+
+// resolveIP returns the client address of the request
+func resolveIP(r *http.Request, opts *RealIPOptions) netip.Addr {
+	peer, err := netip.ParseAddr(remoteHost(r))
+	if err != nil {
+		return netip.Addr{}
+	}
+	peer = peer.Unmap()
+
+	if !containsIP(opts.TrustedProxies, peer) {
+		return peer
+	}
+
+	for _, header := range opts.Headers {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		if !strings.EqualFold(header, "X-Forwarded-For") {
+			if ip, err := netip.ParseAddr(strings.TrimSpace(values[0])); err == nil {
+				return ip.Unmap()
+			}
+			continue
+		}
+
+		// Each proxy appends the address it received the request from, so the rightmost untrusted
+		// address is the client; anything to its left may have been sent by the client itself
+		hops := strings.Split(strings.Join(values, ","), ",")
+		var leftmost netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			ip = ip.Unmap()
+			if !containsIP(opts.TrustedProxies, ip) {
+				return ip
+			}
+			leftmost = ip
+		}
+		// Every hop is a trusted proxy, so the request originated inside the trusted network
+		if leftmost.IsValid() {
+			return leftmost
+		}
+	}
+
+	return peer
+}
+
+// remoteHost returns the host portion of the request's RemoteAddr
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// containsIP reports whether any of the prefixes contains ip
+func containsIP(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/route/middleware"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := middleware.MustParsePrefixes("10.0.0.0/8", "192.0.2.1")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "untrusted peer ignores headers",
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "trusted proxy uses forwarded for",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "spoofed hops left of the client are ignored",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 192.0.2.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "all trusted hops",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "10.1.1.1, 10.0.0.3"},
+			expected:   "10.1.1.1",
+		},
+		{
+			name:       "real ip header",
+			remoteAddr: "192.0.2.1:80",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.7"},
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "trusted proxy without headers",
+			remoteAddr: "10.0.0.2:1234",
+			expected:   "10.0.0.2",
+		},
+		{
+			name:       "ipv6 peer",
+			remoteAddr: "[2001:db8::1]:443",
+			expected:   "2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := middleware.RealIP(func(opts *middleware.RealIPOptions) {
+				opts.TrustedProxies = trusted
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = middleware.ClientIP(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestClientIP_WithoutRealIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.5:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	assert.Equal(t, "203.0.113.5", middleware.ClientIP(req))
+}
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := middleware.ParsePrefixes("10.0.0.1/8", "192.0.2.1", "2001:db8::/32")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8", prefixes[0].String())
+	assert.Equal(t, "192.0.2.1/32", prefixes[1].String())
+	assert.Equal(t, "2001:db8::/32", prefixes[2].String())
+
+	_, err = middleware.ParsePrefixes("not-an-ip")
+	assert.Error(t, err)
+}
+
+func TestIPFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		allow      []string
+		deny       []string
+		remoteAddr string
+		expected   int
+	}{
+		{name: "no lists", remoteAddr: "203.0.113.5:1", expected: http.StatusOK},
+		{name: "allowed", allow: []string{"10.0.0.0/8"}, remoteAddr: "10.1.2.3:1", expected: http.StatusOK},
+		{name: "not allowed", allow: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.5:1", expected: http.StatusForbidden},
+		{name: "denied", deny: []string{"203.0.113.0/24"}, remoteAddr: "203.0.113.5:1", expected: http.StatusForbidden},
+		{name: "deny wins", allow: []string{"10.0.0.0/8"}, deny: []string{"10.0.0.5"}, remoteAddr: "10.0.0.5:1", expected: http.StatusForbidden},
+		{name: "unparseable with allow list", allow: []string{"10.0.0.0/8"}, remoteAddr: "unknown", expected: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.IPFilter(func(opts *middleware.IPFilterOptions) {
+				opts.Allow = middleware.MustParsePrefixes(tt.allow...)
+				opts.Deny = middleware.MustParsePrefixes(tt.deny...)
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expected, rec.Code)
+		})
+	}
+
+	t.Run("uses the real ip", func(t *testing.T) {
+		handler := middleware.RealIP(func(opts *middleware.RealIPOptions) {
+			opts.TrustedProxies = middleware.MustParsePrefixes("10.0.0.0/8")
+		})(middleware.IPFilter(func(opts *middleware.IPFilterOptions) {
+			opts.Deny = middleware.MustParsePrefixes("198.51.100.1")
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.2:1234"
+		req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
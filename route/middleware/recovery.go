@@ -5,6 +5,9 @@ import (
 	"log/slog"
 	"net/http"
 	"runtime/debug"
+
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/render"
 )
 
 // ErrorHandler is a function that handles errors during request processing
@@ -42,3 +45,101 @@ func Recovery(logger *slog.Logger, handler ErrorHandler) func(http.Handler) http
 		})
 	}
 }
+
+// PanicHook is called with the request, the recovered value and the stack trace of a panic. It is
+// typically used to forward the panic to an external error reporting service.
+type PanicHook func(r *http.Request, recovered any, stack []byte)
+
+// RecoverOptions configures the Recover middleware
+type RecoverOptions struct {
+	// Logger receives the panic and its stack trace. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Metrics, when set, has its "http_panics_total" counter incremented for every panic
+	Metrics pulse.Collector
+	// Templates, when set, is used to render the 500 system error template
+	Templates *render.TemplateManager
+	// ErrorHandler, when set, writes the error response instead of the 500 template
+	ErrorHandler ErrorHandler
+	// OnPanic, when set, is called for every panic after it has been logged
+	OnPanic PanicHook
+}
+
+// Recover returns middleware that recovers from handler panics. The panic and its stack are logged,
+// the "http_panics_total" counter is incremented, OnPanic is called, and a 500 response is written using
+// ErrorHandler, the system error template, or a plain text error, in that order of preference. If the
+// handler had already started writing the response, nothing more is written. Panics with
+// http.ErrAbortHandler are re-panicked so the server aborts the response as intended.
+//
+// Example:
+//
+//	router.Use(middleware.Recover(func(opts *middleware.RecoverOptions) {
+//		opts.Logger = logger
+//		opts.Metrics = collector
+//		opts.Templates = tm
+//		opts.OnPanic = func(r *http.Request, recovered any, stack []byte) {
+//			reporter.Report(r.Context(), recovered, stack)
+//		}
+//	}))
+func Recover(optsFunc func(opts *RecoverOptions)) func(http.Handler) http.Handler {
+	opts := RecoverOptions{}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	var panics pulse.Counter
+	if opts.Metrics != nil {
+		panics = opts.Metrics.Counter("http_panics_total")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w}
+
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				stack := debug.Stack()
+				opts.Logger.ErrorContext(r.Context(), "panic recovered",
+					slog.Any("error", recovered),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("stack", string(stack)))
+
+				if panics != nil {
+					panics.Inc()
+				}
+
+				if opts.OnPanic != nil {
+					opts.OnPanic(r, recovered, stack)
+				}
+
+				// The status line has already been sent, so an error page would be appended to a partial body
+				if rw.status != 0 || rw.written > 0 {
+					return
+				}
+
+				switch {
+				case opts.ErrorHandler != nil:
+					opts.ErrorHandler(w, r, fmt.Errorf("panic: %v", recovered))
+				case opts.Templates != nil:
+					opts.Templates.NewResponse().StatusError().RenderServerError(w, r)
+				default:
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
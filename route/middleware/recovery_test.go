@@ -1,15 +1,21 @@
 package middleware_test
 
 import (
+	"bytes"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/render"
 	"github.com/patrickward/hop/route/middleware"
 )
 
@@ -67,3 +73,101 @@ func TestRecovery(t *testing.T) {
 		})
 	}
 }
+
+func TestRecover(t *testing.T) {
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	})
+
+	t.Run("reports panics and renders the error template", func(t *testing.T) {
+		tm, err := render.NewTemplateManager(render.Sources{
+			"": fstest.MapFS{
+				"layouts/base.html":     {Data: []byte(`{{define "layout:base"}}{{template "page:main" .}}{{end}}`)},
+				"views/system/500.html": {Data: []byte(`{{define "page:main"}}<h1>Oops</h1>{{end}}`)},
+			},
+		}, render.TemplateManagerOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+		require.NoError(t, err)
+
+		collector := pulse.NewStandardCollector()
+		var (
+			hookValue any
+			hookStack []byte
+		)
+		var logs bytes.Buffer
+
+		handler := middleware.Recover(func(opts *middleware.RecoverOptions) {
+			opts.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+			opts.Metrics = collector
+			opts.Templates = tm
+			opts.OnPanic = func(r *http.Request, recovered any, stack []byte) {
+				hookValue = recovered
+				hookStack = stack
+			}
+		})(panicking)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "<h1>Oops</h1>", strings.TrimSpace(rec.Body.String()))
+		assert.Equal(t, "something went wrong", hookValue)
+		assert.NotEmpty(t, hookStack)
+		assert.Contains(t, logs.String(), "panic recovered")
+		assert.Contains(t, logs.String(), "path=/boom")
+		assert.Equal(t, float64(1), collector.Counter("http_panics_total").Value())
+	})
+
+	t.Run("uses the error handler", func(t *testing.T) {
+		var got error
+		handler := middleware.Recover(func(opts *middleware.RecoverOptions) {
+			opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			opts.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+				got = err
+				http.Error(w, "custom error", http.StatusTeapot)
+			}
+		})(panicking)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusTeapot, rec.Code)
+		assert.EqualError(t, got, "panic: something went wrong")
+	})
+
+	t.Run("falls back to a plain error", func(t *testing.T) {
+		handler := middleware.Recover(func(opts *middleware.RecoverOptions) {
+			opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		})(panicking)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, http.StatusText(http.StatusInternalServerError), strings.TrimSpace(rec.Body.String()))
+	})
+
+	t.Run("does not write after the response started", func(t *testing.T) {
+		handler := middleware.Recover(func(opts *middleware.RecoverOptions) {
+			opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("partial"))
+			panic("mid-stream")
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "partial", rec.Body.String())
+	})
+
+	t.Run("re-panics ErrAbortHandler", func(t *testing.T) {
+		handler := middleware.Recover(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+}
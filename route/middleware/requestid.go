@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+// RequestIDHeader is the default header used to read and write request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDLogKey is the attribute key used for request IDs in log records
+const RequestIDLogKey = "request_id"
+
+// requestIDKey is the context key for the request ID
+type requestIDKey struct{}
+
+// RequestIDOptions configures the RequestID middleware
+type RequestIDOptions struct {
+	// Header is the header used to read incoming request IDs and write the response header. Defaults to X-Request-ID.
+	Header string
+	// TrustIncoming accepts a request ID sent by the client or an upstream proxy. Defaults to true.
+	TrustIncoming bool
+	// MaxLength is the longest incoming request ID that is accepted. Longer IDs are replaced. Defaults to 128.
+	MaxLength int
+	// Generator creates new request IDs. Defaults to 16 random bytes, hex encoded.
+	Generator func() string
+}
+
+// RequestID returns middleware that assigns an ID to every request. The ID is taken from the incoming
+// request header when present and valid, or generated otherwise. It is stored in the request context
+// and written to the response headers.
+//
+// Use RequestIDFromContext to read the ID, and NewRequestIDLogHandler so that log records written with a
+// request context carry the ID. RequestID should be registered before Logger and other middleware that log.
+//
+// Example:
+//
+//	router.Use(middleware.RequestID(nil))
+func RequestID(optsFunc func(opts *RequestIDOptions)) func(http.Handler) http.Handler {
+	opts := RequestIDOptions{
+		Header:        RequestIDHeader,
+		TrustIncoming: true,
+		MaxLength:     128,
+		Generator:     generateRequestID,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var id string
+			if opts.TrustIncoming {
+				id = r.Header.Get(opts.Header)
+				if !validRequestID(id, opts.MaxLength) {
+					id = ""
+				}
+			}
+			if id == "" {
+				id = opts.Generator()
+			}
+
+			w.Header().Set(opts.Header, id)
+			next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// WithRequestID returns a copy of ctx that carries the request ID. This is useful for propagating
+// a request ID to background work started by a request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or an empty string if there is none
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// RequestLogger returns a logger that includes the request ID of r with every record
+func RequestLogger(l *slog.Logger, r *http.Request) *slog.Logger {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		return l.With(slog.String(RequestIDLogKey, id))
+	}
+	return l
+}
+
+// requestIDLogHandler is a slog.Handler that adds the request ID from the record context
+type requestIDLogHandler struct {
+	slog.Handler
+}
+
+// NewRequestIDLogHandler wraps h so that every record logged with a context carrying a request ID
+// (e.g. logger.InfoContext(r.Context(), ...)) includes a request_id attribute.
+//
+// Example:
+//
+//	logger := slog.New(middleware.NewRequestIDLogHandler(slog.NewJSONHandler(os.Stdout, nil)))
+func NewRequestIDLogHandler(h slog.Handler) slog.Handler {
+	return &requestIDLogHandler{Handler: h}
+}
+
+// Handle adds the request ID attribute, if any, and passes the record to the wrapped handler
+func (h *requestIDLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		record.AddAttrs(slog.String(RequestIDLogKey, id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a new wrapped handler with the given attributes
+func (h *requestIDLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestIDLogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a new wrapped handler with the given group
+func (h *requestIDLogHandler) WithGroup(name string) slog.Handler {
+	return &requestIDLogHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// validRequestID reports whether an incoming request ID is safe to reuse. Only printable ASCII
+// without spaces is accepted so IDs cannot be used to inject content into logs or headers.
+func validRequestID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// generateRequestID returns 16 random bytes, hex encoded
+func generateRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,115 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/route/middleware"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		optsFunc func(opts *middleware.RequestIDOptions)
+		incoming string
+		wantID   string
+	}{
+		{
+			name:     "accepts incoming id",
+			incoming: "abc-123",
+			wantID:   "abc-123",
+		},
+		{
+			name:   "generates id",
+			wantID: "generated",
+		},
+		{
+			name:     "rejects unsafe incoming id",
+			incoming: "bad id\n",
+			wantID:   "generated",
+		},
+		{
+			name:     "rejects long incoming id",
+			incoming: strings.Repeat("a", 200),
+			wantID:   "generated",
+		},
+		{
+			name: "ignores incoming id when not trusted",
+			optsFunc: func(opts *middleware.RequestIDOptions) {
+				opts.TrustIncoming = false
+			},
+			incoming: "abc-123",
+			wantID:   "generated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := middleware.RequestID(func(opts *middleware.RequestIDOptions) {
+				opts.Generator = func() string { return "generated" }
+				if tt.optsFunc != nil {
+					tt.optsFunc(opts)
+				}
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = middleware.RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(middleware.RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantID, seen)
+			assert.Equal(t, tt.wantID, rec.Header().Get(middleware.RequestIDHeader))
+		})
+	}
+}
+
+func TestRequestID_DefaultGenerator(t *testing.T) {
+	handler := middleware.RequestID(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	ids := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		id := rec.Header().Get(middleware.RequestIDHeader)
+		assert.Len(t, id, 32)
+		ids[id] = true
+	}
+	assert.Len(t, ids, 3)
+}
+
+func TestRequestIDLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(middleware.NewRequestIDLogHandler(slog.NewJSONHandler(&buf, nil))).With("app", "test")
+
+	handler := middleware.RequestID(nil)(
+		middleware.Logger(logger, slog.LevelInfo)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger.InfoContext(r.Context(), "handling")
+				middleware.RequestLogger(slog.New(slog.NewJSONHandler(&buf, nil)), r).Info("explicit")
+			})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	for _, line := range lines {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, "req-1", record[middleware.RequestIDLogKey], line)
+	}
+}
@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/patrickward/hop/check"
+)
+
+// RouteSchema holds the JSON Schemas of a route's request and response bodies
+type RouteSchema struct {
+	// Request validates the request body. Requests that don't match are rejected with a 422.
+	Request *check.JSONSchema
+	// Response validates successful JSON responses when response validation is enabled. Mismatches are
+	// logged, not rejected.
+	Response *check.JSONSchema
+}
+
+// SchemaOptions configures the Schema middleware
+type SchemaOptions struct {
+	// Routes sets the schemas of individual routes, keyed by the matched route pattern, e.g. "POST /users".
+	// Routes not listed pass through unchecked.
+	Routes map[string]RouteSchema
+	// ValidateResponses enables checking responses against the Response schemas, logging a warning for
+	// every drift from the schema. It buffers a copy of the response, so it is usually enabled in
+	// development only.
+	ValidateResponses bool
+	// MaxBytes is the largest body validated, in bytes. Larger request bodies are rejected with a 413 and
+	// larger responses are not checked. Default is 1 MB.
+	MaxBytes int64
+	// Logger receives the response schema drift warnings. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Schema returns middleware that validates JSON request bodies, and optionally responses, against the
+// JSON Schemas registered for each route. Request bodies that don't match are rejected with a 422
+// Unprocessable Entity listing every problem:
+//
+//	{"error": "request body does not match schema", "errors": [{"path": "/email", "message": "is required"}]}
+//
+// Malformed JSON is rejected with a 400 and a non-JSON Content-Type with a 415. The body is restored after
+// validation, so handlers decode it as usual.
+//
+// Example:
+//
+//	router.Use(middleware.Schema(func(opts *middleware.SchemaOptions) {
+//		opts.Routes = map[string]middleware.RouteSchema{
+//			"POST /users": {Request: createUserSchema, Response: userSchema},
+//		}
+//		opts.ValidateResponses = cfg.IsDevelopment()
+//	}))
+func Schema(optsFunc func(opts *SchemaOptions)) func(http.Handler) http.Handler {
+	opts := SchemaOptions{
+		MaxBytes: 1 << 20,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			schema, ok := opts.Routes[r.Pattern]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if schema.Request != nil && !validateRequestBody(w, r, schema.Request, opts.MaxBytes) {
+				return
+			}
+
+			if schema.Response == nil || !opts.ValidateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sw := &schemaWriter{ResponseWriter: w, status: http.StatusOK, max: opts.MaxBytes}
+			next.ServeHTTP(sw, r)
+			sw.check(r, schema.Response, opts.Logger)
+		})
+	}
+}
+
+// validateRequestBody validates the body and restores it for the handler. It writes an error response and
+// returns false when the body is invalid.
+func validateRequestBody(w http.ResponseWriter, r *http.Request, schema *check.JSONSchema, maxBytes int64) bool {
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		writeSchemaError(w, http.StatusUnsupportedMediaType, "content type must be application/json", nil)
+		return false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			bodyTooLarge(w, r, tooLarge.Limit, nil)
+			return false
+		}
+		writeSchemaError(w, http.StatusBadRequest, "error reading request body", nil)
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := schema.ValidateJSON(body); err != nil {
+		var schemaErrs check.SchemaErrors
+		if errors.As(err, &schemaErrs) {
+			writeSchemaError(w, http.StatusUnprocessableEntity, "request body does not match schema", schemaErrs)
+		} else {
+			writeSchemaError(w, http.StatusBadRequest, "request body is not valid JSON", nil)
+		}
+		return false
+	}
+
+	return true
+}
+
+// writeSchemaError writes a JSON error response, with the schema errors when there are any
+func writeSchemaError(w http.ResponseWriter, status int, message string, errs check.SchemaErrors) {
+	body := map[string]any{"error": message}
+	if len(errs) > 0 {
+		body["errors"] = errs
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// isJSONContentType reports whether a Content-Type header is JSON
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// schemaWriter passes the response through, keeping a copy of the body for validation
+type schemaWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	max         int64
+	overflow    bool
+}
+
+func (sw *schemaWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.status = status
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *schemaWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	if !sw.overflow {
+		if int64(sw.body.Len()+len(b)) > sw.max {
+			sw.overflow = true
+			sw.body.Reset()
+		} else {
+			sw.body.Write(b)
+		}
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so http.ResponseController can reach it
+func (sw *schemaWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// check validates a successful JSON response and logs any drift from the schema
+func (sw *schemaWriter) check(r *http.Request, schema *check.JSONSchema, logger *slog.Logger) {
+	if sw.overflow || sw.status < 200 || sw.status >= 300 || sw.body.Len() == 0 {
+		return
+	}
+	if !isJSONContentType(sw.Header().Get("Content-Type")) {
+		return
+	}
+
+	if err := schema.ValidateJSON(sw.body.Bytes()); err != nil {
+		logger.Warn("response does not match schema",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", r.Pattern),
+			slog.String("error", err.Error()))
+	}
+}
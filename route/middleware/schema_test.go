@@ -0,0 +1,133 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/check"
+	"github.com/patrickward/hop/route/middleware"
+)
+
+func TestSchema(t *testing.T) {
+	userSchema := check.MustParseJSONSchema([]byte(`{
+		"type": "object",
+		"required": ["email"],
+		"properties": {
+			"email": {"type": "string", "format": "email"},
+			"age": {"type": "integer", "minimum": 0}
+		}
+	}`))
+	responseSchema := check.MustParseJSONSchema([]byte(`{"type": "object", "required": ["id"]}`))
+
+	var logs bytes.Buffer
+	validate := middleware.Schema(func(opts *middleware.SchemaOptions) {
+		opts.Routes = map[string]middleware.RouteSchema{
+			"POST /users": {Request: userSchema, Response: responseSchema},
+		}
+		opts.ValidateResponses = true
+		opts.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /users", validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var user map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if user["email"] == "drift@example.com" {
+			_, _ = io.WriteString(w, `{"name": "drift"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"id": 1}`)
+	})))
+	mux.Handle("POST /other", validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := []struct {
+		name         string
+		path         string
+		contentType  string
+		body         string
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			name:         "valid body reaches the handler",
+			path:         "/users",
+			contentType:  "application/json",
+			body:         `{"email": "ada@example.com", "age": 36}`,
+			expectStatus: http.StatusOK,
+			expectBody:   `{"id": 1}`,
+		},
+		{
+			name:         "schema mismatch",
+			path:         "/users",
+			contentType:  "application/json; charset=utf-8",
+			body:         `{"age": -1}`,
+			expectStatus: http.StatusUnprocessableEntity,
+			expectBody:   `{"error":"request body does not match schema","errors":[{"path":"/email","message":"is required"},{"path":"/age","message":"must be at least 0"}]}`,
+		},
+		{
+			name:         "malformed JSON",
+			path:         "/users",
+			contentType:  "application/json",
+			body:         `{"email":`,
+			expectStatus: http.StatusBadRequest,
+			expectBody:   `{"error":"request body is not valid JSON"}`,
+		},
+		{
+			name:         "wrong content type",
+			path:         "/users",
+			contentType:  "application/x-www-form-urlencoded",
+			body:         `email=ada@example.com`,
+			expectStatus: http.StatusUnsupportedMediaType,
+			expectBody:   `{"error":"content type must be application/json"}`,
+		},
+		{
+			name:         "routes without schemas pass through",
+			path:         "/other",
+			contentType:  "text/plain",
+			body:         `anything`,
+			expectStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+			assert.Equal(t, tt.expectBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+	assert.Empty(t, logs.String())
+
+	t.Run("logs response drift", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"email": "drift@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"name": "drift"}`, w.Body.String(), "the response is sent unchanged")
+		assert.Contains(t, logs.String(), "response does not match schema")
+		assert.Contains(t, logs.String(), "/id: is required")
+	})
+}
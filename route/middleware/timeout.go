@@ -1,10 +1,18 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
+	"log/slog"
+	"net"
 	"net/http"
+	"sync"
 	"time"
+
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/render"
 )
 
 // Timeout returns middleware that cancels requests after a timeout
@@ -35,3 +43,235 @@ func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// RouteTimeoutOptions configures the RouteTimeout middleware
+type RouteTimeoutOptions struct {
+	// Timeout applies to routes not listed in Routes. Default is 30 seconds; 0 or less disables it.
+	Timeout time.Duration
+	// Routes sets the timeout of individual routes, keyed by route pattern, e.g. "GET /reports/{id}".
+	// A timeout of 0 or less disables it for the route, e.g. for a long-lived stream.
+	Routes map[string]time.Duration
+	// Status is the status of timed out responses, http.StatusGatewayTimeout (default) or
+	// http.StatusServiceUnavailable
+	Status int
+	// Templates, when set, is used to render the 504 or 503 system error template for HTML requests
+	Templates *render.TemplateManager
+	// Metrics, when set, has its "http_timeouts_total" counter incremented for every timed out request
+	Metrics pulse.Collector
+	// Logger receives timed out requests. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// RouteTimeout returns middleware that limits how long handlers may run. When a handler exceeds its
+// timeout, its request context is canceled and, if it has not started writing a response, a 504 (or 503)
+// error is sent as JSON, the system error template or plain text. Unlike http.TimeoutHandler, responses
+// are not buffered, so streaming handlers keep working: once a handler has started writing, a timeout
+// only cancels its context and the middleware waits for it to return.
+//
+// Example:
+//
+//	router.Use(middleware.RouteTimeout(func(opts *middleware.RouteTimeoutOptions) {
+//		opts.Timeout = 10 * time.Second
+//		opts.Routes = map[string]time.Duration{
+//			"GET /reports/{id}": time.Minute,
+//			"GET /events":       0,
+//		}
+//		opts.Templates = tm
+//		opts.Metrics = collector
+//	}))
+func RouteTimeout(optsFunc func(opts *RouteTimeoutOptions)) func(http.Handler) http.Handler {
+	opts := RouteTimeoutOptions{
+		Timeout: 30 * time.Second,
+		Status:  http.StatusGatewayTimeout,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Status != http.StatusServiceUnavailable {
+		opts.Status = http.StatusGatewayTimeout
+	}
+
+	var timeouts pulse.Counter
+	if opts.Metrics != nil {
+		timeouts = opts.Metrics.Counter("http_timeouts_total")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := opts.Timeout
+			if t, ok := opts.Routes[r.Pattern]; ok {
+				timeout = t
+			}
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			deadline, _ := ctx.Deadline()
+			tw := &timeoutWriter{w: w, header: make(http.Header), deadline: deadline}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return
+			case p := <-panicked:
+				// Re-panic in the server's goroutine, so recovery middleware can handle it
+				panic(p)
+			case <-ctx.Done():
+			}
+
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				// The client went away; wait for the handler so it doesn't outlive the request
+				<-done
+				return
+			}
+
+			if timeouts != nil {
+				timeouts.Inc()
+			}
+			opts.Logger.Warn("request timed out",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Duration("timeout", timeout))
+
+			if !tw.timeout() {
+				// The response has started, so only the context is canceled. Streaming handlers should
+				// return once they notice.
+				select {
+				case <-done:
+				case p := <-panicked:
+					panic(p)
+				}
+				return
+			}
+
+			switch {
+			case wantsJSON(r):
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(opts.Status)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "request timed out"})
+			case opts.Templates != nil && opts.Status == http.StatusServiceUnavailable:
+				opts.Templates.NewResponse().RenderMaintenance(w, r)
+			case opts.Templates != nil:
+				opts.Templates.NewResponse().RenderGatewayTimeout(w, r)
+			default:
+				http.Error(w, http.StatusText(opts.Status), opts.Status)
+			}
+		})
+	}
+}
+
+// timeoutWriter passes writes through to the response until the request times out. Headers are kept
+// apart until the response starts, so the timeout response can be written without racing the handler.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	w           http.ResponseWriter
+	header      http.Header
+	deadline    time.Time
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(status)
+}
+
+// writeHeader starts the response. The caller must hold the lock.
+func (tw *timeoutWriter) writeHeader(status int) {
+	if tw.wroteHeader || tw.expired() {
+		return
+	}
+	tw.wroteHeader = true
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(http.StatusOK)
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.w.Write(b)
+}
+
+// Flush sends buffered data to the client, for streaming handlers
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(http.StatusOK)
+	if tw.timedOut {
+		return
+	}
+	_ = http.NewResponseController(tw.w).Flush()
+}
+
+// Hijack takes over the connection, e.g. for a WebSocket upgrade. A hijacked response counts as started,
+// so a timeout only cancels the handler's context.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, rw, err := http.NewResponseController(tw.w).Hijack()
+	if err == nil {
+		tw.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so http.ResponseController can reach it, e.g. to
+// set write deadlines
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// expired marks the response as timed out if the deadline passed before it started. A handler that notices
+// its context is done can write before the middleware handles the timeout, so the deadline is checked
+// here rather than relying on the middleware getting there first. The caller must hold the lock.
+func (tw *timeoutWriter) expired() bool {
+	if !tw.timedOut && !tw.wroteHeader && !time.Now().Before(tw.deadline) {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+// timeout marks the response as timed out and reports whether the handler had not started it yet
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.wroteHeader {
+		return false
+	}
+	tw.timedOut = true
+	return true
+}
@@ -0,0 +1,144 @@
+package middleware_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/route/middleware"
+)
+
+// counterCollector is a pulse.Collector that only supports counters
+type counterCollector struct {
+	pulse.Collector
+	counters map[string]*testCounter
+}
+
+type testCounter struct{ v atomic.Int64 }
+
+func (c *testCounter) Inc()              { c.v.Add(1) }
+func (c *testCounter) Add(delta float64) { c.v.Add(int64(delta)) }
+func (c *testCounter) Value() float64    { return float64(c.v.Load()) }
+
+func (c *counterCollector) Counter(name string) pulse.Counter {
+	if c.counters[name] == nil {
+		c.counters[name] = &testCounter{}
+	}
+	return c.counters[name]
+}
+
+func TestRouteTimeout(t *testing.T) {
+	metrics := &counterCollector{counters: map[string]*testCounter{}}
+	canceled := make(chan struct{}, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		canceled <- struct{}{}
+		_, err := w.Write([]byte("too late"))
+		assert.ErrorIs(t, err, http.ErrHandlerTimeout)
+	})
+	mux.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Fast", "yes")
+		_, _ = w.Write([]byte("done"))
+	})
+	mux.HandleFunc("GET /stream", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("chunk"))
+		http.NewResponseController(w).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("GET /report", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		_, _ = w.Write([]byte("report"))
+	})
+
+	timeout := middleware.RouteTimeout(func(opts *middleware.RouteTimeoutOptions) {
+		opts.Timeout = 10 * time.Millisecond
+		opts.Routes = map[string]time.Duration{"GET /report": time.Second}
+		opts.Metrics = metrics
+		opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	})
+
+	// Route patterns are only known once the mux has matched the request
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		r.Pattern = pattern
+		timeout(h).ServeHTTP(w, r)
+	})
+
+	serve := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/fast", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "done", rec.Body.String())
+	assert.Equal(t, "yes", rec.Header().Get("X-Fast"))
+
+	rec = serve("/slow", nil)
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Gateway Timeout")
+	<-canceled
+
+	rec = serve("/slow", map[string]string{"Accept": "application/json"})
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.JSONEq(t, `{"error":"request timed out"}`, rec.Body.String())
+	<-canceled
+
+	// Streaming responses are not replaced; the handler's context is canceled instead
+	rec = serve("/stream", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "chunk", rec.Body.String())
+	assert.True(t, rec.Flushed)
+
+	// Per-route timeouts override the default
+	rec = serve("/report", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "report", rec.Body.String())
+
+	assert.Equal(t, float64(3), metrics.Counter("http_timeouts_total").Value())
+}
+
+func TestRouteTimeout_ResponseController(t *testing.T) {
+	timeout := middleware.RouteTimeout(func(opts *middleware.RouteTimeoutOptions) {
+		opts.Timeout = time.Second
+		opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	})
+	srv := httptest.NewServer(timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		if !assert.NoError(t, rc.SetWriteDeadline(time.Now().Add(time.Second))) {
+			return
+		}
+
+		conn, buf, err := rc.Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		_ = buf.Flush()
+	})))
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hijacked", string(body))
+}
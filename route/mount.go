@@ -0,0 +1,92 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mount is a Mux mounted under a prefix of another Mux
+type mount struct {
+	prefix string
+	mux    *Mux
+}
+
+// Mount serves the sub mux under the given prefix, so modules can build their own Mux, with its own
+// middleware and routes, and the app can mount it. The prefix is stripped from the request path before the
+// sub mux handles it: with a sub mux route "GET /users/{id}" mounted at "/admin", a request for
+// /admin/users/42 matches it. The parent's middleware runs before the sub mux's own middleware.
+//
+// The sub mux's routes are included in ListRoutes, and Path, PathWithParams, VerifyRoute and URL fall back
+// to them with the prefix added, including routes registered after mounting. It panics if the prefix
+// doesn't start with a slash.
+//
+//	admin := route.New(requireAdmin)
+//	admin.Named("admin.users", "GET /users", usersHandler)
+//	router.Mount("/admin", admin)
+//	router.URL("admin.users") // "/admin/users"
+func (m *Mux) Mount(prefix string, sub *Mux) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !strings.HasPrefix(prefix, "/") {
+		panic(fmt.Sprintf("route: mount prefix %q must start with /", prefix))
+	}
+
+	h := m.middleware.Then(mountHandler(prefix, sub))
+	m.ServeMux.Handle(prefix, h)
+	m.ServeMux.Handle(prefix+"/", h)
+
+	m.registry.mu.Lock()
+	defer m.registry.mu.Unlock()
+	m.mounts = append(m.mounts, mount{prefix: prefix, mux: sub})
+}
+
+// mountHandler strips the prefix from the request path and passes the request to the sub mux
+func mountHandler(prefix string, sub *Mux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = stripMountPrefix(r.URL.Path, prefix)
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = stripMountPrefix(r.URL.RawPath, prefix)
+		}
+
+		sub.ServeHTTP(w, r2)
+	})
+}
+
+// stripMountPrefix removes the mount prefix from a path; the mount point itself becomes "/"
+func stripMountPrefix(p, prefix string) string {
+	p = strings.TrimPrefix(p, prefix)
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+// getMounts returns a copy of the mounted muxes
+func (m *Mux) getMounts() []mount {
+	m.registry.mu.RLock()
+	defer m.registry.mu.RUnlock()
+	return append([]mount(nil), m.mounts...)
+}
+
+// mountFor returns the mount a pattern falls under and the pattern relative to it
+func (m *Mux) mountFor(pattern string) (mount, string, bool) {
+	for _, mt := range m.getMounts() {
+		if pattern == mt.prefix || strings.HasPrefix(pattern, mt.prefix+"/") {
+			return mt, stripMountPrefix(pattern, mt.prefix), true
+		}
+	}
+	return mount{}, "", false
+}
+
+// joinMountPrefix adds a mount prefix to a path of the sub mux
+func joinMountPrefix(prefix, p string) string {
+	if p == "/" {
+		return prefix + "/"
+	}
+	return prefix + p
+}
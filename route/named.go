@@ -0,0 +1,171 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// namedRoutes maps route names to their path patterns, e.g. "user.show" to "/users/{id}"
+type namedRoutes map[string]string
+
+// Named registers a handler like HandleFunc and gives the route a name, so URLs can be generated with URL
+// instead of hard-coding paths. It panics if the name is already in use.
+//
+//	router.Named("user.show", "GET /users/{id}", showUser)
+//	router.URL("user.show", "id", 42) // "/users/42"
+func (m *Mux) Named(name, pattern string, handler http.Handler, middleware ...Middleware) {
+	m.nameRoute(name, pattern)
+	m.handle(pattern, handler, middleware...)
+}
+
+// Named registers a handler within the group like HandleFunc and gives the route a name. The name refers to
+// the full path, including the group prefix. It panics if the name is already in use.
+func (g *Group) Named(name, pattern string, handler http.Handler, middleware ...Middleware) {
+	if !g.enabled() {
+		return
+	}
+
+	method, p := splitPattern(pattern)
+	full := path.Join(g.prefix, p)
+	if method != "" {
+		full = method + " " + full
+	}
+	g.mux.nameRoute(name, full)
+	g.handle(pattern, handler, middleware...)
+}
+
+// URL returns the path of the named route. Params are name/value pairs that fill in the wildcards of the
+// route pattern; pairs that don't match a wildcard are added to the query string. Values are formatted
+// with fmt.Sprint and escaped.
+//
+//	router.URL("user.show", "id", 42)            // "/users/42"
+//	router.URL("user.list", "page", 2)           // "/users?page=2"
+//	router.URL("docs.page", "path", "a/b c")     // "/docs/a/b%20c" for "/docs/{path...}"
+func (m *Mux) URL(name string, params ...any) (string, error) {
+	m.registry.mu.RLock()
+	pattern, ok := m.names[name]
+	m.registry.mu.RUnlock()
+	if !ok {
+		// Fall back to the named routes of mounted muxes
+		for _, mt := range m.getMounts() {
+			if u, err := mt.mux.URL(name, params...); err == nil {
+				return joinMountPrefix(mt.prefix, u), nil
+			} else if mt.mux.hasName(name) {
+				return "", err
+			}
+		}
+		return "", fmt.Errorf("route name %q not found", name)
+	}
+
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("route %q: params must be name/value pairs", name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	order := make([]string, 0, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("route %q: param name %v is not a string", name, params[i])
+		}
+		if _, exists := values[key]; !exists {
+			order = append(order, key)
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+
+		wildcard := strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		if wildcard == "$" {
+			segments[i] = ""
+			continue
+		}
+
+		rest := strings.HasSuffix(wildcard, "...")
+		wildcard = strings.TrimSuffix(wildcard, "...")
+		value, ok := values[wildcard]
+		if !ok {
+			return "", fmt.Errorf("route %q: missing parameter %q", name, wildcard)
+		}
+		delete(values, wildcard)
+
+		if rest {
+			parts := strings.Split(value, "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		} else {
+			segments[i] = url.PathEscape(value)
+		}
+	}
+
+	u := strings.Join(segments, "/")
+	if len(values) > 0 {
+		query := url.Values{}
+		for _, key := range order {
+			if value, ok := values[key]; ok {
+				query.Set(key, value)
+			}
+		}
+		u += "?" + query.Encode()
+	}
+
+	return u, nil
+}
+
+// MustURL is like URL but panics if the route doesn't exist or a parameter is missing
+func (m *Mux) MustURL(name string, params ...any) string {
+	u, err := m.URL(name, params...)
+	if err != nil {
+		panic(fmt.Sprintf("failed to build URL: %v", err))
+	}
+	return u
+}
+
+// hasName reports whether a route with the given name is registered with the mux or a mounted mux
+func (m *Mux) hasName(name string) bool {
+	m.registry.mu.RLock()
+	_, ok := m.names[name]
+	m.registry.mu.RUnlock()
+	if ok {
+		return true
+	}
+	for _, mt := range m.getMounts() {
+		if mt.mux.hasName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameRoute records the path of a named route
+func (m *Mux) nameRoute(name, pattern string) {
+	_, p := splitPattern(pattern)
+
+	m.registry.mu.Lock()
+	defer m.registry.mu.Unlock()
+
+	if _, exists := m.names[name]; exists {
+		panic(fmt.Sprintf("route: name %q is already registered", name))
+	}
+	m.names[name] = p
+}
+
+// splitPattern splits a pattern into its method, if any, and its path
+func splitPattern(pattern string) (string, string) {
+	if len(pattern) > 0 && pattern[0] != '/' {
+		if method, p, ok := strings.Cut(pattern, " "); ok {
+			return method, strings.TrimSpace(p)
+		}
+	}
+	return "", pattern
+}
@@ -3,6 +3,7 @@ package route
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"path/filepath"
@@ -20,6 +21,15 @@ type Mux struct {
 	middleware      Chain
 	registry        *routeRegistry
 	notFoundHandler http.Handler
+	// methodNotAllowedHandler answers requests for a path whose routes don't allow the request method
+	methodNotAllowedHandler http.Handler
+	environment             string
+	flagEnabled             func(flag string) bool
+	names                   namedRoutes
+	// canonical configures the canonical path redirects, when enabled with Canonicalize
+	canonical *CanonicalOptions
+	// mounts are the muxes mounted with Mount
+	mounts []mount
 }
 
 // New creates a new Mux instance
@@ -28,6 +38,7 @@ func New(middleware ...Middleware) *Mux {
 		ServeMux:   http.NewServeMux(),
 		middleware: NewChain(middleware...),
 		registry:   newRouteRegistry(),
+		names:      make(namedRoutes),
 	}
 
 	// Register the default route to handle OPTIONS and NotFound
@@ -41,13 +52,32 @@ func (m *Mux) Use(middleware ...Middleware) {
 	m.middleware = m.middleware.Append(middleware...)
 }
 
+// SetEnvironment sets the environment, e.g. "development" or "production", used by Group.OnlyIn. It must be
+// called before routes are registered.
+func (m *Mux) SetEnvironment(env string) {
+	m.environment = env
+}
+
+// Environment returns the environment set with SetEnvironment
+func (m *Mux) Environment() string {
+	return m.environment
+}
+
+// SetFlags sets the function that reports whether a feature flag is enabled, used by Group.IfFlag. It must
+// be called before routes are registered.
+func (m *Mux) SetFlags(enabled func(flag string) bool) {
+	m.flagEnabled = enabled
+}
+
 // PrefixGroup creates a new route group with the given prefix and applies the given group configuration function.
+// The group's routes run the mux middleware, including middleware added after the group was created, followed by
+// the group middleware. An independent group doesn't run the mux middleware.
 func (m *Mux) PrefixGroup(prefix string, group GroupFunc) *Group {
 	subGroup := &Group{
 		mux:        m,
 		prefix:     prefix,
-		middleware: m.middleware,
-		parent:     nil, // Root group has no parent
+		middleware: NewChain(), // The mux middleware is added when routes are registered
+		parent:     nil,        // Root group has no parent
 	}
 
 	if group != nil {
@@ -62,6 +92,31 @@ func (m *Mux) Group(group GroupFunc) *Group {
 	return m.PrefixGroup("", group)
 }
 
+// Host creates a route group whose routes only match requests for the given host, e.g. "admin.example.com",
+// so one app can serve several subdomains with separate routes and middleware. Routes registered for a
+// host take precedence over routes for any host with the same path. The port of the request is ignored.
+//
+//	admin := router.Host("admin.example.com")
+//	admin.Use(requireAdmin)
+//	admin.Get("/{$}", dashboardHandler)
+func (m *Mux) Host(host string) *Group {
+	return &Group{
+		mux:        m,
+		host:       strings.ToLower(host),
+		middleware: NewChain(), // The mux middleware is added when routes are registered
+	}
+}
+
+// With returns an inline group without a prefix whose routes use the given middleware in addition to the
+// mux middleware. It protects individual routes without creating a group for each of them.
+//
+//	router.With(auth).Get("/account", accountHandler)
+func (m *Mux) With(middleware ...Middleware) *Group {
+	return m.Group(func(g *Group) {
+		g.Use(middleware...)
+	})
+}
+
 // Home registers a handler for the root path
 func (m *Mux) Home(handler http.Handler) {
 	m.handle("/{$}", handler)
@@ -72,8 +127,15 @@ func (m *Mux) NotFound(handler http.Handler) {
 	m.notFoundHandler = handler
 }
 
-// handle registers a handler with middleware
-func (m *Mux) handle(pattern string, handler http.Handler) {
+// MethodNotAllowed registers a handler for requests to a path whose routes don't allow the request
+// method. The Allow header listing the allowed methods is set before the handler is called. By default,
+// a plain 405 Method Not Allowed response is written.
+func (m *Mux) MethodNotAllowed(handler http.Handler) {
+	m.methodNotAllowedHandler = handler
+}
+
+// handle registers a handler with the mux middleware, followed by any route middleware
+func (m *Mux) handle(pattern string, handler http.Handler, middleware ...Middleware) {
 	// Extract method if present
 	var method string
 	if len(pattern) > 0 && pattern[0] != '/' {
@@ -93,7 +155,7 @@ func (m *Mux) handle(pattern string, handler http.Handler) {
 	}
 
 	// Apply the middleware chain
-	h := m.middleware.Then(handler)
+	h := m.middleware.Append(middleware...).Then(handler)
 
 	// Register the handler
 	m.ServeMux.Handle(pattern, h)
@@ -109,16 +171,43 @@ func (m *Mux) handleNotFound(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
+func (m *Mux) handleMethodNotAllowed(w http.ResponseWriter, r *http.Request, methods []string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	if m.methodNotAllowedHandler != nil {
+		// Wrap the method not allowed handler with the middleware chain
+		h := m.middleware.Then(m.methodNotAllowedHandler)
+		h.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
 func (m *Mux) handleOptions(w http.ResponseWriter, r *http.Request) {
-	// Only handle OPTIONS requests, anything else is a 404
+	// Only handle OPTIONS requests, anything else is a 405 if the path has routes for other methods,
+	// otherwise a 404
 	if r.Method != http.MethodOptions {
+		if methods := m.allowedMethods(r); len(methods) > 0 {
+			m.handleMethodNotAllowed(w, r, methods)
+			return
+		}
 		m.handleNotFound(w, r)
 		return
 	}
 
-	methods := m.registry.getAllowedMethods(r.URL.Path)
+	// Run the middleware chain so middleware such as CORS can answer preflight requests
+	h := m.middleware.Then(http.HandlerFunc(m.serveOptions))
+	h.ServeHTTP(w, r)
+}
+
+// serveOptions responds to OPTIONS requests with the methods allowed for the path
+func (m *Mux) serveOptions(w http.ResponseWriter, r *http.Request) {
+	methods := m.allowedMethods(r)
 	if len(methods) == 0 {
-		m.handleNotFound(w, r)
+		if m.notFoundHandler != nil {
+			m.notFoundHandler.ServeHTTP(w, r)
+			return
+		}
+		http.NotFound(w, r)
 		return
 	}
 
@@ -126,44 +215,83 @@ func (m *Mux) handleOptions(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// probeMethods are the methods checked against the routing table for paths not in the route registry
+var probeMethods = []string{
+	http.MethodDelete, http.MethodGet, http.MethodHead, http.MethodOptions,
+	http.MethodPatch, http.MethodPost, http.MethodPut,
+}
+
+// allowedMethods returns the methods the routes for the request's host and path allow, sorted. Paths
+// are looked up in the route registry first; paths matching routes with wildcards, which the registry
+// only knows by their pattern, are found by matching the request against the routing table for each
+// method.
+func (m *Mux) allowedMethods(r *http.Request) []string {
+	if methods := m.registry.getAllowedMethods(requestHost(r) + r.URL.Path); len(methods) > 0 {
+		return methods
+	}
+	if methods := m.registry.getAllowedMethods(r.URL.Path); len(methods) > 0 {
+		return methods
+	}
+
+	var methods []string
+	probe := *r
+	for _, method := range probeMethods {
+		probe.Method = method
+		// The catch-all "/" pattern only handles OPTIONS and unmatched requests
+		if _, pattern := m.ServeMux.Handler(&probe); pattern != "" && pattern != "/" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// requestHost returns the lowercased host of the request without its port
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
 // HandleFunc registers a handler without method restrictions
-func (m *Mux) HandleFunc(pattern string, handler http.Handler) {
-	m.handle(pattern, handler)
+func (m *Mux) HandleFunc(pattern string, handler http.Handler, middleware ...Middleware) {
+	m.handle(pattern, handler, middleware...)
 }
 
 // Get registers a GET handler
-func (m *Mux) Get(pattern string, handler http.Handler) {
-	m.handle("GET "+pattern, handler)
+func (m *Mux) Get(pattern string, handler http.Handler, middleware ...Middleware) {
+	m.handle("GET "+pattern, handler, middleware...)
 }
 
 // Post registers a POST handler
-func (m *Mux) Post(pattern string, handler http.Handler) {
-	m.handle("POST "+pattern, handler)
+func (m *Mux) Post(pattern string, handler http.Handler, middleware ...Middleware) {
+	m.handle("POST "+pattern, handler, middleware...)
 }
 
 // Put registers a PUT handler
-func (m *Mux) Put(pattern string, handler http.Handler) {
-	m.handle("PUT "+pattern, handler)
+func (m *Mux) Put(pattern string, handler http.Handler, middleware ...Middleware) {
+	m.handle("PUT "+pattern, handler, middleware...)
 }
 
 // Delete registers a DELETE handler
-func (m *Mux) Delete(pattern string, handler http.Handler) {
-	m.handle("DELETE "+pattern, handler)
+func (m *Mux) Delete(pattern string, handler http.Handler, middleware ...Middleware) {
+	m.handle("DELETE "+pattern, handler, middleware...)
 }
 
 // Patch registers a PATCH handler
-func (m *Mux) Patch(pattern string, handler http.Handler) {
-	m.handle("PATCH "+pattern, handler)
+func (m *Mux) Patch(pattern string, handler http.Handler, middleware ...Middleware) {
+	m.handle("PATCH "+pattern, handler, middleware...)
 }
 
 // Options registers an OPTIONS handler
-func (m *Mux) Options(pattern string, handler http.Handler) {
-	m.handle("OPTIONS "+pattern, handler)
+func (m *Mux) Options(pattern string, handler http.Handler, middleware ...Middleware) {
+	m.handle("OPTIONS "+pattern, handler, middleware...)
 }
 
 // Head registers a HEAD handler
-func (m *Mux) Head(pattern string, handler http.Handler) {
-	m.handle("HEAD "+pattern, handler)
+func (m *Mux) Head(pattern string, handler http.Handler, middleware ...Middleware) {
+	m.handle("HEAD "+pattern, handler, middleware...)
 }
 
 type ListInfo struct {
@@ -191,6 +319,14 @@ func (m *Mux) ListRoutes() []ListInfo {
 		})
 	}
 
+	// Include the routes of mounted muxes under their prefix
+	for _, mt := range m.getMounts() {
+		for _, info := range mt.mux.ListRoutes() {
+			info.Pattern = joinMountPrefix(mt.prefix, info.Pattern)
+			list = append(list, info)
+		}
+	}
+
 	return list
 }
 
@@ -216,8 +352,11 @@ func (m *Mux) DumpRoutes() (string, error) {
 //   - pattern must contain the wildcard pattern {file...} (e.g. "/static/{file...}")
 //   - fs cannot be nil
 //
+// The optional middleware wraps the file server only, e.g. to set cache headers. The router's own
+// middleware is not applied.
+//
 // Returns an error if the pattern is invalid or missing the {file...} suffix.
-func (m *Mux) ServeDirectory(pattern string, fs http.FileSystem) error {
+func (m *Mux) ServeDirectory(pattern string, fs http.FileSystem, middleware ...Middleware) error {
 	if fs == nil {
 		return fmt.Errorf("filesystem cannot be nil")
 	}
@@ -227,7 +366,7 @@ func (m *Mux) ServeDirectory(pattern string, fs http.FileSystem) error {
 	}
 
 	fileServer := http.FileServer(fs)
-	m.ServeMux.Handle(pattern, fileServer)
+	m.ServeMux.Handle(pattern, NewChain(middleware...).Then(fileServer))
 	return nil
 }
 
@@ -424,6 +563,13 @@ func (m *Mux) ServeFileFrom(urlPath string, fs http.FileSystem, filePath string)
 func (m *Mux) Path(pattern string) (string, error) {
 	route, exists := m.registry.routes[cleanPattern(pattern)]
 	if !exists {
+		if mt, rel, ok := m.mountFor(pattern); ok {
+			p, err := mt.mux.Path(rel)
+			if err != nil {
+				return "", err
+			}
+			return joinMountPrefix(mt.prefix, p), nil
+		}
 		return "", fmt.Errorf("route pattern %q not found", pattern)
 	}
 
@@ -448,6 +594,13 @@ func (m *Mux) MustPath(pattern string) string {
 func (m *Mux) PathWithParams(pattern string, params map[string]string) (string, error) {
 	route, exists := m.registry.routes[cleanPattern(pattern)]
 	if !exists {
+		if mt, rel, ok := m.mountFor(pattern); ok {
+			p, err := mt.mux.PathWithParams(rel, params)
+			if err != nil {
+				return "", err
+			}
+			return joinMountPrefix(mt.prefix, p), nil
+		}
 		return "", fmt.Errorf("route pattern %q not found", pattern)
 	}
 	return route.BuildPath(params)
@@ -467,6 +620,9 @@ func (m *Mux) MustPathWithParams(pattern string, params map[string]string) strin
 func (m *Mux) VerifyRoute(pattern, method string) bool {
 	route, exists := m.registry.routes[cleanPattern(pattern)]
 	if !exists {
+		if mt, rel, ok := m.mountFor(pattern); ok {
+			return mt.mux.VerifyRoute(rel, method)
+		}
 		return false
 	}
 	_, methodAllowed := route.Methods[method]
@@ -250,6 +250,53 @@ func TestMux_PathWithParams(t *testing.T) {
 	assert.Error(t, err, "Should return an error for extra parameter")
 }
 
+func TestMux_Named(t *testing.T) {
+	mux := route.New()
+
+	mux.Named("home", "GET /{$}", emptyHandler())
+	mux.Named("user.show", "GET /users/{id}", emptyHandler())
+	mux.PrefixGroup("/docs", func(g *route.Group) {
+		g.Named("docs.page", "GET /{path...}", emptyHandler())
+	})
+
+	tests := []struct {
+		name      string
+		route     string
+		params    []any
+		expectURL string
+		expectErr string
+	}{
+		{name: "exact root", route: "home", expectURL: "/"},
+		{name: "wildcard", route: "user.show", params: []any{"id", 42}, expectURL: "/users/42"},
+		{name: "escaped wildcard", route: "user.show", params: []any{"id", "a b/c"}, expectURL: "/users/a%20b%2Fc"},
+		{name: "extra params become the query", route: "user.show", params: []any{"id", 1, "tab", "posts", "page", 2}, expectURL: "/users/1?page=2&tab=posts"},
+		{name: "remaining path in group", route: "docs.page", params: []any{"path", "guide/intro"}, expectURL: "/docs/guide/intro"},
+		{name: "missing param", route: "user.show", expectErr: `missing parameter "id"`},
+		{name: "odd params", route: "user.show", params: []any{"id"}, expectErr: "name/value pairs"},
+		{name: "unknown route", route: "user.edit", expectErr: "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := mux.URL(tt.route, tt.params...)
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectURL, u)
+		})
+	}
+
+	// Named routes are served like any other route
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, mux.MustURL("user.show", "id", 7), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Panics(t, func() { mux.Named("home", "GET /home", emptyHandler()) })
+	assert.Panics(t, func() { mux.MustURL("user.show") })
+}
+
 func TestMux_MustPathWithParams(t *testing.T) {
 	mux := route.New()
 
@@ -280,3 +327,179 @@ func parseAllowHeader(allow string) []string {
 	sort.Strings(methods)
 	return methods
 }
+
+func TestMux_MethodNotAllowed(t *testing.T) {
+	mux := route.New()
+	mux.Get("/users", emptyHandler())
+	mux.Post("/users", emptyHandler())
+	mux.Delete("/users/{id}", emptyHandler())
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		expectCode  int
+		expectAllow string
+	}{
+		{name: "registered path", method: http.MethodPut, path: "/users", expectCode: http.StatusMethodNotAllowed, expectAllow: "GET, HEAD, POST"},
+		{name: "wildcard path", method: http.MethodGet, path: "/users/42", expectCode: http.StatusMethodNotAllowed, expectAllow: "DELETE"},
+		{name: "allowed method", method: http.MethodDelete, path: "/users/42", expectCode: http.StatusOK},
+		{name: "unknown path", method: http.MethodGet, path: "/posts", expectCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Equal(t, tt.expectAllow, w.Header().Get("Allow"))
+		})
+	}
+
+	t.Run("custom handler", func(t *testing.T) {
+		mux.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_, _ = w.Write([]byte("allowed: " + w.Header().Get("Allow")))
+		}))
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/users", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "allowed: GET, HEAD, POST", w.Body.String())
+	})
+}
+
+func TestMux_Canonicalize(t *testing.T) {
+	mux := route.New()
+	mux.Get("/about", emptyHandler())
+	mux.Post("/users", emptyHandler())
+	mux.Get("/users/{name}", emptyHandler())
+	mux.Get("/static/", emptyHandler())
+	mux.Canonicalize(func(opts *route.CanonicalOptions) {
+		opts.TrailingSlash = route.TrailingSlashStrip
+		opts.Lowercase = true
+		opts.CleanSlashes = true
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectCode     int
+		expectLocation string
+	}{
+		{name: "canonical path", method: http.MethodGet, path: "/about", expectCode: http.StatusOK},
+		{name: "trailing slash", method: http.MethodGet, path: "/about/", expectCode: http.StatusMovedPermanently, expectLocation: "/about"},
+		{name: "uppercase with query", method: http.MethodGet, path: "/About?ref=nav", expectCode: http.StatusMovedPermanently, expectLocation: "/about?ref=nav"},
+		{name: "duplicate slashes", method: http.MethodGet, path: "//about", expectCode: http.StatusMovedPermanently, expectLocation: "/about"},
+		{name: "post keeps method", method: http.MethodPost, path: "/users/", expectCode: http.StatusPermanentRedirect, expectLocation: "/users"},
+		{name: "wildcard value keeps case", method: http.MethodGet, path: "/users/Ada", expectCode: http.StatusOK},
+		{name: "route with trailing slash", method: http.MethodGet, path: "/static/", expectCode: http.StatusOK},
+		{name: "no canonical route", method: http.MethodGet, path: "/Missing/", expectCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+		})
+	}
+
+	t.Run("add trailing slash", func(t *testing.T) {
+		mux := route.New()
+		mux.Get("/docs/", emptyHandler())
+		mux.Get("/app.css", emptyHandler())
+		mux.Canonicalize(func(opts *route.CanonicalOptions) {
+			opts.TrailingSlash = route.TrailingSlashAdd
+		})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app.css", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/docs", nil))
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "/docs/", w.Header().Get("Location"))
+	})
+}
+
+func TestMux_Mount(t *testing.T) {
+	var order []string
+	record := func(name string) route.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	admin := route.New(record("admin"))
+	admin.Get("/{$}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("dashboard"))
+	}))
+	admin.Named("admin.user", "GET /users/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("user " + r.PathValue("id") + " at " + r.URL.Path))
+	}))
+
+	mux := route.New(record("app"))
+	mux.Get("/about", emptyHandler())
+	mux.Mount("/admin", admin)
+	admin.Post("/users", emptyHandler())
+
+	t.Run("serves sub mux routes under the prefix", func(t *testing.T) {
+		order = nil
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users/42", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user 42 at /users/42", w.Body.String())
+		assert.Equal(t, []string{"app", "admin"}, order)
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		assert.Equal(t, "dashboard", w.Body.String())
+	})
+
+	t.Run("sub mux answers unmatched paths", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/users", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "POST", w.Header().Get("Allow"))
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/missing", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("merges route helpers", func(t *testing.T) {
+		var patterns []string
+		for _, info := range mux.ListRoutes() {
+			patterns = append(patterns, info.Pattern)
+		}
+		sort.Strings(patterns)
+		assert.Equal(t, []string{"/about", "/admin/users", "/admin/users/{id}", "/admin/{$}"}, patterns)
+
+		assert.True(t, mux.VerifyRoute("/admin/users", http.MethodPost))
+		assert.False(t, mux.VerifyRoute("/admin/users", http.MethodGet))
+
+		path, err := mux.Path("/admin/users")
+		require.NoError(t, err)
+		assert.Equal(t, "/admin/users", path)
+
+		u, err := mux.URL("admin.user", "id", 7)
+		require.NoError(t, err)
+		assert.Equal(t, "/admin/users/7", u)
+
+		_, err = mux.URL("admin.user")
+		assert.ErrorContains(t, err, "missing parameter")
+	})
+
+	t.Run("requires a rooted prefix", func(t *testing.T) {
+		assert.Panics(t, func() { mux.Mount("admin", route.New()) })
+	})
+}
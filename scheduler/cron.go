@@ -0,0 +1,251 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes when a job runs next
+type Schedule interface {
+	// Next returns the first run time after t, or the zero time if the schedule never runs again
+	Next(t time.Time) time.Time
+}
+
+// Every returns a Schedule running every d, counted from the end of the previous wait. It panics if d is not
+// positive.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("scheduler: non-positive interval for Every")
+	}
+	return interval(d)
+}
+
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// cronSchedule is a parsed cron expression. Each field is a bit set of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record a "*" day field: when both day fields are restricted, a day matching
+	// either of them matches, as in Vixie cron
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+// cronField describes the range and names of a cron field
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// descriptors are the predefined schedules
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard five-field cron expression: minute, hour, day of month, month and day of
+// week. Fields accept "*", values, ranges ("1-5"), steps ("*/15", "0-30/10"), lists ("1,15") and, for
+// months and days of the week, names ("JAN", "MON-FRI"). Sunday is 0 or 7. The descriptors @yearly,
+// @annually, @monthly, @weekly, @daily, @midnight, @hourly and "@every <duration>" are supported too.
+//
+// Times are computed in loc, or in the location of the time passed to Next when loc is nil.
+func ParseCron(expr string, loc *time.Location) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("scheduler: invalid interval in %q", expr)
+		}
+		return Every(d), nil
+	}
+	if spec, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = spec
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("scheduler: cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &cronSchedule{loc: loc}
+	var err error
+	if s.minute, _, err = parseCronField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, _, err = parseCronField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, s.domStar, err = parseCronField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, _, err = parseCronField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, s.dowStar, err = parseCronField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// MustParseCron is like ParseCron but panics if the expression is invalid
+func MustParseCron(expr string, loc *time.Location) Schedule {
+	s, err := ParseCron(expr, loc)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseCronField parses a field into a bit set, reporting whether it is "*"
+func parseCronField(field string, f cronField) (uint64, bool, error) {
+	if field == "*" || field == "?" {
+		return bitRange(f.min, f.max, 1), true, nil
+	}
+
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepExpr)
+			if err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("scheduler: invalid step %q in %s field", stepExpr, f.name)
+			}
+		}
+
+		var lo, hi int
+		switch {
+		case rangeExpr == "*":
+			lo, hi = f.min, f.max
+		case strings.Contains(rangeExpr, "-"):
+			loExpr, hiExpr, _ := strings.Cut(rangeExpr, "-")
+			var err error
+			if lo, err = f.value(loExpr); err != nil {
+				return 0, false, err
+			}
+			if hi, err = f.value(hiExpr); err != nil {
+				return 0, false, err
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("scheduler: invalid range %q in %s field", rangeExpr, f.name)
+			}
+		default:
+			var err error
+			if lo, err = f.value(rangeExpr); err != nil {
+				return 0, false, err
+			}
+			hi = lo
+			if hasStep {
+				// "5/15" means from 5 to the end of the range, every 15
+				hi = f.max
+			}
+		}
+		bits |= bitRange(lo, hi, step)
+	}
+	return bits, false, nil
+}
+
+// value parses a number or name of the field, checking its range
+func (f cronField) value(expr string) (int, error) {
+	if v, ok := f.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(expr)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("scheduler: invalid value %q in %s field", expr, f.name)
+	}
+	return v, nil
+}
+
+// bitRange returns the bit set of the values from lo to hi, every step
+func bitRange(lo, hi, step int) uint64 {
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits
+}
+
+// Next returns the first minute after t matching the expression. It gives up after five years, which only
+// happens for dates that never exist, such as February 30th.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := s.loc
+	if loc == nil {
+		loc = t.Location()
+	}
+	orig := t
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+
+wrap:
+	if t.Year() > limit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	return t.In(orig.Location())
+}
+
+// dayMatches reports whether the day of t matches the day of month and day of week fields
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
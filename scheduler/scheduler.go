@@ -0,0 +1,413 @@
+// Package scheduler runs recurring jobs on cron expressions or fixed intervals, in process. Each job has a
+// timeout, skips a run while its previous run is still going unless overlaps are allowed, and can start
+// with a random delay so instances of a service don't all run it at the same moment.
+//
+// A Scheduler is a hop module: registered with the App, it starts with the other modules and stops during
+// shutdown, waiting for running jobs to finish.
+//
+//	s := scheduler.New(func(opts *scheduler.Options) {
+//	    opts.Metrics = collector
+//	})
+//	_ = s.AddCron("cleanup-sessions", "*/15 * * * *", store.DeleteExpired, scheduler.Timeout(time.Minute))
+//	_ = s.Add("refresh-rates", scheduler.Every(time.Hour), rates.Refresh, scheduler.Jitter(5*time.Minute))
+//	app.RegisterModule(s)
+//
+// For jobs that must run once across several instances, give the schedulers a lease.Locker sharing one
+// store: each run then takes the job's lease first, and the instances that can't get it skip the run.
+//
+//	s := scheduler.New(scheduler.WithLocker(lease.NewLocker(lease.NewSQLStore(db, lease.DialectPostgres))))
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+	"github.com/patrickward/hop/lease"
+	"github.com/patrickward/hop/pulse"
+)
+
+// Job is a scheduled function. Its context is canceled when the job times out or the scheduler stops.
+type Job func(ctx context.Context) error
+
+// Options configures a Scheduler
+type Options struct {
+	// Timeout is the default timeout of a run. Default is 0, for no timeout.
+	Timeout time.Duration
+	// Location is the time zone of cron expressions added with AddCron. Default is time.Local.
+	Location *time.Location
+	// Logger receives runs, failures and skipped runs. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Metrics, when set, records the "scheduler_runs_total", "scheduler_failures_total" and
+	// "scheduler_skipped_total" counters, a "scheduler_running" gauge and a "scheduler_duration_ms" histogram
+	Metrics pulse.Collector
+	// Clock tells the time and waits for runs. Default is the real clock.
+	Clock clock.Clock
+	// Locker, when set, runs each job through Locker.RunExclusive under a lease named "scheduler:<name>",
+	// so a job runs on one instance at a time across the instances sharing the locker's store. A run is
+	// skipped, and counted in "scheduler_skipped_total", while another instance holds the job's lease.
+	Locker *lease.Locker
+	// LockTTL is the TTL of the job leases, renewed while a job runs. Default is 30 seconds.
+	LockTTL time.Duration
+}
+
+// WithLocker returns options that run each job through locker.RunExclusive, see Options.Locker
+func WithLocker(locker *lease.Locker) func(opts *Options) {
+	return func(opts *Options) {
+		opts.Locker = locker
+	}
+}
+
+// JobOption configures a scheduled job
+type JobOption func(e *entry)
+
+// Timeout limits each run of the job, overriding the scheduler's default
+func Timeout(d time.Duration) JobOption {
+	return func(e *entry) {
+		e.timeout = d
+	}
+}
+
+// Jitter delays each run by a random duration up to d
+func Jitter(d time.Duration) JobOption {
+	return func(e *entry) {
+		e.jitter = d
+	}
+}
+
+// AllowOverlap lets a run start while the previous run of the job is still going. By default, such runs
+// are skipped.
+func AllowOverlap() JobOption {
+	return func(e *entry) {
+		e.allowOverlap = true
+	}
+}
+
+// Entry describes a scheduled job
+type Entry struct {
+	// Name identifies the job
+	Name string
+	// Next is when the job runs next, zero when the scheduler isn't running
+	Next time.Time
+	// Prev is when the job last started, zero if it never ran
+	Prev time.Time
+	// LastError is the error of the last run, if it failed
+	LastError error
+	// Running is the number of runs in progress
+	Running int
+}
+
+// entry is a scheduled job and its state
+type entry struct {
+	name         string
+	schedule     Schedule
+	job          Job
+	timeout      time.Duration
+	jitter       time.Duration
+	allowOverlap bool
+
+	// guarded by Scheduler.mu
+	next, prev time.Time
+	lastErr    error
+	running    int
+}
+
+// Scheduler runs jobs on their schedules
+type Scheduler struct {
+	opts  Options
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	started bool
+	// loopsCtx stops the loops waiting for the next runs, runsCtx cancels the runs in progress
+	loopsCtx, runsCtx       context.Context
+	loopsCancel, runsCancel context.CancelFunc
+	loops, runs             sync.WaitGroup
+
+	runsTotal, failures, skipped pulse.Counter
+	running                      pulse.Gauge
+	duration                     pulse.Histogram
+}
+
+// New creates a new Scheduler
+func New(optsFunc func(opts *Options)) *Scheduler {
+	opts := Options{
+		Location: time.Local,
+		LockTTL:  30 * time.Second,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = 30 * time.Second
+	}
+
+	s := &Scheduler{
+		opts:    opts,
+		clock:   clock.OrReal(opts.Clock),
+		entries: make(map[string]*entry),
+	}
+
+	if opts.Metrics != nil {
+		s.runsTotal = opts.Metrics.Counter("scheduler_runs_total")
+		s.failures = opts.Metrics.Counter("scheduler_failures_total")
+		s.skipped = opts.Metrics.Counter("scheduler_skipped_total")
+		s.running = opts.Metrics.Gauge("scheduler_running")
+		s.duration = opts.Metrics.Histogram("scheduler_duration_ms")
+	}
+
+	return s
+}
+
+// Add schedules a job under a unique name. Jobs added while the scheduler runs start right away.
+func (s *Scheduler) Add(name string, schedule Schedule, job Job, opts ...JobOption) error {
+	e := &entry{name: name, schedule: schedule, job: job, timeout: s.opts.Timeout}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[name]; exists {
+		return fmt.Errorf("scheduler: job already scheduled: %s", name)
+	}
+	s.entries[name] = e
+	if s.started {
+		s.startLoop(e)
+	}
+	return nil
+}
+
+// AddCron schedules a job on a cron expression, parsed with ParseCron in the scheduler's location
+func (s *Scheduler) AddCron(name, expr string, job Job, opts ...JobOption) error {
+	schedule, err := ParseCron(expr, s.opts.Location)
+	if err != nil {
+		return err
+	}
+	return s.Add(name, schedule, job, opts...)
+}
+
+// Entries returns the scheduled jobs, sorted by name
+func (s *Scheduler) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, Entry{Name: e.name, Next: e.next, Prev: e.prev, LastError: e.lastErr, Running: e.running})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// ID implements hop.Module
+func (s *Scheduler) ID() string {
+	return "hop.scheduler"
+}
+
+// Init implements hop.Module
+func (s *Scheduler) Init() error {
+	return nil
+}
+
+// Start starts running the jobs on their schedules
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return nil
+	}
+	s.loopsCtx, s.loopsCancel = context.WithCancel(context.WithoutCancel(ctx))
+	s.runsCtx, s.runsCancel = context.WithCancel(context.WithoutCancel(ctx))
+	s.started = true
+	for _, e := range s.entries {
+		s.startLoop(e)
+	}
+	return nil
+}
+
+// Stop stops scheduling runs and waits for the running ones to finish. If ctx is done first, the running
+// jobs' contexts are canceled and ctx's error is returned.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	s.started = false
+	loopsCancel, runsCancel := s.loopsCancel, s.runsCancel
+	s.mu.Unlock()
+	defer runsCancel()
+
+	loopsCancel()
+	s.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		runsCancel()
+		return ctx.Err()
+	}
+}
+
+// startLoop starts the goroutine waiting for the runs of e; s.mu must be held
+func (s *Scheduler) startLoop(e *entry) {
+	s.loops.Add(1)
+	go func() {
+		defer s.loops.Done()
+		s.loop(s.loopsCtx, e)
+	}()
+}
+
+// loop waits for each run time of e and starts the run, until ctx is done
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	for {
+		now := s.clock.Now()
+		next := e.schedule.Next(now)
+		if next.IsZero() {
+			return
+		}
+		if e.jitter > 0 {
+			next = next.Add(rand.N(e.jitter))
+		}
+		s.setNext(e, next)
+
+		timer := s.clock.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.setNext(e, time.Time{})
+			return
+		case <-timer.C():
+		}
+
+		s.start(e)
+	}
+}
+
+// start starts a run of e, unless the previous run is still going and overlaps aren't allowed
+func (s *Scheduler) start(e *entry) {
+	s.mu.Lock()
+	if e.running > 0 && !e.allowOverlap {
+		s.mu.Unlock()
+		s.opts.Logger.Warn("Skipped scheduled job, the previous run is still going", slog.String("job", e.name))
+		if s.skipped != nil {
+			s.skipped.Inc()
+		}
+		return
+	}
+	e.running++
+	e.prev = s.clock.Now()
+	s.runs.Add(1)
+	runsCtx := s.runsCtx
+	s.mu.Unlock()
+
+	go func() {
+		defer s.runs.Done()
+		err := s.run(runsCtx, e)
+
+		s.mu.Lock()
+		e.running--
+		e.lastErr = err
+		s.mu.Unlock()
+	}()
+}
+
+// run runs e once with its timeout, recording its outcome
+func (s *Scheduler) run(ctx context.Context, e *entry) (err error) {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = clock.WithTimeout(ctx, s.clock, e.timeout)
+		defer cancel()
+	}
+
+	if s.running != nil {
+		s.running.Add(1)
+		defer s.running.Sub(1)
+	}
+
+	var (
+		start = s.clock.Now()
+		ran   = true
+	)
+	defer func() {
+		if !ran {
+			s.opts.Logger.Debug("Skipped scheduled job, another instance is running it", slog.String("job", e.name))
+			if s.skipped != nil {
+				s.skipped.Inc()
+			}
+			return
+		}
+
+		if p := recover(); p != nil {
+			s.opts.Logger.Error("Scheduled job panicked",
+				slog.String("job", e.name),
+				slog.Any("panic", p),
+				slog.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("panic: %v", p)
+		}
+
+		duration := s.clock.Since(start)
+		if s.duration != nil {
+			s.duration.Observe(float64(duration.Milliseconds()))
+		}
+		if s.runsTotal != nil {
+			s.runsTotal.Inc()
+		}
+
+		if err != nil {
+			if e.timeout > 0 && errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+				err = fmt.Errorf("timed out after %s: %w", e.timeout, err)
+			}
+			s.opts.Logger.Error("Scheduled job failed",
+				slog.String("job", e.name),
+				slog.Duration("duration", duration),
+				slog.String("error", err.Error()))
+			if s.failures != nil {
+				s.failures.Inc()
+			}
+			return
+		}
+		s.opts.Logger.Debug("Scheduled job completed", slog.String("job", e.name), slog.Duration("duration", duration))
+	}()
+
+	if s.opts.Locker == nil {
+		return e.job(ctx)
+	}
+
+	ran, err = s.opts.Locker.RunExclusive(ctx, "scheduler:"+e.name, s.opts.LockTTL, e.job)
+	ran = ran || err != nil
+	return err
+}
+
+// setNext records when e runs next
+func (s *Scheduler) setNext(e *entry, next time.Time) {
+	s.mu.Lock()
+	e.next = next
+	s.mu.Unlock()
+}
@@ -0,0 +1,397 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/lease"
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/scheduler"
+)
+
+var start = time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+
+// testCollector is a pulse.Collector recording counters, gauges and histogram observation counts
+type testCollector struct {
+	pulse.Collector
+	mu         sync.Mutex
+	counters   map[string]*testMetric
+	gauges     map[string]*testMetric
+	histograms map[string]*testMetric
+}
+
+func newTestCollector() *testCollector {
+	return &testCollector{
+		counters:   map[string]*testMetric{},
+		gauges:     map[string]*testMetric{},
+		histograms: map[string]*testMetric{},
+	}
+}
+
+type testMetric struct {
+	mu    sync.Mutex
+	value float64
+	count uint64
+}
+
+func (m *testMetric) Inc()              { m.Add(1) }
+func (m *testMetric) Sub(delta float64) { m.Add(-delta) }
+func (m *testMetric) Set(value float64) { m.mu.Lock(); m.value = value; m.mu.Unlock() }
+func (m *testMetric) Add(delta float64) { m.mu.Lock(); m.value += delta; m.mu.Unlock() }
+func (m *testMetric) Value() float64    { m.mu.Lock(); defer m.mu.Unlock(); return m.value }
+func (m *testMetric) Count() uint64     { m.mu.Lock(); defer m.mu.Unlock(); return m.count }
+func (m *testMetric) Sum() float64      { return m.Value() }
+func (m *testMetric) Observe(value float64) {
+	m.mu.Lock()
+	m.value += value
+	m.count++
+	m.mu.Unlock()
+}
+
+func (c *testCollector) metric(metrics map[string]*testMetric, name string) *testMetric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if metrics[name] == nil {
+		metrics[name] = &testMetric{}
+	}
+	return metrics[name]
+}
+
+func (c *testCollector) Counter(name string) pulse.Counter     { return c.metric(c.counters, name) }
+func (c *testCollector) Gauge(name string) pulse.Gauge         { return c.metric(c.gauges, name) }
+func (c *testCollector) Histogram(name string) pulse.Histogram { return c.metric(c.histograms, name) }
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"every minute", "* * * * *", start, start.Add(time.Minute)},
+		{"every minute mid-minute", "* * * * *", start.Add(20 * time.Second), start.Add(time.Minute)},
+		{"step", "*/15 * * * *", start.Add(time.Minute), start.Add(15 * time.Minute)},
+		{"hour rollover", "0 * * * *", start, time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)},
+		{"list", "5,50 10 * * *", start, time.Date(2024, 3, 15, 10, 50, 0, 0, time.UTC)},
+		{"range with step", "0 9-17/4 * * *", start, time.Date(2024, 3, 15, 13, 0, 0, 0, time.UTC)},
+		{"day rollover", "0 9 * * *", start, time.Date(2024, 3, 16, 9, 0, 0, 0, time.UTC)},
+		{"weekday names", "0 9 * * MON-FRI", start, time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", start, time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"month name", "0 0 1 jun *", start, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"year rollover", "0 0 1 1 *", start, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", start, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"day of month or week", "0 0 1 * MON", start, time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"descriptor", "@daily", start, time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"every descriptor", "@every 90s", start, start.Add(90 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := scheduler.ParseCron(tt.expr, time.UTC)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(tt.from))
+		})
+	}
+
+	t.Run("location", func(t *testing.T) {
+		ny, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Skip("time zone database not available")
+		}
+		schedule := scheduler.MustParseCron("0 9 * * *", ny)
+		assert.Equal(t, time.Date(2024, 3, 15, 13, 0, 0, 0, time.UTC), schedule.Next(start))
+	})
+
+	t.Run("never", func(t *testing.T) {
+		schedule := scheduler.MustParseCron("0 0 30 2 *", time.UTC)
+		assert.True(t, schedule.Next(start).IsZero())
+	})
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "@every nope", "@every -1s"} {
+		_, err := scheduler.ParseCron(expr, time.UTC)
+		assert.Error(t, err, expr)
+	}
+}
+
+func newScheduler(t *testing.T, clk *hoptest.Clock, collector pulse.Collector) *scheduler.Scheduler {
+	t.Helper()
+
+	s := scheduler.New(func(opts *scheduler.Options) {
+		opts.Clock = clk
+		opts.Location = time.UTC
+		opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		opts.Metrics = collector
+	})
+	t.Cleanup(func() { _ = s.Stop(context.Background()) })
+	return s
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("runs jobs on their schedule", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		collector := newTestCollector()
+		s := newScheduler(t, clk, collector)
+
+		ran := make(chan time.Time, 10)
+		require.NoError(t, s.AddCron("report", "*/15 * * * *", func(ctx context.Context) error {
+			ran <- clk.Now()
+			return nil
+		}))
+		require.NoError(t, s.Start(context.Background()))
+
+		clk.BlockUntil(1)
+		entries := s.Entries()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "report", entries[0].Name)
+		assert.Equal(t, time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC), entries[0].Next)
+
+		clk.Advance(15 * time.Minute)
+		assert.Equal(t, time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC), <-ran)
+
+		clk.BlockUntil(1)
+		clk.Advance(15 * time.Minute)
+		assert.Equal(t, time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC), <-ran)
+
+		require.NoError(t, s.Stop(context.Background()))
+		assert.Equal(t, float64(2), collector.Counter("scheduler_runs_total").Value())
+		assert.Equal(t, uint64(2), collector.Histogram("scheduler_duration_ms").Count())
+		assert.Equal(t, float64(0), collector.Gauge("scheduler_running").Value())
+		assert.Equal(t, 0, clk.Waiters())
+	})
+
+	t.Run("rejects duplicate names", func(t *testing.T) {
+		s := newScheduler(t, hoptest.NewClock(start), nil)
+		job := func(ctx context.Context) error { return nil }
+
+		require.NoError(t, s.Add("job", scheduler.Every(time.Minute), job))
+		assert.Error(t, s.Add("job", scheduler.Every(time.Hour), job))
+		assert.Error(t, s.AddCron("bad", "not a cron", job))
+	})
+
+	t.Run("skips overlapping runs", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		collector := newTestCollector()
+		s := newScheduler(t, clk, collector)
+
+		var runs atomic.Int32
+		release := make(chan struct{})
+		started := make(chan struct{}, 10)
+		require.NoError(t, s.Add("slow", scheduler.Every(time.Minute), func(ctx context.Context) error {
+			runs.Add(1)
+			started <- struct{}{}
+			<-release
+			return nil
+		}))
+		require.NoError(t, s.Start(context.Background()))
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+		<-started
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+		clk.BlockUntil(1)
+
+		assert.Equal(t, int32(1), runs.Load())
+		assert.Equal(t, float64(1), collector.Counter("scheduler_skipped_total").Value())
+		assert.Equal(t, 1, s.Entries()[0].Running)
+		close(release)
+	})
+
+	t.Run("allows overlapping runs", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		s := newScheduler(t, clk, nil)
+
+		release := make(chan struct{})
+		started := make(chan struct{}, 10)
+		require.NoError(t, s.Add("slow", scheduler.Every(time.Minute), func(ctx context.Context) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}, scheduler.AllowOverlap()))
+		require.NoError(t, s.Start(context.Background()))
+
+		for range 2 {
+			clk.BlockUntil(1)
+			clk.Advance(time.Minute)
+			<-started
+		}
+		assert.Equal(t, 2, s.Entries()[0].Running)
+		close(release)
+	})
+
+	t.Run("times out runs", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		collector := newTestCollector()
+		s := newScheduler(t, clk, collector)
+
+		done := make(chan struct{})
+		require.NoError(t, s.Add("stuck", scheduler.Every(time.Minute), func(ctx context.Context) error {
+			defer close(done)
+			<-ctx.Done()
+			return ctx.Err()
+		}, scheduler.Timeout(10*time.Second)))
+		require.NoError(t, s.Start(context.Background()))
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+		clk.BlockUntil(2)
+		clk.Advance(10 * time.Second)
+		<-done
+
+		assert.Eventually(t, func() bool {
+			return s.Entries()[0].LastError != nil
+		}, time.Second, time.Millisecond)
+		err := s.Entries()[0].LastError
+		assert.Contains(t, err.Error(), "timed out after 10s")
+		assert.Equal(t, float64(1), collector.Counter("scheduler_failures_total").Value())
+	})
+
+	t.Run("recovers from panics", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		s := newScheduler(t, clk, nil)
+
+		require.NoError(t, s.Add("panics", scheduler.Every(time.Minute), func(ctx context.Context) error {
+			panic("boom")
+		}))
+		require.NoError(t, s.Start(context.Background()))
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+
+		assert.Eventually(t, func() bool {
+			err := s.Entries()[0].LastError
+			return err != nil && err.Error() == "panic: boom"
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("stop waits for running jobs", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		s := newScheduler(t, clk, nil)
+
+		started := make(chan struct{})
+		var finished atomic.Bool
+		require.NoError(t, s.Add("job", scheduler.Every(time.Minute), func(ctx context.Context) error {
+			close(started)
+			time.Sleep(20 * time.Millisecond)
+			finished.Store(true)
+			return nil
+		}))
+		require.NoError(t, s.Start(context.Background()))
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+		<-started
+
+		require.NoError(t, s.Stop(context.Background()))
+		assert.True(t, finished.Load())
+		assert.True(t, s.Entries()[0].Next.IsZero())
+	})
+
+	t.Run("stop cancels running jobs when its context is done", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		s := newScheduler(t, clk, nil)
+
+		started := make(chan struct{})
+		canceled := make(chan error, 1)
+		require.NoError(t, s.Add("job", scheduler.Every(time.Minute), func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return ctx.Err()
+		}))
+		require.NoError(t, s.Start(context.Background()))
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+		<-started
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := s.Stop(ctx)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.ErrorIs(t, <-canceled, context.Canceled)
+	})
+
+	t.Run("jobs added while running start right away", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		s := newScheduler(t, clk, nil)
+		require.NoError(t, s.Start(context.Background()))
+
+		ran := make(chan struct{}, 1)
+		require.NoError(t, s.Add("late", scheduler.Every(time.Hour), func(ctx context.Context) error {
+			ran <- struct{}{}
+			return nil
+		}, scheduler.Jitter(time.Minute)))
+
+		clk.BlockUntil(1)
+		next := s.Entries()[0].Next
+		assert.False(t, next.Before(start.Add(time.Hour)))
+		assert.True(t, next.Before(start.Add(time.Hour+time.Minute)))
+
+		clk.Advance(time.Hour + time.Minute)
+		<-ran
+	})
+
+	t.Run("runs jobs on one instance with a shared locker", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		store := lease.NewMemoryStore()
+
+		var (
+			runs       atomic.Int32
+			collectors []*testCollector
+		)
+		release := make(chan struct{})
+		started := make(chan struct{}, 10)
+		for _, owner := range []string{"instance-1", "instance-2"} {
+			collector := newTestCollector()
+			collectors = append(collectors, collector)
+
+			s := scheduler.New(func(opts *scheduler.Options) {
+				scheduler.WithLocker(lease.NewLocker(store, lease.WithOwner(owner)))(opts)
+				opts.Clock = clk
+				opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+				opts.Metrics = collector
+			})
+			t.Cleanup(func() { _ = s.Stop(context.Background()) })
+
+			require.NoError(t, s.Add("report", scheduler.Every(time.Minute), func(ctx context.Context) error {
+				runs.Add(1)
+				started <- struct{}{}
+				<-release
+				return nil
+			}))
+			require.NoError(t, s.Start(context.Background()))
+		}
+
+		clk.BlockUntil(2)
+		clk.Advance(time.Minute)
+		<-started
+
+		skipped := func() float64 {
+			var total float64
+			for _, c := range collectors {
+				total += c.Counter("scheduler_skipped_total").Value()
+			}
+			return total
+		}
+		assert.Eventually(t, func() bool { return skipped() == 1 }, time.Second, time.Millisecond)
+		assert.Equal(t, int32(1), runs.Load())
+
+		close(release)
+		assert.Eventually(t, func() bool {
+			runs := collectors[0].Counter("scheduler_runs_total").Value() + collectors[1].Counter("scheduler_runs_total").Value()
+			return runs == 1
+		}, time.Second, time.Millisecond)
+	})
+}
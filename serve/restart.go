@@ -0,0 +1,150 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RestartEvent is the event name sent to streaming clients when the server begins shutting down
+const RestartEvent = "server.restarting"
+
+// RestartNotice is broadcast to long-lived SSE and WebSocket connections when the server begins a
+// graceful shutdown, so clients can reconnect smoothly after a deploy or restart.
+type RestartNotice struct {
+	// ReconnectAfter is how long clients should wait before reconnecting
+	ReconnectAfter time.Duration
+	// Message is an optional human-readable message
+	Message string
+}
+
+// MarshalJSON encodes the notice as a WebSocket-friendly message:
+//
+//	{"type":"server.restarting","reconnect_after_ms":5000,"message":"..."}
+func (n RestartNotice) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type             string `json:"type"`
+		ReconnectAfterMs int64  `json:"reconnect_after_ms"`
+		Message          string `json:"message,omitempty"`
+	}{
+		Type:             RestartEvent,
+		ReconnectAfterMs: n.ReconnectAfter.Milliseconds(),
+		Message:          n.Message,
+	})
+}
+
+// WriteSSE writes the notice as a server-sent event and flushes it. The retry field sets the
+// EventSource reconnection delay, so browsers reconnect on their own once the server is back.
+func (n RestartNotice) WriteSSE(w http.ResponseWriter) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "event: %s\nretry: %d\ndata: %s\n\n", RestartEvent, n.ReconnectAfter.Milliseconds(), data); err != nil {
+		return err
+	}
+
+	return http.NewResponseController(w).Flush()
+}
+
+// RestartSubscription receives the restart notice for a single streaming connection.
+// It must be closed when the connection ends.
+type RestartSubscription struct {
+	// C receives the notice when the server begins shutting down
+	C <-chan RestartNotice
+
+	ch     chan RestartNotice
+	server *Server
+	once   sync.Once
+}
+
+// Close removes the subscription. Shutdown waits for open subscriptions to close, within the
+// shutdown timeout, so handlers have a chance to deliver the notice before connections are closed.
+func (sub *RestartSubscription) Close() {
+	sub.once.Do(func() {
+		s := sub.server
+		s.streamsMu.Lock()
+		delete(s.streams, sub)
+		s.streamsMu.Unlock()
+		s.streamsWG.Done()
+	})
+}
+
+// SubscribeRestart registers a long-lived streaming connection to be notified when the server begins
+// shutting down. The handler should deliver the notice to its client and return:
+//
+//	sub := srv.SubscribeRestart()
+//	defer sub.Close()
+//
+//	for {
+//		select {
+//		case notice := <-sub.C:
+//			_ = notice.WriteSSE(w)
+//			return
+//		case msg := <-messages:
+//			// write msg
+//		}
+//	}
+//
+// If the server is already shutting down, the notice is delivered immediately.
+func (s *Server) SubscribeRestart() *RestartSubscription {
+	ch := make(chan RestartNotice, 1)
+	sub := &RestartSubscription{C: ch, ch: ch, server: s}
+
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	s.streamsWG.Add(1)
+	if s.restarting {
+		ch <- s.restartNotice()
+	} else {
+		s.streams[sub] = struct{}{}
+	}
+
+	return sub
+}
+
+// restartNotice returns the notice sent to streaming clients
+func (s *Server) restartNotice() RestartNotice {
+	return RestartNotice{
+		ReconnectAfter: s.config.Server.ReconnectAfter.Duration,
+		Message:        "Server is restarting",
+	}
+}
+
+// notifyRestart broadcasts the restart notice to all subscribed streams and waits for them to close,
+// or for ctx to be done
+func (s *Server) notifyRestart(ctx context.Context) {
+	s.streamsMu.Lock()
+	s.restarting = true
+	notice := s.restartNotice()
+	count := len(s.streams)
+	for sub := range s.streams {
+		sub.ch <- notice
+		delete(s.streams, sub)
+	}
+	s.streamsMu.Unlock()
+
+	if count == 0 {
+		return
+	}
+
+	s.logger.Info("notified streaming clients of restart", slog.Int("clients", count))
+
+	done := make(chan struct{})
+	go func() {
+		s.streamsWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger.Warn("timeout waiting for streaming clients to close")
+	}
+}
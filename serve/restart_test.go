@@ -0,0 +1,83 @@
+package serve_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/serve"
+)
+
+func TestRestartNotice(t *testing.T) {
+	notice := serve.RestartNotice{ReconnectAfter: 3 * time.Second, Message: "deploying"}
+
+	data, err := json.Marshal(notice)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"server.restarting","reconnect_after_ms":3000,"message":"deploying"}`, string(data))
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, notice.WriteSSE(rec))
+	assert.Equal(t, "event: server.restarting\nretry: 3000\ndata: "+string(data)+"\n\n", rec.Body.String())
+	assert.True(t, rec.Flushed)
+}
+
+func TestServerNotifiesStreamsOnShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	cfg := newTestConfig()
+	cfg.Server.ReconnectAfter.Duration = 2 * time.Second
+
+	router := newTestRouter()
+	srv := serve.NewServer(cfg, newTestLogger(), router)
+	srv.SetListener(ln)
+
+	subscribed := make(chan struct{})
+	router.Get("/events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sub := srv.SubscribeRestart()
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_ = http.NewResponseController(w).Flush()
+		close(subscribed)
+
+		select {
+		case notice := <-sub.C:
+			_ = notice.WriteSSE(w)
+		case <-r.Context().Done():
+		}
+	}))
+
+	stop := startServer(t, srv)
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + ln.Addr().String() + "/events")
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	defer resp.Body.Close()
+
+	<-subscribed
+	go func() { _ = srv.Shutdown(context.Background()) }()
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: server.restarting", strings.TrimSpace(line))
+
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "retry: 2000", strings.TrimSpace(line))
+
+	stop()
+}
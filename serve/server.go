@@ -6,14 +6,19 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"os/signal"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/patrickward/hop/clock"
 	"github.com/patrickward/hop/conf"
 	"github.com/patrickward/hop/route"
 )
@@ -26,11 +31,20 @@ type Server struct {
 	config     *conf.HopConfig
 	onShutdown func(context.Context) error
 	httpServer *http.Server
+	listener   net.Listener
+	socketPath string
 	logger     *slog.Logger
 	router     *route.Mux
 	wg         *sync.WaitGroup
 	stopChan   chan struct{}
 	stopping   sync.Once
+	streamsMu  sync.Mutex
+	streams    map[*RestartSubscription]struct{}
+	streamsWG  sync.WaitGroup
+	restarting bool
+	clock      clock.Clock
+	// shuttingDown is set once graceful shutdown begins
+	shuttingDown atomic.Bool
 }
 
 // NewServer creates a new server with the given configuration and logger.
@@ -55,6 +69,8 @@ func NewServer(config *conf.HopConfig, logger *slog.Logger, router *route.Mux) *
 		router:     router,
 		wg:         &sync.WaitGroup{},
 		stopChan:   make(chan struct{}),
+		streams:    make(map[*RestartSubscription]struct{}),
+		clock:      clock.Real(),
 	}
 
 	return srv
@@ -80,6 +96,81 @@ func (s *Server) OnShutdown(fn func(context.Context) error) {
 	s.onShutdown = fn
 }
 
+// SetListener sets the listener the server accepts connections on, overriding the configured
+// network and address. This is useful when the listener is created elsewhere, such as in tests
+// or by a process manager. It must be called before Start.
+func (s *Server) SetListener(ln net.Listener) {
+	s.listener = ln
+}
+
+// SetClock sets the clock used for the shutdown timeouts, e.g. a fake clock from the hoptest package so
+// shutdown behavior can be tested without waiting. It must be called before Start.
+func (s *Server) SetClock(c clock.Clock) {
+	s.clock = clock.OrReal(c)
+}
+
+// listen returns the listener for the server. If no listener has been set, one is created from
+// the server configuration, either a TCP listener on the configured port or a Unix domain socket
+// when the configured network is "unix".
+func (s *Server) listen() (net.Listener, error) {
+	if s.listener != nil {
+		return s.listener, nil
+	}
+
+	cfg := s.config.Server
+	if cfg.Network != "unix" {
+		return net.Listen("tcp", s.httpServer.Addr)
+	}
+
+	if cfg.SocketPath == "" {
+		return nil, errors.New("socket path is required for unix network")
+	}
+
+	// Remove a stale socket file left behind by a previous run, but never another kind of file
+	if info, err := os.Lstat(cfg.SocketPath); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("socket path %s exists and is not a socket", cfg.SocketPath)
+		}
+		if err := os.Remove(cfg.SocketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("checking socket path: %w", err)
+	}
+
+	ln, err := net.Listen("unix", cfg.SocketPath)
+	if err != nil {
+		return nil, err
+	}
+	s.socketPath = cfg.SocketPath
+
+	if cfg.SocketMode != "" {
+		mode, err := strconv.ParseUint(cfg.SocketMode, 8, 32)
+		if err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("invalid socket mode %q: %w", cfg.SocketMode, err)
+		}
+		if err := os.Chmod(cfg.SocketPath, os.FileMode(mode)); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("setting socket mode: %w", err)
+		}
+	}
+
+	return ln, nil
+}
+
+// cleanupSocket removes the Unix domain socket file created by the server, if any.
+func (s *Server) cleanupSocket() {
+	if s.socketPath == "" {
+		return
+	}
+
+	if err := os.Remove(s.socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.logger.Warn("failed to remove socket file",
+			slog.String("path", s.socketPath), slog.String("error", err.Error()))
+	}
+}
+
 // BackgroundTask runs a function in a goroutine, and reports any errors to the server's error logger.
 func (s *Server) BackgroundTask(r *http.Request, fn func() error) {
 	s.wg.Add(1)
@@ -217,15 +308,23 @@ func (s *Server) Start() error {
 		}
 	}()
 
+	ln, err := s.listen()
+	if err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+	defer s.cleanupSocket()
+
 	// Create errgroup with our cancellable context
 	eg, gCtx := errgroup.WithContext(runCtx)
 
 	// Start HTTP server
 	eg.Go(func() error {
 		s.logger.Info("starting server",
-			slog.Group("server", slog.String("addr", s.httpServer.Addr)))
+			slog.Group("server",
+				slog.String("network", ln.Addr().Network()),
+				slog.String("addr", ln.Addr().String())))
 
-		if err := s.httpServer.ListenAndServe(); err != nil &&
+		if err := s.httpServer.Serve(ln); err != nil &&
 			!errors.Is(err, http.ErrServerClosed) {
 			return fmt.Errorf("server error: %w", err)
 		}
@@ -236,14 +335,40 @@ func (s *Server) Start() error {
 	eg.Go(func() error {
 		<-gCtx.Done()
 
+		s.shuttingDown.Store(true)
 		s.logger.Info("initiating graceful shutdown")
 
-		// Split the shutdown timeout between WaitGroup and server shutdown
+		// Split the shutdown timeout between server shutdown and WaitGroup
 		totalTimeout := s.config.Server.ShutdownTimeout.Duration
 		wgTimeout := totalTimeout / 2
 		serverTimeout := totalTimeout - wgTimeout
 
-		// Wait for background tasks
+		// Create context for server shutdown
+		shutdownCtx, shutdownCancel := clock.WithTimeout(
+			context.Background(),
+			s.clock,
+			serverTimeout,
+		)
+		defer shutdownCancel()
+
+		// Tell streaming clients to reconnect, so their handlers return before the HTTP server shuts down
+		s.notifyRestart(shutdownCtx)
+
+		// Drain HTTP first, so in-flight requests finish before the background tasks and modules they
+		// may depend on are stopped
+		s.logger.Info("shutting down http server",
+			slog.Duration("timeout", serverTimeout))
+
+		var shutdownErr error
+		if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+			shutdownErr = fmt.Errorf("shutdown error: %w", err)
+		}
+
+		// Create context for WaitGroup timeout
+		wgCtx, wgCancel := clock.WithTimeout(context.Background(), s.clock, wgTimeout)
+		defer wgCancel()
+
+		// Wait for background tasks, including those started by the requests that just finished
 		wgDone := make(chan struct{})
 		go func() {
 			s.logger.Info("waiting for background tasks to complete",
@@ -252,10 +377,6 @@ func (s *Server) Start() error {
 			close(wgDone)
 		}()
 
-		// Create context for WaitGroup timeout
-		wgCtx, wgCancel := context.WithTimeout(context.Background(), wgTimeout)
-		defer wgCancel()
-
 		// Wait for either WaitGroup completion or timeout
 		select {
 		case <-wgDone:
@@ -265,29 +386,14 @@ func (s *Server) Start() error {
 				slog.Duration("elapsed", wgTimeout))
 		}
 
-		// Call onShutdown handler if registered
+		// Call onShutdown handler if registered. For an App, this runs its shutdown phases.
 		if s.onShutdown != nil {
 			if err := s.onShutdown(context.Background()); err != nil {
 				s.logger.Error("onShutdown error", slog.String("error", err.Error()))
 			}
 		}
 
-		// Create context for server shutdown
-		shutdownCtx, shutdownCancel := context.WithTimeout(
-			context.Background(),
-			serverTimeout,
-		)
-		defer shutdownCancel()
-
-		s.logger.Info("shutting down http server",
-			slog.Duration("timeout", serverTimeout))
-
-		// Proceed with server shutdown
-		if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
-			return fmt.Errorf("shutdown error: %w", err)
-		}
-
-		return nil
+		return shutdownErr
 	})
 
 	// Wait for all errgroup goroutines to complete or error
@@ -300,6 +406,12 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// ShuttingDown reports whether the server has begun a graceful shutdown. Requests can still arrive on
+// open connections while background tasks are drained.
+func (s *Server) ShuttingDown() bool {
+	return s.shuttingDown.Load()
+}
+
 // Shutdown initiates a graceful shutdown of the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	// Use sync.Once to ensure we only trigger shutdown once
@@ -0,0 +1,216 @@
+package serve_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/conf"
+	"github.com/patrickward/hop/conf/conftype"
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/route"
+	"github.com/patrickward/hop/serve"
+)
+
+func newTestConfig() *conf.HopConfig {
+	cfg := &conf.HopConfig{}
+	cfg.Server.ShutdownTimeout = conftype.Duration{Duration: 2 * time.Second}
+	return cfg
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func newTestRouter() *route.Mux {
+	mux := route.New()
+	mux.Get("/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	}))
+	return mux
+}
+
+// startServer runs the server in the background and returns a function that shuts it down
+// and waits for Start to return.
+func startServer(t *testing.T, srv *serve.Server) func() {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Start() }()
+
+	return func() {
+		require.NoError(t, srv.Shutdown(context.Background()))
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("server did not stop")
+		}
+	}
+}
+
+func unixClient(path string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	require.Eventually(t, func() bool {
+		resp, err = client.Get(url)
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestServerUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "hop")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "hop.sock")
+
+	// A stale socket file should be replaced
+	stale, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	cfg := newTestConfig()
+	cfg.Server.Network = "unix"
+	cfg.Server.SocketPath = socketPath
+	cfg.Server.SocketMode = "0660"
+
+	srv := serve.NewServer(cfg, newTestLogger(), newTestRouter())
+	stop := startServer(t, srv)
+
+	assert.Equal(t, "pong", get(t, unixClient(socketPath), "http://unix/ping"))
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSocket, info.Mode().Type())
+	assert.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+
+	stop()
+
+	_, err = os.Stat(socketPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestServerUnixSocketKeepsOtherFiles(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "hop.sock")
+	require.NoError(t, os.WriteFile(socketPath, []byte("data"), 0o600))
+
+	cfg := newTestConfig()
+	cfg.Server.Network = "unix"
+	cfg.Server.SocketPath = socketPath
+
+	srv := serve.NewServer(cfg, newTestLogger(), newTestRouter())
+	assert.ErrorContains(t, srv.Start(), "is not a socket")
+
+	data, err := os.ReadFile(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data), "a file that isn't a socket should not be removed")
+}
+
+func TestServerUnixSocketRequiresPath(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Server.Network = "unix"
+
+	srv := serve.NewServer(cfg, newTestLogger(), nil)
+	assert.ErrorContains(t, srv.Start(), "socket path is required")
+}
+
+func TestServerSetListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := serve.NewServer(newTestConfig(), newTestLogger(), newTestRouter())
+	srv.SetListener(ln)
+	stop := startServer(t, srv)
+	defer stop()
+
+	assert.Equal(t, "pong", get(t, http.DefaultClient, "http://"+ln.Addr().String()+"/ping"))
+}
+
+func TestServerShuttingDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := serve.NewServer(newTestConfig(), newTestLogger(), newTestRouter())
+	srv.SetListener(ln)
+	stop := startServer(t, srv)
+
+	assert.Equal(t, "pong", get(t, http.DefaultClient, "http://"+ln.Addr().String()+"/ping"))
+	assert.False(t, srv.ShuttingDown())
+
+	stop()
+	assert.True(t, srv.ShuttingDown())
+}
+
+func TestServerShutdownTimeoutWithClock(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	cfg := newTestConfig()
+	cfg.Server.ShutdownTimeout = conftype.Duration{Duration: time.Hour}
+	clk := hoptest.NewClock(time.Now())
+
+	srv := serve.NewServer(cfg, newTestLogger(), newTestRouter())
+	srv.SetListener(ln)
+	srv.SetClock(clk)
+
+	// A background task that never finishes holds up the shutdown until the timeout
+	release := make(chan struct{})
+	defer close(release)
+	srv.BackgroundTask(httptest.NewRequest(http.MethodGet, "/", nil), func() error {
+		<-release
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Start() }()
+	get(t, http.DefaultClient, "http://"+ln.Addr().String()+"/ping")
+	require.NoError(t, srv.Shutdown(context.Background()))
+
+	// Both the HTTP drain and the background task timeouts are waiting on the clock
+	clk.BlockUntil(2)
+	select {
+	case <-done:
+		t.Fatal("server stopped before the background task timeout")
+	default:
+	}
+
+	clk.Advance(30 * time.Minute)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after the timeout")
+	}
+}
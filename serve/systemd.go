@@ -0,0 +1,96 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd (SD_LISTEN_FDS_START)
+const listenFdsStart = 3
+
+// ErrNoSystemdListeners is returned when the process was not started with systemd socket activation.
+var ErrNoSystemdListeners = errors.New("no systemd listeners")
+
+// ListenersFromSystemd returns the listeners passed to the process by systemd socket activation.
+//
+// It reads the LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES environment variables as described in
+// sd_listen_fds(3). The environment variables are unset once read so they are not inherited by
+// child processes. If the process was not socket activated, ErrNoSystemdListeners is returned.
+func ListenersFromSystemd() ([]net.Listener, error) {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, ErrNoSystemdListeners
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, ErrNoSystemdListeners
+	}
+
+	var names []string
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	listeners := make([]net.Listener, 0, count)
+	for i := 0; i < count; i++ {
+		fd := listenFdsStart + i
+		syscall.CloseOnExec(fd)
+
+		name := fmt.Sprintf("LISTEN_FD_%d", fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		file := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(file)
+		// FileListener duplicates the descriptor, so the original can always be closed
+		_ = file.Close()
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("listener from fd %d (%s): %w", fd, name, err)
+		}
+
+		listeners = append(listeners, ln)
+	}
+
+	return listeners, nil
+}
+
+// UseSystemdListener configures the server to accept connections on the first listener passed by systemd
+// socket activation. It reports whether a listener was found, so callers can fall back to the configured
+// network and address when the process was started without socket activation.
+func (s *Server) UseSystemdListener() (bool, error) {
+	listeners, err := ListenersFromSystemd()
+	if errors.Is(err, ErrNoSystemdListeners) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Only a single listener is served; close any extras so their descriptors are not leaked
+	for _, ln := range listeners[1:] {
+		_ = ln.Close()
+	}
+
+	s.SetListener(listeners[0])
+	s.logger.Info("using systemd socket activation",
+		slog.String("addr", listeners[0].Addr().String()))
+
+	return true, nil
+}
@@ -0,0 +1,49 @@
+package serve_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/serve"
+)
+
+func TestListenersFromSystemd(t *testing.T) {
+	tests := []struct {
+		name string
+		pid  string
+		fds  string
+	}{
+		{name: "not socket activated"},
+		{name: "different pid", pid: strconv.Itoa(os.Getpid() + 1), fds: "1"},
+		{name: "no descriptors", pid: strconv.Itoa(os.Getpid()), fds: "0"},
+		{name: "invalid descriptor count", pid: strconv.Itoa(os.Getpid()), fds: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tt.pid)
+			t.Setenv("LISTEN_FDS", tt.fds)
+
+			listeners, err := serve.ListenersFromSystemd()
+			assert.ErrorIs(t, err, serve.ErrNoSystemdListeners)
+			assert.Empty(t, listeners)
+
+			_, ok := os.LookupEnv("LISTEN_FDS")
+			assert.False(t, ok, "LISTEN_FDS should be unset")
+		})
+	}
+}
+
+func TestServerUseSystemdListenerFallback(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+
+	srv := serve.NewServer(newTestConfig(), newTestLogger(), nil)
+	ok, err := srv.UseSystemdListener()
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,59 @@
+package hop
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrServiceNotFound is returned by Resolve when no service of the requested type is registered
+var ErrServiceNotFound = errors.New("service not registered")
+
+// Register publishes a service under its type T, so other modules can look it up with Resolve without
+// knowing which module provides it. T is usually an interface, so consumers depend on what the service
+// does rather than on its implementation:
+//
+//	// in the mail module's constructor or Init
+//	hop.Register[mail.Sender](app, sender)
+//
+//	// in a module registered later
+//	sender, err := hop.Resolve[mail.Sender](app)
+//
+// Registering a second service of the same type is an error.
+func Register[T any](a *App, service T) error {
+	t := reflect.TypeFor[T]()
+
+	a.servicesMu.Lock()
+	defer a.servicesMu.Unlock()
+
+	if _, exists := a.services[t]; exists {
+		return fmt.Errorf("service already registered: %s", t)
+	}
+	a.services[t] = service
+	return nil
+}
+
+// Resolve returns the service registered under type T. The error wraps ErrServiceNotFound and names the
+// type when there is none, e.g. when the module providing it was registered after the one resolving it.
+func Resolve[T any](a *App) (T, error) {
+	t := reflect.TypeFor[T]()
+
+	a.servicesMu.RLock()
+	defer a.servicesMu.RUnlock()
+
+	service, exists := a.services[t]
+	if !exists {
+		var zero T
+		return zero, fmt.Errorf("%w: %s", ErrServiceNotFound, t)
+	}
+	return service.(T), nil
+}
+
+// MustResolve is like Resolve but panics if the service is not registered
+func MustResolve[T any](a *App) T {
+	service, err := Resolve[T](a)
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
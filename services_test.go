@@ -0,0 +1,89 @@
+package hop_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop"
+)
+
+type greeter interface {
+	Greet(name string) string
+}
+
+type englishGreeter struct{}
+
+func (englishGreeter) Greet(name string) string { return "Hello, " + name }
+
+// greetingModule resolves a greeter during Init
+type greetingModule struct {
+	app      *hop.App
+	greeting string
+}
+
+func (m *greetingModule) ID() string { return "greeting" }
+func (m *greetingModule) Init() error {
+	g, err := hop.Resolve[greeter](m.app)
+	if err != nil {
+		return err
+	}
+	m.greeting = g.Greet("Ada")
+	return nil
+}
+
+func TestServices(t *testing.T) {
+	t.Run("register and resolve", func(t *testing.T) {
+		app, err := createTestApp(t)
+		require.NoError(t, err)
+
+		require.NoError(t, hop.Register[greeter](app, englishGreeter{}))
+
+		g, err := hop.Resolve[greeter](app)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello, Ada", g.Greet("Ada"))
+		assert.Equal(t, "Hello, Ada", hop.MustResolve[greeter](app).Greet("Ada"))
+
+		// Services are keyed by the exact type they were registered under
+		_, err = hop.Resolve[englishGreeter](app)
+		assert.ErrorIs(t, err, hop.ErrServiceNotFound)
+	})
+
+	t.Run("duplicate registration", func(t *testing.T) {
+		app, err := createTestApp(t)
+		require.NoError(t, err)
+
+		require.NoError(t, hop.Register[greeter](app, englishGreeter{}))
+		assert.Error(t, hop.Register[greeter](app, englishGreeter{}))
+	})
+
+	t.Run("missing service", func(t *testing.T) {
+		app, err := createTestApp(t)
+		require.NoError(t, err)
+
+		_, err = hop.Resolve[fmt.Stringer](app)
+		require.ErrorIs(t, err, hop.ErrServiceNotFound)
+		assert.Contains(t, err.Error(), "fmt.Stringer")
+		assert.Panics(t, func() { hop.MustResolve[fmt.Stringer](app) })
+	})
+
+	t.Run("modules resolve services during init", func(t *testing.T) {
+		app, err := createTestApp(t)
+		require.NoError(t, err)
+
+		m := &greetingModule{app: app}
+		app.RegisterModule(m)
+		require.ErrorIs(t, app.Error(), hop.ErrServiceNotFound)
+
+		app, err = createTestApp(t)
+		require.NoError(t, err)
+		require.NoError(t, hop.Register[greeter](app, englishGreeter{}))
+
+		m = &greetingModule{app: app}
+		app.RegisterModule(m)
+		require.NoError(t, app.Error())
+		assert.Equal(t, "Hello, Ada", m.greeting)
+	})
+}
@@ -0,0 +1,58 @@
+package sess
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemoryIndex is an in-memory Index. Sessions are lost when the process exits, so it is only suitable for
+// tests and development.
+type MemoryIndex struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewMemoryIndex creates a new MemoryIndex
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{sessions: make(map[string]Session)}
+}
+
+// Save adds or updates a session
+func (i *MemoryIndex) Save(_ context.Context, s Session) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if existing, ok := i.sessions[s.Token]; ok {
+		s.CreatedAt = existing.CreatedAt
+	}
+	s.Current = false
+	i.sessions[s.Token] = s
+	return nil
+}
+
+// Delete removes a session
+func (i *MemoryIndex) Delete(_ context.Context, token string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.sessions, token)
+	return nil
+}
+
+// ForUser returns the sessions of a user, most recently seen first
+func (i *MemoryIndex) ForUser(_ context.Context, userID string) ([]Session, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var sessions []Session
+	for _, s := range i.sessions {
+		if s.UserID == userID {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.Slice(sessions, func(a, b int) bool {
+		return sessions[a].LastSeenAt.After(sessions[b].LastSeenAt)
+	})
+	return sessions, nil
+}
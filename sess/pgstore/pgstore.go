@@ -0,0 +1,153 @@
+// Package pgstore provides a PostgreSQL-backed scs.Store, so instances of an application behind a load
+// balancer share their sessions. It works with any database/sql driver for PostgreSQL, like pgx's stdlib
+// package or lib/pq, and expects a sessions table with the following schema:
+//
+//	CREATE TABLE sessions (
+//		token TEXT PRIMARY KEY,
+//		data BYTEA NOT NULL,
+//		expiry TIMESTAMPTZ NOT NULL
+//	);
+//	CREATE INDEX sessions_expiry_idx ON sessions (expiry);
+package pgstore
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/patrickward/hop/clock"
+)
+
+// PostgresStore represents the session store.
+type PostgresStore struct {
+	db          *sql.DB
+	stopCleanup chan bool
+	clock       clock.Clock
+}
+
+// NewPostgresStore returns a new PostgresStore instance, with a background cleanup goroutine
+// that runs every 5 minutes to remove expired session data.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return NewPostgresStoreWithCleanupInterval(db, 5*time.Minute)
+}
+
+// NewPostgresStoreWithCleanupInterval returns a new PostgresStore instance. The cleanupInterval
+// parameter controls how frequently expired session data is removed by the background cleanup
+// goroutine. Setting it to 0 prevents the cleanup goroutine from running, for deployments that
+// delete expired sessions themselves, e.g. with a pg_cron job, or that run the cleanup on one
+// instance only.
+func NewPostgresStoreWithCleanupInterval(db *sql.DB, cleanupInterval time.Duration) *PostgresStore {
+	return NewPostgresStoreWithClock(db, cleanupInterval, clock.Real())
+}
+
+// NewPostgresStoreWithClock returns a new PostgresStore instance that uses the given clock to tell
+// whether sessions have expired and to schedule the cleanup, instead of the database's clock.
+func NewPostgresStoreWithClock(db *sql.DB, cleanupInterval time.Duration, clk clock.Clock) *PostgresStore {
+	p := &PostgresStore{db: db, clock: clock.OrReal(clk)}
+	if cleanupInterval > 0 {
+		p.stopCleanup = make(chan bool)
+		go p.startCleanup(cleanupInterval)
+	}
+	return p
+}
+
+// Find returns the data for a given session token from the PostgresStore instance.
+// If the session token is not found or is expired, the returned exists flag will
+// be set to false.
+func (p *PostgresStore) Find(token string) (b []byte, exists bool, err error) {
+	row := p.db.QueryRow("SELECT data FROM sessions WHERE token = $1 AND $2 < expiry", token, p.now())
+	err = row.Scan(&b)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Commit adds a session token and data to the PostgresStore instance with the
+// given expiry time. If the session token already exists, then the data and expiry
+// time are updated.
+func (p *PostgresStore) Commit(token string, b []byte, expiry time.Time) error {
+	_, err := p.db.Exec(`INSERT INTO sessions (token, data, expiry) VALUES ($1, $2, $3)
+		ON CONFLICT (token) DO UPDATE SET data = EXCLUDED.data, expiry = EXCLUDED.expiry`, token, b, expiry.UTC())
+	return err
+}
+
+// Delete removes a session token and corresponding data from the PostgresStore
+// instance.
+func (p *PostgresStore) Delete(token string) error {
+	_, err := p.db.Exec("DELETE FROM sessions WHERE token = $1", token)
+	return err
+}
+
+// All returns a map containing the token and data for all active (i.e.
+// not expired) sessions in the PostgresStore instance.
+func (p *PostgresStore) All() (map[string][]byte, error) {
+	rows, err := p.db.Query("SELECT token, data FROM sessions WHERE $1 < expiry", p.now())
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	sessions := make(map[string][]byte)
+
+	for rows.Next() {
+		var (
+			token string
+			data  []byte
+		)
+
+		err = rows.Scan(&token, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions[token] = data
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (p *PostgresStore) startCleanup(interval time.Duration) {
+	ticker := p.clock.NewTicker(interval)
+	for {
+		select {
+		case <-ticker.C():
+			err := p.deleteExpired()
+			if err != nil {
+				log.Println(err)
+			}
+		case <-p.stopCleanup:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// StopCleanup terminates the background cleanup goroutine for the PostgresStore
+// instance. Like SQLiteStore's, it's mostly useful for transient stores, like those
+// created in tests, which would otherwise never be garbage collected.
+func (p *PostgresStore) StopCleanup() {
+	if p.stopCleanup != nil {
+		p.stopCleanup <- true
+	}
+}
+
+func (p *PostgresStore) deleteExpired() error {
+	_, err := p.db.Exec("DELETE FROM sessions WHERE expiry < $1", p.now())
+	return err
+}
+
+// now returns the current time of the store's clock
+func (p *PostgresStore) now() time.Time {
+	return p.clock.Now().UTC()
+}
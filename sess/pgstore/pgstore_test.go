@@ -0,0 +1,109 @@
+package pgstore_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Import the SQLite driver
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/sess/pgstore"
+)
+
+// newDB returns a database with the sessions table. The tests run the store against SQLite, which
+// understands its queries, so they don't need a PostgreSQL server. The go-sqlite3 driver stores
+// times as text, which compares in time order as every time is in UTC.
+func newDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sessions.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE sessions (
+		token TEXT PRIMARY KEY,
+		data BLOB NOT NULL,
+		expiry TIMESTAMP NOT NULL
+	);
+	CREATE INDEX sessions_expiry_idx ON sessions (expiry);`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestPostgresStore(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("commits, finds and deletes sessions", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		store := pgstore.NewPostgresStoreWithClock(newDB(t), 0, clk)
+
+		_, found, err := store.Find("missing")
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		require.NoError(t, store.Commit("token", []byte("data"), clk.Now().Add(time.Hour)))
+		b, found, err := store.Find("token")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []byte("data"), b)
+
+		require.NoError(t, store.Commit("token", []byte("updated"), clk.Now().Add(time.Hour)))
+		b, _, err = store.Find("token")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("updated"), b)
+
+		require.NoError(t, store.Delete("token"))
+		_, found, err = store.Find("token")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("lists active sessions", func(t *testing.T) {
+		clk := hoptest.NewClock(start)
+		store := pgstore.NewPostgresStoreWithClock(newDB(t), 0, clk)
+
+		require.NoError(t, store.Commit("a", []byte("data a"), clk.Now().Add(time.Hour)))
+		require.NoError(t, store.Commit("b", []byte("data b"), clk.Now().Add(time.Hour)))
+		require.NoError(t, store.Commit("expired", []byte("data"), clk.Now().Add(time.Minute)))
+		clk.Advance(time.Minute)
+
+		sessions, err := store.All()
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"a": []byte("data a"), "b": []byte("data b")}, sessions)
+	})
+
+	t.Run("expires and cleans up sessions", func(t *testing.T) {
+		db := newDB(t)
+		clk := hoptest.NewClock(start)
+		store := pgstore.NewPostgresStoreWithClock(db, time.Hour, clk)
+		defer store.StopCleanup()
+
+		require.NoError(t, store.Commit("token", []byte("data"), clk.Now().Add(30*time.Minute)))
+
+		clk.BlockUntil(1)
+		clk.Advance(29 * time.Minute)
+		_, found, _ := store.Find("token")
+		assert.True(t, found)
+
+		clk.Advance(time.Minute)
+		_, found, _ = store.Find("token")
+		assert.False(t, found)
+
+		// The cleanup runs when the clock reaches the cleanup interval
+		clk.Advance(30 * time.Minute)
+		assert.Eventually(t, func() bool {
+			var count int
+			require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&count))
+			return count == 0
+		}, 2*time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("stops without a cleanup goroutine", func(t *testing.T) {
+		store := pgstore.NewPostgresStoreWithCleanupInterval(newDB(t), 0)
+		store.StopCleanup()
+	})
+}
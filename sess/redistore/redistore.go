@@ -0,0 +1,205 @@
+// Package redistore provides a Redis-backed scs.Store, so instances of an application behind a load
+// balancer share their sessions. Redis expires the sessions itself, so the store has no cleanup
+// goroutine.
+//
+// The store sends commands through the Client interface rather than a particular Redis library. Most
+// clients adapt to it with a ClientFunc, e.g. with go-redis:
+//
+//	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	store := redistore.NewRedisStore(redistore.ClientFunc(func(ctx context.Context, args ...any) (any, error) {
+//		return rdb.Do(ctx, args...).Result()
+//	}))
+//
+// Clients that return an error for missing keys rather than a nil reply, like go-redis's redis.Nil, are
+// supported too: an error with the message "redis: nil" is treated as a nil reply.
+package redistore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultPrefix is the prefix of the keys the sessions are stored in by default
+const DefaultPrefix = "scs:session:"
+
+// nilReply is the message of the error go-redis returns for a nil reply
+const nilReply = "redis: nil"
+
+// scanCount is the number of keys All asks Redis to scan per iteration
+const scanCount = 100
+
+// Client sends a command to Redis and returns its reply: nil for a missing key, a string or []byte for
+// bulk strings, an int64 for integers and a []any for arrays.
+type Client interface {
+	Do(ctx context.Context, args ...any) (any, error)
+}
+
+// ClientFunc adapts a function to a Client
+type ClientFunc func(ctx context.Context, args ...any) (any, error)
+
+// Do calls f(ctx, args...)
+func (f ClientFunc) Do(ctx context.Context, args ...any) (any, error) {
+	return f(ctx, args...)
+}
+
+// RedisStore represents the session store.
+type RedisStore struct {
+	client Client
+	prefix string
+}
+
+// NewRedisStore returns a new RedisStore instance that stores sessions in keys prefixed with
+// DefaultPrefix.
+func NewRedisStore(client Client) *RedisStore {
+	return NewRedisStoreWithPrefix(client, DefaultPrefix)
+}
+
+// NewRedisStoreWithPrefix returns a new RedisStore instance that stores sessions in keys with the
+// given prefix, so several applications can share a Redis database.
+func NewRedisStoreWithPrefix(client Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Find returns the data for a given session token from the RedisStore instance.
+// If the session token is not found or is expired, the returned exists flag will
+// be set to false.
+func (r *RedisStore) Find(token string) (b []byte, exists bool, err error) {
+	return r.FindCtx(context.Background(), token)
+}
+
+// FindCtx is the same as Find, except it takes a context.Context.
+func (r *RedisStore) FindCtx(ctx context.Context, token string) (b []byte, exists bool, err error) {
+	reply, err := r.do(ctx, "GET", r.prefix+token)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	b, err = bytesReply(reply)
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Commit adds a session token and data to the RedisStore instance with the
+// given expiry time. If the session token already exists, then the data and expiry
+// time are updated.
+func (r *RedisStore) Commit(token string, b []byte, expiry time.Time) error {
+	return r.CommitCtx(context.Background(), token, b, expiry)
+}
+
+// CommitCtx is the same as Commit, except it takes a context.Context.
+func (r *RedisStore) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	// PXAT needs Redis 6.2, but unlike SET and PEXPIREAT it sets the data and expiry atomically
+	_, err := r.do(ctx, "SET", r.prefix+token, b, "PXAT", expiry.UnixMilli())
+	return err
+}
+
+// Delete removes a session token and corresponding data from the RedisStore
+// instance.
+func (r *RedisStore) Delete(token string) error {
+	return r.DeleteCtx(context.Background(), token)
+}
+
+// DeleteCtx is the same as Delete, except it takes a context.Context.
+func (r *RedisStore) DeleteCtx(ctx context.Context, token string) error {
+	_, err := r.do(ctx, "DEL", r.prefix+token)
+	return err
+}
+
+// All returns a map containing the token and data for all active (i.e.
+// not expired) sessions in the RedisStore instance.
+func (r *RedisStore) All() (map[string][]byte, error) {
+	return r.AllCtx(context.Background())
+}
+
+// AllCtx is the same as All, except it takes a context.Context. It scans the keys with SCAN rather than
+// KEYS, so it doesn't block Redis while it iterates over a large number of sessions.
+func (r *RedisStore) AllCtx(ctx context.Context) (map[string][]byte, error) {
+	sessions := make(map[string][]byte)
+
+	cursor := "0"
+	for {
+		reply, err := r.do(ctx, "SCAN", cursor, "MATCH", r.prefix+"*", "COUNT", scanCount)
+		if err != nil {
+			return nil, err
+		}
+		next, keys, err := scanReply(reply)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			token, ok := strings.CutPrefix(key, r.prefix)
+			if !ok {
+				continue
+			}
+			// A session that expired since the scan is skipped
+			b, found, err := r.FindCtx(ctx, token)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				sessions[token] = b
+			}
+		}
+
+		if next == "0" {
+			return sessions, nil
+		}
+		cursor = next
+	}
+}
+
+// do sends a command, turning nil reply errors into nil replies
+func (r *RedisStore) do(ctx context.Context, args ...any) (any, error) {
+	reply, err := r.client.Do(ctx, args...)
+	if err != nil {
+		if err.Error() == nilReply {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// bytesReply converts a bulk string reply to bytes
+func bytesReply(reply any) ([]byte, error) {
+	switch v := reply.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unexpected reply type %T", reply)
+	}
+}
+
+// scanReply converts a SCAN reply to its cursor and keys
+func scanReply(reply any) (string, []string, error) {
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return "", nil, fmt.Errorf("unexpected SCAN reply %v", reply)
+	}
+
+	cursor, err := bytesReply(values[0])
+	if err != nil {
+		return "", nil, fmt.Errorf("unexpected SCAN cursor: %w", err)
+	}
+
+	items, ok := values[1].([]any)
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected SCAN keys %v", values[1])
+	}
+	keys := make([]string, len(items))
+	for i, item := range items {
+		key, err := bytesReply(item)
+		if err != nil {
+			return "", nil, fmt.Errorf("unexpected SCAN key: %w", err)
+		}
+		keys[i] = string(key)
+	}
+
+	return string(cursor), keys, nil
+}
@@ -0,0 +1,167 @@
+package redistore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/sess/redistore"
+)
+
+type entry struct {
+	value  []byte
+	expiry time.Time
+}
+
+// fakeRedis implements the commands the store sends, with keys that expire on a fake clock. It
+// returns the keys of a SCAN two at a time, to exercise the cursor, and go-redis's error for
+// missing keys.
+type fakeRedis struct {
+	mu    sync.Mutex
+	clock *hoptest.Clock
+	data  map[string]entry
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		clock: hoptest.NewClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)),
+		data:  make(map[string]entry),
+	}
+}
+
+func (f *fakeRedis) Do(_ context.Context, args ...any) (any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, e := range f.data {
+		if !f.clock.Now().Before(e.expiry) {
+			delete(f.data, key)
+		}
+	}
+
+	switch args[0] {
+	case "GET":
+		e, ok := f.data[args[1].(string)]
+		if !ok {
+			return nil, errors.New("redis: nil")
+		}
+		return string(e.value), nil
+	case "SET":
+		if args[3] != "PXAT" {
+			return nil, fmt.Errorf("unexpected SET option %v", args[3])
+		}
+		f.data[args[1].(string)] = entry{value: args[2].([]byte), expiry: time.UnixMilli(args[4].(int64))}
+		return "OK", nil
+	case "DEL":
+		delete(f.data, args[1].(string))
+		return int64(1), nil
+	case "SCAN":
+		var keys []string
+		for key := range f.data {
+			if strings.HasPrefix(key, strings.TrimSuffix(args[3].(string), "*")) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var start int
+		_, _ = fmt.Sscan(args[1].(string), &start)
+		end := min(start+2, len(keys))
+		next := fmt.Sprint(end)
+		if end == len(keys) {
+			next = "0"
+		}
+		page := make([]any, 0, end-start)
+		for _, key := range keys[start:end] {
+			page = append(page, []byte(key))
+		}
+		return []any{[]byte(next), page}, nil
+	}
+	return nil, fmt.Errorf("unexpected command %v", args[0])
+}
+
+func TestRedisStore(t *testing.T) {
+	t.Run("commits, finds and deletes sessions", func(t *testing.T) {
+		client := newFakeRedis()
+		store := redistore.NewRedisStore(client)
+
+		_, found, err := store.Find("missing")
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		require.NoError(t, store.Commit("token", []byte("data"), client.clock.Now().Add(time.Hour)))
+		b, found, err := store.Find("token")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []byte("data"), b)
+		assert.Contains(t, client.data, "scs:session:token")
+
+		require.NoError(t, store.Commit("token", []byte("updated"), client.clock.Now().Add(time.Hour)))
+		b, _, err = store.Find("token")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("updated"), b)
+
+		require.NoError(t, store.Delete("token"))
+		_, found, err = store.Find("token")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("sessions expire", func(t *testing.T) {
+		client := newFakeRedis()
+		store := redistore.NewRedisStore(client)
+
+		require.NoError(t, store.Commit("token", []byte("data"), client.clock.Now().Add(30*time.Minute)))
+		client.clock.Advance(29 * time.Minute)
+		_, found, _ := store.Find("token")
+		assert.True(t, found)
+
+		client.clock.Advance(time.Minute)
+		_, found, _ = store.Find("token")
+		assert.False(t, found)
+	})
+
+	t.Run("lists every session under its prefix", func(t *testing.T) {
+		client := newFakeRedis()
+		store := redistore.NewRedisStoreWithPrefix(client, "app:")
+		other := redistore.NewRedisStore(client)
+
+		expiry := client.clock.Now().Add(time.Hour)
+		for _, token := range []string{"a", "b", "c", "d", "e"} {
+			require.NoError(t, store.Commit(token, []byte("data "+token), expiry))
+		}
+		require.NoError(t, store.Commit("expired", []byte("data"), client.clock.Now().Add(time.Minute)))
+		require.NoError(t, other.Commit("other", []byte("data"), expiry))
+		client.clock.Advance(time.Minute)
+
+		sessions, err := store.All()
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{
+			"a": []byte("data a"),
+			"b": []byte("data b"),
+			"c": []byte("data c"),
+			"d": []byte("data d"),
+			"e": []byte("data e"),
+		}, sessions)
+	})
+
+	t.Run("returns client errors", func(t *testing.T) {
+		store := redistore.NewRedisStore(redistore.ClientFunc(func(context.Context, ...any) (any, error) {
+			return nil, errors.New("connection refused")
+		}))
+
+		_, _, err := store.Find("token")
+		assert.EqualError(t, err, "connection refused")
+		_, err = store.All()
+		assert.EqualError(t, err, "connection refused")
+	})
+}
@@ -0,0 +1,295 @@
+// Package sess tracks the sessions of each user, so applications can list the devices a user is logged in
+// on and log them out, e.g. for a "log out other devices" button. A Tracker's middleware records the
+// token of every session holding a user ID in an Index, and revoking a session deletes it from the
+// session store, so the device is logged out on its next request. Set Options.Remember to the auth
+// package's RememberStore so revoking the sessions of a user also revokes their remember-me tokens, which
+// would otherwise log the devices back in.
+//
+// Implementations of Index are provided for memory (for tests) and, in the sqlitestore package, for
+// SQLite, next to the sessions table of its SQLiteStore.
+//
+//	index := sqlitestore.NewUserIndex(db, db)
+//	tracker := sess.NewTracker(app.Session(), index, func(opts *sess.Options) {
+//	    opts.Remember = rememberStore
+//	})
+//	router.Use(app.Session().LoadAndSave, tracker.Middleware)
+//
+//	sessions, err := tracker.ListSessions(r.Context(), user.AuthID())
+//	err = tracker.RevokeOtherSessions(r.Context())
+package sess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+
+	"github.com/patrickward/hop/clock"
+	"github.com/patrickward/hop/route/middleware"
+)
+
+// Session keys the tracker stores its state in
+const (
+	trackedTokenKey = "sess.tracked_token"
+	trackedAtKey    = "sess.tracked_at"
+)
+
+// Session describes a session of a user
+type Session struct {
+	Token      string
+	UserID     string
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	// Current is true for the session of the request ListSessions was called with
+	Current bool
+}
+
+// Index stores the sessions of each user
+type Index interface {
+	// Save adds a session, or updates the user agent, IP address and LastSeenAt of an existing one,
+	// keeping its CreatedAt
+	Save(ctx context.Context, s Session) error
+	// Delete removes a session. Deleting a session that doesn't exist is not an error.
+	Delete(ctx context.Context, token string) error
+	// ForUser returns the sessions of a user, most recently seen first
+	ForUser(ctx context.Context, userID string) ([]Session, error)
+}
+
+// RememberStore deletes the remember-me tokens of a user. auth.RememberStore implements it.
+type RememberStore interface {
+	DeleteForUser(ctx context.Context, userID string) error
+}
+
+// Options configures a Tracker
+type Options struct {
+	// UserKey is the session key holding the user ID. Defaults to "auth.user_id", the key of the auth
+	// package.
+	UserKey string
+	// TouchInterval is how often the LastSeenAt of a session is updated. Defaults to 1 minute.
+	TouchInterval time.Duration
+	// Remember, when set, has its remember-me tokens for a user deleted by RevokeAllForUser and
+	// RevokeOtherSessions, so revoked devices aren't logged back in by a remember-me cookie
+	Remember RememberStore
+	// Clock tells the time sessions are seen at. Defaults to the real clock.
+	Clock clock.Clock
+	// Logger is used to log failures to update the index. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Tracker tracks the sessions of each user
+type Tracker struct {
+	sessions *scs.SessionManager
+	index    Index
+	opts     Options
+}
+
+// NewTracker creates a Tracker for the sessions of the session manager
+func NewTracker(sessions *scs.SessionManager, index Index, optsFunc func(opts *Options)) *Tracker {
+	opts := Options{
+		UserKey:       "auth.user_id",
+		TouchInterval: time.Minute,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.UserKey == "" {
+		opts.UserKey = "auth.user_id"
+	}
+	if opts.TouchInterval <= 0 {
+		opts.TouchInterval = time.Minute
+	}
+	opts.Clock = clock.OrReal(opts.Clock)
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	return &Tracker{sessions: sessions, index: index, opts: opts}
+}
+
+// Middleware records the session of each request in the index, so a session is tracked from the
+// request that logs the user in, saved again at most once per TouchInterval, and removed when the user
+// logs out or the token is renewed. It must be used inside the session manager's LoadAndSave middleware.
+func (t *Tracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &trackingWriter{ResponseWriter: w, tracker: t, request: r}
+		next.ServeHTTP(tw, r)
+		tw.track()
+	})
+}
+
+// trackingWriter tracks the session before the response is written, as the session manager saves the
+// session once the handler starts writing its response
+type trackingWriter struct {
+	http.ResponseWriter
+	tracker *Tracker
+	request *http.Request
+	tracked bool
+}
+
+func (tw *trackingWriter) track() {
+	if !tw.tracked {
+		tw.tracked = true
+		tw.tracker.track(tw.request)
+	}
+}
+
+func (tw *trackingWriter) WriteHeader(status int) {
+	tw.track()
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *trackingWriter) Write(b []byte) (int, error) {
+	tw.track()
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so http.ResponseController can reach it
+func (tw *trackingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// track updates the index with the session of the request
+func (t *Tracker) track(r *http.Request) {
+	ctx := r.Context()
+	token := t.sessions.Token(ctx)
+	userID := t.sessions.GetString(ctx, t.opts.UserKey)
+	tracked := t.sessions.GetString(ctx, trackedTokenKey)
+
+	// The session was renewed or logged out, so its old token is gone
+	if tracked != "" && (tracked != token || userID == "") {
+		if err := t.index.Delete(ctx, tracked); err != nil {
+			t.opts.Logger.Error("Failed to remove session from index", slog.String("error", err.Error()))
+		}
+		t.sessions.Remove(ctx, trackedTokenKey)
+		t.sessions.Remove(ctx, trackedAtKey)
+		tracked = ""
+	}
+
+	// New sessions have no token until they are saved, so they are tracked on their next request
+	if userID == "" || token == "" {
+		return
+	}
+
+	now := t.opts.Clock.Now()
+	if tracked == token && now.Sub(time.UnixMilli(t.sessions.GetInt64(ctx, trackedAtKey))) < t.opts.TouchInterval {
+		return
+	}
+
+	err := t.index.Save(ctx, Session{
+		Token:      token,
+		UserID:     userID,
+		UserAgent:  r.UserAgent(),
+		IPAddress:  middleware.ClientIP(r),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	})
+	if err != nil {
+		t.opts.Logger.Error("Failed to save session to index", slog.String("error", err.Error()))
+		return
+	}
+	t.sessions.Put(ctx, trackedTokenKey, token)
+	// Stored as an int64, as the session manager's codec can't encode a time.Time
+	t.sessions.Put(ctx, trackedAtKey, now.UnixMilli())
+}
+
+// ListSessions returns the active sessions of a user, most recently seen first. Sessions that expired
+// are removed from the index. When ctx holds a session, like the context of a request, that session is
+// marked as Current.
+func (t *Tracker) ListSessions(ctx context.Context, userID string) ([]Session, error) {
+	indexed, err := t.index.ForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+
+	current := t.currentToken(ctx)
+	sessions := make([]Session, 0, len(indexed))
+	for _, s := range indexed {
+		_, found, err := t.sessions.Store.Find(s.Token)
+		if err != nil {
+			return nil, fmt.Errorf("finding session: %w", err)
+		}
+		if !found {
+			if err := t.index.Delete(ctx, s.Token); err != nil {
+				return nil, fmt.Errorf("removing expired session: %w", err)
+			}
+			continue
+		}
+		s.Current = s.Token == current
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// RevokeSession deletes a session, logging its device out. Remember-me tokens can't be matched to a
+// session, so they are left in place; use RevokeOtherSessions or RevokeAllForUser to revoke them too.
+func (t *Tracker) RevokeSession(ctx context.Context, token string) error {
+	if err := t.sessions.Store.Delete(token); err != nil {
+		return fmt.Errorf("deleting session: %w", err)
+	}
+	if err := t.index.Delete(ctx, token); err != nil {
+		return fmt.Errorf("removing session from index: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser deletes every session of a user, e.g. after a password reset
+func (t *Tracker) RevokeAllForUser(ctx context.Context, userID string) error {
+	return t.revokeForUser(ctx, userID, "")
+}
+
+// RevokeOtherSessions deletes the sessions of the user of ctx's session, except that session, logging
+// the user out of every other device. With Options.Remember set, every remember-me token of the user is
+// deleted, including the current device's: it stays logged in for as long as its session lasts.
+func (t *Tracker) RevokeOtherSessions(ctx context.Context) error {
+	userID := t.sessions.GetString(ctx, t.opts.UserKey)
+	if userID == "" {
+		return errors.New("revoking other sessions: no user is logged in")
+	}
+	return t.revokeForUser(ctx, userID, t.sessions.Token(ctx))
+}
+
+// revokeForUser deletes the remember-me tokens and sessions of a user, except the session with the
+// token keep
+func (t *Tracker) revokeForUser(ctx context.Context, userID, keep string) error {
+	// Remember-me tokens go first, so a device can't be logged back in between the two
+	if t.opts.Remember != nil {
+		if err := t.opts.Remember.DeleteForUser(ctx, userID); err != nil {
+			return fmt.Errorf("deleting remember-me tokens: %w", err)
+		}
+	}
+
+	sessions, err := t.index.ForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("listing sessions: %w", err)
+	}
+
+	var errs []error
+	for _, s := range sessions {
+		if s.Token == keep {
+			continue
+		}
+		if err := t.RevokeSession(ctx, s.Token); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// currentToken returns the token of ctx's session, or "" when ctx holds no session
+func (t *Tracker) currentToken(ctx context.Context) (token string) {
+	// The session manager panics for contexts without session data
+	defer func() {
+		if recover() != nil {
+			token = ""
+		}
+	}()
+	return t.sessions.Token(ctx)
+}
@@ -0,0 +1,272 @@
+package sess_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/auth"
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/sess"
+)
+
+var _ sess.RememberStore = (auth.RememberStore)(nil)
+
+// testUser is a user of the auth package
+type testUser string
+
+func (u testUser) AuthID() string { return string(u) }
+
+// testApp is an application with login, logout and session management routes
+type testApp struct {
+	sessions *scs.SessionManager
+	index    *sess.MemoryIndex
+	remember *auth.MemoryRememberStore
+	tracker  *sess.Tracker
+	clock    *hoptest.Clock
+	handler  http.Handler
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+
+	a := &testApp{
+		sessions: scs.New(),
+		index:    sess.NewMemoryIndex(),
+		remember: auth.NewMemoryRememberStore(),
+		clock:    hoptest.NewClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)),
+	}
+	a.sessions.Store = memstore.NewWithCleanupInterval(0)
+	a.tracker = sess.NewTracker(a.sessions, a.index, func(opts *sess.Options) {
+		opts.Remember = a.remember
+		opts.Clock = a.clock
+	})
+	authn := auth.New(&auth.Config{
+		Session: a.sessions,
+		Users: auth.UserStoreFunc(func(_ context.Context, id string) (auth.User, error) {
+			return testUser(id), nil
+		}),
+		Remember: a.remember,
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, a.sessions.RenewToken(r.Context()))
+		a.sessions.Put(r.Context(), "auth.user_id", r.URL.Query().Get("user"))
+	})
+	mux.HandleFunc("/login-remember", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, authn.Login(w, r, testUser(r.URL.Query().Get("user")), true))
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		a.sessions.Remove(r.Context(), "auth.user_id")
+		require.NoError(t, a.sessions.RenewToken(r.Context()))
+	})
+	mux.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(a.sessions.GetString(r.Context(), "auth.user_id")))
+	})
+	mux.HandleFunc("/sessions", func(w http.ResponseWriter, r *http.Request) {
+		sessions, err := a.tracker.ListSessions(r.Context(), a.sessions.GetString(r.Context(), "auth.user_id"))
+		require.NoError(t, err)
+		for _, s := range sessions {
+			_, _ = fmt.Fprintf(w, "%s %v\n", s.UserAgent, s.Current)
+		}
+	})
+	mux.HandleFunc("/revoke-others", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, a.tracker.RevokeOtherSessions(r.Context()))
+	})
+	a.handler = a.sessions.LoadAndSave(a.tracker.Middleware(authn.Middleware(mux)))
+
+	return a
+}
+
+// device is a browser holding a session cookie, and a remember-me cookie once remembered
+type device struct {
+	app      *testApp
+	name     string
+	cookie   *http.Cookie
+	remember *http.Cookie
+}
+
+func (d *device) get(path string) string {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("User-Agent", d.name)
+	if d.cookie != nil {
+		req.AddCookie(d.cookie)
+	}
+	if d.remember != nil {
+		req.AddCookie(d.remember)
+	}
+	rec := httptest.NewRecorder()
+	d.app.handler.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		switch {
+		case c.Name == d.app.sessions.Cookie.Name:
+			d.cookie = c
+		case c.Name == "remember_token" && c.MaxAge < 0:
+			d.remember = nil
+		case c.Name == "remember_token":
+			d.remember = c
+		}
+	}
+	return rec.Body.String()
+}
+
+func (d *device) token() string {
+	return d.cookie.Value
+}
+
+func TestTracker(t *testing.T) {
+	t.Run("lists the sessions of a user", func(t *testing.T) {
+		app := newTestApp(t)
+		laptop := &device{app: app, name: "laptop"}
+		phone := &device{app: app, name: "phone"}
+		other := &device{app: app, name: "other"}
+
+		laptop.get("/login?user=42")
+		app.clock.Advance(time.Second)
+		phone.get("/login?user=42")
+		other.get("/login?user=7")
+
+		assert.Equal(t, "phone false\nlaptop true\n", laptop.get("/sessions"))
+		assert.Equal(t, "other true\n", other.get("/sessions"))
+	})
+
+	t.Run("logs out other devices", func(t *testing.T) {
+		app := newTestApp(t)
+		laptop := &device{app: app, name: "laptop"}
+		phone := &device{app: app, name: "phone"}
+
+		laptop.get("/login?user=42")
+		phone.get("/login?user=42")
+		laptop.get("/revoke-others")
+
+		assert.Equal(t, "", phone.get("/whoami"))
+		assert.Equal(t, "42", laptop.get("/whoami"))
+		assert.Equal(t, "laptop true\n", laptop.get("/sessions"))
+	})
+
+	t.Run("revokes every session of a user", func(t *testing.T) {
+		app := newTestApp(t)
+		laptop := &device{app: app, name: "laptop"}
+		phone := &device{app: app, name: "phone"}
+
+		laptop.get("/login?user=42")
+		phone.get("/login?user=42")
+		require.NoError(t, app.tracker.RevokeAllForUser(context.Background(), "42"))
+
+		assert.Equal(t, "", laptop.get("/whoami"))
+		assert.Equal(t, "", phone.get("/whoami"))
+		sessions, err := app.tracker.ListSessions(context.Background(), "42")
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("revokes the remember-me tokens of other devices", func(t *testing.T) {
+		app := newTestApp(t)
+		laptop := &device{app: app, name: "laptop"}
+		phone := &device{app: app, name: "phone"}
+
+		laptop.get("/login-remember?user=42")
+		phone.get("/login-remember?user=42")
+		require.NotNil(t, phone.remember)
+		laptop.get("/revoke-others")
+
+		assert.Equal(t, "", phone.get("/whoami"), "the remember-me cookie must not log the device back in")
+		assert.Nil(t, phone.remember)
+		assert.Equal(t, "42", laptop.get("/whoami"))
+	})
+
+	t.Run("revokes every remember-me token of a user", func(t *testing.T) {
+		app := newTestApp(t)
+		laptop := &device{app: app, name: "laptop"}
+		phone := &device{app: app, name: "phone"}
+
+		laptop.get("/login-remember?user=42")
+		phone.get("/login-remember?user=42")
+		require.NoError(t, app.tracker.RevokeAllForUser(context.Background(), "42"))
+
+		assert.Equal(t, "", laptop.get("/whoami"))
+		assert.Equal(t, "", phone.get("/whoami"))
+	})
+
+	t.Run("revokes a single session", func(t *testing.T) {
+		app := newTestApp(t)
+		laptop := &device{app: app, name: "laptop"}
+		phone := &device{app: app, name: "phone"}
+
+		laptop.get("/login?user=42")
+		phone.get("/login?user=42")
+		require.NoError(t, app.tracker.RevokeSession(context.Background(), phone.token()))
+
+		assert.Equal(t, "", phone.get("/whoami"))
+		assert.Equal(t, "42", laptop.get("/whoami"))
+	})
+
+	t.Run("forgets renewed and logged out sessions", func(t *testing.T) {
+		app := newTestApp(t)
+		laptop := &device{app: app, name: "laptop"}
+
+		laptop.get("/login?user=42")
+		first := laptop.token()
+		laptop.get("/login?user=42")
+		require.NotEqual(t, first, laptop.token())
+
+		sessions, err := app.index.ForUser(context.Background(), "42")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, laptop.token(), sessions[0].Token)
+
+		laptop.get("/logout")
+		sessions, err = app.index.ForUser(context.Background(), "42")
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("removes expired sessions from the index", func(t *testing.T) {
+		app := newTestApp(t)
+		laptop := &device{app: app, name: "laptop"}
+
+		laptop.get("/login?user=42")
+		require.NoError(t, app.sessions.Store.Delete(laptop.token()))
+
+		sessions, err := app.tracker.ListSessions(context.Background(), "42")
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+		indexed, err := app.index.ForUser(context.Background(), "42")
+		require.NoError(t, err)
+		assert.Empty(t, indexed)
+	})
+
+	t.Run("updates the last seen time once per touch interval", func(t *testing.T) {
+		app := newTestApp(t)
+		laptop := &device{app: app, name: "laptop"}
+
+		laptop.get("/login?user=42")
+		loggedIn := app.clock.Now()
+
+		app.clock.Advance(30 * time.Second)
+		laptop.get("/whoami")
+		sessions, err := app.index.ForUser(context.Background(), "42")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, loggedIn, sessions[0].LastSeenAt)
+
+		app.clock.Advance(30 * time.Second)
+		laptop.get("/whoami")
+		sessions, err = app.index.ForUser(context.Background(), "42")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, loggedIn, sessions[0].CreatedAt)
+		assert.Equal(t, app.clock.Now(), sessions[0].LastSeenAt)
+		assert.True(t, strings.HasPrefix(sessions[0].IPAddress, "192.0.2."), sessions[0].IPAddress)
+	})
+}
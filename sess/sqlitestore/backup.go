@@ -0,0 +1,103 @@
+package sqlitestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Backup is a hop.BackupModule holding the sessions of a SQLiteStore, so signed-in users stay signed in
+// after a restore. Register it with the App next to the session store:
+//
+//	app.RegisterModule(sqlitestore.NewBackup(store))
+type Backup struct {
+	store *SQLiteStore
+}
+
+// backupSession is a session as written to a backup, one JSON object per line
+type backupSession struct {
+	Token  string  `json:"token"`
+	Data   []byte  `json:"data"`
+	Expiry float64 `json:"expiry"`
+}
+
+// NewBackup creates a Backup for the sessions of store
+func NewBackup(store *SQLiteStore) *Backup {
+	return &Backup{store: store}
+}
+
+// ID implements hop.Module
+func (b *Backup) ID() string {
+	return "hop.sessions"
+}
+
+// Init implements hop.Module
+func (b *Backup) Init() error {
+	return nil
+}
+
+// Start implements hop.Module
+func (b *Backup) Start(context.Context) error {
+	return nil
+}
+
+// Stop implements hop.Module
+func (b *Backup) Stop(context.Context) error {
+	return nil
+}
+
+// Backup writes the sessions that haven't expired to w
+func (b *Backup) Backup(ctx context.Context, w io.Writer) error {
+	rows, err := b.store.readDB.QueryContext(ctx,
+		"SELECT token, data, expiry FROM sessions WHERE JULIANDAY($1) < expiry ORDER BY token", b.store.now())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	enc := json.NewEncoder(w)
+	for rows.Next() {
+		var s backupSession
+		if err := rows.Scan(&s.Token, &s.Data, &s.Expiry); err != nil {
+			return err
+		}
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Restore replaces all sessions with those read from r
+func (b *Backup) Restore(ctx context.Context, r io.Reader) error {
+	tx, err := b.store.writeDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sessions"); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(r)
+	for {
+		var s backupSession
+		err := dec.Decode(&s)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("decoding session: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "INSERT INTO sessions (token, data, expiry) VALUES ($1, $2, $3)",
+			s.Token, s.Data, s.Expiry); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
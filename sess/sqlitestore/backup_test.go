@@ -0,0 +1,54 @@
+package sqlitestore_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/hoptest"
+	"github.com/patrickward/hop/sess/sqlitestore"
+)
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open(dbDriver, filepath.Join(t.TempDir(), "sessions.db"))
+	require.NoError(t, err)
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+	require.NoError(t, createDBWithSessionTable(db))
+
+	clk := hoptest.NewClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := sqlitestore.NewSQLiteStoreWithClock(db, db, 0, clk)
+	require.NoError(t, store.Commit("active", []byte("alice"), clk.Now().Add(time.Hour)))
+	require.NoError(t, store.Commit("expired", []byte("bob"), clk.Now().Add(-time.Hour)))
+
+	backup := sqlitestore.NewBackup(store)
+	assert.Equal(t, "hop.sessions", backup.ID())
+
+	var buf bytes.Buffer
+	require.NoError(t, backup.Backup(ctx, &buf))
+	assert.NotContains(t, buf.String(), "expired", "expired sessions should be left out")
+
+	// Sessions created after the backup are dropped by the restore
+	require.NoError(t, store.Delete("active"))
+	require.NoError(t, store.Commit("later", []byte("carol"), clk.Now().Add(time.Hour)))
+
+	require.NoError(t, backup.Restore(ctx, &buf))
+
+	sessions, err := store.All()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"active": []byte("alice")}, sessions)
+
+	// The restored session keeps its expiry
+	clk.Advance(time.Hour)
+	_, found, err := store.Find("active")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
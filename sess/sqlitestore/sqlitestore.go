@@ -5,13 +5,19 @@ import (
 	"errors"
 	"log"
 	"time"
+
+	"github.com/patrickward/hop/clock"
 )
 
+// timeFormat is the format of the times passed to SQLite's JULIANDAY function
+const timeFormat = "2006-01-02T15:04:05.999"
+
 // SQLiteStore represents the session store.
 type SQLiteStore struct {
 	readDB      *sql.DB
 	writeDB     *sql.DB
 	stopCleanup chan bool
+	clock       clock.Clock
 }
 
 // NewSQLiteStore returns a new SQLiteStore instance, with a background cleanup goroutine
@@ -25,7 +31,14 @@ func NewSQLiteStore(readDB *sql.DB, writeDB *sql.DB) *SQLiteStore {
 // background cleanup goroutine. Setting it to 0 prevents the cleanup goroutine
 // from running (i.e. expired sessions will not be removed).
 func NewSQLiteStoreWithCleanupInterval(readDB *sql.DB, writeDB *sql.DB, cleanupInterval time.Duration) *SQLiteStore {
-	p := &SQLiteStore{readDB: readDB, writeDB: writeDB}
+	return NewSQLiteStoreWithClock(readDB, writeDB, cleanupInterval, clock.Real())
+}
+
+// NewSQLiteStoreWithClock returns a new SQLiteStore instance that uses the given clock to tell
+// whether sessions have expired and to schedule the cleanup, instead of the database's clock. Tests
+// can pass a fake clock from the hoptest package to expire sessions without waiting.
+func NewSQLiteStoreWithClock(readDB *sql.DB, writeDB *sql.DB, cleanupInterval time.Duration, clk clock.Clock) *SQLiteStore {
+	p := &SQLiteStore{readDB: readDB, writeDB: writeDB, clock: clock.OrReal(clk)}
 	if cleanupInterval > 0 {
 		go p.startCleanup(cleanupInterval)
 	}
@@ -36,7 +49,7 @@ func NewSQLiteStoreWithCleanupInterval(readDB *sql.DB, writeDB *sql.DB, cleanupI
 // If the session token is not found or is expired, the returned exists flag will
 // be set to false.
 func (p *SQLiteStore) Find(token string) (b []byte, exists bool, err error) {
-	row := p.readDB.QueryRow("SELECT data FROM sessions WHERE token = $1 AND JULIANDAY('now') < expiry", token)
+	row := p.readDB.QueryRow("SELECT data FROM sessions WHERE token = $1 AND JULIANDAY($2) < expiry", token, p.now())
 	err = row.Scan(&b)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, false, nil
@@ -50,7 +63,7 @@ func (p *SQLiteStore) Find(token string) (b []byte, exists bool, err error) {
 // given expiry time. If the session token already exists, then the data and expiry
 // time are updated.
 func (p *SQLiteStore) Commit(token string, b []byte, expiry time.Time) error {
-	_, err := p.writeDB.Exec("REPLACE INTO sessions (token, data, expiry) VALUES ($1, $2, JULIANDAY($3))", token, b, expiry.UTC().Format("2006-01-02T15:04:05.999"))
+	_, err := p.writeDB.Exec("REPLACE INTO sessions (token, data, expiry) VALUES ($1, $2, JULIANDAY($3))", token, b, expiry.UTC().Format(timeFormat))
 	if err != nil {
 		return err
 	}
@@ -67,7 +80,7 @@ func (p *SQLiteStore) Delete(token string) error {
 // All returns a map containing the token and data for all active (i.e.
 // not expired) sessions in the SQLiteStore instance.
 func (p *SQLiteStore) All() (map[string][]byte, error) {
-	rows, err := p.readDB.Query("SELECT token, data FROM sessions WHERE JULIANDAY('now') < expiry")
+	rows, err := p.readDB.Query("SELECT token, data FROM sessions WHERE JULIANDAY($1) < expiry", p.now())
 	if err != nil {
 		return nil, err
 	}
@@ -101,10 +114,10 @@ func (p *SQLiteStore) All() (map[string][]byte, error) {
 
 func (p *SQLiteStore) startCleanup(interval time.Duration) {
 	p.stopCleanup = make(chan bool)
-	ticker := time.NewTicker(interval)
+	ticker := p.clock.NewTicker(interval)
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			err := p.deleteExpired()
 			if err != nil {
 				log.Println(err)
@@ -133,6 +146,11 @@ func (p *SQLiteStore) StopCleanup() {
 }
 
 func (p *SQLiteStore) deleteExpired() error {
-	_, err := p.writeDB.Exec("DELETE FROM sessions WHERE expiry < JULIANDAY('now')")
+	_, err := p.writeDB.Exec("DELETE FROM sessions WHERE expiry < JULIANDAY($1)", p.now())
 	return err
 }
+
+// now returns the current time in the format passed to JULIANDAY
+func (p *SQLiteStore) now() string {
+	return p.clock.Now().UTC().Format(timeFormat)
+}
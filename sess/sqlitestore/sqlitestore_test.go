@@ -3,14 +3,15 @@ package sqlitestore_test
 import (
 	"bytes"
 	"database/sql"
-	"fmt"
 	"os"
 	"reflect"
+	"runtime"
 	"testing"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3" // Import the SQLite driver
 
+	"github.com/patrickward/hop/hoptest"
 	"github.com/patrickward/hop/sess/sqlitestore"
 )
 
@@ -248,9 +249,9 @@ func TestExpiry(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	p := sqlitestore.NewSQLiteStoreWithCleanupInterval(db, db, 0)
-	fmt.Print()
-	err = p.Commit("session_token", []byte("encoded_data"), time.Now().Add(100*time.Millisecond))
+	clk := hoptest.NewClock(time.Now())
+	p := sqlitestore.NewSQLiteStoreWithClock(db, db, 0, clk)
+	err = p.Commit("session_token", []byte("encoded_data"), clk.Now().Add(100*time.Millisecond))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -260,7 +261,7 @@ func TestExpiry(t *testing.T) {
 		t.Fatalf("got %v: expected %v", found, true)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	clk.Advance(100 * time.Millisecond)
 	_, found, _ = p.Find("session_token")
 	if found != false {
 		t.Fatalf("got %v: expected %v", found, false)
@@ -290,10 +291,11 @@ func TestCleanup(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	p := sqlitestore.NewSQLiteStoreWithCleanupInterval(db, db, 200*time.Millisecond)
+	clk := hoptest.NewClock(time.Now())
+	p := sqlitestore.NewSQLiteStoreWithClock(db, db, 200*time.Millisecond, clk)
 	defer p.StopCleanup()
 
-	err = p.Commit("session_token", []byte("encoded_data"), time.Now().Add(100*time.Millisecond))
+	err = p.Commit("session_token", []byte("encoded_data"), clk.Now().Add(100*time.Millisecond))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -308,15 +310,9 @@ func TestCleanup(t *testing.T) {
 		t.Fatalf("got %d: expected %d", count, 1)
 	}
 
-	time.Sleep(300 * time.Millisecond)
-	row = db.QueryRow("SELECT COUNT(*) FROM sessions WHERE token = 'session_token'")
-	err = row.Scan(&count)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if count != 0 {
-		t.Fatalf("got %d: expected %d", count, 0)
-	}
+	clk.BlockUntil(1)
+	clk.Advance(200 * time.Millisecond)
+	waitForCount(t, db, 0)
 }
 
 func TestStopNilCleanup(t *testing.T) {
@@ -339,7 +335,70 @@ func TestStopNilCleanup(t *testing.T) {
 	}
 
 	p := sqlitestore.NewSQLiteStoreWithCleanupInterval(db, db, 0)
-	time.Sleep(100 * time.Millisecond)
 	// A send to a nil channel will block forever
 	p.StopCleanup()
 }
+
+func TestExpiryWithClock(t *testing.T) {
+	if err := removeDBfile(); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(dbDriver, testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+	defer func(name string) {
+		_ = os.Remove(name)
+	}(testDSN)
+
+	if err := createDBWithSessionTable(db); err != nil {
+		t.Fatal(err)
+	}
+
+	clk := hoptest.NewClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	p := sqlitestore.NewSQLiteStoreWithClock(db, db, time.Hour, clk)
+	defer p.StopCleanup()
+
+	err = p.Commit("session_token", []byte("encoded_data"), clk.Now().Add(30*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clk.BlockUntil(1)
+	clk.Advance(29 * time.Minute)
+	if _, found, _ := p.Find("session_token"); found != true {
+		t.Fatalf("got %v: expected %v", found, true)
+	}
+
+	clk.Advance(time.Minute)
+	if _, found, _ := p.Find("session_token"); found != false {
+		t.Fatalf("got %v: expected %v", found, false)
+	}
+
+	// The cleanup runs when the clock reaches the cleanup interval
+	clk.Advance(30 * time.Minute)
+	waitForCount(t, db, 0)
+}
+
+// waitForCount waits for the background cleanup to leave want sessions in the table
+func waitForCount(t *testing.T, db *sql.DB, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d: expected %d", count, want)
+		}
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,101 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/patrickward/hop/sess"
+)
+
+// UserIndex is a sess.Index that keeps the sessions of each user in a session_users table, next to the
+// sessions table of the SQLiteStore. Migrate creates the table:
+//
+//	CREATE TABLE session_users (
+//		token TEXT PRIMARY KEY,
+//		user_id TEXT NOT NULL,
+//		user_agent TEXT NOT NULL DEFAULT '',
+//		ip_address TEXT NOT NULL DEFAULT '',
+//		created_at INTEGER NOT NULL,
+//		last_seen_at INTEGER NOT NULL
+//	);
+//	CREATE INDEX session_users_user_id_idx ON session_users(user_id);
+type UserIndex struct {
+	readDB  *sql.DB
+	writeDB *sql.DB
+}
+
+// NewUserIndex returns a new UserIndex instance
+func NewUserIndex(readDB *sql.DB, writeDB *sql.DB) *UserIndex {
+	return &UserIndex{readDB: readDB, writeDB: writeDB}
+}
+
+// Migrate creates the session_users table if it does not exist
+func (u *UserIndex) Migrate(ctx context.Context) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS session_users (
+			token TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			user_agent TEXT NOT NULL DEFAULT '',
+			ip_address TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL,
+			last_seen_at INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS session_users_user_id_idx ON session_users(user_id)`,
+	}
+
+	for _, q := range queries {
+		if _, err := u.writeDB.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("creating session_users table: %w", err)
+		}
+	}
+	return nil
+}
+
+// Save adds a session, or updates an existing one, keeping its created_at
+func (u *UserIndex) Save(ctx context.Context, s sess.Session) error {
+	_, err := u.writeDB.ExecContext(ctx, `INSERT INTO session_users (token, user_id, user_agent, ip_address, created_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (token) DO UPDATE SET user_id = excluded.user_id, user_agent = excluded.user_agent,
+			ip_address = excluded.ip_address, last_seen_at = excluded.last_seen_at`,
+		s.Token, s.UserID, s.UserAgent, s.IPAddress, s.CreatedAt.UnixMilli(), s.LastSeenAt.UnixMilli())
+	return err
+}
+
+// Delete removes a session
+func (u *UserIndex) Delete(ctx context.Context, token string) error {
+	_, err := u.writeDB.ExecContext(ctx, "DELETE FROM session_users WHERE token = $1", token)
+	return err
+}
+
+// ForUser returns the sessions of a user, most recently seen first
+func (u *UserIndex) ForUser(ctx context.Context, userID string) ([]sess.Session, error) {
+	rows, err := u.readDB.QueryContext(ctx, `SELECT token, user_id, user_agent, ip_address, created_at, last_seen_at
+		FROM session_users WHERE user_id = $1 ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var sessions []sess.Session
+	for rows.Next() {
+		var (
+			s                 sess.Session
+			createdAt, seenAt int64
+		)
+		if err := rows.Scan(&s.Token, &s.UserID, &s.UserAgent, &s.IPAddress, &createdAt, &seenAt); err != nil {
+			return nil, err
+		}
+		s.CreatedAt = time.UnixMilli(createdAt)
+		s.LastSeenAt = time.UnixMilli(seenAt)
+		sessions = append(sessions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
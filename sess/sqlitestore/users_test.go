@@ -0,0 +1,55 @@
+package sqlitestore_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/sess"
+	"github.com/patrickward/hop/sess/sqlitestore"
+)
+
+var _ sess.Index = (*sqlitestore.UserIndex)(nil)
+
+func TestUserIndex(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open(dbDriver, filepath.Join(t.TempDir(), "sessions.db"))
+	require.NoError(t, err)
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	index := sqlitestore.NewUserIndex(db, db)
+	require.NoError(t, index.Migrate(ctx))
+	require.NoError(t, index.Migrate(ctx), "migrating twice is a no-op")
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, index.Save(ctx, sess.Session{Token: "a", UserID: "42", UserAgent: "laptop", IPAddress: "192.0.2.1", CreatedAt: start, LastSeenAt: start}))
+	require.NoError(t, index.Save(ctx, sess.Session{Token: "b", UserID: "42", UserAgent: "phone", CreatedAt: start, LastSeenAt: start.Add(time.Minute)}))
+	require.NoError(t, index.Save(ctx, sess.Session{Token: "c", UserID: "7", CreatedAt: start, LastSeenAt: start}))
+
+	// Saving an existing session keeps its creation time
+	later := start.Add(time.Hour)
+	require.NoError(t, index.Save(ctx, sess.Session{Token: "a", UserID: "42", UserAgent: "laptop", IPAddress: "192.0.2.2", CreatedAt: later, LastSeenAt: later}))
+
+	sessions, err := index.ForUser(ctx, "42")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "a", sessions[0].Token)
+	assert.Equal(t, "192.0.2.2", sessions[0].IPAddress)
+	assert.True(t, start.Equal(sessions[0].CreatedAt), sessions[0].CreatedAt)
+	assert.True(t, later.Equal(sessions[0].LastSeenAt), sessions[0].LastSeenAt)
+	assert.Equal(t, "b", sessions[1].Token)
+
+	require.NoError(t, index.Delete(ctx, "a"))
+	require.NoError(t, index.Delete(ctx, "missing"))
+	sessions, err = index.ForUser(ctx, "42")
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "b", sessions[0].Token)
+}
@@ -0,0 +1,159 @@
+package hop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ShutdownPhase is a stage of the app's graceful shutdown. Phases run in order, and each phase is a
+// barrier: the next phase only begins once every step of the current phase has returned.
+//
+// When the server shuts down, it first stops accepting connections, waits for in-flight requests and
+// background tasks, and then stops the app, which runs the phases:
+//
+//  1. ShutdownDrainHTTP: HTTP-related teardown, e.g. closing websocket hubs or upstream connections
+//  2. ShutdownStopJobs: in-flight event handlers are drained, then job runners and workers stop
+//  3. ShutdownStopModules: every ShutdownModule is stopped in reverse registration order
+//  4. ShutdownFlush: logs, metrics and traces are flushed, after everything that could emit them stopped
+type ShutdownPhase int
+
+const (
+	// ShutdownDrainHTTP runs once the HTTP server has drained
+	ShutdownDrainHTTP ShutdownPhase = iota
+	// ShutdownStopJobs stops background jobs and event handlers
+	ShutdownStopJobs
+	// ShutdownStopModules stops the modules
+	ShutdownStopModules
+	// ShutdownFlush flushes logs, metrics and other buffered telemetry
+	ShutdownFlush
+)
+
+// shutdownPhases lists the phases in the order they run
+var shutdownPhases = []ShutdownPhase{ShutdownDrainHTTP, ShutdownStopJobs, ShutdownStopModules, ShutdownFlush}
+
+// String returns the name of the phase
+func (p ShutdownPhase) String() string {
+	switch p {
+	case ShutdownDrainHTTP:
+		return "drain-http"
+	case ShutdownStopJobs:
+		return "stop-jobs"
+	case ShutdownStopModules:
+		return "stop-modules"
+	case ShutdownFlush:
+		return "flush"
+	default:
+		return fmt.Sprintf("phase-%d", int(p))
+	}
+}
+
+// ShutdownStep is a named function run during a shutdown phase
+type ShutdownStep struct {
+	// Phase is the phase the step runs in
+	Phase ShutdownPhase
+	// Name identifies the step in logs, errors and ShutdownPlan
+	Name string
+	// Fn performs the step. It should respect the context's deadline.
+	Fn func(ctx context.Context) error
+}
+
+// RegisterShutdownPhase adds a step to a shutdown phase. Steps registered for the same phase run
+// concurrently, and the phase ends when all of them have returned. It panics for an unknown phase.
+//
+//	app.RegisterShutdownPhase(hop.ShutdownStopJobs, "jobs", jobs.Stop)
+//	app.RegisterShutdownPhase(hop.ShutdownFlush, "tracing", tracer.Shutdown)
+func (a *App) RegisterShutdownPhase(phase ShutdownPhase, name string, fn func(ctx context.Context) error) {
+	if phase < ShutdownDrainHTTP || phase > ShutdownFlush {
+		panic(fmt.Sprintf("hop: unknown shutdown phase %d", int(phase)))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.shutdownSteps = append(a.shutdownSteps, ShutdownStep{Phase: phase, Name: name, Fn: fn})
+}
+
+// ShutdownPlan returns the steps of the app's shutdown in the order their phases run, including the
+// built-in steps, so the teardown order can be logged or checked in tests
+func (a *App) ShutdownPlan() []ShutdownStep {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var plan []ShutdownStep
+	for _, phase := range shutdownPhases {
+		plan = append(plan, a.phaseSteps(phase)...)
+	}
+	return plan
+}
+
+// runShutdown runs every shutdown phase in order. The caller must hold the read lock.
+func (a *App) runShutdown(ctx context.Context) error {
+	var errs []error
+	for _, phase := range shutdownPhases {
+		steps := a.phaseSteps(phase)
+		if len(steps) == 0 {
+			continue
+		}
+
+		start := time.Now()
+		a.logger.Info("shutdown phase", slog.String("phase", phase.String()), slog.Int("steps", len(steps)))
+		if err := runShutdownSteps(ctx, steps); err != nil {
+			errs = append(errs, err)
+			a.logger.Error("shutdown phase failed", slog.String("phase", phase.String()), slog.String("error", err.Error()))
+		}
+		a.logger.Debug("shutdown phase completed", slog.String("phase", phase.String()), slog.Duration("duration", time.Since(start)))
+	}
+	return errors.Join(errs...)
+}
+
+// phaseSteps returns the built-in steps of a phase followed by the registered ones. The caller must hold
+// the read lock.
+func (a *App) phaseSteps(phase ShutdownPhase) []ShutdownStep {
+	var steps []ShutdownStep
+
+	switch phase {
+	case ShutdownStopJobs:
+		// Wait for in-flight event handlers while the modules they depend on are still running
+		steps = append(steps, ShutdownStep{Phase: phase, Name: "events", Fn: a.events.Shutdown})
+	case ShutdownStopModules:
+		steps = append(steps, ShutdownStep{Phase: phase, Name: "modules", Fn: a.stopModules})
+	case ShutdownFlush:
+		if a.onShutdown != nil {
+			steps = append(steps, ShutdownStep{Phase: phase, Name: "on-shutdown", Fn: a.onShutdown})
+		}
+	}
+
+	for _, step := range a.shutdownSteps {
+		if step.Phase == phase {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// runShutdownSteps runs the steps of a phase concurrently and waits for all of them
+func runShutdownSteps(ctx context.Context, steps []ShutdownStep) error {
+	errs := make([]error, len(steps))
+
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					errs[i] = fmt.Errorf("%s: panic: %v", step.Name, recovered)
+				}
+			}()
+			if err := step.Fn(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", step.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
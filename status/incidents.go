@@ -0,0 +1,200 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/patrickward/hop/probe"
+)
+
+// Signatures of the incident events
+const (
+	// EventOpenIncident opens or updates an incident. The payload is an OpenIncidentEvent.
+	EventOpenIncident = "status.open_incident"
+	// EventResolveIncident resolves an incident. The payload is a ResolveIncidentEvent.
+	EventResolveIncident = "status.resolve_incident"
+	// EventIncidentOpened is emitted when an incident is opened. The payload is the Incident.
+	EventIncidentOpened = "status.incident_opened"
+	// EventIncidentResolved is emitted when an incident is resolved. The payload is the Incident.
+	EventIncidentResolved = "status.incident_resolved"
+)
+
+// maxStoredIncidents is the number of incidents kept in memory
+const maxStoredIncidents = 100
+
+// Incident is an event affecting the system, shown on the status page
+type Incident struct {
+	// ID identifies the incident, e.g. "probe:api" or "db-maintenance-2024-06"
+	ID string
+	// Title summarizes the incident
+	Title string
+	// Message is the latest update on the incident
+	Message string
+	// Components are the names of the affected components
+	Components []string
+	// StartedAt is when the incident was opened
+	StartedAt time.Time
+	// UpdatedAt is when the incident was last updated
+	UpdatedAt time.Time
+	// ResolvedAt is when the incident was resolved, or zero while it is open
+	ResolvedAt time.Time
+}
+
+// Resolved reports whether the incident has been resolved
+func (i Incident) Resolved() bool {
+	return !i.ResolvedAt.IsZero()
+}
+
+// OpenIncidentEvent is the payload of EventOpenIncident
+type OpenIncidentEvent struct {
+	ID         string
+	Title      string
+	Message    string
+	Components []string
+}
+
+// ResolveIncidentEvent is the payload of EventResolveIncident
+type ResolveIncidentEvent struct {
+	ID      string
+	Message string
+}
+
+// OpenIncident opens an incident affecting the given components. If an incident with the same ID is
+// already open, its title, message and components are updated instead.
+func (m *Module) OpenIncident(id, title, message string, components ...string) Incident {
+	now := m.now()
+
+	m.mu.Lock()
+	if i := m.openIncident(id); i >= 0 {
+		incident := &m.incidents[i]
+		incident.Title = title
+		incident.Message = message
+		incident.Components = components
+		incident.UpdatedAt = now
+		updated := *incident
+		m.mu.Unlock()
+		return updated
+	}
+
+	incident := Incident{
+		ID:         id,
+		Title:      title,
+		Message:    message,
+		Components: components,
+		StartedAt:  now,
+		UpdatedAt:  now,
+	}
+	m.incidents = append(m.incidents, incident)
+	if len(m.incidents) > maxStoredIncidents {
+		m.incidents = m.incidents[len(m.incidents)-maxStoredIncidents:]
+	}
+	m.mu.Unlock()
+
+	m.config.Logger.Warn("incident opened", slog.String("id", id), slog.String("title", title))
+	m.emit(EventIncidentOpened, incident)
+	return incident
+}
+
+// ResolveIncident resolves the open incident with the given ID, with a final message. It reports whether
+// an open incident was found.
+func (m *Module) ResolveIncident(id, message string) bool {
+	now := m.now()
+
+	m.mu.Lock()
+	i := m.openIncident(id)
+	if i < 0 {
+		m.mu.Unlock()
+		return false
+	}
+	incident := &m.incidents[i]
+	if message != "" {
+		incident.Message = message
+	}
+	incident.UpdatedAt = now
+	incident.ResolvedAt = now
+	resolved := *incident
+	m.mu.Unlock()
+
+	m.config.Logger.Info("incident resolved", slog.String("id", id), slog.Duration("duration", now.Sub(resolved.StartedAt)))
+	m.emit(EventIncidentResolved, resolved)
+	return true
+}
+
+// Incidents returns the stored incidents, most recent first
+func (m *Module) Incidents() []Incident {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	incidents := make([]Incident, len(m.incidents))
+	for i, incident := range m.incidents {
+		incidents[len(m.incidents)-1-i] = incident
+	}
+	return incidents
+}
+
+// OnProbeAlert opens an incident when a probe check starts failing and resolves it when the check
+// recovers. It has the signature of probe.AlertHandler, so it can be used as the probe module's OnAlert.
+func (m *Module) OnProbeAlert(result probe.Result, failing bool) {
+	id := "probe:" + result.Check
+
+	if !failing {
+		m.ResolveIncident(id, "The issue has been resolved.")
+		return
+	}
+
+	var components []string
+	for _, c := range m.config.Components {
+		if c.Check == result.Check {
+			components = append(components, c.Name)
+		}
+	}
+
+	title := fmt.Sprintf("%s is unavailable", result.Check)
+	if len(components) > 0 {
+		title = fmt.Sprintf("%s is unavailable", components[0])
+	}
+	m.OpenIncident(id, title, "We are investigating the issue.", components...)
+}
+
+// openIncident returns the index of the open incident with the given ID, or -1. The caller must hold the
+// lock.
+func (m *Module) openIncident(id string) int {
+	for i := len(m.incidents) - 1; i >= 0; i-- {
+		if m.incidents[i].ID == id && !m.incidents[i].Resolved() {
+			return i
+		}
+	}
+	return -1
+}
+
+// recentIncidents returns the most recent incidents shown on the page, open ones first. The caller must
+// hold the read lock.
+func (m *Module) recentIncidents() []Incident {
+	incidents := make([]Incident, len(m.incidents))
+	copy(incidents, m.incidents)
+
+	sort.SliceStable(incidents, func(i, j int) bool {
+		if incidents[i].Resolved() != incidents[j].Resolved() {
+			return !incidents[i].Resolved()
+		}
+		return incidents[i].StartedAt.After(incidents[j].StartedAt)
+	})
+
+	if len(incidents) > m.config.MaxIncidents {
+		incidents = incidents[:m.config.MaxIncidents]
+	}
+	return incidents
+}
+
+// emit emits an incident event, when a dispatcher is configured
+func (m *Module) emit(signature string, incident Incident) {
+	if m.config.Events == nil {
+		return
+	}
+	if err := m.config.Events.Emit(context.Background(), signature, incident); err != nil {
+		m.config.Logger.Error("failed to emit incident event", slog.String("event", signature), slog.String("error", err.Error()))
+	}
+}
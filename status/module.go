@@ -0,0 +1,411 @@
+// Package status provides a public status page module. The page shows the health of the application's
+// components, taken from probe checks, their uptime over recent days, computed from the probe gauges
+// recorded in pulse, and recent incidents, opened manually, from events or from probe alerts.
+//
+//	var statusMod *status.Module
+//	probeMod := probe.NewModule(&probe.Config{
+//	    Collector: collector,
+//	    Checks:    checks,
+//	    OnAlert:   func(r probe.Result, failing bool) { statusMod.OnProbeAlert(r, failing) },
+//	})
+//	statusMod = status.NewModule(&status.Config{
+//	    Health:    probeMod,
+//	    Collector: collector,
+//	    Components: []status.Component{
+//	        {Name: "Website", Check: "home"},
+//	        {Name: "API", Check: "api"},
+//	    },
+//	})
+package status
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/probe"
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/render"
+	"github.com/patrickward/hop/route"
+)
+
+//go:embed templates/status.html
+var defaultTemplate string
+
+// State is the state of a component, or of the whole system
+type State string
+
+const (
+	// StateOperational means the component's check is passing
+	StateOperational State = "operational"
+	// StateDegraded means the component's check is passing, but it is affected by an open incident
+	StateDegraded State = "degraded"
+	// StateOutage means the component's check is failing
+	StateOutage State = "outage"
+	// StateUnknown means the component's check hasn't run yet
+	StateUnknown State = "unknown"
+)
+
+// HealthSource provides the latest results of the health checks. The probe module implements it.
+type HealthSource interface {
+	Results() []probe.Result
+}
+
+// Component is a part of the system shown on the status page
+type Component struct {
+	// Name is the display name of the component
+	Name string
+	// Description is an optional description shown with the component
+	Description string
+	// Check is the name of the probe check that reports the component's health
+	Check string
+}
+
+// Config configures the status page module
+type Config struct {
+	// RoutePath is where the status page is served. Default is "/status".
+	RoutePath string
+	// Title is the title of the page. Default is "System Status".
+	Title string
+	// Components are the components shown on the page, in order
+	Components []Component
+	// Health provides the current state of the components, usually the probe module
+	Health HealthSource
+	// Collector is the pulse collector the probe checks record to. Its up gauges are sampled to compute
+	// the uptime of each component.
+	Collector pulse.Collector
+	// SampleInterval is how often the up gauges are sampled. Default is 1 minute.
+	SampleInterval time.Duration
+	// HistoryDays is the number of days of uptime shown for each component. Default is 90.
+	HistoryDays int
+	// MaxIncidents is the number of recent incidents shown. Default is 10.
+	MaxIncidents int
+	// Templates, when set, renders the page with the app's templates, using Template as the view path.
+	// The page is available to the template as .Status. Otherwise, a built-in page is rendered.
+	Templates *render.TemplateManager
+	// Template is the view path used with Templates. Default is "status".
+	Template string
+	// CacheMaxAge is the max-age of the public Cache-Control header of the page. Default is 30 seconds.
+	CacheMaxAge time.Duration
+	// Events, when set, opens and resolves incidents from EventOpenIncident and EventResolveIncident
+	// events, and receives EventIncidentOpened and EventIncidentResolved events
+	Events *dispatch.Dispatcher
+	// Logger is used to log incidents. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// ComponentStatus is the status of a component shown on the page
+type ComponentStatus struct {
+	Component
+	// State is the current state of the component
+	State State
+	// CheckedAt is when the component's check last ran
+	CheckedAt time.Time
+	// Uptime is the percentage of samples in which the component was up over the history, or -1 when no
+	// samples were taken
+	Uptime float64
+	// Days is the uptime of each day of the history, oldest first
+	Days []DayUptime
+}
+
+// DayUptime is the uptime of a component for a day
+type DayUptime struct {
+	// Date is the start of the day, in UTC
+	Date time.Time
+	// Uptime is the percentage of samples in which the component was up, or -1 when no samples were taken
+	Uptime float64
+}
+
+// Page is the data shown on the status page
+type Page struct {
+	// Title is the title of the page
+	Title string
+	// State is the overall state: the worst state of the components
+	State State
+	// UpdatedAt is when the page data was computed
+	UpdatedAt time.Time
+	// Components are the statuses of the components
+	Components []ComponentStatus
+	// Incidents are the most recent incidents, open ones first
+	Incidents []Incident
+}
+
+// Module implements hop.Module for a public status page
+type Module struct {
+	config *Config
+	tmpl   *template.Template
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	history   map[string]*uptimeHistory
+	incidents []Incident
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewModule creates a new status page module
+func NewModule(config *Config) *Module {
+	if config == nil {
+		config = &Config{}
+	}
+
+	if config.RoutePath == "" {
+		config.RoutePath = "/status"
+	}
+	if config.Title == "" {
+		config.Title = "System Status"
+	}
+	if config.SampleInterval <= 0 {
+		config.SampleInterval = time.Minute
+	}
+	if config.HistoryDays <= 0 {
+		config.HistoryDays = 90
+	}
+	if config.MaxIncidents <= 0 {
+		config.MaxIncidents = 10
+	}
+	if config.Template == "" {
+		config.Template = "status"
+	}
+	if config.CacheMaxAge <= 0 {
+		config.CacheMaxAge = 30 * time.Second
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	history := make(map[string]*uptimeHistory, len(config.Components))
+	for _, c := range config.Components {
+		history[c.Name] = &uptimeHistory{}
+	}
+
+	return &Module{
+		config:  config,
+		tmpl:    template.Must(template.New("status").Funcs(funcMap).Parse(defaultTemplate)),
+		now:     time.Now,
+		history: history,
+	}
+}
+
+func (m *Module) ID() string {
+	return "hop.status"
+}
+
+// Init subscribes to the incident events, when a dispatcher is configured
+func (m *Module) Init() error {
+	if m.config.Events == nil {
+		return nil
+	}
+
+	m.config.Events.On(EventOpenIncident, dispatch.HandlePayload(func(_ context.Context, e OpenIncidentEvent) {
+		m.OpenIncident(e.ID, e.Title, e.Message, e.Components...)
+	}))
+	m.config.Events.On(EventResolveIncident, dispatch.HandlePayload(func(_ context.Context, e ResolveIncidentEvent) {
+		m.ResolveIncident(e.ID, e.Message)
+	}))
+	return nil
+}
+
+// RegisterRoutes registers the status page
+func (m *Module) RegisterRoutes(router *route.Mux) {
+	router.Get(m.config.RoutePath, http.HandlerFunc(m.ServeHTTP))
+}
+
+// Start begins sampling the up gauges for the uptime history
+func (m *Module) Start(ctx context.Context) error {
+	if m.config.Collector == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	m.done = done
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.config.SampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				m.Sample()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop halts the sampling. It is safe to call more than once, and the module can be started again.
+func (m *Module) Stop(_ context.Context) error {
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+	}
+	m.wg.Wait()
+	return nil
+}
+
+// ServeHTTP renders the status page, or its data as JSON with ?format=json
+func (m *Module) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page := m.Page()
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(m.config.CacheMaxAge.Seconds()))
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", cacheControl)
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			m.config.Logger.Error("failed to encode status page", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	if m.config.Templates != nil {
+		m.config.Templates.NewResponse().
+			Path(m.config.Template).
+			Title(page.Title).
+			CacheControl(cacheControl).
+			Data("Status", page).
+			Render(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", cacheControl)
+	if err := m.tmpl.Execute(w, page); err != nil {
+		http.Error(w, "Error rendering status page: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Page returns the current data of the status page
+func (m *Module) Page() Page {
+	now := m.now()
+	page := Page{
+		Title:     m.config.Title,
+		State:     StateOperational,
+		UpdatedAt: now,
+	}
+
+	results := m.results()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	affected := make(map[string]bool)
+	for _, incident := range m.incidents {
+		if !incident.Resolved() {
+			for _, name := range incident.Components {
+				affected[name] = true
+			}
+		}
+	}
+
+	known := false
+	for _, c := range m.config.Components {
+		status := ComponentStatus{Component: c, State: StateUnknown}
+		if result, ok := results[c.Check]; ok {
+			status.CheckedAt = result.Time
+			status.State = StateOperational
+			if !result.OK() {
+				status.State = StateOutage
+			}
+		}
+		if status.State == StateOperational && affected[c.Name] {
+			status.State = StateDegraded
+		}
+		status.Uptime, status.Days = m.history[c.Name].uptime(now, m.config.HistoryDays)
+
+		if status.State != StateUnknown {
+			known = true
+		}
+		if severity(status.State) > severity(page.State) {
+			page.State = status.State
+		}
+		page.Components = append(page.Components, status)
+	}
+	if !known && len(m.config.Components) > 0 {
+		page.State = StateUnknown
+	}
+
+	page.Incidents = m.recentIncidents()
+	return page
+}
+
+// Sample records whether each component is up in its uptime history, from the probe up gauges. Components
+// whose check hasn't run yet are skipped. It is called on every SampleInterval once the module starts.
+func (m *Module) Sample() {
+	if m.config.Collector == nil {
+		return
+	}
+
+	now := m.now()
+	results := m.results()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.config.Components {
+		if c.Check == "" {
+			continue
+		}
+		if _, ok := results[c.Check]; !ok && m.config.Health != nil {
+			continue
+		}
+		up := m.config.Collector.Gauge(probe.UpMetric(c.Check)).Value() >= 1
+		m.history[c.Name].record(now, up, m.config.HistoryDays)
+	}
+}
+
+// results returns the latest health check results by check name
+func (m *Module) results() map[string]probe.Result {
+	results := make(map[string]probe.Result)
+	if m.config.Health == nil {
+		return results
+	}
+	for _, result := range m.config.Health.Results() {
+		results[result.Check] = result
+	}
+	return results
+}
+
+// severity orders states from best to worst
+func severity(state State) int {
+	switch state {
+	case StateDegraded:
+		return 1
+	case StateOutage:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// funcMap holds the functions of the built-in page
+var funcMap = template.FuncMap{
+	"percent": func(uptime float64) string {
+		if uptime < 0 {
+			return "No data"
+		}
+		return fmt.Sprintf("%.2f%%", uptime)
+	},
+	"date": func(t time.Time) string {
+		return t.Format("Jan 2, 2006")
+	},
+	"datetime": func(t time.Time) string {
+		return t.Format("Jan 2, 2006 15:04 MST")
+	},
+}
@@ -0,0 +1,154 @@
+package status_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/probe"
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/status"
+)
+
+// fakeHealth is a HealthSource with settable results
+type fakeHealth struct {
+	mu      sync.Mutex
+	results []probe.Result
+}
+
+func (f *fakeHealth) Results() []probe.Result {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.results
+}
+
+func (f *fakeHealth) set(results ...probe.Result) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = results
+}
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestModule(t *testing.T) {
+	health := &fakeHealth{}
+	collector := pulse.NewStandardCollector()
+	events := dispatch.NewDispatcher(quietLogger())
+
+	mod := status.NewModule(&status.Config{
+		Health:      health,
+		Collector:   collector,
+		HistoryDays: 7,
+		Events:      events,
+		Logger:      quietLogger(),
+		Components: []status.Component{
+			{Name: "Website", Check: "home"},
+			{Name: "API", Check: "api"},
+		},
+	})
+	require.NoError(t, mod.Init())
+
+	t.Run("unknown before the checks run", func(t *testing.T) {
+		page := mod.Page()
+		assert.Equal(t, status.StateUnknown, page.State)
+		require.Len(t, page.Components, 2)
+		assert.Equal(t, status.StateUnknown, page.Components[0].State)
+		assert.Equal(t, float64(-1), page.Components[0].Uptime)
+		assert.Len(t, page.Components[0].Days, 7)
+	})
+
+	t.Run("uptime from the probe gauges", func(t *testing.T) {
+		health.set(probe.Result{Check: "home", Time: time.Now()}, probe.Result{Check: "api", Time: time.Now()})
+
+		collector.Gauge(probe.UpMetric("home")).Set(1)
+		collector.Gauge(probe.UpMetric("api")).Set(1)
+		mod.Sample()
+		mod.Sample()
+		collector.Gauge(probe.UpMetric("api")).Set(0)
+		mod.Sample()
+
+		page := mod.Page()
+		assert.Equal(t, status.StateOperational, page.State)
+		assert.Equal(t, float64(100), page.Components[0].Uptime)
+		assert.InDelta(t, 66.67, page.Components[1].Uptime, 0.01)
+		assert.InDelta(t, 66.67, page.Components[1].Days[6].Uptime, 0.01)
+		assert.Equal(t, float64(-1), page.Components[1].Days[0].Uptime)
+	})
+
+	t.Run("probe alerts open and resolve incidents", func(t *testing.T) {
+		failed := probe.Result{Check: "api", Time: time.Now(), Err: errors.New("status 503")}
+		health.set(probe.Result{Check: "home", Time: time.Now()}, failed)
+		mod.OnProbeAlert(failed, true)
+
+		page := mod.Page()
+		assert.Equal(t, status.StateOutage, page.State)
+		assert.Equal(t, status.StateOutage, page.Components[1].State)
+		require.Len(t, page.Incidents, 1)
+		assert.Equal(t, "API is unavailable", page.Incidents[0].Title)
+		assert.Equal(t, []string{"API"}, page.Incidents[0].Components)
+		assert.False(t, page.Incidents[0].Resolved())
+
+		health.set(probe.Result{Check: "home", Time: time.Now()}, probe.Result{Check: "api", Time: time.Now()})
+		mod.OnProbeAlert(probe.Result{Check: "api"}, false)
+
+		page = mod.Page()
+		assert.Equal(t, status.StateOperational, page.State)
+		require.Len(t, page.Incidents, 1)
+		assert.True(t, page.Incidents[0].Resolved())
+	})
+
+	t.Run("incidents from events", func(t *testing.T) {
+		require.NoError(t, events.EmitSync(context.Background(), status.EventOpenIncident, status.OpenIncidentEvent{
+			ID:         "maintenance",
+			Title:      "Scheduled maintenance",
+			Components: []string{"Website"},
+		}))
+
+		page := mod.Page()
+		assert.Equal(t, status.StateDegraded, page.State)
+		assert.Equal(t, status.StateDegraded, page.Components[0].State)
+		assert.Equal(t, "maintenance", page.Incidents[0].ID, "open incidents come first")
+
+		require.NoError(t, events.EmitSync(context.Background(), status.EventResolveIncident, status.ResolveIncidentEvent{
+			ID:      "maintenance",
+			Message: "Maintenance is complete.",
+		}))
+
+		incidents := mod.Incidents()
+		require.Len(t, incidents, 2)
+		assert.Equal(t, "Maintenance is complete.", incidents[0].Message)
+		assert.True(t, incidents[0].Resolved())
+		assert.Equal(t, status.StateOperational, mod.Page().State)
+	})
+
+	t.Run("serves the page", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mod.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "public, max-age=30", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Body.String(), "All systems operational")
+		assert.Contains(t, w.Body.String(), "Scheduled maintenance")
+
+		w = httptest.NewRecorder()
+		mod.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status?format=json", nil))
+
+		var page status.Page
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, status.StateOperational, page.State)
+		assert.Len(t, page.Components, 2)
+	})
+}
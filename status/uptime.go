@@ -0,0 +1,74 @@
+package status
+
+import "time"
+
+// dayBucket counts the samples of a day
+type dayBucket struct {
+	date  time.Time
+	up    int
+	total int
+}
+
+// uptimeHistory holds the daily samples of a component, oldest first
+type uptimeHistory struct {
+	days []dayBucket
+}
+
+// record adds a sample and drops the days that fall outside the history
+func (h *uptimeHistory) record(now time.Time, up bool, historyDays int) {
+	day := startOfDay(now)
+	if n := len(h.days); n == 0 || !h.days[n-1].date.Equal(day) {
+		h.days = append(h.days, dayBucket{date: day})
+	}
+
+	bucket := &h.days[len(h.days)-1]
+	bucket.total++
+	if up {
+		bucket.up++
+	}
+
+	oldest := day.AddDate(0, 0, -(historyDays - 1))
+	for len(h.days) > 0 && h.days[0].date.Before(oldest) {
+		h.days = h.days[1:]
+	}
+}
+
+// uptime returns the uptime over the history, and the uptime of each day, oldest first. Percentages are -1
+// when there are no samples.
+func (h *uptimeHistory) uptime(now time.Time, historyDays int) (float64, []DayUptime) {
+	days := make([]DayUptime, historyDays)
+	first := startOfDay(now).AddDate(0, 0, -(historyDays - 1))
+	for i := range days {
+		days[i] = DayUptime{Date: first.AddDate(0, 0, i), Uptime: -1}
+	}
+
+	if h == nil {
+		return -1, days
+	}
+
+	var up, total int
+	for _, bucket := range h.days {
+		i := int(bucket.date.Sub(first).Hours() / 24)
+		if i < 0 || i >= historyDays || bucket.total == 0 {
+			continue
+		}
+		days[i].Uptime = percent(bucket.up, bucket.total)
+		up += bucket.up
+		total += bucket.total
+	}
+
+	if total == 0 {
+		return -1, days
+	}
+	return percent(up, total), days
+}
+
+// percent returns part as a percentage of total
+func percent(part, total int) float64 {
+	return float64(part) / float64(total) * 100
+}
+
+// startOfDay returns the start of the day of t, in UTC
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
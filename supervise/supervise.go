@@ -0,0 +1,294 @@
+// Package supervise runs long-running background workers, such as pollers and queue consumers, under a
+// crash-only supervisor. A worker that returns an error or panics is restarted after an exponential
+// backoff, until it exceeds its restart policy, so modules don't need their own ad-hoc restart loops.
+//
+//	sup := supervise.New(func(opts *supervise.Options) {
+//	    opts.Logger = logger
+//	    opts.Metrics = collector
+//	})
+//
+//	// In the module's Start method
+//	sup.Go(ctx, "inbox-poller", func(ctx context.Context) error {
+//	    ticker := time.NewTicker(time.Minute)
+//	    defer ticker.Stop()
+//	    for {
+//	        select {
+//	        case <-ctx.Done():
+//	            return nil
+//	        case <-ticker.C:
+//	            if err := poll(ctx); err != nil {
+//	                return err // restarted after a backoff
+//	            }
+//	        }
+//	    }
+//	})
+//
+//	// In the module's Stop method
+//	return sup.Stop(ctx)
+package supervise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/pulse"
+)
+
+// ErrGaveUp is reported to OnGiveUp when a worker exceeded MaxRestarts within RestartWindow
+var ErrGaveUp = errors.New("supervise: worker exceeded its restart limit")
+
+// Worker is a long-running function. It should run until ctx is canceled and then return nil. Returning
+// nil before that means the worker is finished and is not restarted; returning an error or panicking
+// restarts it.
+type Worker func(ctx context.Context) error
+
+// PanicError is the error recorded for a worker run that panicked
+type PanicError struct {
+	// Value is the value passed to panic
+	Value any
+	// Stack is the stack trace of the panic
+	Stack []byte
+}
+
+// Error implements the error interface
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Options configures a Supervisor
+type Options struct {
+	// InitialBackoff is the delay before the first restart. It doubles for each consecutive restart.
+	// Default is 1 second.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between restarts. Default is 1 minute.
+	MaxBackoff time.Duration
+	// ResetAfter is how long a run must last for the backoff to start again from InitialBackoff.
+	// Default is 1 minute.
+	ResetAfter time.Duration
+	// MaxRestarts is the number of restarts allowed within RestartWindow before the supervisor gives up
+	// on the worker. Default is 0, which restarts the worker indefinitely.
+	MaxRestarts int
+	// RestartWindow is the period MaxRestarts applies to. Default is 0, which counts every restart since
+	// the worker was started.
+	RestartWindow time.Duration
+	// Logger receives worker failures and restarts. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Metrics, when set, records "worker_<name>_restarts_total" and "worker_<name>_panics_total"
+	// counters and a "worker_<name>_running" gauge for each worker
+	Metrics pulse.Collector
+	// OnGiveUp, when set, is called when a worker exceeded its restart policy, with the last error
+	// wrapped in ErrGaveUp
+	OnGiveUp func(name string, err error)
+}
+
+// Status describes the state of a supervised worker
+type Status struct {
+	// Name is the name of the worker
+	Name string
+	// Running reports whether the worker is running or waiting to be restarted
+	Running bool
+	// Restarts is the number of times the worker has been restarted
+	Restarts int
+	// LastError is the error of the last failed run, if any
+	LastError error
+	// LastRestart is when the worker was last restarted
+	LastRestart time.Time
+}
+
+// Supervisor runs workers and restarts them when they fail
+type Supervisor struct {
+	opts    Options
+	mu      sync.Mutex
+	workers map[string]*Status
+	cancels []context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New creates a new Supervisor
+func New(optsFunc func(opts *Options)) *Supervisor {
+	opts := Options{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		ResetAfter:     time.Minute,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = time.Second
+	}
+	if opts.MaxBackoff < opts.InitialBackoff {
+		opts.MaxBackoff = opts.InitialBackoff
+	}
+
+	return &Supervisor{
+		opts:    opts,
+		workers: make(map[string]*Status),
+	}
+}
+
+// Go starts the worker in the background under the given name. The worker is stopped when ctx is
+// canceled or Stop is called. Names should be unique and suitable for metric names, e.g. "inbox_poller".
+func (s *Supervisor) Go(ctx context.Context, name string, worker Worker) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	s.workers[name] = &Status{Name: name, Running: true}
+	s.cancels = append(s.cancels, cancel)
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		s.supervise(ctx, name, worker)
+	}()
+}
+
+// Stop cancels every worker and waits for them to return, or for ctx to be done
+func (s *Supervisor) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("supervise: waiting for workers: %w", ctx.Err())
+	}
+}
+
+// Statuses returns the status of every worker, sorted by name
+func (s *Supervisor) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.workers))
+	for _, st := range s.workers {
+		statuses = append(statuses, *st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// supervise runs the worker until it finishes, ctx is canceled, or it exceeds its restart policy
+func (s *Supervisor) supervise(ctx context.Context, name string, worker Worker) {
+	var (
+		restarts       pulse.Counter
+		panics         pulse.Counter
+		running        pulse.Gauge
+		backoff        = s.opts.InitialBackoff
+		recentRestarts []time.Time
+	)
+	if s.opts.Metrics != nil {
+		restarts = s.opts.Metrics.Counter("worker_" + name + "_restarts_total")
+		panics = s.opts.Metrics.Counter("worker_" + name + "_panics_total")
+		running = s.opts.Metrics.Gauge("worker_" + name + "_running")
+		running.Set(1)
+		defer running.Set(0)
+	}
+	defer s.update(name, func(st *Status) { st.Running = false })
+
+	for {
+		started := time.Now()
+		err := run(ctx, worker)
+		if ctx.Err() != nil || err == nil {
+			return
+		}
+
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			if panics != nil {
+				panics.Inc()
+			}
+			s.opts.Logger.Error("worker panicked",
+				slog.String("worker", name),
+				slog.Any("panic", panicErr.Value),
+				slog.String("stack", string(panicErr.Stack)))
+		} else {
+			s.opts.Logger.Error("worker failed", slog.String("worker", name), slog.String("error", err.Error()))
+		}
+
+		// A run that lasted long enough means the worker had recovered, so start backing off afresh
+		if time.Since(started) >= s.opts.ResetAfter {
+			backoff = s.opts.InitialBackoff
+		}
+
+		now := time.Now()
+		recentRestarts = append(recentRestarts, now)
+		if s.opts.RestartWindow > 0 {
+			cutoff := now.Add(-s.opts.RestartWindow)
+			for len(recentRestarts) > 0 && recentRestarts[0].Before(cutoff) {
+				recentRestarts = recentRestarts[1:]
+			}
+		}
+		if s.opts.MaxRestarts > 0 && len(recentRestarts) > s.opts.MaxRestarts {
+			s.update(name, func(st *Status) { st.LastError = err })
+			s.opts.Logger.Error("worker exceeded its restart limit, giving up",
+				slog.String("worker", name),
+				slog.Int("max_restarts", s.opts.MaxRestarts))
+			if s.opts.OnGiveUp != nil {
+				s.opts.OnGiveUp(name, fmt.Errorf("%w: %w", ErrGaveUp, err))
+			}
+			return
+		}
+
+		s.opts.Logger.Info("restarting worker", slog.String("worker", name), slog.Duration("backoff", backoff))
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, s.opts.MaxBackoff)
+		if restarts != nil {
+			restarts.Inc()
+		}
+		s.update(name, func(st *Status) {
+			st.Restarts++
+			st.LastError = err
+			st.LastRestart = time.Now()
+		})
+	}
+}
+
+// update changes the status of a worker under the lock
+func (s *Supervisor) update(name string, fn func(st *Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.workers[name]; ok {
+		fn(st)
+	}
+}
+
+// run calls the worker once, turning a panic into a PanicError
+func run(ctx context.Context, worker Worker) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = &PanicError{Value: recovered, Stack: debug.Stack()}
+		}
+	}()
+	return worker(ctx)
+}
@@ -0,0 +1,127 @@
+package supervise_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/pulse"
+	"github.com/patrickward/hop/supervise"
+)
+
+func newSupervisor(optsFunc func(opts *supervise.Options)) *supervise.Supervisor {
+	return supervise.New(func(opts *supervise.Options) {
+		opts.InitialBackoff = time.Millisecond
+		opts.MaxBackoff = 5 * time.Millisecond
+		opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		if optsFunc != nil {
+			optsFunc(opts)
+		}
+	})
+}
+
+func TestSupervisor_RestartsFailingWorker(t *testing.T) {
+	collector := pulse.NewStandardCollector()
+	sup := newSupervisor(func(opts *supervise.Options) {
+		opts.Metrics = collector
+	})
+
+	var runs atomic.Int32
+	done := make(chan struct{})
+	sup.Go(context.Background(), "flaky", func(ctx context.Context) error {
+		switch runs.Add(1) {
+		case 1:
+			return errors.New("connection lost")
+		case 2:
+			panic("boom")
+		default:
+			close(done)
+			<-ctx.Done()
+			return nil
+		}
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker was not restarted")
+	}
+
+	statuses := sup.Statuses()
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Running)
+	assert.Equal(t, 2, statuses[0].Restarts)
+	var panicErr *supervise.PanicError
+	assert.ErrorAs(t, statuses[0].LastError, &panicErr)
+	assert.Equal(t, float64(2), collector.Counter("worker_flaky_restarts_total").Value())
+	assert.Equal(t, float64(1), collector.Counter("worker_flaky_panics_total").Value())
+
+	require.NoError(t, sup.Stop(context.Background()))
+	assert.False(t, sup.Statuses()[0].Running)
+	assert.Equal(t, int32(3), runs.Load())
+}
+
+func TestSupervisor_FinishedWorkerIsNotRestarted(t *testing.T) {
+	sup := newSupervisor(nil)
+
+	var runs atomic.Int32
+	sup.Go(context.Background(), "once", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	require.NoError(t, sup.Stop(context.Background()))
+	assert.Equal(t, int32(1), runs.Load())
+	assert.Equal(t, 0, sup.Statuses()[0].Restarts)
+}
+
+func TestSupervisor_GivesUpAfterMaxRestarts(t *testing.T) {
+	gaveUp := make(chan error, 1)
+	sup := newSupervisor(func(opts *supervise.Options) {
+		opts.MaxRestarts = 2
+		opts.OnGiveUp = func(name string, err error) { gaveUp <- err }
+	})
+
+	var runs atomic.Int32
+	sup.Go(context.Background(), "broken", func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("misconfigured")
+	})
+
+	select {
+	case err := <-gaveUp:
+		assert.ErrorIs(t, err, supervise.ErrGaveUp)
+		assert.ErrorContains(t, err, "misconfigured")
+	case <-time.After(time.Second):
+		t.Fatal("supervisor did not give up")
+	}
+
+	require.NoError(t, sup.Stop(context.Background()))
+	assert.Equal(t, int32(3), runs.Load(), "the first run and two restarts")
+	assert.False(t, sup.Statuses()[0].Running)
+}
+
+func TestSupervisor_StopCancelsBackoff(t *testing.T) {
+	sup := newSupervisor(func(opts *supervise.Options) {
+		opts.InitialBackoff = time.Hour
+		opts.MaxBackoff = time.Hour
+	})
+
+	failed := make(chan struct{})
+	sup.Go(context.Background(), "slow", func(ctx context.Context) error {
+		close(failed)
+		return errors.New("failed")
+	})
+	<-failed
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, sup.Stop(ctx))
+}
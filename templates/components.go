@@ -0,0 +1,155 @@
+package templates
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ComponentPrefix is the prefix of the template name a component is defined under, so a component
+// named "button" is used with {{template "component:button" .}}
+const ComponentPrefix = "component:"
+
+// Medium identifies where a component is rendered
+type Medium string
+
+const (
+	// MediumWeb is used for pages rendered by the render package
+	MediumWeb Medium = "web"
+	// MediumEmail is used for emails rendered by the mail package
+	MediumEmail Medium = "email"
+)
+
+// Components is a registry of template components, such as buttons, alerts, and address blocks, that
+// are shared between web pages and emails. Each component has a shared definition and optional
+// per-medium overrides, for example a table-based button for email clients.
+//
+// Components are added to a template set with AddTo, and used like any other named template:
+//
+//	{{template "component:button" (dict "URL" .ResetURL "Label" "Reset password")}}
+type Components struct {
+	mu        sync.RWMutex
+	shared    map[string]string
+	overrides map[Medium]map[string]string
+}
+
+// NewComponents creates an empty component registry
+func NewComponents() *Components {
+	return &Components{
+		shared:    make(map[string]string),
+		overrides: make(map[Medium]map[string]string),
+	}
+}
+
+// Register sets the shared definition of a component, used by every medium without an override
+func (c *Components) Register(name, src string) *Components {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shared[name] = src
+	return c
+}
+
+// Override sets the definition of a component for a single medium
+func (c *Components) Override(medium Medium, name, src string) *Components {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.overrides[medium] == nil {
+		c.overrides[medium] = make(map[string]string)
+	}
+	c.overrides[medium][name] = src
+	return c
+}
+
+// LoadFS registers the components in dir. Files directly in dir are shared definitions, named after the
+// file without its extension. Files in a subdirectory named after a medium are overrides for that medium:
+//
+//	components/button.html        shared "button"
+//	components/email/button.html  "button" override for MediumEmail
+func (c *Components) LoadFS(fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, dir), "/")
+		name := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("reading component %s: %w", p, err)
+		}
+
+		switch parent := path.Dir(rel); parent {
+		case ".":
+			c.Register(name, string(data))
+		default:
+			if strings.Contains(parent, "/") {
+				return fmt.Errorf("component %s is nested too deeply", p)
+			}
+			c.Override(Medium(parent), name, string(data))
+		}
+		return nil
+	})
+}
+
+// Names returns the names of all registered components, sorted
+func (c *Components) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for name := range c.shared {
+		seen[name] = true
+	}
+	for _, overrides := range c.overrides {
+		for name := range overrides {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Source returns the definition of a component for a medium, preferring the medium override over the
+// shared definition
+func (c *Components) Source(medium Medium, name string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if src, ok := c.overrides[medium][name]; ok {
+		return src, true
+	}
+	src, ok := c.shared[name]
+	return src, ok
+}
+
+// AddTo parses the components for a medium into the template set t, each under its ComponentPrefix name.
+// Components can use any function available to t.
+func (c *Components) AddTo(t *template.Template, medium Medium) error {
+	for _, name := range c.Names() {
+		src, ok := c.Source(medium, name)
+		if !ok {
+			// Only overridden for other media
+			continue
+		}
+
+		if _, err := t.New(ComponentPrefix + name).Parse(src); err != nil {
+			return fmt.Errorf("parsing component %s for %s: %w", name, medium, err)
+		}
+	}
+	return nil
+}
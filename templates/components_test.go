@@ -0,0 +1,75 @@
+package templates_test
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/templates"
+)
+
+func TestComponents_LoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"components/button.html":       {Data: []byte(`<a class="btn">{{.}}</a>`)},
+		"components/alert.html":        {Data: []byte(`<div class="alert">{{.}}</div>`)},
+		"components/email/button.html": {Data: []byte(`<table><tr><td>{{.}}</td></tr></table>`)},
+		"components/web/address.html":  {Data: []byte(`<address>{{.}}</address>`)},
+	}
+
+	c := templates.NewComponents()
+	require.NoError(t, c.LoadFS(fsys, "components"))
+
+	assert.Equal(t, []string{"address", "alert", "button"}, c.Names())
+
+	tests := []struct {
+		name     string
+		medium   templates.Medium
+		expected map[string]string
+	}{
+		{
+			name:   "web",
+			medium: templates.MediumWeb,
+			expected: map[string]string{
+				"button":  `<a class="btn">Save</a>`,
+				"alert":   `<div class="alert">Save</div>`,
+				"address": `<address>Save</address>`,
+			},
+		},
+		{
+			name:   "email",
+			medium: templates.MediumEmail,
+			expected: map[string]string{
+				"button": `<table><tr><td>Save</td></tr></table>`,
+				"alert":  `<div class="alert">Save</div>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.New("root").Funcs(templates.FuncMap())
+			require.NoError(t, c.AddTo(tmpl, tt.medium))
+
+			for name, want := range tt.expected {
+				var buf bytes.Buffer
+				require.NoError(t, tmpl.ExecuteTemplate(&buf, templates.ComponentPrefix+name, "Save"))
+				assert.Equal(t, want, buf.String())
+			}
+
+			// Components only overridden for other media are not defined
+			if tt.medium == templates.MediumEmail {
+				assert.Nil(t, tmpl.Lookup(templates.ComponentPrefix+"address"))
+			}
+		})
+	}
+}
+
+func TestComponents_ParseError(t *testing.T) {
+	c := templates.NewComponents().Register("broken", `{{if}}`)
+	err := c.AddTo(template.New("root"), templates.MediumWeb)
+	assert.ErrorContains(t, err, "parsing component broken")
+}
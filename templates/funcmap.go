@@ -5,6 +5,7 @@ import (
 
 	"github.com/patrickward/hop/templates/funcmap/attr"
 	"github.com/patrickward/hop/templates/funcmap/collections"
+	"github.com/patrickward/hop/templates/funcmap/contact"
 	"github.com/patrickward/hop/templates/funcmap/conversions"
 	"github.com/patrickward/hop/templates/funcmap/core"
 	"github.com/patrickward/hop/templates/funcmap/debug"
@@ -42,6 +43,7 @@ func FuncMap() template.FuncMap {
 		core.FuncMap(),
 		attr.FuncMap(),
 		collections.FuncMap(),
+		contact.FuncMap(),
 		conversions.FuncMap(),
 		debug.FuncMap(),
 		html.FuncMap(),
@@ -0,0 +1,58 @@
+package contact
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/patrickward/hop/check"
+)
+
+// FuncMap returns a function map with functions for displaying phone numbers and postal addresses.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"contact_address":      address,      // Format an address as HTML lines separated by <br>
+		"contact_addressLines": addressLines, // Format an address as a slice of display lines
+		"contact_phone":        phone,        // Format a phone number for display
+		"contact_phoneLink":    phoneLink,    // Create a tel: URL for a phone number
+	}
+}
+
+// phone formats a phone number for display. National numbers are interpreted using the optional country.
+func phone(number string, country ...string) string {
+	normalized, err := check.NormalizePhone(number, firstOr(country, ""))
+	if err != nil {
+		return number
+	}
+	return check.FormatPhone(normalized)
+}
+
+// phoneLink returns a tel: URL for a phone number, or an empty URL if the number is invalid
+func phoneLink(number string, country ...string) template.URL {
+	normalized, err := check.NormalizePhone(number, firstOr(country, ""))
+	if err != nil {
+		return ""
+	}
+	return template.URL("tel:" + normalized)
+}
+
+// addressLines returns the display lines for an address
+func addressLines(a check.Address) []string {
+	return check.FormatAddress(a)
+}
+
+// address formats an address as escaped HTML lines separated by <br> elements
+func address(a check.Address) template.HTML {
+	lines := check.FormatAddress(a)
+	for i, line := range lines {
+		lines[i] = template.HTMLEscapeString(line)
+	}
+	return template.HTML(strings.Join(lines, "<br>"))
+}
+
+// firstOr returns the first value, or def if there are none
+func firstOr(values []string, def string) string {
+	if len(values) > 0 {
+		return values[0]
+	}
+	return def
+}
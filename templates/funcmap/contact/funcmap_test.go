@@ -0,0 +1,49 @@
+package contact_test
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/check"
+	"github.com/patrickward/hop/templates/funcmap/contact"
+)
+
+func render(t *testing.T, tmpl string, data any) string {
+	t.Helper()
+	tpl, err := template.New("test").Funcs(contact.FuncMap()).Parse(tmpl)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, tpl.Execute(&buf, data))
+	return buf.String()
+}
+
+func TestPhone(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{name: "e164", tmpl: `{{contact_phone "+15551234567"}}`, want: "&#43;1 (555) 123-4567"},
+		{name: "national with country", tmpl: `{{contact_phone "020 7946 0958" "GB"}}`, want: "&#43;44 207 946 0958"},
+		{name: "invalid is unchanged", tmpl: `{{contact_phone "ext. 12"}}`, want: "ext. 12"},
+		{name: "link", tmpl: `<a href="{{contact_phoneLink "(555) 123-4567" "US"}}">`, want: `<a href="tel:&#43;15551234567">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, render(t, tt.tmpl, nil))
+		})
+	}
+}
+
+func TestAddress(t *testing.T) {
+	a := check.Address{Line1: "1 <Main> St", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "US"}
+
+	assert.Equal(t, "1 &lt;Main&gt; St<br>Springfield, IL 62701<br>US", render(t, `{{contact_address .}}`, a))
+	assert.Equal(t, "1 &lt;Main&gt; St|Springfield, IL 62701|US|", render(t, `{{range contact_addressLines .}}{{.}}|{{end}}`, a))
+}
@@ -12,6 +12,7 @@ func FuncMap() template.FuncMap {
 		"unless":   unless,
 		"default":  defaultValue,
 		"coalesce": coalesce,
+		"ternary":  ternary,
 	}
 }
 
@@ -33,6 +34,15 @@ func unless(condition bool, a any) any {
 	return ""
 }
 
+// ternary returns a if the condition is true, otherwise b
+func ternary(condition bool, a, b any) any {
+	if condition {
+		return a
+	}
+
+	return b
+}
+
 // default returns defaultValue if value is zero/empty, otherwise returns value
 func defaultValue(value, defaultValue any) any {
 	if isZero(value) {
@@ -40,6 +40,12 @@ func TestUnless(t *testing.T) {
 	}
 }
 
+func TestTernary(t *testing.T) {
+	ternary := core.FuncMap()["ternary"].(func(bool, any, any) any)
+	assert.Equal(t, "yes", ternary(true, "yes", "no"))
+	assert.Equal(t, "no", ternary(false, "yes", "no"))
+}
+
 func TestDefaultValue(t *testing.T) {
 	tests := []struct {
 		value        any
@@ -2,12 +2,16 @@ package html
 
 import (
 	"html/template"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 // FuncMap returns a template.FuncMap for HTML templates
 func FuncMap() template.FuncMap {
 	return template.FuncMap{
-		"html_safe": safeHTML, // Mark a string as safe for HTML output
+		"html_highlight": Highlight, // Wrap matched query terms in <mark> elements
+		"html_safe":      safeHTML,  // Mark a string as safe for HTML output
 	}
 }
 
@@ -15,3 +19,74 @@ func FuncMap() template.FuncMap {
 func safeHTML(s string) template.HTML {
 	return template.HTML(s)
 }
+
+// Highlight HTML-escapes text and wraps every case-insensitive match of the query terms in <mark> elements.
+// Each query is split into terms on whitespace; quoted phrases are kept together. Overlapping matches
+// prefer the longest term.
+//
+// Example:
+//
+//	{{ html_highlight .Result.Title .Query }}
+//	{{ html_highlight "Go templates in Go" "go" }} // <mark>Go</mark> templates in <mark>Go</mark>
+func Highlight(text string, queries ...string) template.HTML {
+	var terms []string
+	for _, q := range queries {
+		terms = append(terms, queryTerms(q)...)
+	}
+
+	if len(terms) == 0 || text == "" {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+
+	// Longer terms first, so the alternation prefers them at the same position
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	rx := regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+
+	var (
+		b    strings.Builder
+		last int
+	)
+	for _, loc := range rx.FindAllStringIndex(text, -1) {
+		b.WriteString(template.HTMLEscapeString(text[last:loc[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(text[loc[0]:loc[1]]))
+		b.WriteString("</mark>")
+		last = loc[1]
+	}
+	b.WriteString(template.HTMLEscapeString(text[last:]))
+
+	return template.HTML(b.String())
+}
+
+// queryTerms splits a search query into unique terms, keeping double-quoted phrases together
+func queryTerms(query string) []string {
+	var (
+		terms []string
+		seen  = make(map[string]bool)
+	)
+
+	add := func(term string) {
+		term = strings.TrimSpace(term)
+		key := strings.ToLower(term)
+		if term != "" && !seen[key] {
+			seen[key] = true
+			terms = append(terms, term)
+		}
+	}
+
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			add(part)
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			add(field)
+		}
+	}
+
+	return terms
+}
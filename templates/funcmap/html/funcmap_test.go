@@ -23,3 +23,61 @@ func TestSafeHTML(t *testing.T) {
 		assert.Equal(t, tt.expected, result)
 	}
 }
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		queries  []string
+		expected template.HTML
+	}{
+		{
+			name:     "case insensitive",
+			text:     "Go templates in go",
+			queries:  []string{"GO"},
+			expected: "<mark>Go</mark> templates in <mark>go</mark>",
+		},
+		{
+			name:     "multiple terms",
+			text:     "fast search results",
+			queries:  []string{"search fast"},
+			expected: "<mark>fast</mark> <mark>search</mark> results",
+		},
+		{
+			name:     "quoted phrase",
+			text:     "the quick brown fox",
+			queries:  []string{`"quick brown"`},
+			expected: "the <mark>quick brown</mark> fox",
+		},
+		{
+			name:     "prefers longest term",
+			text:     "searching",
+			queries:  []string{"search", "searching"},
+			expected: "<mark>searching</mark>",
+		},
+		{
+			name:     "escapes text and matches",
+			text:     "<b>Tom & Jerry</b>",
+			queries:  []string{"&", "b"},
+			expected: "&lt;<mark>b</mark>&gt;Tom <mark>&amp;</mark> Jerry&lt;/<mark>b</mark>&gt;",
+		},
+		{
+			name:     "regexp characters are literal",
+			text:     "a.b axb",
+			queries:  []string{"a.b"},
+			expected: "<mark>a.b</mark> axb",
+		},
+		{
+			name:     "empty query",
+			text:     "<i>hi</i>",
+			queries:  []string{"  "},
+			expected: "&lt;i&gt;hi&lt;/i&gt;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, html.Highlight(tt.text, tt.queries...))
+		})
+	}
+}
@@ -16,16 +16,20 @@ var printer = message.NewPrinter(language.English)
 // FuncMap returns a function map with functions for working with numbers.
 func FuncMap() template.FuncMap {
 	return template.FuncMap{
-		"num_add":      add,                // Add two integers
-		"num_cents":    cents,              // Format a number as currency with cents
-		"num_currency": currency,           // Format a number as currency
-		"num_decr":     decrement,          // Decrement a number by 1
-		"num_format":   format,             // Format any number with commas and decimals
-		"num_incr":     increment,          // Increment a number by 1
-		"num_mod":      mod,                // Get the remainder of a division
-		"num_percent":  percentage,         // Format a number as a percentage
-		"num_sci":      scientificNotation, // Format a number in scientific notation
-		"num_sub":      subtract,           // Subtract two integers
+		"num_add":       add,                // Add two integers
+		"num_cents":     cents,              // Format a number as currency with cents
+		"num_currency":  currency,           // Format a number as currency
+		"num_decr":      decrement,          // Decrement a number by 1
+		"num_format":    format,             // Format any number with commas and decimals
+		"num_fmt_cur":   fmtCurrency,        // Format an amount in a currency for a locale
+		"num_fmt_float": fmtFloat,           // Format a number with decimals for a locale
+		"num_fmt_int":   fmtInt,             // Format a whole number for a locale
+		"num_fmt_pct":   fmtPercent,         // Format a fraction as a percentage for a locale
+		"num_incr":      increment,          // Increment a number by 1
+		"num_mod":       mod,                // Get the remainder of a division
+		"num_percent":   percentage,         // Format a number as a percentage
+		"num_sci":       scientificNotation, // Format a number in scientific notation
+		"num_sub":       subtract,           // Subtract two integers
 	}
 }
 
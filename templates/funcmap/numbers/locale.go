@@ -0,0 +1,125 @@
+package numbers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	xcurrency "golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// printers caches a message printer per locale tag
+var printers sync.Map
+
+// symbolAfter lists the languages that write the currency symbol after the amount, e.g. "1.234,50 €".
+// The symbol is separated from the amount by a non-breaking space.
+var symbolAfter = map[string]bool{
+	"bg": true, "cs": true, "da": true, "de": true, "es": true, "et": true, "fi": true, "fr": true,
+	"hr": true, "hu": true, "it": true, "lt": true, "lv": true, "nb": true, "pl": true, "ro": true,
+	"ru": true, "sk": true, "sl": true, "sv": true, "uk": true,
+}
+
+// symbolSpaced lists the languages that write the currency symbol before the amount with a space,
+// e.g. "€ 1.234,50"
+var symbolSpaced = map[string]bool{"nl": true, "pt": true}
+
+// localePrinter returns the message printer for a BCP 47 locale tag such as "de-DE". An invalid or empty
+// tag falls back to English.
+func localePrinter(locale string) (*message.Printer, language.Tag) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.English
+	}
+
+	if p, ok := printers.Load(tag); ok {
+		return p.(*message.Printer), tag
+	}
+	p, _ := printers.LoadOrStore(tag, message.NewPrinter(tag))
+	return p.(*message.Printer), tag
+}
+
+// fmtInt formats a number as a whole number with the locale's digit grouping, e.g. "1.234.567" in de-DE.
+// Returns original input as string if not numeric.
+func fmtInt(locale string, i any) string {
+	f, err := toFloat64(i)
+	if err != nil {
+		return fmt.Sprintf("%v", i)
+	}
+
+	p, _ := localePrinter(locale)
+	return p.Sprint(number.Decimal(f, number.Scale(0)))
+}
+
+// fmtFloat formats a number with a fixed number of decimals and the locale's separators, e.g.
+// "1.234,50" in de-DE. Returns original input as string if not numeric.
+func fmtFloat(locale string, decimals int, i any) string {
+	f, err := toFloat64(i)
+	if err != nil {
+		return fmt.Sprintf("%v", i)
+	}
+
+	p, _ := localePrinter(locale)
+	return p.Sprint(number.Decimal(f, number.Scale(max(decimals, 0))))
+}
+
+// fmtPercent formats a fraction as a percentage in the locale, e.g. 0.256 is "25,6 %" in de-DE with one
+// decimal. Returns "0%" for non-numeric.
+func fmtPercent(locale string, decimals int, i any) string {
+	f, err := toFloat64(i)
+	if err != nil {
+		return "0%"
+	}
+
+	p, _ := localePrinter(locale)
+	return p.Sprint(number.Percent(f, number.Scale(max(decimals, 0))))
+}
+
+// fmtCurrency formats an amount in a currency, given as an ISO 4217 code such as "EUR", in the locale.
+// The amount is rounded to the currency's minor unit, so JPY has no decimals, and the symbol is placed
+// as the locale writes it, e.g. "$1,234.50" in en-US and "1.234,50 €" in de-DE. An unknown code is
+// used as the symbol. Non-numeric amounts are formatted as zero.
+func fmtCurrency(locale, code string, i any) string {
+	f, err := toFloat64(i)
+	if err != nil {
+		f = 0
+	}
+
+	p, tag := localePrinter(locale)
+
+	symbol := strings.ToUpper(code)
+	decimals := 2
+	if unit, err := xcurrency.ParseISO(code); err == nil {
+		symbol = p.Sprint(xcurrency.Symbol(unit))
+		decimals, _ = xcurrency.Standard.Rounding(unit)
+	}
+
+	sign := ""
+	if f < 0 && math.Round(f*math.Pow10(decimals)) != 0 {
+		sign = "-"
+	}
+	amount := p.Sprint(number.Decimal(math.Abs(f), number.Scale(decimals)))
+
+	base, _ := tag.Base()
+	switch {
+	case symbolAfter[base.String()]:
+		return sign + amount + "\u00a0" + symbol
+	case symbolSpaced[base.String()] || len(symbol) > 1 && isLetters(symbol):
+		return sign + symbol + "\u00a0" + amount
+	default:
+		return sign + symbol + amount
+	}
+}
+
+// isLetters reports whether s is made of ASCII letters only, like a currency code used as a symbol
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package numbers_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/patrickward/hop/templates/funcmap/numbers"
+)
+
+func TestFmtInt(t *testing.T) {
+	tests := []struct {
+		locale   string
+		input    any
+		expected string
+	}{
+		{"en-US", 1234567, "1,234,567"},
+		{"de-DE", 1234567, "1.234.567"},
+		{"fr-FR", int64(1234567), "1 234 567"},
+		{"de-DE", 1234.6, "1.235"},
+		{"invalid tag", 1234, "1,234"},
+		{"en-US", "abc", "abc"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, numbers.FuncMap()["num_fmt_int"].(func(string, any) string)(tt.locale, tt.input))
+	}
+}
+
+func TestFmtFloat(t *testing.T) {
+	tests := []struct {
+		locale   string
+		decimals int
+		input    any
+		expected string
+	}{
+		{"en-US", 2, 1234.5, "1,234.50"},
+		{"de-DE", 2, 1234.5, "1.234,50"},
+		{"de-DE", 0, 1234.5, "1.234"},
+		{"en-GB", -1, 3, "3"},
+		{"en-US", 2, "abc", "abc"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, numbers.FuncMap()["num_fmt_float"].(func(string, int, any) string)(tt.locale, tt.decimals, tt.input))
+	}
+}
+
+func TestFmtPercent(t *testing.T) {
+	tests := []struct {
+		locale   string
+		decimals int
+		input    any
+		expected string
+	}{
+		{"en-US", 1, 0.256, "25.6%"},
+		{"de-DE", 1, 0.256, "25,6 %"},
+		{"en-US", 0, 1, "100%"},
+		{"en-US", 0, "abc", "0%"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, numbers.FuncMap()["num_fmt_pct"].(func(string, int, any) string)(tt.locale, tt.decimals, tt.input))
+	}
+}
+
+func TestFmtCurrency(t *testing.T) {
+	tests := []struct {
+		locale   string
+		code     string
+		input    any
+		expected string
+	}{
+		{"en-US", "USD", 1234.5, "$1,234.50"},
+		{"en-US", "usd", -1234.567, "-$1,234.57"},
+		{"en-US", "EUR", 1234.5, "€1,234.50"},
+		{"en-US", "JPY", 1234.5, "¥1,234"},
+		{"de-DE", "EUR", 1234.5, "1.234,50 €"},
+		{"de-DE", "EUR", -0.001, "0,00 €"},
+		{"fr-FR", "EUR", 1234.5, "1 234,50 €"},
+		{"nl-NL", "EUR", 1234.5, "€ 1.234,50"},
+		{"en-GB", "USD", 5, "US$5.00"},
+		{"en-US", "CHF", 5, "CHF 5.00"},
+		{"en-US", "XYZ", 5, "XYZ 5.00"},
+		{"en-US", "USD", "abc", "$0.00"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, numbers.FuncMap()["num_fmt_cur"].(func(string, string, any) string)(tt.locale, tt.code, tt.input), tt.locale+" "+tt.code)
+	}
+}
@@ -3,6 +3,7 @@ package slices
 import (
 	"fmt"
 	"html/template"
+	"math/rand/v2"
 	"reflect"
 	"sort"
 )
@@ -10,15 +11,19 @@ import (
 // FuncMap returns slice-specific functions
 func FuncMap() template.FuncMap {
 	return template.FuncMap{
-		"slc_chunk":   Chunk,   // Was: "group"
-		"slc_filter":  Filter,  // Filter slice by predicate
-		"slc_group":   Group,   // Was: N/A"
-		"slc_has":     Has,     // Was: "has"
-		"slc_new":     New,     // Was: "slice" - Creates new slice
-		"slc_reverse": Reverse, // Reverse sort a slice
-		"slc_sort":    Sort,    // Sort any slice
-		"slc_unique":  Unique,  // Get unique elements
-
+		"slc_chunk":      Chunk,     // Was: "group"
+		"slc_filter":     Filter,    // Filter slice by predicate
+		"slc_group":      Group,     // Was: N/A"
+		"slc_has":        Has,       // Was: "has"
+		"slc_has_int":    HasInt,    // Check if any slice contains an integer
+		"slc_has_string": HasString, // Check if any slice contains a string
+		"slc_list":       List,      // Convert any slice to []any
+		"slc_new":        New,       // Was: "slice" - Creates new slice
+		"slc_pluck":      Pluck,     // Get a field or key from each element
+		"slc_reverse":    Reverse,   // Reverse sort a slice
+		"slc_shuffle":    Shuffle,   // Shuffle any slice
+		"slc_sort":       Sort,      // Sort any slice
+		"slc_unique":     Unique,    // Get unique elements
 	}
 }
 
@@ -168,3 +173,108 @@ func Filter(items []any, pred func(any) bool) []any {
 	}
 	return result
 }
+
+// List converts a slice or array of any type, such as []string or []User, to []any, so it can be passed
+// to the functions that take []any. A nil or non-slice value returns an empty slice.
+//
+// Example: {{ slc_sort (slc_list .Tags) }}
+func List(items any) []any {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []any{}
+	}
+
+	result := make([]any, v.Len())
+	for i := range result {
+		result[i] = v.Index(i).Interface()
+	}
+	return result
+}
+
+// Shuffle returns the elements of a slice in random order
+//
+// Example: {{ slc_shuffle .Slice }} -> [3 1 5 2 4]
+func Shuffle(items any) []any {
+	result := List(items)
+	rand.Shuffle(len(result), func(i, j int) {
+		result[i], result[j] = result[j], result[i]
+	})
+	return result
+}
+
+// Pluck returns the value of a struct field or map key from each element of a slice. Pointers are
+// followed, and elements without the field or key give nil.
+//
+// Example: {{ slc_pluck .Users "Name" }} -> [Alice Bob]
+func Pluck(items any, key string) []any {
+	list := List(items)
+	result := make([]any, len(list))
+	for i, item := range list {
+		result[i] = field(item, key)
+	}
+	return result
+}
+
+// HasString checks if a slice of strings, or of any string type, contains s
+//
+// Example: {{ slc_has_string .Roles "admin" }}
+func HasString(items any, s string) bool {
+	for _, item := range List(items) {
+		v := reflect.ValueOf(item)
+		if v.Kind() == reflect.String && v.String() == s {
+			return true
+		}
+	}
+	return false
+}
+
+// HasInt checks if a slice of integers of any size contains n
+//
+// Example: {{ slc_has_int .IDs 42 }}
+func HasInt(items any, n int) bool {
+	for _, item := range List(items) {
+		v := reflect.ValueOf(item)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.Int() == int64(n) {
+				return true
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if n >= 0 && v.Uint() == uint64(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// field returns the struct field or map key of item, or nil if there is none
+func field(item any, key string) any {
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		value := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return nil
+		}
+		return value.Interface()
+	case reflect.Struct:
+		f, ok := v.Type().FieldByName(key)
+		if !ok || !f.IsExported() {
+			return nil
+		}
+		return v.FieldByIndex(f.Index).Interface()
+	default:
+		return nil
+	}
+}
@@ -155,3 +155,58 @@ func TestFilter(t *testing.T) {
 		assert.Equal(t, tt.expected, result)
 	}
 }
+
+type user struct {
+	Name  string
+	Admin bool
+	email string
+}
+
+type role string
+
+func TestList(t *testing.T) {
+	list := slices.FuncMap()["slc_list"].(func(any) []any)
+	assert.Equal(t, []any{"a", "b"}, list([]string{"a", "b"}))
+	assert.Equal(t, []any{1, 2}, list([2]int{1, 2}))
+	assert.Equal(t, []any{}, list(nil))
+	assert.Equal(t, []any{}, list("not a slice"))
+}
+
+func TestShuffle(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	result := slices.FuncMap()["slc_shuffle"].(func(any) []any)(items)
+	assert.ElementsMatch(t, []any{1, 2, 3, 4, 5}, result)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, items, "the original slice is unchanged")
+}
+
+func TestPluck(t *testing.T) {
+	pluck := slices.FuncMap()["slc_pluck"].(func(any, string) []any)
+
+	users := []user{{Name: "Alice", Admin: true}, {Name: "Bob"}}
+	assert.Equal(t, []any{"Alice", "Bob"}, pluck(users, "Name"))
+	assert.Equal(t, []any{true, false}, pluck(users, "Admin"))
+	assert.Equal(t, []any{nil, nil}, pluck(users, "email"), "unexported fields are not plucked")
+	assert.Equal(t, []any{"Alice", nil}, pluck([]*user{&users[0], nil}, "Name"))
+
+	rows := []map[string]any{{"id": 1}, {"id": 2}, {}}
+	assert.Equal(t, []any{1, 2, nil}, pluck(rows, "id"))
+	assert.Equal(t, []any{"x", nil}, pluck([]any{map[string]string{"k": "x"}, 3}, "k"))
+}
+
+func TestHasString(t *testing.T) {
+	has := slices.FuncMap()["slc_has_string"].(func(any, string) bool)
+	assert.True(t, has([]string{"admin", "editor"}, "admin"))
+	assert.True(t, has([]role{"admin"}, "admin"))
+	assert.True(t, has([]any{1, "admin"}, "admin"))
+	assert.False(t, has([]string{"editor"}, "admin"))
+	assert.False(t, has(nil, "admin"))
+}
+
+func TestHasInt(t *testing.T) {
+	has := slices.FuncMap()["slc_has_int"].(func(any, int) bool)
+	assert.True(t, has([]int{1, 42}, 42))
+	assert.True(t, has([]int64{42}, 42))
+	assert.True(t, has([]uint8{42}, 42))
+	assert.False(t, has([]uint{1}, -1))
+	assert.False(t, has([]string{"42"}, 42))
+}
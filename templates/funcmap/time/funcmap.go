@@ -9,12 +9,20 @@ import (
 // FuncMap returns a function map with functions for working with time.Time values.
 func FuncMap() template.FuncMap {
 	return template.FuncMap{
-		"time_ago":     timeAgo,
-		"time_format":  formatTime,
-		"time_isToday": isToday,
-		"time_now":     time.Now,
-		"time_since":   time.Since,
-		"time_until":   time.Until,
+		"time_add_days":       addDays,
+		"time_ago":            timeAgo,
+		"time_format":         formatTime,
+		"time_html":           timeHTML,
+		"time_humanize":       humanize,
+		"time_in":             in,
+		"time_isToday":        isToday,
+		"time_now":            time.Now,
+		"time_rfc3339":        rfc3339,
+		"time_since":          time.Since,
+		"time_start_of_day":   startOfDay,
+		"time_start_of_month": startOfMonth,
+		"time_start_of_week":  startOfWeek,
+		"time_until":          time.Until,
 	}
 }
 
@@ -43,54 +51,119 @@ func timeAgo(t time.Time) string {
 	}
 
 	// Very recent
-	if duration < time.Minute {
-		secs := int(duration.Seconds())
-		if secs <= 3 {
+	if isJustNow(duration) {
+		return "just now"
+	}
+
+	return approximateDuration(duration) + " ago"
+}
+
+// humanize returns how long ago or from now the given time is, e.g. "3 hours ago" or "in 2 days"
+func humanize(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	duration := time.Since(t)
+	if duration < 0 {
+		duration = -duration
+		if isJustNow(duration) {
 			return "just now"
 		}
-		return fmt.Sprintf("%d seconds ago", secs)
+		return "in " + approximateDuration(duration)
 	}
 
-	// Minutes
-	if duration < time.Hour {
-		mins := int(duration.Minutes())
-		if mins == 1 {
-			return "1 minute ago"
-		}
-		return fmt.Sprintf("%d minutes ago", mins)
+	if isJustNow(duration) {
+		return "just now"
 	}
+	return approximateDuration(duration) + " ago"
+}
 
-	// Hours
-	if duration < 24*time.Hour {
-		hours := int(duration.Hours())
-		if hours == 1 {
-			return "1 hour ago"
-		}
-		return fmt.Sprintf("%d hours ago", hours)
+// isJustNow reports whether a duration is short enough to call "just now"
+func isJustNow(duration time.Duration) bool {
+	return int(duration.Seconds()) <= 3
+}
+
+// approximateDuration describes a duration in its largest whole unit, e.g. "3 hours" or "1 month"
+func approximateDuration(duration time.Duration) string {
+	switch {
+	case duration < time.Minute:
+		return plural(int(duration.Seconds()), "second")
+	case duration < time.Hour:
+		return plural(int(duration.Minutes()), "minute")
+	case duration < 24*time.Hour:
+		return plural(int(duration.Hours()), "hour")
+	case duration < 30*24*time.Hour:
+		return plural(int(duration.Hours()/24), "day")
+	case duration < 365*24*time.Hour:
+		return plural(int(duration.Hours()/24/30), "month")
+	default:
+		return plural(int(duration.Hours()/24/365), "year")
 	}
+}
 
-	// Days
-	if duration < 30*24*time.Hour {
-		days := int(duration.Hours() / 24)
-		if days == 1 {
-			return "1 day ago"
-		}
-		return fmt.Sprintf("%d days ago", days)
+// plural returns the count with the unit, adding an s unless the count is 1
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
 	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
 
-	// Months
-	if duration < 365*24*time.Hour {
-		months := int(duration.Hours() / 24 / 30)
-		if months == 1 {
-			return "1 month ago"
-		}
-		return fmt.Sprintf("%d months ago", months)
+// rfc3339 formats a time as RFC 3339, the format of the datetime attribute of HTML time elements
+func rfc3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
+	return t.Format(time.RFC3339)
+}
 
-	// Years
-	years := int(duration.Hours() / 24 / 365)
-	if years == 1 {
-		return "1 year ago"
+// timeHTML returns a time element with the time formatted for display and as RFC 3339 in its datetime
+// attribute, e.g. <time datetime="2025-01-02T15:04:05Z">Jan 2, 2025</time>
+func timeHTML(format string, t time.Time) template.HTML {
+	if t.IsZero() {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf(`<time datetime="%s">%s</time>`,
+		template.HTMLEscapeString(t.Format(time.RFC3339)),
+		template.HTMLEscapeString(t.Format(format))))
+}
+
+// in converts a time to a timezone, given as a *time.Location or an IANA name such as "Europe/Berlin".
+// An unknown timezone leaves the time unchanged.
+func in(location any, t time.Time) time.Time {
+	switch loc := location.(type) {
+	case *time.Location:
+		if loc != nil {
+			return t.In(loc)
+		}
+	case string:
+		if l, err := time.LoadLocation(loc); err == nil {
+			return t.In(l)
+		}
 	}
-	return fmt.Sprintf("%d years ago", years)
+	return t
+}
+
+// addDays adds a number of days to a time, keeping the time of day across daylight saving changes
+func addDays(days int, t time.Time) time.Time {
+	return t.AddDate(0, 0, days)
+}
+
+// startOfDay returns midnight at the start of the time's day, in the time's timezone
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns midnight at the start of the time's week, in the time's timezone. Weeks start on Monday.
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -offset)
+}
+
+// startOfMonth returns midnight on the first day of the time's month, in the time's timezone
+func startOfMonth(t time.Time) time.Time {
+	year, month, _ := t.Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
 }
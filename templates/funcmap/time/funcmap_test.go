@@ -1,10 +1,12 @@
 package time_test
 
 import (
+	"html/template"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	hopTime "github.com/patrickward/hop/templates/funcmap/time"
 )
@@ -93,3 +95,77 @@ func TestTimeUntil(t *testing.T) {
 	result := hopTime.FuncMap()["time_until"].(func(time.Time) time.Duration)(future)
 	assert.WithinDuration(t, now, now.Add(result), time.Hour+time.Second)
 }
+
+func TestHumanize(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		input    time.Time
+		expected string
+	}{
+		{now.Add(-time.Second), "just now"},
+		{now.Add(-time.Minute * 2), "2 minutes ago"},
+		{now.Add(-time.Hour * 3), "3 hours ago"},
+		{now.Add(time.Second), "just now"},
+		{now.Add(time.Minute*5 + time.Second), "in 5 minutes"},
+		{now.Add(time.Hour*24*2 + time.Minute), "in 2 days"},
+		{now.Add(time.Hour*24*365 + time.Minute), "in 1 year"},
+		{time.Time{}, ""},
+	}
+
+	for _, tt := range tests {
+		result := hopTime.FuncMap()["time_humanize"].(func(time.Time) string)(tt.input)
+		assert.Equal(t, tt.expected, result)
+	}
+}
+
+func TestRFC3339(t *testing.T) {
+	rfc3339 := hopTime.FuncMap()["time_rfc3339"].(func(time.Time) string)
+	assert.Equal(t, "2025-03-04T05:06:07Z", rfc3339(time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)))
+	assert.Equal(t, "2025-03-04T05:06:07+01:00", rfc3339(time.Date(2025, 3, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600))))
+	assert.Equal(t, "", rfc3339(time.Time{}))
+}
+
+func TestTimeHTML(t *testing.T) {
+	timeHTML := hopTime.FuncMap()["time_html"].(func(string, time.Time) template.HTML)
+	assert.Equal(t, template.HTML(`<time datetime="2025-03-04T05:06:07Z">Mar 4, 2025</time>`),
+		timeHTML("Jan 2, 2006", time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)))
+	assert.Equal(t, template.HTML(""), timeHTML("Jan 2, 2006", time.Time{}))
+}
+
+func TestIn(t *testing.T) {
+	in := hopTime.FuncMap()["time_in"].(func(any, time.Time) time.Time)
+	input := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	berlin := in("Europe/Berlin", input)
+	assert.Equal(t, 14, berlin.Hour())
+	assert.True(t, berlin.Equal(input))
+
+	assert.Equal(t, 8, in(time.FixedZone("EDT", -4*3600), input).Hour())
+	assert.Equal(t, input, in("Not/AZone", input))
+	assert.Equal(t, input, in((*time.Location)(nil), input))
+	assert.Equal(t, input, in(42, input))
+}
+
+func TestDateMath(t *testing.T) {
+	funcs := hopTime.FuncMap()
+	addDays := funcs["time_add_days"].(func(int, time.Time) time.Time)
+	startOfDay := funcs["time_start_of_day"].(func(time.Time) time.Time)
+	startOfWeek := funcs["time_start_of_week"].(func(time.Time) time.Time)
+	startOfMonth := funcs["time_start_of_month"].(func(time.Time) time.Time)
+
+	// Thursday
+	input := time.Date(2025, 3, 13, 15, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 16, 15, 30, 0, 0, time.UTC), addDays(3, input))
+	assert.Equal(t, time.Date(2025, 3, 6, 15, 30, 0, 0, time.UTC), addDays(-7, input))
+	assert.Equal(t, time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC), startOfDay(input))
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), startOfWeek(input))
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), startOfWeek(time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), startOfWeek(time.Date(2025, 3, 16, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), startOfMonth(input))
+
+	// Days are calendar days across a daylight saving change
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	beforeDST := time.Date(2025, 3, 8, 9, 0, 0, 0, newYork)
+	assert.Equal(t, 9, addDays(1, beforeDST).Hour())
+}
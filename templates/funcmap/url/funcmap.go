@@ -11,6 +11,7 @@ func FuncMap() template.FuncMap {
 	return template.FuncMap{
 		"url_set":     urlSetParam,     // Set a query parameter in a URL
 		"url_del":     urlDelParam,     // Delete a query parameter from a URL
+		"url_page":    urlSetPage,      // Set the page query parameter in a URL
 		"url_to_attr": urlToAttr,       // Convert a URL to a template.HTMLAttr value
 		"url_escape":  url.QueryEscape, // Escape a string for use in a URL
 	}
@@ -27,6 +28,11 @@ func urlSetParam(key string, value any, u *url.URL) *url.URL {
 	return &nu
 }
 
+// urlSetPage sets the page query parameter in a URL, keeping the other parameters
+func urlSetPage(page any, u *url.URL) *url.URL {
+	return urlSetParam("page", page, u)
+}
+
 // urlDelParam deletes a query parameter from a URL
 func urlDelParam(key string, u *url.URL) *url.URL {
 	nu := *u
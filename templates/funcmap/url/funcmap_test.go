@@ -75,3 +75,10 @@ func TestUrlEscape(t *testing.T) {
 		assert.Equal(t, tt.expected, result)
 	}
 }
+
+func TestUrlSetPage(t *testing.T) {
+	u, _ := url.Parse("https://example.com/items?q=go&page=1")
+	result := hopURL.FuncMap()["url_page"].(func(any, *url.URL) *url.URL)(3, u)
+	assert.Equal(t, "https://example.com/items?page=3&q=go", result.String())
+	assert.Equal(t, "https://example.com/items?q=go&page=1", u.String(), "the original URL is unchanged")
+}
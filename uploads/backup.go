@@ -0,0 +1,150 @@
+package uploads
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Backup is a hop.BackupModule holding the files of a Storage. Register it with the App next to the
+// storage:
+//
+//	app.RegisterModule(uploads.NewBackup(storage))
+//
+// The files are written as a tar archive. Restore saves every file of the archive and deletes the stored
+// files that aren't in it.
+type Backup struct {
+	storage Storage
+}
+
+// NewBackup creates a Backup for the files of storage
+func NewBackup(storage Storage) *Backup {
+	return &Backup{storage: storage}
+}
+
+// ID implements hop.Module
+func (b *Backup) ID() string {
+	return "hop.uploads"
+}
+
+// Init implements hop.Module
+func (b *Backup) Init() error {
+	return nil
+}
+
+// Start implements hop.Module
+func (b *Backup) Start(context.Context) error {
+	return nil
+}
+
+// Stop implements hop.Module
+func (b *Backup) Stop(context.Context) error {
+	return nil
+}
+
+// Backup writes the stored files to w as a tar archive
+func (b *Backup) Backup(ctx context.Context, w io.Writer) error {
+	names, err := b.files()
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := b.backupFile(tw, name); err != nil {
+			return fmt.Errorf("backing up %s: %w", name, err)
+		}
+	}
+	return tw.Close()
+}
+
+// Restore saves the files of the tar archive read from r and deletes the stored files missing from it
+func (b *Backup) Restore(ctx context.Context, r io.Reader) error {
+	restored := make(map[string]bool)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if _, err := b.storage.Save(ctx, hdr.Name, tr); err != nil {
+			return fmt.Errorf("restoring %s: %w", hdr.Name, err)
+		}
+		restored[hdr.Name] = true
+	}
+
+	names, err := b.files()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if restored[name] {
+			continue
+		}
+		if err := b.storage.Delete(ctx, name); err != nil {
+			return fmt.Errorf("deleting %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// files returns the names of the stored files, leaving out the temporary files of saves in progress
+func (b *Backup) files() ([]string, error) {
+	var names []string
+	err := fs.WalkDir(b.storage, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && !strings.HasPrefix(path.Base(name), ".upload-") {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing files: %w", err)
+	}
+	return names, nil
+}
+
+// backupFile writes a stored file to the archive
+func (b *Backup) backupFile(tw *tar.Writer, name string) error {
+	f, err := b.storage.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(tw, f, info.Size())
+	return err
+}
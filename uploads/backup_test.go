@@ -0,0 +1,51 @@
+package uploads_test
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/uploads"
+)
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	storage, err := uploads.NewDiskStorage(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = storage.Save(ctx, "avatars/a.png", strings.NewReader(pngHeader))
+	require.NoError(t, err)
+	_, err = storage.Save(ctx, "docs/report.pdf", strings.NewReader("%PDF-1.7"))
+	require.NoError(t, err)
+
+	backup := uploads.NewBackup(storage)
+	assert.Equal(t, "hop.uploads", backup.ID())
+
+	var buf bytes.Buffer
+	require.NoError(t, backup.Backup(ctx, &buf))
+
+	// Files changed, deleted and added after the backup are restored to their backed up state
+	_, err = storage.Save(ctx, "avatars/a.png", strings.NewReader("changed"))
+	require.NoError(t, err)
+	require.NoError(t, storage.Delete(ctx, "docs/report.pdf"))
+	_, err = storage.Save(ctx, "avatars/b.png", strings.NewReader(pngHeader))
+	require.NoError(t, err)
+
+	require.NoError(t, backup.Restore(ctx, &buf))
+
+	data, err := fs.ReadFile(storage, "avatars/a.png")
+	require.NoError(t, err)
+	assert.Equal(t, pngHeader, string(data))
+
+	data, err = fs.ReadFile(storage, "docs/report.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.7", string(data))
+
+	_, err = fs.Stat(storage, "avatars/b.png")
+	assert.ErrorIs(t, err, fs.ErrNotExist, "files missing from the backup should be deleted")
+}
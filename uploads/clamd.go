@@ -0,0 +1,163 @@
+package uploads
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ClamdOptions configures a ClamdScanner
+type ClamdOptions struct {
+	// Network is "tcp" or "unix". Default is "tcp".
+	Network string
+	// Address is the address of the daemon, e.g. "127.0.0.1:3310" or "/run/clamav/clamd.ctl". Default is
+	// "127.0.0.1:3310".
+	Address string
+	// Timeout limits connecting and each scan, in addition to the context's deadline. Default is 1 minute.
+	Timeout time.Duration
+	// ChunkSize is the size of the chunks streamed to the daemon. It must not exceed the daemon's
+	// StreamMaxLength. Default is 64 KiB.
+	ChunkSize int
+}
+
+// ClamdScanner is a Scanner that streams files to a ClamAV daemon with the INSTREAM command
+type ClamdScanner struct {
+	opts ClamdOptions
+}
+
+// NewClamdScanner creates a new ClamdScanner
+func NewClamdScanner(optsFunc func(opts *ClamdOptions)) *ClamdScanner {
+	opts := ClamdOptions{
+		Network:   "tcp",
+		Address:   "127.0.0.1:3310",
+		Timeout:   time.Minute,
+		ChunkSize: 64 * 1024,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 64 * 1024
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+
+	return &ClamdScanner{opts: opts}
+}
+
+// Scan streams the content of r to the daemon and returns its verdict
+func (c *ClamdScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, fmt.Errorf("clamd: sending command: %w", err)
+	}
+
+	buf := make([]byte, 4+c.opts.ChunkSize)
+	for {
+		n, readErr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return ScanResult{}, fmt.Errorf("clamd: streaming file: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return ScanResult{}, fmt.Errorf("clamd: reading file: %w", readErr)
+		}
+		if ctx.Err() != nil {
+			return ScanResult{}, ctx.Err()
+		}
+	}
+
+	// A zero-length chunk ends the stream
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return ScanResult{}, fmt.Errorf("clamd: ending stream: %w", err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	return parseScanReply(reply)
+}
+
+// Ping checks that the daemon is reachable
+func (c *ClamdScanner) Ping(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return fmt.Errorf("clamd: sending command: %w", err)
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("clamd: unexpected reply to PING: %q", reply)
+	}
+	return nil
+}
+
+// dial connects to the daemon, with a deadline from the timeout and the context
+func (c *ClamdScanner) dial(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: c.opts.Timeout}
+	conn, err := dialer.DialContext(ctx, c.opts.Network, c.opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: connecting: %w", err)
+	}
+
+	deadline := time.Now().Add(c.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+// readReply reads a null-terminated reply
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && !(errors.Is(err, io.EOF) && len(reply) > 0) {
+		return "", fmt.Errorf("clamd: reading reply: %w", err)
+	}
+	return string(bytes.TrimRight(reply, "\x00\n")), nil
+}
+
+// parseScanReply parses an INSTREAM reply, e.g. "stream: OK" or "stream: Eicar-Test-Signature FOUND"
+func parseScanReply(reply string) (ScanResult, error) {
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return ScanResult{Verdict: VerdictClean, ScannedAt: time.Now()}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return ScanResult{
+			Verdict:   VerdictInfected,
+			Signature: strings.TrimSuffix(result, " FOUND"),
+			ScannedAt: time.Now(),
+		}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd: %s", result)
+	}
+}
@@ -0,0 +1,102 @@
+package uploads_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/uploads"
+)
+
+// fakeClamd answers INSTREAM commands, reporting streams containing "EICAR" as infected
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				cmd := make([]byte, 0, 16)
+				b := make([]byte, 1)
+				for {
+					if _, err := conn.Read(b); err != nil {
+						return
+					}
+					if b[0] == 0 {
+						break
+					}
+					cmd = append(cmd, b[0])
+				}
+
+				switch string(cmd) {
+				case "zPING":
+					_, _ = conn.Write([]byte("PONG\x00"))
+				case "zINSTREAM":
+					var data bytes.Buffer
+					for {
+						var size uint32
+						if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+							return
+						}
+						if size == 0 {
+							break
+						}
+						if _, err := io.CopyN(&data, conn, int64(size)); err != nil {
+							return
+						}
+					}
+					if strings.Contains(data.String(), "EICAR") {
+						_, _ = conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+						return
+					}
+					_, _ = conn.Write([]byte("stream: OK\x00"))
+				default:
+					_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+				}
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestClamdScanner(t *testing.T) {
+	addr := fakeClamd(t)
+	scanner := uploads.NewClamdScanner(func(opts *uploads.ClamdOptions) {
+		opts.Address = addr
+		opts.ChunkSize = 4 // exercise several chunks
+	})
+	ctx := context.Background()
+
+	require.NoError(t, scanner.Ping(ctx))
+
+	result, err := scanner.Scan(ctx, strings.NewReader("a harmless document"))
+	require.NoError(t, err)
+	assert.Equal(t, uploads.VerdictClean, result.Verdict)
+
+	result, err = scanner.Scan(ctx, strings.NewReader("X5O!P%@AP EICAR test file"))
+	require.NoError(t, err)
+	assert.Equal(t, uploads.VerdictInfected, result.Verdict)
+	assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+
+	unreachable := uploads.NewClamdScanner(func(opts *uploads.ClamdOptions) {
+		opts.Address = "127.0.0.1:1"
+	})
+	_, err = unreachable.Scan(ctx, strings.NewReader("data"))
+	assert.ErrorContains(t, err, "clamd: connecting")
+}
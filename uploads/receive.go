@@ -0,0 +1,358 @@
+package uploads
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/patrickward/hop/render/request"
+)
+
+// Errors wrapped by the *request.DecodeError returned by Receive
+var (
+	// ErrNotMultipart is returned when the request is not a multipart/form-data request
+	ErrNotMultipart = errors.New("uploads: request is not multipart/form-data")
+	// ErrTooLarge is returned when a file or the whole request exceeds its size limit
+	ErrTooLarge = errors.New("uploads: upload is too large")
+	// ErrTooManyFiles is returned when the request holds more files than allowed
+	ErrTooManyFiles = errors.New("uploads: too many files")
+	// ErrTypeNotAllowed is returned when the content of a file is not of an allowed type
+	ErrTypeNotAllowed = errors.New("uploads: file type is not allowed")
+	// ErrNotScannable is returned when a ScanGuard is set but the storage doesn't keep files on disk
+	ErrNotScannable = errors.New("uploads: storage does not keep files on disk, so they can't be scanned")
+)
+
+// ReceiveOptions configures Receive
+type ReceiveOptions struct {
+	// MaxFileSize is the largest file accepted, in bytes. Default is 10 MB.
+	MaxFileSize int64
+	// MaxFiles is the largest number of files accepted in one request. Default is 10.
+	MaxFiles int
+	// MaxRequestSize is the largest request body accepted, in bytes. Default is MaxFiles * MaxFileSize
+	// plus 1 MB for the other form fields.
+	MaxRequestSize int64
+	// AllowedTypes lists the accepted content types, detected from the content of the files rather than
+	// trusted from the client, e.g. "image/png" or "image/*". Default is empty, which accepts any type.
+	AllowedTypes []string
+	// Fields lists the form fields that may hold files. Default is empty, which accepts files in any field.
+	Fields []string
+	// Dir is the directory of the storage the files are saved in, e.g. "avatars". Default is the root.
+	Dir string
+	// Name returns the name a file is stored under, from its client file name. Default is UniqueName.
+	Name func(original string) string
+	// Scan, when set, scans each file once stored, under its stored name as the ID. An infected file fails
+	// the upload with ErrInfected, unless the guard is Async, in which case the file is accepted with a
+	// pending result to look up with Scan.Status. The storage must keep files on disk, like DiskStorage.
+	Scan *ScanGuard
+}
+
+// File describes a stored upload
+type File struct {
+	// Field is the form field the file was uploaded in
+	Field string
+	// Name is the name the file is stored under, including Dir
+	Name string
+	// OriginalName is the base name of the file on the client, for display only
+	OriginalName string
+	// ContentType is the content type detected from the file content
+	ContentType string
+	// Size is the size of the file in bytes
+	Size int64
+	// Scan is the result of scanning the file when ReceiveOptions.Scan is set. It is VerdictPending
+	// when the guard scans asynchronously.
+	Scan ScanResult
+}
+
+// Upload is the result of Receive: the stored files and the other form values of the request
+type Upload struct {
+	Files  []File
+	Values url.Values
+}
+
+// File returns the first file uploaded in field, and whether there is one
+func (u *Upload) File(field string) (File, bool) {
+	for _, f := range u.Files {
+		if f.Field == field {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+// Receive streams the files of a multipart/form-data request to storage, without buffering them in memory
+// or temporary files, and returns them with the other form values. Files are checked against the size and
+// type limits as they are read, and scanned once stored when ReceiveOptions.Scan is set; when one fails,
+// the files already stored are deleted.
+//
+// Problems with the request are returned as a *request.DecodeError with the status to respond with (400,
+// 413, 415 or 422), wrapping ErrNotMultipart, ErrTooLarge, ErrTooManyFiles, ErrTypeNotAllowed or
+// ErrInfected, so render.ProblemFrom and Response.RenderError describe them to the client. Other errors
+// come from the storage or the scanner.
+//
+//	upload, err := uploads.Receive(w, r, store, func(opts *uploads.ReceiveOptions) {
+//	    opts.MaxFileSize = 5 << 20
+//	    opts.AllowedTypes = []string{"image/png", "image/jpeg"}
+//	    opts.Dir = "avatars"
+//	})
+//	if err != nil {
+//	    app.NewResponse(r).RenderError(w, r, err)
+//	    return
+//	}
+func Receive(w http.ResponseWriter, r *http.Request, storage Storage, optsFunc func(opts *ReceiveOptions)) (*Upload, error) {
+	opts := ReceiveOptions{
+		MaxFileSize: 10 << 20,
+		MaxFiles:    10,
+		Name:        UniqueName,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = 10 << 20
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = 10
+	}
+	if opts.MaxRequestSize <= 0 {
+		opts.MaxRequestSize = int64(opts.MaxFiles)*opts.MaxFileSize + 1<<20
+	}
+	if opts.Name == nil {
+		opts.Name = UniqueName
+	}
+
+	var disk pathStorage
+	if opts.Scan != nil {
+		var ok bool
+		if disk, ok = storage.(pathStorage); !ok {
+			return nil, ErrNotScannable
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return nil, &request.DecodeError{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: "Content-Type must be multipart/form-data",
+			Err:     ErrNotMultipart,
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, opts.MaxRequestSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, &request.DecodeError{Status: http.StatusBadRequest, Message: "Request body is not valid multipart data", Err: err}
+	}
+
+	upload := &Upload{Values: make(url.Values)}
+	if err := receiveParts(r.Context(), reader, storage, disk, opts, upload); err != nil {
+		for _, f := range upload.Files {
+			_ = storage.Delete(context.WithoutCancel(r.Context()), f.Name)
+		}
+		return nil, err
+	}
+	return upload, nil
+}
+
+// receiveParts reads the parts of the request, storing files and collecting form values into upload
+func receiveParts(ctx context.Context, reader *multipart.Reader, storage Storage, disk pathStorage, opts ReceiveOptions, upload *Upload) error {
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return readError(err, "")
+		}
+
+		field := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(part)
+			if err != nil {
+				return readError(err, field)
+			}
+			upload.Values.Add(field, string(value))
+			continue
+		}
+
+		if len(opts.Fields) > 0 && !contains(opts.Fields, field) {
+			return &request.DecodeError{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("Field %q does not accept files", field),
+				Field:   field,
+			}
+		}
+		if len(upload.Files) >= opts.MaxFiles {
+			return &request.DecodeError{
+				Status:  http.StatusRequestEntityTooLarge,
+				Message: fmt.Sprintf("No more than %d files can be uploaded at once", opts.MaxFiles),
+				Field:   field,
+				Err:     ErrTooManyFiles,
+			}
+		}
+
+		file, err := receiveFile(ctx, part, storage, opts)
+		if err != nil {
+			return err
+		}
+		upload.Files = append(upload.Files, file)
+
+		if opts.Scan != nil {
+			if err := scanUpload(ctx, disk, opts.Scan, &upload.Files[len(upload.Files)-1]); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// pathStorage is a Storage keeping files on disk, which a ScanGuard can scan
+type pathStorage interface {
+	Storage
+	Path(name string) (string, error)
+}
+
+// scanUpload scans a stored file with the guard, recording the result in file. Synchronous results are
+// dropped from the guard once recorded, since the caller gets them with the file.
+func scanUpload(ctx context.Context, disk pathStorage, guard *ScanGuard, file *File) error {
+	path, err := disk.Path(file.Name)
+	if err != nil {
+		return err
+	}
+
+	result, err := guard.Check(ctx, file.Name, path)
+	file.Scan = result
+	if result.Verdict != VerdictPending {
+		guard.Forget(file.Name)
+	}
+	if errors.Is(err, ErrInfected) {
+		return &request.DecodeError{
+			Status:  http.StatusUnprocessableEntity,
+			Message: fmt.Sprintf("File %q was refused by the malware scanner", file.OriginalName),
+			Field:   file.Field,
+			Err:     ErrInfected,
+		}
+	}
+	return err
+}
+
+// receiveFile checks the type of a file part and streams it to storage
+func receiveFile(ctx context.Context, part *multipart.Part, storage Storage, opts ReceiveOptions) (File, error) {
+	field := part.FormName()
+	original := path.Base(strings.ReplaceAll(part.FileName(), "\\", "/"))
+
+	// Detect the content type from the first bytes, which are kept to be stored with the rest
+	buffered := bufio.NewReaderSize(&limitedReader{r: part, remaining: opts.MaxFileSize}, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return File{}, readError(err, field)
+	}
+	contentType := http.DetectContentType(head)
+	if len(opts.AllowedTypes) > 0 && !typeAllowed(opts.AllowedTypes, contentType) {
+		return File{}, &request.DecodeError{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("Files of type %s are not allowed", mediaTypeOf(contentType)),
+			Field:   field,
+			Err:     ErrTypeNotAllowed,
+		}
+	}
+
+	name := path.Join(opts.Dir, opts.Name(original))
+	size, err := storage.Save(ctx, name, buffered)
+	if err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			return File{}, &request.DecodeError{
+				Status:  http.StatusRequestEntityTooLarge,
+				Message: fmt.Sprintf("Files must be no larger than %d bytes", opts.MaxFileSize),
+				Field:   field,
+				Err:     ErrTooLarge,
+			}
+		}
+		return File{}, readError(err, field)
+	}
+
+	return File{
+		Field:        field,
+		Name:         name,
+		OriginalName: original,
+		ContentType:  contentType,
+		Size:         size,
+	}, nil
+}
+
+// readError turns an error reading the request into a *request.DecodeError, or returns it as is when it
+// didn't come from the request
+func readError(err error, field string) error {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &request.DecodeError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("Request body must be no larger than %d bytes", maxBytesErr.Limit),
+			Field:   field,
+			Err:     ErrTooLarge,
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF), strings.HasPrefix(err.Error(), "multipart:"):
+		return &request.DecodeError{Status: http.StatusBadRequest, Message: "Request body is not valid multipart data", Field: field, Err: err}
+	}
+	return err
+}
+
+// typeAllowed reports whether the detected content type matches one of the allowed types
+func typeAllowed(allowed []string, contentType string) bool {
+	mediaType := mediaTypeOf(contentType)
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	for _, a := range allowed {
+		if a == mediaType || a == mainType+"/*" || a == "*/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaTypeOf returns the media type of a content type, without its parameters
+func mediaTypeOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedReader fails with ErrTooLarge once more than remaining bytes have been read
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
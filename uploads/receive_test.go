@@ -0,0 +1,295 @@
+package uploads_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/render/request"
+	"github.com/patrickward/hop/uploads"
+)
+
+// pngHeader is the start of a PNG file, enough for content type detection
+var pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+type part struct {
+	field, filename, content string
+}
+
+func multipartRequest(t *testing.T, parts ...part) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range parts {
+		var w io.Writer
+		var err error
+		if p.filename == "" {
+			w, err = mw.CreateFormField(p.field)
+		} else {
+			w, err = mw.CreateFormFile(p.field, p.filename)
+		}
+		require.NoError(t, err)
+		_, err = io.WriteString(w, p.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func storedFiles(t *testing.T, store uploads.Storage) []string {
+	t.Helper()
+	var names []string
+	require.NoError(t, fs.WalkDir(store, ".", func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, name)
+		}
+		return err
+	}))
+	return names
+}
+
+func TestReceive(t *testing.T) {
+	t.Run("stores files under unique names", func(t *testing.T) {
+		store, err := uploads.NewDiskStorage(t.TempDir())
+		require.NoError(t, err)
+
+		r := multipartRequest(t,
+			part{field: "title", content: "Holidays"},
+			part{field: "photo", filename: `C:\Users\ada\Beach Photo.PNG`, content: pngHeader + "data"},
+			part{field: "notes", filename: "notes.txt", content: "hello"},
+		)
+		upload, err := uploads.Receive(httptest.NewRecorder(), r, store, func(opts *uploads.ReceiveOptions) {
+			opts.Dir = "albums"
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Holidays", upload.Values.Get("title"))
+		require.Len(t, upload.Files, 2)
+
+		photo, ok := upload.File("photo")
+		require.True(t, ok)
+		assert.Regexp(t, `^albums/[0-9a-f]{32}\.png$`, photo.Name)
+		assert.Equal(t, "Beach Photo.PNG", photo.OriginalName)
+		assert.Equal(t, "image/png", photo.ContentType)
+		assert.Equal(t, int64(len(pngHeader)+4), photo.Size)
+
+		content, err := fs.ReadFile(store, photo.Name)
+		require.NoError(t, err)
+		assert.Equal(t, pngHeader+"data", string(content))
+
+		notes, _ := upload.File("notes")
+		assert.Equal(t, "text/plain; charset=utf-8", notes.ContentType)
+		assert.ElementsMatch(t, []string{photo.Name, notes.Name}, storedFiles(t, store))
+	})
+
+	tests := []struct {
+		name       string
+		parts      []part
+		opts       func(opts *uploads.ReceiveOptions)
+		wantStatus int
+		wantErr    error
+	}{
+		{
+			name:       "file too large",
+			parts:      []part{{field: "a", filename: "a.txt", content: "ok"}, {field: "b", filename: "b.txt", content: strings.Repeat("x", 2048)}},
+			opts:       func(opts *uploads.ReceiveOptions) { opts.MaxFileSize = 1024 },
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantErr:    uploads.ErrTooLarge,
+		},
+		{
+			name:       "request too large",
+			parts:      []part{{field: "a", filename: "a.txt", content: strings.Repeat("x", 4096)}},
+			opts:       func(opts *uploads.ReceiveOptions) { opts.MaxRequestSize = 1024 },
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantErr:    uploads.ErrTooLarge,
+		},
+		{
+			name:       "too many files",
+			parts:      []part{{field: "a", filename: "a.txt", content: "a"}, {field: "b", filename: "b.txt", content: "b"}},
+			opts:       func(opts *uploads.ReceiveOptions) { opts.MaxFiles = 1 },
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantErr:    uploads.ErrTooManyFiles,
+		},
+		{
+			name:       "type detected from the content",
+			parts:      []part{{field: "a", filename: "a.png", content: "<html><script>alert(1)</script>"}},
+			opts:       func(opts *uploads.ReceiveOptions) { opts.AllowedTypes = []string{"image/*"} },
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantErr:    uploads.ErrTypeNotAllowed,
+		},
+		{
+			name:       "field not accepting files",
+			parts:      []part{{field: "other", filename: "a.txt", content: "a"}},
+			opts:       func(opts *uploads.ReceiveOptions) { opts.Fields = []string{"avatar"} },
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := uploads.NewDiskStorage(t.TempDir())
+			require.NoError(t, err)
+
+			_, err = uploads.Receive(httptest.NewRecorder(), multipartRequest(t, tt.parts...), store, tt.opts)
+			var decodeErr *request.DecodeError
+			require.True(t, errors.As(err, &decodeErr), "got %v", err)
+			assert.Equal(t, tt.wantStatus, decodeErr.Status)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Empty(t, storedFiles(t, store), "stored files are deleted on failure")
+		})
+	}
+
+	t.Run("not multipart", func(t *testing.T) {
+		store, err := uploads.NewDiskStorage(t.TempDir())
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}"))
+		r.Header.Set("Content-Type", "application/json")
+		_, err = uploads.Receive(httptest.NewRecorder(), r, store, nil)
+		assert.ErrorIs(t, err, uploads.ErrNotMultipart)
+	})
+}
+
+func TestDiskStorage(t *testing.T) {
+	ctx := context.Background()
+	store, err := uploads.NewDiskStorage(t.TempDir())
+	require.NoError(t, err)
+
+	n, err := store.Save(ctx, "a/b.txt", strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	_, err = store.Save(ctx, "../escape.txt", strings.NewReader("x"))
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+
+	_, err = store.Save(ctx, "a/failed.txt", io.MultiReader(strings.NewReader("partial"), errReader{}))
+	assert.Error(t, err)
+	assert.Equal(t, []string{"a/b.txt"}, storedFiles(t, store), "failed saves leave no file behind")
+
+	require.NoError(t, store.Delete(ctx, "a/b.txt"))
+	require.NoError(t, store.Delete(ctx, "a/b.txt"), "deleting a missing file is not an error")
+	assert.Empty(t, storedFiles(t, store))
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	store, err := uploads.NewDiskStorage(t.TempDir())
+	require.NoError(t, err)
+	_, err = store.Save(ctx, "photos/cat.png", strings.NewReader(pngHeader))
+	require.NoError(t, err)
+	_, err = store.Save(ctx, "pages/evil.html", strings.NewReader("<html><script>alert(1)</script></html>"))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /uploads/{file...}", uploads.Handler(store, nil))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/uploads/photos/cat.png")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, `inline; filename=cat.png`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, pngHeader, w.Body.String())
+
+	w = get("/uploads/pages/evil.html")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename=evil.html`, w.Header().Get("Content-Disposition"))
+
+	assert.Equal(t, http.StatusNotFound, get("/uploads/photos/dog.png").Code)
+	assert.Equal(t, http.StatusNotFound, get("/uploads/photos").Code)
+}
+
+func TestReceive_Scan(t *testing.T) {
+	newGuard := func(async bool) *uploads.ScanGuard {
+		return uploads.NewScanGuard(func(opts *uploads.ScanOptions) {
+			opts.Scanner = stubScanner{}
+			opts.Async = async
+			opts.Logger = quietLogger
+		})
+	}
+
+	t.Run("clean files are accepted", func(t *testing.T) {
+		store, err := uploads.NewDiskStorage(t.TempDir())
+		require.NoError(t, err)
+		guard := newGuard(false)
+
+		upload, err := uploads.Receive(httptest.NewRecorder(), multipartRequest(t,
+			part{field: "a", filename: "a.txt", content: "hello"},
+		), store, func(opts *uploads.ReceiveOptions) { opts.Scan = guard })
+		require.NoError(t, err)
+
+		file, _ := upload.File("a")
+		assert.Equal(t, uploads.VerdictClean, file.Scan.Verdict)
+		assert.Equal(t, []string{file.Name}, storedFiles(t, store))
+		assert.Zero(t, guard.Len(), "results returned with the file are not kept")
+	})
+
+	t.Run("infected files fail the upload", func(t *testing.T) {
+		store, err := uploads.NewDiskStorage(t.TempDir())
+		require.NoError(t, err)
+
+		_, err = uploads.Receive(httptest.NewRecorder(), multipartRequest(t,
+			part{field: "a", filename: "a.txt", content: "hello"},
+			part{field: "b", filename: "b.txt", content: "a virus"},
+		), store, func(opts *uploads.ReceiveOptions) { opts.Scan = newGuard(false) })
+
+		var decodeErr *request.DecodeError
+		require.True(t, errors.As(err, &decodeErr), "got %v", err)
+		assert.Equal(t, http.StatusUnprocessableEntity, decodeErr.Status)
+		assert.Equal(t, "b", decodeErr.Field)
+		assert.ErrorIs(t, err, uploads.ErrInfected)
+		assert.Empty(t, storedFiles(t, store), "stored files are deleted on failure")
+	})
+
+	t.Run("async scans leave files pending", func(t *testing.T) {
+		store, err := uploads.NewDiskStorage(t.TempDir())
+		require.NoError(t, err)
+		guard := newGuard(true)
+
+		upload, err := uploads.Receive(httptest.NewRecorder(), multipartRequest(t,
+			part{field: "a", filename: "a.txt", content: "a virus"},
+		), store, func(opts *uploads.ReceiveOptions) { opts.Scan = guard })
+		require.NoError(t, err)
+
+		file, _ := upload.File("a")
+		assert.Equal(t, uploads.VerdictPending, file.Scan.Verdict)
+
+		require.NoError(t, guard.Wait(context.Background()))
+		status, ok := guard.Status(file.Name)
+		require.True(t, ok)
+		assert.Equal(t, uploads.VerdictInfected, status.Verdict)
+		assert.Empty(t, storedFiles(t, store))
+	})
+
+	t.Run("storage not on disk", func(t *testing.T) {
+		store, err := uploads.NewDiskStorage(t.TempDir())
+		require.NoError(t, err)
+
+		_, err = uploads.Receive(httptest.NewRecorder(), multipartRequest(t,
+			part{field: "a", filename: "a.txt", content: "hello"},
+		), struct{ uploads.Storage }{store}, func(opts *uploads.ReceiveOptions) { opts.Scan = newGuard(false) })
+		assert.ErrorIs(t, err, uploads.ErrNotScannable)
+	})
+}
@@ -0,0 +1,384 @@
+// Package uploads provides helpers for accepting user uploads safely.
+//
+// Receive streams the files of a multipart request to a Storage, such as DiskStorage, enforcing size,
+// count and content type limits and storing each file under a unique name. Handler serves stored files
+// back through a route, as attachments unless they are images.
+//
+//	store, err := uploads.NewDiskStorage("/var/lib/app/uploads")
+//	...
+//	upload, err := uploads.Receive(w, r, store, func(opts *uploads.ReceiveOptions) {
+//	    opts.AllowedTypes = []string{"image/*"}
+//	})
+//	...
+//	router.Get("/uploads/{file...}", uploads.Handler(store, nil))
+//
+// A Scanner inspects the content of a file. ClamdScanner talks to a ClamAV daemon over TCP or a Unix
+// socket. A ScanGuard applies a policy to scan results: infected files are rejected (deleted) or
+// quarantined (moved aside), either before the upload is accepted or asynchronously, with the upload
+// in a pending state until the result arrives as an event.
+//
+//	guard := uploads.NewScanGuard(func(opts *uploads.ScanOptions) {
+//	    opts.Scanner = uploads.NewClamdScanner(nil)
+//	    opts.Action = uploads.Quarantine
+//	    opts.QuarantineDir = "/var/lib/app/quarantine"
+//	})
+//
+//	upload, err := uploads.Receive(w, r, store, func(opts *uploads.ReceiveOptions) {
+//	    opts.Scan = guard
+//	})
+//	if errors.Is(err, uploads.ErrInfected) {
+//	    // tell the user the file was refused
+//	}
+//
+// Files stored elsewhere can be checked directly with guard.Check(ctx, id, path).
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/patrickward/hop/dispatch"
+)
+
+// Signatures of the events emitted by a ScanGuard. The payload is a ScanEvent.
+const (
+	// EventScanCompleted is emitted when an asynchronous scan finishes, whatever its verdict
+	EventScanCompleted = "uploads.scan_completed"
+	// EventInfected is emitted when a file is found to be infected and has been rejected or quarantined
+	EventInfected = "uploads.infected"
+)
+
+// ErrInfected is returned when a scanned file contains malware
+var ErrInfected = errors.New("uploads: file is infected")
+
+// Verdict is the outcome of scanning a file
+type Verdict int
+
+const (
+	// VerdictPending means the file is waiting to be scanned
+	VerdictPending Verdict = iota
+	// VerdictClean means no malware was found
+	VerdictClean
+	// VerdictInfected means malware was found
+	VerdictInfected
+	// VerdictError means the file could not be scanned
+	VerdictError
+)
+
+// String returns the name of the verdict
+func (v Verdict) String() string {
+	switch v {
+	case VerdictPending:
+		return "pending"
+	case VerdictClean:
+		return "clean"
+	case VerdictInfected:
+		return "infected"
+	case VerdictError:
+		return "error"
+	default:
+		return fmt.Sprintf("verdict-%d", int(v))
+	}
+}
+
+// ScanResult is the result of scanning a file
+type ScanResult struct {
+	// Verdict is the outcome of the scan
+	Verdict Verdict
+	// Signature names the malware found, for infected files
+	Signature string
+	// Err describes why the file could not be scanned, for VerdictError
+	Err error
+	// ScannedAt is when the scan finished
+	ScannedAt time.Time
+}
+
+// Scanner scans the content of a file for malware. Scan returns an error only when the file could not be
+// scanned; infected files are reported with VerdictInfected.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// InfectedAction is what a ScanGuard does with an infected file
+type InfectedAction int
+
+const (
+	// Reject deletes infected files
+	Reject InfectedAction = iota
+	// Quarantine moves infected files to the quarantine directory, so they can be inspected
+	Quarantine
+)
+
+// ScanEvent is the payload of the events emitted by a ScanGuard
+type ScanEvent struct {
+	// ID identifies the upload, as passed to Check
+	ID string
+	// Path is where the file was stored when it was scanned
+	Path string
+	// QuarantinePath is where an infected file was moved to, when it was quarantined
+	QuarantinePath string
+	// Result is the result of the scan
+	Result ScanResult
+}
+
+// ScanOptions configures a ScanGuard
+type ScanOptions struct {
+	// Scanner scans the files (required)
+	Scanner Scanner
+	// Action is what happens to infected files. Default is Reject.
+	Action InfectedAction
+	// QuarantineDir is where infected files are moved to with the Quarantine action (required for it)
+	QuarantineDir string
+	// Async scans files in the background. Check returns a pending result immediately, and the result is
+	// available from Status and emitted as an EventScanCompleted event when the scan finishes.
+	Async bool
+	// FailOpen accepts files that could not be scanned, e.g. because the scanner is unavailable. By
+	// default, such files are treated like infected ones.
+	FailOpen bool
+	// Timeout limits each scan. Default is 1 minute.
+	Timeout time.Duration
+	// ResultTTL is how long finished results are kept for Status. Default is 1 hour.
+	ResultTTL time.Duration
+	// Events, when set, receives EventScanCompleted and EventInfected events
+	Events *dispatch.Dispatcher
+	// Logger receives scan failures and infections. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// ScanGuard scans uploaded files and rejects or quarantines infected ones
+type ScanGuard struct {
+	opts      ScanOptions
+	mu        sync.RWMutex
+	results   map[string]scanEntry
+	lastPrune time.Time
+	wg        sync.WaitGroup
+}
+
+// scanEntry is a stored result and when it expires. Pending results don't expire.
+type scanEntry struct {
+	result  ScanResult
+	expires time.Time
+}
+
+// NewScanGuard creates a new ScanGuard. It panics if no Scanner is set, or if the Quarantine action is
+// used without a QuarantineDir.
+func NewScanGuard(optsFunc func(opts *ScanOptions)) *ScanGuard {
+	opts := ScanOptions{
+		Timeout:   time.Minute,
+		ResultTTL: time.Hour,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.Scanner == nil {
+		panic("uploads: ScanGuard requires a Scanner")
+	}
+	if opts.Action == Quarantine && opts.QuarantineDir == "" {
+		panic("uploads: the Quarantine action requires a QuarantineDir")
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+	if opts.ResultTTL <= 0 {
+		opts.ResultTTL = time.Hour
+	}
+
+	return &ScanGuard{
+		opts:      opts,
+		results:   make(map[string]scanEntry),
+		lastPrune: time.Now(),
+	}
+}
+
+// Check scans the file at path, stored for the upload identified by id. An infected file is rejected or
+// quarantined and ErrInfected is returned. With Async, the scan happens in the background and Check
+// returns a pending result straight away.
+func (g *ScanGuard) Check(ctx context.Context, id, path string) (ScanResult, error) {
+	if g.opts.Async {
+		pending := ScanResult{Verdict: VerdictPending}
+		g.setResult(id, pending)
+
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			ctx := context.WithoutCancel(ctx)
+			result, _ := g.scan(ctx, id, path)
+			g.emit(ctx, EventScanCompleted, ScanEvent{ID: id, Path: path, Result: result})
+		}()
+
+		return pending, nil
+	}
+
+	return g.scan(ctx, id, path)
+}
+
+// Status returns the latest result for the upload identified by id, and whether it is known. Finished
+// results are forgotten after ResultTTL.
+func (g *ScanGuard) Status(id string) (ScanResult, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	entry, ok := g.results[id]
+	if !ok || entry.expired(time.Now()) {
+		return ScanResult{}, false
+	}
+	return entry.result, true
+}
+
+// Forget removes the stored result for the upload identified by id, e.g. once it has been persisted,
+// rather than waiting for it to expire
+func (g *ScanGuard) Forget(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.results, id)
+}
+
+// Len returns the number of stored results, including expired ones not yet removed
+func (g *ScanGuard) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.results)
+}
+
+// Wait waits for the asynchronous scans in progress to finish, or for ctx to be done
+func (g *ScanGuard) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// scan scans the file and applies the infected action
+func (g *ScanGuard) scan(ctx context.Context, id, path string) (ScanResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.opts.Timeout)
+	defer cancel()
+
+	result, err := g.scanFile(ctx, path)
+	if err == nil && result.Verdict != VerdictClean && result.Verdict != VerdictInfected {
+		err = result.Err
+		if err == nil {
+			err = fmt.Errorf("unexpected verdict: %s", result.Verdict)
+		}
+	}
+	if err != nil {
+		result = ScanResult{Verdict: VerdictError, Err: err, ScannedAt: time.Now()}
+		g.opts.Logger.Error("failed to scan upload", slog.String("id", id), slog.String("error", err.Error()))
+		if g.opts.FailOpen {
+			g.setResult(id, result)
+			return result, nil
+		}
+	}
+
+	if result.Verdict == VerdictClean {
+		g.setResult(id, result)
+		return result, nil
+	}
+
+	event := ScanEvent{ID: id, Path: path, Result: result}
+	if actionErr := g.applyAction(&event); actionErr != nil {
+		g.opts.Logger.Error("failed to remove infected upload", slog.String("id", id), slog.String("error", actionErr.Error()))
+	}
+	g.setResult(id, result)
+
+	if result.Verdict == VerdictInfected {
+		g.opts.Logger.Warn("infected upload",
+			slog.String("id", id),
+			slog.String("signature", result.Signature),
+			slog.String("quarantine_path", event.QuarantinePath))
+		g.emit(ctx, EventInfected, event)
+		return result, fmt.Errorf("%w: %s", ErrInfected, result.Signature)
+	}
+	return result, fmt.Errorf("uploads: scanning file: %w", err)
+}
+
+// scanFile opens the file and passes it to the scanner
+func (g *ScanGuard) scanFile(ctx context.Context, path string) (ScanResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer f.Close()
+
+	result, err := g.opts.Scanner.Scan(ctx, f)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	if result.ScannedAt.IsZero() {
+		result.ScannedAt = time.Now()
+	}
+	return result, nil
+}
+
+// applyAction deletes or quarantines a file that is infected or could not be scanned
+func (g *ScanGuard) applyAction(event *ScanEvent) error {
+	if g.opts.Action == Reject {
+		return os.Remove(event.Path)
+	}
+
+	if err := os.MkdirAll(g.opts.QuarantineDir, 0o700); err != nil {
+		return err
+	}
+	dest := filepath.Join(g.opts.QuarantineDir, filepath.Base(event.Path))
+	if err := os.Rename(event.Path, dest); err != nil {
+		return err
+	}
+	event.QuarantinePath = dest
+	return nil
+}
+
+// setResult stores the latest result for an upload, removing the expired ones at most once per ResultTTL
+func (g *ScanGuard) setResult(id string, result ScanResult) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	entry := scanEntry{result: result}
+	if result.Verdict != VerdictPending {
+		entry.expires = now.Add(g.opts.ResultTTL)
+	}
+	g.results[id] = entry
+
+	if now.Sub(g.lastPrune) < g.opts.ResultTTL {
+		return
+	}
+	g.lastPrune = now
+	for id, entry := range g.results {
+		if entry.expired(now) {
+			delete(g.results, id)
+		}
+	}
+}
+
+// expired reports whether a finished result has outlived its TTL
+func (e scanEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// emit emits an event when a dispatcher is configured
+func (g *ScanGuard) emit(ctx context.Context, signature string, event ScanEvent) {
+	if g.opts.Events == nil {
+		return
+	}
+	if err := g.opts.Events.Emit(ctx, signature, event); err != nil {
+		g.opts.Logger.Error("failed to emit scan event", slog.String("event", signature), slog.String("error", err.Error()))
+	}
+}
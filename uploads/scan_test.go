@@ -0,0 +1,195 @@
+package uploads_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/uploads"
+)
+
+// stubScanner reports files containing "virus" as infected, and fails when err is set
+type stubScanner struct {
+	err error
+}
+
+func (s stubScanner) Scan(_ context.Context, r io.Reader) (uploads.ScanResult, error) {
+	if s.err != nil {
+		return uploads.ScanResult{}, s.err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return uploads.ScanResult{}, err
+	}
+	if strings.Contains(string(data), "virus") {
+		return uploads.ScanResult{Verdict: uploads.VerdictInfected, Signature: "Test.Virus"}, nil
+	}
+	return uploads.ScanResult{Verdict: uploads.VerdictClean}, nil
+}
+
+func writeUpload(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+var quietLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestScanGuard(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("clean files are kept", func(t *testing.T) {
+		guard := uploads.NewScanGuard(func(opts *uploads.ScanOptions) {
+			opts.Scanner = stubScanner{}
+			opts.Logger = quietLogger
+		})
+		path := writeUpload(t, t.TempDir(), "a.txt", "hello")
+
+		result, err := guard.Check(ctx, "a", path)
+		require.NoError(t, err)
+		assert.Equal(t, uploads.VerdictClean, result.Verdict)
+		assert.FileExists(t, path)
+
+		status, ok := guard.Status("a")
+		assert.True(t, ok)
+		assert.Equal(t, uploads.VerdictClean, status.Verdict)
+	})
+
+	t.Run("infected files are rejected", func(t *testing.T) {
+		guard := uploads.NewScanGuard(func(opts *uploads.ScanOptions) {
+			opts.Scanner = stubScanner{}
+			opts.Logger = quietLogger
+		})
+		path := writeUpload(t, t.TempDir(), "b.txt", "a virus")
+
+		result, err := guard.Check(ctx, "b", path)
+		assert.ErrorIs(t, err, uploads.ErrInfected)
+		assert.Equal(t, "Test.Virus", result.Signature)
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("infected files are quarantined", func(t *testing.T) {
+		quarantine := filepath.Join(t.TempDir(), "quarantine")
+		events := dispatch.NewDispatcher(quietLogger)
+		infected := make(chan uploads.ScanEvent, 1)
+		events.On(uploads.EventInfected, func(ctx context.Context, event dispatch.Event) {
+			infected <- event.Payload.(uploads.ScanEvent)
+		})
+
+		guard := uploads.NewScanGuard(func(opts *uploads.ScanOptions) {
+			opts.Scanner = stubScanner{}
+			opts.Action = uploads.Quarantine
+			opts.QuarantineDir = quarantine
+			opts.Events = events
+			opts.Logger = quietLogger
+		})
+		path := writeUpload(t, t.TempDir(), "c.txt", "a virus")
+
+		_, err := guard.Check(ctx, "c", path)
+		assert.ErrorIs(t, err, uploads.ErrInfected)
+		assert.NoFileExists(t, path)
+		assert.FileExists(t, filepath.Join(quarantine, "c.txt"))
+
+		select {
+		case event := <-infected:
+			assert.Equal(t, "c", event.ID)
+			assert.Equal(t, filepath.Join(quarantine, "c.txt"), event.QuarantinePath)
+		case <-time.After(time.Second):
+			t.Fatal("no infected event")
+		}
+	})
+
+	t.Run("scan failures", func(t *testing.T) {
+		dir := t.TempDir()
+		closed := uploads.NewScanGuard(func(opts *uploads.ScanOptions) {
+			opts.Scanner = stubScanner{err: errors.New("scanner down")}
+			opts.Logger = quietLogger
+		})
+		path := writeUpload(t, dir, "d.txt", "hello")
+		result, err := closed.Check(ctx, "d", path)
+		assert.ErrorContains(t, err, "scanner down")
+		assert.Equal(t, uploads.VerdictError, result.Verdict)
+		assert.NoFileExists(t, path, "files that can't be scanned are rejected by default")
+
+		open := uploads.NewScanGuard(func(opts *uploads.ScanOptions) {
+			opts.Scanner = stubScanner{err: errors.New("scanner down")}
+			opts.FailOpen = true
+			opts.Logger = quietLogger
+		})
+		path = writeUpload(t, dir, "e.txt", "hello")
+		result, err = open.Check(ctx, "e", path)
+		assert.NoError(t, err)
+		assert.Equal(t, uploads.VerdictError, result.Verdict)
+		assert.FileExists(t, path)
+	})
+
+	t.Run("async scans", func(t *testing.T) {
+		events := dispatch.NewDispatcher(quietLogger)
+		completed := make(chan uploads.ScanEvent, 1)
+		events.On(uploads.EventScanCompleted, func(ctx context.Context, event dispatch.Event) {
+			completed <- event.Payload.(uploads.ScanEvent)
+		})
+
+		guard := uploads.NewScanGuard(func(opts *uploads.ScanOptions) {
+			opts.Scanner = stubScanner{}
+			opts.Async = true
+			opts.Events = events
+			opts.Logger = quietLogger
+		})
+		path := writeUpload(t, t.TempDir(), "f.txt", "a virus")
+
+		result, err := guard.Check(ctx, "f", path)
+		require.NoError(t, err)
+		assert.Equal(t, uploads.VerdictPending, result.Verdict)
+
+		select {
+		case event := <-completed:
+			assert.Equal(t, uploads.VerdictInfected, event.Result.Verdict)
+		case <-time.After(time.Second):
+			t.Fatal("no scan completed event")
+		}
+
+		require.NoError(t, guard.Wait(ctx))
+		status, _ := guard.Status("f")
+		assert.Equal(t, uploads.VerdictInfected, status.Verdict)
+		assert.NoFileExists(t, path)
+
+		guard.Forget("f")
+		_, ok := guard.Status("f")
+		assert.False(t, ok)
+	})
+}
+
+func TestScanGuard_ResultTTL(t *testing.T) {
+	guard := uploads.NewScanGuard(func(opts *uploads.ScanOptions) {
+		opts.Scanner = stubScanner{}
+		opts.ResultTTL = 10 * time.Millisecond
+		opts.Logger = quietLogger
+	})
+	dir := t.TempDir()
+
+	_, err := guard.Check(context.Background(), "a", writeUpload(t, dir, "a.txt", "hello"))
+	require.NoError(t, err)
+	_, ok := guard.Status("a")
+	assert.True(t, ok)
+
+	time.Sleep(20 * time.Millisecond)
+	_, ok = guard.Status("a")
+	assert.False(t, ok, "finished results expire")
+
+	// Storing another result prunes the expired ones
+	_, err = guard.Check(context.Background(), "b", writeUpload(t, dir, "b.txt", "hello"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, guard.Len())
+}
@@ -0,0 +1,97 @@
+package uploads
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ServeOptions configures the Handler serving stored files
+type ServeOptions struct {
+	// PathValue is the wildcard of the route pattern holding the file name. Default is "file", for
+	// patterns such as "/uploads/{file...}".
+	PathValue string
+	// Inline lists the content types displayed in the browser, e.g. "image/*". Other files are sent as
+	// attachments, so uploaded HTML or SVG can't run scripts on the site. Default is the raster image
+	// types: image/png, image/jpeg, image/gif and image/webp.
+	Inline []string
+	// CacheControl is the Cache-Control header of served files. Default is "private, max-age=3600".
+	CacheControl string
+}
+
+// Handler returns a handler serving the files of storage, for routes with a {file...} wildcard:
+//
+//	router.Get("/uploads/{file...}", uploads.Handler(store, nil), requireLogin)
+//
+// Files are served with their content type detected from their content and "X-Content-Type-Options:
+// nosniff", and range requests are supported.
+func Handler(storage Storage, optsFunc func(opts *ServeOptions)) http.Handler {
+	opts := ServeOptions{
+		PathValue:    "file",
+		Inline:       []string{"image/png", "image/jpeg", "image/gif", "image/webp"},
+		CacheControl: "private, max-age=3600",
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.PathValue == "" {
+		opts.PathValue = "file"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue(opts.PathValue)
+		if !fs.ValidPath(name) || name == "." || strings.HasPrefix(path.Base(name), ".") {
+			http.NotFound(w, r)
+			return
+		}
+
+		f, err := storage.Open(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer func() { _ = f.Close() }()
+
+		stat, err := f.Stat()
+		if err != nil || stat.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		content, ok := f.(io.ReadSeeker)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(content, head)
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		contentType := http.DetectContentType(head[:n])
+
+		disposition := "attachment"
+		if typeAllowed(opts.Inline, contentType) {
+			disposition = "inline"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": path.Base(name)}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if opts.CacheControl != "" {
+			w.Header().Set("Cache-Control", opts.CacheControl)
+		}
+		http.ServeContent(w, r, path.Base(name), stat.ModTime(), content)
+	})
+}
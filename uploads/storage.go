@@ -0,0 +1,132 @@
+package uploads
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Storage stores uploaded files. Names are slash-separated paths valid for fs.FS, e.g.
+// "avatars/3f2a1b9c.png"; Open makes a Storage usable as an fs.FS to read the files back.
+type Storage interface {
+	fs.FS
+	// Save stores the content of r under name, replacing any file with that name. A failed Save leaves
+	// no partial file behind.
+	Save(ctx context.Context, name string, r io.Reader) (int64, error)
+	// Delete removes the file stored under name. Deleting a missing file is not an error.
+	Delete(ctx context.Context, name string) error
+}
+
+// DiskStorage is a Storage keeping files in a directory of the local disk
+type DiskStorage struct {
+	root string
+	fsys fs.FS
+}
+
+var _ Storage = (*DiskStorage)(nil)
+
+// NewDiskStorage creates a DiskStorage keeping files under root, creating the directory if needed
+func NewDiskStorage(root string) (*DiskStorage, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("uploads: creating storage directory: %w", err)
+	}
+	return &DiskStorage{root: root, fsys: os.DirFS(root)}, nil
+}
+
+// Open opens the file stored under name for reading
+func (s *DiskStorage) Open(name string) (fs.File, error) {
+	return s.fsys.Open(name)
+}
+
+// Path returns the path on disk of the file stored under name, e.g. to scan it with a ScanGuard
+func (s *DiskStorage) Path(name string) (string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return "", &fs.PathError{Op: "path", Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(s.root, filepath.FromSlash(name)), nil
+}
+
+// Save writes the content of r to a temporary file and renames it to name once complete
+func (s *DiskStorage) Save(ctx context.Context, name string, r io.Reader) (int64, error) {
+	dst, err := s.Path(name)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	n, err := io.Copy(tmp, contextReader{ctx: ctx, r: r})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, err
+	}
+
+	return n, os.Rename(tmp.Name(), dst)
+}
+
+// Delete removes the file stored under name
+func (s *DiskStorage) Delete(_ context.Context, name string) error {
+	p, err := s.Path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// UniqueName returns a random name keeping the extension of the original file name, e.g.
+// "3f2a1b9c4d5e6f708192a3b4c5d6e7f8.png" for "My Photo.PNG". The client's file name is never used as is,
+// so uploads can't overwrite each other or escape the storage directory.
+func UniqueName(original string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("uploads: generating a file name: " + err.Error())
+	}
+	return hex.EncodeToString(b) + safeExt(original)
+}
+
+// safeExt returns the lowercased extension of a client file name, or "" if it isn't short and alphanumeric
+func safeExt(name string) string {
+	ext := strings.ToLower(path.Ext(strings.ReplaceAll(name, "\\", "/")))
+	if len(ext) < 2 || len(ext) > 11 {
+		return ""
+	}
+	for _, c := range ext[1:] {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// contextReader stops reading once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
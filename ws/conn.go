@@ -0,0 +1,245 @@
+package ws
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/patrickward/hop/serve"
+)
+
+// ErrConnClosed is returned when sending to a closed connection
+var ErrConnClosed = errors.New("ws: connection closed")
+
+// Conn is a WebSocket connection managed by a Hub. Messages are queued with Send and written by the
+// connection's write pump.
+type Conn struct {
+	// ID is a random identifier of the connection
+	ID string
+	// Request is the upgrade request, e.g. to read the session or the authenticated user
+	Request *http.Request
+
+	hub  *Hub
+	ws   *websocket.Conn
+	send chan []byte
+
+	// rooms is guarded by hub.mu
+	rooms map[string]struct{}
+
+	mu     sync.Mutex
+	values map[string]any
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newConn creates a connection for the hub
+func newConn(h *Hub, ws *websocket.Conn, r *http.Request) *Conn {
+	return &Conn{
+		ID:      newConnID(),
+		Request: r,
+		hub:     h,
+		ws:      ws,
+		send:    make(chan []byte, h.opts.SendBuffer),
+		rooms:   make(map[string]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Send queues a text message. It returns false when the connection is closed, or when its queue is full,
+// in which case the connection is too slow to keep up and is closed.
+func (c *Conn) Send(msg []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		c.hub.opts.Logger.Warn("closing slow websocket connection", slog.String("conn", c.ID))
+		c.Close()
+		return false
+	}
+}
+
+// SendJSON encodes v as JSON and queues it
+func (c *Conn) SendJSON(v any) error {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if !c.Send(msg) {
+		return ErrConnClosed
+	}
+	return nil
+}
+
+// Join adds the connection to a room
+func (c *Conn) Join(room string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.hub.join(c, room)
+}
+
+// Leave removes the connection from a room
+func (c *Conn) Leave(room string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.hub.leave(c, room)
+}
+
+// Rooms returns the rooms the connection has joined, sorted
+func (c *Conn) Rooms() []string {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// Set stores a value on the connection, e.g. the user it belongs to
+func (c *Conn) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.values == nil {
+		c.values = make(map[string]any)
+	}
+	c.values[key] = value
+}
+
+// Get returns a value stored with Set
+func (c *Conn) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// Close closes the connection after the write pump sends a close frame. It is safe to call Close more
+// than once.
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// readPump delivers messages to OnMessage until the connection fails or is closed
+func (c *Conn) readPump() {
+	for {
+		var msg []byte
+		if err := websocket.Message.Receive(c.ws, &msg); err != nil {
+			if !errors.Is(err, io.EOF) && !isClosed(c.done) {
+				c.hub.opts.Logger.Debug("websocket read failed",
+					slog.String("conn", c.ID),
+					slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		if c.hub.opts.OnMessage != nil {
+			c.hub.opts.OnMessage(c, msg)
+		}
+	}
+}
+
+// writePump writes queued messages and pings until the connection is closed, then closes the socket,
+// which ends the read pump
+func (c *Conn) writePump() {
+	defer func() { _ = c.ws.Close() }()
+
+	ticker := time.NewTicker(c.hub.opts.PingInterval)
+	defer ticker.Stop()
+
+	var restart <-chan serve.RestartNotice
+	if c.hub.opts.Server != nil {
+		sub := c.hub.opts.Server.SubscribeRestart()
+		defer sub.Close()
+		restart = sub.C
+	}
+
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.write(websocket.TextFrame, msg); err != nil {
+				c.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.write(websocket.PingFrame, nil); err != nil {
+				c.Close()
+				return
+			}
+		case notice := <-restart:
+			if msg, err := json.Marshal(notice); err == nil {
+				_ = c.write(websocket.TextFrame, msg)
+			}
+			c.Close()
+			return
+		case <-c.done:
+			c.flush()
+			return
+		}
+	}
+}
+
+// flush writes the messages still queued when the connection is closed
+func (c *Conn) flush() {
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.write(websocket.TextFrame, msg); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// write writes a frame with the write timeout
+func (c *Conn) write(frameType byte, msg []byte) error {
+	_ = c.ws.SetWriteDeadline(time.Now().Add(c.hub.opts.WriteTimeout))
+	c.ws.PayloadType = frameType
+	_, err := c.ws.Write(msg)
+	if err != nil {
+		c.hub.opts.Logger.Debug("websocket write failed",
+			slog.String("conn", c.ID),
+			slog.String("error", err.Error()))
+	}
+	return err
+}
+
+// isClosed reports whether the channel is closed
+func isClosed(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// newConnID returns a random connection ID
+func newConnID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,343 @@
+// Package ws provides WebSocket support: an upgrade handler, a Hub tracking connections and the rooms they
+// joined, broadcast helpers, and forwarding of dispatch events to connected clients. Each connection has a
+// read pump, delivering messages to OnMessage, and a write pump, sending queued messages and pings, so
+// handlers never write to the network directly.
+//
+//	hub := ws.NewHub(func(opts *ws.HubOptions) {
+//		opts.Server = app.Server()
+//		opts.OnConnect = func(c *ws.Conn) error {
+//			c.Join("orders")
+//			return nil
+//		}
+//	})
+//	hub.Forward(app.Dispatcher(), "orders.*", func(dispatch.Event) string { return "orders" })
+//	app.Router().Get("/ws", hub)
+//	app.RegisterShutdownPhase(hop.ShutdownDrainHTTP, "websockets", hub.Shutdown)
+package ws
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/serve"
+)
+
+// HubOptions configures a Hub
+type HubOptions struct {
+	// SendBuffer is the number of messages queued for each connection. A connection whose queue is full
+	// is too slow to keep up and is closed. Default is 64.
+	SendBuffer int
+	// WriteTimeout limits each write to a connection. Default is 10 seconds.
+	WriteTimeout time.Duration
+	// PingInterval is how often connections are pinged to keep them alive through proxies and to detect
+	// dead peers. Default is 30 seconds.
+	PingInterval time.Duration
+	// MaxMessageSize is the largest message accepted from a client, in bytes. Larger messages close the
+	// connection. Default is 64 KB.
+	MaxMessageSize int
+	// CheckOrigin reports whether an upgrade request is allowed. Default allows requests without an Origin
+	// header and requests whose Origin host matches the Host header.
+	CheckOrigin func(r *http.Request) bool
+	// OnConnect is called for each new connection before its messages are read, e.g. to join rooms based
+	// on the authenticated user. Returning an error closes the connection.
+	OnConnect func(c *Conn) error
+	// OnMessage is called for each message received from a client, in the connection's read goroutine
+	OnMessage func(c *Conn, msg []byte)
+	// OnDisconnect is called when a connection closes, after it has left its rooms
+	OnDisconnect func(c *Conn)
+	// Server, when set, sends the server's restart notice to every connection when it begins a graceful
+	// shutdown, then closes the connection, so clients know to reconnect
+	Server *serve.Server
+	// Logger receives connection errors. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Hub tracks WebSocket connections and the rooms they have joined. It is an http.Handler that upgrades
+// requests to WebSocket connections. A Hub is safe for concurrent use.
+type Hub struct {
+	opts HubOptions
+
+	mu       sync.RWMutex
+	conns    map[*Conn]struct{}
+	rooms    map[string]map[*Conn]struct{}
+	draining bool
+	wg       sync.WaitGroup
+}
+
+// NewHub creates a new hub
+func NewHub(optsFunc func(opts *HubOptions)) *Hub {
+	opts := HubOptions{
+		SendBuffer:     64,
+		WriteTimeout:   10 * time.Second,
+		PingInterval:   30 * time.Second,
+		MaxMessageSize: 64 << 10,
+	}
+
+	if optsFunc != nil {
+		optsFunc(&opts)
+	}
+
+	if opts.SendBuffer <= 0 {
+		opts.SendBuffer = 64
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = 64 << 10
+	}
+	if opts.CheckOrigin == nil {
+		opts.CheckOrigin = sameOrigin
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	return &Hub{
+		opts:  opts,
+		conns: make(map[*Conn]struct{}),
+		rooms: make(map[string]map[*Conn]struct{}),
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves it until it closes. Requests are
+// rejected with a 403 when CheckOrigin fails, and with a 503 once the hub is shutting down.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.opts.CheckOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	h.mu.Lock()
+	if h.draining {
+		h.mu.Unlock()
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	h.wg.Add(1)
+	h.mu.Unlock()
+	defer h.wg.Done()
+
+	server := websocket.Server{
+		// The origin was checked above; x/net's default check rejects clients without an Origin header
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			h.serve(ws, r)
+		},
+	}
+	server.ServeHTTP(hijackWriter{w}, r)
+}
+
+// Broadcast sends a text message to every connection
+func (h *Hub) Broadcast(msg []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.conns {
+		c.Send(msg)
+	}
+}
+
+// BroadcastTo sends a text message to every connection in the room
+func (h *Hub) BroadcastTo(room string, msg []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.rooms[room] {
+		c.Send(msg)
+	}
+}
+
+// BroadcastJSON encodes v as JSON and sends it to every connection in the room, or to every connection
+// when room is empty
+func (h *Hub) BroadcastJSON(room string, v any) error {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if room == "" {
+		h.Broadcast(msg)
+	} else {
+		h.BroadcastTo(room, msg)
+	}
+	return nil
+}
+
+// Forward pushes the events matching the signature to connected clients, encoded as JSON:
+//
+//	{"id": "...", "signature": "orders.created", "payload": {...}, "timestamp": "..."}
+//
+// The room function picks the room of each event; an empty room sends it to every connection. A nil
+// room function sends every event to every connection. Unsubscribe the returned subscription to stop.
+func (h *Hub) Forward(d *dispatch.Dispatcher, signature string, room func(e dispatch.Event) string) *dispatch.Subscription {
+	return d.On(signature, func(_ context.Context, e dispatch.Event) {
+		target := ""
+		if room != nil {
+			target = room(e)
+		}
+		if err := h.BroadcastJSON(target, e); err != nil {
+			h.opts.Logger.Error("failed to encode event for websocket clients",
+				slog.String("signature", e.Signature),
+				slog.String("error", err.Error()))
+		}
+	})
+}
+
+// Len returns the number of open connections
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// RoomLen returns the number of connections in the room
+func (h *Hub) RoomLen(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Shutdown stops accepting connections, closes the open ones and waits for them to finish, or for ctx to be
+// done. Register it with the app's shutdown phases so connections are drained with the HTTP server.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.draining = true
+	conns := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serve runs a connection's pumps until it closes
+func (h *Hub) serve(ws *websocket.Conn, r *http.Request) {
+	ws.MaxPayloadBytes = h.opts.MaxMessageSize
+
+	c := newConn(h, ws, r)
+	if !h.add(c) {
+		_ = ws.Close()
+		return
+	}
+	defer h.remove(c)
+
+	if h.opts.OnConnect != nil {
+		if err := h.opts.OnConnect(c); err != nil {
+			h.opts.Logger.Debug("websocket connection rejected", slog.String("error", err.Error()))
+			_ = ws.Close()
+			return
+		}
+	}
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		c.writePump()
+	}()
+
+	c.readPump()
+	c.Close()
+	<-writerDone
+}
+
+// add registers a connection, unless the hub is shutting down
+func (h *Hub) add(c *Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.draining {
+		return false
+	}
+	h.conns[c] = struct{}{}
+	return true
+}
+
+// remove unregisters a connection and takes it out of its rooms
+func (h *Hub) remove(c *Conn) {
+	h.mu.Lock()
+	delete(h.conns, c)
+	for room := range c.rooms {
+		h.leave(c, room)
+	}
+	h.mu.Unlock()
+
+	if h.opts.OnDisconnect != nil {
+		h.opts.OnDisconnect(c)
+	}
+}
+
+// join adds a connection to a room; h.mu must be held
+func (h *Hub) join(c *Conn, room string) {
+	members, ok := h.rooms[room]
+	if !ok {
+		members = make(map[*Conn]struct{})
+		h.rooms[room] = members
+	}
+	members[c] = struct{}{}
+	c.rooms[room] = struct{}{}
+}
+
+// leave removes a connection from a room; h.mu must be held
+func (h *Hub) leave(c *Conn, room string) {
+	if members, ok := h.rooms[room]; ok {
+		delete(members, c)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+	delete(c.rooms, room)
+}
+
+// sameOrigin allows requests without an Origin header, and requests from the same host
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// hijackWriter lets x/net/websocket hijack connections through response writers wrapped by middleware
+type hijackWriter struct {
+	http.ResponseWriter
+}
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,124 @@
+package ws_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	"github.com/patrickward/hop/dispatch"
+	"github.com/patrickward/hop/ws"
+)
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// dial connects a client to the test server
+func dial(t *testing.T, server *httptest.Server, path string) *websocket.Conn {
+	t.Helper()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+path, "", server.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// receive reads a text message from the client connection
+func receive(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var msg string
+	require.NoError(t, websocket.Message.Receive(conn, &msg))
+	return msg
+}
+
+func TestHub(t *testing.T) {
+	hub := ws.NewHub(func(opts *ws.HubOptions) {
+		opts.Logger = quietLogger()
+		opts.OnConnect = func(c *ws.Conn) error {
+			if room := c.Request.URL.Query().Get("room"); room != "" {
+				c.Join(room)
+			}
+			return nil
+		}
+		opts.OnMessage = func(c *ws.Conn, msg []byte) {
+			c.Send(append([]byte("echo: "), msg...))
+		}
+	})
+
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	t.Run("echoes messages", func(t *testing.T) {
+		conn := dial(t, server, "")
+		require.NoError(t, websocket.Message.Send(conn, "hello"))
+		assert.Equal(t, "echo: hello", receive(t, conn))
+	})
+
+	t.Run("broadcasts to rooms", func(t *testing.T) {
+		member := dial(t, server, "/?room=orders")
+		other := dial(t, server, "")
+
+		require.Eventually(t, func() bool { return hub.RoomLen("orders") == 1 }, time.Second, 10*time.Millisecond)
+
+		hub.BroadcastTo("orders", []byte("order shipped"))
+		hub.Broadcast([]byte("everyone"))
+
+		assert.Equal(t, "order shipped", receive(t, member))
+		assert.Equal(t, "everyone", receive(t, member))
+		assert.Equal(t, "everyone", receive(t, other))
+
+		_ = member.Close()
+		assert.Eventually(t, func() bool { return hub.RoomLen("orders") == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("forwards events", func(t *testing.T) {
+		events := dispatch.NewDispatcher(quietLogger())
+		hub.Forward(events, "orders.*", nil)
+
+		conn := dial(t, server, "")
+		require.Eventually(t, func() bool { return hub.Len() >= 1 }, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, events.EmitSync(context.Background(), "orders.created", map[string]int{"id": 7}))
+
+		var event struct {
+			Signature string         `json:"signature"`
+			Payload   map[string]int `json:"payload"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(receive(t, conn)), &event))
+		assert.Equal(t, "orders.created", event.Signature)
+		assert.Equal(t, map[string]int{"id": 7}, event.Payload)
+	})
+
+	t.Run("rejects cross-origin requests", func(t *testing.T) {
+		_, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", "http://evil.example.com")
+		assert.Error(t, err)
+	})
+
+	t.Run("shutdown closes connections", func(t *testing.T) {
+		conn := dial(t, server, "")
+		require.Eventually(t, func() bool { return hub.Len() >= 1 }, time.Second, 10*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		require.NoError(t, hub.Shutdown(ctx))
+		assert.Equal(t, 0, hub.Len())
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		var msg string
+		assert.Error(t, websocket.Message.Receive(conn, &msg))
+
+		_, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+		assert.Error(t, err, "new connections are refused while draining")
+	})
+}